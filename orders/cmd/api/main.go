@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"orders/internal/domain/entity"
+	"orders/internal/infra/broker"
 	"orders/internal/infra/database"
 	grpcClient "orders/internal/infra/grpc/client"
+	"orders/internal/infra/http/auth"
 	"orders/internal/infra/http/handler"
+	"orders/internal/infra/http/problem"
 	infraRepo "orders/internal/infra/repository"
 	"orders/internal/usecase"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	_ "orders/docs"
@@ -22,7 +31,8 @@ import (
 
 // @title Orders API
 // @version 1.0
-// @description API robusta de gerenciamento de pedidos com carrinho de compras
+// @description API robusta de gerenciamento de pedidos com carrinho de compras.
+// @description Erros seguem a RFC 7807: corpo application/problem+json com type, title, status, detail, instance, request_id, errors (campos inválidos) e um code estável, listado no schema problem.Problem.
 // @termsOfService http://swagger.io/terms/
 
 // @contact.name API Support
@@ -35,6 +45,11 @@ import (
 // @BasePath /api/v1
 // @schemes http https
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT (HS256 or RS256) as "Bearer <token>". Roles: admin, customer, service.
+
 func main() {
 	// Setup structured logging
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
@@ -64,7 +79,18 @@ func main() {
 		paymentServiceAddr = "localhost:50051"
 	}
 
-	paymentClient, err := grpcClient.NewPaymentClient(paymentServiceAddr, logger)
+	// TLS is enabled by PAYMENT_SERVICE_TLS or by any of the certificate
+	// files; the files are read again when they are rotated
+	paymentServiceSecurity := grpcClient.SecurityConfig{
+		TLS:        os.Getenv("PAYMENT_SERVICE_TLS") == "true",
+		CAFile:     os.Getenv("PAYMENT_SERVICE_CA_FILE"),
+		CertFile:   os.Getenv("PAYMENT_SERVICE_CERT_FILE"),
+		KeyFile:    os.Getenv("PAYMENT_SERVICE_KEY_FILE"),
+		ServerName: os.Getenv("PAYMENT_SERVICE_SERVER_NAME"),
+		Token:      os.Getenv("PAYMENT_SERVICE_TOKEN"),
+	}
+
+	paymentClient, err := grpcClient.NewPaymentClient(paymentServiceAddr, paymentServiceSecurity, logger)
 	if err != nil {
 		slog.Error("Failed to connect to payment service", "error", err)
 		os.Exit(1)
@@ -75,27 +101,230 @@ func main() {
 	// Initialize repositories
 	productRepo := infraRepo.NewProductRepository(db, logger)
 	orderRepo := infraRepo.NewOrderRepository(db, logger)
+	customerRepo := infraRepo.NewCustomerRepository(db, logger)
+	orderStatusHistoryRepo := infraRepo.NewOrderStatusHistoryRepository(db, logger)
+	stockReservationRepo := infraRepo.NewStockReservationRepository(db, logger)
+	outboxRepo := infraRepo.NewOutboxRepository(db, logger)
+	checkoutSagaRepo := infraRepo.NewCheckoutSagaRepository(db, logger)
+	streamCursorRepo := infraRepo.NewStreamCursorRepository(db, logger)
+	promotionRepo := infraRepo.NewPromotionRepository(db, logger)
+
+	// Order events are published through the broker chosen by EVENT_BROKER
+	eventBroker, err := broker.New(os.Getenv("EVENT_BROKER"), getEnv("EVENT_BROKER_FILE", "events.jsonl"))
+	if err != nil {
+		slog.Error("Failed to create event broker", "error", err)
+		os.Exit(1)
+	}
+	defer eventBroker.Close()
+
+	outboxRelayInterval := 2 * time.Second
+	if interval := os.Getenv("OUTBOX_RELAY_INTERVAL"); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil {
+			slog.Error("Invalid OUTBOX_RELAY_INTERVAL", "value", interval, "error", err)
+			os.Exit(1)
+		}
+		outboxRelayInterval = parsed
+	}
+
+	// Unfinished checkout sagas are resumed once they have been left alone
+	// for CHECKOUT_SAGA_STALE_AFTER, checking every CHECKOUT_SAGA_RESUME_INTERVAL
+	checkoutSagaResumeInterval := 30 * time.Second
+	if interval := os.Getenv("CHECKOUT_SAGA_RESUME_INTERVAL"); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil {
+			slog.Error("Invalid CHECKOUT_SAGA_RESUME_INTERVAL", "value", interval, "error", err)
+			os.Exit(1)
+		}
+		checkoutSagaResumeInterval = parsed
+	}
+
+	checkoutSagaStaleAfter := time.Minute
+	if staleAfter := os.Getenv("CHECKOUT_SAGA_STALE_AFTER"); staleAfter != "" {
+		parsed, err := time.ParseDuration(staleAfter)
+		if err != nil {
+			slog.Error("Invalid CHECKOUT_SAGA_STALE_AFTER", "value", staleAfter, "error", err)
+			os.Exit(1)
+		}
+		checkoutSagaStaleAfter = parsed
+	}
+
+	// Cart stock reservations are released when the cart is left untouched
+	cartReservationTTL := 30 * time.Minute
+	if ttl := os.Getenv("CART_RESERVATION_TTL"); ttl != "" {
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil {
+			slog.Error("Invalid CART_RESERVATION_TTL", "value", ttl, "error", err)
+			os.Exit(1)
+		}
+		cartReservationTTL = parsed
+	}
+
+	// Carts expire when left untouched for CART_TTL (GUEST_CART_TTL for
+	// anonymous carts); expired carts are looked for every CART_EXPIRY_INTERVAL
+	cartTTL := 72 * time.Hour
+	if ttl := os.Getenv("CART_TTL"); ttl != "" {
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil {
+			slog.Error("Invalid CART_TTL", "value", ttl, "error", err)
+			os.Exit(1)
+		}
+		cartTTL = parsed
+	}
+	guestCartTTL := 24 * time.Hour
+	if ttl := os.Getenv("GUEST_CART_TTL"); ttl != "" {
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil {
+			slog.Error("Invalid GUEST_CART_TTL", "value", ttl, "error", err)
+			os.Exit(1)
+		}
+		guestCartTTL = parsed
+	}
+	cartExpiryInterval := time.Minute
+	if interval := os.Getenv("CART_EXPIRY_INTERVAL"); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil {
+			slog.Error("Invalid CART_EXPIRY_INTERVAL", "value", interval, "error", err)
+			os.Exit(1)
+		}
+		cartExpiryInterval = parsed
+	}
+
+	// Carts are charged SHIPPING_FEE (in major units, e.g. 15.90) for
+	// delivery, which free shipping promotions waive
+	var shippingFee int64
+	if fee := os.Getenv("SHIPPING_FEE"); fee != "" {
+		parsed, err := entity.ParseDecimal(fee)
+		if err != nil || parsed < 0 {
+			slog.Error("Invalid SHIPPING_FEE", "value", fee, "error", err)
+			os.Exit(1)
+		}
+		shippingFee = parsed
+	}
+
+	// Orders are priced from the catalog only; PRODUCT_QUICK_ADD=true lets
+	// admins order unknown products, created with the price they send
+	productQuickAdd := os.Getenv("PRODUCT_QUICK_ADD") == "true"
+	if productQuickAdd {
+		slog.Warn("Product quick add is enabled: admin orders create unknown products")
+	}
 
 	// Initialize use cases
 	productUseCase := usecase.NewProductUseCase(productRepo, logger)
-	orderUseCase := usecase.NewOrderUseCase(orderRepo, logger)
-	cartUseCase := usecase.NewCartUseCase(orderRepo, productRepo, logger)
-	createOrderWithPaymentUseCase := usecase.NewCreateOrderUseCase(orderRepo, productRepo, paymentClient, logger)
-	cancelOrderUseCase := usecase.NewCancelOrderUseCase(orderRepo, paymentClient, logger)
+	orderUseCase := usecase.NewOrderUseCase(orderRepo, orderStatusHistoryRepo, logger)
+	customerUseCase := usecase.NewCustomerUseCase(customerRepo, orderRepo, logger)
+	stockReservationUseCase := usecase.NewStockReservationUseCase(stockReservationRepo, productRepo, cartReservationTTL, logger)
+	promotionUseCase := usecase.NewPromotionUseCase(promotionRepo, shippingFee, logger)
+	cartUseCase := usecase.NewCartUseCase(orderRepo, productRepo, customerRepo, stockReservationUseCase, promotionUseCase, cartTTL, guestCartTTL, logger)
+	cartExpiryUseCase := usecase.NewCartExpiryUseCase(orderRepo, customerRepo, stockReservationUseCase, 100, logger)
+	checkoutSagaUseCase := usecase.NewCheckoutSagaUseCase(checkoutSagaRepo, orderRepo, stockReservationUseCase, paymentClient, 100, logger)
+	createOrderWithPaymentUseCase := usecase.NewCreateOrderUseCase(orderRepo, productRepo, customerRepo, promotionUseCase, checkoutSagaUseCase, paymentClient, productQuickAdd, logger)
+	cancelOrderUseCase := usecase.NewCancelOrderUseCase(orderRepo, checkoutSagaUseCase, logger)
+	refundOrderUseCase := usecase.NewRefundOrderUseCase(orderRepo, paymentClient, logger)
+	boletoSlipUseCase := usecase.NewBoletoSlipUseCase(orderRepo, paymentClient, logger)
+	paymentWatcherUseCase := usecase.NewPaymentWatcherUseCase(checkoutSagaRepo, orderRepo, streamCursorRepo, checkoutSagaUseCase, paymentClient, logger)
+	updateOrderStatusUseCase := usecase.NewUpdateOrderStatusUseCase(orderRepo, paymentClient, logger)
+	outboxRelayUseCase := usecase.NewOutboxRelayUseCase(outboxRepo, eventBroker, 100, logger)
+
+	// Background workers stop with the server on SIGINT or SIGTERM; the
+	// server waits for them to finish what they are doing before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(ctx)
+		}()
+	}
+
+	// Release expired cart reservations in the background
+	runWorker(func(ctx context.Context) {
+		stockReservationUseCase.Run(ctx, time.Minute)
+	})
+
+	// Expire abandoned carts, releasing their stock and recording
+	// CartAbandoned for marketing
+	runWorker(func(ctx context.Context) {
+		cartExpiryUseCase.Run(ctx, cartExpiryInterval)
+	})
+
+	// Finish checkouts interrupted by a crash, a failed step or a payment
+	// still being processed
+	runWorker(func(ctx context.Context) {
+		checkoutSagaUseCase.Run(ctx, checkoutSagaResumeInterval, checkoutSagaStaleAfter)
+	})
+
+	// Apply payment status changes pushed by the payments service, so PIX and
+	// boleto orders are confirmed or canceled as soon as they are decided
+	runWorker(paymentWatcherUseCase.Run)
+
+	// Publish order events saved in the outbox
+	runWorker(func(ctx context.Context) {
+		outboxRelayUseCase.Run(ctx, outboxRelayInterval)
+	})
+
+	// Requests are authenticated with JWTs signed with JWT_SECRET (HS256) or
+	// by a key of JWT_JWKS_FILE (RS256). AUTH_DISABLED=true lets every request
+	// through as an admin, for local development only.
+	var verifier *auth.Verifier
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		slog.Warn("Authentication is disabled, every request acts as an admin")
+	} else {
+		jwtLeeway := 30 * time.Second
+		if leeway := os.Getenv("JWT_LEEWAY"); leeway != "" {
+			parsed, err := time.ParseDuration(leeway)
+			if err != nil {
+				slog.Error("Invalid JWT_LEEWAY", "value", leeway, "error", err)
+				os.Exit(1)
+			}
+			jwtLeeway = parsed
+		}
+
+		verifier, err = auth.NewVerifier(auth.Config{
+			HMACSecret: os.Getenv("JWT_SECRET"),
+			JWKSFile:   os.Getenv("JWT_JWKS_FILE"),
+			Issuer:     os.Getenv("JWT_ISSUER"),
+			Audience:   os.Getenv("JWT_AUDIENCE"),
+			Leeway:     jwtLeeway,
+		})
+		if err != nil {
+			slog.Error("Failed to configure authentication", "error", err)
+			os.Exit(1)
+		}
+	}
+	authMiddleware := auth.NewMiddleware(verifier, logger)
+	requireAdmin := authMiddleware.RequireRole(auth.RoleAdmin)
+	requirePrivileged := authMiddleware.RequireRole(auth.RoleAdmin, auth.RoleService)
+
+	// Customers only reach their own profile, carts and orders
+	customerOwner := func(r *http.Request) (string, error) {
+		return chi.URLParam(r, "id"), nil
+	}
+	orderOwner := func(r *http.Request) (string, error) {
+		order, err := orderUseCase.GetOrder(chi.URLParam(r, "id"))
+		if err != nil {
+			return "", err
+		}
+		return order.CustomerID, nil
+	}
 
 	// Initialize handlers
 	productHandler := handler.NewProductHandler(productUseCase, logger)
 	orderHandler := handler.NewOrderHandler(orderUseCase, logger)
 	cartHandler := handler.NewCartHandler(cartUseCase, logger)
-	orderWithPaymentHandler := handler.NewOrderWithPaymentHandler(createOrderWithPaymentUseCase, cancelOrderUseCase, logger)
+	customerHandler := handler.NewCustomerHandler(customerUseCase, logger)
+	promotionHandler := handler.NewPromotionHandler(promotionUseCase, logger)
+	orderWithPaymentHandler := handler.NewOrderWithPaymentHandler(createOrderWithPaymentUseCase, cancelOrderUseCase, refundOrderUseCase, updateOrderStatusUseCase, boletoSlipUseCase, logger)
 
 	// Setup router
 	r := chi.NewRouter()
 
 	// Middlewares
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Timeout(60 * time.Second))
 
@@ -103,12 +332,20 @@ func main() {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Idempotency-Key", "If-Match"},
+		ExposedHeaders:   []string{"Link", "Idempotent-Replayed", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
 
+	// Unknown routes answer with problems too
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeNotFound, "Route not found"))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.New(http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "Method not allowed on this route"))
+	})
+
 	// Health check
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -120,35 +357,81 @@ func main() {
 
 	// API Routes
 	r.Route("/api/v1", func(r chi.Router) {
-		// Product routes
+		// Product routes: the catalog is public, changes need an admin
 		r.Route("/products", func(r chi.Router) {
 			r.Get("/", productHandler.List)
-			r.Post("/", productHandler.Create)
 			r.Get("/{id}", productHandler.GetByID)
-			r.Put("/{id}", productHandler.Update)
-			r.Delete("/{id}", productHandler.Delete)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.Authenticate, requireAdmin)
+				r.Post("/", productHandler.Create)
+				r.Put("/{id}", productHandler.Update)
+				r.Delete("/{id}", productHandler.Delete)
+			})
 		})
 
-		// Order routes
-		r.Route("/orders", func(r chi.Router) {
-			r.Get("/", orderHandler.List)
-			r.Get("/{id}", orderHandler.GetByID)
-			r.Delete("/{id}", orderHandler.Delete)
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.Authenticate)
 
-			// Order with payment integration
-			r.Post("/with-payment", orderWithPaymentHandler.CreateOrderWithPayment)
-			r.Post("/{id}/cancel", orderWithPaymentHandler.CancelOrder)
-		})
+			// Customer routes
+			r.Route("/customers", func(r chi.Router) {
+				r.With(requireAdmin).Get("/", customerHandler.List)
+				r.With(requirePrivileged).Post("/", customerHandler.Create)
+				r.With(requireAdmin).Delete("/{id}", customerHandler.Delete)
+
+				r.Group(func(r chi.Router) {
+					r.Use(authMiddleware.RequireOwner(customerOwner))
+					r.Get("/{id}", customerHandler.GetByID)
+					r.Put("/{id}", customerHandler.Update)
+					r.Get("/{id}/orders", customerHandler.ListOrders)
+				})
+			})
+
+			// Order routes
+			r.Route("/orders", func(r chi.Router) {
+				r.With(requirePrivileged).Get("/", orderHandler.List)
+				r.With(requireAdmin).Delete("/{id}", orderHandler.Delete)
+
+				// Order with payment integration
+				r.Post("/with-payment", orderWithPaymentHandler.CreateOrderWithPayment)
+				r.With(requirePrivileged).Post("/{id}/refund", orderWithPaymentHandler.RefundOrder)
+				r.With(requireAdmin).Put("/{id}/status", orderWithPaymentHandler.UpdateStatus)
+
+				r.Group(func(r chi.Router) {
+					r.Use(authMiddleware.RequireOwner(orderOwner))
+					r.Get("/{id}", orderHandler.GetByID)
+					r.Get("/{id}/history", orderHandler.History)
+					r.Post("/{id}/cancel", orderWithPaymentHandler.CancelOrder)
+					r.Get("/{id}/boleto", orderWithPaymentHandler.BoletoSlip)
+				})
+			})
+
+			// Promotion routes: coupons and automatic discount rules
+			r.Route("/promotions", func(r chi.Router) {
+				r.Use(requireAdmin)
+				r.Post("/", promotionHandler.Create)
+				r.Get("/", promotionHandler.List)
+				r.Get("/{id}", promotionHandler.GetByID)
+				r.Delete("/{id}", promotionHandler.Deactivate)
+			})
+
+			// Cart routes
+			r.Route("/cart", func(r chi.Router) {
+				r.Post("/", cartHandler.CreateCart)
+				r.With(requireAdmin).Put("/{id}/status", cartHandler.UpdateStatus)
 
-		// Cart routes
-		r.Route("/cart", func(r chi.Router) {
-			r.Post("/", cartHandler.CreateCart)
-			r.Get("/{id}", cartHandler.GetCart)
-			r.Post("/{id}/items", cartHandler.AddItem)
-			r.Delete("/{id}/items/{itemId}", cartHandler.RemoveItem)
-			r.Put("/{id}/items/{itemId}", cartHandler.UpdateItemQuantity)
-			r.Get("/{id}/calculate", cartHandler.CalculateTotal)
-			r.Put("/{id}/status", cartHandler.UpdateStatus)
+				r.Group(func(r chi.Router) {
+					r.Use(authMiddleware.RequireOwner(orderOwner))
+					r.Get("/{id}", cartHandler.GetCart)
+					r.Post("/{id}/items", cartHandler.AddItem)
+					r.Delete("/{id}/items/{itemId}", cartHandler.RemoveItem)
+					r.Put("/{id}/items/{itemId}", cartHandler.UpdateItemQuantity)
+					r.Post("/{id}/coupons", cartHandler.ApplyCoupon)
+					r.Delete("/{id}/coupons/{code}", cartHandler.RemoveCoupon)
+					r.Get("/{id}/calculate", cartHandler.CalculateTotal)
+					r.Post("/{id}/checkout", orderWithPaymentHandler.CheckoutCart)
+				})
+			})
 		})
 	})
 
@@ -158,9 +441,40 @@ func main() {
 		port = "8080"
 	}
 
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	// Handle graceful shutdown: stop taking requests, let the running ones
+	// finish, then wait for the workers
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		slog.Info("Shutting down server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to shut down server", "error", err)
+		}
+	}()
+
 	slog.Info("Server starting", "port", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		slog.Error("Failed to start server", "error", err)
 		os.Exit(1)
 	}
+
+	// ListenAndServe returns as soon as Shutdown starts
+	<-shutdownDone
+	workers.Wait()
+	slog.Info("Server stopped")
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
 }
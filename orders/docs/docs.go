@@ -25,7 +25,12 @@ const docTemplate = `{
     "paths": {
         "/cart": {
             "post": {
-                "description": "Create a new shopping cart (order)",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new shopping cart (order), optionally linked to a customer. Carts expire when left untouched (expires_at, pushed forward by every change): their reserved stock is released and they move to the expired status.",
                 "consumes": [
                     "application/json"
                 ],
@@ -36,17 +41,45 @@ const docTemplate = `{
                     "cart"
                 ],
                 "summary": "Create a new cart",
+                "parameters": [
+                    {
+                        "description": "Customer who owns the cart; customers can only create their own",
+                        "name": "cart",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateCartRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/entity.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the cart, to send in If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Cart for another customer",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
@@ -54,6 +87,11 @@ const docTemplate = `{
         },
         "/cart/{id}": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Get a shopping cart by its ID",
                 "consumes": [
                     "application/json"
@@ -79,12 +117,18 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/entity.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the cart, to send in If-Match"
+                            }
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
@@ -92,7 +136,12 @@ const docTemplate = `{
         },
         "/cart/{id}/calculate": {
             "get": {
-                "description": "Calculate the total amount for payment",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Calculate the total amount for payment: the subtotal of the items, the line discounts taken off the items, the order discounts taken off what is left, the shipping fee less what free shipping waived, and the discounts that make them up",
                 "consumes": [
                     "application/json"
                 ],
@@ -123,21 +172,26 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/cart/{id}/items": {
+        "/cart/{id}/checkout": {
             "post": {
-                "description": "Add a product item to the shopping cart",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Turns a cart into a paid order. The cart is locked (its items can no longer change), every item is re-priced at the current catalog price and the order is charged through the payments service with the stock the cart already reserved. The same order moves to paid, or to canceled when the payment is declined; PIX and boleto orders stay pending and the response carries the charge to pay, as in /orders/with-payment. price_changes lists the items whose catalog price changed since they were added to the cart. Carts of registered customers use their email and name unless customer_email and customer_name are sent; anonymous carts require them. Send If-Match to check out only the version of the cart the customer reviewed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -147,7 +201,7 @@ const docTemplate = `{
                 "tags": [
                     "cart"
                 ],
-                "summary": "Add item to cart",
+                "summary": "Check out cart",
                 "parameters": [
                     {
                         "type": "string",
@@ -157,12 +211,18 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Item to add",
-                        "name": "item",
+                        "type": "string",
+                        "description": "ETag of the cart the customer reviewed",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Payment method, payment details and, for anonymous carts, the customer",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.AddItemRequest"
+                            "$ref": "#/definitions/handler.CheckoutCartRequest"
                         }
                     }
                 ],
@@ -170,27 +230,62 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/entity.Order"
+                            "$ref": "#/definitions/handler.CheckoutCartResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid request, empty cart, items that can no longer be sold (removed or inactive product) or invalid payment details",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Cart not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Cart already checked out (CART_LOCKED), changed concurrently or insufficient stock",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "The cart expired",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "If-Match does not match the current version",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment service unavailable, the checkout is retried in the background",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/cart/{id}/items/{itemId}": {
-            "put": {
-                "description": "Update the quantity of an item in the cart",
+        "/cart/{id}/coupons": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Enter a coupon code in the cart and price it again. The coupon is rejected, and the cart left unchanged, when it is unknown, outside its validity window, past its usage limits, gives the cart no discount or cannot be combined with the other discounts. Applying a coupon again changes nothing. Coupons are checked again at checkout.",
                 "consumes": [
                     "application/json"
                 ],
@@ -200,7 +295,7 @@ const docTemplate = `{
                 "tags": [
                     "cart"
                 ],
-                "summary": "Update item quantity",
+                "summary": "Apply a coupon to the cart",
                 "parameters": [
                     {
                         "type": "string",
@@ -211,18 +306,17 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Item ID",
-                        "name": "itemId",
-                        "in": "path",
-                        "required": true
+                        "description": "ETag of the cart the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
                     },
                     {
-                        "description": "New quantity",
-                        "name": "item",
+                        "description": "Coupon code",
+                        "name": "coupon",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.UpdateItemRequest"
+                            "$ref": "#/definitions/handler.ApplyCouponRequest"
                         }
                     }
                 ],
@@ -231,24 +325,55 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/entity.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the cart"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Unknown coupon (COUPON_NOT_FOUND) or outside its validity window (COUPON_NOT_VALID)",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Coupon past its usage limits, not applicable or not stackable, the cart was checked out (CART_LOCKED) or changed concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "The cart expired",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "If-Match does not match the current version",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/cart/{id}/coupons/{code}": {
             "delete": {
-                "description": "Remove an item from the shopping cart",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Take a coupon code out of the cart and price it again",
                 "consumes": [
                     "application/json"
                 ],
@@ -258,7 +383,7 @@ const docTemplate = `{
                 "tags": [
                     "cart"
                 ],
-                "summary": "Remove item from cart",
+                "summary": "Remove a coupon from the cart",
                 "parameters": [
                     {
                         "type": "string",
@@ -269,8 +394,14 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Item ID",
-                        "name": "itemId",
+                        "description": "ETag of the cart the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Coupon code",
+                        "name": "code",
                         "in": "path",
                         "required": true
                     }
@@ -280,26 +411,55 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/entity.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the cart"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Cart not found, or the coupon is not in the cart (COUPON_NOT_APPLIED)",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The cart was checked out (CART_LOCKED) or changed concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "The cart expired",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "If-Match does not match the current version",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/cart/{id}/status": {
-            "put": {
-                "description": "Update the status of an order (pending, paid, canceled, completed)",
+        "/cart/{id}/items": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add a product item to the shopping cart",
                 "consumes": [
                     "application/json"
                 ],
@@ -309,7 +469,7 @@ const docTemplate = `{
                 "tags": [
                     "cart"
                 ],
-                "summary": "Update order status",
+                "summary": "Add item to cart",
                 "parameters": [
                     {
                         "type": "string",
@@ -319,12 +479,18 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "New status",
-                        "name": "status",
+                        "type": "string",
+                        "description": "ETag of the cart the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Item to add",
+                        "name": "item",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.UpdateOrderStatusRequest"
+                            "$ref": "#/definitions/handler.AddItemRequest"
                         }
                     }
                 ],
@@ -333,58 +499,55 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/entity.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the cart"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
-                    }
-                }
-            }
-        },
-        "/orders": {
-            "get": {
-                "description": "Get a list of all orders",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "orders"
-                ],
-                "summary": "List all orders",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "409": {
+                        "description": "Insufficient stock, the cart was checked out (CART_LOCKED) or changed concurrently",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/entity.Order"
-                            }
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "410": {
+                        "description": "The cart expired",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "If-Match does not match the current version",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/orders/with-payment": {
-            "post": {
-                "description": "Creates a new order and processes payment via gRPC",
+        "/cart/{id}/items/{itemId}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update the quantity of an item in the cart",
                 "consumes": [
                     "application/json"
                 ],
@@ -392,45 +555,92 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "cart"
                 ],
-                "summary": "Create order with payment processing",
+                "summary": "Update item quantity",
                 "parameters": [
                     {
-                        "description": "Order and Payment Info",
-                        "name": "request",
+                        "type": "string",
+                        "description": "Cart ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cart the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Item ID",
+                        "name": "itemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New quantity",
+                        "name": "item",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CreateOrderWithPaymentRequest"
+                            "$ref": "#/definitions/handler.UpdateItemRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.CreateOrderWithPaymentResponse"
+                            "$ref": "#/definitions/entity.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the cart"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Insufficient stock, the cart was checked out (CART_LOCKED) or changed concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "The cart expired",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "If-Match does not match the current version",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/orders/{id}": {
-            "get": {
-                "description": "Get a single order by its ID",
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove an item from the shopping cart",
                 "consumes": [
                     "application/json"
                 ],
@@ -438,16 +648,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "cart"
                 ],
-                "summary": "Get order by ID",
+                "summary": "Remove item from cart",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Order ID",
+                        "description": "Cart ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cart the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Item ID",
+                        "name": "itemId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -455,59 +678,55 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/entity.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the cart"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The cart was checked out (CART_LOCKED) or changed concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "The cart expired",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "If-Match does not match the current version",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete an order by ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "orders"
-                ],
-                "summary": "Delete an order",
-                "parameters": [
+            }
+        },
+        "/cart/{id}/status": {
+            "put": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "BearerAuth": []
                     }
                 ],
-                "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/orders/{id}/cancel": {
-            "post": {
-                "description": "Cancels an order and its associated payment via gRPC",
+                "description": "Move an order to another status (pending, payment_failed, authorized, paid, shipped, delivered, completed, canceled, refunded, expired). Only the transitions of the order state machine are allowed. Requires the admin role.",
                 "consumes": [
                     "application/json"
                 ],
@@ -515,24 +734,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "cart"
                 ],
-                "summary": "Cancel order and payment",
+                "summary": "Update order status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Order ID",
+                        "description": "Cart ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Payment ID",
-                        "name": "request",
+                        "type": "string",
+                        "description": "ETag of the cart the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "New status",
+                        "name": "status",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CancelOrderRequest"
+                            "$ref": "#/definitions/handler.UpdateOrderStatusRequest"
                         }
                     }
                 ],
@@ -540,27 +765,50 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.MessageResponse"
+                            "$ref": "#/definitions/entity.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the cart"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transition not allowed from the current status, or the cart was changed concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "If-Match does not match the current version",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/products": {
+        "/customers": {
             "get": {
-                "description": "Get a list of all products",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a list of all customers, newest first. Requires the admin role.",
                 "consumes": [
                     "application/json"
                 ],
@@ -568,29 +816,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "customers"
                 ],
-                "summary": "List all products",
+                "summary": "List all customers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/entity.Product"
+                                "$ref": "#/definitions/entity.Customer"
                             }
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Create a new product with name, description, price and stock",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a customer with name, email, optional CPF and phone, and delivery addresses. Requires the admin or service role.",
                 "consumes": [
                     "application/json"
                 ],
@@ -598,17 +851,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "customers"
                 ],
-                "summary": "Create a new product",
+                "summary": "Create a new customer",
                 "parameters": [
                     {
-                        "description": "Product data",
-                        "name": "product",
+                        "description": "Customer data",
+                        "name": "customer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CreateProductRequest"
+                            "$ref": "#/definitions/handler.CustomerRequest"
                         }
                     }
                 ],
@@ -616,21 +869,32 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/entity.Product"
+                            "$ref": "#/definitions/entity.Customer"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or CPF already used by another customer",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/products/{id}": {
+        "/customers/{id}": {
             "get": {
-                "description": "Get a single product by its ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a single customer, with their addresses, by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -638,13 +902,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "customers"
                 ],
-                "summary": "Get product by ID",
+                "summary": "Get customer by ID",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Product ID",
+                        "description": "Customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -654,19 +918,24 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/entity.Product"
+                            "$ref": "#/definitions/entity.Customer"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             },
             "put": {
-                "description": "Update an existing product by ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the data of an existing customer; the addresses sent replace the saved ones",
                 "consumes": [
                     "application/json"
                 ],
@@ -674,24 +943,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "customers"
                 ],
-                "summary": "Update a product",
+                "summary": "Update a customer",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Product ID",
+                        "description": "Customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated product data",
-                        "name": "product",
+                        "description": "Updated customer data",
+                        "name": "customer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.UpdateProductRequest"
+                            "$ref": "#/definitions/handler.CustomerRequest"
                         }
                     }
                 ],
@@ -699,25 +968,36 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/entity.Product"
+                            "$ref": "#/definitions/entity.Customer"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or CPF already used by another customer",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Delete a product by ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a customer and their addresses. Customers with orders cannot be deleted, so the order history is kept. Requires the admin role.",
                 "consumes": [
                     "application/json"
                 ],
@@ -725,13 +1005,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "customers"
                 ],
-                "summary": "Delete a product",
+                "summary": "Delete a customer",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Product ID",
+                        "description": "Customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -741,134 +1021,1795 @@ const docTemplate = `{
                     "204": {
                         "description": "No Content"
                     },
+                    "409": {
+                        "description": "Customer has orders",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{id}/orders": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the order history of a customer, newest first, including carts still open",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "List customer orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.Order"
+                            }
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "entity.Item": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "string"
-                },
-                "order_id": {
-                    "type": "string"
-                },
-                "product": {
-                    "$ref": "#/definitions/entity.Product"
-                },
-                "product_id": {
-                    "type": "string"
-                },
-                "quantity": {
-                    "type": "integer"
-                },
-                "total": {
-                    "type": "number"
-                },
-                "unit_price": {
-                    "type": "number"
+        },
+        "/orders": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of orders, newest first by default, with optional filters. Pages are keyset based: pass the next_page_token of a response as page_token, keeping the other parameters, to get the next page; the Link header carries the same URL with rel=\"next\". Requires the admin or service role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "List orders",
+                "parameters": [
+                    {
+                        "enum": [
+                            "pending",
+                            "payment_failed",
+                            "authorized",
+                            "paid",
+                            "shipped",
+                            "delivered",
+                            "completed",
+                            "canceled",
+                            "refunded",
+                            "expired"
+                        ],
+                        "type": "string",
+                        "description": "Order status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-06-01T00:00:00Z",
+                        "description": "Created at or after (RFC 3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-07-01T00:00:00Z",
+                        "description": "Created before (RFC 3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "10.50",
+                        "description": "Minimum total, in major units",
+                        "name": "min_total",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "500.00",
+                        "description": "Maximum total, in major units",
+                        "name": "max_total",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "-created_at",
+                            "created_at"
+                        ],
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "Sort order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size (1-100)",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_page_token of the previous page",
+                        "name": "page_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.OrderListResponse"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "URL of the next page, rel=\\\"next\\"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter, sort, page size or page token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
                 }
             }
         },
-        "entity.Order": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "items": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/entity.Item"
+        "/orders/with-payment": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
                     }
-                },
-                "status": {
-                    "$ref": "#/definitions/entity.OrderStatus"
-                },
+                ],
+                "description": "Creates a new order and processes payment via gRPC. Card, PIX or boleto data go in payment_details and are validated by the payments service, which stores only safe fields (last four digits, brand, masked document); invalid details cancel the order and return 400. PIX and boleto orders stay pending and the response carries what the customer needs to pay them: the QR code (pix) or the digitable line and slip URL (boleto, which requires payment_details.boleto with the payer document). The order is paid once the payment is confirmed, or canceled when the charge expires; boletos expire after the due date plus a grace period. Send an Idempotency-Key header to retry safely: a repeated request from the same customer returns the original order and payment, with the Idempotent-Replayed header set, instead of charging again; keys are scoped to the customer, so another customer sending the same key gets an order of their own. Send customer_id to link the order to a registered customer; their email and name are used when customer_email and customer_name are omitted. Items are charged at the catalog price: unknown or inactive products are rejected, and an item price, when sent, must match the catalog price; every rejected item is listed in errors. With PRODUCT_QUICK_ADD enabled, admins may order unknown products, which are created with the price sent once no item is rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Create order with payment processing",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key for safely retrying the request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Order and Payment Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateOrderWithPaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateOrderWithPaymentResponse"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response comes from an earlier request with the same key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, unknown customer_id, items that cannot be sold (unknown or inactive product, price different from the catalog) or payment details (card, PIX key, document, due date)",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Order for another customer",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Insufficient stock",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key reused with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment service unavailable, retry with the same Idempotency-Key",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a single order by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Order"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete an order by ID. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Delete an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/boleto": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the printable boleto of an order as an HTML page, with the digitable line and the barcode. It can be printed or saved as PDF by the browser.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order boleto slip",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML boleto slip",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found or not paid with boleto",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment service unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancels an order by undoing its checkout: the payment is refunded (if approved) or canceled (if still processing) via gRPC, then the reserved stock is released and the order is marked canceled. When the payment cannot be undone right away the order keeps its status, 202 is returned and the cancellation is retried in the background.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Cancel order and payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional cancellation reason",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CancelOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.MessageResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Cancellation in progress",
+                        "schema": {
+                            "$ref": "#/definitions/handler.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Order can no longer be canceled (e.g. shipped)",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment service unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get every status transition of an order, oldest first, with who made it and why",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order status history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.StatusChange"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/refund": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Refunds all or part of an order payment via gRPC. Partial refunds may be repeated until the paid amount is reached; omit amount to refund everything left. Requires the admin or service role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Refund order payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment ID, optional amount and reason",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RefundOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RefundOrderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid amount; errors lists what the payments service refused",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Order or payment not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment cannot be refunded in its current status (code PAYMENT_NOT_REFUNDABLE)",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment service unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/status": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Moves a paid or authorized order through fulfillment: shipped, delivered or completed. Card payments are only authorized at checkout; shipping or completing an authorized order captures them first and marks it paid, and the status is kept when the capture fails. Cancellations and refunds have their own endpoints. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Update order fulfillment status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status (shipped, delivered or completed)",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateOrderStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transition not allowed from the current status",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Payment could not be captured",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment service unavailable, the status is kept",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products": {
+            "get": {
+                "description": "Get a page of products, newest first by default, with optional price and date filters. Pass the next_page_token of a response as page_token, keeping the other parameters, to get the next page; the Link header carries the same URL with rel=\"next\".",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "10.50",
+                        "description": "Minimum price, in major units",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "5000.00",
+                        "description": "Maximum price, in major units",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-06-01T00:00:00Z",
+                        "description": "Created at or after (RFC 3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-07-01T00:00:00Z",
+                        "description": "Created before (RFC 3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "-created_at",
+                            "created_at"
+                        ],
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "Sort order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size (1-100)",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_page_token of the previous page",
+                        "name": "page_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ProductListResponse"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "URL of the next page, rel=\\\"next\\"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter, sort, page size or page token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new product with name, description, category, price and stock. The category is optional and lets promotions target the product. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Create a new product",
+                "parameters": [
+                    {
+                        "description": "Product data",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}": {
+            "get": {
+                "description": "Get a single product by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Product"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing product by ID. Set active to false to take it off sale: carts and orders reject inactive products. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated product data",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a product by ID. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/promotions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a list of all promotions, active or not, newest first. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "promotions"
+                ],
+                "summary": "List all promotions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.Promotion"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a coupon (with a code) or an automatic promotion: percentage or fixed amount off the order or off the targeted products and categories, free shipping, buy X get Y and tiered quantity discounts. Discounts off the items always leave at least one cent to charge; free shipping waives the shipping fee of the cart. Promotions have an optional validity window and usage limits in total and per customer; non-stackable ones are only applied alone, the highest priority first. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "promotions"
+                ],
+                "summary": "Create a promotion",
+                "parameters": [
+                    {
+                        "description": "Promotion rules",
+                        "name": "promotion",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreatePromotionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Promotion"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Coupon code already used (DUPLICATE_COUPON_CODE)",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/promotions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a single promotion by ID. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "promotions"
+                ],
+                "summary": "Get promotion by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Promotion ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Promotion"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "End a promotion: it no longer applies to carts and its coupon is rejected. It is kept for the orders that used it. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "promotions"
+                ],
+                "summary": "Deactivate a promotion",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Promotion ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Promotion"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "entity.Address": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "complement": {
+                    "type": "string"
+                },
+                "district": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "label": {
+                    "description": "e.g. home, work",
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "state": {
+                    "description": "UF, e.g. SP",
+                    "type": "string"
+                },
+                "street": {
+                    "type": "string"
+                },
+                "zip_code": {
+                    "description": "CEP, digits only",
+                    "type": "string"
+                }
+            }
+        },
+        "entity.Customer": {
+            "type": "object",
+            "properties": {
+                "addresses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Address"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "document": {
+                    "description": "Document is the customer CPF, digits only; optional",
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.Discount": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "item_id": {
+                    "type": "string"
+                },
+                "level": {
+                    "$ref": "#/definitions/entity.DiscountLevel"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "promotion_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.DiscountLevel": {
+            "type": "string",
+            "enum": [
+                "line",
+                "order",
+                "shipping"
+            ],
+            "x-enum-varnames": [
+                "DiscountLine",
+                "DiscountOrder",
+                "DiscountShipping"
+            ]
+        },
+        "entity.DiscountTier": {
+            "type": "object",
+            "properties": {
+                "min_quantity": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "percentage": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "entity.FieldViolation": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.Item": {
+            "type": "object",
+            "properties": {
+                "discount": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "product": {
+                    "$ref": "#/definitions/entity.Product"
+                },
+                "product_id": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "Total is the unit price times the quantity; Discount is what the\npromotions of the order take off it",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.Money"
+                        }
+                    ]
+                },
+                "unit_price": {
+                    "$ref": "#/definitions/entity.Money"
+                }
+            }
+        },
+        "entity.Money": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "example": 350000
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "BRL"
+                }
+            }
+        },
+        "entity.Order": {
+            "type": "object",
+            "properties": {
+                "coupons": {
+                    "description": "Coupons are the coupon codes entered in the cart, and Discounts what\nthey and the automatic promotions took off",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "description": "CustomerID is the customer who placed the order; empty for guest\norders",
+                    "type": "string"
+                },
+                "discounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Discount"
+                    }
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when a cart expires unless it is changed again; placed\norders never expire",
+                    "type": "string"
+                },
+                "free_shipping": {
+                    "description": "FreeShipping is set when a free shipping promotion waived the fee",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Item"
+                    }
+                },
+                "line_discount": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "order_discount": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "placed_at": {
+                    "description": "PlacedAt is when the order was submitted for payment; carts have none\nuntil they are checked out, and placed orders are locked",
+                    "type": "string"
+                },
+                "shipping": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "shipping_discount": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "status": {
+                    "$ref": "#/definitions/entity.OrderStatus"
+                },
+                "subtotal": {
+                    "description": "Subtotal is the sum of the items; LineDiscount is taken off the\nitems and OrderDiscount off what is left. Shipping is the fee for\ndelivering the items, less ShippingDiscount, and with them makes the\nTotal charged.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.Money"
+                        }
+                    ]
+                },
                 "total": {
-                    "type": "number"
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.OrderStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "payment_failed",
+                "authorized",
+                "paid",
+                "shipped",
+                "delivered",
+                "completed",
+                "canceled",
+                "refunded",
+                "expired"
+            ],
+            "x-enum-varnames": [
+                "OrderStatusPending",
+                "OrderStatusPaymentFailed",
+                "OrderStatusAuthorized",
+                "OrderStatusPaid",
+                "OrderStatusShipped",
+                "OrderStatusDelivered",
+                "OrderStatusCompleted",
+                "OrderStatusCanceled",
+                "OrderStatusRefunded",
+                "OrderStatusExpired"
+            ]
+        },
+        "entity.Product": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Active products can be sold; inactive ones stay in the catalog for\nthe orders that already have them",
+                    "type": "boolean"
+                },
+                "category": {
+                    "description": "Category groups products for the promotions that target it",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.Promotion": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "amount": {
+                    "description": "Amount is taken off by fixed amount promotions",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.Money"
+                        }
+                    ]
+                },
+                "buy_quantity": {
+                    "type": "integer"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "get_quantity": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "percentage": {
+                    "description": "Percentage is a whole percent, for percentage promotions",
+                    "type": "integer"
+                },
+                "priority": {
+                    "description": "Priority decides which promotions win when they cannot be combined,\nhighest first",
+                    "type": "integer"
+                },
+                "product_ids": {
+                    "description": "ProductIDs and Categories restrict the promotion to the matching\nitems; an item matches either list",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "stackable": {
+                    "description": "Stackable promotions combine with other stackable ones; the others\nare only applied alone",
+                    "type": "boolean"
+                },
+                "starts_at": {
+                    "description": "StartsAt and EndsAt bound when the promotion is valid; either may be\nopen",
+                    "type": "string"
+                },
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.DiscountTier"
+                    }
+                },
+                "type": {
+                    "$ref": "#/definitions/entity.PromotionType"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "usage_limit": {
+                    "description": "UsageLimit caps the orders that redeem the promotion and\nUsageLimitPerCustomer the orders of each customer; zero is unlimited",
+                    "type": "integer"
+                },
+                "usage_limit_per_customer": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.PromotionType": {
+            "type": "string",
+            "enum": [
+                "percentage",
+                "fixed_amount",
+                "free_shipping",
+                "buy_x_get_y",
+                "tiered_quantity"
+            ],
+            "x-enum-varnames": [
+                "PromotionPercentage",
+                "PromotionFixedAmount",
+                "PromotionFreeShipping",
+                "PromotionBuyXGetY",
+                "PromotionTieredQuantity"
+            ]
+        },
+        "entity.StatusChange": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "changed_at": {
+                    "type": "string"
+                },
+                "from": {
+                    "$ref": "#/definitions/entity.OrderStatus"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "$ref": "#/definitions/entity.OrderStatus"
+                }
+            }
+        },
+        "handler.AddItemRequest": {
+            "type": "object",
+            "properties": {
+                "product_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "handler.AddressRequest": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string",
+                    "example": "São Paulo"
+                },
+                "complement": {
+                    "type": "string",
+                    "example": "Apto 42"
+                },
+                "district": {
+                    "type": "string",
+                    "example": "Bela Vista"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "casa"
+                },
+                "number": {
+                    "type": "string",
+                    "example": "1000"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "SP"
+                },
+                "street": {
+                    "type": "string",
+                    "example": "Avenida Paulista"
+                },
+                "zip_code": {
+                    "type": "string",
+                    "example": "01310-100"
+                }
+            }
+        },
+        "handler.ApplyCouponRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "WELCOME10"
+                }
+            }
+        },
+        "handler.BoletoChargeResponse": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string",
+                    "example": "00199112900000150500000001234567000000000117"
+                },
+                "digitable_line": {
+                    "description": "linha digitável",
+                    "type": "string",
+                    "example": "00190000090123456700400000001172911290000015050"
+                },
+                "due_date": {
+                    "type": "string",
+                    "example": "2025-07-01"
+                },
+                "expires_at": {
+                    "description": "vencimento mais a carência; depois disso o pedido é cancelado",
+                    "type": "string"
+                },
+                "number": {
+                    "description": "nosso número",
+                    "type": "string",
+                    "example": "12345670000000001"
+                },
+                "slip_url": {
+                    "type": "string",
+                    "example": "/api/v1/orders/uuid-do-pedido/boleto"
+                }
+            }
+        },
+        "handler.BoletoDetailsRequest": {
+            "type": "object",
+            "properties": {
+                "document": {
+                    "description": "CPF ou CNPJ do pagador",
+                    "type": "string",
+                    "example": "529.982.247-25"
+                },
+                "due_date": {
+                    "description": "YYYY-MM-DD; opcional",
+                    "type": "string",
+                    "example": "2030-01-31"
+                }
+            }
+        },
+        "handler.CancelOrderRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
                 }
             }
         },
-        "entity.OrderStatus": {
-            "type": "string",
-            "enum": [
-                "pending",
-                "paid",
-                "canceled",
-                "completed"
-            ],
-            "x-enum-varnames": [
-                "OrderStatusPending",
-                "OrderStatusPaid",
-                "OrderStatusCanceled",
-                "OrderStatusCompleted"
-            ]
-        },
-        "entity.Product": {
+        "handler.CardDetailsRequest": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "cvv": {
+                    "type": "string",
+                    "example": "123"
                 },
-                "description": {
-                    "type": "string"
+                "expiry_date": {
+                    "description": "MM/YY",
+                    "type": "string",
+                    "example": "12/30"
                 },
-                "id": {
+                "holder_name": {
+                    "type": "string",
+                    "example": "Maria Silva"
+                },
+                "number": {
+                    "type": "string",
+                    "example": "4111111111111111"
+                }
+            }
+        },
+        "handler.CheckoutCartRequest": {
+            "type": "object",
+            "properties": {
+                "customer_email": {
+                    "description": "Obrigatórios em carrinhos anônimos; nos de clientes cadastrados,\nsubstituem o email e o nome do cadastro",
                     "type": "string"
                 },
-                "name": {
+                "customer_name": {
                     "type": "string"
                 },
-                "price": {
-                    "type": "number"
+                "payment_details": {
+                    "$ref": "#/definitions/handler.PaymentDetailsRequest"
                 },
-                "stock": {
+                "payment_method": {
+                    "description": "1=CREDIT_CARD, 2=DEBIT_CARD, 3=PIX, 4=BOLETO, 5=PAYPAL",
                     "type": "integer"
-                },
-                "updated_at": {
-                    "type": "string"
                 }
             }
         },
-        "handler.AddItemRequest": {
+        "handler.CheckoutCartResponse": {
             "type": "object",
             "properties": {
-                "product_id": {
-                    "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                "boleto": {
+                    "$ref": "#/definitions/handler.BoletoChargeResponse"
                 },
-                "quantity": {
-                    "type": "integer",
-                    "example": 2
+                "order_id": {
+                    "type": "string"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "pix": {
+                    "description": "Pix e Boleto vêm preenchidos enquanto o pedido aguarda o pagamento",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handler.PixChargeResponse"
+                        }
+                    ]
+                },
+                "price_changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.PriceChangeResponse"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/entity.Money"
                 }
             }
         },
-        "handler.CancelOrderRequest": {
+        "handler.CreateCartRequest": {
             "type": "object",
             "properties": {
-                "payment_id": {
-                    "type": "string"
+                "customer_id": {
+                    "type": "string",
+                    "example": "3f2b8c1e-6d4a-4f7e-9a2b-1c5d8e9f0a7b"
                 }
             }
         },
@@ -878,6 +2819,11 @@ const docTemplate = `{
                 "customer_email": {
                     "type": "string"
                 },
+                "customer_id": {
+                    "description": "Cliente cadastrado dono do pedido; sem ele, customer_email e\ncustomer_name são obrigatórios",
+                    "type": "string",
+                    "example": "3f2b8c1e-6d4a-4f7e-9a2b-1c5d8e9f0a7b"
+                },
                 "customer_name": {
                     "type": "string"
                 },
@@ -887,6 +2833,14 @@ const docTemplate = `{
                         "$ref": "#/definitions/handler.OrderItemRequest"
                     }
                 },
+                "payment_details": {
+                    "description": "Dados do cartão, chave PIX ou boleto; repassados ao payments service,\nque guarda apenas os dados seguros (final do cartão, bandeira,\ndocumento mascarado)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handler.PaymentDetailsRequest"
+                        }
+                    ]
+                },
                 "payment_method": {
                     "description": "1=CREDIT_CARD, 2=DEBIT_CARD, 3=PIX, 4=BOLETO, 5=PAYPAL",
                     "type": "integer"
@@ -896,23 +2850,38 @@ const docTemplate = `{
         "handler.CreateOrderWithPaymentResponse": {
             "type": "object",
             "properties": {
+                "boleto": {
+                    "$ref": "#/definitions/handler.BoletoChargeResponse"
+                },
                 "order_id": {
                     "type": "string"
                 },
                 "payment_id": {
                     "type": "string"
                 },
+                "pix": {
+                    "description": "Pix e Boleto vêm preenchidos enquanto o pedido aguarda o pagamento",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handler.PixChargeResponse"
+                        }
+                    ]
+                },
                 "status": {
                     "type": "string"
                 },
                 "total": {
-                    "type": "number"
+                    "$ref": "#/definitions/entity.Money"
                 }
             }
         },
         "handler.CreateProductRequest": {
             "type": "object",
             "properties": {
+                "category": {
+                    "type": "string",
+                    "example": "notebooks"
+                },
                 "description": {
                     "type": "string",
                     "example": "Laptop com 16GB RAM e SSD 512GB"
@@ -922,8 +2891,7 @@ const docTemplate = `{
                     "example": "Laptop Dell Inspiron"
                 },
                 "price": {
-                    "type": "number",
-                    "example": 3500
+                    "$ref": "#/definitions/entity.Money"
                 },
                 "stock": {
                     "type": "integer",
@@ -931,11 +2899,117 @@ const docTemplate = `{
                 }
             }
         },
-        "handler.ErrorResponse": {
+        "handler.CreatePromotionRequest": {
             "type": "object",
             "properties": {
-                "error": {
+                "amount": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "buy_quantity": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "notebooks"
+                    ]
+                },
+                "code": {
+                    "type": "string",
+                    "example": "WELCOME10"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "get_quantity": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Boas-vindas"
+                },
+                "percentage": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "priority": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "product_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "stackable": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "starts_at": {
                     "type": "string"
+                },
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.DiscountTier"
+                    }
+                },
+                "type": {
+                    "enum": [
+                        "percentage",
+                        "fixed_amount",
+                        "free_shipping",
+                        "buy_x_get_y",
+                        "tiered_quantity"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.PromotionType"
+                        }
+                    ],
+                    "example": "percentage"
+                },
+                "usage_limit": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "usage_limit_per_customer": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handler.CustomerRequest": {
+            "type": "object",
+            "properties": {
+                "addresses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.AddressRequest"
+                    }
+                },
+                "document": {
+                    "description": "CPF",
+                    "type": "string",
+                    "example": "529.982.247-25"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "maria@example.com"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Maria Silva"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+55 11 98765-4321"
                 }
             }
         },
@@ -951,7 +3025,12 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "price": {
-                    "type": "number"
+                    "description": "Preço que o cliente viu; opcional. Se enviado, precisa ser o preço\natual do catálogo, que é sempre o preço cobrado",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.Money"
+                        }
+                    ]
                 },
                 "product_id": {
                     "type": "string"
@@ -961,6 +3040,137 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.OrderListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Order"
+                    }
+                },
+                "next_page_token": {
+                    "type": "string",
+                    "example": "eyJjIjoiMjAyNS0wNi0wMVQxMjowMDowMFoiLCJpIjoiNTUwZTg0MDAifQ"
+                }
+            }
+        },
+        "handler.PaymentDetailsRequest": {
+            "type": "object",
+            "properties": {
+                "boleto": {
+                    "$ref": "#/definitions/handler.BoletoDetailsRequest"
+                },
+                "card": {
+                    "$ref": "#/definitions/handler.CardDetailsRequest"
+                },
+                "pix": {
+                    "$ref": "#/definitions/handler.PixDetailsRequest"
+                }
+            }
+        },
+        "handler.PixChargeResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "qr_code": {
+                    "description": "BR Code \"copia e cola\"",
+                    "type": "string",
+                    "example": "00020101021226..."
+                },
+                "qr_code_image": {
+                    "description": "pode ser usado direto em \u003cimg src\u003e",
+                    "type": "string",
+                    "example": "data:image/png;base64,iVBORw0..."
+                }
+            }
+        },
+        "handler.PixDetailsRequest": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "description": "CPF, CNPJ, email, telefone (+55...) ou chave aleatória",
+                    "type": "string",
+                    "example": "maria@example.com"
+                }
+            }
+        },
+        "handler.PriceChangeResponse": {
+            "type": "object",
+            "properties": {
+                "item_id": {
+                    "type": "string"
+                },
+                "new_price": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "old_price": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "product_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ProductListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Product"
+                    }
+                },
+                "next_page_token": {
+                    "type": "string",
+                    "example": "eyJjIjoiMjAyNS0wNi0wMVQxMjowMDowMFoiLCJpIjoiNTUwZTg0MDAifQ"
+                }
+            }
+        },
+        "handler.RefundOrderRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "omitido reembolsa todo o saldo restante",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.Money"
+                        }
+                    ]
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.RefundOrderResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "payment_status": {
+                    "type": "string"
+                },
+                "refund_id": {
+                    "type": "string"
+                },
+                "refunded_amount": {
+                    "$ref": "#/definitions/entity.Money"
+                },
+                "remaining_amount": {
+                    "$ref": "#/definitions/entity.Money"
+                }
+            }
+        },
         "handler.UpdateItemRequest": {
             "type": "object",
             "properties": {
@@ -973,6 +3183,9 @@ const docTemplate = `{
         "handler.UpdateOrderStatusRequest": {
             "type": "object",
             "properties": {
+                "reason": {
+                    "type": "string"
+                },
                 "status": {
                     "allOf": [
                         {
@@ -986,6 +3199,15 @@ const docTemplate = `{
         "handler.UpdateProductRequest": {
             "type": "object",
             "properties": {
+                "active": {
+                    "description": "Active takes the product off sale (false) or back on sale (true);\nomitted keeps it as it is",
+                    "type": "boolean",
+                    "example": true
+                },
+                "category": {
+                    "type": "string",
+                    "example": "notebooks"
+                },
                 "description": {
                     "type": "string",
                     "example": "Laptop com 32GB RAM e SSD 1TB"
@@ -995,14 +3217,124 @@ const docTemplate = `{
                     "example": "Laptop Dell Inspiron Pro"
                 },
                 "price": {
-                    "type": "number",
-                    "example": 5000
+                    "$ref": "#/definitions/entity.Money"
                 },
                 "stock": {
                     "type": "integer",
                     "example": 5
                 }
             }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "enum": [
+                        "INVALID_BODY",
+                        "VALIDATION_FAILED",
+                        "INVALID_IDEMPOTENCY_KEY",
+                        "INVALID_AMOUNT",
+                        "INVALID_CURRENCY",
+                        "INVALID_PAYMENT_DETAILS",
+                        "INVALID_PAYMENT_REQUEST",
+                        "EMPTY_ORDER",
+                        "PAYMENT_NOT_FOR_ORDER",
+                        "INVALID_PAGE_TOKEN",
+                        "CUSTOMER_NOT_FOUND",
+                        "PRODUCT_NOT_FOUND",
+                        "PRODUCT_INACTIVE",
+                        "INVALID_ORDER_ITEMS",
+                        "MISSING_TOKEN",
+                        "INVALID_TOKEN",
+                        "TOKEN_EXPIRED",
+                        "FORBIDDEN",
+                        "NOT_FOUND",
+                        "ORDER_NOT_FOUND",
+                        "CART_NOT_FOUND",
+                        "CART_EXPIRED",
+                        "ITEM_NOT_FOUND",
+                        "BOLETO_NOT_FOUND",
+                        "PAYMENT_NOT_FOUND",
+                        "METHOD_NOT_ALLOWED",
+                        "INSUFFICIENT_STOCK",
+                        "INVALID_STATUS_TRANSITION",
+                        "DUPLICATE_CUSTOMER",
+                        "CUSTOMER_HAS_ORDERS",
+                        "PRECONDITION_FAILED",
+                        "PAYMENT_OPERATION_NOT_ALLOWED",
+                        "PAYMENT_CONFLICT",
+                        "IDEMPOTENCY_KEY_REUSED",
+                        "PAYMENT_SERVICE_UNAVAILABLE",
+                        "INTERNAL",
+                        "INVALID_ARGUMENT",
+                        "PAYMENT_METHOD_NOT_SUPPORTED",
+                        "REFUND_EXCEEDS_AMOUNT",
+                        "CAPTURE_EXCEEDS_AUTHORIZATION",
+                        "PAYMENT_NOT_CANCELABLE",
+                        "PAYMENT_NOT_REFUNDABLE",
+                        "AUTHORIZATION_NOT_SUPPORTED",
+                        "PAYMENT_NOT_AUTHORIZED",
+                        "AUTHORIZATION_EXPIRED",
+                        "CONFIRMATION_NOT_SUPPORTED",
+                        "PAYMENT_NOT_AWAITING_CONFIRMATION",
+                        "PAYMENT_EXPIRED",
+                        "BOLETO_NOT_ISSUED",
+                        "CONCURRENT_UPDATE",
+                        "CART_LOCKED",
+                        "COUPON_NOT_FOUND",
+                        "COUPON_NOT_VALID",
+                        "COUPON_NOT_APPLIED",
+                        "COUPON_USAGE_LIMIT_REACHED",
+                        "COUPON_NOT_APPLICABLE",
+                        "COUPON_NOT_STACKABLE",
+                        "DUPLICATE_COUPON_CODE",
+                        "PROMOTION_NOT_FOUND",
+                        "GATEWAY_UNAVAILABLE",
+                        "DATABASE_UNAVAILABLE"
+                    ],
+                    "example": "ORDER_NOT_FOUND"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "Order not found"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.FieldViolation"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/v1/orders/550e8400-e29b-41d4-a716-446655440000"
+                },
+                "request_id": {
+                    "description": "RequestID matches the X-Request-Id of the request, to find it in the\nlogs",
+                    "type": "string",
+                    "example": "orders-api/Ab12Cd34Ef-000042"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "urn:orders:problem:order-not-found"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "JWT (HS256 or RS256) as \"Bearer \u003ctoken\u003e\". Roles: admin, customer, service.",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -1014,7 +3346,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/api/v1",
 	Schemes:          []string{"http", "https"},
 	Title:            "Orders API",
-	Description:      "API robusta de gerenciamento de pedidos com carrinho de compras",
+	Description:      "API robusta de gerenciamento de pedidos com carrinho de compras.\nErros seguem a RFC 7807: corpo application/problem+json com type, title, status, detail, instance, request_id, errors (campos inválidos) e um code estável, listado no schema problem.Problem.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,180 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SagaStatus string
+
+const (
+	// SagaStatusRunning means the forward steps are still being executed
+	SagaStatusRunning SagaStatus = "running"
+	// SagaStatusCompensating means a step failed for good (or the order was
+	// canceled) and the effects of the checkout are being undone
+	SagaStatusCompensating SagaStatus = "compensating"
+	SagaStatusCompleted    SagaStatus = "completed"
+	SagaStatusCompensated  SagaStatus = "compensated"
+)
+
+type SagaStep string
+
+const (
+	SagaStepReserveStock     SagaStep = "reserve_stock"
+	SagaStepAuthorizePayment SagaStep = "authorize_payment"
+	SagaStepConfirmOrder     SagaStep = "confirm_order"
+
+	SagaStepUndoPayment  SagaStep = "undo_payment"
+	SagaStepReleaseStock SagaStep = "release_stock"
+	SagaStepCancelOrder  SagaStep = "cancel_order"
+)
+
+var (
+	// checkoutSteps take an order from placed to paid
+	checkoutSteps = []SagaStep{SagaStepReserveStock, SagaStepAuthorizePayment, SagaStepConfirmOrder}
+	// compensationSteps undo a checkout. They always run in full: each one is
+	// a no-op when there is nothing to undo, so it does not matter how far
+	// the checkout got. The order is canceled last, once nothing is charged
+	// or held for it anymore.
+	compensationSteps = []SagaStep{SagaStepUndoPayment, SagaStepReleaseStock, SagaStepCancelOrder}
+)
+
+// Payment methods whose amount is only authorized at checkout and captured
+// when the order is completed (same numbering as the payments service)
+const (
+	PaymentMethodCreditCard int32 = 1
+	PaymentMethodDebitCard  int32 = 2
+)
+
+var (
+	ErrSagaFinished          = errors.New("checkout saga is already finished")
+	ErrDuplicateCheckoutSaga = errors.New("order already has a checkout saga")
+	ErrSagaConflict          = errors.New("checkout saga was changed concurrently")
+	// ErrPaymentDeclined fails a checkout for good: the order is canceled
+	ErrPaymentDeclined = errors.New("payment declined")
+	// ErrPaymentExpired fails a checkout whose payment, such as a PIX
+	// charge, was not paid in time (or was canceled before it was paid)
+	ErrPaymentExpired = errors.New("payment expired before it was paid")
+	// ErrCancellationPending means an order could not be canceled right away;
+	// its saga keeps compensating in the background
+	ErrCancellationPending = errors.New("order cancellation is in progress")
+)
+
+// CheckoutSaga is the persisted progress of an order checkout. It is saved
+// after every step so that a checkout interrupted by a crash can be resumed
+// from where it stopped; every step must therefore be safe to run again.
+type CheckoutSaga struct {
+	ID            string     `json:"id"`
+	OrderID       string     `json:"order_id"`
+	Status        SagaStatus `json:"status"`
+	Step          SagaStep   `json:"step"`
+	PaymentID     string     `json:"payment_id,omitempty"`
+	PaymentMethod int32      `json:"payment_method"`
+	CustomerEmail string     `json:"customer_email"`
+	CustomerName  string     `json:"customer_name"`
+	// FailureReason explains why the saga started compensating
+	FailureReason string `json:"failure_reason,omitempty"`
+	// LastError and Attempts describe failures of the current step, which
+	// is retried until it succeeds or the saga gives up on it
+	LastError string    `json:"last_error,omitempty"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version guards against two processes driving the same saga: an update
+	// based on a stale version fails with ErrSagaConflict
+	Version int `json:"-"`
+}
+
+func NewCheckoutSaga(orderID string, paymentMethod int32, customerEmail, customerName string) *CheckoutSaga {
+	now := time.Now()
+	return &CheckoutSaga{
+		ID:            uuid.New().String(),
+		OrderID:       orderID,
+		Status:        SagaStatusRunning,
+		Step:          checkoutSteps[0],
+		PaymentMethod: paymentMethod,
+		CustomerEmail: customerEmail,
+		CustomerName:  customerName,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Advance marks the current step as done and moves to the next one, finishing
+// the saga after the last step of its current phase.
+func (s *CheckoutSaga) Advance() error {
+	if s.IsFinished() {
+		return ErrSagaFinished
+	}
+
+	steps := checkoutSteps
+	if s.Status == SagaStatusCompensating {
+		steps = compensationSteps
+	}
+
+	next := indexOf(steps, s.Step) + 1
+	if next < len(steps) {
+		s.Step = steps[next]
+	} else if s.Status == SagaStatusCompensating {
+		s.Status = SagaStatusCompensated
+	} else {
+		s.Status = SagaStatusCompleted
+	}
+
+	s.LastError = ""
+	s.Attempts = 0
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+// Compensate switches the saga to undoing the checkout. A completed saga can
+// be compensated too, which is how a paid order gets canceled. Compensating
+// twice is a no-op, so concurrent cancellations do not restart the work.
+func (s *CheckoutSaga) Compensate(reason string) error {
+	switch s.Status {
+	case SagaStatusCompensating:
+		return nil
+	case SagaStatusCompensated:
+		return ErrSagaFinished
+	}
+
+	s.Status = SagaStatusCompensating
+	s.Step = compensationSteps[0]
+	s.FailureReason = reason
+	s.LastError = ""
+	s.Attempts = 0
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+// RecordFailure keeps the error of a failed attempt at the current step.
+func (s *CheckoutSaga) RecordFailure(err error) {
+	s.LastError = err.Error()
+	s.Attempts++
+	s.UpdatedAt = time.Now()
+}
+
+// CapturesLater tells whether the checkout only authorizes the payment,
+// leaving the capture to when the order is completed
+func (s *CheckoutSaga) CapturesLater() bool {
+	return s.PaymentMethod == PaymentMethodCreditCard || s.PaymentMethod == PaymentMethodDebitCard
+}
+
+func (s *CheckoutSaga) IsCompensating() bool {
+	return s.Status == SagaStatusCompensating
+}
+
+func (s *CheckoutSaga) IsFinished() bool {
+	return s.Status == SagaStatusCompleted || s.Status == SagaStatusCompensated
+}
+
+func indexOf(steps []SagaStep, step SagaStep) int {
+	for i, s := range steps {
+		if s == step {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,138 @@
+package entity
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCustomerName  = errors.New("customer name is required")
+	ErrInvalidCustomerEmail = errors.New("customer email must be a valid email address")
+	ErrInvalidCustomerCPF   = errors.New("customer document must be a valid CPF")
+	ErrInvalidCustomerPhone = errors.New("customer phone must have 10 to 13 digits")
+	ErrInvalidAddress       = errors.New("address needs street, number, city, state (UF) and an 8 digit CEP")
+
+	ErrDuplicateCustomer = errors.New("a customer with this email or document already exists")
+	ErrCustomerHasOrders = errors.New("customer has orders and cannot be deleted")
+)
+
+type Customer struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// Document is the customer CPF, digits only; optional
+	Document  string    `json:"document,omitempty"`
+	Phone     string    `json:"phone,omitempty"`
+	Addresses []Address `json:"addresses"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Address struct {
+	ID         string `json:"id"`
+	Label      string `json:"label,omitempty"` // e.g. home, work
+	Street     string `json:"street"`
+	Number     string `json:"number"`
+	Complement string `json:"complement,omitempty"`
+	District   string `json:"district,omitempty"`
+	City       string `json:"city"`
+	State      string `json:"state"`    // UF, e.g. SP
+	ZipCode    string `json:"zip_code"` // CEP, digits only
+}
+
+func NewCustomer(name, email, document, phone string, addresses []Address) (*Customer, error) {
+	customer := &Customer{
+		ID:        uuid.New().String(),
+		CreatedAt: time.Now(),
+	}
+
+	if err := customer.Update(name, email, document, phone, addresses); err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+// Update replaces the customer data, normalizing it the same way
+// NewCustomer does. Addresses without an ID get a new one.
+func (c *Customer) Update(name, email, document, phone string, addresses []Address) error {
+	c.Name = strings.TrimSpace(name)
+	c.Email = strings.ToLower(strings.TrimSpace(email))
+	c.Document = onlyDigits(document)
+	c.Phone = strings.TrimSpace(phone)
+
+	c.Addresses = make([]Address, len(addresses))
+	for i, address := range addresses {
+		if address.ID == "" {
+			address.ID = uuid.New().String()
+		}
+		address.State = strings.ToUpper(strings.TrimSpace(address.State))
+		address.ZipCode = onlyDigits(address.ZipCode)
+		c.Addresses[i] = address
+	}
+	c.UpdatedAt = time.Now()
+
+	return c.Validate()
+}
+
+func (c *Customer) Validate() error {
+	if c.Name == "" {
+		return ErrInvalidCustomerName
+	}
+	if address, err := mail.ParseAddress(c.Email); err != nil || address.Address != c.Email {
+		return ErrInvalidCustomerEmail
+	}
+	if c.Document != "" && !cpfValid(c.Document) {
+		return ErrInvalidCustomerCPF
+	}
+	if digits := len(onlyDigits(c.Phone)); c.Phone != "" && (digits < 10 || digits > 13) {
+		return ErrInvalidCustomerPhone
+	}
+	for _, address := range c.Addresses {
+		if err := address.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Address) Validate() error {
+	if strings.TrimSpace(a.Street) == "" || strings.TrimSpace(a.Number) == "" || strings.TrimSpace(a.City) == "" ||
+		len(a.State) != 2 || len(a.ZipCode) != 8 {
+		return ErrInvalidAddress
+	}
+	return nil
+}
+
+// cpfValid checks the two mod 11 check digits of an 11 digit CPF
+func cpfValid(cpf string) bool {
+	if len(cpf) != 11 || strings.Count(cpf, cpf[:1]) == len(cpf) {
+		return false
+	}
+	return cpfCheckDigit(cpf[:9]) == int(cpf[9]-'0') && cpfCheckDigit(cpf[:10]) == int(cpf[10]-'0')
+}
+
+func cpfCheckDigit(digits string) int {
+	sum := 0
+	for i := range digits {
+		sum += int(digits[i]-'0') * (len(digits) + 1 - i)
+	}
+	if rest := sum % 11; rest >= 2 {
+		return 11 - rest
+	}
+	return 0
+}
+
+func onlyDigits(value string) string {
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
+}
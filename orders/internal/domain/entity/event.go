@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventOrderCreated  EventType = "OrderCreated"
+	EventOrderPaid     EventType = "OrderPaid"
+	EventOrderCanceled EventType = "OrderCanceled"
+	// EventCartAbandoned is recorded when a cart with items expires
+	EventCartAbandoned EventType = "CartAbandoned"
+)
+
+const AggregateOrder = "order"
+
+// Event is a domain event stored in the transactional outbox and later
+// published to the message broker. Consumers must tolerate duplicates:
+// delivery is at least once, and ID identifies the event across retries.
+type Event struct {
+	ID            string          `json:"id"`
+	Type          EventType       `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	PublishedAt   *time.Time      `json:"-"`
+	Attempts      int             `json:"-"`
+	LastError     string          `json:"-"`
+}
+
+func NewEvent(eventType EventType, aggregateType, aggregateID string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:            uuid.New().String(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+		OccurredAt:    time.Now(),
+	}, nil
+}
+
+// OrderEventPayload is the body of every order event
+type OrderEventPayload struct {
+	OrderID        string           `json:"order_id"`
+	Status         OrderStatus      `json:"status"`
+	PreviousStatus OrderStatus      `json:"previous_status,omitempty"`
+	Total          Money            `json:"total"`
+	Items          []OrderEventItem `json:"items"`
+}
+
+type OrderEventItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice Money  `json:"unit_price"`
+}
+
+// CartAbandonedPayload is the body of CartAbandoned: what was left in an
+// expired cart and who left it, so marketing can follow up. The customer
+// fields are empty for anonymous carts.
+type CartAbandonedPayload struct {
+	OrderID        string           `json:"order_id"`
+	CustomerID     string           `json:"customer_id,omitempty"`
+	CustomerEmail  string           `json:"customer_email,omitempty"`
+	CustomerName   string           `json:"customer_name,omitempty"`
+	Total          Money            `json:"total"`
+	Items          []OrderEventItem `json:"items"`
+	LastActivityAt time.Time        `json:"last_activity_at"`
+	ExpiredAt      time.Time        `json:"expired_at"`
+}
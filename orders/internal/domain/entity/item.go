@@ -17,8 +17,11 @@ type Item struct {
 	ProductID string   `json:"product_id"`
 	Product   *Product `json:"product,omitempty"`
 	Quantity  int      `json:"quantity"`
-	UnitPrice float64  `json:"unit_price"`
-	Total     float64  `json:"total"`
+	UnitPrice Money    `json:"unit_price"`
+	// Total is the unit price times the quantity; Discount is what the
+	// promotions of the order take off it
+	Total    Money `json:"total"`
+	Discount Money `json:"discount"`
 }
 
 func NewItem(orderID, productID string, product *Product, quantity int) (*Item, error) {
@@ -36,21 +39,35 @@ func NewItem(orderID, productID string, product *Product, quantity int) (*Item,
 		Product:   product,
 		Quantity:  quantity,
 		UnitPrice: product.Price,
+		Discount:  Zero(product.Price.Currency),
 	}
 
-	item.CalculateTotal()
+	if err := item.CalculateTotal(); err != nil {
+		return nil, err
+	}
 	return item, nil
 }
 
-func (i *Item) CalculateTotal() {
-	i.Total = i.UnitPrice * float64(i.Quantity)
+// CalculateTotal sets the total of the item, failing with ErrMoneyOverflow
+// when the quantity is too large for its unit price
+func (i *Item) CalculateTotal() error {
+	total, err := i.UnitPrice.Multiply(i.Quantity)
+	if err != nil {
+		return err
+	}
+	i.Total = total
+	return nil
 }
 
 func (i *Item) UpdateQuantity(quantity int) error {
 	if quantity <= 0 {
 		return ErrInvalidQuantity
 	}
+	previous := i.Quantity
 	i.Quantity = quantity
-	i.CalculateTotal()
+	if err := i.CalculateTotal(); err != nil {
+		i.Quantity = previous
+		return err
+	}
 	return nil
 }
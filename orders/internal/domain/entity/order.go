@@ -2,68 +2,144 @@ package entity
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-type OrderStatus string
-
-const (
-	OrderStatusPending   OrderStatus = "pending"
-	OrderStatusPaid      OrderStatus = "paid"
-	OrderStatusCanceled  OrderStatus = "canceled"
-	OrderStatusCompleted OrderStatus = "completed"
-)
-
 var (
 	ErrEmptyOrder         = errors.New("order must have at least one item")
 	ErrItemNotFound       = errors.New("item not found in order")
-	ErrInvalidOrderStatus = errors.New("invalid order status")
+	ErrPaymentNotForOrder = errors.New("payment does not belong to this order")
+	ErrBoletoNotFound     = errors.New("order has no boleto")
+
+	ErrIdempotencyKeyReused    = errors.New("idempotency key was already used for a different order")
+	ErrDuplicateIdempotencyKey = errors.New("an order with this idempotency key already exists")
+
+	// ErrOrderConflict means the order was saved by someone else between
+	// being read and being saved; reading it again and retrying is safe
+	ErrOrderConflict = errors.New("order was changed concurrently")
+	// ErrOrderVersionMismatch means the client changed an order based on a
+	// version that is no longer the current one
+	ErrOrderVersionMismatch = errors.New("order was changed since the given version")
+	// ErrCartLocked means the order was placed (a cart that was checked out)
+	// and its items can no longer change
+	ErrCartLocked = errors.New("cart was checked out and can no longer change")
+	// ErrCartExpired means the cart was left untouched past its expiry; its
+	// stock was released and a new cart is needed
+	ErrCartExpired = errors.New("cart has expired")
 )
 
 type Order struct {
-	ID        string      `json:"id"`
-	Status    OrderStatus `json:"status"`
-	Items     []Item      `json:"items"`
-	Total     float64     `json:"total"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
+	ID     string      `json:"id"`
+	Status OrderStatus `json:"status"`
+	// CustomerID is the customer who placed the order; empty for guest
+	// orders
+	CustomerID string `json:"customer_id,omitempty"`
+	Items      []Item `json:"items"`
+	// Subtotal is the sum of the items; LineDiscount is taken off the
+	// items and OrderDiscount off what is left. Shipping is the fee for
+	// delivering the items, less ShippingDiscount, and with them makes the
+	// Total charged.
+	Subtotal         Money `json:"subtotal"`
+	LineDiscount     Money `json:"line_discount"`
+	OrderDiscount    Money `json:"order_discount"`
+	Shipping         Money `json:"shipping"`
+	ShippingDiscount Money `json:"shipping_discount"`
+	Total            Money `json:"total"`
+	// FreeShipping is set when a free shipping promotion waived the fee
+	FreeShipping bool `json:"free_shipping,omitempty"`
+	// Coupons are the coupon codes entered in the cart, and Discounts what
+	// they and the automatic promotions took off
+	Coupons   []string   `json:"coupons"`
+	Discounts []Discount `json:"discounts"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	// PlacedAt is when the order was submitted for payment; carts have none
+	// until they are checked out, and placed orders are locked
+	PlacedAt *time.Time `json:"placed_at,omitempty"`
+	// ExpiresAt is when a cart expires unless it is changed again; placed
+	// orders never expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// IdempotencyKey identifies the client request that created the order
+	IdempotencyKey string `json:"-"`
+	// Version is bumped on every update; an update based on a stale version
+	// fails with ErrOrderConflict. Clients see it as the ETag of the cart.
+	Version int `json:"-"`
+
+	// events recorded since the order was loaded, saved to the outbox by
+	// the repository in the same transaction as the order
+	events []Event
+	// statusChanges made since the order was loaded, saved to the status
+	// history by the repository in the same transaction as the order
+	statusChanges []StatusChange
+	// redemptions of the promotions of the order when it was placed, checked
+	// against their usage limits and saved by the repository in the same
+	// transaction as the order
+	redemptions []Redemption
 }
 
 func NewOrder() *Order {
 	return &Order{
-		ID:        uuid.New().String(),
-		Status:    OrderStatusPending,
-		Items:     []Item{},
-		Total:     0,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:               uuid.New().String(),
+		Status:           OrderStatusPending,
+		Items:            []Item{},
+		Subtotal:         Zero(DefaultCurrency),
+		LineDiscount:     Zero(DefaultCurrency),
+		OrderDiscount:    Zero(DefaultCurrency),
+		Shipping:         Zero(DefaultCurrency),
+		ShippingDiscount: Zero(DefaultCurrency),
+		Total:            Zero(DefaultCurrency),
+		Coupons:          []string{},
+		Discounts:        []Discount{},
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
 	}
 }
 
-func (o *Order) AddItem(item *Item) {
+func (o *Order) AddItem(item *Item) error {
+	if o.IsPlaced() {
+		return ErrCartLocked
+	}
+
+	// An order is charged in a single currency: the first item sets it
+	if len(o.Items) == 0 {
+		o.Total = Zero(item.UnitPrice.Currency)
+	} else if item.UnitPrice.Currency != o.Total.Currency {
+		return fmt.Errorf("%w: order is in %s, item is in %s", ErrCurrencyMismatch, o.Total.Currency, item.UnitPrice.Currency)
+	}
+
 	// Check if item already exists, if so, update quantity
 	for i, existingItem := range o.Items {
 		if existingItem.ProductID == item.ProductID {
-			o.Items[i].Quantity += item.Quantity
-			o.Items[i].CalculateTotal()
+			if err := o.Items[i].UpdateQuantity(existingItem.Quantity + item.Quantity); err != nil {
+				return err
+			}
+			o.clearDiscounts()
 			o.CalculateTotal()
 			o.UpdatedAt = time.Now()
-			return
+			return nil
 		}
 	}
 
 	// Add new item
 	o.Items = append(o.Items, *item)
+	o.clearDiscounts()
 	o.CalculateTotal()
 	o.UpdatedAt = time.Now()
+	return nil
 }
 
 func (o *Order) RemoveItem(itemID string) error {
+	if o.IsPlaced() {
+		return ErrCartLocked
+	}
+
 	for i, item := range o.Items {
 		if item.ID == itemID {
 			o.Items = append(o.Items[:i], o.Items[i+1:]...)
+			o.clearDiscounts()
 			o.CalculateTotal()
 			o.UpdatedAt = time.Now()
 			return nil
@@ -73,11 +149,42 @@ func (o *Order) RemoveItem(itemID string) error {
 }
 
 func (o *Order) UpdateItemQuantity(itemID string, quantity int) error {
+	if o.IsPlaced() {
+		return ErrCartLocked
+	}
+
 	for i, item := range o.Items {
 		if item.ID == itemID {
 			if err := o.Items[i].UpdateQuantity(quantity); err != nil {
 				return err
 			}
+			o.clearDiscounts()
+			o.CalculateTotal()
+			o.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrItemNotFound
+}
+
+// UpdateItemPrice charges an item at a new unit price, such as the current
+// catalog price of a cart item when the cart is checked out
+func (o *Order) UpdateItemPrice(itemID string, price Money) error {
+	if o.IsPlaced() {
+		return ErrCartLocked
+	}
+	if price.Currency != o.Total.Currency {
+		return fmt.Errorf("%w: order is in %s, price is in %s", ErrCurrencyMismatch, o.Total.Currency, price.Currency)
+	}
+
+	for i, item := range o.Items {
+		if item.ID == itemID {
+			o.Items[i].UnitPrice = price
+			if err := o.Items[i].CalculateTotal(); err != nil {
+				o.Items[i].UnitPrice = item.UnitPrice
+				return err
+			}
+			o.clearDiscounts()
 			o.CalculateTotal()
 			o.UpdatedAt = time.Now()
 			return nil
@@ -87,11 +194,128 @@ func (o *Order) UpdateItemQuantity(itemID string, quantity int) error {
 }
 
 func (o *Order) CalculateTotal() {
-	total := 0.0
+	currency := o.Total.Currency
+	if len(o.Items) > 0 {
+		currency = o.Items[0].Total.Currency
+	}
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	// AddItem keeps every item in the order currency, so summing the
+	// minor units is exact
+	var subtotal, lineDiscount, orderDiscount, shippingDiscount int64
 	for _, item := range o.Items {
-		total += item.Total
+		subtotal += item.Total.Amount
+		lineDiscount += item.Discount.Amount
 	}
-	o.Total = total
+	for _, discount := range o.Discounts {
+		switch discount.Level {
+		case DiscountOrder:
+			orderDiscount += discount.Amount.Amount
+		case DiscountShipping:
+			shippingDiscount += discount.Amount.Amount
+		}
+	}
+	// Nothing is shipped without items
+	shipping := o.Shipping.Amount
+	if len(o.Items) == 0 {
+		shipping = 0
+	}
+	shippingDiscount = min(shippingDiscount, shipping)
+
+	o.Subtotal = NewMoney(subtotal, currency)
+	o.LineDiscount = NewMoney(lineDiscount, currency)
+	o.OrderDiscount = NewMoney(orderDiscount, currency)
+	o.Shipping = NewMoney(shipping, currency)
+	o.ShippingDiscount = NewMoney(shippingDiscount, currency)
+	o.Total = NewMoney(subtotal-lineDiscount-orderDiscount+shipping-shippingDiscount, currency)
+}
+
+// SetShipping sets the fee for delivering the items of a cart, in minor
+// units of the order currency. Free shipping promotions are applied to it
+// by ApplyPromotions.
+func (o *Order) SetShipping(fee int64) error {
+	if o.IsPlaced() {
+		return ErrCartLocked
+	}
+	if fee < 0 {
+		return ErrInvalidMoney
+	}
+	o.Shipping = NewMoney(fee, o.Total.Currency)
+	o.CalculateTotal()
+	return nil
+}
+
+// AddCoupon enters a coupon code in the cart; entering it again changes
+// nothing. The cart is priced with it by ApplyPromotions.
+func (o *Order) AddCoupon(code string) error {
+	if o.IsPlaced() {
+		return ErrCartLocked
+	}
+	for _, coupon := range o.Coupons {
+		if coupon == code {
+			return nil
+		}
+	}
+	o.Coupons = append(o.Coupons, code)
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+// RemoveCoupon takes a coupon code out of the cart
+func (o *Order) RemoveCoupon(code string) error {
+	if o.IsPlaced() {
+		return ErrCartLocked
+	}
+	for i, coupon := range o.Coupons {
+		if coupon == code {
+			o.Coupons = append(o.Coupons[:i], o.Coupons[i+1:]...)
+			o.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrCouponNotApplied
+}
+
+// ApplyPromotions replaces the discounts of the order with the ones of
+// promotions, which are the automatic promotions in effect and the coupons
+// of the cart. It returns the promotions that gave no discount, by id, with
+// the reason: ErrCouponNotApplicable or ErrCouponNotStackable.
+func (o *Order) ApplyPromotions(promotions []Promotion) (map[string]error, error) {
+	if o.IsPlaced() {
+		return nil, ErrCartLocked
+	}
+
+	o.CalculateTotal()
+	discounts, skipped := computeDiscounts(o.Items, o.Shipping, promotions)
+
+	o.clearDiscounts()
+	for _, discount := range discounts {
+		switch discount.Level {
+		case DiscountLine:
+			for i := range o.Items {
+				if o.Items[i].ID == discount.ItemID {
+					o.Items[i].Discount.Amount += discount.Amount.Amount
+				}
+			}
+		case DiscountShipping:
+			o.FreeShipping = true
+		}
+		o.Discounts = append(o.Discounts, discount)
+	}
+	o.CalculateTotal()
+	return skipped, nil
+}
+
+// clearDiscounts drops the discounts of the order, which no longer hold
+// once its items change, until promotions are applied again
+func (o *Order) clearDiscounts() {
+	for i := range o.Items {
+		o.Items[i].Discount = Zero(o.Items[i].UnitPrice.Currency)
+	}
+	o.Discounts = []Discount{}
+	o.FreeShipping = false
 }
 
 func (o *Order) PrepareForPayment() error {
@@ -102,19 +326,191 @@ func (o *Order) PrepareForPayment() error {
 	return nil
 }
 
-func (o *Order) UpdateStatus(status OrderStatus) error {
-	validStatuses := map[OrderStatus]bool{
-		OrderStatusPending:   true,
-		OrderStatusPaid:      true,
-		OrderStatusCanceled:  true,
-		OrderStatusCompleted: true,
+// Place submits a new order for payment, recording OrderCreated and the
+// first entry of its status history. Its items are locked from then on.
+func (o *Order) Place() error {
+	if o.IsPlaced() {
+		return ErrCartLocked
+	}
+	if err := o.PrepareForPayment(); err != nil {
+		return err
+	}
+	placedAt := time.Now()
+	o.PlacedAt = &placedAt
+	o.ExpiresAt = nil
+	o.recordRedemptions(placedAt)
+	o.statusChanges = append(o.statusChanges, newStatusChange(o.ID, "", o.Status, ActorSystem, "order placed"))
+	return o.recordEvent(EventOrderCreated, "")
+}
+
+// IsPlaced tells whether the order was submitted for payment
+func (o *Order) IsPlaced() bool {
+	return o.PlacedAt != nil
+}
+
+// ExtendExpiry keeps a cart alive for ttl from now, on every change made to
+// it. Placed orders do not expire.
+func (o *Order) ExtendExpiry(ttl time.Duration) {
+	if o.IsPlaced() || ttl <= 0 {
+		return
+	}
+	expiresAt := time.Now().Add(ttl)
+	o.ExpiresAt = &expiresAt
+}
+
+// IsExpired tells whether the order is a cart that expired, or that was left
+// untouched past ExpiresAt and is waiting to be expired
+func (o *Order) IsExpired(now time.Time) bool {
+	if o.Status == OrderStatusExpired {
+		return true
+	}
+	return !o.IsPlaced() && o.ExpiresAt != nil && !o.ExpiresAt.After(now)
+}
+
+// Expire ends a cart left untouched past its expiry. A cart with items
+// records CartAbandoned with its contents and customer, who is nil for
+// anonymous carts.
+func (o *Order) Expire(customer *Customer) error {
+	if o.IsPlaced() {
+		return ErrCartLocked
+	}
+	if o.Status == OrderStatusExpired {
+		return nil
+	}
+
+	lastActivityAt := o.UpdatedAt
+	if err := o.TransitionTo(OrderStatusExpired, ActorSystem, "cart expired"); err != nil {
+		return err
+	}
+	if len(o.Items) == 0 {
+		return nil
+	}
+
+	payload := CartAbandonedPayload{
+		OrderID:        o.ID,
+		CustomerID:     o.CustomerID,
+		Total:          o.Total,
+		Items:          make([]OrderEventItem, 0, len(o.Items)),
+		LastActivityAt: lastActivityAt,
+		ExpiredAt:      o.UpdatedAt,
+	}
+	if customer != nil {
+		payload.CustomerEmail = customer.Email
+		payload.CustomerName = customer.Name
+	}
+	for _, item := range o.Items {
+		payload.Items = append(payload.Items, OrderEventItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		})
 	}
 
-	if !validStatuses[status] {
+	event, err := NewEvent(EventCartAbandoned, AggregateOrder, o.ID, payload)
+	if err != nil {
+		return err
+	}
+	o.events = append(o.events, event)
+	return nil
+}
+
+// UpdateStatus moves the order to status on behalf of the system, without a
+// reason. See TransitionTo.
+func (o *Order) UpdateStatus(status OrderStatus) error {
+	return o.TransitionTo(status, ActorSystem, "")
+}
+
+// TransitionTo moves the order to status if the state machine allows it,
+// recording who did it and why in the status history. Moving to the current
+// status is a no-op, so retried operations do not fail or duplicate history.
+func (o *Order) TransitionTo(status OrderStatus, actor, reason string) error {
+	if !status.IsValid() {
 		return ErrInvalidOrderStatus
 	}
 
+	previous := o.Status
+	if previous == status {
+		return nil
+	}
+	if !previous.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidStatusTransition, previous, status)
+	}
+
 	o.Status = status
 	o.UpdatedAt = time.Now()
+	o.statusChanges = append(o.statusChanges, newStatusChange(o.ID, previous, status, actor, reason))
+
+	switch status {
+	case OrderStatusPaid:
+		return o.recordEvent(EventOrderPaid, previous)
+	case OrderStatusCanceled:
+		return o.recordEvent(EventOrderCanceled, previous)
+	}
+	return nil
+}
+
+// StatusChanges returns the status changes made and not yet saved
+func (o *Order) StatusChanges() []StatusChange {
+	return o.statusChanges
+}
+
+// Events returns the events recorded and not yet saved
+func (o *Order) Events() []Event {
+	return o.events
+}
+
+// Redemptions returns the promotion redemptions recorded and not yet saved
+func (o *Order) Redemptions() []Redemption {
+	return o.redemptions
+}
+
+// ClearEvents is called by the repository once the events, status changes
+// and redemptions are saved
+func (o *Order) ClearEvents() {
+	o.events = nil
+	o.statusChanges = nil
+	o.redemptions = nil
+}
+
+// recordRedemptions records one redemption for each promotion that
+// discounted the order
+func (o *Order) recordRedemptions(redeemedAt time.Time) {
+	redeemed := make(map[string]bool)
+	for _, discount := range o.Discounts {
+		if redeemed[discount.PromotionID] {
+			continue
+		}
+		redeemed[discount.PromotionID] = true
+		o.redemptions = append(o.redemptions, Redemption{
+			ID:          uuid.New().String(),
+			PromotionID: discount.PromotionID,
+			OrderID:     o.ID,
+			CustomerID:  o.CustomerID,
+			RedeemedAt:  redeemedAt,
+		})
+	}
+}
+
+func (o *Order) recordEvent(eventType EventType, previous OrderStatus) error {
+	payload := OrderEventPayload{
+		OrderID:        o.ID,
+		Status:         o.Status,
+		PreviousStatus: previous,
+		Total:          o.Total,
+		Items:          make([]OrderEventItem, 0, len(o.Items)),
+	}
+	for _, item := range o.Items {
+		payload.Items = append(payload.Items, OrderEventItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		})
+	}
+
+	event, err := NewEvent(eventType, AggregateOrder, o.ID, payload)
+	if err != nil {
+		return err
+	}
+	o.events = append(o.events, event)
 	return nil
 }
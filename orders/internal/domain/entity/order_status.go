@@ -0,0 +1,100 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusPending OrderStatus = "pending"
+	// OrderStatusPaymentFailed marks orders whose payment was declined, or
+	// failed so many times that the checkout saga gave up on it
+	OrderStatusPaymentFailed OrderStatus = "payment_failed"
+	// OrderStatusAuthorized marks orders whose card payment was only
+	// authorized at checkout; they are marked paid once it is captured
+	OrderStatusAuthorized OrderStatus = "authorized"
+	OrderStatusPaid       OrderStatus = "paid"
+	OrderStatusShipped    OrderStatus = "shipped"
+	OrderStatusDelivered  OrderStatus = "delivered"
+	OrderStatusCompleted  OrderStatus = "completed"
+	OrderStatusCanceled   OrderStatus = "canceled"
+	OrderStatusRefunded   OrderStatus = "refunded"
+	// OrderStatusExpired marks carts left untouched until they expired;
+	// they were never placed
+	OrderStatusExpired OrderStatus = "expired"
+)
+
+var (
+	ErrInvalidOrderStatus      = errors.New("invalid order status")
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+)
+
+// orderTransitions is the order state machine: the statuses an order may move
+// to from each status. Canceled, refunded and expired orders are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:       {OrderStatusAuthorized, OrderStatusPaid, OrderStatusPaymentFailed, OrderStatusCanceled, OrderStatusExpired},
+	OrderStatusPaymentFailed: {OrderStatusAuthorized, OrderStatusPaid, OrderStatusCanceled},
+	OrderStatusAuthorized:    {OrderStatusPaid, OrderStatusCanceled},
+	OrderStatusPaid:          {OrderStatusShipped, OrderStatusCompleted, OrderStatusCanceled, OrderStatusRefunded},
+	OrderStatusShipped:       {OrderStatusDelivered, OrderStatusRefunded},
+	OrderStatusDelivered:     {OrderStatusCompleted, OrderStatusRefunded},
+	OrderStatusCompleted:     {OrderStatusRefunded},
+	OrderStatusCanceled:      {},
+	OrderStatusRefunded:      {},
+	OrderStatusExpired:       {},
+}
+
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s OrderStatus) IsFinal() bool {
+	return s.IsValid() && len(orderTransitions[s]) == 0
+}
+
+// Actors recorded in the status history when no user is known
+const (
+	ActorSystem   = "system"
+	ActorCheckout = "checkout"
+	ActorAPI      = "api"
+	// ActorPayments is a change reported by the payments service
+	ActorPayments = "payments"
+)
+
+// StatusChange is an entry of the order status history. From is empty for
+// the entry recorded when the order is placed.
+type StatusChange struct {
+	ID        string      `json:"id"`
+	OrderID   string      `json:"order_id"`
+	From      OrderStatus `json:"from,omitempty"`
+	To        OrderStatus `json:"to"`
+	Actor     string      `json:"actor"`
+	Reason    string      `json:"reason,omitempty"`
+	ChangedAt time.Time   `json:"changed_at"`
+}
+
+func newStatusChange(orderID string, from, to OrderStatus, actor, reason string) StatusChange {
+	return StatusChange{
+		ID:        uuid.New().String(),
+		OrderID:   orderID,
+		From:      from,
+		To:        to,
+		Actor:     actor,
+		Reason:    reason,
+		ChangedAt: time.Now(),
+	}
+}
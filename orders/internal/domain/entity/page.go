@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidPageToken means a page token was not issued by this API or was
+// issued for another sort order.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// PageCursor is the last row of a page. Listings are sorted by created_at
+// and then by id, so the next page starts right after it.
+type PageCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// pageToken is the JSON inside the opaque page token
+type pageToken struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+	Ascending bool      `json:"a,omitempty"`
+}
+
+// EncodePageToken builds the opaque token of the page after cursor.
+func EncodePageToken(cursor PageCursor, ascending bool) string {
+	data, _ := json.Marshal(pageToken{CreatedAt: cursor.CreatedAt.UTC(), ID: cursor.ID, Ascending: ascending})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParsePageToken returns the cursor a token points after. An empty token is
+// the first page and returns nil.
+func ParsePageToken(token string, ascending bool) (*PageCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	var decoded pageToken
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID == "" || decoded.Ascending != ascending {
+		return nil, ErrInvalidPageToken
+	}
+	return &PageCursor{CreatedAt: decoded.CreatedAt, ID: decoded.ID}, nil
+}
@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Payment methods whose details are a PIX key or a boleto payer (same
+// numbering as the payments service)
+const (
+	PaymentMethodPix    int32 = 3
+	PaymentMethodBoleto int32 = 4
+)
+
+// ErrInvalidPaymentDetails means the card, PIX key or boleto data sent with
+// an order were rejected, either here or by the payments service
+var ErrInvalidPaymentDetails = errors.New("invalid payment details")
+
+var (
+	errTooManyPaymentDetails  = fmt.Errorf("%w: send only one of card, pix or boleto", ErrInvalidPaymentDetails)
+	errPaymentDetailsMismatch = fmt.Errorf("%w: details do not match the payment method", ErrInvalidPaymentDetails)
+)
+
+// PaymentDetails are the card, PIX key or boleto data sent with an order. At
+// most one of them is set. They are only forwarded to the payments service,
+// which validates them and keeps their safe fields: orders never stores
+// them. A checkout resumed later asks again without them, which only returns
+// the payment created the first time; when there is none, the payments
+// service rejects the request and the checkout is undone.
+type PaymentDetails struct {
+	Card   *CardDetails
+	Pix    *PixDetails
+	Boleto *BoletoDetails
+}
+
+type CardDetails struct {
+	Number     string
+	HolderName string
+	ExpiryDate string // MM/YY
+	CVV        string
+}
+
+type PixDetails struct {
+	Key string // CPF, CNPJ, email, phone (+55...) or random key
+}
+
+type BoletoDetails struct {
+	Document string     // CPF or CNPJ of the payer
+	DueDate  *time.Time // optional
+}
+
+// Validate checks that the details are the kind the payment method needs.
+// The data itself (Luhn, expiry, documents) is checked by the payments
+// service.
+func (d *PaymentDetails) Validate(paymentMethod int32) error {
+	sent := 0
+	for _, set := range []bool{d.Card != nil, d.Pix != nil, d.Boleto != nil} {
+		if set {
+			sent++
+		}
+	}
+	if sent > 1 {
+		return errTooManyPaymentDetails
+	}
+
+	switch {
+	case d.Card != nil && paymentMethod != PaymentMethodCreditCard && paymentMethod != PaymentMethodDebitCard,
+		d.Pix != nil && paymentMethod != PaymentMethodPix,
+		d.Boleto != nil && paymentMethod != PaymentMethodBoleto:
+		return errPaymentDetailsMismatch
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "errors"
+
+// Errors of the payments service. The gRPC client translates the status of
+// a failed call into a PaymentError of one of these kinds, so callers never
+// look at gRPC codes.
+var (
+	ErrPaymentNotFound            = errors.New("payment not found")
+	ErrInvalidPaymentRequest      = errors.New("invalid payment request")
+	ErrPaymentOperationNotAllowed = errors.New("payment does not allow this operation in its current status")
+	ErrPaymentConflict            = errors.New("payment was changed by another request")
+	ErrPaymentServiceUnavailable  = errors.New("payment service unavailable")
+)
+
+// FieldViolation is a request field the payments service refused
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// PaymentError is a failed call to the payments service. It matches its Kind
+// with errors.Is; Reason is the stable code sent by the service, such as
+// PAYMENT_NOT_REFUNDABLE, and Fields lists the invalid fields of the request.
+type PaymentError struct {
+	Kind    error
+	Reason  string
+	Message string
+	Fields  []FieldViolation
+}
+
+func (e *PaymentError) Error() string {
+	if e.Message == "" {
+		return e.Kind.Error()
+	}
+	return e.Message
+}
+
+func (e *PaymentError) Unwrap() error {
+	return e.Kind
+}
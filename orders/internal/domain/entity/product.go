@@ -2,6 +2,7 @@ package entity
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,25 +11,32 @@ import (
 var (
 	ErrInvalidProductName  = errors.New("product name is required")
 	ErrInvalidProductPrice = errors.New("product price must be greater than zero")
+	ErrProductInactive     = errors.New("product is not available for sale")
 )
 
 type Product struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	Stock       int       `json:"stock"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Category groups products for the promotions that target it
+	Category string `json:"category,omitempty"`
+	Price    Money  `json:"price"`
+	Stock    int    `json:"stock"`
+	// Active products can be sold; inactive ones stay in the catalog for
+	// the orders that already have them
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-func NewProduct(name, description string, price float64, stock int) (*Product, error) {
+func NewProduct(name, description string, price Money, stock int) (*Product, error) {
 	product := &Product{
 		ID:          uuid.New().String(),
 		Name:        name,
 		Description: description,
 		Price:       price,
 		Stock:       stock,
+		Active:      true,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -40,19 +48,24 @@ func NewProduct(name, description string, price float64, stock int) (*Product, e
 	return product, nil
 }
 
+// NormalizeCategory makes categories case insensitive
+func NormalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
 func (p *Product) Validate() error {
 	if p.Name == "" {
 		return ErrInvalidProductName
 	}
-	if p.Price <= 0 {
+	if !p.Price.IsPositive() {
 		return ErrInvalidProductPrice
 	}
-	return nil
+	return p.Price.Validate()
 }
 
 func (p *Product) UpdateStock(quantity int) error {
 	if p.Stock+quantity < 0 {
-		return errors.New("insufficient stock")
+		return ErrInsufficientStock
 	}
 	p.Stock += quantity
 	p.UpdatedAt = time.Now()
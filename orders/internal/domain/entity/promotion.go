@@ -0,0 +1,446 @@
+package entity
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPromotionName  = errors.New("promotion name is required")
+	ErrInvalidPromotionType  = errors.New("promotion type must be percentage, fixed_amount, free_shipping, buy_x_get_y or tiered_quantity")
+	ErrInvalidCouponCode     = errors.New("coupon code must have 3 to 32 letters, digits, - or _")
+	ErrInvalidPercentage     = errors.New("percentage must be between 1 and 100")
+	ErrInvalidDiscountAmount = errors.New("discount amount must be greater than zero")
+	ErrInvalidBuyXGetY       = errors.New("buy and get quantities must be greater than zero")
+	ErrInvalidDiscountTiers  = errors.New("tiers need increasing minimum quantities and percentages between 1 and 100")
+	ErrInvalidValidityWindow = errors.New("promotion must end after it starts")
+	ErrInvalidUsageLimit     = errors.New("usage limits cannot be negative")
+
+	ErrDuplicateCouponCode = errors.New("a promotion with this coupon code already exists")
+
+	// ErrCouponNotValid means the coupon is inactive or outside its
+	// validity window
+	ErrCouponNotValid = errors.New("coupon is not valid at this time")
+	// ErrCouponUsageLimitReached means the coupon was used as many times as
+	// allowed, in total or by the customer
+	ErrCouponUsageLimitReached = errors.New("coupon usage limit was reached")
+	// ErrCouponNotApplicable means no item of the cart qualifies for the
+	// coupon
+	ErrCouponNotApplicable = errors.New("coupon does not apply to the cart")
+	// ErrCouponNotStackable means the coupon or a discount already in the
+	// cart cannot be combined with other promotions
+	ErrCouponNotStackable = errors.New("coupon cannot be combined with the other discounts of the cart")
+	ErrCouponNotApplied   = errors.New("coupon is not applied to the cart")
+)
+
+type PromotionType string
+
+const (
+	// PromotionPercentage takes a percentage off the matching items, or off
+	// the order when it targets no product or category
+	PromotionPercentage PromotionType = "percentage"
+	// PromotionFixedAmount takes Amount off each matching unit, or once off
+	// the order when it targets no product or category
+	PromotionFixedAmount PromotionType = "fixed_amount"
+	// PromotionFreeShipping waives the shipping fee of the order
+	PromotionFreeShipping PromotionType = "free_shipping"
+	// PromotionBuyXGetY gives GetQuantity units free for every BuyQuantity
+	// units bought of the same item
+	PromotionBuyXGetY PromotionType = "buy_x_get_y"
+	// PromotionTieredQuantity takes the percentage of the highest tier
+	// reached by the quantity of each item
+	PromotionTieredQuantity PromotionType = "tiered_quantity"
+)
+
+// DiscountLevel tells what a discount was taken from
+type DiscountLevel string
+
+const (
+	DiscountLine     DiscountLevel = "line"
+	DiscountOrder    DiscountLevel = "order"
+	DiscountShipping DiscountLevel = "shipping"
+)
+
+type DiscountTier struct {
+	MinQuantity int `json:"min_quantity" example:"5"`
+	Percentage  int `json:"percentage" example:"10"`
+}
+
+// Promotion is a discount rule. Promotions with a Code are coupons, applied
+// to the carts where customers enter the code; the others are automatic and
+// apply to every cart they match.
+type Promotion struct {
+	ID   string        `json:"id"`
+	Name string        `json:"name"`
+	Code string        `json:"code,omitempty"`
+	Type PromotionType `json:"type"`
+	// Percentage is a whole percent, for percentage promotions
+	Percentage int `json:"percentage,omitempty"`
+	// Amount is taken off by fixed amount promotions
+	Amount      *Money         `json:"amount,omitempty"`
+	BuyQuantity int            `json:"buy_quantity,omitempty"`
+	GetQuantity int            `json:"get_quantity,omitempty"`
+	Tiers       []DiscountTier `json:"tiers,omitempty"`
+	// ProductIDs and Categories restrict the promotion to the matching
+	// items; an item matches either list
+	ProductIDs []string `json:"product_ids,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	// StartsAt and EndsAt bound when the promotion is valid; either may be
+	// open
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	// UsageLimit caps the orders that redeem the promotion and
+	// UsageLimitPerCustomer the orders of each customer; zero is unlimited
+	UsageLimit            int `json:"usage_limit,omitempty"`
+	UsageLimitPerCustomer int `json:"usage_limit_per_customer,omitempty"`
+	// Stackable promotions combine with other stackable ones; the others
+	// are only applied alone
+	Stackable bool `json:"stackable"`
+	// Priority decides which promotions win when they cannot be combined,
+	// highest first
+	Priority  int       `json:"priority"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Discount is what a promotion took off an order: off one item (line), off
+// the order subtotal after the line discounts, or off the shipping fee
+type Discount struct {
+	PromotionID string        `json:"promotion_id"`
+	Code        string        `json:"code,omitempty"`
+	Name        string        `json:"name"`
+	Level       DiscountLevel `json:"level"`
+	ItemID      string        `json:"item_id,omitempty"`
+	Amount      Money         `json:"amount"`
+}
+
+// Redemption is a promotion used by a placed order, counted against its
+// usage limits
+type Redemption struct {
+	ID          string
+	PromotionID string
+	OrderID     string
+	CustomerID  string
+	RedeemedAt  time.Time
+}
+
+// NewPromotion creates an active promotion from the rules in promotion,
+// normalizing the code and categories
+func NewPromotion(promotion Promotion) (*Promotion, error) {
+	promotion.ID = uuid.New().String()
+	promotion.Code = NormalizeCouponCode(promotion.Code)
+	for i, category := range promotion.Categories {
+		promotion.Categories[i] = NormalizeCategory(category)
+	}
+	promotion.Active = true
+	promotion.CreatedAt = time.Now()
+	promotion.UpdatedAt = promotion.CreatedAt
+
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// NormalizeCouponCode makes coupon codes case insensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (p *Promotion) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return ErrInvalidPromotionName
+	}
+	if p.Code != "" && !validCouponCode(p.Code) {
+		return ErrInvalidCouponCode
+	}
+
+	switch p.Type {
+	case PromotionPercentage:
+		if !validPercentage(p.Percentage) {
+			return ErrInvalidPercentage
+		}
+	case PromotionFixedAmount:
+		if p.Amount == nil || !p.Amount.IsPositive() {
+			return ErrInvalidDiscountAmount
+		}
+		if err := p.Amount.Validate(); err != nil {
+			return err
+		}
+	case PromotionFreeShipping:
+	case PromotionBuyXGetY:
+		if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+			return ErrInvalidBuyXGetY
+		}
+	case PromotionTieredQuantity:
+		if len(p.Tiers) == 0 {
+			return ErrInvalidDiscountTiers
+		}
+		for i, tier := range p.Tiers {
+			if tier.MinQuantity <= 0 || !validPercentage(tier.Percentage) || i > 0 && tier.MinQuantity <= p.Tiers[i-1].MinQuantity {
+				return ErrInvalidDiscountTiers
+			}
+		}
+	default:
+		return ErrInvalidPromotionType
+	}
+
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return ErrInvalidValidityWindow
+	}
+	if p.UsageLimit < 0 || p.UsageLimitPerCustomer < 0 {
+		return ErrInvalidUsageLimit
+	}
+	return nil
+}
+
+// IsCoupon tells whether the promotion needs its code to be entered
+func (p *Promotion) IsCoupon() bool {
+	return p.Code != ""
+}
+
+// IsValidAt tells whether the promotion is active and inside its validity
+// window at the given time
+func (p *Promotion) IsValidAt(now time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	return p.EndsAt == nil || now.Before(*p.EndsAt)
+}
+
+// Deactivate ends the promotion; orders that redeemed it keep their
+// discounts
+func (p *Promotion) Deactivate() {
+	p.Active = false
+	p.UpdatedAt = time.Now()
+}
+
+// level is where the discounts of the promotion are taken from
+func (p *Promotion) level() DiscountLevel {
+	switch {
+	case p.Type == PromotionFreeShipping:
+		return DiscountShipping
+	case (p.Type == PromotionPercentage || p.Type == PromotionFixedAmount) && !p.targeted():
+		return DiscountOrder
+	}
+	return DiscountLine
+}
+
+func (p *Promotion) targeted() bool {
+	return len(p.ProductIDs) > 0 || len(p.Categories) > 0
+}
+
+// matches tells whether an item qualifies for the promotion
+func (p *Promotion) matches(item Item) bool {
+	if !p.targeted() {
+		return true
+	}
+	for _, id := range p.ProductIDs {
+		if id == item.ProductID {
+			return true
+		}
+	}
+	if item.Product == nil || item.Product.Category == "" {
+		return false
+	}
+	for _, category := range p.Categories {
+		if category == item.Product.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// lineDiscount is how much the promotion takes off an item, given what is
+// left of the item after the discounts already applied
+func (p *Promotion) lineDiscount(item Item, remaining int64) int64 {
+	var amount int64
+	switch p.Type {
+	case PromotionPercentage:
+		amount = remaining * int64(p.Percentage) / 100
+	case PromotionFixedAmount:
+		if p.Amount.Currency == item.UnitPrice.Currency {
+			amount = p.Amount.Amount * int64(item.Quantity)
+		}
+	case PromotionBuyXGetY:
+		free := item.Quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+		amount = item.UnitPrice.Amount * int64(free)
+	case PromotionTieredQuantity:
+		percentage := 0
+		for _, tier := range p.Tiers {
+			if item.Quantity >= tier.MinQuantity {
+				percentage = tier.Percentage
+			}
+		}
+		amount = remaining * int64(percentage) / 100
+	}
+	return min(amount, remaining)
+}
+
+// orderDiscount is how much the promotion takes off the subtotal left
+// after the line discounts
+func (p *Promotion) orderDiscount(subtotal Money) int64 {
+	var amount int64
+	switch p.Type {
+	case PromotionPercentage:
+		amount = subtotal.Amount * int64(p.Percentage) / 100
+	case PromotionFixedAmount:
+		if p.Amount.Currency == subtotal.Currency {
+			amount = p.Amount.Amount
+		}
+	}
+	return min(amount, subtotal.Amount)
+}
+
+// minimumTotal is the least the items of an order are charged after their
+// discounts: the payments service does not take zero amounts, so a fully
+// discounted order could never be paid. The shipping fee is discounted on
+// its own and does not count towards it.
+const minimumTotal = 1
+
+// computeDiscounts prices items with promotions. Each promotion that would
+// discount the items on their own is a candidate; candidates are taken by
+// priority, and a promotion that is not stackable is only taken when it
+// comes first, after which no other is. Line discounts are applied before
+// order discounts, so percentages off the order use the discounted lines.
+// Discounts off the items stop at minimumTotal; free shipping waives the
+// shipping fee. Promotions not taken, or left with nothing to take off, are
+// returned with the reason.
+func computeDiscounts(items []Item, shipping Money, promotions []Promotion) ([]Discount, map[string]error) {
+	currency := shipping.Currency
+	sorted := make([]Promotion, len(promotions))
+	copy(sorted, promotions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	var subtotal int64
+	for _, item := range items {
+		subtotal += item.Total.Amount
+	}
+
+	skipped := make(map[string]error)
+	var taken []Promotion
+	exclusive := false
+	for _, promotion := range sorted {
+		if !promotion.applies(items, NewMoney(subtotal, currency), shipping) {
+			skipped[promotion.ID] = ErrCouponNotApplicable
+			continue
+		}
+		if exclusive || !promotion.Stackable && len(taken) > 0 {
+			skipped[promotion.ID] = ErrCouponNotStackable
+			continue
+		}
+		taken = append(taken, promotion)
+		exclusive = !promotion.Stackable
+	}
+
+	var discounts []Discount
+	remaining := make(map[string]int64, len(items))
+	for _, item := range items {
+		remaining[item.ID] = item.Total.Amount
+	}
+	left := subtotal
+	for _, promotion := range taken {
+		if promotion.level() != DiscountLine {
+			continue
+		}
+		for _, item := range items {
+			if !promotion.matches(item) {
+				continue
+			}
+			amount := min(promotion.lineDiscount(item, remaining[item.ID]), left-minimumTotal)
+			if amount <= 0 {
+				continue
+			}
+			remaining[item.ID] -= amount
+			left -= amount
+			discounts = append(discounts, promotion.discount(DiscountLine, item.ID, NewMoney(amount, currency)))
+		}
+	}
+
+	for _, promotion := range taken {
+		if promotion.level() != DiscountOrder {
+			continue
+		}
+		amount := min(promotion.orderDiscount(NewMoney(left, currency)), left-minimumTotal)
+		if amount <= 0 {
+			continue
+		}
+		left -= amount
+		discounts = append(discounts, promotion.discount(DiscountOrder, "", NewMoney(amount, currency)))
+	}
+
+	shippingLeft := shipping.Amount
+	for _, promotion := range taken {
+		if promotion.level() != DiscountShipping || shippingLeft <= 0 {
+			continue
+		}
+		discounts = append(discounts, promotion.discount(DiscountShipping, "", NewMoney(shippingLeft, currency)))
+		shippingLeft = 0
+	}
+
+	for _, promotion := range taken {
+		if !slices.ContainsFunc(discounts, func(discount Discount) bool { return discount.PromotionID == promotion.ID }) {
+			skipped[promotion.ID] = ErrCouponNotApplicable
+		}
+	}
+
+	return discounts, skipped
+}
+
+// applies tells whether the promotion alone would discount the items, or
+// the shipping fee
+func (p *Promotion) applies(items []Item, subtotal, shipping Money) bool {
+	if !subtotal.IsPositive() {
+		return false
+	}
+	switch p.level() {
+	case DiscountShipping:
+		return shipping.IsPositive()
+	case DiscountOrder:
+		return p.orderDiscount(subtotal) > 0
+	}
+	for _, item := range items {
+		if p.matches(item) && p.lineDiscount(item, item.Total.Amount) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Promotion) discount(level DiscountLevel, itemID string, amount Money) Discount {
+	return Discount{
+		PromotionID: p.ID,
+		Code:        p.Code,
+		Name:        p.Name,
+		Level:       level,
+		ItemID:      itemID,
+		Amount:      amount,
+	}
+}
+
+func validPercentage(percentage int) bool {
+	return percentage >= 1 && percentage <= 100
+}
+
+func validCouponCode(code string) bool {
+	if len(code) < 3 || len(code) > 32 {
+		return false
+	}
+	for _, c := range code {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
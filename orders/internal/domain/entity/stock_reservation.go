@@ -0,0 +1,146 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReservationStatus string
+
+const (
+	ReservationStatusReserved  ReservationStatus = "reserved"
+	ReservationStatusCommitted ReservationStatus = "committed"
+	ReservationStatusReleased  ReservationStatus = "released"
+)
+
+var (
+	ErrInsufficientStock            = errors.New("insufficient stock")
+	ErrReservationNotActive         = errors.New("reservation is not active")
+	ErrReservationCannotBeCommitted = errors.New("reservation must be reserved to be committed")
+	// ErrReservationConflict means the reservation was changed by another
+	// request since it was loaded
+	ErrReservationConflict = errors.New("reservation was changed concurrently")
+)
+
+// StockReservation holds stock taken from a product on behalf of an order.
+// Stock is decremented when the reservation is made; committing keeps it
+// sold, releasing gives it back to the product.
+type StockReservation struct {
+	ID        string            `json:"id"`
+	OrderID   string            `json:"order_id"`
+	ProductID string            `json:"product_id"`
+	Quantity  int               `json:"quantity"`
+	Status    ReservationStatus `json:"status"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// NewStockReservation creates a reservation. A zero ttl means the reservation
+// never expires on its own and must be committed or released explicitly.
+func NewStockReservation(orderID, productID string, quantity int, ttl time.Duration) (*StockReservation, error) {
+	if productID == "" {
+		return nil, ErrInvalidProduct
+	}
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	now := time.Now()
+	reservation := &StockReservation{
+		ID:        uuid.New().String(),
+		OrderID:   orderID,
+		ProductID: productID,
+		Quantity:  quantity,
+		Status:    ReservationStatusReserved,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	reservation.extend(ttl)
+
+	return reservation, nil
+}
+
+// Add increases the reserved quantity and pushes the expiry forward.
+func (r *StockReservation) Add(quantity int, ttl time.Duration) error {
+	if r.Status != ReservationStatusReserved {
+		return ErrReservationNotActive
+	}
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+
+	r.Quantity += quantity
+	r.extend(ttl)
+	r.UpdatedAt = time.Now()
+	return nil
+}
+
+// Reduce gives back part of the reservation. Reducing it to zero releases it.
+func (r *StockReservation) Reduce(quantity int) error {
+	if r.Status != ReservationStatusReserved {
+		return ErrReservationNotActive
+	}
+	if quantity <= 0 || quantity > r.Quantity {
+		return ErrInvalidQuantity
+	}
+
+	r.Quantity -= quantity
+	if r.Quantity == 0 {
+		r.Status = ReservationStatusReleased
+	}
+	r.UpdatedAt = time.Now()
+	return nil
+}
+
+// Keep stops the reservation from expiring, once the cart holding it is
+// being checked out
+func (r *StockReservation) Keep() error {
+	if r.Status != ReservationStatusReserved {
+		return ErrReservationNotActive
+	}
+
+	r.ExpiresAt = nil
+	r.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *StockReservation) Commit() error {
+	if r.Status != ReservationStatusReserved {
+		return ErrReservationCannotBeCommitted
+	}
+
+	r.Status = ReservationStatusCommitted
+	r.ExpiresAt = nil
+	r.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *StockReservation) Release() error {
+	if r.Status == ReservationStatusReleased {
+		return ErrReservationNotActive
+	}
+
+	r.Status = ReservationStatusReleased
+	r.ExpiresAt = nil
+	r.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *StockReservation) IsActive() bool {
+	return r.Status == ReservationStatusReserved
+}
+
+func (r *StockReservation) IsExpired(now time.Time) bool {
+	return r.IsActive() && r.ExpiresAt != nil && !r.ExpiresAt.After(now)
+}
+
+func (r *StockReservation) extend(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	expiresAt := time.Now().Add(ttl)
+	r.ExpiresAt = &expiresAt
+}
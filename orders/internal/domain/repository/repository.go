@@ -1,23 +1,115 @@
 package repository
 
-import "orders/internal/domain/entity"
+import (
+	"orders/internal/domain/entity"
+	"time"
+)
 
 type ProductRepository interface {
 	Create(product *entity.Product) error
 	FindByID(id string) (*entity.Product, error)
-	FindAll() ([]entity.Product, error)
+	// List returns the products matching filter, sorted by created_at and id.
+	List(filter ProductFilter) ([]entity.Product, error)
 	Update(product *entity.Product) error
 	Delete(id string) error
+	// DecreaseStock atomically takes quantity from the product stock and
+	// returns entity.ErrInsufficientStock when not enough is available.
+	DecreaseStock(id string, quantity int) error
+	IncreaseStock(id string, quantity int) error
 }
 
 type OrderRepository interface {
+	// Create returns entity.ErrDuplicateIdempotencyKey when another order
+	// of the same customer was already stored with the same idempotency key.
 	Create(order *entity.Order) error
 	FindByID(id string) (*entity.Order, error)
-	FindAll() ([]entity.Order, error)
+	// FindByIdempotencyKey finds the order the customer created with the
+	// key; an empty customerID looks up guest orders. It returns nil without
+	// error when there is none.
+	FindByIdempotencyKey(customerID, key string) (*entity.Order, error)
+	// List returns the orders matching filter, sorted by created_at and id,
+	// without their items.
+	List(filter OrderFilter) ([]entity.Order, error)
+	// FindByCustomerID returns the orders of a customer, newest first.
+	FindByCustomerID(customerID string) ([]entity.Order, error)
+	// FindExpiredCarts returns up to limit carts (pending orders that were
+	// never placed) whose expiry is at or before now, oldest expiry first,
+	// without their items.
+	FindExpiredCarts(now time.Time, limit int) ([]entity.Order, error)
 	Update(order *entity.Order) error
 	Delete(id string) error
 }
 
+// Page selects the rows after the After cursor, up to Limit, sorted by
+// created_at and id: newest first unless Ascending.
+type Page struct {
+	After     *entity.PageCursor
+	Ascending bool
+	Limit     int
+}
+
+// ProductFilter selects the products returned by List; zero fields match
+// every product. Prices are in minor units.
+type ProductFilter struct {
+	MinPrice    *int64
+	MaxPrice    *int64
+	CreatedFrom time.Time // inclusive
+	CreatedTo   time.Time // exclusive
+	Page
+}
+
+// OrderFilter selects the orders returned by List; zero fields match every
+// order. Totals are in minor units.
+type OrderFilter struct {
+	Status      entity.OrderStatus
+	CustomerID  string
+	MinTotal    *int64
+	MaxTotal    *int64
+	CreatedFrom time.Time // inclusive
+	CreatedTo   time.Time // exclusive
+	Page
+}
+
+type CustomerRepository interface {
+	// Create returns entity.ErrDuplicateCustomer when the email or document
+	// already belongs to another customer.
+	Create(customer *entity.Customer) error
+	FindByID(id string) (*entity.Customer, error)
+	FindAll() ([]entity.Customer, error)
+	// Update replaces the customer addresses with the given ones and returns
+	// entity.ErrDuplicateCustomer like Create.
+	Update(customer *entity.Customer) error
+	// Delete returns entity.ErrCustomerHasOrders when orders point to the
+	// customer.
+	Delete(id string) error
+}
+
+type PromotionRepository interface {
+	// Create returns entity.ErrDuplicateCouponCode when another promotion
+	// already uses the coupon code.
+	Create(promotion *entity.Promotion) error
+	FindByID(id string) (*entity.Promotion, error)
+	// FindByCode returns sql.ErrNoRows when no promotion has the coupon code.
+	FindByCode(code string) (*entity.Promotion, error)
+	// FindAll returns every promotion, newest first.
+	FindAll() ([]entity.Promotion, error)
+	// FindAutomatic returns the active promotions without a coupon code
+	// valid at now.
+	FindAutomatic(now time.Time) ([]entity.Promotion, error)
+	Update(promotion *entity.Promotion) error
+	// CountRedemptions returns how many orders redeemed the promotion, in
+	// total and by the customer (zero for an empty customerID). Redemptions
+	// of canceled orders do not count.
+	CountRedemptions(promotionID, customerID string) (total int, byCustomer int, err error)
+}
+
+// OrderStatusHistoryRepository reads the status changes saved by the order
+// repository together with the order.
+type OrderStatusHistoryRepository interface {
+	// FindByOrderID returns the changes of an order, oldest first.
+	FindByOrderID(orderID string) ([]entity.StatusChange, error)
+}
+
 type ItemRepository interface {
 	Create(item *entity.Item) error
 	FindByID(id string) (*entity.Item, error)
@@ -25,3 +117,54 @@ type ItemRepository interface {
 	Update(item *entity.Item) error
 	Delete(id string) error
 }
+
+type StockReservationRepository interface {
+	Create(reservation *entity.StockReservation) error
+	FindByOrderID(orderID string) ([]entity.StockReservation, error)
+	// FindActive returns nil without error when the order holds no active
+	// reservation for the product.
+	FindActive(orderID, productID string) (*entity.StockReservation, error)
+	FindExpired(now time.Time) ([]entity.StockReservation, error)
+	// Update saves the reservation only over the reservation as it was
+	// loaded (previous), and fails with entity.ErrReservationConflict when
+	// another request changed it first.
+	Update(reservation *entity.StockReservation, previous entity.StockReservation) error
+	// GiveBack saves a reservation that gave back quantity units and adds
+	// them to the product stock, in one transaction. It only saves over the
+	// reservation as it was loaded (previous): when another request changed
+	// it first, nothing is given back and it fails with
+	// entity.ErrReservationConflict.
+	GiveBack(reservation *entity.StockReservation, previous entity.StockReservation, quantity int) error
+}
+
+// OutboxRepository reads the events saved by the other repositories in the
+// same transaction as the aggregate they describe.
+type OutboxRepository interface {
+	// FindUnpublished returns pending events, oldest first.
+	FindUnpublished(limit int) ([]entity.Event, error)
+	MarkPublished(id string, publishedAt time.Time) error
+	MarkFailed(id string, reason string) error
+}
+
+type CheckoutSagaRepository interface {
+	// Create returns entity.ErrDuplicateCheckoutSaga when the order already
+	// has a saga.
+	Create(saga *entity.CheckoutSaga) error
+	// FindByOrderID returns nil without error when the order has no saga.
+	FindByOrderID(orderID string) (*entity.CheckoutSaga, error)
+	// FindUnfinished returns running or compensating sagas last touched
+	// before the given time, oldest first.
+	FindUnfinished(updatedBefore time.Time, limit int) ([]entity.CheckoutSaga, error)
+	// Update saves the saga only if nobody else saved it since it was read,
+	// returning entity.ErrSagaConflict otherwise, and bumps its version.
+	Update(saga *entity.CheckoutSaga) error
+}
+
+// StreamCursorRepository keeps the position reached in the streams consumed
+// from other services.
+type StreamCursorRepository interface {
+	// Find returns an empty cursor without error when the stream was never
+	// consumed.
+	Find(stream string) (string, error)
+	Save(stream, cursor string) error
+}
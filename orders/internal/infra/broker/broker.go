@@ -0,0 +1,28 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"orders/internal/domain/entity"
+)
+
+// Broker delivers outbox events to downstream consumers (shipping, email,
+// analytics). Publish must only return nil once the broker accepted the
+// event; the relay retries anything else, so delivery is at least once.
+type Broker interface {
+	Publish(ctx context.Context, event entity.Event) error
+	Close() error
+}
+
+// New builds the broker selected by kind: "memory" or "file". path is the
+// JSON Lines file used by the file broker.
+func New(kind, path string) (Broker, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryBroker(), nil
+	case "file":
+		return NewFileBroker(path)
+	default:
+		return nil, fmt.Errorf("unknown event broker %q", kind)
+	}
+}
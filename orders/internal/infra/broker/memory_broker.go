@@ -0,0 +1,53 @@
+package broker
+
+import (
+	"context"
+	"orders/internal/domain/entity"
+	"sync"
+)
+
+// MemoryBroker keeps published events in memory and hands them to
+// in-process subscribers. It is meant for local runs and tests.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	events      []entity.Event
+	subscribers []func(entity.Event)
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event entity.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.events = append(b.events, event)
+	subscribers := append([]func(entity.Event){}, b.subscribers...)
+	b.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(event)
+	}
+	return nil
+}
+
+// Subscribe registers handler for every event published from now on
+func (b *MemoryBroker) Subscribe(handler func(entity.Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, handler)
+}
+
+// Events returns a copy of everything published so far
+func (b *MemoryBroker) Events() []entity.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]entity.Event{}, b.events...)
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// reloadCheckInterval é de quanto em quanto tempo os arquivos de
+// certificado são verificados, para que certificados rotacionados sejam
+// usados sem reiniciar o serviço
+const reloadCheckInterval = 10 * time.Second
+
+// SecurityConfig define como o cliente se autentica no serviço de
+// pagamentos. TLS liga a criptografia; CAFile troca as raízes do sistema
+// pela CA informada; CertFile e KeyFile apresentam um certificado de
+// cliente (mTLS). Token é enviado em toda chamada no metadata authorization.
+type SecurityConfig struct {
+	TLS        bool
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+	Token      string
+}
+
+// certReloader guarda o certificado de cliente e a CA do servidor, lendo os
+// arquivos de novo quando eles mudam. Conexões já abertas mantêm os
+// certificados do handshake em que foram criadas.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	logger   *slog.Logger
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	roots     *x509.CertPool
+	modTimes  [3]time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(certFile, keyFile, caFile string, logger *slog.Logger) (*certReloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		logger:   logger,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// tlsConfig monta a configuração TLS do cliente. Com uma CA própria a
+// cadeia do servidor é verificada em VerifyConnection, contra a CA carregada
+// por último, em vez de RootCAs, que não pode ser trocada depois.
+func (r *certReloader) tlsConfig(serverName string) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			if cert == nil {
+				// Sem certificado o servidor decide se aceita a conexão
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+	}
+	if r.caFile == "" {
+		return config
+	}
+
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		_, roots := r.current()
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("payment service sent no certificate")
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+			DNSName:       state.ServerName,
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		return err
+	}
+	return config
+}
+
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) >= reloadCheckInterval {
+		r.checkedAt = time.Now()
+		if modTimes, err := r.stat(); err == nil && modTimes != r.modTimes {
+			// Certificado e chave gravados um após o outro ficam
+			// diferentes por um instante; o par anterior vale até lá
+			if err := r.reload(); err != nil {
+				r.logger.Warn("Failed to reload TLS certificates, keeping the previous ones", "error", err)
+			} else {
+				r.logger.Info("TLS certificates reloaded", "cert_file", r.certFile, "ca_file", r.caFile)
+			}
+		}
+	}
+
+	return r.cert, r.roots
+}
+
+// reload lê os arquivos; quem chama, fora newCertReloader, segura r.mu
+func (r *certReloader) reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		pair, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cert = &pair
+	}
+
+	var roots *x509.CertPool
+	if r.caFile != "" {
+		data, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return errors.New("CA file has no PEM certificates")
+		}
+	}
+
+	r.cert = cert
+	r.roots = roots
+	r.modTimes = modTimes
+	r.checkedAt = time.Now()
+	return nil
+}
+
+func (r *certReloader) stat() ([3]time.Time, error) {
+	var modTimes [3]time.Time
+	for i, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// serviceToken envia o token do serviço de pedidos em toda chamada
+type serviceToken struct {
+	token      string
+	requireTLS bool
+}
+
+func (t serviceToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+// RequireTransportSecurity só dispensa TLS quando a conexão foi configurada
+// sem ele, o que serve apenas ao desenvolvimento local
+func (t serviceToken) RequireTransportSecurity() bool {
+	return t.requireTLS
+}
+
+var _ credentials.PerRPCCredentials = serviceToken{}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"orders/internal/domain/entity"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// paymentsErrorDomain é o domínio do ErrorInfo enviado pelo payments service
+const paymentsErrorDomain = "payments"
+
+// reasonInvalidPaymentDetails é o motivo enviado quando o cartão, a chave
+// PIX ou o boleto foram recusados
+const reasonInvalidPaymentDetails = "INVALID_PAYMENT_DETAILS"
+
+// paymentError traduz o status de uma chamada que falhou em um
+// *entity.PaymentError, com o motivo do ErrorInfo e os campos do
+// BadRequest. Erros que não vêm do payments service, como um contexto
+// cancelado aqui, são devolvidos como estão.
+func paymentError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	paymentErr := &entity.PaymentError{Message: st.Message()}
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if d.Domain == paymentsErrorDomain {
+				paymentErr.Reason = d.Reason
+			}
+		case *errdetails.BadRequest:
+			for _, violation := range d.FieldViolations {
+				paymentErr.Fields = append(paymentErr.Fields, entity.FieldViolation{
+					Field:       violation.Field,
+					Description: violation.Description,
+				})
+			}
+		}
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		paymentErr.Kind = entity.ErrInvalidPaymentRequest
+		// Versões sem ErrorInfo só indicavam o motivo na mensagem
+		if paymentErr.Reason == reasonInvalidPaymentDetails ||
+			(paymentErr.Reason == "" && strings.HasPrefix(st.Message(), entity.ErrInvalidPaymentDetails.Error())) {
+			paymentErr.Kind = entity.ErrInvalidPaymentDetails
+		}
+	case codes.NotFound:
+		paymentErr.Kind = entity.ErrPaymentNotFound
+	case codes.FailedPrecondition:
+		paymentErr.Kind = entity.ErrPaymentOperationNotAllowed
+	case codes.AlreadyExists, codes.Aborted:
+		paymentErr.Kind = entity.ErrPaymentConflict
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		paymentErr.Kind = entity.ErrPaymentServiceUnavailable
+	default:
+		return err
+	}
+
+	return paymentErr
+}
@@ -4,25 +4,47 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"orders/internal/domain/entity"
 	"time"
 
 	pb "orders/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// listPaymentsPageSize é o maior tamanho de página aceito pelo payments
+// service
+const listPaymentsPageSize = 100
+
 type PaymentClient struct {
 	client pb.PaymentServiceClient
 	conn   *grpc.ClientConn
 	logger *slog.Logger
 }
 
-func NewPaymentClient(paymentServiceAddr string, logger *slog.Logger) (*PaymentClient, error) {
-	conn, err := grpc.NewClient(
-		paymentServiceAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+// NewPaymentClient conecta ao serviço de pagamentos. Sem TLS, CA ou
+// certificado de cliente em security a conexão não é criptografada.
+func NewPaymentClient(paymentServiceAddr string, security SecurityConfig, logger *slog.Logger) (*PaymentClient, error) {
+	useTLS := security.TLS || security.CAFile != "" || security.CertFile != ""
+
+	transport := insecure.NewCredentials()
+	if useTLS {
+		reloader, err := newCertReloader(security.CertFile, security.KeyFile, security.CAFile, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load payment service TLS configuration: %w", err)
+		}
+		transport = credentials.NewTLS(reloader.tlsConfig(security.ServerName))
+	}
+
+	options := []grpc.DialOption{grpc.WithTransportCredentials(transport)}
+	if security.Token != "" {
+		options = append(options, grpc.WithPerRPCCredentials(serviceToken{token: security.Token, requireTLS: useTLS}))
+	}
+
+	conn, err := grpc.NewClient(paymentServiceAddr, options...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to payment service: %w", err)
 	}
@@ -40,24 +62,19 @@ func (c *PaymentClient) Close() error {
 	return c.conn.Close()
 }
 
-// ProcessPayment processa um pagamento via gRPC
-func (c *PaymentClient) ProcessPayment(ctx context.Context, orderID string, amount float64, paymentMethod int32, customerEmail, customerName string) (*pb.ProcessPaymentResponse, error) {
+// ProcessPayment processa um pagamento via gRPC. Repetir a chamada com a
+// mesma idempotencyKey devolve o pagamento original em vez de cobrar de novo.
+func (c *PaymentClient) ProcessPayment(ctx context.Context, orderID string, amount entity.Money, paymentMethod int32, customerEmail, customerName, idempotencyKey string, details *entity.PaymentDetails) (*pb.ProcessPaymentResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	c.logger.Info("Processing payment via gRPC",
 		"order_id", orderID,
-		"amount", amount,
+		"amount", amount.String(),
 		"payment_method", paymentMethod,
 	)
 
-	request := &pb.ProcessPaymentRequest{
-		OrderId:       orderID,
-		Amount:        amount,
-		PaymentMethod: pb.PaymentMethod(paymentMethod),
-		CustomerEmail: customerEmail,
-		CustomerName:  customerName,
-	}
+	request := newProcessPaymentRequest(orderID, amount, paymentMethod, customerEmail, customerName, idempotencyKey, details)
 
 	c.logger.Info("About to call ProcessPayment gRPC", "order_id", orderID)
 	response, err := c.client.ProcessPayment(ctx, request)
@@ -68,7 +85,7 @@ func (c *PaymentClient) ProcessPayment(ctx context.Context, orderID string, amou
 			"error", err,
 			"order_id", orderID,
 		)
-		return nil, fmt.Errorf("failed to process payment: %w", err)
+		return nil, fmt.Errorf("failed to process payment: %w", paymentError(err))
 	}
 
 	c.logger.Info("Payment processed successfully",
@@ -80,6 +97,136 @@ func (c *PaymentClient) ProcessPayment(ctx context.Context, orderID string, amou
 	return response, nil
 }
 
+// AuthorizePayment reserva o valor no cartão sem cobrar. A cobrança é feita
+// depois com CapturePayment; a mesma idempotencyKey devolve a autorização
+// original.
+func (c *PaymentClient) AuthorizePayment(ctx context.Context, orderID string, amount entity.Money, paymentMethod int32, customerEmail, customerName, idempotencyKey string, details *entity.PaymentDetails) (*pb.AuthorizePaymentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	c.logger.Info("Authorizing payment via gRPC",
+		"order_id", orderID,
+		"amount", amount.String(),
+		"payment_method", paymentMethod,
+	)
+
+	request := newProcessPaymentRequest(orderID, amount, paymentMethod, customerEmail, customerName, idempotencyKey, details)
+
+	response, err := c.client.AuthorizePayment(ctx, request)
+	if err != nil {
+		c.logger.Error("Failed to authorize payment",
+			"error", err,
+			"order_id", orderID,
+		)
+		return nil, fmt.Errorf("failed to authorize payment: %w", paymentError(err))
+	}
+
+	c.logger.Info("Payment authorization processed",
+		"payment_id", response.PaymentId,
+		"status", response.Status,
+		"order_id", orderID,
+	)
+
+	return response, nil
+}
+
+// CapturePayment cobra um pagamento autorizado; amount nil captura todo o valor
+func (c *PaymentClient) CapturePayment(ctx context.Context, paymentID string, amount *entity.Money) (*pb.CapturePaymentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	c.logger.Info("Capturing payment via gRPC", "payment_id", paymentID)
+
+	request := &pb.CapturePaymentRequest{
+		PaymentId: paymentID,
+	}
+	if amount != nil {
+		request.Amount = &pb.Money{
+			Amount:   amount.Amount,
+			Currency: amount.Currency,
+		}
+	}
+
+	response, err := c.client.CapturePayment(ctx, request)
+	if err != nil {
+		c.logger.Error("Failed to capture payment",
+			"error", err,
+			"payment_id", paymentID,
+		)
+		return nil, fmt.Errorf("failed to capture payment: %w", paymentError(err))
+	}
+
+	c.logger.Info("Payment captured successfully",
+		"payment_id", paymentID,
+		"status", response.Status,
+	)
+
+	return response, nil
+}
+
+// VoidAuthorization libera uma autorização sem cobrar nada
+func (c *PaymentClient) VoidAuthorization(ctx context.Context, paymentID, reason string) (*pb.VoidAuthorizationResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	c.logger.Info("Voiding authorization via gRPC", "payment_id", paymentID)
+
+	response, err := c.client.VoidAuthorization(ctx, &pb.VoidAuthorizationRequest{
+		PaymentId: paymentID,
+		Reason:    reason,
+	})
+	if err != nil {
+		c.logger.Error("Failed to void authorization",
+			"error", err,
+			"payment_id", paymentID,
+		)
+		return nil, fmt.Errorf("failed to void authorization: %w", paymentError(err))
+	}
+
+	c.logger.Info("Authorization voided successfully", "payment_id", paymentID)
+
+	return response, nil
+}
+
+func newProcessPaymentRequest(orderID string, amount entity.Money, paymentMethod int32, customerEmail, customerName, idempotencyKey string, details *entity.PaymentDetails) *pb.ProcessPaymentRequest {
+	request := &pb.ProcessPaymentRequest{
+		OrderId: orderID,
+		Money: &pb.Money{
+			Amount:   amount.Amount,
+			Currency: amount.Currency,
+		},
+		// Mantido enquanto houver payments services que só leem o campo antigo
+		Amount:         float64(amount.Amount) / 100,
+		PaymentMethod:  pb.PaymentMethod(paymentMethod),
+		CustomerEmail:  customerEmail,
+		CustomerName:   customerName,
+		IdempotencyKey: idempotencyKey,
+	}
+
+	switch {
+	case details == nil:
+	case details.Card != nil:
+		request.PaymentDetails = &pb.ProcessPaymentRequest_CardDetails{CardDetails: &pb.CardDetails{
+			CardNumber:     details.Card.Number,
+			CardHolderName: details.Card.HolderName,
+			ExpiryDate:     details.Card.ExpiryDate,
+			Cvv:            details.Card.CVV,
+		}}
+	case details.Pix != nil:
+		request.PaymentDetails = &pb.ProcessPaymentRequest_PixDetails{PixDetails: &pb.PixDetails{
+			PixKey: details.Pix.Key,
+		}}
+	case details.Boleto != nil:
+		boleto := &pb.BoletoDetails{CustomerDocument: details.Boleto.Document}
+		if details.Boleto.DueDate != nil {
+			boleto.DueDate = timestamppb.New(*details.Boleto.DueDate)
+		}
+		request.PaymentDetails = &pb.ProcessPaymentRequest_BoletoDetails{BoletoDetails: boleto}
+	}
+
+	return request
+}
+
 // GetPayment busca detalhes de um pagamento
 func (c *PaymentClient) GetPayment(ctx context.Context, paymentID string) (*pb.GetPaymentResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -95,12 +242,47 @@ func (c *PaymentClient) GetPayment(ctx context.Context, paymentID string) (*pb.G
 			"error", err,
 			"payment_id", paymentID,
 		)
-		return nil, fmt.Errorf("failed to get payment: %w", err)
+		return nil, fmt.Errorf("failed to get payment: %w", paymentError(err))
+	}
+
+	return response, nil
+}
+
+// GetBoletoSlip busca o boleto de um pagamento pronto para impressão
+func (c *PaymentClient) GetBoletoSlip(ctx context.Context, paymentID string) (*pb.GetBoletoSlipResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	request := &pb.GetBoletoSlipRequest{
+		PaymentId: paymentID,
+	}
+
+	response, err := c.client.GetBoletoSlip(ctx, request)
+	if err != nil {
+		c.logger.Error("Failed to get boleto slip",
+			"error", err,
+			"payment_id", paymentID,
+		)
+		return nil, fmt.Errorf("failed to get boleto slip: %w", paymentError(err))
 	}
 
 	return response, nil
 }
 
+// WatchPayments abre o stream de mudanças de status dos pagamentos a partir
+// do cursor (vazio começa do início). O stream fica aberto até ctx ser
+// cancelado ou a conexão cair, por isso não tem timeout.
+func (c *PaymentClient) WatchPayments(ctx context.Context, cursor string) (pb.PaymentService_WatchPaymentsClient, error) {
+	c.logger.Info("Watching payments via gRPC", "cursor", cursor)
+
+	stream, err := c.client.WatchPayments(ctx, &pb.WatchPaymentsRequest{Cursor: cursor})
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch payments: %w", paymentError(err))
+	}
+
+	return stream, nil
+}
+
 // CancelPayment cancela um pagamento
 func (c *PaymentClient) CancelPayment(ctx context.Context, paymentID string) (*pb.CancelPaymentResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -118,7 +300,7 @@ func (c *PaymentClient) CancelPayment(ctx context.Context, paymentID string) (*p
 			"error", err,
 			"payment_id", paymentID,
 		)
-		return nil, fmt.Errorf("failed to cancel payment: %w", err)
+		return nil, fmt.Errorf("failed to cancel payment: %w", paymentError(err))
 	}
 
 	c.logger.Info("Payment canceled successfully", "payment_id", paymentID)
@@ -127,22 +309,68 @@ func (c *PaymentClient) CancelPayment(ctx context.Context, paymentID string) (*p
 }
 
 // ListPayments lista pagamentos de um pedido
+// ListPayments devolve todos os pagamentos do pedido, do mais recente para
+// o mais antigo, percorrendo as páginas do payments service
 func (c *PaymentClient) ListPayments(ctx context.Context, orderID string) (*pb.ListPaymentsResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	request := &pb.ListPaymentsRequest{
-		OrderId: orderID,
+		OrderId:  orderID,
+		PageSize: listPaymentsPageSize,
+	}
+
+	result := &pb.ListPaymentsResponse{}
+	for {
+		response, err := c.client.ListPayments(ctx, request)
+		if err != nil {
+			c.logger.Error("Failed to list payments",
+				"error", err,
+				"order_id", orderID,
+			)
+			return nil, fmt.Errorf("failed to list payments: %w", paymentError(err))
+		}
+
+		result.Payments = append(result.Payments, response.Payments...)
+		if response.NextPageToken == "" {
+			return result, nil
+		}
+		request.PageToken = response.NextPageToken
+	}
+}
+
+// RefundPayment reembolsa um pagamento; amount nil reembolsa todo o saldo restante
+func (c *PaymentClient) RefundPayment(ctx context.Context, paymentID string, amount *entity.Money, reason string) (*pb.RefundPaymentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	c.logger.Info("Refunding payment via gRPC", "payment_id", paymentID)
+
+	request := &pb.RefundPaymentRequest{
+		PaymentId: paymentID,
+		Reason:    reason,
+	}
+	if amount != nil {
+		request.Amount = &pb.Money{
+			Amount:   amount.Amount,
+			Currency: amount.Currency,
+		}
 	}
 
-	response, err := c.client.ListPayments(ctx, request)
+	response, err := c.client.RefundPayment(ctx, request)
 	if err != nil {
-		c.logger.Error("Failed to list payments",
+		c.logger.Error("Failed to refund payment",
 			"error", err,
-			"order_id", orderID,
+			"payment_id", paymentID,
 		)
-		return nil, fmt.Errorf("failed to list payments: %w", err)
+		return nil, fmt.Errorf("failed to refund payment: %w", paymentError(err))
 	}
 
+	c.logger.Info("Payment refunded successfully",
+		"payment_id", paymentID,
+		"refund_id", response.RefundId,
+		"status", response.Status,
+	)
+
 	return response, nil
 }
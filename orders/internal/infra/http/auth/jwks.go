@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwksCheckInterval is how often the JWKS file is checked for changes, so
+// rotated keys are picked up without a restart
+const jwksCheckInterval = 10 * time.Second
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keySet holds the RSA signing keys of a JWKS file, reloading them when the
+// file changes
+type keySet struct {
+	path string
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func loadKeySet(path string) (*keySet, error) {
+	set := &keySet{path: path}
+	if err := set.reload(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// key returns the key with the given ID. Tokens without kid are accepted
+// when the file has a single key.
+func (s *keySet) key(kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.checkedAt) >= jwksCheckInterval {
+		s.checkedAt = time.Now()
+		if info, err := os.Stat(s.path); err == nil && !info.ModTime().Equal(s.modTime) {
+			// A broken file keeps the keys loaded before
+			_ = s.reload()
+		}
+	}
+
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, nil
+		}
+	}
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// reload reads the file; callers other than loadKeySet hold s.mu
+func (s *keySet) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range document.Keys {
+		// Only RSA signing keys can verify RS256 tokens
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		key, err := rsaPublicKey(k)
+		if err != nil {
+			return fmt.Errorf("invalid JWKS key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS file has no RSA signing keys")
+	}
+
+	s.keys = keys
+	s.modTime = info.ModTime()
+	s.checkedAt = time.Now()
+	return nil
+}
+
+func rsaPublicKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("bad modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("bad exponent")
+	}
+
+	exponent := 0
+	for _, b := range e {
+		exponent = exponent<<8 | int(b)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+}
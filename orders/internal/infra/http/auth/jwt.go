@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Config selects how tokens are verified. HMACSecret enables HS256 and
+// JWKSFile enables RS256 with the RSA keys of a JWKS file; at least one of
+// them is required. Issuer and Audience are only checked when set.
+type Config struct {
+	HMACSecret string
+	JWKSFile   string
+	Issuer     string
+	Audience   string
+	// Leeway tolerates clock differences when checking exp and nbf
+	Leeway time.Duration
+}
+
+// Verifier checks the signature and the registered claims of JWTs and turns
+// them into a Principal
+type Verifier struct {
+	secret   []byte
+	keys     *keySet
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.HMACSecret == "" && cfg.JWKSFile == "" {
+		return nil, errors.New("a JWT secret or a JWKS file is required")
+	}
+
+	verifier := &Verifier{
+		secret:   []byte(cfg.HMACSecret),
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		leeway:   cfg.Leeway,
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadKeySet(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.keys = keys
+	}
+	return verifier, nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type claims struct {
+	Subject    string   `json:"sub"`
+	Issuer     string   `json:"iss"`
+	Audience   audience `json:"aud"`
+	ExpiresAt  *float64 `json:"exp"`
+	NotBefore  *float64 `json:"nbf"`
+	Role       string   `json:"role"`
+	Roles      []string `json:"roles"`
+	CustomerID string   `json:"customer_id"`
+}
+
+// audience accepts both forms allowed for aud: a string or a list of them
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Verify accepts HS256 and RS256 tokens, the latter only when signed by a
+// key of the JWKS file. Tokens must expire: exp is required.
+func (v *Verifier) Verify(token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var head header
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	if err := v.verifySignature(head, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := v.validateClaims(c, now); err != nil {
+		return nil, err
+	}
+
+	roles := c.Roles
+	if c.Role != "" && !slices.Contains(roles, c.Role) {
+		roles = append(roles, c.Role)
+	}
+	customerID := c.CustomerID
+	if customerID == "" && slices.Contains(roles, RoleCustomer) {
+		customerID = c.Subject
+	}
+
+	return &Principal{Subject: c.Subject, Roles: roles, CustomerID: customerID}, nil
+}
+
+func (v *Verifier) verifySignature(head header, signed string, signature []byte) error {
+	switch head.Alg {
+	case "HS256":
+		if len(v.secret) == 0 {
+			return fmt.Errorf("%w: HS256 tokens are not accepted", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+	case "RS256":
+		if v.keys == nil {
+			return fmt.Errorf("%w: RS256 tokens are not accepted", ErrInvalidToken)
+		}
+		key, err := v.keys.key(head.Kid)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+		digest := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+	default:
+		// Never "none", and never an algorithm the key was not meant for
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, head.Alg)
+	}
+}
+
+func (v *Verifier) validateClaims(c claims, now time.Time) error {
+	if c.Subject == "" {
+		return fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+	if c.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if now.After(numericDate(*c.ExpiresAt).Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != nil && now.Add(v.leeway).Before(numericDate(*c.NotBefore)) {
+		return fmt.Errorf("%w: token not valid yet", ErrInvalidToken)
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if v.audience != "" && !slices.Contains(c.Audience, v.audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	return nil
+}
+
+// numericDate converts a JWT NumericDate, in seconds since the epoch
+func numericDate(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
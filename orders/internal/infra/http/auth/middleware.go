@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"orders/internal/infra/http/problem"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Roles carried by the role or roles claim
+const (
+	RoleAdmin    = "admin"
+	RoleCustomer = "customer"
+	RoleService  = "service"
+)
+
+// ErrForbidden means the caller is authenticated but may not act on behalf
+// of the customer it asked for
+var ErrForbidden = errors.New("forbidden")
+
+// Principal is the authenticated caller
+type Principal struct {
+	Subject string
+	Roles   []string
+	// CustomerID is the customer a customer token acts for: the
+	// customer_id claim, or sub when the claim is absent
+	CustomerID string
+}
+
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(p.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrivileged is true for admins and services, who act on any customer
+func (p *Principal) IsPrivileged() bool {
+	return p.HasRole(RoleAdmin, RoleService)
+}
+
+// CanAccessCustomer tells whether the caller may read or change the data of
+// the customer
+func (p *Principal) CanAccessCustomer(customerID string) bool {
+	if p.IsPrivileged() {
+		return true
+	}
+	return p.HasRole(RoleCustomer) && customerID != "" && customerID == p.CustomerID
+}
+
+// ResolveCustomerID returns the customer a new cart or order belongs to.
+// Customers always buy for themselves: an empty customerID becomes theirs
+// and another customer's ID is ErrForbidden.
+func (p *Principal) ResolveCustomerID(customerID string) (string, error) {
+	if p.IsPrivileged() {
+		return customerID, nil
+	}
+	if customerID == "" {
+		customerID = p.CustomerID
+	}
+	if !p.CanAccessCustomer(customerID) {
+		return "", ErrForbidden
+	}
+	return customerID, nil
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the caller stored by Authenticate
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// OwnerFunc returns the customer owning the resource a request refers to.
+// sql.ErrNoRows lets the request through, so the handler answers 404.
+type OwnerFunc func(r *http.Request) (customerID string, err error)
+
+type Middleware struct {
+	verifier *Verifier
+	logger   *slog.Logger
+}
+
+// NewMiddleware authenticates requests with the verifier. A nil verifier
+// disables authentication: every request acts as an admin, which is only
+// meant for local development.
+func NewMiddleware(verifier *Verifier, logger *slog.Logger) *Middleware {
+	return &Middleware{
+		verifier: verifier,
+		logger:   logger,
+	}
+}
+
+// Authenticate requires a valid bearer token and stores its Principal in
+// the request context
+func (m *Middleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.verifier == nil {
+			principal := &Principal{Subject: "anonymous", Roles: []string{RoleAdmin}}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			unauthorized(w, r, ErrMissingToken)
+			return
+		}
+
+		principal, err := m.verifier.Verify(token, time.Now())
+		if err != nil {
+			m.logger.Warn("Rejected token", "path", r.URL.Path, "error", err)
+			unauthorized(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// RequireRole lets through callers with any of the roles
+func (m *Middleware) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFrom(r.Context())
+			if !ok || !principal.HasRole(roles...) {
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden, "Requires role: "+strings.Join(roles, " or ")))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireOwner lets through admins, services and the customer owning the
+// resource
+func (m *Middleware) RequireOwner(owner OwnerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFrom(r.Context())
+			if !ok {
+				forbidden(w, r)
+				return
+			}
+			if principal.IsPrivileged() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			customerID, err := owner(r)
+			if errors.Is(err, sql.ErrNoRows) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				m.logger.Error("Failed to find resource owner", "path", r.URL.Path, "error", err)
+				problem.Write(w, r, problem.New(http.StatusInternalServerError, problem.CodeInternal, "Failed to authorize request"))
+				return
+			}
+			if !principal.CanAccessCustomer(customerID) {
+				m.logger.Warn("Customer denied access", "path", r.URL.Path, "subject", principal.Subject)
+				forbidden(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	code, message := problem.CodeInvalidToken, "Invalid token"
+	switch {
+	case errors.Is(err, ErrMissingToken):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		code, message = problem.CodeMissingToken, "Missing bearer token"
+	case errors.Is(err, ErrTokenExpired):
+		code, message = problem.CodeTokenExpired, "Token expired"
+	}
+	problem.Write(w, r, problem.New(http.StatusUnauthorized, code, message))
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden, ErrForbidden.Error()))
+}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"orders/internal/domain/entity"
+	"orders/internal/infra/http/auth"
+	"orders/internal/infra/http/problem"
 	"orders/internal/usecase"
 
 	"github.com/go-chi/chi/v5"
@@ -22,6 +26,12 @@ func NewCartHandler(cartUseCase *usecase.CartUseCase, logger *slog.Logger) *Cart
 	}
 }
 
+// CreateCartRequest is optional; carts created without a customer are
+// anonymous
+type CreateCartRequest struct {
+	CustomerID string `json:"customer_id,omitempty" example:"3f2b8c1e-6d4a-4f7e-9a2b-1c5d8e9f0a7b"`
+}
+
 type AddItemRequest struct {
 	ProductID string `json:"product_id" example:"550e8400-e29b-41d4-a716-446655440000"`
 	Quantity  int    `json:"quantity" example:"2"`
@@ -31,26 +41,55 @@ type UpdateItemRequest struct {
 	Quantity int `json:"quantity" example:"5"`
 }
 
+type ApplyCouponRequest struct {
+	Code string `json:"code" example:"WELCOME10"`
+}
+
 // CreateCart godoc
 // @Summary Create a new cart
-// @Description Create a new shopping cart (order)
+// @Description Create a new shopping cart (order), optionally linked to a customer. Carts expire when left untouched (expires_at, pushed forward by every change): their reserved stock is released and they move to the expired status.
 // @Tags cart
 // @Accept json
 // @Produce json
+// @Param cart body CreateCartRequest false "Customer who owns the cart; customers can only create their own"
 // @Success 201 {object} entity.Order
-// @Failure 500 {object} ErrorResponse
+// @Header 201 {string} ETag "Version of the cart, to send in If-Match"
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem "Cart for another customer"
+// @Failure 500 {object} problem.Problem
+// @Security BearerAuth
 // @Router /cart [post]
 func (h *CartHandler) CreateCart(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Creating new cart")
 
-	order, err := h.cartUseCase.CreateOrder()
+	// The body is optional: an empty one creates an anonymous cart
+	var req CreateCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error("Failed to decode request", "error", err)
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	// Customers always create carts for themselves
+	customerID := req.CustomerID
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		resolved, err := principal.ResolveCustomerID(req.CustomerID)
+		if err != nil {
+			respondWithError(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden, "Cannot create a cart for another customer"))
+			return
+		}
+		customerID = resolved
+	}
+
+	order, err := h.cartUseCase.CreateOrder(customerID)
 	if err != nil {
-		h.logger.Error("Failed to create cart", "error", err)
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		h.logger.Error("Failed to create cart", "customer_id", customerID, "error", err)
+		respondWithError(w, r, err)
 		return
 	}
 
 	h.logger.Info("Cart created via API", "order_id", order.ID)
+	setETag(w, order)
 	respondWithJSON(w, http.StatusCreated, order)
 }
 
@@ -62,7 +101,9 @@ func (h *CartHandler) CreateCart(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "Cart ID"
 // @Success 200 {object} entity.Order
-// @Failure 404 {object} ErrorResponse
+// @Header 200 {string} ETag "Version of the cart, to send in If-Match"
+// @Failure 404 {object} problem.Problem
+// @Security BearerAuth
 // @Router /cart/{id} [get]
 func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 	orderID := chi.URLParam(r, "id")
@@ -70,11 +111,12 @@ func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 
 	order, err := h.cartUseCase.GetCart(orderID)
 	if err != nil {
-		h.logger.Error("Cart not found", "order_id", orderID, "error", err)
-		respondWithError(w, http.StatusNotFound, "Cart not found")
+		h.logger.Error("Failed to get cart", "order_id", orderID, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCartNotFound, "Cart not found"))
 		return
 	}
 
+	setETag(w, order)
 	respondWithJSON(w, http.StatusOK, order)
 }
 
@@ -85,10 +127,16 @@ func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Cart ID"
+// @Param If-Match header string false "ETag of the cart the change is based on"
 // @Param item body AddItemRequest true "Item to add"
 // @Success 200 {object} entity.Order
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
+// @Header 200 {string} ETag "New version of the cart"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Insufficient stock, the cart was checked out (CART_LOCKED) or changed concurrently"
+// @Failure 410 {object} problem.Problem "The cart expired"
+// @Failure 412 {object} problem.Problem "If-Match does not match the current version"
+// @Security BearerAuth
 // @Router /cart/{id}/items [post]
 func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
 	orderID := chi.URLParam(r, "id")
@@ -97,18 +145,25 @@ func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
 	var req AddItemRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Failed to decode request", "order_id", orderID, "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	order, err := h.cartUseCase.AddItemToCart(orderID, req.ProductID, req.Quantity)
+	order, err := h.cartUseCase.AddItemToCart(orderID, req.ProductID, req.Quantity, version)
 	if err != nil {
 		h.logger.Error("Failed to add item to cart", "order_id", orderID, "product_id", req.ProductID, "error", err)
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCartNotFound, "Cart not found"))
 		return
 	}
 
 	h.logger.Info("Item added to cart via API", "order_id", orderID, "product_id", req.ProductID)
+	setETag(w, order)
 	respondWithJSON(w, http.StatusOK, order)
 }
 
@@ -119,24 +174,37 @@ func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Cart ID"
+// @Param If-Match header string false "ETag of the cart the change is based on"
 // @Param itemId path string true "Item ID"
 // @Success 200 {object} entity.Order
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
+// @Header 200 {string} ETag "New version of the cart"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "The cart was checked out (CART_LOCKED) or changed concurrently"
+// @Failure 410 {object} problem.Problem "The cart expired"
+// @Failure 412 {object} problem.Problem "If-Match does not match the current version"
+// @Security BearerAuth
 // @Router /cart/{id}/items/{itemId} [delete]
 func (h *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
 	orderID := chi.URLParam(r, "id")
 	itemID := chi.URLParam(r, "itemId")
 	h.logger.Info("Removing item from cart", "order_id", orderID, "item_id", itemID)
 
-	order, err := h.cartUseCase.RemoveItemFromCart(orderID, itemID)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	order, err := h.cartUseCase.RemoveItemFromCart(orderID, itemID, version)
 	if err != nil {
 		h.logger.Error("Failed to remove item", "order_id", orderID, "item_id", itemID, "error", err)
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCartNotFound, "Cart not found"))
 		return
 	}
 
 	h.logger.Info("Item removed via API", "order_id", orderID, "item_id", itemID)
+	setETag(w, order)
 	respondWithJSON(w, http.StatusOK, order)
 }
 
@@ -147,11 +215,17 @@ func (h *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Cart ID"
+// @Param If-Match header string false "ETag of the cart the change is based on"
 // @Param itemId path string true "Item ID"
 // @Param item body UpdateItemRequest true "New quantity"
 // @Success 200 {object} entity.Order
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
+// @Header 200 {string} ETag "New version of the cart"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Insufficient stock, the cart was checked out (CART_LOCKED) or changed concurrently"
+// @Failure 410 {object} problem.Problem "The cart expired"
+// @Failure 412 {object} problem.Problem "If-Match does not match the current version"
+// @Security BearerAuth
 // @Router /cart/{id}/items/{itemId} [put]
 func (h *CartHandler) UpdateItemQuantity(w http.ResponseWriter, r *http.Request) {
 	orderID := chi.URLParam(r, "id")
@@ -161,31 +235,127 @@ func (h *CartHandler) UpdateItemQuantity(w http.ResponseWriter, r *http.Request)
 	var req UpdateItemRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Failed to decode request", "order_id", orderID, "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	order, err := h.cartUseCase.UpdateItemQuantity(orderID, itemID, req.Quantity)
+	order, err := h.cartUseCase.UpdateItemQuantity(orderID, itemID, req.Quantity, version)
 	if err != nil {
 		h.logger.Error("Failed to update quantity", "order_id", orderID, "item_id", itemID, "error", err)
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCartNotFound, "Cart not found"))
 		return
 	}
 
 	h.logger.Info("Item quantity updated via API", "order_id", orderID, "item_id", itemID, "quantity", req.Quantity)
+	setETag(w, order)
+	respondWithJSON(w, http.StatusOK, order)
+}
+
+// ApplyCoupon godoc
+// @Summary Apply a coupon to the cart
+// @Description Enter a coupon code in the cart and price it again. The coupon is rejected, and the cart left unchanged, when it is unknown, outside its validity window, past its usage limits, gives the cart no discount or cannot be combined with the other discounts. Applying a coupon again changes nothing. Coupons are checked again at checkout.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param id path string true "Cart ID"
+// @Param If-Match header string false "ETag of the cart the change is based on"
+// @Param coupon body ApplyCouponRequest true "Coupon code"
+// @Success 200 {object} entity.Order
+// @Header 200 {string} ETag "New version of the cart"
+// @Failure 400 {object} problem.Problem "Unknown coupon (COUPON_NOT_FOUND) or outside its validity window (COUPON_NOT_VALID)"
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Coupon past its usage limits, not applicable or not stackable, the cart was checked out (CART_LOCKED) or changed concurrently"
+// @Failure 410 {object} problem.Problem "The cart expired"
+// @Failure 412 {object} problem.Problem "If-Match does not match the current version"
+// @Security BearerAuth
+// @Router /cart/{id}/coupons [post]
+func (h *CartHandler) ApplyCoupon(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "id")
+	h.logger.Info("Applying coupon to cart", "order_id", orderID)
+
+	var req ApplyCouponRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request", "order_id", orderID, "error", err)
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	order, err := h.cartUseCase.ApplyCoupon(orderID, req.Code, version)
+	if err != nil {
+		h.logger.Error("Failed to apply coupon", "order_id", orderID, "code", req.Code, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCartNotFound, "Cart not found"))
+		return
+	}
+
+	h.logger.Info("Coupon applied via API", "order_id", orderID, "code", req.Code)
+	setETag(w, order)
+	respondWithJSON(w, http.StatusOK, order)
+}
+
+// RemoveCoupon godoc
+// @Summary Remove a coupon from the cart
+// @Description Take a coupon code out of the cart and price it again
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param id path string true "Cart ID"
+// @Param If-Match header string false "ETag of the cart the change is based on"
+// @Param code path string true "Coupon code"
+// @Success 200 {object} entity.Order
+// @Header 200 {string} ETag "New version of the cart"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "Cart not found, or the coupon is not in the cart (COUPON_NOT_APPLIED)"
+// @Failure 409 {object} problem.Problem "The cart was checked out (CART_LOCKED) or changed concurrently"
+// @Failure 410 {object} problem.Problem "The cart expired"
+// @Failure 412 {object} problem.Problem "If-Match does not match the current version"
+// @Security BearerAuth
+// @Router /cart/{id}/coupons/{code} [delete]
+func (h *CartHandler) RemoveCoupon(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "id")
+	code := chi.URLParam(r, "code")
+	h.logger.Info("Removing coupon from cart", "order_id", orderID, "code", code)
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	order, err := h.cartUseCase.RemoveCoupon(orderID, code, version)
+	if err != nil {
+		h.logger.Error("Failed to remove coupon", "order_id", orderID, "code", code, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCartNotFound, "Cart not found"))
+		return
+	}
+
+	h.logger.Info("Coupon removed via API", "order_id", orderID, "code", code)
+	setETag(w, order)
 	respondWithJSON(w, http.StatusOK, order)
 }
 
 // CalculateTotal godoc
 // @Summary Calculate cart total
-// @Description Calculate the total amount for payment
+// @Description Calculate the total amount for payment: the subtotal of the items, the line discounts taken off the items, the order discounts taken off what is left, the shipping fee less what free shipping waived, and the discounts that make them up
 // @Tags cart
 // @Accept json
 // @Produce json
 // @Param id path string true "Cart ID"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security BearerAuth
 // @Router /cart/{id}/calculate [get]
 func (h *CartHandler) CalculateTotal(w http.ResponseWriter, r *http.Request) {
 	orderID := chi.URLParam(r, "id")
@@ -194,15 +364,22 @@ func (h *CartHandler) CalculateTotal(w http.ResponseWriter, r *http.Request) {
 	order, err := h.cartUseCase.CalculateTotal(orderID)
 	if err != nil {
 		h.logger.Error("Failed to calculate total", "order_id", orderID, "error", err)
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCartNotFound, "Cart not found"))
 		return
 	}
 
 	response := map[string]interface{}{
-		"order_id": order.ID,
-		"items":    order.Items,
-		"total":    order.Total,
-		"status":   order.Status,
+		"order_id":          order.ID,
+		"items":             order.Items,
+		"subtotal":          order.Subtotal,
+		"line_discount":     order.LineDiscount,
+		"order_discount":    order.OrderDiscount,
+		"shipping":          order.Shipping,
+		"shipping_discount": order.ShippingDiscount,
+		"free_shipping":     order.FreeShipping,
+		"discounts":         order.Discounts,
+		"total":             order.Total,
+		"status":            order.Status,
 	}
 
 	h.logger.Info("Total calculated via API", "order_id", orderID, "total", order.Total)
@@ -211,19 +388,25 @@ func (h *CartHandler) CalculateTotal(w http.ResponseWriter, r *http.Request) {
 
 type UpdateOrderStatusRequest struct {
 	Status entity.OrderStatus `json:"status" example:"paid"`
+	Reason string             `json:"reason,omitempty"`
 }
 
 // UpdateStatus godoc
 // @Summary Update order status
-// @Description Update the status of an order (pending, paid, canceled, completed)
+// @Description Move an order to another status (pending, payment_failed, authorized, paid, shipped, delivered, completed, canceled, refunded, expired). Only the transitions of the order state machine are allowed. Requires the admin role.
 // @Tags cart
 // @Accept json
 // @Produce json
 // @Param id path string true "Cart ID"
+// @Param If-Match header string false "ETag of the cart the change is based on"
 // @Param status body UpdateOrderStatusRequest true "New status"
 // @Success 200 {object} entity.Order
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
+// @Header 200 {string} ETag "New version of the cart"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Transition not allowed from the current status, or the cart was changed concurrently"
+// @Failure 412 {object} problem.Problem "If-Match does not match the current version"
+// @Security BearerAuth
 // @Router /cart/{id}/status [put]
 func (h *CartHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	orderID := chi.URLParam(r, "id")
@@ -232,24 +415,24 @@ func (h *CartHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	var req UpdateOrderStatusRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Failed to decode request", "order_id", orderID, "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		respondWithError(w, r, problem.InvalidBody(err))
 		return
 	}
 
-	// Get order through cart use case and update status
-	order, err := h.cartUseCase.GetCart(orderID)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		h.logger.Error("Order not found", "order_id", orderID, "error", err)
-		respondWithError(w, http.StatusNotFound, "Order not found")
+		respondWithError(w, r, err)
 		return
 	}
 
-	if err := order.UpdateStatus(req.Status); err != nil {
+	order, err := h.cartUseCase.UpdateStatus(orderID, req.Status, req.Reason, version)
+	if err != nil {
 		h.logger.Error("Failed to update status", "order_id", orderID, "status", req.Status, "error", err)
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeOrderNotFound, "Order not found"))
 		return
 	}
 
 	h.logger.Info("Order status updated via API", "order_id", orderID, "status", req.Status)
+	setETag(w, order)
 	respondWithJSON(w, http.StatusOK, order)
 }
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"orders/internal/domain/entity"
+	"orders/internal/infra/http/problem"
+	"orders/internal/usecase"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CustomerHandler struct {
+	customerUseCase *usecase.CustomerUseCase
+	logger          *slog.Logger
+}
+
+func NewCustomerHandler(customerUseCase *usecase.CustomerUseCase, logger *slog.Logger) *CustomerHandler {
+	return &CustomerHandler{
+		customerUseCase: customerUseCase,
+		logger:          logger,
+	}
+}
+
+// CustomerRequest is used both to create and to update a customer; on
+// update the addresses sent replace the saved ones
+type CustomerRequest struct {
+	Name      string           `json:"name" example:"Maria Silva"`
+	Email     string           `json:"email" example:"maria@example.com"`
+	Document  string           `json:"document,omitempty" example:"529.982.247-25"` // CPF
+	Phone     string           `json:"phone,omitempty" example:"+55 11 98765-4321"`
+	Addresses []AddressRequest `json:"addresses,omitempty"`
+}
+
+type AddressRequest struct {
+	Label      string `json:"label,omitempty" example:"casa"`
+	Street     string `json:"street" example:"Avenida Paulista"`
+	Number     string `json:"number" example:"1000"`
+	Complement string `json:"complement,omitempty" example:"Apto 42"`
+	District   string `json:"district,omitempty" example:"Bela Vista"`
+	City       string `json:"city" example:"São Paulo"`
+	State      string `json:"state" example:"SP"`
+	ZipCode    string `json:"zip_code" example:"01310-100"`
+}
+
+func (req CustomerRequest) toInput() usecase.CustomerInput {
+	addresses := make([]entity.Address, len(req.Addresses))
+	for i, address := range req.Addresses {
+		addresses[i] = entity.Address{
+			Label:      address.Label,
+			Street:     address.Street,
+			Number:     address.Number,
+			Complement: address.Complement,
+			District:   address.District,
+			City:       address.City,
+			State:      address.State,
+			ZipCode:    address.ZipCode,
+		}
+	}
+
+	return usecase.CustomerInput{
+		Name:      req.Name,
+		Email:     req.Email,
+		Document:  req.Document,
+		Phone:     req.Phone,
+		Addresses: addresses,
+	}
+}
+
+// Create godoc
+// @Summary Create a new customer
+// @Description Create a customer with name, email, optional CPF and phone, and delivery addresses. Requires the admin or service role.
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param customer body CustomerRequest true "Customer data"
+// @Success 201 {object} entity.Customer
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Email or CPF already used by another customer"
+// @Security BearerAuth
+// @Router /customers [post]
+func (h *CustomerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request", "error", err)
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	customer, err := h.customerUseCase.CreateCustomer(req.toInput())
+	if err != nil {
+		h.logger.Error("Failed to create customer", "error", err)
+		respondWithError(w, r, err)
+		return
+	}
+
+	h.logger.Info("Customer created via API", "customer_id", customer.ID)
+	respondWithJSON(w, http.StatusCreated, customer)
+}
+
+// GetByID godoc
+// @Summary Get customer by ID
+// @Description Get a single customer, with their addresses, by ID
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Success 200 {object} entity.Customer
+// @Failure 404 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id} [get]
+func (h *CustomerHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	h.logger.Info("Getting customer by ID", "customer_id", id)
+
+	customer, err := h.customerUseCase.GetCustomer(id)
+	if err != nil {
+		h.logger.Error("Failed to get customer", "customer_id", id, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCustomerNotFound, "Customer not found"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, customer)
+}
+
+// List godoc
+// @Summary List all customers
+// @Description Get a list of all customers, newest first. Requires the admin role.
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Success 200 {array} entity.Customer
+// @Failure 500 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers [get]
+func (h *CustomerHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Listing all customers")
+
+	customers, err := h.customerUseCase.ListCustomers()
+	if err != nil {
+		h.logger.Error("Failed to list customers", "error", err)
+		respondWithError(w, r, err)
+		return
+	}
+
+	if len(customers) == 0 {
+		respondWithJSON(w, http.StatusOK, []interface{}{})
+		return
+	}
+
+	h.logger.Info("Customers listed successfully", "count", len(customers))
+	respondWithJSON(w, http.StatusOK, customers)
+}
+
+// Update godoc
+// @Summary Update a customer
+// @Description Replace the data of an existing customer; the addresses sent replace the saved ones
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param customer body CustomerRequest true "Updated customer data"
+// @Success 200 {object} entity.Customer
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Email or CPF already used by another customer"
+// @Security BearerAuth
+// @Router /customers/{id} [put]
+func (h *CustomerHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	h.logger.Info("Updating customer", "customer_id", id)
+
+	var req CustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request", "customer_id", id, "error", err)
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	customer, err := h.customerUseCase.UpdateCustomer(id, req.toInput())
+	if err != nil {
+		h.logger.Error("Failed to update customer", "customer_id", id, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCustomerNotFound, "Customer not found"))
+		return
+	}
+
+	h.logger.Info("Customer updated via API", "customer_id", id)
+	respondWithJSON(w, http.StatusOK, customer)
+}
+
+// Delete godoc
+// @Summary Delete a customer
+// @Description Delete a customer and their addresses. Customers with orders cannot be deleted, so the order history is kept. Requires the admin role.
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Success 204
+// @Failure 409 {object} problem.Problem "Customer has orders"
+// @Failure 500 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id} [delete]
+func (h *CustomerHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	h.logger.Info("Deleting customer", "customer_id", id)
+
+	err := h.customerUseCase.DeleteCustomer(id)
+	if err != nil {
+		h.logger.Error("Failed to delete customer", "customer_id", id, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCustomerNotFound, "Customer not found"))
+		return
+	}
+
+	h.logger.Info("Customer deleted via API", "customer_id", id)
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// ListOrders godoc
+// @Summary List customer orders
+// @Description Get the order history of a customer, newest first, including carts still open
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Success 200 {array} entity.Order
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id}/orders [get]
+func (h *CustomerHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	h.logger.Info("Listing customer orders", "customer_id", id)
+
+	orders, err := h.customerUseCase.ListOrders(id)
+	if err != nil {
+		h.logger.Error("Failed to list customer orders", "customer_id", id, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCustomerNotFound, "Customer not found"))
+		return
+	}
+
+	if len(orders) == 0 {
+		respondWithJSON(w, http.StatusOK, []interface{}{})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, orders)
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+	"orders/internal/domain/entity"
+	"orders/internal/usecase"
+	"strconv"
+	"strings"
+)
+
+// setETag tags the response with the version of the order, to be sent back
+// in If-Match by the next change
+func setETag(w http.ResponseWriter, order *entity.Order) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(order.Version)))
+}
+
+// ifMatchVersion reads the version a change is based on from If-Match.
+// Without the header, or with "*", any version is accepted. ETags are
+// compared strongly, so weak or unknown tags never match.
+func ifMatchVersion(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return usecase.AnyVersion, nil
+	}
+
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return 0, entity.ErrOrderVersionMismatch
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 0 {
+		return 0, entity.ErrOrderVersionMismatch
+	}
+	return version, nil
+}
@@ -3,6 +3,9 @@ package handler
 import (
 	"log/slog"
 	"net/http"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"orders/internal/infra/http/problem"
 	"orders/internal/usecase"
 
 	"github.com/go-chi/chi/v5"
@@ -28,7 +31,8 @@ func NewOrderHandler(orderUseCase *usecase.OrderUseCase, logger *slog.Logger) *O
 // @Produce json
 // @Param id path string true "Order ID"
 // @Success 200 {object} entity.Order
-// @Failure 404 {object} ErrorResponse
+// @Failure 404 {object} problem.Problem
+// @Security BearerAuth
 // @Router /orders/{id} [get]
 func (h *OrderHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -36,47 +40,115 @@ func (h *OrderHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	order, err := h.orderUseCase.GetOrder(id)
 	if err != nil {
-		h.logger.Error("Order not found", "order_id", id, "error", err)
-		respondWithError(w, http.StatusNotFound, "Order not found")
+		h.logger.Error("Failed to get order", "order_id", id, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeOrderNotFound, "Order not found"))
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, order)
 }
 
+// History godoc
+// @Summary Get order status history
+// @Description Get every status transition of an order, oldest first, with who made it and why
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {array} entity.StatusChange
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security BearerAuth
+// @Router /orders/{id}/history [get]
+func (h *OrderHandler) History(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	h.logger.Info("Getting order status history", "order_id", id)
+
+	history, err := h.orderUseCase.GetStatusHistory(id)
+	if err != nil {
+		h.logger.Error("Failed to get order status history", "order_id", id, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeOrderNotFound, "Order not found"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, history)
+}
+
+// OrderListResponse is a page of orders; next_page_token is omitted on the
+// last page
+type OrderListResponse struct {
+	Items         []entity.Order `json:"items"`
+	NextPageToken string         `json:"next_page_token,omitempty" example:"eyJjIjoiMjAyNS0wNi0wMVQxMjowMDowMFoiLCJpIjoiNTUwZTg0MDAifQ"`
+}
+
 // List godoc
-// @Summary List all orders
-// @Description Get a list of all orders
+// @Summary List orders
+// @Description Get a page of orders, newest first by default, with optional filters. Pages are keyset based: pass the next_page_token of a response as page_token, keeping the other parameters, to get the next page; the Link header carries the same URL with rel="next". Requires the admin or service role.
 // @Tags orders
 // @Accept json
 // @Produce json
-// @Success 200 {array} entity.Order
-// @Failure 500 {object} ErrorResponse
+// @Param status query string false "Order status" Enums(pending, payment_failed, authorized, paid, shipped, delivered, completed, canceled, refunded, expired)
+// @Param customer_id query string false "Customer ID"
+// @Param created_from query string false "Created at or after (RFC 3339)" example(2025-06-01T00:00:00Z)
+// @Param created_to query string false "Created before (RFC 3339)" example(2025-07-01T00:00:00Z)
+// @Param min_total query string false "Minimum total, in major units" example(10.50)
+// @Param max_total query string false "Maximum total, in major units" example(500.00)
+// @Param sort query string false "Sort order" Enums(-created_at, created_at) default(-created_at)
+// @Param page_size query int false "Page size (1-100)" default(20)
+// @Param page_token query string false "next_page_token of the previous page"
+// @Success 200 {object} OrderListResponse
+// @Header 200 {string} Link "URL of the next page, rel=\"next\""
+// @Failure 400 {object} problem.Problem "Invalid filter, sort, page size or page token"
+// @Failure 500 {object} problem.Problem
+// @Security BearerAuth
 // @Router /orders [get]
 func (h *OrderHandler) List(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Listing all orders")
+	query := newQueryParser(r)
+	filter := repository.OrderFilter{
+		Status:      entity.OrderStatus(query.query.Get("status")),
+		CustomerID:  query.query.Get("customer_id"),
+		MinTotal:    query.amount("min_total"),
+		MaxTotal:    query.amount("max_total"),
+		CreatedFrom: query.time("created_from"),
+		CreatedTo:   query.time("created_to"),
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		query.invalid("status", entity.ErrInvalidOrderStatus.Error())
+	}
+	options := query.listOptions()
+	if err := query.err(); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
 
-	orders, err := h.orderUseCase.ListOrders()
+	h.logger.Info("Listing orders", "status", filter.Status, "customer_id", filter.CustomerID)
+
+	orders, next, err := h.orderUseCase.ListOrders(filter, options)
 	if err != nil {
 		h.logger.Error("Failed to list orders", "error", err)
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
+	if orders == nil {
+		orders = []entity.Order{}
+	}
 
 	h.logger.Info("Orders listed successfully", "count", len(orders))
-	respondWithJSON(w, http.StatusOK, orders)
+	setNextLink(w, r, next)
+	respondWithJSON(w, http.StatusOK, OrderListResponse{Items: orders, NextPageToken: next})
 }
 
 // Delete godoc
 // @Summary Delete an order
-// @Description Delete an order by ID
+// @Description Delete an order by ID. Requires the admin role.
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Param id path string true "Order ID"
 // @Success 204
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security BearerAuth
 // @Router /orders/{id} [delete]
 func (h *OrderHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -85,7 +157,7 @@ func (h *OrderHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	err := h.orderUseCase.DeleteOrder(id)
 	if err != nil {
 		h.logger.Error("Failed to delete order", "order_id", id, "error", err)
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeOrderNotFound, "Order not found"))
 		return
 	}
 
@@ -1,84 +1,188 @@
 package handler
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
+	"orders/internal/domain/entity"
+	"orders/internal/infra/http/auth"
+	"orders/internal/infra/http/problem"
 	"orders/internal/usecase"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type OrderWithPaymentHandler struct {
-	createOrderUseCase *usecase.CreateOrderUseCase
-	cancelOrderUseCase *usecase.CancelOrderUseCase
-	logger             *slog.Logger
+	createOrderUseCase  *usecase.CreateOrderUseCase
+	cancelOrderUseCase  *usecase.CancelOrderUseCase
+	refundOrderUseCase  *usecase.RefundOrderUseCase
+	updateStatusUseCase *usecase.UpdateOrderStatusUseCase
+	boletoSlipUseCase   *usecase.BoletoSlipUseCase
+	logger              *slog.Logger
 }
 
 func NewOrderWithPaymentHandler(
 	createOrderUseCase *usecase.CreateOrderUseCase,
 	cancelOrderUseCase *usecase.CancelOrderUseCase,
+	refundOrderUseCase *usecase.RefundOrderUseCase,
+	updateStatusUseCase *usecase.UpdateOrderStatusUseCase,
+	boletoSlipUseCase *usecase.BoletoSlipUseCase,
 	logger *slog.Logger,
 ) *OrderWithPaymentHandler {
 	return &OrderWithPaymentHandler{
-		createOrderUseCase: createOrderUseCase,
-		cancelOrderUseCase: cancelOrderUseCase,
-		logger:             logger,
+		createOrderUseCase:  createOrderUseCase,
+		cancelOrderUseCase:  cancelOrderUseCase,
+		refundOrderUseCase:  refundOrderUseCase,
+		updateStatusUseCase: updateStatusUseCase,
+		boletoSlipUseCase:   boletoSlipUseCase,
+		logger:              logger,
 	}
 }
 
 type CreateOrderWithPaymentRequest struct {
+	// Cliente cadastrado dono do pedido; sem ele, customer_email e
+	// customer_name são obrigatórios
+	CustomerID    string             `json:"customer_id,omitempty" example:"3f2b8c1e-6d4a-4f7e-9a2b-1c5d8e9f0a7b"`
 	CustomerEmail string             `json:"customer_email"`
 	CustomerName  string             `json:"customer_name"`
 	Items         []OrderItemRequest `json:"items"`
 	PaymentMethod int32              `json:"payment_method"` // 1=CREDIT_CARD, 2=DEBIT_CARD, 3=PIX, 4=BOLETO, 5=PAYPAL
+	// Dados do cartão, chave PIX ou boleto; repassados ao payments service,
+	// que guarda apenas os dados seguros (final do cartão, bandeira,
+	// documento mascarado)
+	PaymentDetails *PaymentDetailsRequest `json:"payment_details,omitempty"`
+}
+
+// PaymentDetailsRequest leva um (e apenas um) de card, pix ou boleto, de
+// acordo com o payment_method
+type PaymentDetailsRequest struct {
+	Card   *CardDetailsRequest   `json:"card,omitempty"`
+	Pix    *PixDetailsRequest    `json:"pix,omitempty"`
+	Boleto *BoletoDetailsRequest `json:"boleto,omitempty"`
+}
+
+type CardDetailsRequest struct {
+	Number     string `json:"number" example:"4111111111111111"`
+	HolderName string `json:"holder_name" example:"Maria Silva"`
+	ExpiryDate string `json:"expiry_date" example:"12/30"` // MM/YY
+	CVV        string `json:"cvv" example:"123"`
+}
+
+type PixDetailsRequest struct {
+	Key string `json:"key" example:"maria@example.com"` // CPF, CNPJ, email, telefone (+55...) ou chave aleatória
+}
+
+type BoletoDetailsRequest struct {
+	Document string `json:"document" example:"529.982.247-25"`       // CPF ou CNPJ do pagador
+	DueDate  string `json:"due_date,omitempty" example:"2030-01-31"` // YYYY-MM-DD; opcional
 }
 
 type OrderItemRequest struct {
-	ProductID string  `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	// Preço que o cliente viu; opcional. Se enviado, precisa ser o preço
+	// atual do catálogo, que é sempre o preço cobrado
+	Price *entity.Money `json:"price,omitempty"`
 }
 
 type CreateOrderWithPaymentResponse struct {
-	OrderID   string  `json:"order_id"`
-	Total     float64 `json:"total"`
-	Status    string  `json:"status"`
-	PaymentID string  `json:"payment_id"`
+	OrderID   string       `json:"order_id"`
+	Total     entity.Money `json:"total"`
+	Status    string       `json:"status"`
+	PaymentID string       `json:"payment_id"`
+	// Pix e Boleto vêm preenchidos enquanto o pedido aguarda o pagamento
+	Pix    *PixChargeResponse    `json:"pix,omitempty"`
+	Boleto *BoletoChargeResponse `json:"boleto,omitempty"`
 }
 
+// PixChargeResponse é o que o cliente precisa para pagar o pedido pelo app
+// do banco
+type PixChargeResponse struct {
+	QRCode      string    `json:"qr_code" example:"00020101021226..."`                      // BR Code "copia e cola"
+	QRCodeImage string    `json:"qr_code_image" example:"data:image/png;base64,iVBORw0..."` // pode ser usado direto em <img src>
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// BoletoChargeResponse é o boleto a ser pago; slip_url leva à versão para
+// impressão
+type BoletoChargeResponse struct {
+	Number        string    `json:"number" example:"12345670000000001"`                                       // nosso número
+	DigitableLine string    `json:"digitable_line" example:"00190000090123456700400000001172911290000015050"` // linha digitável
+	Barcode       string    `json:"barcode" example:"00199112900000150500000001234567000000000117"`
+	DueDate       string    `json:"due_date" example:"2025-07-01"`
+	ExpiresAt     time.Time `json:"expires_at"` // vencimento mais a carência; depois disso o pedido é cancelado
+	SlipURL       string    `json:"slip_url" example:"/api/v1/orders/uuid-do-pedido/boleto"`
+}
+
+// maxIdempotencyKeyLength matches the orders.idempotency_key column
+const maxIdempotencyKeyLength = 255
+
 // CreateOrderWithPayment godoc
 // @Summary Create order with payment processing
-// @Description Creates a new order and processes payment via gRPC
+// @Description Creates a new order and processes payment via gRPC. Card, PIX or boleto data go in payment_details and are validated by the payments service, which stores only safe fields (last four digits, brand, masked document); invalid details cancel the order and return 400. PIX and boleto orders stay pending and the response carries what the customer needs to pay them: the QR code (pix) or the digitable line and slip URL (boleto, which requires payment_details.boleto with the payer document). The order is paid once the payment is confirmed, or canceled when the charge expires; boletos expire after the due date plus a grace period. Send an Idempotency-Key header to retry safely: a repeated request from the same customer returns the original order and payment, with the Idempotent-Replayed header set, instead of charging again; keys are scoped to the customer, so another customer sending the same key gets an order of their own. Send customer_id to link the order to a registered customer; their email and name are used when customer_email and customer_name are omitted. Items are charged at the catalog price: unknown or inactive products are rejected, and an item price, when sent, must match the catalog price; every rejected item is listed in errors. With PRODUCT_QUICK_ADD enabled, admins may order unknown products, which are created with the price sent once no item is rejected.
 // @Tags orders
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Unique key for safely retrying the request"
 // @Param request body CreateOrderWithPaymentRequest true "Order and Payment Info"
 // @Success 201 {object} CreateOrderWithPaymentResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Header 201 {string} Idempotent-Replayed "true when the response comes from an earlier request with the same key"
+// @Failure 400 {object} problem.Problem "Invalid request, unknown customer_id, items that cannot be sold (unknown or inactive product, price different from the catalog) or payment details (card, PIX key, document, due date)"
+// @Failure 403 {object} problem.Problem "Order for another customer"
+// @Failure 409 {object} problem.Problem "Insufficient stock"
+// @Failure 422 {object} problem.Problem "Idempotency key reused with a different request"
+// @Failure 500 {object} problem.Problem
+// @Failure 503 {object} problem.Problem "Payment service unavailable, retry with the same Idempotency-Key"
+// @Security BearerAuth
 // @Router /orders/with-payment [post]
 func (h *OrderWithPaymentHandler) CreateOrderWithPayment(w http.ResponseWriter, r *http.Request) {
 	var req CreateOrderWithPaymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Failed to decode request", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		respondWithError(w, r, problem.InvalidBody(err))
 		return
 	}
 
-	// Validar request
-	if req.CustomerEmail == "" {
-		respondWithError(w, http.StatusBadRequest, "Customer email is required")
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		respondWithError(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidIdempotencyKey, "Idempotency-Key must be at most 255 characters"))
 		return
 	}
-	if req.CustomerName == "" {
-		respondWithError(w, http.StatusBadRequest, "Customer name is required")
-		return
+
+	// Clientes só compram para si mesmos; o cadastro rápido de produtos é
+	// só para admins
+	var quickAdd bool
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		customerID, err := principal.ResolveCustomerID(req.CustomerID)
+		if err != nil {
+			respondWithError(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden, "Cannot create an order for another customer"))
+			return
+		}
+		req.CustomerID = customerID
+		quickAdd = principal.HasRole(auth.RoleAdmin)
+	}
+
+	// Validar request, reportando todos os campos inválidos de uma vez;
+	// clientes cadastrados já têm email e nome
+	var violations []entity.FieldViolation
+	if req.CustomerID == "" && req.CustomerEmail == "" {
+		violations = append(violations, problem.Field("customer_email", "Customer email is required"))
+	}
+	if req.CustomerID == "" && req.CustomerName == "" {
+		violations = append(violations, problem.Field("customer_name", "Customer name is required"))
 	}
 	if len(req.Items) == 0 {
-		respondWithError(w, http.StatusBadRequest, "At least one item is required")
-		return
+		violations = append(violations, problem.Field("items", "At least one item is required"))
 	}
 	if req.PaymentMethod < 1 || req.PaymentMethod > 5 {
-		respondWithError(w, http.StatusBadRequest, "Invalid payment method (1-5)")
+		violations = append(violations, problem.Field("payment_method", "Invalid payment method (1-5)"))
+	}
+	if len(violations) > 0 {
+		respondWithError(w, r, problem.Invalid(violations...))
 		return
 	}
 
@@ -92,70 +196,384 @@ func (h *OrderWithPaymentHandler) CreateOrderWithPayment(w http.ResponseWriter,
 		}
 	}
 
+	paymentDetails, err := convertPaymentDetails(req.PaymentDetails)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
 	// Executar use case
 	input := usecase.CreateOrderInput{
-		CustomerEmail: req.CustomerEmail,
-		CustomerName:  req.CustomerName,
-		Items:         items,
-		PaymentMethod: req.PaymentMethod,
+		CustomerID:       req.CustomerID,
+		CustomerEmail:    req.CustomerEmail,
+		CustomerName:     req.CustomerName,
+		Items:            items,
+		PaymentMethod:    req.PaymentMethod,
+		IdempotencyKey:   idempotencyKey,
+		PaymentDetails:   paymentDetails,
+		QuickAddProducts: quickAdd,
 	}
 
 	output, err := h.createOrderUseCase.Execute(r.Context(), input)
 	if err != nil {
 		h.logger.Error("Failed to create order with payment", "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create order: "+err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
+	response := newOrderWithPaymentResponse(output)
+
+	if output.Replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+// newOrderWithPaymentResponse monta a resposta de um pedido que passou pelo
+// checkout, com a cobrança PIX ou boleto que ainda aguarda pagamento
+func newOrderWithPaymentResponse(output *usecase.CreateOrderOutput) CreateOrderWithPaymentResponse {
 	response := CreateOrderWithPaymentResponse{
 		OrderID:   output.OrderID,
 		Total:     output.Total,
 		Status:    output.Status,
 		PaymentID: output.PaymentID,
 	}
+	if output.Pix != nil {
+		response.Pix = &PixChargeResponse{
+			QRCode:    output.Pix.QRCode,
+			ExpiresAt: output.Pix.ExpiresAt,
+		}
+		if len(output.Pix.QRCodePNG) > 0 {
+			response.Pix.QRCodeImage = "data:image/png;base64," + base64.StdEncoding.EncodeToString(output.Pix.QRCodePNG)
+		}
+	}
+	if output.Boleto != nil {
+		response.Boleto = &BoletoChargeResponse{
+			Number:        output.Boleto.Number,
+			DigitableLine: output.Boleto.DigitableLine,
+			Barcode:       output.Boleto.Barcode,
+			DueDate:       output.Boleto.DueDate.Format(time.DateOnly),
+			ExpiresAt:     output.Boleto.ExpiresAt,
+			SlipURL:       "/api/v1/orders/" + output.OrderID + "/boleto",
+		}
+	}
+	return response
+}
 
-	respondWithJSON(w, http.StatusCreated, response)
+type CheckoutCartRequest struct {
+	// Obrigatórios em carrinhos anônimos; nos de clientes cadastrados,
+	// substituem o email e o nome do cadastro
+	CustomerEmail  string                 `json:"customer_email,omitempty"`
+	CustomerName   string                 `json:"customer_name,omitempty"`
+	PaymentMethod  int32                  `json:"payment_method"` // 1=CREDIT_CARD, 2=DEBIT_CARD, 3=PIX, 4=BOLETO, 5=PAYPAL
+	PaymentDetails *PaymentDetailsRequest `json:"payment_details,omitempty"`
+}
+
+// PriceChangeResponse é um item do carrinho cujo preço mudou no catálogo
+// desde que foi adicionado; new_price é o preço cobrado
+type PriceChangeResponse struct {
+	ItemID    string       `json:"item_id"`
+	ProductID string       `json:"product_id"`
+	OldPrice  entity.Money `json:"old_price"`
+	NewPrice  entity.Money `json:"new_price"`
+}
+
+type CheckoutCartResponse struct {
+	CreateOrderWithPaymentResponse
+	PriceChanges []PriceChangeResponse `json:"price_changes"`
+}
+
+// CheckoutCart godoc
+// @Summary Check out cart
+// @Description Turns a cart into a paid order. The cart is locked (its items can no longer change), every item is re-priced at the current catalog price and the order is charged through the payments service with the stock the cart already reserved. The same order moves to paid, or to canceled when the payment is declined; PIX and boleto orders stay pending and the response carries the charge to pay, as in /orders/with-payment. price_changes lists the items whose catalog price changed since they were added to the cart. Carts of registered customers use their email and name unless customer_email and customer_name are sent; anonymous carts require them. Send If-Match to check out only the version of the cart the customer reviewed.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param id path string true "Cart ID"
+// @Param If-Match header string false "ETag of the cart the customer reviewed"
+// @Param request body CheckoutCartRequest true "Payment method, payment details and, for anonymous carts, the customer"
+// @Success 200 {object} CheckoutCartResponse
+// @Failure 400 {object} problem.Problem "Invalid request, empty cart, items that can no longer be sold (removed or inactive product) or invalid payment details"
+// @Failure 404 {object} problem.Problem "Cart not found"
+// @Failure 409 {object} problem.Problem "Cart already checked out (CART_LOCKED), changed concurrently or insufficient stock"
+// @Failure 410 {object} problem.Problem "The cart expired"
+// @Failure 412 {object} problem.Problem "If-Match does not match the current version"
+// @Failure 500 {object} problem.Problem
+// @Failure 503 {object} problem.Problem "Payment service unavailable, the checkout is retried in the background"
+// @Security BearerAuth
+// @Router /cart/{id}/checkout [post]
+func (h *OrderWithPaymentHandler) CheckoutCart(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "id")
+
+	var req CheckoutCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request", "error", err)
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	if req.PaymentMethod < 1 || req.PaymentMethod > 5 {
+		respondWithError(w, r, problem.Invalid(problem.Field("payment_method", "Invalid payment method (1-5)")))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	paymentDetails, err := convertPaymentDetails(req.PaymentDetails)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	input := usecase.CheckoutCartInput{
+		OrderID:        orderID,
+		Version:        version,
+		CustomerEmail:  req.CustomerEmail,
+		CustomerName:   req.CustomerName,
+		PaymentMethod:  req.PaymentMethod,
+		PaymentDetails: paymentDetails,
+	}
+
+	output, err := h.createOrderUseCase.CheckoutCart(r.Context(), input)
+	if err != nil {
+		h.logger.Error("Failed to check out cart", "error", err, "order_id", orderID)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeCartNotFound, "Cart not found"))
+		return
+	}
+
+	response := CheckoutCartResponse{
+		CreateOrderWithPaymentResponse: newOrderWithPaymentResponse(&output.CreateOrderOutput),
+		PriceChanges:                   make([]PriceChangeResponse, len(output.PriceChanges)),
+	}
+	for i, change := range output.PriceChanges {
+		response.PriceChanges[i] = PriceChangeResponse{
+			ItemID:    change.ItemID,
+			ProductID: change.ProductID,
+			OldPrice:  change.OldPrice,
+			NewPrice:  change.NewPrice,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// convertPaymentDetails converte os dados de pagamento da requisição; a
+// data de vencimento do boleto vem como YYYY-MM-DD
+func convertPaymentDetails(req *PaymentDetailsRequest) (*entity.PaymentDetails, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	details := &entity.PaymentDetails{}
+	if req.Card != nil {
+		details.Card = &entity.CardDetails{
+			Number:     req.Card.Number,
+			HolderName: req.Card.HolderName,
+			ExpiryDate: req.Card.ExpiryDate,
+			CVV:        req.Card.CVV,
+		}
+	}
+	if req.Pix != nil {
+		details.Pix = &entity.PixDetails{Key: req.Pix.Key}
+	}
+	if req.Boleto != nil {
+		details.Boleto = &entity.BoletoDetails{Document: req.Boleto.Document}
+		if req.Boleto.DueDate != "" {
+			dueDate, err := time.Parse(time.DateOnly, req.Boleto.DueDate)
+			if err != nil {
+				return nil, problem.Invalid(problem.Field("payment_details.boleto.due_date", "boleto due_date must be YYYY-MM-DD"))
+			}
+			details.Boleto.DueDate = &dueDate
+		}
+	}
+	return details, nil
+}
+
+// BoletoSlip godoc
+// @Summary Get order boleto slip
+// @Description Returns the printable boleto of an order as an HTML page, with the digitable line and the barcode. It can be printed or saved as PDF by the browser.
+// @Tags orders
+// @Produce html
+// @Param id path string true "Order ID"
+// @Success 200 {string} string "HTML boleto slip"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "Order not found or not paid with boleto"
+// @Failure 500 {object} problem.Problem
+// @Failure 503 {object} problem.Problem "Payment service unavailable"
+// @Security BearerAuth
+// @Router /orders/{id}/boleto [get]
+func (h *OrderWithPaymentHandler) BoletoSlip(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "id")
+
+	slip, err := h.boletoSlipUseCase.Execute(r.Context(), orderID)
+	if err != nil {
+		h.logger.Error("Failed to get boleto slip", "error", err, "order_id", orderID)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeOrderNotFound, "Order not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", slip.ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(slip.Content)
 }
 
 type CancelOrderRequest struct {
-	PaymentID string `json:"payment_id"`
+	Reason string `json:"reason"`
 }
 
 // CancelOrder godoc
 // @Summary Cancel order and payment
-// @Description Cancels an order and its associated payment via gRPC
+// @Description Cancels an order by undoing its checkout: the payment is refunded (if approved) or canceled (if still processing) via gRPC, then the reserved stock is released and the order is marked canceled. When the payment cannot be undone right away the order keeps its status, 202 is returned and the cancellation is retried in the background.
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Param id path string true "Order ID"
-// @Param request body CancelOrderRequest true "Payment ID"
+// @Param request body CancelOrderRequest false "Optional cancellation reason"
 // @Success 200 {object} MessageResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Success 202 {object} MessageResponse "Cancellation in progress"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Order can no longer be canceled (e.g. shipped)"
+// @Failure 500 {object} problem.Problem
+// @Failure 503 {object} problem.Problem "Payment service unavailable"
+// @Security BearerAuth
 // @Router /orders/{id}/cancel [post]
 func (h *OrderWithPaymentHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
-	orderID := r.PathValue("id")
-	if orderID == "" {
-		respondWithError(w, http.StatusBadRequest, "Order ID is required")
-		return
-	}
+	orderID := chi.URLParam(r, "id")
 
+	// O corpo é opcional
 	var req CancelOrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
 		h.logger.Error("Failed to decode request", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		respondWithError(w, r, problem.InvalidBody(err))
 		return
 	}
 
-	if err := h.cancelOrderUseCase.Execute(r.Context(), orderID, req.PaymentID); err != nil {
+	if err := h.cancelOrderUseCase.Execute(r.Context(), orderID, req.Reason); err != nil {
+		if errors.Is(err, entity.ErrCancellationPending) {
+			respondWithJSON(w, http.StatusAccepted, MessageResponse{Message: "Order cancellation is in progress"})
+			return
+		}
 		h.logger.Error("Failed to cancel order", "error", err, "order_id", orderID)
-		respondWithError(w, http.StatusInternalServerError, "Failed to cancel order: "+err.Error())
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeOrderNotFound, "Order not found"))
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, MessageResponse{Message: "Order canceled successfully"})
 }
 
+type RefundOrderRequest struct {
+	PaymentID string        `json:"payment_id"`
+	Amount    *entity.Money `json:"amount,omitempty"` // omitido reembolsa todo o saldo restante
+	Reason    string        `json:"reason"`
+}
+
+type RefundOrderResponse struct {
+	RefundID        string       `json:"refund_id"`
+	PaymentID       string       `json:"payment_id"`
+	PaymentStatus   string       `json:"payment_status"`
+	Amount          entity.Money `json:"amount"`
+	RefundedAmount  entity.Money `json:"refunded_amount"`
+	RemainingAmount entity.Money `json:"remaining_amount"`
+}
+
+// RefundOrder godoc
+// @Summary Refund order payment
+// @Description Refunds all or part of an order payment via gRPC. Partial refunds may be repeated until the paid amount is reached; omit amount to refund everything left. Requires the admin or service role.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param request body RefundOrderRequest true "Payment ID, optional amount and reason"
+// @Success 200 {object} RefundOrderResponse
+// @Failure 400 {object} problem.Problem "Invalid amount; errors lists what the payments service refused"
+// @Failure 404 {object} problem.Problem "Order or payment not found"
+// @Failure 409 {object} problem.Problem "Payment cannot be refunded in its current status (code PAYMENT_NOT_REFUNDABLE)"
+// @Failure 500 {object} problem.Problem
+// @Failure 503 {object} problem.Problem "Payment service unavailable"
+// @Security BearerAuth
+// @Router /orders/{id}/refund [post]
+func (h *OrderWithPaymentHandler) RefundOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "id")
+
+	var req RefundOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request", "error", err)
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	if req.PaymentID == "" {
+		respondWithError(w, r, problem.Invalid(problem.Field("payment_id", "Payment ID is required")))
+		return
+	}
+
+	input := usecase.RefundOrderInput{
+		OrderID:   orderID,
+		PaymentID: req.PaymentID,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+	}
+
+	output, err := h.refundOrderUseCase.Execute(r.Context(), input)
+	if err != nil {
+		h.logger.Error("Failed to refund order", "error", err, "order_id", orderID)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeOrderNotFound, "Order not found"))
+		return
+	}
+
+	response := RefundOrderResponse{
+		RefundID:        output.RefundID,
+		PaymentID:       output.PaymentID,
+		PaymentStatus:   output.PaymentStatus,
+		Amount:          output.Amount,
+		RefundedAmount:  output.RefundedAmount,
+		RemainingAmount: output.RemainingAmount,
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// UpdateStatus godoc
+// @Summary Update order fulfillment status
+// @Description Moves a paid or authorized order through fulfillment: shipped, delivered or completed. Card payments are only authorized at checkout; shipping or completing an authorized order captures them first and marks it paid, and the status is kept when the capture fails. Cancellations and refunds have their own endpoints. Requires the admin role.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param status body UpdateOrderStatusRequest true "New status (shipped, delivered or completed)"
+// @Success 200 {object} entity.Order
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Transition not allowed from the current status"
+// @Failure 500 {object} problem.Problem "Payment could not be captured"
+// @Failure 503 {object} problem.Problem "Payment service unavailable, the status is kept"
+// @Security BearerAuth
+// @Router /orders/{id}/status [put]
+func (h *OrderWithPaymentHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "id")
+
+	var req UpdateOrderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request", "error", err)
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	order, err := h.updateStatusUseCase.Execute(r.Context(), orderID, req.Status, req.Reason)
+	if err != nil {
+		h.logger.Error("Failed to update order status", "error", err, "order_id", orderID, "status", req.Status)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeOrderNotFound, "Order not found"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, order)
+}
+
 type MessageResponse struct {
 	Message string `json:"message"`
 }
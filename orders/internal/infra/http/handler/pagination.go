@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"orders/internal/domain/entity"
+	"orders/internal/infra/http/problem"
+	"orders/internal/usecase"
+	"strconv"
+	"time"
+)
+
+// queryParser reads the query string of a listing and collects every
+// invalid parameter, so they are all reported at once
+type queryParser struct {
+	query      url.Values
+	violations []entity.FieldViolation
+}
+
+func newQueryParser(r *http.Request) *queryParser {
+	return &queryParser{query: r.URL.Query()}
+}
+
+func (p *queryParser) invalid(name, description string) {
+	p.violations = append(p.violations, problem.Field(name, description))
+}
+
+// listOptions reads page_size, page_token and sort; the use case checks
+// their values
+func (p *queryParser) listOptions() usecase.ListOptions {
+	options := usecase.ListOptions{
+		PageToken: p.query.Get("page_token"),
+		Sort:      p.query.Get("sort"),
+	}
+	if value := p.query.Get("page_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			p.invalid("page_size", "must be an integer")
+		}
+		options.PageSize = size
+	}
+	return options
+}
+
+// time reads an RFC 3339 timestamp, e.g. 2025-06-01T00:00:00Z
+func (p *queryParser) time(name string) time.Time {
+	value := p.query.Get(name)
+	if value == "" {
+		return time.Time{}
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		p.invalid(name, "must be an RFC 3339 timestamp, e.g. 2025-06-01T00:00:00Z")
+	}
+	return parsed
+}
+
+// amount reads a decimal in major units, e.g. 10.50, and returns it in
+// minor units
+func (p *queryParser) amount(name string) *int64 {
+	value := p.query.Get(name)
+	if value == "" {
+		return nil
+	}
+	amount, err := entity.ParseDecimal(value)
+	if err != nil {
+		p.invalid(name, "must be a decimal amount with up to two decimal places, e.g. 10.50")
+		return nil
+	}
+	return &amount
+}
+
+// err is the problem of the invalid parameters, or nil
+func (p *queryParser) err() error {
+	if len(p.violations) == 0 {
+		return nil
+	}
+	return problem.Invalid(p.violations...)
+}
+
+// setNextLink points the Link header to the next page: the same request
+// with page_token set to the token returned by the use case
+func setNextLink(w http.ResponseWriter, r *http.Request, nextPageToken string) {
+	if nextPageToken == "" {
+		return
+	}
+	query := r.URL.Query()
+	query.Set("page_token", nextPageToken)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", "<"+next.String()+`>; rel="next"`)
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"orders/internal/infra/http/problem"
 	"orders/internal/usecase"
 
 	"github.com/go-chi/chi/v5"
@@ -22,41 +25,47 @@ func NewProductHandler(productUseCase *usecase.ProductUseCase, logger *slog.Logg
 }
 
 type CreateProductRequest struct {
-	Name        string  `json:"name" example:"Laptop Dell Inspiron"`
-	Description string  `json:"description" example:"Laptop com 16GB RAM e SSD 512GB"`
-	Price       float64 `json:"price" example:"3500.00"`
-	Stock       int     `json:"stock" example:"10"`
+	Name        string       `json:"name" example:"Laptop Dell Inspiron"`
+	Description string       `json:"description" example:"Laptop com 16GB RAM e SSD 512GB"`
+	Category    string       `json:"category,omitempty" example:"notebooks"`
+	Price       entity.Money `json:"price"`
+	Stock       int          `json:"stock" example:"10"`
 }
 
 type UpdateProductRequest struct {
-	Name        string  `json:"name" example:"Laptop Dell Inspiron Pro"`
-	Description string  `json:"description" example:"Laptop com 32GB RAM e SSD 1TB"`
-	Price       float64 `json:"price" example:"5000.00"`
-	Stock       int     `json:"stock" example:"5"`
+	Name        string       `json:"name" example:"Laptop Dell Inspiron Pro"`
+	Description string       `json:"description" example:"Laptop com 32GB RAM e SSD 1TB"`
+	Category    string       `json:"category,omitempty" example:"notebooks"`
+	Price       entity.Money `json:"price"`
+	Stock       int          `json:"stock" example:"5"`
+	// Active takes the product off sale (false) or back on sale (true);
+	// omitted keeps it as it is
+	Active *bool `json:"active,omitempty" example:"true"`
 }
 
 // Create godoc
 // @Summary Create a new product
-// @Description Create a new product with name, description, price and stock
+// @Description Create a new product with name, description, category, price and stock. The category is optional and lets promotions target the product. Requires the admin role.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param product body CreateProductRequest true "Product data"
 // @Success 201 {object} entity.Product
-// @Failure 400 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Security BearerAuth
 // @Router /products [post]
 func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateProductRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Failed to decode request", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		respondWithError(w, r, problem.InvalidBody(err))
 		return
 	}
 
-	product, err := h.productUseCase.CreateProduct(req.Name, req.Description, req.Price, req.Stock)
+	product, err := h.productUseCase.CreateProduct(req.Name, req.Description, req.Category, req.Price, req.Stock)
 	if err != nil {
 		h.logger.Error("Failed to create product", "error", err)
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -72,7 +81,7 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "Product ID"
 // @Success 200 {object} entity.Product
-// @Failure 404 {object} ErrorResponse
+// @Failure 404 {object} problem.Problem
 // @Router /products/{id} [get]
 func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -80,53 +89,82 @@ func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	product, err := h.productUseCase.GetProduct(id)
 	if err != nil {
-		h.logger.Error("Product not found", "product_id", id, "error", err)
-		respondWithError(w, http.StatusNotFound, "Product not found")
+		h.logger.Error("Failed to get product", "product_id", id, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeProductNotFound, "Product not found"))
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, product)
 }
 
+// ProductListResponse is a page of products; next_page_token is omitted on
+// the last page
+type ProductListResponse struct {
+	Items         []entity.Product `json:"items"`
+	NextPageToken string           `json:"next_page_token,omitempty" example:"eyJjIjoiMjAyNS0wNi0wMVQxMjowMDowMFoiLCJpIjoiNTUwZTg0MDAifQ"`
+}
+
 // List godoc
-// @Summary List all products
-// @Description Get a list of all products
+// @Summary List products
+// @Description Get a page of products, newest first by default, with optional price and date filters. Pass the next_page_token of a response as page_token, keeping the other parameters, to get the next page; the Link header carries the same URL with rel="next".
 // @Tags products
 // @Accept json
 // @Produce json
-// @Success 200 {array} entity.Product
-// @Failure 500 {object} ErrorResponse
+// @Param min_price query string false "Minimum price, in major units" example(10.50)
+// @Param max_price query string false "Maximum price, in major units" example(5000.00)
+// @Param created_from query string false "Created at or after (RFC 3339)" example(2025-06-01T00:00:00Z)
+// @Param created_to query string false "Created before (RFC 3339)" example(2025-07-01T00:00:00Z)
+// @Param sort query string false "Sort order" Enums(-created_at, created_at) default(-created_at)
+// @Param page_size query int false "Page size (1-100)" default(20)
+// @Param page_token query string false "next_page_token of the previous page"
+// @Success 200 {object} ProductListResponse
+// @Header 200 {string} Link "URL of the next page, rel=\"next\""
+// @Failure 400 {object} problem.Problem "Invalid filter, sort, page size or page token"
+// @Failure 500 {object} problem.Problem
 // @Router /products [get]
 func (h *ProductHandler) List(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Listing all products")
+	query := newQueryParser(r)
+	filter := repository.ProductFilter{
+		MinPrice:    query.amount("min_price"),
+		MaxPrice:    query.amount("max_price"),
+		CreatedFrom: query.time("created_from"),
+		CreatedTo:   query.time("created_to"),
+	}
+	options := query.listOptions()
+	if err := query.err(); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	h.logger.Info("Listing products")
 
-	products, err := h.productUseCase.ListProducts()
+	products, next, err := h.productUseCase.ListProducts(filter, options)
 	if err != nil {
 		h.logger.Error("Failed to list products", "error", err)
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
-
-	if len(products) == 0 {
-		respondWithJSON(w, http.StatusOK, []interface{}{})
-		return
+	if products == nil {
+		products = []entity.Product{}
 	}
 
 	h.logger.Info("Products listed successfully", "count", len(products))
-	respondWithJSON(w, http.StatusOK, products)
+	setNextLink(w, r, next)
+	respondWithJSON(w, http.StatusOK, ProductListResponse{Items: products, NextPageToken: next})
 }
 
 // Update godoc
 // @Summary Update a product
-// @Description Update an existing product by ID
+// @Description Update an existing product by ID. Set active to false to take it off sale: carts and orders reject inactive products. Requires the admin role.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param id path string true "Product ID"
 // @Param product body UpdateProductRequest true "Updated product data"
 // @Success 200 {object} entity.Product
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security BearerAuth
 // @Router /products/{id} [put]
 func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -135,14 +173,14 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 	var req UpdateProductRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Failed to decode request", "product_id", id, "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		respondWithError(w, r, problem.InvalidBody(err))
 		return
 	}
 
-	product, err := h.productUseCase.UpdateProduct(id, req.Name, req.Description, req.Price, req.Stock)
+	product, err := h.productUseCase.UpdateProduct(id, req.Name, req.Description, req.Category, req.Price, req.Stock, req.Active)
 	if err != nil {
 		h.logger.Error("Failed to update product", "product_id", id, "error", err)
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeProductNotFound, "Product not found"))
 		return
 	}
 
@@ -152,14 +190,15 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 // Delete godoc
 // @Summary Delete a product
-// @Description Delete a product by ID
+// @Description Delete a product by ID. Requires the admin role.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param id path string true "Product ID"
 // @Success 204
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security BearerAuth
 // @Router /products/{id} [delete]
 func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -168,7 +207,7 @@ func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	err := h.productUseCase.DeleteProduct(id)
 	if err != nil {
 		h.logger.Error("Failed to delete product", "product_id", id, "error", err)
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodeProductNotFound, "Product not found"))
 		return
 	}
 
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"orders/internal/domain/entity"
+	"orders/internal/infra/http/problem"
+	"orders/internal/usecase"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type PromotionHandler struct {
+	promotionUseCase *usecase.PromotionUseCase
+	logger           *slog.Logger
+}
+
+func NewPromotionHandler(promotionUseCase *usecase.PromotionUseCase, logger *slog.Logger) *PromotionHandler {
+	return &PromotionHandler{
+		promotionUseCase: promotionUseCase,
+		logger:           logger,
+	}
+}
+
+// CreatePromotionRequest creates a coupon when it has a code, and an
+// automatic promotion otherwise. The fields used depend on the type.
+type CreatePromotionRequest struct {
+	Name                  string                `json:"name" example:"Boas-vindas"`
+	Code                  string                `json:"code,omitempty" example:"WELCOME10"`
+	Type                  entity.PromotionType  `json:"type" example:"percentage" enums:"percentage,fixed_amount,free_shipping,buy_x_get_y,tiered_quantity"`
+	Percentage            int                   `json:"percentage,omitempty" example:"10"`
+	Amount                *entity.Money         `json:"amount,omitempty"`
+	BuyQuantity           int                   `json:"buy_quantity,omitempty" example:"2"`
+	GetQuantity           int                   `json:"get_quantity,omitempty" example:"1"`
+	Tiers                 []entity.DiscountTier `json:"tiers,omitempty"`
+	ProductIDs            []string              `json:"product_ids,omitempty"`
+	Categories            []string              `json:"categories,omitempty" example:"notebooks"`
+	StartsAt              *time.Time            `json:"starts_at,omitempty"`
+	EndsAt                *time.Time            `json:"ends_at,omitempty"`
+	UsageLimit            int                   `json:"usage_limit,omitempty" example:"1000"`
+	UsageLimitPerCustomer int                   `json:"usage_limit_per_customer,omitempty" example:"1"`
+	Stackable             bool                  `json:"stackable" example:"false"`
+	Priority              int                   `json:"priority" example:"0"`
+}
+
+// Create godoc
+// @Summary Create a promotion
+// @Description Create a coupon (with a code) or an automatic promotion: percentage or fixed amount off the order or off the targeted products and categories, free shipping, buy X get Y and tiered quantity discounts. Discounts off the items always leave at least one cent to charge; free shipping waives the shipping fee of the cart. Promotions have an optional validity window and usage limits in total and per customer; non-stackable ones are only applied alone, the highest priority first. Requires the admin role.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param promotion body CreatePromotionRequest true "Promotion rules"
+// @Success 201 {object} entity.Promotion
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Coupon code already used (DUPLICATE_COUPON_CODE)"
+// @Security BearerAuth
+// @Router /promotions [post]
+func (h *PromotionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreatePromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request", "error", err)
+		respondWithError(w, r, problem.InvalidBody(err))
+		return
+	}
+
+	promotion, err := h.promotionUseCase.CreatePromotion(entity.Promotion{
+		Name:                  req.Name,
+		Code:                  req.Code,
+		Type:                  req.Type,
+		Percentage:            req.Percentage,
+		Amount:                req.Amount,
+		BuyQuantity:           req.BuyQuantity,
+		GetQuantity:           req.GetQuantity,
+		Tiers:                 req.Tiers,
+		ProductIDs:            req.ProductIDs,
+		Categories:            req.Categories,
+		StartsAt:              req.StartsAt,
+		EndsAt:                req.EndsAt,
+		UsageLimit:            req.UsageLimit,
+		UsageLimitPerCustomer: req.UsageLimitPerCustomer,
+		Stackable:             req.Stackable,
+		Priority:              req.Priority,
+	})
+	if err != nil {
+		h.logger.Error("Failed to create promotion", "error", err)
+		respondWithError(w, r, err)
+		return
+	}
+
+	h.logger.Info("Promotion created via API", "promotion_id", promotion.ID)
+	respondWithJSON(w, http.StatusCreated, promotion)
+}
+
+// GetByID godoc
+// @Summary Get promotion by ID
+// @Description Get a single promotion by ID. Requires the admin role.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} entity.Promotion
+// @Failure 404 {object} problem.Problem
+// @Security BearerAuth
+// @Router /promotions/{id} [get]
+func (h *PromotionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	h.logger.Info("Getting promotion by ID", "promotion_id", id)
+
+	promotion, err := h.promotionUseCase.GetPromotion(id)
+	if err != nil {
+		h.logger.Error("Failed to get promotion", "promotion_id", id, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodePromotionNotFound, "Promotion not found"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, promotion)
+}
+
+// List godoc
+// @Summary List all promotions
+// @Description Get a list of all promotions, active or not, newest first. Requires the admin role.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Success 200 {array} entity.Promotion
+// @Failure 500 {object} problem.Problem
+// @Security BearerAuth
+// @Router /promotions [get]
+func (h *PromotionHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Listing all promotions")
+
+	promotions, err := h.promotionUseCase.ListPromotions()
+	if err != nil {
+		h.logger.Error("Failed to list promotions", "error", err)
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, promotions)
+}
+
+// Deactivate godoc
+// @Summary Deactivate a promotion
+// @Description End a promotion: it no longer applies to carts and its coupon is rejected. It is kept for the orders that used it. Requires the admin role.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} entity.Promotion
+// @Failure 404 {object} problem.Problem
+// @Security BearerAuth
+// @Router /promotions/{id} [delete]
+func (h *PromotionHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	h.logger.Info("Deactivating promotion", "promotion_id", id)
+
+	promotion, err := h.promotionUseCase.DeactivatePromotion(id)
+	if err != nil {
+		h.logger.Error("Failed to deactivate promotion", "promotion_id", id, "error", err)
+		respondWithError(w, r, problem.OrNotFound(err, problem.CodePromotionNotFound, "Promotion not found"))
+		return
+	}
+
+	h.logger.Info("Promotion deactivated via API", "promotion_id", id)
+	respondWithJSON(w, http.StatusOK, promotion)
+}
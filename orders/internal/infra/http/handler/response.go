@@ -3,14 +3,14 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"orders/internal/infra/http/problem"
 )
 
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
-
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, ErrorResponse{Error: message})
+// respondWithError answers err as a problem+json: the status and the code
+// come from the central mapping in the problem package, so the same error
+// gets the same answer on every endpoint
+func respondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	problem.Write(w, r, problem.FromError(err))
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -0,0 +1,125 @@
+package problem
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"orders/internal/domain/entity"
+	"orders/internal/usecase"
+)
+
+// errorRule maps an error of the domain or of a use case to the problem
+// returned to clients. Field, when set, is reported as an invalid field.
+type errorRule struct {
+	err    error
+	status int
+	code   string
+	field  string
+}
+
+// errorRules are checked in order, so specific errors come before the ones
+// they wrap
+var errorRules = []errorRule{
+	// Invalid requests
+	{entity.ErrInvalidCustomerName, http.StatusBadRequest, CodeValidationFailed, "name"},
+	{entity.ErrInvalidCustomerEmail, http.StatusBadRequest, CodeValidationFailed, "email"},
+	{entity.ErrInvalidCustomerCPF, http.StatusBadRequest, CodeValidationFailed, "document"},
+	{entity.ErrInvalidCustomerPhone, http.StatusBadRequest, CodeValidationFailed, "phone"},
+	{entity.ErrInvalidAddress, http.StatusBadRequest, CodeValidationFailed, "addresses"},
+	{entity.ErrInvalidProductName, http.StatusBadRequest, CodeValidationFailed, "name"},
+	{entity.ErrInvalidProductPrice, http.StatusBadRequest, CodeValidationFailed, "price.amount"},
+	{entity.ErrInvalidQuantity, http.StatusBadRequest, CodeValidationFailed, "quantity"},
+	{entity.ErrInvalidProduct, http.StatusBadRequest, CodeValidationFailed, "product_id"},
+	{entity.ErrInvalidOrderStatus, http.StatusBadRequest, CodeValidationFailed, "status"},
+	{entity.ErrInvalidCurrency, http.StatusBadRequest, "INVALID_CURRENCY", ""},
+	{entity.ErrCurrencyMismatch, http.StatusBadRequest, "INVALID_CURRENCY", ""},
+	{entity.ErrInvalidMoney, http.StatusBadRequest, "INVALID_AMOUNT", ""},
+	{entity.ErrInvalidPaymentDetails, http.StatusBadRequest, "INVALID_PAYMENT_DETAILS", "payment_details"},
+	{entity.ErrEmptyOrder, http.StatusBadRequest, "EMPTY_ORDER", ""},
+	{entity.ErrPaymentNotForOrder, http.StatusBadRequest, "PAYMENT_NOT_FOR_ORDER", "payment_id"},
+	{entity.ErrInvalidPageToken, http.StatusBadRequest, "INVALID_PAGE_TOKEN", "page_token"},
+	{usecase.ErrInvalidPageSize, http.StatusBadRequest, CodeValidationFailed, "page_size"},
+	{usecase.ErrInvalidSort, http.StatusBadRequest, CodeValidationFailed, "sort"},
+	{usecase.ErrCustomerContactRequired, http.StatusBadRequest, CodeValidationFailed, "customer_email"},
+	{entity.ErrInvalidPromotionName, http.StatusBadRequest, CodeValidationFailed, "name"},
+	{entity.ErrInvalidPromotionType, http.StatusBadRequest, CodeValidationFailed, "type"},
+	{entity.ErrInvalidCouponCode, http.StatusBadRequest, CodeValidationFailed, "code"},
+	{entity.ErrInvalidPercentage, http.StatusBadRequest, CodeValidationFailed, "percentage"},
+	{entity.ErrInvalidDiscountAmount, http.StatusBadRequest, CodeValidationFailed, "amount"},
+	{entity.ErrInvalidBuyXGetY, http.StatusBadRequest, CodeValidationFailed, "buy_quantity"},
+	{entity.ErrInvalidDiscountTiers, http.StatusBadRequest, CodeValidationFailed, "tiers"},
+	{entity.ErrInvalidValidityWindow, http.StatusBadRequest, CodeValidationFailed, "ends_at"},
+	{entity.ErrInvalidUsageLimit, http.StatusBadRequest, CodeValidationFailed, "usage_limit"},
+	// Coupons entered in a cart
+	{usecase.ErrCouponNotFound, http.StatusBadRequest, "COUPON_NOT_FOUND", "code"},
+	{entity.ErrCouponNotValid, http.StatusBadRequest, "COUPON_NOT_VALID", "code"},
+	// Unknown ids sent in the body, not in the path
+	{usecase.ErrCustomerNotFound, http.StatusBadRequest, CodeCustomerNotFound, "customer_id"},
+	{usecase.ErrProductNotFound, http.StatusBadRequest, CodeProductNotFound, "product_id"},
+	{entity.ErrProductInactive, http.StatusBadRequest, "PRODUCT_INACTIVE", "product_id"},
+	// An OrderItemsError lists the rejected items itself
+	{usecase.ErrInvalidOrderItems, http.StatusBadRequest, "INVALID_ORDER_ITEMS", ""},
+
+	{entity.ErrItemNotFound, http.StatusNotFound, "ITEM_NOT_FOUND", ""},
+	{entity.ErrCouponNotApplied, http.StatusNotFound, "COUPON_NOT_APPLIED", ""},
+	{entity.ErrBoletoNotFound, http.StatusNotFound, "BOLETO_NOT_FOUND", ""},
+	{entity.ErrCartExpired, http.StatusGone, "CART_EXPIRED", ""},
+	{sql.ErrNoRows, http.StatusNotFound, CodeNotFound, ""},
+
+	// Conflicts with the current state
+	{entity.ErrInsufficientStock, http.StatusConflict, "INSUFFICIENT_STOCK", ""},
+	{entity.ErrInvalidStatusTransition, http.StatusConflict, "INVALID_STATUS_TRANSITION", ""},
+	{entity.ErrDuplicateCustomer, http.StatusConflict, "DUPLICATE_CUSTOMER", ""},
+	{entity.ErrCustomerHasOrders, http.StatusConflict, "CUSTOMER_HAS_ORDERS", ""},
+	{entity.ErrOrderConflict, http.StatusConflict, "CONCURRENT_UPDATE", ""},
+	{entity.ErrCartLocked, http.StatusConflict, "CART_LOCKED", ""},
+	{entity.ErrDuplicateCouponCode, http.StatusConflict, "DUPLICATE_COUPON_CODE", ""},
+	{entity.ErrCouponUsageLimitReached, http.StatusConflict, "COUPON_USAGE_LIMIT_REACHED", ""},
+	{entity.ErrCouponNotApplicable, http.StatusConflict, "COUPON_NOT_APPLICABLE", ""},
+	{entity.ErrCouponNotStackable, http.StatusConflict, "COUPON_NOT_STACKABLE", ""},
+	{entity.ErrOrderVersionMismatch, http.StatusPreconditionFailed, "PRECONDITION_FAILED", ""},
+	{entity.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", ""},
+
+	// Payments service; a PaymentError carries the reason the service sent
+	{entity.ErrInvalidPaymentRequest, http.StatusBadRequest, "INVALID_PAYMENT_REQUEST", ""},
+	{entity.ErrPaymentNotFound, http.StatusNotFound, "PAYMENT_NOT_FOUND", ""},
+	{entity.ErrPaymentOperationNotAllowed, http.StatusConflict, "PAYMENT_OPERATION_NOT_ALLOWED", ""},
+	{entity.ErrPaymentConflict, http.StatusConflict, "PAYMENT_CONFLICT", ""},
+	{entity.ErrPaymentServiceUnavailable, http.StatusServiceUnavailable, "PAYMENT_SERVICE_UNAVAILABLE", ""},
+}
+
+// FromError maps an error to its problem. Problems are returned as they
+// are; errors of the payments service keep the reason and the fields the
+// service sent, and rejected order items are listed one by one. Errors
+// without a rule are internal, with a generic detail so internals do not
+// leak to clients.
+func FromError(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	for _, rule := range errorRules {
+		if !errors.Is(err, rule.err) {
+			continue
+		}
+
+		p = New(rule.status, rule.code, err.Error())
+		var paymentErr *entity.PaymentError
+		var itemsErr *usecase.OrderItemsError
+		switch {
+		case errors.As(err, &paymentErr):
+			if paymentErr.Reason != "" {
+				p = New(rule.status, paymentErr.Reason, paymentErr.Error())
+			}
+			p.Errors = paymentErr.Fields
+		case errors.As(err, &itemsErr):
+			p.Errors = itemsErr.Violations
+		case rule.field != "":
+			p.Errors = []entity.FieldViolation{Field(rule.field, err.Error())}
+		}
+		return p
+	}
+
+	return New(http.StatusInternalServerError, CodeInternal, "An unexpected error occurred")
+}
@@ -0,0 +1,128 @@
+// Package problem writes API errors as RFC 7807 problem details
+// (application/problem+json), with a stable code clients can rely on
+// instead of the detail message.
+package problem
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"orders/internal/domain/entity"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ContentType is the media type of every error response
+const ContentType = "application/problem+json"
+
+// typePrefix builds the type URI of a problem from its code, e.g.
+// urn:orders:problem:order-not-found
+const typePrefix = "urn:orders:problem:"
+
+// Problem is the body of an error response. Code is stable and documented;
+// Detail is meant for humans and may change. Errors lists the invalid
+// fields of the request, when there are any.
+type Problem struct {
+	Type     string `json:"type" example:"urn:orders:problem:order-not-found"`
+	Title    string `json:"title" example:"Not Found"`
+	Status   int    `json:"status" example:"404"`
+	Detail   string `json:"detail,omitempty" example:"Order not found"`
+	Instance string `json:"instance,omitempty" example:"/api/v1/orders/550e8400-e29b-41d4-a716-446655440000"`
+	Code     string `json:"code" example:"ORDER_NOT_FOUND" enums:"INVALID_BODY,VALIDATION_FAILED,INVALID_IDEMPOTENCY_KEY,INVALID_AMOUNT,INVALID_CURRENCY,INVALID_PAYMENT_DETAILS,INVALID_PAYMENT_REQUEST,EMPTY_ORDER,PAYMENT_NOT_FOR_ORDER,INVALID_PAGE_TOKEN,CUSTOMER_NOT_FOUND,PRODUCT_NOT_FOUND,PRODUCT_INACTIVE,INVALID_ORDER_ITEMS,MISSING_TOKEN,INVALID_TOKEN,TOKEN_EXPIRED,FORBIDDEN,NOT_FOUND,ORDER_NOT_FOUND,CART_NOT_FOUND,CART_EXPIRED,ITEM_NOT_FOUND,BOLETO_NOT_FOUND,PAYMENT_NOT_FOUND,METHOD_NOT_ALLOWED,INSUFFICIENT_STOCK,INVALID_STATUS_TRANSITION,DUPLICATE_CUSTOMER,CUSTOMER_HAS_ORDERS,PRECONDITION_FAILED,PAYMENT_OPERATION_NOT_ALLOWED,PAYMENT_CONFLICT,IDEMPOTENCY_KEY_REUSED,PAYMENT_SERVICE_UNAVAILABLE,INTERNAL,INVALID_ARGUMENT,PAYMENT_METHOD_NOT_SUPPORTED,REFUND_EXCEEDS_AMOUNT,CAPTURE_EXCEEDS_AUTHORIZATION,PAYMENT_NOT_CANCELABLE,PAYMENT_NOT_REFUNDABLE,AUTHORIZATION_NOT_SUPPORTED,PAYMENT_NOT_AUTHORIZED,AUTHORIZATION_EXPIRED,CONFIRMATION_NOT_SUPPORTED,PAYMENT_NOT_AWAITING_CONFIRMATION,PAYMENT_EXPIRED,BOLETO_NOT_ISSUED,CONCURRENT_UPDATE,CART_LOCKED,COUPON_NOT_FOUND,COUPON_NOT_VALID,COUPON_NOT_APPLIED,COUPON_USAGE_LIMIT_REACHED,COUPON_NOT_APPLICABLE,COUPON_NOT_STACKABLE,DUPLICATE_COUPON_CODE,PROMOTION_NOT_FOUND,GATEWAY_UNAVAILABLE,DATABASE_UNAVAILABLE"`
+	// RequestID matches the X-Request-Id of the request, to find it in the
+	// logs
+	RequestID string                  `json:"request_id,omitempty" example:"orders-api/Ab12Cd34Ef-000042"`
+	Errors    []entity.FieldViolation `json:"errors,omitempty"`
+}
+
+// Codes of the problems raised by the API itself. Errors of the domain and
+// of the payments service get theirs from the rules in errors.go.
+const (
+	CodeInvalidBody           = "INVALID_BODY"
+	CodeValidationFailed      = "VALIDATION_FAILED"
+	CodeInvalidIdempotencyKey = "INVALID_IDEMPOTENCY_KEY"
+	CodeMissingToken          = "MISSING_TOKEN"
+	CodeInvalidToken          = "INVALID_TOKEN"
+	CodeTokenExpired          = "TOKEN_EXPIRED"
+	CodeForbidden             = "FORBIDDEN"
+	CodeNotFound              = "NOT_FOUND"
+	CodeOrderNotFound         = "ORDER_NOT_FOUND"
+	CodeCartNotFound          = "CART_NOT_FOUND"
+	CodeCustomerNotFound      = "CUSTOMER_NOT_FOUND"
+	CodeProductNotFound       = "PRODUCT_NOT_FOUND"
+	CodePromotionNotFound     = "PROMOTION_NOT_FOUND"
+	CodeMethodNotAllowed      = "METHOD_NOT_ALLOWED"
+	CodeInternal              = "INTERNAL"
+)
+
+// New creates a problem; its type and title come from the code and status
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + strings.ToLower(strings.ReplaceAll(code, "_", "-")),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Error lets handlers pass a problem wherever an error is expected
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+// Invalid is a request with invalid fields
+func Invalid(fields ...entity.FieldViolation) *Problem {
+	p := New(http.StatusBadRequest, CodeValidationFailed, "The request has invalid fields")
+	p.Errors = fields
+	return p
+}
+
+// Field is a shorthand for a violation of a single field
+func Field(field, description string) entity.FieldViolation {
+	return entity.FieldViolation{Field: field, Description: description}
+}
+
+// InvalidBody is a body that could not be decoded. A value of the wrong
+// type is reported on its field.
+func InvalidBody(err error) *Problem {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		p := New(http.StatusBadRequest, CodeInvalidBody, "The request body has a field of the wrong type")
+		p.Errors = []entity.FieldViolation{Field(typeErr.Field, "must be a "+typeErr.Type.String())}
+		return p
+	case errors.As(err, &syntaxErr):
+		return New(http.StatusBadRequest, CodeInvalidBody, "The request body is not valid JSON")
+	case errors.Is(err, io.EOF):
+		return New(http.StatusBadRequest, CodeInvalidBody, "The request body is required")
+	}
+	return New(http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+}
+
+// OrNotFound turns sql.ErrNoRows, which repositories return for a missing
+// row, into the not found problem of the resource; any other error is
+// returned as is
+func OrNotFound(err error, code, detail string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return New(http.StatusNotFound, code, detail)
+	}
+	return err
+}
+
+// Write sends the problem, adding the path and the id of the request
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	body := *p
+	if body.Instance == "" {
+		body.Instance = r.URL.Path
+	}
+	body.RequestID = middleware.GetReqID(r.Context())
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(body.Status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type CheckoutSagaRepositoryMySQL struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewCheckoutSagaRepository(db *sql.DB, logger *slog.Logger) *CheckoutSagaRepositoryMySQL {
+	return &CheckoutSagaRepositoryMySQL{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *CheckoutSagaRepositoryMySQL) Create(saga *entity.CheckoutSaga) error {
+	r.logger.Info("Creating checkout saga", "saga_id", saga.ID, "order_id", saga.OrderID)
+
+	query := `
+		INSERT INTO checkout_sagas (id, order_id, status, step, payment_id, payment_method, customer_email,
+		                            customer_name, failure_reason, last_error, attempts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := r.db.Exec(query,
+		saga.ID,
+		saga.OrderID,
+		saga.Status,
+		saga.Step,
+		nullString(saga.PaymentID),
+		saga.PaymentMethod,
+		saga.CustomerEmail,
+		saga.CustomerName,
+		nullString(saga.FailureReason),
+		nullString(saga.LastError),
+		saga.Attempts,
+		saga.CreatedAt,
+		saga.UpdatedAt,
+	)
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return entity.ErrDuplicateCheckoutSaga
+	}
+	if err != nil {
+		r.logger.Error("Failed to create checkout saga", "saga_id", saga.ID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *CheckoutSagaRepositoryMySQL) FindByOrderID(orderID string) (*entity.CheckoutSaga, error) {
+	query := `
+		SELECT id, order_id, status, step, payment_id, payment_method, customer_email, customer_name,
+		       failure_reason, last_error, attempts, created_at, updated_at, version
+		FROM checkout_sagas
+		WHERE order_id = ?
+	`
+	sagas, err := r.query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sagas) == 0 {
+		return nil, nil
+	}
+	return &sagas[0], nil
+}
+
+func (r *CheckoutSagaRepositoryMySQL) FindUnfinished(updatedBefore time.Time, limit int) ([]entity.CheckoutSaga, error) {
+	query := `
+		SELECT id, order_id, status, step, payment_id, payment_method, customer_email, customer_name,
+		       failure_reason, last_error, attempts, created_at, updated_at, version
+		FROM checkout_sagas
+		WHERE status IN (?, ?) AND updated_at < ?
+		ORDER BY updated_at
+		LIMIT ?
+	`
+	return r.query(query, entity.SagaStatusRunning, entity.SagaStatusCompensating, updatedBefore, limit)
+}
+
+func (r *CheckoutSagaRepositoryMySQL) Update(saga *entity.CheckoutSaga) error {
+	r.logger.Info("Updating checkout saga",
+		"saga_id", saga.ID,
+		"order_id", saga.OrderID,
+		"status", saga.Status,
+		"step", saga.Step,
+	)
+
+	query := `
+		UPDATE checkout_sagas
+		SET status = ?, step = ?, payment_id = ?, failure_reason = ?, last_error = ?, attempts = ?, updated_at = ?,
+		    version = version + 1
+		WHERE id = ? AND version = ?
+	`
+	result, err := r.db.Exec(query,
+		saga.Status,
+		saga.Step,
+		nullString(saga.PaymentID),
+		nullString(saga.FailureReason),
+		nullString(saga.LastError),
+		saga.Attempts,
+		saga.UpdatedAt,
+		saga.ID,
+		saga.Version,
+	)
+	if err != nil {
+		r.logger.Error("Failed to update checkout saga", "saga_id", saga.ID, "error", err)
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		r.logger.Warn("Checkout saga changed concurrently", "saga_id", saga.ID, "version", saga.Version)
+		return entity.ErrSagaConflict
+	}
+	saga.Version++
+
+	return nil
+}
+
+func (r *CheckoutSagaRepositoryMySQL) query(query string, args ...interface{}) ([]entity.CheckoutSaga, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		r.logger.Error("Failed to query checkout sagas", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sagas []entity.CheckoutSaga
+	for rows.Next() {
+		var saga entity.CheckoutSaga
+		var paymentID, failureReason, lastError sql.NullString
+		err := rows.Scan(
+			&saga.ID,
+			&saga.OrderID,
+			&saga.Status,
+			&saga.Step,
+			&paymentID,
+			&saga.PaymentMethod,
+			&saga.CustomerEmail,
+			&saga.CustomerName,
+			&failureReason,
+			&lastError,
+			&saga.Attempts,
+			&saga.CreatedAt,
+			&saga.UpdatedAt,
+			&saga.Version,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan checkout saga", "error", err)
+			return nil, err
+		}
+		saga.PaymentID = paymentID.String
+		saga.FailureReason = failureReason.String
+		saga.LastError = lastError.String
+		sagas = append(sagas, saga)
+	}
+
+	return sagas, rows.Err()
+}
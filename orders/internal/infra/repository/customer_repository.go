@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlRowIsReferenced is the MySQL error number for deleting a row that a
+// foreign key still points to
+const mysqlRowIsReferenced = 1451
+
+type CustomerRepositoryMySQL struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewCustomerRepository(db *sql.DB, logger *slog.Logger) *CustomerRepositoryMySQL {
+	return &CustomerRepositoryMySQL{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *CustomerRepositoryMySQL) Create(customer *entity.Customer) error {
+	r.logger.Info("Creating customer", "customer_id", customer.ID)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "customer_id", customer.ID, "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO customers (id, name, email, document, phone, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.Exec(query,
+		customer.ID,
+		customer.Name,
+		customer.Email,
+		nullString(customer.Document),
+		nullString(customer.Phone),
+		customer.CreatedAt,
+		customer.UpdatedAt,
+	)
+	if isMySQLError(err, mysqlDuplicateEntry) {
+		r.logger.Warn("Customer email or document already used", "customer_id", customer.ID)
+		return entity.ErrDuplicateCustomer
+	}
+	if err != nil {
+		r.logger.Error("Failed to insert customer", "customer_id", customer.ID, "error", err)
+		return err
+	}
+
+	if err := insertAddresses(tx, customer); err != nil {
+		r.logger.Error("Failed to insert customer addresses", "customer_id", customer.ID, "error", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "customer_id", customer.ID, "error", err)
+		return err
+	}
+
+	r.logger.Info("Customer created successfully", "customer_id", customer.ID)
+	return nil
+}
+
+func (r *CustomerRepositoryMySQL) FindByID(id string) (*entity.Customer, error) {
+	r.logger.Info("Finding customer by ID", "customer_id", id)
+
+	customers, err := r.query(`WHERE c.id = ?`, id)
+	if err != nil {
+		r.logger.Error("Failed to find customer", "customer_id", id, "error", err)
+		return nil, err
+	}
+	if len(customers) == 0 {
+		r.logger.Warn("Customer not found", "customer_id", id)
+		return nil, sql.ErrNoRows
+	}
+
+	r.logger.Info("Customer found", "customer_id", id)
+	return &customers[0], nil
+}
+
+func (r *CustomerRepositoryMySQL) FindAll() ([]entity.Customer, error) {
+	r.logger.Info("Finding all customers")
+
+	customers, err := r.query("")
+	if err != nil {
+		r.logger.Error("Failed to query customers", "error", err)
+		return nil, err
+	}
+
+	r.logger.Info("Customers found", "count", len(customers))
+	return customers, nil
+}
+
+func (r *CustomerRepositoryMySQL) Update(customer *entity.Customer) error {
+	r.logger.Info("Updating customer", "customer_id", customer.ID)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "customer_id", customer.ID, "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	customer.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE customers
+		SET name = ?, email = ?, document = ?, phone = ?, updated_at = ?
+		WHERE id = ?
+	`
+	_, err = tx.Exec(query,
+		customer.Name,
+		customer.Email,
+		nullString(customer.Document),
+		nullString(customer.Phone),
+		customer.UpdatedAt,
+		customer.ID,
+	)
+	if isMySQLError(err, mysqlDuplicateEntry) {
+		r.logger.Warn("Customer email or document already used", "customer_id", customer.ID)
+		return entity.ErrDuplicateCustomer
+	}
+	if err != nil {
+		r.logger.Error("Failed to update customer", "customer_id", customer.ID, "error", err)
+		return err
+	}
+
+	// Replace the addresses
+	_, err = tx.Exec(`DELETE FROM customer_addresses WHERE customer_id = ?`, customer.ID)
+	if err != nil {
+		r.logger.Error("Failed to delete customer addresses", "customer_id", customer.ID, "error", err)
+		return err
+	}
+	if err := insertAddresses(tx, customer); err != nil {
+		r.logger.Error("Failed to insert customer addresses", "customer_id", customer.ID, "error", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "customer_id", customer.ID, "error", err)
+		return err
+	}
+
+	r.logger.Info("Customer updated successfully", "customer_id", customer.ID)
+	return nil
+}
+
+func (r *CustomerRepositoryMySQL) Delete(id string) error {
+	r.logger.Info("Deleting customer", "customer_id", id)
+
+	// Addresses are removed by ON DELETE CASCADE
+	_, err := r.db.Exec(`DELETE FROM customers WHERE id = ?`, id)
+	if isMySQLError(err, mysqlRowIsReferenced) {
+		r.logger.Warn("Customer has orders", "customer_id", id)
+		return entity.ErrCustomerHasOrders
+	}
+	if err != nil {
+		r.logger.Error("Failed to delete customer", "customer_id", id, "error", err)
+		return err
+	}
+
+	r.logger.Info("Customer deleted successfully", "customer_id", id)
+	return nil
+}
+
+// query loads the customers matching where, newest first, with their
+// addresses. The addresses of all of them are loaded by a single query
+// instead of one query per customer.
+func (r *CustomerRepositoryMySQL) query(where string, args ...interface{}) ([]entity.Customer, error) {
+	rows, err := r.db.Query(`
+		SELECT c.id, c.name, c.email, c.document, c.phone, c.created_at, c.updated_at
+		FROM customers c
+		`+where+`
+		ORDER BY c.created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []entity.Customer
+	for rows.Next() {
+		var customer entity.Customer
+		var document, phone sql.NullString
+		err := rows.Scan(
+			&customer.ID,
+			&customer.Name,
+			&customer.Email,
+			&document,
+			&phone,
+			&customer.CreatedAt,
+			&customer.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		customer.Document = document.String
+		customer.Phone = phone.String
+		customer.Addresses = []entity.Address{}
+		customers = append(customers, customer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(customers) == 0 {
+		return customers, nil
+	}
+
+	addressRows, err := r.db.Query(`
+		SELECT a.id, a.customer_id, a.label, a.street, a.number, a.complement, a.district, a.city, a.state, a.zip_code
+		FROM customer_addresses a
+		INNER JOIN customers c ON c.id = a.customer_id
+		`+where+`
+		ORDER BY a.customer_id, a.position
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer addressRows.Close()
+
+	addresses := make(map[string][]entity.Address)
+	for addressRows.Next() {
+		var address entity.Address
+		var customerID string
+		var label, complement, district sql.NullString
+		err := addressRows.Scan(
+			&address.ID,
+			&customerID,
+			&label,
+			&address.Street,
+			&address.Number,
+			&complement,
+			&district,
+			&address.City,
+			&address.State,
+			&address.ZipCode,
+		)
+		if err != nil {
+			return nil, err
+		}
+		address.Label = label.String
+		address.Complement = complement.String
+		address.District = district.String
+		addresses[customerID] = append(addresses[customerID], address)
+	}
+	if err := addressRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range customers {
+		if found, ok := addresses[customers[i].ID]; ok {
+			customers[i].Addresses = found
+		}
+	}
+	return customers, nil
+}
+
+func insertAddresses(tx *sql.Tx, customer *entity.Customer) error {
+	query := `
+		INSERT INTO customer_addresses (id, customer_id, position, label, street, number, complement,
+		                                district, city, state, zip_code)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	for i, address := range customer.Addresses {
+		_, err := tx.Exec(query,
+			address.ID,
+			customer.ID,
+			i,
+			nullString(address.Label),
+			address.Street,
+			address.Number,
+			nullString(address.Complement),
+			nullString(address.District),
+			address.City,
+			address.State,
+			address.ZipCode,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isMySQLError(err error, number uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == number
+}
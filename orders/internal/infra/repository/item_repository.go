@@ -5,8 +5,16 @@ import (
 	"orders/internal/domain/entity"
 )
 
+// dbtx is satisfied by both *sql.DB and *sql.Tx, so the item repository
+// also works inside the transaction of the order repository
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type ItemRepositoryMySQL struct {
-	db *sql.DB
+	db dbtx
 }
 
 func NewItemRepository(db *sql.DB) *ItemRepositoryMySQL {
@@ -15,24 +23,26 @@ func NewItemRepository(db *sql.DB) *ItemRepositoryMySQL {
 
 func (r *ItemRepositoryMySQL) Create(item *entity.Item) error {
 	query := `
-		INSERT INTO items (id, order_id, product_id, quantity, unit_price, total)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO items (id, order_id, product_id, quantity, unit_price_cents, total_cents, discount_cents, currency)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err := r.db.Exec(query,
 		item.ID,
 		item.OrderID,
 		item.ProductID,
 		item.Quantity,
-		item.UnitPrice,
-		item.Total,
+		item.UnitPrice.Amount,
+		item.Total.Amount,
+		item.Discount.Amount,
+		item.UnitPrice.Currency,
 	)
 	return err
 }
 
 func (r *ItemRepositoryMySQL) FindByID(id string) (*entity.Item, error) {
 	query := `
-		SELECT i.id, i.order_id, i.product_id, i.quantity, i.unit_price, i.total,
-		       p.id, p.name, p.description, p.price, p.stock, p.created_at, p.updated_at
+		SELECT i.id, i.order_id, i.product_id, i.quantity, i.unit_price_cents, i.total_cents, i.discount_cents, i.currency,
+		       p.id, p.name, p.description, p.category, p.price_cents, p.currency, p.stock, p.active, p.created_at, p.updated_at
 		FROM items i
 		INNER JOIN products p ON i.product_id = p.id
 		WHERE i.id = ?
@@ -44,27 +54,34 @@ func (r *ItemRepositoryMySQL) FindByID(id string) (*entity.Item, error) {
 		&item.OrderID,
 		&item.ProductID,
 		&item.Quantity,
-		&item.UnitPrice,
-		&item.Total,
+		&item.UnitPrice.Amount,
+		&item.Total.Amount,
+		&item.Discount.Amount,
+		&item.UnitPrice.Currency,
 		&product.ID,
 		&product.Name,
 		&product.Description,
-		&product.Price,
+		&product.Category,
+		&product.Price.Amount,
+		&product.Price.Currency,
 		&product.Stock,
+		&product.Active,
 		&product.CreatedAt,
 		&product.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+	item.Total.Currency = item.UnitPrice.Currency
+	item.Discount.Currency = item.UnitPrice.Currency
 	item.Product = &product
 	return &item, nil
 }
 
 func (r *ItemRepositoryMySQL) FindByOrderID(orderID string) ([]entity.Item, error) {
 	query := `
-		SELECT i.id, i.order_id, i.product_id, i.quantity, i.unit_price, i.total,
-		       p.id, p.name, p.description, p.price, p.stock, p.created_at, p.updated_at
+		SELECT i.id, i.order_id, i.product_id, i.quantity, i.unit_price_cents, i.total_cents, i.discount_cents, i.currency,
+		       p.id, p.name, p.description, p.category, p.price_cents, p.currency, p.stock, p.active, p.created_at, p.updated_at
 		FROM items i
 		INNER JOIN products p ON i.product_id = p.id
 		WHERE i.order_id = ?
@@ -84,35 +101,44 @@ func (r *ItemRepositoryMySQL) FindByOrderID(orderID string) ([]entity.Item, erro
 			&item.OrderID,
 			&item.ProductID,
 			&item.Quantity,
-			&item.UnitPrice,
-			&item.Total,
+			&item.UnitPrice.Amount,
+			&item.Total.Amount,
+			&item.Discount.Amount,
+			&item.UnitPrice.Currency,
 			&product.ID,
 			&product.Name,
 			&product.Description,
-			&product.Price,
+			&product.Category,
+			&product.Price.Amount,
+			&product.Price.Currency,
 			&product.Stock,
+			&product.Active,
 			&product.CreatedAt,
 			&product.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		item.Total.Currency = item.UnitPrice.Currency
+		item.Discount.Currency = item.UnitPrice.Currency
 		item.Product = &product
 		items = append(items, item)
 	}
-	return items, nil
+	return items, rows.Err()
 }
 
 func (r *ItemRepositoryMySQL) Update(item *entity.Item) error {
 	query := `
 		UPDATE items
-		SET quantity = ?, unit_price = ?, total = ?
+		SET quantity = ?, unit_price_cents = ?, total_cents = ?, discount_cents = ?, currency = ?
 		WHERE id = ?
 	`
 	_, err := r.db.Exec(query,
 		item.Quantity,
-		item.UnitPrice,
-		item.Total,
+		item.UnitPrice.Amount,
+		item.Total.Amount,
+		item.Discount.Amount,
+		item.UnitPrice.Currency,
 		item.ID,
 	)
 	return err
@@ -2,11 +2,19 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"log/slog"
 	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
 )
 
+// mysqlDuplicateEntry is the MySQL error number for unique key violations
+const mysqlDuplicateEntry = 1062
+
 type OrderRepositoryMySQL struct {
 	db     *sql.DB
 	logger *slog.Logger
@@ -31,45 +39,75 @@ func (r *OrderRepositoryMySQL) Create(order *entity.Order) error {
 
 	// Insert order
 	query := `
-		INSERT INTO orders (id, status, total, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO orders (id, status, customer_id, subtotal_cents, line_discount_cents, order_discount_cents,
+		                    shipping_cents, shipping_discount_cents, total_cents, free_shipping,
+		                    currency, idempotency_key, created_at, updated_at, placed_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err = tx.Exec(query,
 		order.ID,
 		order.Status,
-		order.Total,
+		nullString(order.CustomerID),
+		order.Subtotal.Amount,
+		order.LineDiscount.Amount,
+		order.OrderDiscount.Amount,
+		order.Shipping.Amount,
+		order.ShippingDiscount.Amount,
+		order.Total.Amount,
+		order.FreeShipping,
+		order.Total.Currency,
+		nullString(order.IdempotencyKey),
 		order.CreatedAt,
 		order.UpdatedAt,
+		order.PlacedAt,
+		order.ExpiresAt,
 	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry && order.IdempotencyKey != "" {
+		r.logger.Warn("Order idempotency key already used", "order_id", order.ID)
+		return entity.ErrDuplicateIdempotencyKey
+	}
 	if err != nil {
 		r.logger.Error("Failed to insert order", "order_id", order.ID, "error", err)
 		return err
 	}
 
 	// Insert items
-	for _, item := range order.Items {
-		itemQuery := `
-			INSERT INTO items (id, order_id, product_id, quantity, unit_price, total)
-			VALUES (?, ?, ?, ?, ?, ?)
-		`
-		_, err = tx.Exec(itemQuery,
-			item.ID,
-			order.ID,
-			item.ProductID,
-			item.Quantity,
-			item.UnitPrice,
-			item.Total,
-		)
-		if err != nil {
+	items := &ItemRepositoryMySQL{db: tx}
+	for i := range order.Items {
+		item := order.Items[i]
+		item.OrderID = order.ID
+		if err := items.Create(&item); err != nil {
 			r.logger.Error("Failed to insert order item", "order_id", order.ID, "item_id", item.ID, "error", err)
 			return err
 		}
 	}
 
+	if err := saveDiscounts(tx, order); err != nil {
+		r.logger.Error("Failed to save order discounts", "order_id", order.ID, "error", err)
+		return err
+	}
+
+	if err := insertRedemptions(tx, order.Redemptions()); err != nil {
+		r.logger.Error("Failed to redeem order promotions", "order_id", order.ID, "error", err)
+		return err
+	}
+
+	if err := insertStatusChanges(tx, order.StatusChanges()); err != nil {
+		r.logger.Error("Failed to insert order status history", "order_id", order.ID, "error", err)
+		return err
+	}
+
+	if err := insertOutboxEvents(tx, order.Events()); err != nil {
+		r.logger.Error("Failed to insert order events", "order_id", order.ID, "error", err)
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		r.logger.Error("Failed to commit transaction", "order_id", order.ID, "error", err)
 		return err
 	}
+	order.ClearEvents()
 
 	r.logger.Info("Order created successfully", "order_id", order.ID, "total", order.Total)
 	return nil
@@ -79,17 +117,32 @@ func (r *OrderRepositoryMySQL) FindByID(id string) (*entity.Order, error) {
 	r.logger.Info("Finding order by ID", "order_id", id)
 
 	query := `
-		SELECT id, status, total, created_at, updated_at
+		SELECT id, status, customer_id, subtotal_cents, line_discount_cents, order_discount_cents,
+		       shipping_cents, shipping_discount_cents, total_cents, free_shipping,
+		       currency, created_at, updated_at, placed_at, expires_at, version
 		FROM orders
 		WHERE id = ?
 	`
 	var order entity.Order
+	var customerID sql.NullString
+	var placedAt, expiresAt sql.NullTime
 	err := r.db.QueryRow(query, id).Scan(
 		&order.ID,
 		&order.Status,
-		&order.Total,
+		&customerID,
+		&order.Subtotal.Amount,
+		&order.LineDiscount.Amount,
+		&order.OrderDiscount.Amount,
+		&order.Shipping.Amount,
+		&order.ShippingDiscount.Amount,
+		&order.Total.Amount,
+		&order.FreeShipping,
+		&order.Total.Currency,
 		&order.CreatedAt,
 		&order.UpdatedAt,
+		&placedAt,
+		&expiresAt,
+		&order.Version,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -99,63 +152,116 @@ func (r *OrderRepositoryMySQL) FindByID(id string) (*entity.Order, error) {
 		}
 		return nil, err
 	}
+	order.CustomerID = customerID.String
+	setOrderCurrency(&order)
+	if placedAt.Valid {
+		order.PlacedAt = &placedAt.Time
+	}
+	if expiresAt.Valid {
+		order.ExpiresAt = &expiresAt.Time
+	}
 
 	// Load items
-	itemsQuery := `
-		SELECT i.id, i.order_id, i.product_id, i.quantity, i.unit_price, i.total,
-		       p.id, p.name, p.description, p.price, p.stock, p.created_at, p.updated_at
-		FROM items i
-		INNER JOIN products p ON i.product_id = p.id
-		WHERE i.order_id = ?
-	`
-	rows, err := r.db.Query(itemsQuery, id)
+	items, err := (&ItemRepositoryMySQL{db: r.db}).FindByOrderID(id)
 	if err != nil {
-		r.logger.Error("Failed to query order items", "order_id", id, "error", err)
+		r.logger.Error("Failed to load order items", "order_id", id, "error", err)
 		return nil, err
 	}
-	defer rows.Close()
+	order.Items = items
 
-	var items []entity.Item
-	for rows.Next() {
-		var item entity.Item
-		var product entity.Product
-		err := rows.Scan(
-			&item.ID,
-			&item.OrderID,
-			&item.ProductID,
-			&item.Quantity,
-			&item.UnitPrice,
-			&item.Total,
-			&product.ID,
-			&product.Name,
-			&product.Description,
-			&product.Price,
-			&product.Stock,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-		)
-		if err != nil {
-			r.logger.Error("Failed to scan order item", "order_id", id, "error", err)
-			return nil, err
-		}
-		item.Product = &product
-		items = append(items, item)
+	if err := r.loadDiscounts(&order); err != nil {
+		r.logger.Error("Failed to load order discounts", "order_id", id, "error", err)
+		return nil, err
 	}
-	order.Items = items
 
 	r.logger.Info("Order found", "order_id", id, "items_count", len(items))
 	return &order, nil
 }
 
-func (r *OrderRepositoryMySQL) FindAll() ([]entity.Order, error) {
-	r.logger.Info("Finding all orders")
+func (r *OrderRepositoryMySQL) FindByIdempotencyKey(customerID, key string) (*entity.Order, error) {
+	r.logger.Info("Finding order by idempotency key", "customer_id", customerID)
+
+	var id string
+	err := r.db.QueryRow(`SELECT id FROM orders WHERE idempotency_key = ? AND customer_id <=> ?`, key, nullString(customerID)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to find order by idempotency key", "error", err)
+		return nil, err
+	}
+
+	order, err := r.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	order.IdempotencyKey = key
+	return order, nil
+}
+
+func (r *OrderRepositoryMySQL) List(filter repository.OrderFilter) ([]entity.Order, error) {
+	r.logger.Info("Listing orders", "status", filter.Status, "customer_id", filter.CustomerID, "limit", filter.Limit)
+
+	var where whereClause
+	if filter.Status != "" {
+		where.add("status = ?", filter.Status)
+	}
+	if filter.CustomerID != "" {
+		where.add("customer_id = ?", filter.CustomerID)
+	}
+	where.addRange("total_cents", filter.MinTotal, filter.MaxTotal)
+	where.addPeriod("created_at", filter.CreatedFrom, filter.CreatedTo)
+
+	query, args := where.paged(`
+		SELECT id, status, customer_id, subtotal_cents, line_discount_cents, order_discount_cents,
+		       shipping_cents, shipping_discount_cents, total_cents, free_shipping,
+		       currency, created_at, updated_at, placed_at, expires_at
+		FROM orders`, filter.Page)
+	orders, err := r.findOrders(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("Orders found", "count", len(orders))
+	return orders, nil
+}
+
+func (r *OrderRepositoryMySQL) FindByCustomerID(customerID string) ([]entity.Order, error) {
+	r.logger.Info("Finding orders by customer", "customer_id", customerID)
 
 	query := `
-		SELECT id, status, total, created_at, updated_at
+		SELECT id, status, customer_id, subtotal_cents, line_discount_cents, order_discount_cents,
+		       shipping_cents, shipping_discount_cents, total_cents, free_shipping,
+		       currency, created_at, updated_at, placed_at, expires_at
 		FROM orders
+		WHERE customer_id = ?
 		ORDER BY created_at DESC
 	`
-	rows, err := r.db.Query(query)
+	orders, err := r.findOrders(query, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("Customer orders found", "customer_id", customerID, "count", len(orders))
+	return orders, nil
+}
+
+func (r *OrderRepositoryMySQL) FindExpiredCarts(now time.Time, limit int) ([]entity.Order, error) {
+	query := `
+		SELECT id, status, customer_id, subtotal_cents, line_discount_cents, order_discount_cents,
+		       shipping_cents, shipping_discount_cents, total_cents, free_shipping,
+		       currency, created_at, updated_at, placed_at, expires_at
+		FROM orders
+		WHERE status = ? AND placed_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?
+		ORDER BY expires_at
+		LIMIT ?
+	`
+	return r.findOrders(query, entity.OrderStatusPending, now, limit)
+}
+
+// findOrders loads the orders selected by query, without their items
+func (r *OrderRepositoryMySQL) findOrders(query string, args ...interface{}) ([]entity.Order, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		r.logger.Error("Failed to query orders", "error", err)
 		return nil, err
@@ -165,21 +271,44 @@ func (r *OrderRepositoryMySQL) FindAll() ([]entity.Order, error) {
 	var orders []entity.Order
 	for rows.Next() {
 		var order entity.Order
+		var customerID sql.NullString
+		var placedAt, expiresAt sql.NullTime
 		err := rows.Scan(
 			&order.ID,
 			&order.Status,
-			&order.Total,
+			&customerID,
+			&order.Subtotal.Amount,
+			&order.LineDiscount.Amount,
+			&order.OrderDiscount.Amount,
+			&order.Shipping.Amount,
+			&order.ShippingDiscount.Amount,
+			&order.Total.Amount,
+			&order.FreeShipping,
+			&order.Total.Currency,
 			&order.CreatedAt,
 			&order.UpdatedAt,
+			&placedAt,
+			&expiresAt,
 		)
 		if err != nil {
 			r.logger.Error("Failed to scan order row", "error", err)
 			return nil, err
 		}
+		order.CustomerID = customerID.String
+		setOrderCurrency(&order)
+		if placedAt.Valid {
+			order.PlacedAt = &placedAt.Time
+		}
+		if expiresAt.Valid {
+			order.ExpiresAt = &expiresAt.Time
+		}
 		orders = append(orders, order)
 	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Failed to read order rows", "error", err)
+		return nil, err
+	}
 
-	r.logger.Info("Orders found", "count", len(orders))
 	return orders, nil
 }
 
@@ -195,56 +324,244 @@ func (r *OrderRepositoryMySQL) Update(order *entity.Order) error {
 
 	order.UpdatedAt = time.Now()
 
-	// Update order
+	// Update order, only if nobody else saved it since it was read
 	query := `
 		UPDATE orders
-		SET status = ?, total = ?, updated_at = ?
-		WHERE id = ?
+		SET status = ?, customer_id = ?, subtotal_cents = ?, line_discount_cents = ?, order_discount_cents = ?,
+		    shipping_cents = ?, shipping_discount_cents = ?, total_cents = ?, free_shipping = ?, currency = ?, updated_at = ?, placed_at = ?, expires_at = ?,
+		    version = version + 1
+		WHERE id = ? AND version = ?
 	`
-	_, err = tx.Exec(query,
+	result, err := tx.Exec(query,
 		order.Status,
-		order.Total,
+		nullString(order.CustomerID),
+		order.Subtotal.Amount,
+		order.LineDiscount.Amount,
+		order.OrderDiscount.Amount,
+		order.Shipping.Amount,
+		order.ShippingDiscount.Amount,
+		order.Total.Amount,
+		order.FreeShipping,
+		order.Total.Currency,
 		order.UpdatedAt,
+		order.PlacedAt,
+		order.ExpiresAt,
 		order.ID,
+		order.Version,
 	)
 	if err != nil {
 		r.logger.Error("Failed to update order", "order_id", order.ID, "error", err)
 		return err
 	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		r.logger.Warn("Order changed concurrently", "order_id", order.ID, "version", order.Version)
+		return entity.ErrOrderConflict
+	}
+
+	if err := r.saveItems(tx, order); err != nil {
+		r.logger.Error("Failed to save order items", "order_id", order.ID, "error", err)
+		return err
+	}
+
+	if err := saveDiscounts(tx, order); err != nil {
+		r.logger.Error("Failed to save order discounts", "order_id", order.ID, "error", err)
+		return err
+	}
+
+	if err := insertRedemptions(tx, order.Redemptions()); err != nil {
+		r.logger.Error("Failed to redeem order promotions", "order_id", order.ID, "error", err)
+		return err
+	}
+
+	if err := insertStatusChanges(tx, order.StatusChanges()); err != nil {
+		r.logger.Error("Failed to insert order status history", "order_id", order.ID, "error", err)
+		return err
+	}
 
-	// Delete existing items
-	_, err = tx.Exec(`DELETE FROM items WHERE order_id = ?`, order.ID)
+	if err := insertOutboxEvents(tx, order.Events()); err != nil {
+		r.logger.Error("Failed to insert order events", "order_id", order.ID, "error", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "order_id", order.ID, "error", err)
+		return err
+	}
+	order.ClearEvents()
+	order.Version++
+
+	r.logger.Info("Order updated successfully", "order_id", order.ID, "version", order.Version)
+	return nil
+}
+
+// saveItems writes only the items that changed since the order was saved:
+// new ones are inserted, changed ones updated and missing ones deleted. The
+// version check of Update already serializes writers of the same order.
+func (r *OrderRepositoryMySQL) saveItems(tx *sql.Tx, order *entity.Order) error {
+	items := &ItemRepositoryMySQL{db: tx}
+	saved, err := items.FindByOrderID(order.ID)
 	if err != nil {
-		r.logger.Error("Failed to delete existing items", "order_id", order.ID, "error", err)
 		return err
 	}
 
-	// Insert updated items
-	for _, item := range order.Items {
-		itemQuery := `
-			INSERT INTO items (id, order_id, product_id, quantity, unit_price, total)
-			VALUES (?, ?, ?, ?, ?, ?)
-		`
-		_, err = tx.Exec(itemQuery,
-			item.ID,
-			order.ID,
-			item.ProductID,
-			item.Quantity,
-			item.UnitPrice,
-			item.Total,
+	previous := make(map[string]entity.Item, len(saved))
+	for _, item := range saved {
+		previous[item.ID] = item
+	}
+
+	for i := range order.Items {
+		item := order.Items[i]
+		item.OrderID = order.ID
+		old, ok := previous[item.ID]
+		delete(previous, item.ID)
+		switch {
+		case !ok:
+			err = items.Create(&item)
+		case old.Quantity != item.Quantity || old.UnitPrice != item.UnitPrice || old.Total != item.Total || old.Discount != item.Discount:
+			err = items.Update(&item)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for id := range previous {
+		if err := items.Delete(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDiscounts reads the coupons and discounts of an order
+func (r *OrderRepositoryMySQL) loadDiscounts(order *entity.Order) error {
+	order.Coupons = []string{}
+	order.Discounts = []entity.Discount{}
+
+	rows, err := r.db.Query(`SELECT code FROM order_coupons WHERE order_id = ? ORDER BY position`, order.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return err
+		}
+		order.Coupons = append(order.Coupons, code)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	discountRows, err := r.db.Query(`
+		SELECT promotion_id, code, name, level, item_id, amount_cents, currency
+		FROM order_discounts
+		WHERE order_id = ?
+		ORDER BY position
+	`, order.ID)
+	if err != nil {
+		return err
+	}
+	defer discountRows.Close()
+	for discountRows.Next() {
+		var discount entity.Discount
+		var code, itemID sql.NullString
+		err := discountRows.Scan(
+			&discount.PromotionID,
+			&code,
+			&discount.Name,
+			&discount.Level,
+			&itemID,
+			&discount.Amount.Amount,
+			&discount.Amount.Currency,
 		)
 		if err != nil {
-			r.logger.Error("Failed to insert updated item", "order_id", order.ID, "item_id", item.ID, "error", err)
 			return err
 		}
+		discount.Code = code.String
+		discount.ItemID = itemID.String
+		order.Discounts = append(order.Discounts, discount)
 	}
+	return discountRows.Err()
+}
 
-	if err := tx.Commit(); err != nil {
-		r.logger.Error("Failed to commit transaction", "order_id", order.ID, "error", err)
+// saveDiscounts replaces the coupons and discounts of an order; they are
+// few and change together with the items
+func saveDiscounts(tx *sql.Tx, order *entity.Order) error {
+	if _, err := tx.Exec(`DELETE FROM order_coupons WHERE order_id = ?`, order.ID); err != nil {
 		return err
 	}
+	for i, code := range order.Coupons {
+		_, err := tx.Exec(`INSERT INTO order_coupons (order_id, position, code) VALUES (?, ?, ?)`, order.ID, i, code)
+		if err != nil {
+			return err
+		}
+	}
 
-	r.logger.Info("Order updated successfully", "order_id", order.ID)
+	if _, err := tx.Exec(`DELETE FROM order_discounts WHERE order_id = ?`, order.ID); err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO order_discounts (id, order_id, position, promotion_id, code, name, level, item_id, amount_cents, currency)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	for i, discount := range order.Discounts {
+		_, err := tx.Exec(query,
+			uuid.New().String(),
+			order.ID,
+			i,
+			discount.PromotionID,
+			nullString(discount.Code),
+			discount.Name,
+			discount.Level,
+			nullString(discount.ItemID),
+			discount.Amount.Amount,
+			discount.Amount.Currency,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertRedemptions saves the promotions redeemed by a placed order. The
+// promotion row is locked while its redemptions are counted, so concurrent
+// checkouts cannot go past the usage limits together.
+func insertRedemptions(tx *sql.Tx, redemptions []entity.Redemption) error {
+	for _, redemption := range redemptions {
+		var usageLimit, usageLimitPerCustomer int
+		err := tx.QueryRow(`
+			SELECT usage_limit, usage_limit_per_customer FROM promotions WHERE id = ? FOR UPDATE
+		`, redemption.PromotionID).Scan(&usageLimit, &usageLimitPerCustomer)
+		if err != nil {
+			return err
+		}
+
+		total, byCustomer, err := countRedemptions(tx, redemption.PromotionID, redemption.CustomerID)
+		if err != nil {
+			return err
+		}
+		if usageLimit > 0 && total >= usageLimit ||
+			usageLimitPerCustomer > 0 && byCustomer >= usageLimitPerCustomer {
+			return entity.ErrCouponUsageLimitReached
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO promotion_redemptions (id, promotion_id, order_id, customer_id, redeemed_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, redemption.ID, redemption.PromotionID, redemption.OrderID, nullString(redemption.CustomerID), redemption.RedeemedAt)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -278,3 +595,19 @@ func (r *OrderRepositoryMySQL) Delete(id string) error {
 	r.logger.Info("Order deleted successfully", "order_id", id)
 	return nil
 }
+
+// setOrderCurrency gives the amounts read from an order row the currency of
+// the order
+func setOrderCurrency(order *entity.Order) {
+	order.Subtotal.Currency = order.Total.Currency
+	order.LineDiscount.Currency = order.Total.Currency
+	order.OrderDiscount.Currency = order.Total.Currency
+	order.Shipping.Currency = order.Total.Currency
+	order.ShippingDiscount.Currency = order.Total.Currency
+}
+
+// nullString stores empty optional values as NULL, so unique indexes only
+// apply to rows that actually carry a value
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+	"log/slog"
+	"orders/internal/domain/entity"
+)
+
+type OrderStatusHistoryRepositoryMySQL struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewOrderStatusHistoryRepository(db *sql.DB, logger *slog.Logger) *OrderStatusHistoryRepositoryMySQL {
+	return &OrderStatusHistoryRepositoryMySQL{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// insertStatusChanges writes status changes inside the transaction that
+// changes the order, so the history always matches the saved status
+func insertStatusChanges(tx *sql.Tx, changes []entity.StatusChange) error {
+	for _, change := range changes {
+		_, err := tx.Exec(`
+			INSERT INTO order_status_history (id, order_id, from_status, to_status, actor, reason, changed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`,
+			change.ID,
+			change.OrderID,
+			nullString(string(change.From)),
+			change.To,
+			change.Actor,
+			nullString(change.Reason),
+			change.ChangedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *OrderStatusHistoryRepositoryMySQL) FindByOrderID(orderID string) ([]entity.StatusChange, error) {
+	query := `
+		SELECT id, order_id, from_status, to_status, actor, reason, changed_at
+		FROM order_status_history
+		WHERE order_id = ?
+		ORDER BY changed_at, id
+	`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		r.logger.Error("Failed to query order status history", "order_id", orderID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []entity.StatusChange{}
+	for rows.Next() {
+		var change entity.StatusChange
+		var from, reason sql.NullString
+		err := rows.Scan(
+			&change.ID,
+			&change.OrderID,
+			&from,
+			&change.To,
+			&change.Actor,
+			&reason,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan order status change", "order_id", orderID, "error", err)
+			return nil, err
+		}
+		change.From = entity.OrderStatus(from.String)
+		change.Reason = reason.String
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
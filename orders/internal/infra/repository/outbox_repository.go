@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"database/sql"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"time"
+)
+
+type OutboxRepositoryMySQL struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewOutboxRepository(db *sql.DB, logger *slog.Logger) *OutboxRepositoryMySQL {
+	return &OutboxRepositoryMySQL{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// insertOutboxEvents writes events inside the transaction that changes the
+// aggregate, so an event exists if and only if the change was committed
+func insertOutboxEvents(tx *sql.Tx, events []entity.Event) error {
+	for _, event := range events {
+		_, err := tx.Exec(`
+			INSERT INTO outbox_events (id, aggregate_type, aggregate_id, event_type, payload, occurred_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`,
+			event.ID,
+			event.AggregateType,
+			event.AggregateID,
+			event.Type,
+			[]byte(event.Payload),
+			event.OccurredAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *OutboxRepositoryMySQL) FindUnpublished(limit int) ([]entity.Event, error) {
+	query := `
+		SELECT id, aggregate_type, aggregate_id, event_type, payload, occurred_at, attempts, last_error
+		FROM outbox_events
+		WHERE published_at IS NULL
+		ORDER BY occurred_at, id
+		LIMIT ?
+	`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		r.logger.Error("Failed to query outbox events", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []entity.Event
+	for rows.Next() {
+		var event entity.Event
+		var payload []byte
+		var lastError sql.NullString
+		err := rows.Scan(
+			&event.ID,
+			&event.AggregateType,
+			&event.AggregateID,
+			&event.Type,
+			&payload,
+			&event.OccurredAt,
+			&event.Attempts,
+			&lastError,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan outbox event", "error", err)
+			return nil, err
+		}
+		event.Payload = payload
+		event.LastError = lastError.String
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *OutboxRepositoryMySQL) MarkPublished(id string, publishedAt time.Time) error {
+	_, err := r.db.Exec(`UPDATE outbox_events SET published_at = ?, attempts = attempts + 1 WHERE id = ?`, publishedAt, id)
+	if err != nil {
+		r.logger.Error("Failed to mark outbox event as published", "event_id", id, "error", err)
+	}
+	return err
+}
+
+func (r *OutboxRepositoryMySQL) MarkFailed(id string, reason string) error {
+	_, err := r.db.Exec(`UPDATE outbox_events SET attempts = attempts + 1, last_error = ? WHERE id = ?`, reason, id)
+	if err != nil {
+		r.logger.Error("Failed to record outbox publish failure", "event_id", id, "error", err)
+	}
+	return err
+}
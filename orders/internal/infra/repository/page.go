@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"orders/internal/domain/repository"
+	"strings"
+	"time"
+)
+
+// whereClause collects the conditions of a listing query and their
+// arguments
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *whereClause) add(condition string, args ...interface{}) {
+	w.conditions = append(w.conditions, condition)
+	w.args = append(w.args, args...)
+}
+
+func (w *whereClause) addRange(column string, min, max *int64) {
+	if min != nil {
+		w.add(column+" >= ?", *min)
+	}
+	if max != nil {
+		w.add(column+" <= ?", *max)
+	}
+}
+
+func (w *whereClause) addPeriod(column string, from, to time.Time) {
+	if !from.IsZero() {
+		w.add(column+" >= ?", from)
+	}
+	if !to.IsZero() {
+		w.add(column+" < ?", to)
+	}
+}
+
+// paged completes a SELECT with the conditions and a keyset page on
+// (created_at, id), so deep pages cost the same as the first one
+func (w *whereClause) paged(query string, page repository.Page) (string, []interface{}) {
+	direction, comparison := "DESC", "<"
+	if page.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if page.After != nil {
+		w.add("(created_at, id) "+comparison+" (?, ?)", page.After.CreatedAt, page.After.ID)
+	}
+
+	if len(w.conditions) > 0 {
+		query += " WHERE " + strings.Join(w.conditions, " AND ")
+	}
+	query += " ORDER BY created_at " + direction + ", id " + direction + " LIMIT ?"
+	return query, append(w.args, page.Limit)
+}
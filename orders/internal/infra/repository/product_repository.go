@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"log/slog"
 	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
 	"time"
 )
 
@@ -23,15 +24,18 @@ func (r *ProductRepositoryMySQL) Create(product *entity.Product) error {
 	r.logger.Info("Creating product", "product_id", product.ID, "name", product.Name)
 
 	query := `
-		INSERT INTO products (id, name, description, price, stock, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO products (id, name, description, category, price_cents, currency, stock, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err := r.db.Exec(query,
 		product.ID,
 		product.Name,
 		product.Description,
-		product.Price,
+		product.Category,
+		product.Price.Amount,
+		product.Price.Currency,
 		product.Stock,
+		product.Active,
 		product.CreatedAt,
 		product.UpdatedAt,
 	)
@@ -49,7 +53,7 @@ func (r *ProductRepositoryMySQL) FindByID(id string) (*entity.Product, error) {
 	r.logger.Info("Finding product by ID", "product_id", id)
 
 	query := `
-		SELECT id, name, description, price, stock, created_at, updated_at
+		SELECT id, name, description, category, price_cents, currency, stock, active, created_at, updated_at
 		FROM products
 		WHERE id = ?
 	`
@@ -58,8 +62,11 @@ func (r *ProductRepositoryMySQL) FindByID(id string) (*entity.Product, error) {
 		&product.ID,
 		&product.Name,
 		&product.Description,
-		&product.Price,
+		&product.Category,
+		&product.Price.Amount,
+		&product.Price.Currency,
 		&product.Stock,
+		&product.Active,
 		&product.CreatedAt,
 		&product.UpdatedAt,
 	)
@@ -76,15 +83,17 @@ func (r *ProductRepositoryMySQL) FindByID(id string) (*entity.Product, error) {
 	return &product, nil
 }
 
-func (r *ProductRepositoryMySQL) FindAll() ([]entity.Product, error) {
-	r.logger.Info("Finding all products")
+func (r *ProductRepositoryMySQL) List(filter repository.ProductFilter) ([]entity.Product, error) {
+	r.logger.Info("Listing products", "limit", filter.Limit)
 
-	query := `
-		SELECT id, name, description, price, stock, created_at, updated_at
-		FROM products
-		ORDER BY created_at DESC
-	`
-	rows, err := r.db.Query(query)
+	var where whereClause
+	where.addRange("price_cents", filter.MinPrice, filter.MaxPrice)
+	where.addPeriod("created_at", filter.CreatedFrom, filter.CreatedTo)
+
+	query, args := where.paged(`
+		SELECT id, name, description, category, price_cents, currency, stock, active, created_at, updated_at
+		FROM products`, filter.Page)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		r.logger.Error("Failed to query products", "error", err)
 		return nil, err
@@ -98,8 +107,11 @@ func (r *ProductRepositoryMySQL) FindAll() ([]entity.Product, error) {
 			&product.ID,
 			&product.Name,
 			&product.Description,
-			&product.Price,
+			&product.Category,
+			&product.Price.Amount,
+			&product.Price.Currency,
 			&product.Stock,
+			&product.Active,
 			&product.CreatedAt,
 			&product.UpdatedAt,
 		)
@@ -119,15 +131,18 @@ func (r *ProductRepositoryMySQL) Update(product *entity.Product) error {
 
 	query := `
 		UPDATE products
-		SET name = ?, description = ?, price = ?, stock = ?, updated_at = ?
+		SET name = ?, description = ?, category = ?, price_cents = ?, currency = ?, stock = ?, active = ?, updated_at = ?
 		WHERE id = ?
 	`
 	product.UpdatedAt = time.Now()
 	_, err := r.db.Exec(query,
 		product.Name,
 		product.Description,
-		product.Price,
+		product.Category,
+		product.Price.Amount,
+		product.Price.Currency,
 		product.Stock,
+		product.Active,
 		product.UpdatedAt,
 		product.ID,
 	)
@@ -155,3 +170,66 @@ func (r *ProductRepositoryMySQL) Delete(id string) error {
 	r.logger.Info("Product deleted successfully", "product_id", id)
 	return nil
 }
+
+func (r *ProductRepositoryMySQL) DecreaseStock(id string, quantity int) error {
+	r.logger.Info("Decreasing product stock", "product_id", id, "quantity", quantity)
+
+	// The stock check and the decrement happen in a single statement so
+	// concurrent checkouts can never take the stock below zero.
+	query := `
+		UPDATE products
+		SET stock = stock - ?, updated_at = ?
+		WHERE id = ? AND stock >= ?
+	`
+	result, err := r.db.Exec(query, quantity, time.Now(), id, quantity)
+	if err != nil {
+		r.logger.Error("Failed to decrease product stock", "product_id", id, "error", err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Failed to read affected rows", "product_id", id, "error", err)
+		return err
+	}
+
+	if affected == 0 {
+		if _, err := r.FindByID(id); err != nil {
+			return err
+		}
+		r.logger.Warn("Insufficient stock", "product_id", id, "quantity", quantity)
+		return entity.ErrInsufficientStock
+	}
+
+	r.logger.Info("Product stock decreased", "product_id", id, "quantity", quantity)
+	return nil
+}
+
+func (r *ProductRepositoryMySQL) IncreaseStock(id string, quantity int) error {
+	r.logger.Info("Increasing product stock", "product_id", id, "quantity", quantity)
+
+	query := `
+		UPDATE products
+		SET stock = stock + ?, updated_at = ?
+		WHERE id = ?
+	`
+	result, err := r.db.Exec(query, quantity, time.Now(), id)
+	if err != nil {
+		r.logger.Error("Failed to increase product stock", "product_id", id, "error", err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Failed to read affected rows", "product_id", id, "error", err)
+		return err
+	}
+
+	if affected == 0 {
+		r.logger.Warn("Product not found", "product_id", id)
+		return sql.ErrNoRows
+	}
+
+	r.logger.Info("Product stock increased", "product_id", id, "quantity", quantity)
+	return nil
+}
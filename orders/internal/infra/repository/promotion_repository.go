@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"time"
+)
+
+type PromotionRepositoryMySQL struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewPromotionRepository(db *sql.DB, logger *slog.Logger) *PromotionRepositoryMySQL {
+	return &PromotionRepositoryMySQL{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const promotionColumns = `
+	id, name, code, type, percentage, amount_cents, currency, buy_quantity, get_quantity, tiers,
+	product_ids, categories, starts_at, ends_at, usage_limit, usage_limit_per_customer, stackable,
+	priority, active, created_at, updated_at`
+
+func (r *PromotionRepositoryMySQL) Create(promotion *entity.Promotion) error {
+	r.logger.Info("Creating promotion", "promotion_id", promotion.ID, "code", promotion.Code)
+
+	args, err := promotionArgs(promotion)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO promotions (` + promotionColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.db.Exec(query, args...)
+	if isMySQLError(err, mysqlDuplicateEntry) {
+		r.logger.Warn("Coupon code already used", "promotion_id", promotion.ID, "code", promotion.Code)
+		return entity.ErrDuplicateCouponCode
+	}
+	if err != nil {
+		r.logger.Error("Failed to create promotion", "promotion_id", promotion.ID, "error", err)
+		return err
+	}
+
+	r.logger.Info("Promotion created successfully", "promotion_id", promotion.ID)
+	return nil
+}
+
+func (r *PromotionRepositoryMySQL) FindByID(id string) (*entity.Promotion, error) {
+	return r.findOne(`WHERE id = ?`, id)
+}
+
+func (r *PromotionRepositoryMySQL) FindByCode(code string) (*entity.Promotion, error) {
+	return r.findOne(`WHERE code = ?`, code)
+}
+
+func (r *PromotionRepositoryMySQL) FindAll() ([]entity.Promotion, error) {
+	r.logger.Info("Finding all promotions")
+	return r.query(`ORDER BY created_at DESC`)
+}
+
+func (r *PromotionRepositoryMySQL) FindAutomatic(now time.Time) ([]entity.Promotion, error) {
+	return r.query(`
+		WHERE active = TRUE AND code IS NULL
+		  AND (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)
+		ORDER BY priority DESC, created_at`, now, now)
+}
+
+func (r *PromotionRepositoryMySQL) Update(promotion *entity.Promotion) error {
+	r.logger.Info("Updating promotion", "promotion_id", promotion.ID)
+
+	args, err := promotionArgs(promotion)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE promotions
+		SET name = ?, code = ?, type = ?, percentage = ?, amount_cents = ?, currency = ?, buy_quantity = ?,
+		    get_quantity = ?, tiers = ?, product_ids = ?, categories = ?, starts_at = ?, ends_at = ?,
+		    usage_limit = ?, usage_limit_per_customer = ?, stackable = ?, priority = ?, active = ?,
+		    created_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+	_, err = r.db.Exec(query, append(args[1:], promotion.ID)...)
+	if isMySQLError(err, mysqlDuplicateEntry) {
+		return entity.ErrDuplicateCouponCode
+	}
+	if err != nil {
+		r.logger.Error("Failed to update promotion", "promotion_id", promotion.ID, "error", err)
+		return err
+	}
+
+	r.logger.Info("Promotion updated successfully", "promotion_id", promotion.ID)
+	return nil
+}
+
+func (r *PromotionRepositoryMySQL) CountRedemptions(promotionID, customerID string) (int, int, error) {
+	return countRedemptions(r.db, promotionID, customerID)
+}
+
+// countRedemptions counts the redemptions of orders that were not canceled;
+// the order repository calls it inside its transaction
+func countRedemptions(db dbtx, promotionID, customerID string) (int, int, error) {
+	var total, byCustomer int
+	err := db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(r.customer_id = ?), 0)
+		FROM promotion_redemptions r
+		INNER JOIN orders o ON o.id = r.order_id
+		WHERE r.promotion_id = ? AND o.status <> ?
+	`, customerID, promotionID, entity.OrderStatusCanceled).Scan(&total, &byCustomer)
+	if customerID == "" {
+		byCustomer = 0
+	}
+	return total, byCustomer, err
+}
+
+func (r *PromotionRepositoryMySQL) findOne(where string, args ...interface{}) (*entity.Promotion, error) {
+	promotions, err := r.query(where, args...)
+	if err != nil {
+		r.logger.Error("Failed to find promotion", "error", err)
+		return nil, err
+	}
+	if len(promotions) == 0 {
+		r.logger.Warn("Promotion not found")
+		return nil, sql.ErrNoRows
+	}
+	return &promotions[0], nil
+}
+
+func (r *PromotionRepositoryMySQL) query(where string, args ...interface{}) ([]entity.Promotion, error) {
+	rows, err := r.db.Query(`SELECT `+promotionColumns+` FROM promotions `+where, args...)
+	if err != nil {
+		r.logger.Error("Failed to query promotions", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := []entity.Promotion{}
+	for rows.Next() {
+		var promotion entity.Promotion
+		var code, currency sql.NullString
+		var amount sql.NullInt64
+		var tiers, productIDs, categories []byte
+		var startsAt, endsAt sql.NullTime
+		err := rows.Scan(
+			&promotion.ID,
+			&promotion.Name,
+			&code,
+			&promotion.Type,
+			&promotion.Percentage,
+			&amount,
+			&currency,
+			&promotion.BuyQuantity,
+			&promotion.GetQuantity,
+			&tiers,
+			&productIDs,
+			&categories,
+			&startsAt,
+			&endsAt,
+			&promotion.UsageLimit,
+			&promotion.UsageLimitPerCustomer,
+			&promotion.Stackable,
+			&promotion.Priority,
+			&promotion.Active,
+			&promotion.CreatedAt,
+			&promotion.UpdatedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan promotion row", "error", err)
+			return nil, err
+		}
+		promotion.Code = code.String
+		if amount.Valid {
+			money := entity.NewMoney(amount.Int64, currency.String)
+			promotion.Amount = &money
+		}
+		if startsAt.Valid {
+			promotion.StartsAt = &startsAt.Time
+		}
+		if endsAt.Valid {
+			promotion.EndsAt = &endsAt.Time
+		}
+		if err := decodeJSON(tiers, &promotion.Tiers); err != nil {
+			return nil, err
+		}
+		if err := decodeJSON(productIDs, &promotion.ProductIDs); err != nil {
+			return nil, err
+		}
+		if err := decodeJSON(categories, &promotion.Categories); err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Failed to read promotion rows", "error", err)
+		return nil, err
+	}
+
+	return promotions, nil
+}
+
+// promotionArgs are the values of promotionColumns, in order
+func promotionArgs(promotion *entity.Promotion) ([]interface{}, error) {
+	var amount sql.NullInt64
+	var currency sql.NullString
+	if promotion.Amount != nil {
+		amount = sql.NullInt64{Int64: promotion.Amount.Amount, Valid: true}
+		currency = nullString(promotion.Amount.Currency)
+	}
+
+	tiers, err := json.Marshal(promotion.Tiers)
+	if err != nil {
+		return nil, err
+	}
+	productIDs, err := json.Marshal(promotion.ProductIDs)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := json.Marshal(promotion.Categories)
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{
+		promotion.ID,
+		promotion.Name,
+		nullString(promotion.Code),
+		promotion.Type,
+		promotion.Percentage,
+		amount,
+		currency,
+		promotion.BuyQuantity,
+		promotion.GetQuantity,
+		tiers,
+		productIDs,
+		categories,
+		promotion.StartsAt,
+		promotion.EndsAt,
+		promotion.UsageLimit,
+		promotion.UsageLimitPerCustomer,
+		promotion.Stackable,
+		promotion.Priority,
+		promotion.Active,
+		promotion.CreatedAt,
+		promotion.UpdatedAt,
+	}, nil
+}
+
+// decodeJSON reads a JSON column, leaving into empty for NULL
+func decodeJSON(data []byte, into interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, into)
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"database/sql"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"time"
+)
+
+type StockReservationRepositoryMySQL struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewStockReservationRepository(db *sql.DB, logger *slog.Logger) *StockReservationRepositoryMySQL {
+	return &StockReservationRepositoryMySQL{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *StockReservationRepositoryMySQL) Create(reservation *entity.StockReservation) error {
+	r.logger.Info("Creating stock reservation",
+		"reservation_id", reservation.ID,
+		"order_id", reservation.OrderID,
+		"product_id", reservation.ProductID,
+	)
+
+	query := `
+		INSERT INTO stock_reservations (id, order_id, product_id, quantity, status, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := r.db.Exec(query,
+		reservation.ID,
+		reservation.OrderID,
+		reservation.ProductID,
+		reservation.Quantity,
+		reservation.Status,
+		reservation.ExpiresAt,
+		reservation.CreatedAt,
+		reservation.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error("Failed to create stock reservation", "reservation_id", reservation.ID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *StockReservationRepositoryMySQL) FindByOrderID(orderID string) ([]entity.StockReservation, error) {
+	query := `
+		SELECT id, order_id, product_id, quantity, status, expires_at, created_at, updated_at
+		FROM stock_reservations
+		WHERE order_id = ?
+		ORDER BY created_at
+	`
+	return r.query(query, orderID)
+}
+
+func (r *StockReservationRepositoryMySQL) FindActive(orderID, productID string) (*entity.StockReservation, error) {
+	query := `
+		SELECT id, order_id, product_id, quantity, status, expires_at, created_at, updated_at
+		FROM stock_reservations
+		WHERE order_id = ? AND product_id = ? AND status = ?
+		LIMIT 1
+	`
+	reservations, err := r.query(query, orderID, productID, entity.ReservationStatusReserved)
+	if err != nil {
+		return nil, err
+	}
+	if len(reservations) == 0 {
+		return nil, nil
+	}
+	return &reservations[0], nil
+}
+
+func (r *StockReservationRepositoryMySQL) FindExpired(now time.Time) ([]entity.StockReservation, error) {
+	query := `
+		SELECT id, order_id, product_id, quantity, status, expires_at, created_at, updated_at
+		FROM stock_reservations
+		WHERE status = ? AND expires_at IS NOT NULL AND expires_at <= ?
+		ORDER BY expires_at
+	`
+	return r.query(query, entity.ReservationStatusReserved, now)
+}
+
+func (r *StockReservationRepositoryMySQL) Update(reservation *entity.StockReservation, previous entity.StockReservation) error {
+	r.logger.Info("Updating stock reservation",
+		"reservation_id", reservation.ID,
+		"status", reservation.Status,
+		"quantity", reservation.Quantity,
+	)
+
+	return r.update(r.db, reservation, previous)
+}
+
+func (r *StockReservationRepositoryMySQL) GiveBack(reservation *entity.StockReservation, previous entity.StockReservation, quantity int) error {
+	r.logger.Info("Giving back reserved stock",
+		"reservation_id", reservation.ID,
+		"product_id", reservation.ProductID,
+		"quantity", quantity,
+	)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "reservation_id", reservation.ID, "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// Two requests releasing the reservation at once (e.g. expiry and
+	// cancel) give the stock back once
+	if err := r.update(tx, reservation, previous); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE products
+		SET stock = stock + ?, updated_at = ?
+		WHERE id = ?
+	`, quantity, time.Now(), reservation.ProductID)
+	if err != nil {
+		r.logger.Error("Failed to increase product stock", "product_id", reservation.ProductID, "error", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Failed to read affected rows", "product_id", reservation.ProductID, "error", err)
+		return err
+	}
+	if affected == 0 {
+		r.logger.Warn("Product not found", "product_id", reservation.ProductID)
+		return sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "reservation_id", reservation.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// update saves the reservation only over the reservation as it was loaded,
+// so a request that loaded it before another one changed it cannot write
+// the old state back
+func (r *StockReservationRepositoryMySQL) update(db dbtx, reservation *entity.StockReservation, previous entity.StockReservation) error {
+	query := `
+		UPDATE stock_reservations
+		SET quantity = ?, status = ?, expires_at = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND quantity = ? AND expires_at <=> ?
+	`
+	result, err := db.Exec(query,
+		reservation.Quantity,
+		reservation.Status,
+		reservation.ExpiresAt,
+		reservation.UpdatedAt,
+		reservation.ID,
+		previous.Status,
+		previous.Quantity,
+		previous.ExpiresAt,
+	)
+	if err != nil {
+		r.logger.Error("Failed to update stock reservation", "reservation_id", reservation.ID, "error", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Failed to read affected rows", "reservation_id", reservation.ID, "error", err)
+		return err
+	}
+	if affected == 0 {
+		r.logger.Warn("Stock reservation changed concurrently", "reservation_id", reservation.ID)
+		return entity.ErrReservationConflict
+	}
+	return nil
+}
+
+func (r *StockReservationRepositoryMySQL) query(query string, args ...interface{}) ([]entity.StockReservation, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		r.logger.Error("Failed to query stock reservations", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []entity.StockReservation
+	for rows.Next() {
+		var reservation entity.StockReservation
+		var expiresAt sql.NullTime
+		err := rows.Scan(
+			&reservation.ID,
+			&reservation.OrderID,
+			&reservation.ProductID,
+			&reservation.Quantity,
+			&reservation.Status,
+			&expiresAt,
+			&reservation.CreatedAt,
+			&reservation.UpdatedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan stock reservation", "error", err)
+			return nil, err
+		}
+		if expiresAt.Valid {
+			reservation.ExpiresAt = &expiresAt.Time
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, rows.Err()
+}
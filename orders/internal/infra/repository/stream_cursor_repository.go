@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+type StreamCursorRepositoryMySQL struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewStreamCursorRepository(db *sql.DB, logger *slog.Logger) *StreamCursorRepositoryMySQL {
+	return &StreamCursorRepositoryMySQL{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *StreamCursorRepositoryMySQL) Find(stream string) (string, error) {
+	var cursor string
+	err := r.db.QueryRow("SELECT cursor_value FROM stream_cursors WHERE stream = ?", stream).Scan(&cursor)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to find stream cursor", "stream", stream, "error", err)
+		return "", err
+	}
+	return cursor, nil
+}
+
+func (r *StreamCursorRepositoryMySQL) Save(stream, cursor string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO stream_cursors (stream, cursor_value, updated_at)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE cursor_value = VALUES(cursor_value), updated_at = VALUES(updated_at)
+	`, stream, cursor, time.Now())
+	if err != nil {
+		r.logger.Error("Failed to save stream cursor", "stream", stream, "error", err)
+		return err
+	}
+	return nil
+}
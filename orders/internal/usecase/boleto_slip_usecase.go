@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"orders/internal/infra/grpc/client"
+)
+
+type BoletoSlipOutput struct {
+	PaymentID   string
+	ContentType string
+	Content     []byte
+}
+
+// BoletoSlipUseCase devolve o boleto de um pedido pronto para impressão,
+// gerado pelo payments service
+type BoletoSlipUseCase struct {
+	orderRepo     repository.OrderRepository
+	paymentClient *client.PaymentClient
+	logger        *slog.Logger
+}
+
+func NewBoletoSlipUseCase(
+	orderRepo repository.OrderRepository,
+	paymentClient *client.PaymentClient,
+	logger *slog.Logger,
+) *BoletoSlipUseCase {
+	return &BoletoSlipUseCase{
+		orderRepo:     orderRepo,
+		paymentClient: paymentClient,
+		logger:        logger,
+	}
+}
+
+func (uc *BoletoSlipUseCase) Execute(ctx context.Context, orderID string) (*BoletoSlipOutput, error) {
+	order, err := uc.orderRepo.FindByID(orderID)
+	if err != nil {
+		uc.logger.Error("Failed to find order", "error", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to find order: %w", err)
+	}
+
+	payments, err := uc.paymentClient.ListPayments(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ListPayments ordena do mais recente para o mais antigo
+	for _, payment := range payments.Payments {
+		if payment.GetBoletoCharge() == nil {
+			continue
+		}
+
+		slip, err := uc.paymentClient.GetBoletoSlip(ctx, payment.PaymentId)
+		if err != nil {
+			return nil, err
+		}
+		return &BoletoSlipOutput{
+			PaymentID:   slip.PaymentId,
+			ContentType: slip.ContentType,
+			Content:     slip.Content,
+		}, nil
+	}
+
+	return nil, entity.ErrBoletoNotFound
+}
@@ -6,28 +6,36 @@ import (
 	"log/slog"
 	"orders/internal/domain/entity"
 	"orders/internal/domain/repository"
-	"orders/internal/infra/grpc/client"
 )
 
+// defaultCancelReason is sent to the payments service when the client does
+// not give one
+const defaultCancelReason = "order canceled"
+
 type CancelOrderUseCase struct {
-	orderRepo     repository.OrderRepository
-	paymentClient *client.PaymentClient
-	logger        *slog.Logger
+	orderRepo    repository.OrderRepository
+	checkoutSaga *CheckoutSagaUseCase
+	logger       *slog.Logger
 }
 
 func NewCancelOrderUseCase(
 	orderRepo repository.OrderRepository,
-	paymentClient *client.PaymentClient,
+	checkoutSaga *CheckoutSagaUseCase,
 	logger *slog.Logger,
 ) *CancelOrderUseCase {
 	return &CancelOrderUseCase{
-		orderRepo:     orderRepo,
-		paymentClient: paymentClient,
-		logger:        logger,
+		orderRepo:    orderRepo,
+		checkoutSaga: checkoutSaga,
+		logger:       logger,
 	}
 }
 
-func (uc *CancelOrderUseCase) Execute(ctx context.Context, orderID string, paymentID string) error {
+// Execute cancela o pedido desfazendo sua saga de checkout: o pagamento é
+// reembolsado ou cancelado antes de o estoque ser devolvido e o pedido
+// marcado como cancelado. Se algum passo falhar, o pedido continua como
+// está e o erro envolve entity.ErrCancellationPending: a saga termina o
+// cancelamento em segundo plano.
+func (uc *CancelOrderUseCase) Execute(ctx context.Context, orderID string, reason string) error {
 	// 1. Buscar pedido
 	order, err := uc.orderRepo.FindByID(orderID)
 	if err != nil {
@@ -35,29 +43,25 @@ func (uc *CancelOrderUseCase) Execute(ctx context.Context, orderID string, payme
 		return fmt.Errorf("failed to find order: %w", err)
 	}
 
-	// 2. Cancelar pagamento via gRPC
-	if paymentID != "" {
-		_, err := uc.paymentClient.CancelPayment(ctx, paymentID)
-		if err != nil {
-			uc.logger.Error("Failed to cancel payment",
-				"error", err,
-				"payment_id", paymentID,
-				"order_id", orderID,
-			)
-			// Continuar mesmo se falhar o cancelamento do pagamento
-		} else {
-			uc.logger.Info("Payment canceled successfully",
-				"payment_id", paymentID,
-				"order_id", orderID,
-			)
-		}
+	// Pedidos já enviados não podem ser cancelados, apenas reembolsados
+	if order.Status != entity.OrderStatusCanceled && !order.Status.CanTransitionTo(entity.OrderStatusCanceled) {
+		uc.logger.Warn("Order cannot be canceled", "order_id", orderID, "status", order.Status)
+		return fmt.Errorf("%w: %s to %s", entity.ErrInvalidStatusTransition, order.Status, entity.OrderStatusCanceled)
+	}
+
+	if reason == "" {
+		reason = defaultCancelReason
 	}
 
-	// 3. Atualizar status do pedido
-	order.Status = entity.OrderStatusCanceled
-	if err := uc.orderRepo.Update(order); err != nil {
-		uc.logger.Error("Failed to update order status", "error", err)
-		return fmt.Errorf("failed to update order status: %w", err)
+	// 2. Compensar a saga: pagamento, estoque e por último o pedido
+	saga, err := uc.checkoutSaga.Cancel(ctx, order, reason)
+	if err != nil {
+		if saga != nil && saga.IsCompensating() {
+			uc.logger.Warn("Order cancellation will be retried", "error", err, "order_id", orderID)
+			return fmt.Errorf("%w: %v", entity.ErrCancellationPending, err)
+		}
+		uc.logger.Error("Failed to cancel order", "error", err, "order_id", orderID)
+		return err
 	}
 
 	uc.logger.Info("Order canceled successfully", "order_id", orderID)
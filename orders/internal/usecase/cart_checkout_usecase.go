@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orders/internal/domain/entity"
+	"time"
+)
+
+// ErrCustomerContactRequired indica um carrinho sem cliente cadastrado
+// finalizado sem o email e o nome de quem paga
+var ErrCustomerContactRequired = errors.New("customer email and name are required for carts without a customer")
+
+type CheckoutCartInput struct {
+	OrderID string
+	// Version é a versão do carrinho que o cliente viu (If-Match); com
+	// AnyVersion o carrinho é finalizado como estiver
+	Version int
+	// CustomerEmail e CustomerName são obrigatórios em carrinhos anônimos;
+	// nos de clientes cadastrados, substituem o email e o nome do cadastro
+	CustomerEmail  string
+	CustomerName   string
+	PaymentMethod  int32 // 1=CREDIT_CARD, 2=DEBIT_CARD, 3=PIX, 4=BOLETO, 5=PAYPAL
+	PaymentDetails *entity.PaymentDetails
+}
+
+// PriceChange é um item cobrado por um preço diferente do que tinha quando
+// foi adicionado ao carrinho
+type PriceChange struct {
+	ItemID    string
+	ProductID string
+	OldPrice  entity.Money
+	NewPrice  entity.Money
+}
+
+type CheckoutCartOutput struct {
+	CreateOrderOutput
+	// PriceChanges lista os itens cujo preço mudou no catálogo; o pedido é
+	// cobrado pelos preços novos
+	PriceChanges []PriceChange
+}
+
+// CheckoutCart transforma um carrinho no pedido pago: o mesmo registro é
+// travado, tem os itens repreçados pelo catálogo e os descontos recalculados,
+// e passa pela saga de checkout, que usa o estoque já reservado pelo
+// carrinho, cobra e marca o pedido como pago (ou cancelado, se o pagamento
+// for recusado).
+func (uc *CreateOrderUseCase) CheckoutCart(ctx context.Context, input CheckoutCartInput) (*CheckoutCartOutput, error) {
+	order, err := uc.orderRepo.FindByID(input.OrderID)
+	if err != nil {
+		uc.logger.Error("Failed to find cart", "error", err, "order_id", input.OrderID)
+		return nil, err
+	}
+	if input.Version != AnyVersion && input.Version != order.Version {
+		uc.logger.Warn("Cart version mismatch", "order_id", order.ID, "version", order.Version, "if_match", input.Version)
+		return nil, entity.ErrOrderVersionMismatch
+	}
+	if order.IsPlaced() {
+		uc.logger.Warn("Cart was already checked out", "order_id", order.ID, "status", order.Status)
+		return nil, entity.ErrCartLocked
+	}
+	if order.IsExpired(time.Now()) {
+		uc.logger.Warn("Cart has expired", "order_id", order.ID, "expires_at", order.ExpiresAt)
+		return nil, entity.ErrCartExpired
+	}
+
+	// Conferir se os dados de pagamento correspondem ao método escolhido
+	if input.PaymentDetails != nil {
+		if err := input.PaymentDetails.Validate(input.PaymentMethod); err != nil {
+			return nil, err
+		}
+	}
+
+	// Carrinho de cliente cadastrado: completar o email e o nome com os dele
+	if order.CustomerID != "" {
+		customer, err := findCustomer(uc.customerRepo, order.CustomerID)
+		if err != nil {
+			uc.logger.Error("Failed to find cart customer", "customer_id", order.CustomerID, "error", err)
+			return nil, err
+		}
+		if input.CustomerEmail == "" {
+			input.CustomerEmail = customer.Email
+		}
+		if input.CustomerName == "" {
+			input.CustomerName = customer.Name
+		}
+	}
+	if input.CustomerEmail == "" || input.CustomerName == "" {
+		return nil, ErrCustomerContactRequired
+	}
+
+	changes, err := uc.repriceCart(order)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aplicar de novo as promoções sobre os preços atuais. Um cupom que
+	// deixou de valer (vencido, desativado ou esgotado) recusa o checkout em
+	// vez de cobrar sem o desconto que o cliente viu no carrinho.
+	couponErrs, err := uc.promotions.ApplyDiscounts(order)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range order.Coupons {
+		err := couponErrs[code]
+		if err == nil || errors.Is(err, entity.ErrCouponNotApplicable) || errors.Is(err, entity.ErrCouponNotStackable) {
+			continue
+		}
+		uc.logger.Warn("Cart coupon is no longer valid", "order_id", order.ID, "code", code, "reason", err)
+		return nil, fmt.Errorf("coupon %s: %w", code, err)
+	}
+
+	// Travar o carrinho: a versão garante que nenhuma alteração ou outro
+	// checkout concorrente foi salvo depois da leitura
+	if err := order.Place(); err != nil {
+		uc.logger.Error("Failed to place cart", "error", err, "order_id", order.ID)
+		return nil, err
+	}
+	if err := uc.orderRepo.Update(order); err != nil {
+		uc.logger.Error("Failed to lock cart", "error", err, "order_id", order.ID)
+		return nil, err
+	}
+
+	uc.logger.Info("Cart checked out",
+		"order_id", order.ID,
+		"total", order.Total.String(),
+		"discounts", len(order.Discounts),
+		"price_changes", len(changes),
+	)
+
+	saga, err := uc.checkoutSaga.Start(ctx, order, input.PaymentMethod, input.CustomerEmail, input.CustomerName, input.PaymentDetails)
+	output, err := uc.result(ctx, order, saga, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckoutCartOutput{CreateOrderOutput: *output, PriceChanges: changes}, nil
+}
+
+// repriceCart aplica aos itens o preço atual do catálogo e devolve o que
+// mudou. Produtos removidos ou fora de venda são reportados todos de uma
+// vez, por posição no carrinho, e nada é alterado.
+func (uc *CreateOrderUseCase) repriceCart(order *entity.Order) ([]PriceChange, error) {
+	var violations []entity.FieldViolation
+	changes := []PriceChange{}
+	for i, item := range order.Items {
+		field := fmt.Sprintf("items[%d]", i)
+
+		product, err := uc.catalogProduct(OrderItemInput{ProductID: item.ProductID, Quantity: item.Quantity}, false, nil)
+		if err != nil {
+			if !errors.Is(err, ErrProductNotFound) && !errors.Is(err, entity.ErrProductInactive) {
+				return nil, err
+			}
+			violations = append(violations, entity.FieldViolation{Field: field + ".product_id", Description: err.Error()})
+			continue
+		}
+
+		if product.Price.Currency != order.Total.Currency {
+			violations = append(violations, entity.FieldViolation{
+				Field:       field + ".price",
+				Description: fmt.Sprintf("catalog price %s is not in the cart currency %s", product.Price, order.Total.Currency),
+			})
+			continue
+		}
+		if product.Price != item.UnitPrice {
+			changes = append(changes, PriceChange{
+				ItemID:    item.ID,
+				ProductID: item.ProductID,
+				OldPrice:  item.UnitPrice,
+				NewPrice:  product.Price,
+			})
+		}
+	}
+	if len(violations) > 0 {
+		uc.logger.Warn("Cart has items that cannot be sold", "order_id", order.ID, "violations", len(violations))
+		return nil, &OrderItemsError{Violations: violations}
+	}
+
+	for _, change := range changes {
+		if err := order.UpdateItemPrice(change.ItemID, change.NewPrice); err != nil {
+			uc.logger.Error("Failed to reprice cart item", "error", err, "order_id", order.ID, "item_id", change.ItemID)
+			return nil, err
+		}
+		uc.logger.Info("Cart item repriced",
+			"order_id", order.ID,
+			"product_id", change.ProductID,
+			"old_price", change.OldPrice.String(),
+			"new_price", change.NewPrice.String(),
+		)
+	}
+	return changes, nil
+}
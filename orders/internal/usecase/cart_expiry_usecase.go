@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"time"
+)
+
+// CartExpiryUseCase expires carts left untouched past their expiry: the
+// cart moves to the expired status, records CartAbandoned with its contents
+// and customer, and gives its reserved stock back.
+type CartExpiryUseCase struct {
+	orderRepo        repository.OrderRepository
+	customerRepo     repository.CustomerRepository
+	stockReservation *StockReservationUseCase
+	batchSize        int
+	logger           *slog.Logger
+}
+
+func NewCartExpiryUseCase(
+	orderRepo repository.OrderRepository,
+	customerRepo repository.CustomerRepository,
+	stockReservation *StockReservationUseCase,
+	batchSize int,
+	logger *slog.Logger,
+) *CartExpiryUseCase {
+	return &CartExpiryUseCase{
+		orderRepo:        orderRepo,
+		customerRepo:     customerRepo,
+		stockReservation: stockReservation,
+		batchSize:        batchSize,
+		logger:           logger,
+	}
+}
+
+// ExpireStale expires up to one batch of carts whose expiry has passed and
+// returns how many were expired. A cart changed while it is being expired
+// is skipped, since the change extended it.
+func (uc *CartExpiryUseCase) ExpireStale(ctx context.Context) (int, error) {
+	now := time.Now()
+	carts, err := uc.orderRepo.FindExpiredCarts(now, uc.batchSize)
+	if err != nil {
+		uc.logger.Error("Failed to find expired carts", "error", err)
+		return 0, err
+	}
+
+	expired := 0
+	for _, cart := range carts {
+		if ctx.Err() != nil {
+			break
+		}
+		if uc.expire(cart.ID, now) {
+			expired++
+		}
+	}
+
+	if expired > 0 {
+		uc.logger.Info("Expired carts", "count", expired)
+	}
+	return expired, nil
+}
+
+// Run expires stale carts every interval until ctx is canceled
+func (uc *CartExpiryUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Drain the backlog before waiting for the next tick
+			for ctx.Err() == nil {
+				expired, err := uc.ExpireStale(ctx)
+				if err != nil || expired < uc.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// expire expires one cart, reading it again with its items, and tells
+// whether it did. Failures are logged and the cart is left for the next run.
+func (uc *CartExpiryUseCase) expire(orderID string, now time.Time) bool {
+	cart, err := uc.orderRepo.FindByID(orderID)
+	if err != nil {
+		uc.logger.Error("Failed to load expired cart", "error", err, "order_id", orderID)
+		return false
+	}
+	if cart.Status != entity.OrderStatusPending || !cart.IsExpired(now) {
+		return false
+	}
+
+	// The abandoned cart record still goes out without the customer contact
+	var customer *entity.Customer
+	if cart.CustomerID != "" {
+		customer, err = uc.customerRepo.FindByID(cart.CustomerID)
+		if err != nil {
+			uc.logger.Warn("Failed to find customer of expired cart", "error", err, "order_id", orderID, "customer_id", cart.CustomerID)
+			customer = nil
+		}
+	}
+
+	if err := cart.Expire(customer); err != nil {
+		uc.logger.Error("Failed to expire cart", "error", err, "order_id", orderID)
+		return false
+	}
+	if err := uc.orderRepo.Update(cart); err != nil {
+		if errors.Is(err, entity.ErrOrderConflict) {
+			uc.logger.Info("Cart changed while expiring, skipped", "order_id", orderID)
+		} else {
+			uc.logger.Error("Failed to save expired cart", "error", err, "order_id", orderID)
+		}
+		return false
+	}
+
+	// Reservations not released here still expire with their own TTL
+	if err := uc.stockReservation.Release(orderID); err != nil {
+		uc.logger.Error("Failed to release stock of expired cart", "error", err, "order_id", orderID)
+	}
+
+	uc.logger.Info("Cart expired",
+		"order_id", orderID,
+		"customer_id", cart.CustomerID,
+		"items_count", len(cart.Items),
+		"total", cart.Total.String(),
+	)
+	return true
+}
@@ -5,35 +5,69 @@ import (
 	"log/slog"
 	"orders/internal/domain/entity"
 	"orders/internal/domain/repository"
+	"time"
 )
 
 var (
 	ErrProductNotFound = errors.New("product not found")
 )
 
+// AnyVersion changes a cart whatever its current version, for requests sent
+// without If-Match. Concurrent changes still fail with
+// entity.ErrOrderConflict instead of overwriting each other.
+const AnyVersion = -1
+
 type CartUseCase struct {
-	orderRepo   repository.OrderRepository
-	productRepo repository.ProductRepository
-	logger      *slog.Logger
+	orderRepo        repository.OrderRepository
+	productRepo      repository.ProductRepository
+	customerRepo     repository.CustomerRepository
+	stockReservation *StockReservationUseCase
+	promotions       *PromotionUseCase
+	// cartTTL and guestCartTTL are how long carts of customers and
+	// anonymous carts live after their last change
+	cartTTL      time.Duration
+	guestCartTTL time.Duration
+	logger       *slog.Logger
 }
 
 func NewCartUseCase(
 	orderRepo repository.OrderRepository,
 	productRepo repository.ProductRepository,
+	customerRepo repository.CustomerRepository,
+	stockReservation *StockReservationUseCase,
+	promotions *PromotionUseCase,
+	cartTTL time.Duration,
+	guestCartTTL time.Duration,
 	logger *slog.Logger,
 ) *CartUseCase {
 	return &CartUseCase{
-		orderRepo:   orderRepo,
-		productRepo: productRepo,
-		logger:      logger,
+		orderRepo:        orderRepo,
+		productRepo:      productRepo,
+		customerRepo:     customerRepo,
+		stockReservation: stockReservation,
+		promotions:       promotions,
+		cartTTL:          cartTTL,
+		guestCartTTL:     guestCartTTL,
+		logger:           logger,
 	}
 }
 
-// CreateOrder creates a new order (cart)
-func (uc *CartUseCase) CreateOrder() (*entity.Order, error) {
-	uc.logger.Info("Creating new cart/order")
+// CreateOrder creates a new order (cart), owned by the customer when
+// customerID is set or anonymous otherwise
+func (uc *CartUseCase) CreateOrder(customerID string) (*entity.Order, error) {
+	uc.logger.Info("Creating new cart/order", "customer_id", customerID)
 
 	order := entity.NewOrder()
+	if customerID != "" {
+		customer, err := findCustomer(uc.customerRepo, customerID)
+		if err != nil {
+			uc.logger.Error("Failed to find cart customer", "customer_id", customerID, "error", err)
+			return nil, err
+		}
+		order.CustomerID = customer.ID
+	}
+	uc.extendExpiry(order)
+
 	err := uc.orderRepo.Create(order)
 	if err != nil {
 		uc.logger.Error("Failed to create cart/order", "error", err)
@@ -44,14 +78,14 @@ func (uc *CartUseCase) CreateOrder() (*entity.Order, error) {
 	return order, nil
 }
 
-// AddItemToCart adds an item to the order/cart
-func (uc *CartUseCase) AddItemToCart(orderID, productID string, quantity int) (*entity.Order, error) {
+// AddItemToCart adds an item to the order/cart, if the cart is still at
+// version (see AnyVersion)
+func (uc *CartUseCase) AddItemToCart(orderID, productID string, quantity, version int) (*entity.Order, error) {
 	uc.logger.Info("Adding item to cart", "order_id", orderID, "product_id", productID, "quantity", quantity)
 
 	// Get order
-	order, err := uc.orderRepo.FindByID(orderID)
+	order, err := uc.findOpenCart(orderID, version)
 	if err != nil {
-		uc.logger.Error("Failed to find order", "order_id", orderID, "error", err)
 		return nil, err
 	}
 
@@ -61,6 +95,10 @@ func (uc *CartUseCase) AddItemToCart(orderID, productID string, quantity int) (*
 		uc.logger.Error("Product not found", "product_id", productID, "error", err)
 		return nil, ErrProductNotFound
 	}
+	if !product.Active {
+		uc.logger.Warn("Product is not for sale", "product_id", productID)
+		return nil, entity.ErrProductInactive
+	}
 
 	// Create item
 	item, err := entity.NewItem(orderID, productID, product, quantity)
@@ -69,13 +107,26 @@ func (uc *CartUseCase) AddItemToCart(orderID, productID string, quantity int) (*
 		return nil, err
 	}
 
+	// Reserve stock before the item lands in the cart
+	if err := uc.stockReservation.ReserveForCart(orderID, productID, quantity); err != nil {
+		uc.logger.Error("Failed to reserve stock", "order_id", orderID, "product_id", productID, "error", err)
+		return nil, err
+	}
+
 	// Add item to order
-	order.AddItem(item)
+	if err := order.AddItem(item); err != nil {
+		uc.logger.Error("Failed to add item to order", "order_id", orderID, "product_id", productID, "error", err)
+		uc.releaseQuantity(orderID, productID, quantity)
+		return nil, err
+	}
 
 	// Update order
+	uc.applyDiscounts(order)
+	uc.extendExpiry(order)
 	err = uc.orderRepo.Update(order)
 	if err != nil {
 		uc.logger.Error("Failed to update order with new item", "order_id", orderID, "error", err)
+		uc.releaseQuantity(orderID, productID, quantity)
 		return nil, err
 	}
 
@@ -83,58 +134,160 @@ func (uc *CartUseCase) AddItemToCart(orderID, productID string, quantity int) (*
 	return order, nil
 }
 
-// RemoveItemFromCart removes an item from the cart
-func (uc *CartUseCase) RemoveItemFromCart(orderID, itemID string) (*entity.Order, error) {
+// RemoveItemFromCart removes an item from the cart, if the cart is still at
+// version
+func (uc *CartUseCase) RemoveItemFromCart(orderID, itemID string, version int) (*entity.Order, error) {
 	uc.logger.Info("Removing item from cart", "order_id", orderID, "item_id", itemID)
 
-	order, err := uc.orderRepo.FindByID(orderID)
+	order, err := uc.findOpenCart(orderID, version)
 	if err != nil {
-		uc.logger.Error("Failed to find order", "order_id", orderID, "error", err)
 		return nil, err
 	}
 
+	item, err := findItem(order, itemID)
+	if err != nil {
+		uc.logger.Error("Failed to remove item from order", "order_id", orderID, "item_id", itemID, "error", err)
+		return nil, err
+	}
+	productID, quantity := item.ProductID, item.Quantity
+
 	err = order.RemoveItem(itemID)
 	if err != nil {
 		uc.logger.Error("Failed to remove item from order", "order_id", orderID, "item_id", itemID, "error", err)
 		return nil, err
 	}
 
+	uc.applyDiscounts(order)
+	uc.extendExpiry(order)
 	err = uc.orderRepo.Update(order)
 	if err != nil {
 		uc.logger.Error("Failed to update order after removing item", "order_id", orderID, "error", err)
 		return nil, err
 	}
 
+	uc.releaseQuantity(orderID, productID, quantity)
+
 	uc.logger.Info("Item removed from cart successfully", "order_id", orderID, "item_id", itemID)
 	return order, nil
 }
 
-// UpdateItemQuantity updates the quantity of an item in the cart
-func (uc *CartUseCase) UpdateItemQuantity(orderID, itemID string, quantity int) (*entity.Order, error) {
+// UpdateItemQuantity updates the quantity of an item in the cart, if the
+// cart is still at version
+func (uc *CartUseCase) UpdateItemQuantity(orderID, itemID string, quantity, version int) (*entity.Order, error) {
 	uc.logger.Info("Updating item quantity", "order_id", orderID, "item_id", itemID, "quantity", quantity)
 
-	order, err := uc.orderRepo.FindByID(orderID)
+	order, err := uc.findOpenCart(orderID, version)
 	if err != nil {
-		uc.logger.Error("Failed to find order", "order_id", orderID, "error", err)
 		return nil, err
 	}
 
+	item, err := findItem(order, itemID)
+	if err != nil {
+		uc.logger.Error("Failed to update item quantity", "order_id", orderID, "item_id", itemID, "error", err)
+		return nil, err
+	}
+	productID, delta := item.ProductID, quantity-item.Quantity
+
+	// Only the difference is reserved; a lower quantity is released after saving
+	if delta > 0 {
+		if err := uc.stockReservation.ReserveForCart(orderID, productID, delta); err != nil {
+			uc.logger.Error("Failed to reserve stock", "order_id", orderID, "product_id", productID, "error", err)
+			return nil, err
+		}
+	}
+
 	err = order.UpdateItemQuantity(itemID, quantity)
 	if err != nil {
 		uc.logger.Error("Failed to update item quantity", "order_id", orderID, "item_id", itemID, "error", err)
+		if delta > 0 {
+			uc.releaseQuantity(orderID, productID, delta)
+		}
 		return nil, err
 	}
 
+	uc.applyDiscounts(order)
+	uc.extendExpiry(order)
 	err = uc.orderRepo.Update(order)
 	if err != nil {
 		uc.logger.Error("Failed to update order after quantity change", "order_id", orderID, "error", err)
+		if delta > 0 {
+			uc.releaseQuantity(orderID, productID, delta)
+		}
 		return nil, err
 	}
 
+	if delta < 0 {
+		uc.releaseQuantity(orderID, productID, -delta)
+	}
+
 	uc.logger.Info("Item quantity updated successfully", "order_id", orderID, "item_id", itemID, "quantity", quantity)
 	return order, nil
 }
 
+// ApplyCoupon enters a coupon code in the cart, if the cart is still at
+// version. A coupon that gives the cart no discount is rejected with the
+// reason; applying a coupon again changes nothing.
+func (uc *CartUseCase) ApplyCoupon(orderID, code string, version int) (*entity.Order, error) {
+	code = entity.NormalizeCouponCode(code)
+	uc.logger.Info("Applying coupon to cart", "order_id", orderID, "code", code)
+
+	order, err := uc.findOpenCart(orderID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := order.AddCoupon(code); err != nil {
+		uc.logger.Error("Failed to add coupon to cart", "order_id", orderID, "code", code, "error", err)
+		return nil, err
+	}
+	couponErrs, err := uc.promotions.ApplyDiscounts(order)
+	if err != nil {
+		return nil, err
+	}
+	if err := couponErrs[code]; err != nil {
+		uc.logger.Warn("Coupon rejected", "order_id", orderID, "code", code, "reason", err)
+		return nil, err
+	}
+
+	uc.extendExpiry(order)
+	if err := uc.orderRepo.Update(order); err != nil {
+		uc.logger.Error("Failed to update order with coupon", "order_id", orderID, "error", err)
+		return nil, err
+	}
+
+	uc.logger.Info("Coupon applied to cart successfully", "order_id", orderID, "code", code, "total", order.Total.String())
+	return order, nil
+}
+
+// RemoveCoupon takes a coupon code out of the cart, if the cart is still at
+// version
+func (uc *CartUseCase) RemoveCoupon(orderID, code string, version int) (*entity.Order, error) {
+	code = entity.NormalizeCouponCode(code)
+	uc.logger.Info("Removing coupon from cart", "order_id", orderID, "code", code)
+
+	order, err := uc.findOpenCart(orderID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := order.RemoveCoupon(code); err != nil {
+		uc.logger.Error("Failed to remove coupon from cart", "order_id", orderID, "code", code, "error", err)
+		return nil, err
+	}
+	if _, err := uc.promotions.ApplyDiscounts(order); err != nil {
+		return nil, err
+	}
+
+	uc.extendExpiry(order)
+	if err := uc.orderRepo.Update(order); err != nil {
+		uc.logger.Error("Failed to update order after removing coupon", "order_id", orderID, "error", err)
+		return nil, err
+	}
+
+	uc.logger.Info("Coupon removed from cart successfully", "order_id", orderID, "code", code)
+	return order, nil
+}
+
 // CalculateTotal calculates and returns the total for payment
 func (uc *CartUseCase) CalculateTotal(orderID string) (*entity.Order, error) {
 	uc.logger.Info("Calculating total", "order_id", orderID)
@@ -151,7 +304,7 @@ func (uc *CartUseCase) CalculateTotal(orderID string) (*entity.Order, error) {
 		return nil, err
 	}
 
-	uc.logger.Info("Total calculated successfully", "order_id", orderID, "total", order.Total)
+	uc.logger.Info("Total calculated successfully", "order_id", orderID, "total", order.Total.String())
 	return order, nil
 }
 
@@ -159,3 +312,100 @@ func (uc *CartUseCase) CalculateTotal(orderID string) (*entity.Order, error) {
 func (uc *CartUseCase) GetCart(orderID string) (*entity.Order, error) {
 	return uc.orderRepo.FindByID(orderID)
 }
+
+// UpdateStatus moves the order to status, if the order is still at version,
+// and saves it together with its status history
+func (uc *CartUseCase) UpdateStatus(orderID string, status entity.OrderStatus, reason string, version int) (*entity.Order, error) {
+	uc.logger.Info("Updating order status", "order_id", orderID, "status", status)
+
+	order, err := uc.findCart(orderID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := order.TransitionTo(status, entity.ActorAPI, reason); err != nil {
+		uc.logger.Error("Failed to update order status", "order_id", orderID, "status", status, "error", err)
+		return nil, err
+	}
+
+	if err := uc.orderRepo.Update(order); err != nil {
+		uc.logger.Error("Failed to save order status", "order_id", orderID, "error", err)
+		return nil, err
+	}
+
+	uc.logger.Info("Order status updated successfully", "order_id", orderID, "status", order.Status)
+	return order, nil
+}
+
+// findCart loads the cart a change applies to and checks the client based
+// the change on its current version
+func (uc *CartUseCase) findCart(orderID string, version int) (*entity.Order, error) {
+	order, err := uc.orderRepo.FindByID(orderID)
+	if err != nil {
+		uc.logger.Error("Failed to find order", "order_id", orderID, "error", err)
+		return nil, err
+	}
+	if version != AnyVersion && version != order.Version {
+		uc.logger.Warn("Cart version mismatch", "order_id", orderID, "version", order.Version, "if_match", version)
+		return nil, entity.ErrOrderVersionMismatch
+	}
+	return order, nil
+}
+
+// findOpenCart is findCart for changes to the items, which are locked once
+// the cart is checked out and gone once it expired
+func (uc *CartUseCase) findOpenCart(orderID string, version int) (*entity.Order, error) {
+	order, err := uc.findCart(orderID, version)
+	if err != nil {
+		return nil, err
+	}
+	if order.IsPlaced() {
+		uc.logger.Warn("Cart was checked out", "order_id", orderID, "status", order.Status)
+		return nil, entity.ErrCartLocked
+	}
+	if order.IsExpired(time.Now()) {
+		uc.logger.Warn("Cart has expired", "order_id", orderID, "expires_at", order.ExpiresAt)
+		return nil, entity.ErrCartExpired
+	}
+	return order, nil
+}
+
+// applyDiscounts prices the cart again after its items changed, only
+// logging failures: the cart is then saved without discounts, and they are
+// applied again at checkout anyway
+func (uc *CartUseCase) applyDiscounts(order *entity.Order) {
+	couponErrs, err := uc.promotions.ApplyDiscounts(order)
+	if err != nil {
+		uc.logger.Error("Failed to apply cart discounts", "order_id", order.ID, "error", err)
+		return
+	}
+	for code, reason := range couponErrs {
+		uc.logger.Info("Coupon gives no discount", "order_id", order.ID, "code", code, "reason", reason)
+	}
+}
+
+// extendExpiry keeps the cart alive for its TTL after a change
+func (uc *CartUseCase) extendExpiry(order *entity.Order) {
+	if order.CustomerID == "" {
+		order.ExtendExpiry(uc.guestCartTTL)
+		return
+	}
+	order.ExtendExpiry(uc.cartTTL)
+}
+
+// releaseQuantity gives reserved stock back, only logging failures: a
+// reservation that could not be released still expires with the cart TTL
+func (uc *CartUseCase) releaseQuantity(orderID, productID string, quantity int) {
+	if err := uc.stockReservation.ReleaseQuantity(orderID, productID, quantity); err != nil {
+		uc.logger.Error("Failed to release reserved stock", "order_id", orderID, "product_id", productID, "error", err)
+	}
+}
+
+func findItem(order *entity.Order, itemID string) (*entity.Item, error) {
+	for i := range order.Items {
+		if order.Items[i].ID == itemID {
+			return &order.Items[i], nil
+		}
+	}
+	return nil, entity.ErrItemNotFound
+}
@@ -0,0 +1,420 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"orders/internal/infra/grpc/client"
+	pb "orders/proto"
+	"time"
+)
+
+// maxStepAttempts is how many times reserving stock or authorizing a payment
+// may fail (e.g. payments service unreachable) before the checkout is given
+// up and undone. Confirming an approved order and the compensation steps are
+// never given up: they are retried until they succeed.
+const maxStepAttempts = 5
+
+// errPaymentPending means the payment was accepted but not decided yet; the
+// saga waits at authorize_payment and asks again when it is resumed
+var errPaymentPending = errors.New("payment is still being processed")
+
+// CheckoutSagaUseCase orchestrates the checkout of an order: reserve stock,
+// authorize payment and confirm the order, undoing what was done when a step
+// fails for good or the order is canceled. The saga is saved after every
+// step, and every step is idempotent, so a checkout interrupted by a crash is
+// finished by ResumePending without reserving stock or charging twice. An
+// order is only marked paid once its payment is approved; card payments are
+// only authorized, leaving the order authorized until they are captured when
+// it is shipped or completed. Orders are only marked canceled once their
+// payment was refunded, voided or canceled.
+type CheckoutSagaUseCase struct {
+	sagaRepo         repository.CheckoutSagaRepository
+	orderRepo        repository.OrderRepository
+	stockReservation *StockReservationUseCase
+	paymentClient    *client.PaymentClient
+	batchSize        int
+	logger           *slog.Logger
+}
+
+func NewCheckoutSagaUseCase(
+	sagaRepo repository.CheckoutSagaRepository,
+	orderRepo repository.OrderRepository,
+	stockReservation *StockReservationUseCase,
+	paymentClient *client.PaymentClient,
+	batchSize int,
+	logger *slog.Logger,
+) *CheckoutSagaUseCase {
+	return &CheckoutSagaUseCase{
+		sagaRepo:         sagaRepo,
+		orderRepo:        orderRepo,
+		stockReservation: stockReservation,
+		paymentClient:    paymentClient,
+		batchSize:        batchSize,
+		logger:           logger,
+	}
+}
+
+// Start creates the saga of a saved order and runs it as far as it can go.
+// The returned error is the step failure that stopped the checkout: either
+// a permanent one (e.g. entity.ErrInsufficientStock, after the checkout was
+// undone) or a transient one left for ResumePending to retry. The payment
+// details are only used by this run and are not saved with the saga.
+func (uc *CheckoutSagaUseCase) Start(ctx context.Context, order *entity.Order, paymentMethod int32, customerEmail, customerName string, details *entity.PaymentDetails) (*entity.CheckoutSaga, error) {
+	saga := entity.NewCheckoutSaga(order.ID, paymentMethod, customerEmail, customerName)
+	if err := uc.sagaRepo.Create(saga); err != nil {
+		uc.logger.Error("Failed to create checkout saga", "error", err, "order_id", order.ID)
+		return nil, fmt.Errorf("failed to create checkout saga: %w", err)
+	}
+
+	uc.logger.Info("Checkout saga started", "saga_id", saga.ID, "order_id", order.ID)
+
+	return saga, uc.run(ctx, saga, order, details)
+}
+
+// Resume continues the unfinished saga of an order, with the payment details
+// of the retried request. It returns nil without error when the order has no
+// saga.
+func (uc *CheckoutSagaUseCase) Resume(ctx context.Context, order *entity.Order, details *entity.PaymentDetails) (*entity.CheckoutSaga, error) {
+	saga, err := uc.sagaRepo.FindByOrderID(order.ID)
+	if err != nil {
+		uc.logger.Error("Failed to find checkout saga", "error", err, "order_id", order.ID)
+		return nil, fmt.Errorf("failed to find checkout saga: %w", err)
+	}
+	if saga == nil || saga.IsFinished() {
+		return saga, nil
+	}
+
+	return saga, uc.run(ctx, saga, order, details)
+}
+
+// Cancel undoes the checkout of an order, refunding or canceling its payment
+// before releasing its stock and marking it canceled. When a step fails the
+// saga is left compensating and the error is returned; the order is not
+// marked canceled until ResumePending (or another Cancel) finishes the job.
+// Orders without a saga, such as carts, get one that starts compensating.
+func (uc *CheckoutSagaUseCase) Cancel(ctx context.Context, order *entity.Order, reason string) (*entity.CheckoutSaga, error) {
+	saga, err := uc.sagaRepo.FindByOrderID(order.ID)
+	if err != nil {
+		uc.logger.Error("Failed to find checkout saga", "error", err, "order_id", order.ID)
+		return nil, fmt.Errorf("failed to find checkout saga: %w", err)
+	}
+
+	if saga == nil {
+		saga = entity.NewCheckoutSaga(order.ID, 0, "", "")
+		saga.Compensate(reason)
+		if err := uc.sagaRepo.Create(saga); err != nil {
+			uc.logger.Error("Failed to create checkout saga", "error", err, "order_id", order.ID)
+			return nil, fmt.Errorf("failed to create checkout saga: %w", err)
+		}
+	} else {
+		for {
+			if err := saga.Compensate(reason); err != nil {
+				if errors.Is(err, entity.ErrSagaFinished) {
+					// Already canceled
+					return saga, nil
+				}
+				return nil, err
+			}
+			err := uc.sagaRepo.Update(saga)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, entity.ErrSagaConflict) {
+				return nil, fmt.Errorf("failed to save checkout saga: %w", err)
+			}
+			// Someone else moved the saga on: compensate from where it is now
+			if saga, err = uc.reload(saga); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	uc.logger.Info("Checkout saga compensating", "saga_id", saga.ID, "order_id", order.ID, "reason", reason)
+
+	return saga, uc.run(ctx, saga, order, nil)
+}
+
+// ResumePending runs up to one batch of sagas left unfinished by a crash, a
+// failed step or a payment still being processed, and returns how many were
+// resumed. Sagas saved less than staleAfter ago are skipped, since a request
+// may still be working on them. A saga that fails again is logged and
+// retried on a later call.
+func (uc *CheckoutSagaUseCase) ResumePending(ctx context.Context, staleAfter time.Duration) (int, error) {
+	sagas, err := uc.sagaRepo.FindUnfinished(time.Now().Add(-staleAfter), uc.batchSize)
+	if err != nil {
+		uc.logger.Error("Failed to load unfinished checkout sagas", "error", err)
+		return 0, err
+	}
+
+	resumed := 0
+	for i := range sagas {
+		saga := &sagas[i]
+
+		order, err := uc.orderRepo.FindByID(saga.OrderID)
+		if err != nil {
+			uc.logger.Error("Failed to load order of checkout saga", "error", err, "saga_id", saga.ID, "order_id", saga.OrderID)
+			continue
+		}
+
+		uc.logger.Info("Resuming checkout saga",
+			"saga_id", saga.ID,
+			"order_id", saga.OrderID,
+			"status", saga.Status,
+			"step", saga.Step,
+		)
+		if err := uc.run(ctx, saga, order, nil); err != nil {
+			uc.logger.Warn("Checkout saga did not finish", "error", err, "saga_id", saga.ID, "order_id", saga.OrderID)
+		}
+		resumed++
+	}
+
+	return resumed, nil
+}
+
+// Run resumes pending sagas every interval until ctx is canceled
+func (uc *CheckoutSagaUseCase) Run(ctx context.Context, interval, staleAfter time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			uc.ResumePending(ctx, staleAfter)
+		}
+	}
+}
+
+// run executes the saga from its current step until it finishes, waits for
+// a payment, or a step fails. The saga is saved after every step.
+func (uc *CheckoutSagaUseCase) run(ctx context.Context, saga *entity.CheckoutSaga, order *entity.Order, details *entity.PaymentDetails) error {
+	var failure error
+
+	for !saga.IsFinished() {
+		err := uc.execute(ctx, saga, order, details)
+
+		var stepErr error
+		switch {
+		case err == nil:
+			saga.Advance()
+		case errors.Is(err, errPaymentPending):
+			// Saved so the payment ID is kept and the next poll waits staleAfter
+			uc.logger.Info("Checkout waiting for payment", "saga_id", saga.ID, "payment_id", saga.PaymentID)
+			saga.UpdatedAt = time.Now()
+			return uc.save(saga)
+		case errors.Is(err, entity.ErrInsufficientStock), errors.Is(err, entity.ErrPaymentDeclined),
+			errors.Is(err, entity.ErrPaymentExpired), errors.Is(err, entity.ErrInvalidPaymentDetails),
+			errors.Is(err, entity.ErrInvalidPaymentRequest):
+			uc.logger.Warn("Checkout failed, compensating", "error", err, "saga_id", saga.ID, "step", saga.Step)
+			failure = err
+			if errors.Is(err, entity.ErrPaymentDeclined) {
+				uc.markPaymentFailed(order, err)
+			}
+			saga.Compensate(err.Error())
+		default:
+			uc.logger.Error("Checkout saga step failed",
+				"error", err,
+				"saga_id", saga.ID,
+				"order_id", saga.OrderID,
+				"status", saga.Status,
+				"step", saga.Step,
+				"attempts", saga.Attempts+1,
+			)
+			stepErr = fmt.Errorf("checkout step %s failed: %w", saga.Step, err)
+			saga.RecordFailure(err)
+			// A temporary failure leaves the order pending while the step is
+			// retried; only giving up on the payment marks it failed
+			if uc.canGiveUp(saga) && saga.Attempts >= maxStepAttempts {
+				if saga.Step == entity.SagaStepAuthorizePayment {
+					uc.markPaymentFailed(order, err)
+				}
+				failure = stepErr
+				saga.Compensate(fmt.Sprintf("%s failed %d times: %v", saga.Step, saga.Attempts, err))
+				stepErr = nil
+			}
+		}
+
+		if err := uc.save(saga); err != nil {
+			return err
+		}
+		if stepErr != nil {
+			return stepErr
+		}
+	}
+
+	uc.logger.Info("Checkout saga finished", "saga_id", saga.ID, "order_id", saga.OrderID, "status", saga.Status)
+	return failure
+}
+
+// canGiveUp tells whether the checkout may still be undone after its current
+// step failed: once the payment is approved the order must be confirmed
+func (uc *CheckoutSagaUseCase) canGiveUp(saga *entity.CheckoutSaga) bool {
+	return saga.Status == entity.SagaStatusRunning && saga.Step != entity.SagaStepConfirmOrder
+}
+
+func (uc *CheckoutSagaUseCase) execute(ctx context.Context, saga *entity.CheckoutSaga, order *entity.Order, details *entity.PaymentDetails) error {
+	switch saga.Step {
+	case entity.SagaStepReserveStock:
+		return uc.stockReservation.ReserveItems(saga.OrderID, order.Items)
+	case entity.SagaStepAuthorizePayment:
+		return uc.authorizePayment(ctx, saga, order, details)
+	case entity.SagaStepConfirmOrder:
+		return uc.confirmOrder(saga)
+	case entity.SagaStepUndoPayment:
+		return uc.undoPayment(ctx, saga)
+	case entity.SagaStepReleaseStock:
+		return uc.stockReservation.Release(saga.OrderID)
+	case entity.SagaStepCancelOrder:
+		return uc.setOrderStatus(saga.OrderID, entity.OrderStatusCanceled, saga.FailureReason)
+	default:
+		return fmt.Errorf("unknown checkout saga step %q", saga.Step)
+	}
+}
+
+// authorizePayment charges the order, or only authorizes card payments so
+// they are captured when the order is completed. The order ID is the
+// idempotency key of the payment, so asking again returns the payment
+// created the first time.
+func (uc *CheckoutSagaUseCase) authorizePayment(ctx context.Context, saga *entity.CheckoutSaga, order *entity.Order, details *entity.PaymentDetails) error {
+	var paymentID string
+	var status pb.PaymentStatus
+
+	if saga.CapturesLater() {
+		response, err := uc.paymentClient.AuthorizePayment(ctx, order.ID, order.Total, saga.PaymentMethod, saga.CustomerEmail, saga.CustomerName, order.ID, details)
+		if err != nil {
+			return err
+		}
+		paymentID, status = response.PaymentId, response.Status
+	} else {
+		response, err := uc.paymentClient.ProcessPayment(ctx, order.ID, order.Total, saga.PaymentMethod, saga.CustomerEmail, saga.CustomerName, order.ID, details)
+		if err != nil {
+			return err
+		}
+		paymentID, status = response.PaymentId, response.Status
+	}
+
+	saga.PaymentID = paymentID
+
+	switch status {
+	case pb.PaymentStatus_PAYMENT_STATUS_APPROVED, pb.PaymentStatus_PAYMENT_STATUS_AUTHORIZED:
+		return nil
+	case pb.PaymentStatus_PAYMENT_STATUS_DECLINED:
+		return entity.ErrPaymentDeclined
+	case pb.PaymentStatus_PAYMENT_STATUS_EXPIRED, pb.PaymentStatus_PAYMENT_STATUS_CANCELED:
+		return entity.ErrPaymentExpired
+	default:
+		// PIX charges stay pending until the customer pays them
+		return errPaymentPending
+	}
+}
+
+// confirmOrder keeps the reserved stock and marks the order paid, or only
+// authorized when its payment is captured later. The order is read again
+// because it may have changed since the saga started.
+func (uc *CheckoutSagaUseCase) confirmOrder(saga *entity.CheckoutSaga) error {
+	if err := uc.stockReservation.Commit(saga.OrderID); err != nil {
+		return err
+	}
+	if saga.CapturesLater() {
+		return uc.setOrderStatus(saga.OrderID, entity.OrderStatusAuthorized, "payment authorized")
+	}
+	return uc.setOrderStatus(saga.OrderID, entity.OrderStatusPaid, "payment confirmed")
+}
+
+// undoPayment refunds approved payments of the order, voids authorized ones
+// and cancels the ones still being processed. Payments are looked up instead of taken from the
+// saga, since a call that failed may still have created one.
+func (uc *CheckoutSagaUseCase) undoPayment(ctx context.Context, saga *entity.CheckoutSaga) error {
+	payments, err := uc.paymentClient.ListPayments(ctx, saga.OrderID)
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range payments.Payments {
+		switch payment.Status {
+		case pb.PaymentStatus_PAYMENT_STATUS_APPROVED, pb.PaymentStatus_PAYMENT_STATUS_PARTIALLY_REFUNDED:
+			if _, err := uc.paymentClient.RefundPayment(ctx, payment.PaymentId, nil, saga.FailureReason); err != nil {
+				return err
+			}
+		case pb.PaymentStatus_PAYMENT_STATUS_AUTHORIZED:
+			if _, err := uc.paymentClient.VoidAuthorization(ctx, payment.PaymentId, saga.FailureReason); err != nil {
+				return err
+			}
+		case pb.PaymentStatus_PAYMENT_STATUS_PENDING, pb.PaymentStatus_PAYMENT_STATUS_PROCESSING:
+			response, err := uc.paymentClient.CancelPayment(ctx, payment.PaymentId)
+			if err != nil {
+				return err
+			}
+			if !response.Success {
+				return fmt.Errorf("payment %s was not canceled: %s", payment.PaymentId, response.Message)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (uc *CheckoutSagaUseCase) setOrderStatus(orderID string, status entity.OrderStatus, reason string) error {
+	order, err := uc.orderRepo.FindByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status == status {
+		return nil
+	}
+	if err := order.TransitionTo(status, entity.ActorCheckout, reason); err != nil {
+		return err
+	}
+	return uc.orderRepo.Update(order)
+}
+
+// markPaymentFailed flags an order whose payment was declined or given up
+// on, before the saga undoes its checkout. Failures are only logged: the
+// order is canceled by the compensation regardless.
+func (uc *CheckoutSagaUseCase) markPaymentFailed(order *entity.Order, cause error) {
+	if order.Status != entity.OrderStatusPending {
+		return
+	}
+	if err := order.TransitionTo(entity.OrderStatusPaymentFailed, entity.ActorCheckout, cause.Error()); err != nil {
+		return
+	}
+	if err := uc.orderRepo.Update(order); err != nil {
+		uc.logger.Error("Failed to update order status", "error", err, "order_id", order.ID)
+	}
+}
+
+// save stores the saga. When another process saved it first, the saga is
+// reloaded in place so the caller continues from that process' progress;
+// steps are idempotent, so running one again is harmless.
+func (uc *CheckoutSagaUseCase) save(saga *entity.CheckoutSaga) error {
+	err := uc.sagaRepo.Update(saga)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, entity.ErrSagaConflict) {
+		return fmt.Errorf("failed to save checkout saga: %w", err)
+	}
+
+	reloaded, err := uc.reload(saga)
+	if err != nil {
+		return err
+	}
+	*saga = *reloaded
+	return nil
+}
+
+func (uc *CheckoutSagaUseCase) reload(saga *entity.CheckoutSaga) (*entity.CheckoutSaga, error) {
+	reloaded, err := uc.sagaRepo.FindByOrderID(saga.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reload checkout saga: %w", err)
+	}
+	if reloaded == nil {
+		return nil, fmt.Errorf("checkout saga %s disappeared", saga.ID)
+	}
+	return reloaded, nil
+}
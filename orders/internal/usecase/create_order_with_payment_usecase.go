@@ -2,159 +2,431 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"orders/internal/domain/entity"
 	"orders/internal/domain/repository"
 	"orders/internal/infra/grpc/client"
-	pb "orders/proto"
 	"time"
 )
 
+// ErrInvalidOrderItems indica itens que não podem ser vendidos; o
+// OrderItemsError que o carrega diz o motivo de cada um
+var ErrInvalidOrderItems = errors.New("order has items that cannot be sold")
+
+// OrderItemsError lista os itens recusados, por posição na requisição
+// (items[0].product_id, items[1].price...)
+type OrderItemsError struct {
+	Violations []entity.FieldViolation
+}
+
+func (e *OrderItemsError) Error() string {
+	return fmt.Sprintf("%s: %d invalid item(s)", ErrInvalidOrderItems, len(e.Violations))
+}
+
+func (e *OrderItemsError) Unwrap() error {
+	return ErrInvalidOrderItems
+}
+
 type OrderItemInput struct {
 	ProductID string
 	Quantity  int
-	Price     float64
+	// Price é o preço que o cliente viu; quando informado, precisa ser o
+	// preço atual do catálogo. O pedido é sempre cobrado pelo catálogo.
+	Price *entity.Money
 }
 
 type CreateOrderInput struct {
+	// CustomerID liga o pedido a um cliente cadastrado, cujo email e nome
+	// são usados quando CustomerEmail e CustomerName não são informados
+	CustomerID    string
 	CustomerEmail string
 	CustomerName  string
 	Items         []OrderItemInput
 	PaymentMethod int32 // 1=CREDIT_CARD, 2=DEBIT_CARD, 3=PIX, 4=BOLETO, 5=PAYPAL
+	// IdempotencyKey faz com que uma requisição repetida devolva o pedido
+	// original em vez de criar outro pedido e cobrar novamente
+	IdempotencyKey string
+	// PaymentDetails são repassados ao payments service e não são salvos
+	PaymentDetails *entity.PaymentDetails
+	// QuickAddProducts cria os produtos desconhecidos com o preço enviado,
+	// se o cadastro rápido estiver habilitado; apenas para admins
+	QuickAddProducts bool
 }
 
 type CreateOrderOutput struct {
 	OrderID   string
-	Total     float64
+	Total     entity.Money
 	Status    string
 	PaymentID string
+	Replayed  bool // true quando o resultado veio de uma requisição anterior
+	// Pix e Boleto são a cobrança a ser paga pelo cliente enquanto o pedido
+	// aguarda o pagamento
+	Pix    *PixCharge
+	Boleto *BoletoCharge
+}
+
+type PixCharge struct {
+	QRCode    string // BR Code "copia e cola"
+	QRCodePNG []byte
+	ExpiresAt time.Time
+}
+
+type BoletoCharge struct {
+	Number        string // nosso número
+	Barcode       string // 44 dígitos
+	DigitableLine string // linha digitável, 47 dígitos
+	DueDate       time.Time
+	// ExpiresAt é o vencimento mais a carência; depois disso o pedido é
+	// cancelado
+	ExpiresAt time.Time
 }
 
 type CreateOrderUseCase struct {
 	orderRepo     repository.OrderRepository
 	productRepo   repository.ProductRepository
+	customerRepo  repository.CustomerRepository
+	promotions    *PromotionUseCase
+	checkoutSaga  *CheckoutSagaUseCase
 	paymentClient *client.PaymentClient
+	// allowQuickAdd habilita CreateOrderInput.QuickAddProducts
+	allowQuickAdd bool
 	logger        *slog.Logger
 }
 
 func NewCreateOrderUseCase(
 	orderRepo repository.OrderRepository,
 	productRepo repository.ProductRepository,
+	customerRepo repository.CustomerRepository,
+	promotions *PromotionUseCase,
+	checkoutSaga *CheckoutSagaUseCase,
 	paymentClient *client.PaymentClient,
+	allowQuickAdd bool,
 	logger *slog.Logger,
 ) *CreateOrderUseCase {
 	return &CreateOrderUseCase{
 		orderRepo:     orderRepo,
 		productRepo:   productRepo,
+		customerRepo:  customerRepo,
+		promotions:    promotions,
+		checkoutSaga:  checkoutSaga,
 		paymentClient: paymentClient,
+		allowQuickAdd: allowQuickAdd,
 		logger:        logger,
 	}
 }
 
 func (uc *CreateOrderUseCase) Execute(ctx context.Context, input CreateOrderInput) (*CreateOrderOutput, error) {
+	// 0. Requisição repetida: continuar o pedido criado pela primeira tentativa
+	if input.IdempotencyKey != "" {
+		existing, err := uc.orderRepo.FindByIdempotencyKey(input.CustomerID, input.IdempotencyKey)
+		if err != nil {
+			uc.logger.Error("Failed to look up idempotency key", "error", err)
+			return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+		}
+		if existing != nil {
+			return uc.retry(ctx, existing, input)
+		}
+	}
+
+	// Conferir se os dados de pagamento correspondem ao método escolhido
+	if input.PaymentDetails != nil {
+		if err := input.PaymentDetails.Validate(input.PaymentMethod); err != nil {
+			return nil, err
+		}
+	}
+
+	// Pedido de cliente cadastrado: completar o email e o nome com os dele
+	if input.CustomerID != "" {
+		customer, err := findCustomer(uc.customerRepo, input.CustomerID)
+		if err != nil {
+			uc.logger.Error("Failed to find order customer", "customer_id", input.CustomerID, "error", err)
+			return nil, err
+		}
+		if input.CustomerEmail == "" {
+			input.CustomerEmail = customer.Email
+		}
+		if input.CustomerName == "" {
+			input.CustomerName = customer.Name
+		}
+	}
+
 	// 1. Criar o pedido
 	order := entity.NewOrder()
+	order.IdempotencyKey = input.IdempotencyKey
+	order.CustomerID = input.CustomerID
+
+	// 2. Adicionar os itens pelo preço do catálogo, reportando todos os
+	// itens inválidos de uma vez. Os produtos do cadastro rápido só são
+	// criados depois, se nenhum item for recusado.
+	var violations []entity.FieldViolation
+	quickAdded := make(map[string]*entity.Product)
+	for i, itemInput := range input.Items {
+		field := fmt.Sprintf("items[%d]", i)
 
-	// 2. Adicionar items ao pedido e validar/criar produtos
-	for _, itemInput := range input.Items {
-		// Verificar se o produto existe
-		product, err := uc.productRepo.FindByID(itemInput.ProductID)
+		product, err := uc.catalogProduct(itemInput, input.QuickAddProducts && uc.allowQuickAdd, quickAdded)
 		if err != nil {
-			// Produto não existe, criar um produto temporário para o pedido
-			uc.logger.Warn("Product not found, creating temporary product",
-				"product_id", itemInput.ProductID,
-			)
-
-			now := time.Now()
-			product = &entity.Product{
-				ID:        itemInput.ProductID,
-				Name:      "Product " + itemInput.ProductID,
-				Price:     itemInput.Price,
-				CreatedAt: now,
-				UpdatedAt: now,
+			if !errors.Is(err, ErrProductNotFound) && !errors.Is(err, entity.ErrProductInactive) && !errors.Is(err, entity.ErrInvalidProductPrice) {
+				return nil, err
 			}
-
-			if err := uc.productRepo.Create(product); err != nil {
-				uc.logger.Error("Failed to create product", "error", err)
-				return nil, fmt.Errorf("failed to create product: %w", err)
+			invalid := field + ".product_id"
+			if errors.Is(err, entity.ErrInvalidProductPrice) {
+				invalid = field + ".price"
 			}
+			violations = append(violations, entity.FieldViolation{Field: invalid, Description: err.Error()})
+			continue
+		}
+
+		if itemInput.Price != nil && *itemInput.Price != product.Price {
+			violations = append(violations, entity.FieldViolation{
+				Field:       field + ".price",
+				Description: fmt.Sprintf("price %s does not match the catalog price %s", itemInput.Price, product.Price),
+			})
+			continue
 		}
 
 		item, err := entity.NewItem(order.ID, itemInput.ProductID, product, itemInput.Quantity)
 		if err != nil {
-			uc.logger.Error("Failed to create item", "error", err)
-			return nil, fmt.Errorf("failed to create item: %w", err)
+			violations = append(violations, entity.FieldViolation{Field: field + ".quantity", Description: err.Error()})
+			continue
 		}
 
-		order.AddItem(item)
+		if err := order.AddItem(item); err != nil {
+			uc.logger.Error("Failed to add item to order", "error", err, "product_id", itemInput.ProductID)
+			return nil, err
+		}
+	}
+	if len(violations) > 0 {
+		uc.logger.Warn("Order has items that cannot be sold", "violations", len(violations))
+		return nil, &OrderItemsError{Violations: violations}
 	}
 
-	// Validar pedido (verificar se tem itens)
-	if len(order.Items) == 0 {
-		uc.logger.Error("Order has no items")
-		return nil, entity.ErrEmptyOrder
+	// Validar pedido (verificar se tem itens) e registrar OrderCreated
+	if err := order.Place(); err != nil {
+		uc.logger.Error("Failed to place order", "error", err)
+		return nil, err
+	}
+
+	for _, product := range quickAdded {
+		uc.logger.Warn("Product not found, quick adding it", "product_id", product.ID, "price", product.Price.String())
+		if err := uc.productRepo.Create(product); err != nil {
+			uc.logger.Error("Failed to create product", "error", err)
+			return nil, fmt.Errorf("failed to create product: %w", err)
+		}
 	}
 
 	// 3. Salvar pedido no banco (isso já salva os items também)
 	if err := uc.orderRepo.Create(order); err != nil {
+		if errors.Is(err, entity.ErrDuplicateIdempotencyKey) {
+			// Uma requisição concorrente com a mesma chave salvou primeiro
+			existing, findErr := uc.orderRepo.FindByIdempotencyKey(input.CustomerID, input.IdempotencyKey)
+			if findErr != nil || existing == nil {
+				uc.logger.Error("Failed to load order for idempotency key", "error", findErr)
+				return nil, fmt.Errorf("failed to load order for idempotency key: %w", err)
+			}
+			return uc.retry(ctx, existing, input)
+		}
 		uc.logger.Error("Failed to save order", "error", err)
 		return nil, fmt.Errorf("failed to save order: %w", err)
 	}
 
 	uc.logger.Info("Order created successfully",
 		"order_id", order.ID,
-		"total", order.Total,
+		"total", order.Total.String(),
 	)
 
-	// 4. Processar pagamento via gRPC
-	paymentResponse, err := uc.paymentClient.ProcessPayment(
-		ctx,
-		order.ID,
-		order.Total,
-		input.PaymentMethod,
-		input.CustomerEmail,
-		input.CustomerName,
+	// 4. Executar a saga de checkout: reservar estoque, cobrar e confirmar
+	saga, err := uc.checkoutSaga.Start(ctx, order, input.PaymentMethod, input.CustomerEmail, input.CustomerName, input.PaymentDetails)
+	return uc.result(ctx, order, saga, err)
+}
+
+// catalogProduct busca o produto de um item, que precisa existir e estar à
+// venda. Com o cadastro rápido, um produto desconhecido é montado com o
+// preço enviado e estoque para o pedido, e guardado em quickAdded para ser
+// criado depois.
+func (uc *CreateOrderUseCase) catalogProduct(itemInput OrderItemInput, quickAdd bool, quickAdded map[string]*entity.Product) (*entity.Product, error) {
+	// O mesmo produto novo em mais de um item: estoque para todos eles
+	if product, ok := quickAdded[itemInput.ProductID]; ok {
+		product.Stock += max(itemInput.Quantity, 0)
+		return product, nil
+	}
+
+	product, err := uc.productRepo.FindByID(itemInput.ProductID)
+	if err == nil {
+		if !product.Active {
+			uc.logger.Warn("Product is not for sale", "product_id", itemInput.ProductID)
+			return nil, entity.ErrProductInactive
+		}
+		return product, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		uc.logger.Error("Failed to find product", "error", err, "product_id", itemInput.ProductID)
+		return nil, fmt.Errorf("failed to find product: %w", err)
+	}
+	if !quickAdd {
+		uc.logger.Warn("Product not found", "product_id", itemInput.ProductID)
+		return nil, ErrProductNotFound
+	}
+
+	// Cadastro rápido: o admin informa o preço do produto novo
+	if itemInput.Price == nil {
+		return nil, entity.ErrInvalidProductPrice
+	}
+	product, err = entity.NewProduct("Product "+itemInput.ProductID, "", *itemInput.Price, max(itemInput.Quantity, 0))
+	if err != nil {
+		return nil, err
+	}
+	product.ID = itemInput.ProductID
+
+	quickAdded[product.ID] = product
+	return product, nil
+}
+
+// result monta a resposta a partir do estado salvo pela saga. Pagamento
+// recusado ou vencido não é erro da requisição: o pedido é devolvido como
+// cancelado.
+func (uc *CreateOrderUseCase) result(ctx context.Context, order *entity.Order, saga *entity.CheckoutSaga, sagaErr error) (*CreateOrderOutput, error) {
+	if sagaErr != nil && !errors.Is(sagaErr, entity.ErrPaymentDeclined) && !errors.Is(sagaErr, entity.ErrPaymentExpired) {
+		uc.logger.Error("Checkout failed", "error", sagaErr, "order_id", order.ID)
+		return nil, sagaErr
+	}
+
+	// O status foi alterado pelos passos da saga
+	saved, err := uc.orderRepo.FindByID(order.ID)
+	if err != nil {
+		uc.logger.Error("Failed to reload order", "error", err, "order_id", order.ID)
+		return nil, fmt.Errorf("failed to reload order: %w", err)
+	}
+
+	uc.logger.Info("Order checkout processed",
+		"order_id", saved.ID,
+		"status", saved.Status,
+		"payment_id", saga.PaymentID,
+		"saga_status", saga.Status,
 	)
+
+	output := &CreateOrderOutput{
+		OrderID:   saved.ID,
+		Total:     saved.Total,
+		Status:    string(saved.Status),
+		PaymentID: saga.PaymentID,
+	}
+	uc.addPendingCharge(ctx, saga, output)
+	return output, nil
+}
+
+// addPendingCharge busca o QR code PIX ou o boleto do pagamento que a saga
+// aguarda. Sem eles o pedido continua válido, então falhas são apenas
+// registradas.
+func (uc *CreateOrderUseCase) addPendingCharge(ctx context.Context, saga *entity.CheckoutSaga, output *CreateOrderOutput) {
+	if (saga.PaymentMethod != entity.PaymentMethodPix && saga.PaymentMethod != entity.PaymentMethodBoleto) ||
+		saga.IsFinished() || saga.Step != entity.SagaStepAuthorizePayment || saga.PaymentID == "" {
+		return
+	}
+
+	payment, err := uc.paymentClient.GetPayment(ctx, saga.PaymentID)
 	if err != nil {
-		// Se falhar, marcar pedido como falha no pagamento
-		order.Status = "payment_failed"
-		_ = uc.orderRepo.Update(order)
+		uc.logger.Warn("Failed to load pending charge", "error", err, "payment_id", saga.PaymentID)
+		return
+	}
 
-		uc.logger.Error("Payment processing failed",
-			"error", err,
+	if charge := payment.GetPixCharge(); charge != nil {
+		output.Pix = &PixCharge{
+			QRCode:    charge.QrCode,
+			QRCodePNG: charge.QrCodePng,
+			ExpiresAt: charge.ExpiresAt.AsTime(),
+		}
+	}
+	if charge := payment.GetBoletoCharge(); charge != nil {
+		output.Boleto = &BoletoCharge{
+			Number:        charge.Number,
+			Barcode:       charge.Barcode,
+			DigitableLine: charge.DigitableLine,
+			DueDate:       charge.DueDate.AsTime(),
+			ExpiresAt:     charge.ExpiresAt.AsTime(),
+		}
+	}
+}
+
+// retry responde a uma requisição repetida com o pedido já criado por ela.
+// Se a saga do pedido não terminou (ex.: a primeira tentativa caiu no meio),
+// ela é retomada de onde parou; senão apenas devolve o resultado registrado.
+func (uc *CreateOrderUseCase) retry(ctx context.Context, order *entity.Order, input CreateOrderInput) (*CreateOrderOutput, error) {
+	// A chave de outro cliente nunca devolve nem cobra o pedido dele
+	if order.CustomerID != input.CustomerID {
+		uc.logger.Warn("Idempotency key reused by another customer",
+			"order_id", order.ID,
+			"customer_id", input.CustomerID,
+		)
+		return nil, entity.ErrIdempotencyKeyReused
+	}
+	if !sameItems(order, input.Items) {
+		uc.logger.Warn("Idempotency key reused with different items",
 			"order_id", order.ID,
+			"idempotency_key", input.IdempotencyKey,
 		)
-		return nil, fmt.Errorf("payment processing failed: %w", err)
+		return nil, entity.ErrIdempotencyKeyReused
 	}
 
-	// 5. Atualizar status do pedido baseado no pagamento
-	switch paymentResponse.Status {
-	case pb.PaymentStatus_PAYMENT_STATUS_APPROVED:
-		order.Status = entity.OrderStatusPaid
-	case pb.PaymentStatus_PAYMENT_STATUS_PROCESSING:
-		order.Status = entity.OrderStatusPending
-	case pb.PaymentStatus_PAYMENT_STATUS_DECLINED:
-		order.Status = entity.OrderStatusCanceled
-	default:
-		order.Status = entity.OrderStatusPending
+	uc.logger.Info("Replaying order for idempotency key",
+		"order_id", order.ID,
+		"status", order.Status,
+		"idempotency_key", input.IdempotencyKey,
+	)
+
+	saga, err := uc.checkoutSaga.Resume(ctx, order, input.PaymentDetails)
+
+	var output *CreateOrderOutput
+	if saga == nil && err == nil {
+		// Pedido anterior à saga de checkout
+		output, err = uc.recordedPayment(ctx, order)
+	} else if saga != nil {
+		output, err = uc.result(ctx, order, saga, err)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	output.Replayed = true
+	return output, nil
+}
 
-	if err := uc.orderRepo.Update(order); err != nil {
-		uc.logger.Error("Failed to update order status", "error", err)
-		return nil, fmt.Errorf("failed to update order status: %w", err)
+// recordedPayment devolve o resultado de um pedido que já teve o pagamento
+// concluído, sem efeitos colaterais
+func (uc *CreateOrderUseCase) recordedPayment(ctx context.Context, order *entity.Order) (*CreateOrderOutput, error) {
+	payments, err := uc.paymentClient.ListPayments(ctx, order.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	uc.logger.Info("Order and payment processed successfully",
-		"order_id", order.ID,
-		"payment_id", paymentResponse.PaymentId,
-		"payment_status", paymentResponse.Status,
-	)
+	output := &CreateOrderOutput{
+		OrderID: order.ID,
+		Total:   order.Total,
+		Status:  string(order.Status),
+	}
+	// ListPayments ordena do mais recente para o mais antigo
+	if len(payments.Payments) > 0 {
+		output.PaymentID = payments.Payments[0].PaymentId
+	}
+	return output, nil
+}
 
-	return &CreateOrderOutput{
-		OrderID:   order.ID,
-		Total:     order.Total,
-		Status:    string(order.Status),
-		PaymentID: paymentResponse.PaymentId,
-	}, nil
+// sameItems confere se a requisição repetida pede os mesmos produtos e
+// quantidades do pedido salvo
+func sameItems(order *entity.Order, items []OrderItemInput) bool {
+	requested := make(map[string]int)
+	for _, item := range items {
+		requested[item.ProductID] += item.Quantity
+	}
+	if len(requested) != len(order.Items) {
+		return false
+	}
+	for _, item := range order.Items {
+		if requested[item.ProductID] != item.Quantity {
+			return false
+		}
+	}
+	return true
 }
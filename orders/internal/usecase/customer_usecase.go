@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+)
+
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+)
+
+type CustomerInput struct {
+	Name      string
+	Email     string
+	Document  string // CPF, com ou sem pontuação; opcional
+	Phone     string
+	Addresses []entity.Address
+}
+
+type CustomerUseCase struct {
+	customerRepo repository.CustomerRepository
+	orderRepo    repository.OrderRepository
+	logger       *slog.Logger
+}
+
+func NewCustomerUseCase(customerRepo repository.CustomerRepository, orderRepo repository.OrderRepository, logger *slog.Logger) *CustomerUseCase {
+	return &CustomerUseCase{
+		customerRepo: customerRepo,
+		orderRepo:    orderRepo,
+		logger:       logger,
+	}
+}
+
+func (uc *CustomerUseCase) CreateCustomer(input CustomerInput) (*entity.Customer, error) {
+	uc.logger.Info("Creating customer", "addresses_count", len(input.Addresses))
+
+	customer, err := entity.NewCustomer(input.Name, input.Email, input.Document, input.Phone, input.Addresses)
+	if err != nil {
+		uc.logger.Error("Failed to create customer entity", "error", err)
+		return nil, err
+	}
+
+	if err := uc.customerRepo.Create(customer); err != nil {
+		uc.logger.Error("Failed to save customer", "customer_id", customer.ID, "error", err)
+		return nil, err
+	}
+
+	uc.logger.Info("Customer created successfully", "customer_id", customer.ID)
+	return customer, nil
+}
+
+func (uc *CustomerUseCase) GetCustomer(id string) (*entity.Customer, error) {
+	uc.logger.Info("Getting customer", "customer_id", id)
+	return uc.customerRepo.FindByID(id)
+}
+
+func (uc *CustomerUseCase) ListCustomers() ([]entity.Customer, error) {
+	uc.logger.Info("Listing all customers")
+	return uc.customerRepo.FindAll()
+}
+
+func (uc *CustomerUseCase) UpdateCustomer(id string, input CustomerInput) (*entity.Customer, error) {
+	uc.logger.Info("Updating customer", "customer_id", id)
+
+	customer, err := uc.customerRepo.FindByID(id)
+	if err != nil {
+		uc.logger.Error("Failed to find customer for update", "customer_id", id, "error", err)
+		return nil, err
+	}
+
+	if err := customer.Update(input.Name, input.Email, input.Document, input.Phone, input.Addresses); err != nil {
+		uc.logger.Error("Customer validation failed", "customer_id", id, "error", err)
+		return nil, err
+	}
+
+	if err := uc.customerRepo.Update(customer); err != nil {
+		uc.logger.Error("Failed to update customer", "customer_id", id, "error", err)
+		return nil, err
+	}
+
+	uc.logger.Info("Customer updated successfully", "customer_id", id)
+	return customer, nil
+}
+
+func (uc *CustomerUseCase) DeleteCustomer(id string) error {
+	uc.logger.Info("Deleting customer", "customer_id", id)
+
+	if err := uc.customerRepo.Delete(id); err != nil {
+		uc.logger.Error("Failed to delete customer", "customer_id", id, "error", err)
+		return err
+	}
+
+	uc.logger.Info("Customer deleted successfully", "customer_id", id)
+	return nil
+}
+
+// ListOrders devolve o histórico de pedidos do cliente, do mais recente para
+// o mais antigo
+func (uc *CustomerUseCase) ListOrders(customerID string) ([]entity.Order, error) {
+	uc.logger.Info("Listing customer orders", "customer_id", customerID)
+
+	if _, err := uc.customerRepo.FindByID(customerID); err != nil {
+		uc.logger.Error("Failed to find customer", "customer_id", customerID, "error", err)
+		return nil, err
+	}
+
+	orders, err := uc.orderRepo.FindByCustomerID(customerID)
+	if err != nil {
+		uc.logger.Error("Failed to list customer orders", "customer_id", customerID, "error", err)
+		return nil, err
+	}
+
+	uc.logger.Info("Customer orders listed", "customer_id", customerID, "count", len(orders))
+	return orders, nil
+}
+
+// findCustomer busca o cliente de um pedido ou carrinho, trocando o "não
+// encontrado" do repositório por ErrCustomerNotFound
+func findCustomer(customerRepo repository.CustomerRepository, customerID string) (*entity.Customer, error) {
+	customer, err := customerRepo.FindByID(customerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCustomerNotFound
+	}
+	return customer, err
+}
@@ -7,14 +7,20 @@ import (
 )
 
 type OrderUseCase struct {
-	orderRepo repository.OrderRepository
-	logger    *slog.Logger
+	orderRepo   repository.OrderRepository
+	historyRepo repository.OrderStatusHistoryRepository
+	logger      *slog.Logger
 }
 
-func NewOrderUseCase(orderRepo repository.OrderRepository, logger *slog.Logger) *OrderUseCase {
+func NewOrderUseCase(
+	orderRepo repository.OrderRepository,
+	historyRepo repository.OrderStatusHistoryRepository,
+	logger *slog.Logger,
+) *OrderUseCase {
 	return &OrderUseCase{
-		orderRepo: orderRepo,
-		logger:    logger,
+		orderRepo:   orderRepo,
+		historyRepo: historyRepo,
+		logger:      logger,
 	}
 }
 
@@ -23,9 +29,42 @@ func (uc *OrderUseCase) GetOrder(id string) (*entity.Order, error) {
 	return uc.orderRepo.FindByID(id)
 }
 
-func (uc *OrderUseCase) ListOrders() ([]entity.Order, error) {
-	uc.logger.Info("Listing all orders")
-	return uc.orderRepo.FindAll()
+// ListOrders returns a page of the orders matching filter and the token of
+// the next page, empty on the last one
+func (uc *OrderUseCase) ListOrders(filter repository.OrderFilter, options ListOptions) ([]entity.Order, string, error) {
+	page, size, err := options.page()
+	if err != nil {
+		return nil, "", err
+	}
+	filter.Page = page
+
+	uc.logger.Info("Listing orders", "status", filter.Status, "customer_id", filter.CustomerID, "page_size", size)
+	orders, err := uc.orderRepo.List(filter)
+	if err != nil {
+		uc.logger.Error("Failed to list orders", "error", err)
+		return nil, "", err
+	}
+
+	orders, next := trimPage(orders, size, page, func(o entity.Order) entity.PageCursor {
+		return entity.PageCursor{CreatedAt: o.CreatedAt, ID: o.ID}
+	})
+	return orders, next, nil
+}
+
+// GetStatusHistory returns every status change of an order, oldest first
+func (uc *OrderUseCase) GetStatusHistory(id string) ([]entity.StatusChange, error) {
+	uc.logger.Info("Getting order status history", "order_id", id)
+
+	if _, err := uc.orderRepo.FindByID(id); err != nil {
+		return nil, err
+	}
+
+	history, err := uc.historyRepo.FindByOrderID(id)
+	if err != nil {
+		uc.logger.Error("Failed to get order status history", "order_id", id, "error", err)
+		return nil, err
+	}
+	return history, nil
 }
 
 func (uc *OrderUseCase) UpdateOrderStatus(id string, status entity.OrderStatus) (*entity.Order, error) {
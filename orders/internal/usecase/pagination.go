@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"errors"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+)
+
+const (
+	// DefaultPageSize is used when the request does not set page_size
+	DefaultPageSize = 20
+	// MaxPageSize caps the page_size asked by clients
+	MaxPageSize = 100
+)
+
+var (
+	ErrInvalidPageSize = errors.New("page size must be between 1 and 100")
+	ErrInvalidSort     = errors.New(`sort must be "created_at" or "-created_at"`)
+)
+
+// ListOptions pages and sorts a listing. Sort is "-created_at" (newest
+// first, the default) or "created_at"; PageToken is the next_page_token of
+// the previous page.
+type ListOptions struct {
+	PageSize  int
+	PageToken string
+	Sort      string
+}
+
+// page turns the options into the page asked to the repository, one row
+// longer than the page size to know whether there is a next page
+func (o ListOptions) page() (repository.Page, int, error) {
+	var ascending bool
+	switch o.Sort {
+	case "", "-created_at":
+	case "created_at":
+		ascending = true
+	default:
+		return repository.Page{}, 0, ErrInvalidSort
+	}
+
+	size := o.PageSize
+	if size == 0 {
+		size = DefaultPageSize
+	}
+	if size < 1 || size > MaxPageSize {
+		return repository.Page{}, 0, ErrInvalidPageSize
+	}
+
+	after, err := entity.ParsePageToken(o.PageToken, ascending)
+	if err != nil {
+		return repository.Page{}, 0, err
+	}
+	return repository.Page{After: after, Ascending: ascending, Limit: size + 1}, size, nil
+}
+
+// trimPage drops the extra row asked by page and returns the token of the
+// next page, empty on the last one
+func trimPage[T any](rows []T, size int, page repository.Page, cursor func(T) entity.PageCursor) ([]T, string) {
+	if len(rows) <= size {
+		return rows, ""
+	}
+	rows = rows[:size]
+	return rows, entity.EncodePageToken(cursor(rows[size-1]), page.Ascending)
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"orders/internal/infra/grpc/client"
+	pb "orders/proto"
+	"time"
+)
+
+// paymentsStream é o nome do stream WatchPayments na tabela de cursores
+const paymentsStream = "payments.watch"
+
+// Espera entre reconexões ao stream, dobrando a cada falha seguida
+const (
+	minWatchBackoff = time.Second
+	maxWatchBackoff = 30 * time.Second
+)
+
+// PaymentWatcherUseCase acompanha as mudanças de status dos pagamentos pelo
+// stream WatchPayments e as aplica aos pedidos. O cursor é salvo depois de
+// cada mudança aplicada, então uma reconexão retoma do ponto em que parou
+// sem perder mudanças; uma mudança pode ser recebida de novo e aplicá-la
+// outra vez não tem efeito.
+type PaymentWatcherUseCase struct {
+	sagaRepo      repository.CheckoutSagaRepository
+	orderRepo     repository.OrderRepository
+	cursorRepo    repository.StreamCursorRepository
+	checkoutSaga  *CheckoutSagaUseCase
+	paymentClient *client.PaymentClient
+	logger        *slog.Logger
+}
+
+func NewPaymentWatcherUseCase(
+	sagaRepo repository.CheckoutSagaRepository,
+	orderRepo repository.OrderRepository,
+	cursorRepo repository.StreamCursorRepository,
+	checkoutSaga *CheckoutSagaUseCase,
+	paymentClient *client.PaymentClient,
+	logger *slog.Logger,
+) *PaymentWatcherUseCase {
+	return &PaymentWatcherUseCase{
+		sagaRepo:      sagaRepo,
+		orderRepo:     orderRepo,
+		cursorRepo:    cursorRepo,
+		checkoutSaga:  checkoutSaga,
+		paymentClient: paymentClient,
+		logger:        logger,
+	}
+}
+
+// Run consome o stream até ctx ser cancelado, reconectando quando ele cai
+func (uc *PaymentWatcherUseCase) Run(ctx context.Context) {
+	backoff := minWatchBackoff
+
+	for ctx.Err() == nil {
+		applied, err := uc.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if applied > 0 {
+			backoff = minWatchBackoff
+		}
+
+		uc.logger.Warn("Payment watch interrupted, reconnecting", "error", err, "retry_in", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxWatchBackoff)
+	}
+}
+
+// watch abre o stream a partir do último cursor salvo e aplica as mudanças
+// até o stream terminar ou uma mudança falhar, devolvendo quantas aplicou
+func (uc *PaymentWatcherUseCase) watch(ctx context.Context) (int, error) {
+	cursor, err := uc.cursorRepo.Find(paymentsStream)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load payments cursor: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := uc.paymentClient.WatchPayments(ctx, cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for {
+		change, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return applied, errors.New("payments stream closed by the server")
+		}
+		if err != nil {
+			return applied, err
+		}
+
+		if err := uc.Apply(ctx, change); err != nil {
+			uc.logger.Error("Failed to apply payment change",
+				"error", err,
+				"payment_id", change.PaymentId,
+				"order_id", change.OrderId,
+				"cursor", change.Cursor,
+			)
+			return applied, err
+		}
+		if err := uc.cursorRepo.Save(paymentsStream, change.Cursor); err != nil {
+			return applied, fmt.Errorf("failed to save payments cursor: %w", err)
+		}
+		applied++
+	}
+}
+
+// Apply leva uma mudança de pagamento ao pedido. Um pagamento decidido
+// retoma na hora a saga que o aguardava, que confirma ou cancela o pedido;
+// um reembolso total feito fora do checkout (ex.: pelo gateway) marca o
+// pedido como reembolsado. Outras mudanças já são tratadas por quem as fez.
+// Erros são temporários: a mudança é recebida de novo na reconexão.
+func (uc *PaymentWatcherUseCase) Apply(ctx context.Context, change *pb.PaymentStatusChange) error {
+	saga, err := uc.sagaRepo.FindByOrderID(change.OrderId)
+	if err != nil {
+		return fmt.Errorf("failed to find checkout saga: %w", err)
+	}
+
+	switch {
+	case saga != nil && !saga.IsFinished():
+		return uc.resumeWaitingSaga(ctx, saga, change)
+	case change.Status == pb.PaymentStatus_PAYMENT_STATUS_REFUNDED:
+		// Reembolsos da compensação terminam com o pedido cancelado, não
+		// reembolsado
+		if saga != nil && saga.Status == entity.SagaStatusCompensated {
+			return nil
+		}
+		return uc.markRefunded(change)
+	}
+	return nil
+}
+
+// resumeWaitingSaga continua a saga parada em authorize_payment esperando
+// este pagamento. Sagas em outros passos estão sendo executadas por outra
+// requisição ou pelo ResumePending, que também retoma a saga se esta
+// tentativa falhar.
+func (uc *PaymentWatcherUseCase) resumeWaitingSaga(ctx context.Context, saga *entity.CheckoutSaga, change *pb.PaymentStatusChange) error {
+	if saga.Status != entity.SagaStatusRunning || saga.Step != entity.SagaStepAuthorizePayment ||
+		saga.PaymentID != change.PaymentId {
+		return nil
+	}
+	if change.Status == pb.PaymentStatus_PAYMENT_STATUS_PENDING || change.Status == pb.PaymentStatus_PAYMENT_STATUS_PROCESSING {
+		return nil
+	}
+
+	order, err := uc.loadOrder(change)
+	if err != nil || order == nil {
+		return err
+	}
+
+	uc.logger.Info("Payment decided, resuming checkout",
+		"order_id", order.ID,
+		"payment_id", change.PaymentId,
+		"payment_status", change.Status,
+	)
+
+	if _, err := uc.checkoutSaga.Resume(ctx, order, nil); err != nil {
+		// Recusa e vencimento terminam com o pedido cancelado; outras falhas
+		// ficam registradas na saga para o ResumePending
+		uc.logger.Warn("Checkout did not finish after payment change", "error", err, "order_id", order.ID)
+	}
+	return nil
+}
+
+func (uc *PaymentWatcherUseCase) markRefunded(change *pb.PaymentStatusChange) error {
+	order, err := uc.loadOrder(change)
+	if err != nil || order == nil {
+		return err
+	}
+
+	if order.Status == entity.OrderStatusRefunded || !order.Status.CanTransitionTo(entity.OrderStatusRefunded) {
+		return nil
+	}
+	if err := order.TransitionTo(entity.OrderStatusRefunded, entity.ActorPayments, "payment refunded"); err != nil {
+		return err
+	}
+	if err := uc.orderRepo.Update(order); err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+
+	uc.logger.Info("Order refunded by payment change", "order_id", order.ID, "payment_id", change.PaymentId)
+	return nil
+}
+
+// loadOrder busca o pedido do pagamento. Pagamentos de pedidos que não
+// existem aqui (ex.: criados direto no payments service) são ignorados,
+// devolvendo nil sem erro, para não travar o stream.
+func (uc *PaymentWatcherUseCase) loadOrder(change *pb.PaymentStatusChange) (*entity.Order, error) {
+	order, err := uc.orderRepo.FindByID(change.OrderId)
+	if errors.Is(err, sql.ErrNoRows) {
+		uc.logger.Warn("Ignoring change of payment without order", "payment_id", change.PaymentId, "order_id", change.OrderId)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order: %w", err)
+	}
+	return order, nil
+}
@@ -18,14 +18,17 @@ func NewProductUseCase(productRepo repository.ProductRepository, logger *slog.Lo
 	}
 }
 
-func (uc *ProductUseCase) CreateProduct(name, description string, price float64, stock int) (*entity.Product, error) {
-	uc.logger.Info("Creating product", "name", name, "price", price, "stock", stock)
+// CreateProduct creates a product; category is optional and lets
+// promotions target the product
+func (uc *ProductUseCase) CreateProduct(name, description, category string, price entity.Money, stock int) (*entity.Product, error) {
+	uc.logger.Info("Creating product", "name", name, "price", price.String(), "stock", stock)
 
 	product, err := entity.NewProduct(name, description, price, stock)
 	if err != nil {
 		uc.logger.Error("Failed to create product entity", "name", name, "error", err)
 		return nil, err
 	}
+	product.Category = entity.NormalizeCategory(category)
 
 	err = uc.productRepo.Create(product)
 	if err != nil {
@@ -42,12 +45,31 @@ func (uc *ProductUseCase) GetProduct(id string) (*entity.Product, error) {
 	return uc.productRepo.FindByID(id)
 }
 
-func (uc *ProductUseCase) ListProducts() ([]entity.Product, error) {
-	uc.logger.Info("Listing all products")
-	return uc.productRepo.FindAll()
+// ListProducts returns a page of the products matching filter and the token
+// of the next page, empty on the last one
+func (uc *ProductUseCase) ListProducts(filter repository.ProductFilter, options ListOptions) ([]entity.Product, string, error) {
+	page, size, err := options.page()
+	if err != nil {
+		return nil, "", err
+	}
+	filter.Page = page
+
+	uc.logger.Info("Listing products", "page_size", size)
+	products, err := uc.productRepo.List(filter)
+	if err != nil {
+		uc.logger.Error("Failed to list products", "error", err)
+		return nil, "", err
+	}
+
+	products, next := trimPage(products, size, page, func(p entity.Product) entity.PageCursor {
+		return entity.PageCursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	})
+	return products, next, nil
 }
 
-func (uc *ProductUseCase) UpdateProduct(id, name, description string, price float64, stock int) (*entity.Product, error) {
+// UpdateProduct replaces the product data; active, when set, takes the
+// product on or off sale
+func (uc *ProductUseCase) UpdateProduct(id, name, description, category string, price entity.Money, stock int, active *bool) (*entity.Product, error) {
 	uc.logger.Info("Updating product", "product_id", id)
 
 	product, err := uc.productRepo.FindByID(id)
@@ -58,8 +80,12 @@ func (uc *ProductUseCase) UpdateProduct(id, name, description string, price floa
 
 	product.Name = name
 	product.Description = description
+	product.Category = entity.NormalizeCategory(category)
 	product.Price = price
 	product.Stock = stock
+	if active != nil {
+		product.Active = *active
+	}
 
 	if err := product.Validate(); err != nil {
 		uc.logger.Error("Product validation failed", "product_id", id, "error", err)
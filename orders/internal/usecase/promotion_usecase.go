@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"time"
+)
+
+var (
+	ErrCouponNotFound = errors.New("coupon not found")
+)
+
+type PromotionUseCase struct {
+	promotionRepo repository.PromotionRepository
+	// shippingFee is charged on every cart, in minor units of its currency
+	shippingFee int64
+	logger      *slog.Logger
+}
+
+func NewPromotionUseCase(promotionRepo repository.PromotionRepository, shippingFee int64, logger *slog.Logger) *PromotionUseCase {
+	return &PromotionUseCase{
+		promotionRepo: promotionRepo,
+		shippingFee:   shippingFee,
+		logger:        logger,
+	}
+}
+
+// CreatePromotion creates a coupon, when promotion has a code, or an
+// automatic promotion
+func (uc *PromotionUseCase) CreatePromotion(promotion entity.Promotion) (*entity.Promotion, error) {
+	uc.logger.Info("Creating promotion", "name", promotion.Name, "type", promotion.Type, "code", promotion.Code)
+
+	created, err := entity.NewPromotion(promotion)
+	if err != nil {
+		uc.logger.Error("Failed to create promotion entity", "name", promotion.Name, "error", err)
+		return nil, err
+	}
+
+	if err := uc.promotionRepo.Create(created); err != nil {
+		uc.logger.Error("Failed to save promotion", "promotion_id", created.ID, "error", err)
+		return nil, err
+	}
+
+	uc.logger.Info("Promotion created successfully", "promotion_id", created.ID)
+	return created, nil
+}
+
+func (uc *PromotionUseCase) GetPromotion(id string) (*entity.Promotion, error) {
+	uc.logger.Info("Getting promotion", "promotion_id", id)
+	return uc.promotionRepo.FindByID(id)
+}
+
+func (uc *PromotionUseCase) ListPromotions() ([]entity.Promotion, error) {
+	uc.logger.Info("Listing all promotions")
+	return uc.promotionRepo.FindAll()
+}
+
+// DeactivatePromotion ends a promotion. It is kept, with its redemptions,
+// for the orders that used it.
+func (uc *PromotionUseCase) DeactivatePromotion(id string) (*entity.Promotion, error) {
+	uc.logger.Info("Deactivating promotion", "promotion_id", id)
+
+	promotion, err := uc.promotionRepo.FindByID(id)
+	if err != nil {
+		uc.logger.Error("Failed to find promotion", "promotion_id", id, "error", err)
+		return nil, err
+	}
+
+	promotion.Deactivate()
+	if err := uc.promotionRepo.Update(promotion); err != nil {
+		uc.logger.Error("Failed to deactivate promotion", "promotion_id", id, "error", err)
+		return nil, err
+	}
+
+	uc.logger.Info("Promotion deactivated successfully", "promotion_id", id)
+	return promotion, nil
+}
+
+// ApplyDiscounts prices a cart with the shipping fee, the automatic
+// promotions in effect and the coupons entered in it. Automatic promotions
+// past their usage limits are left out. It returns, by code, the coupons that gave no discount and
+// why; the cart keeps them, so callers decide whether that is an error.
+func (uc *PromotionUseCase) ApplyDiscounts(order *entity.Order) (map[string]error, error) {
+	now := time.Now()
+
+	automatic, err := uc.promotionRepo.FindAutomatic(now)
+	if err != nil {
+		uc.logger.Error("Failed to find automatic promotions", "order_id", order.ID, "error", err)
+		return nil, err
+	}
+
+	promotions := make([]entity.Promotion, 0, len(automatic)+len(order.Coupons))
+	for _, promotion := range automatic {
+		if err := uc.checkUsage(&promotion, order.CustomerID); err != nil {
+			if !errors.Is(err, entity.ErrCouponUsageLimitReached) && !errors.Is(err, entity.ErrCouponNotApplicable) {
+				return nil, err
+			}
+			continue
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	couponErrs := make(map[string]error)
+	codes := make(map[string]string)
+	for _, code := range order.Coupons {
+		promotion, err := uc.findCoupon(code, order.CustomerID, now)
+		if err != nil {
+			if !isCouponError(err) {
+				return nil, err
+			}
+			couponErrs[code] = err
+			continue
+		}
+		codes[promotion.ID] = code
+		promotions = append(promotions, *promotion)
+	}
+
+	if err := order.SetShipping(uc.shippingFee); err != nil {
+		uc.logger.Error("Failed to set cart shipping", "order_id", order.ID, "error", err)
+		return nil, err
+	}
+	skipped, err := order.ApplyPromotions(promotions)
+	if err != nil {
+		uc.logger.Error("Failed to apply promotions", "order_id", order.ID, "error", err)
+		return nil, err
+	}
+	for id, reason := range skipped {
+		if code, ok := codes[id]; ok {
+			couponErrs[code] = reason
+		}
+	}
+
+	uc.logger.Info("Cart discounts applied",
+		"order_id", order.ID,
+		"discounts", len(order.Discounts),
+		"line_discount", order.LineDiscount.String(),
+		"order_discount", order.OrderDiscount.String(),
+		"shipping", order.Shipping.String(),
+		"shipping_discount", order.ShippingDiscount.String(),
+	)
+	return couponErrs, nil
+}
+
+// findCoupon loads the promotion of a coupon code and checks it can be used
+// now by the customer of the cart
+func (uc *PromotionUseCase) findCoupon(code, customerID string, now time.Time) (*entity.Promotion, error) {
+	promotion, err := uc.promotionRepo.FindByCode(code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCouponNotFound
+	}
+	if err != nil {
+		uc.logger.Error("Failed to find coupon", "code", code, "error", err)
+		return nil, err
+	}
+	if !promotion.IsValidAt(now) {
+		return nil, entity.ErrCouponNotValid
+	}
+	if err := uc.checkUsage(promotion, customerID); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// checkUsage tells whether the promotion can still be redeemed by the
+// customer. Limits per customer need a customer: anonymous carts cannot use
+// those promotions. The limits are checked again when the order is placed.
+func (uc *PromotionUseCase) checkUsage(promotion *entity.Promotion, customerID string) error {
+	if promotion.UsageLimit == 0 && promotion.UsageLimitPerCustomer == 0 {
+		return nil
+	}
+	if promotion.UsageLimitPerCustomer > 0 && customerID == "" {
+		return fmt.Errorf("%w: it is limited per customer and the cart has none", entity.ErrCouponNotApplicable)
+	}
+
+	total, byCustomer, err := uc.promotionRepo.CountRedemptions(promotion.ID, customerID)
+	if err != nil {
+		uc.logger.Error("Failed to count promotion redemptions", "promotion_id", promotion.ID, "error", err)
+		return err
+	}
+	if promotion.UsageLimit > 0 && total >= promotion.UsageLimit ||
+		promotion.UsageLimitPerCustomer > 0 && byCustomer >= promotion.UsageLimitPerCustomer {
+		return entity.ErrCouponUsageLimitReached
+	}
+	return nil
+}
+
+// isCouponError tells whether err is a reason for a coupon to give no
+// discount, rather than a failure
+func isCouponError(err error) bool {
+	return errors.Is(err, ErrCouponNotFound) ||
+		errors.Is(err, entity.ErrCouponNotValid) ||
+		errors.Is(err, entity.ErrCouponUsageLimitReached) ||
+		errors.Is(err, entity.ErrCouponNotApplicable) ||
+		errors.Is(err, entity.ErrCouponNotStackable)
+}
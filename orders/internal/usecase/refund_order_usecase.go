@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"orders/internal/infra/grpc/client"
+	pb "orders/proto"
+)
+
+type RefundOrderInput struct {
+	OrderID   string
+	PaymentID string
+	Amount    *entity.Money // nil reembolsa todo o saldo restante
+	Reason    string
+}
+
+type RefundOrderOutput struct {
+	RefundID        string
+	PaymentID       string
+	PaymentStatus   string
+	Amount          entity.Money
+	RefundedAmount  entity.Money
+	RemainingAmount entity.Money
+}
+
+type RefundOrderUseCase struct {
+	orderRepo     repository.OrderRepository
+	paymentClient *client.PaymentClient
+	logger        *slog.Logger
+}
+
+func NewRefundOrderUseCase(
+	orderRepo repository.OrderRepository,
+	paymentClient *client.PaymentClient,
+	logger *slog.Logger,
+) *RefundOrderUseCase {
+	return &RefundOrderUseCase{
+		orderRepo:     orderRepo,
+		paymentClient: paymentClient,
+		logger:        logger,
+	}
+}
+
+func (uc *RefundOrderUseCase) Execute(ctx context.Context, input RefundOrderInput) (*RefundOrderOutput, error) {
+	// 1. Buscar pedido
+	order, err := uc.orderRepo.FindByID(input.OrderID)
+	if err != nil {
+		uc.logger.Error("Failed to find order", "error", err, "order_id", input.OrderID)
+		return nil, fmt.Errorf("failed to find order: %w", err)
+	}
+
+	// 2. Validar o valor parcial, que deve estar na moeda do pedido
+	if input.Amount != nil {
+		if !input.Amount.IsPositive() {
+			return nil, entity.ErrInvalidMoney
+		}
+		if input.Amount.Currency != order.Total.Currency {
+			return nil, fmt.Errorf("%w: order is in %s, refund is in %s", entity.ErrCurrencyMismatch, order.Total.Currency, input.Amount.Currency)
+		}
+	}
+
+	// 3. Garantir que o pagamento pertence ao pedido
+	payment, err := uc.paymentClient.GetPayment(ctx, input.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.OrderId != order.ID {
+		uc.logger.Warn("Payment does not belong to order",
+			"payment_id", input.PaymentID,
+			"order_id", order.ID,
+		)
+		return nil, entity.ErrPaymentNotForOrder
+	}
+
+	// 4. Reembolsar via gRPC
+	refund, err := uc.paymentClient.RefundPayment(ctx, input.PaymentID, input.Amount, input.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Reembolso total encerra o pedido como reembolsado
+	if refund.Status == pb.PaymentStatus_PAYMENT_STATUS_REFUNDED && order.Status.CanTransitionTo(entity.OrderStatusRefunded) {
+		if err := order.TransitionTo(entity.OrderStatusRefunded, entity.ActorAPI, input.Reason); err == nil {
+			if err := uc.orderRepo.Update(order); err != nil {
+				// O dinheiro já foi devolvido: a falha é registrada, não desfeita
+				uc.logger.Error("Failed to mark order as refunded", "error", err, "order_id", order.ID)
+			}
+		}
+	}
+
+	uc.logger.Info("Order refunded successfully",
+		"order_id", order.ID,
+		"payment_id", input.PaymentID,
+		"refund_id", refund.RefundId,
+		"payment_status", refund.Status,
+	)
+
+	return &RefundOrderOutput{
+		RefundID:        refund.RefundId,
+		PaymentID:       refund.PaymentId,
+		PaymentStatus:   refund.Status.String(),
+		Amount:          moneyFromProto(refund.Amount),
+		RefundedAmount:  moneyFromProto(refund.RefundedAmount),
+		RemainingAmount: moneyFromProto(refund.RemainingAmount),
+	}, nil
+}
+
+func moneyFromProto(money *pb.Money) entity.Money {
+	if money == nil {
+		return entity.Money{}
+	}
+	return entity.NewMoney(money.Amount, money.Currency)
+}
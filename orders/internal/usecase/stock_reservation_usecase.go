@@ -0,0 +1,374 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"time"
+)
+
+// maxSaveAttempts is how many times saving a reservation is tried when it
+// keeps being changed by other requests in between
+const maxSaveAttempts = 3
+
+// StockReservationUseCase takes stock from products while an order is being
+// built or paid for, and either keeps it (commit) or gives it back (release).
+type StockReservationUseCase struct {
+	reservationRepo repository.StockReservationRepository
+	productRepo     repository.ProductRepository
+	cartTTL         time.Duration
+	logger          *slog.Logger
+}
+
+func NewStockReservationUseCase(
+	reservationRepo repository.StockReservationRepository,
+	productRepo repository.ProductRepository,
+	cartTTL time.Duration,
+	logger *slog.Logger,
+) *StockReservationUseCase {
+	return &StockReservationUseCase{
+		reservationRepo: reservationRepo,
+		productRepo:     productRepo,
+		cartTTL:         cartTTL,
+		logger:          logger,
+	}
+}
+
+// ReserveForCart reserves quantity of a product for a cart. Cart reservations
+// expire after the configured TTL unless the cart is touched again.
+func (uc *StockReservationUseCase) ReserveForCart(orderID, productID string, quantity int) error {
+	return uc.reserve(orderID, productID, quantity, uc.cartTTL)
+}
+
+// ReserveItems makes sure an order holds stock for every item being checked
+// out. Only the quantity the order does not hold yet is reserved, so calling
+// it again for the same order (e.g. when a checkout is resumed) takes nothing
+// twice, and what a cart already holds stops expiring. It is
+// all-or-nothing: if any item cannot be reserved, what this call already
+// reserved is released before returning the error.
+func (uc *StockReservationUseCase) ReserveItems(orderID string, items []entity.Item) error {
+	uc.logger.Info("Reserving stock for order", "order_id", orderID, "items_count", len(items))
+
+	reserved := make(map[string]int)
+	for _, item := range items {
+		missing, err := uc.missingQuantity(orderID, item)
+		if err == nil && missing > 0 {
+			err = uc.reserve(orderID, item.ProductID, missing, 0)
+		}
+		if err != nil {
+			for productID, quantity := range reserved {
+				if releaseErr := uc.ReleaseQuantity(orderID, productID, quantity); releaseErr != nil {
+					uc.logger.Error("Failed to roll back stock reservation",
+						"order_id", orderID,
+						"product_id", productID,
+						"error", releaseErr,
+					)
+				}
+			}
+			return err
+		}
+		if missing > 0 {
+			reserved[item.ProductID] += missing
+		}
+	}
+
+	uc.logger.Info("Stock reserved for order", "order_id", orderID)
+	return nil
+}
+
+// missingQuantity is how much of the item the order still has to reserve.
+// A cart reservation the order already holds is kept from expiring while
+// the order is paid for.
+func (uc *StockReservationUseCase) missingQuantity(orderID string, item entity.Item) (int, error) {
+	reservation, err := uc.reservationRepo.FindActive(orderID, item.ProductID)
+	if err != nil {
+		uc.logger.Error("Failed to find stock reservation", "order_id", orderID, "product_id", item.ProductID, "error", err)
+		return 0, err
+	}
+	if reservation == nil {
+		return item.Quantity, nil
+	}
+
+	// Released by its expiry in between: the whole item is missing again
+	held := 0
+	err = uc.save(reservation, func(reservation *entity.StockReservation) (bool, error) {
+		if !reservation.IsActive() {
+			held = 0
+			return false, nil
+		}
+		held = reservation.Quantity
+		if reservation.ExpiresAt == nil {
+			return false, nil
+		}
+		return true, reservation.Keep()
+	})
+	if err != nil {
+		uc.logger.Error("Failed to keep stock reservation", "reservation_id", reservation.ID, "error", err)
+		return 0, err
+	}
+	return item.Quantity - held, nil
+}
+
+// ReleaseQuantity gives back part of the stock an order holds for a product.
+func (uc *StockReservationUseCase) ReleaseQuantity(orderID, productID string, quantity int) error {
+	uc.logger.Info("Releasing reserved stock", "order_id", orderID, "product_id", productID, "quantity", quantity)
+
+	reservation, err := uc.reservationRepo.FindActive(orderID, productID)
+	if err != nil {
+		uc.logger.Error("Failed to find stock reservation", "order_id", orderID, "product_id", productID, "error", err)
+		return err
+	}
+	if reservation == nil {
+		uc.logger.Warn("No active stock reservation to release", "order_id", orderID, "product_id", productID)
+		return nil
+	}
+
+	_, err = uc.giveBack(reservation, func(reservation *entity.StockReservation) (int, error) {
+		if !reservation.IsActive() {
+			return 0, nil
+		}
+		reduced := min(quantity, reservation.Quantity)
+		return reduced, reservation.Reduce(reduced)
+	})
+	return err
+}
+
+// Commit keeps the stock reserved by an order once its payment is approved.
+func (uc *StockReservationUseCase) Commit(orderID string) error {
+	uc.logger.Info("Committing stock reservations", "order_id", orderID)
+
+	reservations, err := uc.reservationRepo.FindByOrderID(orderID)
+	if err != nil {
+		uc.logger.Error("Failed to find stock reservations", "order_id", orderID, "error", err)
+		return err
+	}
+
+	for i := range reservations {
+		// A reservation released in between is not committed back
+		err := uc.save(&reservations[i], func(reservation *entity.StockReservation) (bool, error) {
+			if !reservation.IsActive() {
+				return false, nil
+			}
+			return true, reservation.Commit()
+		})
+		if err != nil {
+			uc.logger.Error("Failed to commit stock reservation", "reservation_id", reservations[i].ID, "error", err)
+			return err
+		}
+	}
+
+	uc.logger.Info("Stock reservations committed", "order_id", orderID)
+	return nil
+}
+
+// Release gives back all stock held by an order, whether only reserved or
+// already committed. It is used when a payment is declined or an order is
+// canceled.
+func (uc *StockReservationUseCase) Release(orderID string) error {
+	uc.logger.Info("Releasing stock reservations", "order_id", orderID)
+
+	reservations, err := uc.reservationRepo.FindByOrderID(orderID)
+	if err != nil {
+		uc.logger.Error("Failed to find stock reservations", "order_id", orderID, "error", err)
+		return err
+	}
+
+	for i := range reservations {
+		if err := uc.release(&reservations[i]); err != nil {
+			return err
+		}
+	}
+
+	uc.logger.Info("Stock reservations released", "order_id", orderID)
+	return nil
+}
+
+// ReleaseExpired releases cart reservations whose TTL has passed and returns
+// how many were released.
+func (uc *StockReservationUseCase) ReleaseExpired() (int, error) {
+	now := time.Now()
+	reservations, err := uc.reservationRepo.FindExpired(now)
+	if err != nil {
+		uc.logger.Error("Failed to find expired stock reservations", "error", err)
+		return 0, err
+	}
+
+	released := 0
+	for i := range reservations {
+		// Checked again if the reservation changes in between, since it may
+		// have been kept or extended by its cart
+		quantity, err := uc.giveBack(&reservations[i], func(reservation *entity.StockReservation) (int, error) {
+			if !reservation.IsExpired(now) {
+				return 0, nil
+			}
+			return reservation.Quantity, reservation.Release()
+		})
+		if err != nil {
+			return released, err
+		}
+		if quantity > 0 {
+			released++
+		}
+	}
+
+	if released > 0 {
+		uc.logger.Info("Expired stock reservations released", "count", released)
+	}
+	return released, nil
+}
+
+// Run releases expired cart reservations every interval until ctx is
+// canceled
+func (uc *StockReservationUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			uc.ReleaseExpired()
+		}
+	}
+}
+
+func (uc *StockReservationUseCase) reserve(orderID, productID string, quantity int, ttl time.Duration) error {
+	uc.logger.Info("Reserving stock", "order_id", orderID, "product_id", productID, "quantity", quantity)
+
+	if quantity <= 0 {
+		return entity.ErrInvalidQuantity
+	}
+
+	reservation, err := uc.reservationRepo.FindActive(orderID, productID)
+	if err != nil {
+		uc.logger.Error("Failed to find stock reservation", "order_id", orderID, "product_id", productID, "error", err)
+		return err
+	}
+
+	if err := uc.productRepo.DecreaseStock(productID, quantity); err != nil {
+		if errors.Is(err, entity.ErrInsufficientStock) {
+			uc.logger.Warn("Insufficient stock", "order_id", orderID, "product_id", productID, "quantity", quantity)
+			return fmt.Errorf("%w for product %s", entity.ErrInsufficientStock, productID)
+		}
+		uc.logger.Error("Failed to decrease product stock", "product_id", productID, "error", err)
+		return err
+	}
+
+	if err := uc.hold(reservation, orderID, productID, quantity, ttl); err != nil {
+		uc.logger.Error("Failed to save stock reservation", "order_id", orderID, "product_id", productID, "error", err)
+		if restoreErr := uc.productRepo.IncreaseStock(productID, quantity); restoreErr != nil {
+			uc.logger.Error("Failed to restore product stock", "product_id", productID, "error", restoreErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// hold adds quantity, already taken from the product, to the reservation
+// the order holds for it, or creates one when it holds none. A reservation
+// changed in between is loaded again, so a released one is never saved back
+// as reserved.
+func (uc *StockReservationUseCase) hold(reservation *entity.StockReservation, orderID, productID string, quantity int, ttl time.Duration) error {
+	for attempt := 1; ; attempt++ {
+		if reservation == nil {
+			reservation, err := entity.NewStockReservation(orderID, productID, quantity, ttl)
+			if err != nil {
+				return err
+			}
+			return uc.reservationRepo.Create(reservation)
+		}
+
+		previous := *reservation
+		if err := reservation.Add(quantity, ttl); err != nil {
+			return err
+		}
+		err := uc.reservationRepo.Update(reservation, previous)
+		if !errors.Is(err, entity.ErrReservationConflict) || attempt == maxSaveAttempts {
+			return err
+		}
+
+		if reservation, err = uc.reservationRepo.FindActive(orderID, productID); err != nil {
+			return err
+		}
+	}
+}
+
+func (uc *StockReservationUseCase) release(reservation *entity.StockReservation) error {
+	_, err := uc.giveBack(reservation, func(reservation *entity.StockReservation) (int, error) {
+		if reservation.Status == entity.ReservationStatusReleased {
+			return 0, nil
+		}
+		return reservation.Quantity, reservation.Release()
+	})
+	return err
+}
+
+// giveBack applies change to the reservation, which returns how much stock
+// it gives back, and saves both together. When another request changed the
+// reservation first, it is loaded again and change applied to what it is
+// now, so stock is never given back twice. It returns the quantity given
+// back.
+func (uc *StockReservationUseCase) giveBack(reservation *entity.StockReservation, change func(*entity.StockReservation) (int, error)) (int, error) {
+	for attempt := 1; ; attempt++ {
+		previous := *reservation
+		quantity, err := change(reservation)
+		if err != nil || quantity == 0 {
+			return 0, err
+		}
+
+		err = uc.reservationRepo.GiveBack(reservation, previous, quantity)
+		if err == nil {
+			return quantity, nil
+		}
+		if !errors.Is(err, entity.ErrReservationConflict) || attempt == maxSaveAttempts {
+			uc.logger.Error("Failed to give back reserved stock", "reservation_id", reservation.ID, "error", err)
+			return 0, err
+		}
+
+		if reservation, err = uc.reload(previous); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// save applies change to the reservation, which returns false when there is
+// nothing to save, and saves it. When another request changed the
+// reservation first, it is loaded again and change applied to what it is
+// now.
+func (uc *StockReservationUseCase) save(reservation *entity.StockReservation, change func(*entity.StockReservation) (bool, error)) error {
+	for attempt := 1; ; attempt++ {
+		previous := *reservation
+		changed, err := change(reservation)
+		if err != nil || !changed {
+			return err
+		}
+
+		err = uc.reservationRepo.Update(reservation, previous)
+		if !errors.Is(err, entity.ErrReservationConflict) || attempt == maxSaveAttempts {
+			return err
+		}
+
+		if reservation, err = uc.reload(previous); err != nil {
+			return err
+		}
+	}
+}
+
+func (uc *StockReservationUseCase) reload(reservation entity.StockReservation) (*entity.StockReservation, error) {
+	reservations, err := uc.reservationRepo.FindByOrderID(reservation.OrderID)
+	if err != nil {
+		uc.logger.Error("Failed to find stock reservations", "order_id", reservation.OrderID, "error", err)
+		return nil, err
+	}
+	for i := range reservations {
+		if reservations[i].ID == reservation.ID {
+			return &reservations[i], nil
+		}
+	}
+	return nil, fmt.Errorf("stock reservation %s not found", reservation.ID)
+}
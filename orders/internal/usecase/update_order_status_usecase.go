@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
+	"orders/internal/infra/grpc/client"
+	pb "orders/proto"
+)
+
+// fulfillmentStatuses are the statuses an order may be moved to through the
+// API. The others move money and have their own flows: paid by the checkout,
+// canceled by CancelOrderUseCase and refunded by RefundOrderUseCase.
+var fulfillmentStatuses = map[entity.OrderStatus]bool{
+	entity.OrderStatusShipped:   true,
+	entity.OrderStatusDelivered: true,
+	entity.OrderStatusCompleted: true,
+}
+
+type UpdateOrderStatusUseCase struct {
+	orderRepo     repository.OrderRepository
+	paymentClient *client.PaymentClient
+	logger        *slog.Logger
+}
+
+func NewUpdateOrderStatusUseCase(
+	orderRepo repository.OrderRepository,
+	paymentClient *client.PaymentClient,
+	logger *slog.Logger,
+) *UpdateOrderStatusUseCase {
+	return &UpdateOrderStatusUseCase{
+		orderRepo:     orderRepo,
+		paymentClient: paymentClient,
+		logger:        logger,
+	}
+}
+
+// Execute move o pedido pelo fluxo de entrega. Os pagamentos de cartão apenas
+// autorizados no checkout são capturados quando o pedido é enviado ou
+// concluído, e só então o pedido passa a pago; se a captura falhar o pedido
+// não muda de status e a chamada pode ser repetida.
+func (uc *UpdateOrderStatusUseCase) Execute(ctx context.Context, orderID string, status entity.OrderStatus, reason string) (*entity.Order, error) {
+	if !status.IsValid() {
+		return nil, entity.ErrInvalidOrderStatus
+	}
+	if !fulfillmentStatuses[status] {
+		return nil, fmt.Errorf("%w: %s must go through its own endpoint", entity.ErrInvalidStatusTransition, status)
+	}
+
+	// 1. Buscar pedido
+	order, err := uc.orderRepo.FindByID(orderID)
+	if err != nil {
+		uc.logger.Error("Failed to find order", "error", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to find order: %w", err)
+	}
+
+	if order.Status == status {
+		return order, nil
+	}
+	from := order.Status
+	if from == entity.OrderStatusAuthorized {
+		from = entity.OrderStatusPaid
+	}
+	if !from.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: %s to %s", entity.ErrInvalidStatusTransition, order.Status, status)
+	}
+
+	// 2. Capturar o que foi autorizado no checkout antes de enviar ou concluir
+	if order.Status == entity.OrderStatusAuthorized || status == entity.OrderStatusCompleted {
+		if err := uc.captureAuthorizedPayments(ctx, order.ID); err != nil {
+			return nil, err
+		}
+	}
+	if order.Status == entity.OrderStatusAuthorized {
+		if err := order.TransitionTo(entity.OrderStatusPaid, entity.ActorPayments, "payment captured"); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. Salvar o novo status
+	if err := order.TransitionTo(status, entity.ActorAPI, reason); err != nil {
+		return nil, err
+	}
+	if err := uc.orderRepo.Update(order); err != nil {
+		uc.logger.Error("Failed to update order status", "error", err, "order_id", order.ID)
+		return nil, fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	uc.logger.Info("Order status updated", "order_id", order.ID, "status", status)
+	return order, nil
+}
+
+// captureAuthorizedPayments cobra o valor total das autorizações do pedido.
+// Pagamentos já capturados não aparecem mais como autorizados, então repetir
+// a chamada não cobra duas vezes.
+func (uc *UpdateOrderStatusUseCase) captureAuthorizedPayments(ctx context.Context, orderID string) error {
+	payments, err := uc.paymentClient.ListPayments(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range payments.Payments {
+		if payment.Status != pb.PaymentStatus_PAYMENT_STATUS_AUTHORIZED {
+			continue
+		}
+		if _, err := uc.paymentClient.CapturePayment(ctx, payment.PaymentId, nil); err != nil {
+			uc.logger.Error("Failed to capture payment", "error", err, "order_id", orderID, "payment_id", payment.PaymentId)
+			return err
+		}
+	}
+
+	return nil
+}
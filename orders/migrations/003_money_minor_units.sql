@@ -0,0 +1,21 @@
+-- Store money as integer minor units (cents) plus an ISO-4217 currency
+-- instead of DECIMAL(10, 2), so totals computed in Go match the database.
+
+ALTER TABLE products
+    ADD COLUMN price_cents BIGINT NOT NULL DEFAULT 0 AFTER price,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'BRL' AFTER price_cents;
+UPDATE products SET price_cents = ROUND(price * 100);
+ALTER TABLE products DROP COLUMN price;
+
+ALTER TABLE orders
+    ADD COLUMN total_cents BIGINT NOT NULL DEFAULT 0 AFTER total,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'BRL' AFTER total_cents;
+UPDATE orders SET total_cents = ROUND(total * 100);
+ALTER TABLE orders DROP COLUMN total;
+
+ALTER TABLE items
+    ADD COLUMN unit_price_cents BIGINT NOT NULL DEFAULT 0 AFTER unit_price,
+    ADD COLUMN total_cents BIGINT NOT NULL DEFAULT 0 AFTER total,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'BRL' AFTER total_cents;
+UPDATE items SET unit_price_cents = ROUND(unit_price * 100), total_cents = ROUND(total * 100);
+ALTER TABLE items DROP COLUMN unit_price, DROP COLUMN total;
@@ -0,0 +1,6 @@
+-- Remember the Idempotency-Key of POST /orders/with-payment so a retried
+-- request returns the original order instead of creating and charging a
+-- new one. NULL keys are allowed repeatedly by the unique index.
+ALTER TABLE orders
+    ADD COLUMN idempotency_key VARCHAR(255) NULL AFTER currency,
+    ADD UNIQUE INDEX idx_idempotency_key (idempotency_key);
@@ -0,0 +1,20 @@
+-- Checkout sagas: one per order, saved after every step so an interrupted
+-- checkout can be resumed (or undone) after a restart.
+CREATE TABLE IF NOT EXISTS checkout_sagas (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    step VARCHAR(30) NOT NULL,
+    payment_id VARCHAR(36) NULL,
+    payment_method INT NOT NULL DEFAULT 0,
+    customer_email VARCHAR(255) NOT NULL DEFAULT '',
+    customer_name VARCHAR(255) NOT NULL DEFAULT '',
+    failure_reason TEXT,
+    last_error TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP(6) NOT NULL,
+    updated_at TIMESTAMP(6) NOT NULL,
+    version INT NOT NULL DEFAULT 0,
+    UNIQUE KEY uk_checkout_sagas_order_id (order_id),
+    INDEX idx_status_updated_at (status, updated_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,8 @@
+-- Position reached in the streams consumed from other services (e.g. the
+-- payments WatchPayments stream), so a consumer resumes where it stopped
+-- after a restart or reconnect.
+CREATE TABLE IF NOT EXISTS stream_cursors (
+    stream VARCHAR(100) PRIMARY KEY,
+    cursor_value VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP(6) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
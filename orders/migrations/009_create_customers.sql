@@ -0,0 +1,37 @@
+-- Customer accounts and their delivery addresses. Orders and carts point to
+-- the customer who placed them; guest orders keep customer_id NULL. Email
+-- and CPF identify a single customer; NULL documents are allowed repeatedly
+-- by the unique index.
+CREATE TABLE IF NOT EXISTS customers (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    document VARCHAR(11) NULL,
+    phone VARCHAR(20) NULL,
+    created_at TIMESTAMP(6) NOT NULL,
+    updated_at TIMESTAMP(6) NOT NULL,
+    UNIQUE INDEX idx_email (email),
+    UNIQUE INDEX idx_document (document)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS customer_addresses (
+    id VARCHAR(36) PRIMARY KEY,
+    customer_id VARCHAR(36) NOT NULL,
+    position INT NOT NULL,
+    label VARCHAR(50) NULL,
+    street VARCHAR(255) NOT NULL,
+    number VARCHAR(20) NOT NULL,
+    complement VARCHAR(255) NULL,
+    district VARCHAR(100) NULL,
+    city VARCHAR(100) NOT NULL,
+    state CHAR(2) NOT NULL,
+    zip_code CHAR(8) NOT NULL,
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+    INDEX idx_customer_position (customer_id, position)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Customers with orders cannot be deleted, so the order history is kept
+ALTER TABLE orders
+    ADD COLUMN customer_id VARCHAR(36) NULL AFTER status,
+    ADD INDEX idx_customer_created_at (customer_id, created_at),
+    ADD CONSTRAINT fk_orders_customer FOREIGN KEY (customer_id) REFERENCES customers(id);
@@ -0,0 +1,8 @@
+-- Keyset pagination of the order and product listings: pages are sorted by
+-- created_at and id and continue after the last row of the previous page.
+ALTER TABLE orders
+    ADD INDEX idx_created_at_id (created_at, id),
+    ADD INDEX idx_status_created_at_id (status, created_at, id);
+
+ALTER TABLE products
+    ADD INDEX idx_created_at_id (created_at, id);
@@ -0,0 +1,5 @@
+-- Optimistic concurrency on orders: every update bumps the version and only
+-- applies if the row is still at the version that was read. The cart API
+-- exposes it as the ETag checked by If-Match.
+ALTER TABLE orders
+    ADD COLUMN version INT NOT NULL DEFAULT 0;
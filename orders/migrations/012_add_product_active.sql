@@ -0,0 +1,9 @@
+-- Only active products can be sold. Orders used to create a placeholder
+-- product named "Product <id>", priced by the client and without stock, for
+-- unknown ids; those are deactivated so they cannot be bought again.
+ALTER TABLE products
+    ADD COLUMN active BOOLEAN NOT NULL DEFAULT TRUE AFTER stock;
+
+UPDATE products
+SET active = FALSE
+WHERE name = CONCAT('Product ', id) AND stock = 0;
@@ -0,0 +1,12 @@
+-- Orders are placed when they are submitted for payment: directly through
+-- /orders/with-payment or by checking out a cart. Placed orders are locked,
+-- so carts can no longer change once checked out. Orders that already went
+-- through a checkout are placed at the time they were created.
+ALTER TABLE orders
+    ADD COLUMN placed_at TIMESTAMP NULL AFTER updated_at;
+
+UPDATE orders
+SET placed_at = created_at, updated_at = updated_at
+WHERE status <> 'pending'
+   OR id IN (SELECT order_id FROM checkout_sagas)
+   OR id IN (SELECT order_id FROM order_status_history);
@@ -0,0 +1,11 @@
+-- Carts (orders never placed) expire when left untouched: every change
+-- pushes expires_at forward by CART_TTL (GUEST_CART_TTL for anonymous carts),
+-- and a worker moves carts past it to the expired status, releasing their
+-- stock. Existing carts get a day from their last change.
+ALTER TABLE orders
+    ADD COLUMN expires_at TIMESTAMP NULL AFTER placed_at,
+    ADD INDEX idx_status_expires_at (status, expires_at);
+
+UPDATE orders
+SET expires_at = DATE_ADD(updated_at, INTERVAL 1 DAY), updated_at = updated_at
+WHERE status = 'pending' AND placed_at IS NULL;
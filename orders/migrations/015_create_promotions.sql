@@ -0,0 +1,85 @@
+-- Promotions: coupons (with a code) and automatic discount rules. Rules
+-- target products by id or by category, so products get one. product_ids,
+-- categories and tiers are JSON arrays.
+ALTER TABLE products
+    ADD COLUMN category VARCHAR(100) NOT NULL DEFAULT '' AFTER description,
+    ADD INDEX idx_category (category);
+
+CREATE TABLE IF NOT EXISTS promotions (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(32) NULL,
+    type VARCHAR(50) NOT NULL,
+    percentage INT NOT NULL DEFAULT 0,
+    amount_cents BIGINT NULL,
+    currency CHAR(3) NULL,
+    buy_quantity INT NOT NULL DEFAULT 0,
+    get_quantity INT NOT NULL DEFAULT 0,
+    tiers JSON NULL,
+    product_ids JSON NULL,
+    categories JSON NULL,
+    starts_at TIMESTAMP NULL,
+    ends_at TIMESTAMP NULL,
+    usage_limit INT NOT NULL DEFAULT 0,
+    usage_limit_per_customer INT NOT NULL DEFAULT 0,
+    stackable BOOLEAN NOT NULL DEFAULT FALSE,
+    priority INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP(6) NOT NULL,
+    updated_at TIMESTAMP(6) NOT NULL,
+    UNIQUE INDEX idx_code (code),
+    INDEX idx_active_code (active, code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Orders keep the subtotal of their items and the discounts taken off it:
+-- line discounts off the items, order discounts off what is left. Existing
+-- orders had no discounts.
+ALTER TABLE orders
+    ADD COLUMN subtotal_cents BIGINT NOT NULL DEFAULT 0 AFTER customer_id,
+    ADD COLUMN line_discount_cents BIGINT NOT NULL DEFAULT 0 AFTER subtotal_cents,
+    ADD COLUMN order_discount_cents BIGINT NOT NULL DEFAULT 0 AFTER line_discount_cents;
+
+UPDATE orders
+SET subtotal_cents = total_cents, updated_at = updated_at;
+
+ALTER TABLE items
+    ADD COLUMN discount_cents BIGINT NOT NULL DEFAULT 0 AFTER total_cents;
+
+-- Coupon codes entered in carts
+CREATE TABLE IF NOT EXISTS order_coupons (
+    order_id VARCHAR(36) NOT NULL,
+    position INT NOT NULL,
+    code VARCHAR(32) NOT NULL,
+    PRIMARY KEY (order_id, code),
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- What each promotion took off an order; item_id is set for line discounts
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    position INT NOT NULL,
+    promotion_id VARCHAR(36) NOT NULL,
+    code VARCHAR(32) NULL,
+    name VARCHAR(255) NOT NULL,
+    level VARCHAR(20) NOT NULL,
+    item_id VARCHAR(36) NULL,
+    amount_cents BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    INDEX idx_order_position (order_id, position)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Promotions used by placed orders, counted against the usage limits. The
+-- redemptions of canceled orders are not counted, so they are given back.
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+    id VARCHAR(36) PRIMARY KEY,
+    promotion_id VARCHAR(36) NOT NULL,
+    order_id VARCHAR(36) NOT NULL,
+    customer_id VARCHAR(36) NULL,
+    redeemed_at TIMESTAMP(6) NOT NULL,
+    FOREIGN KEY (promotion_id) REFERENCES promotions(id),
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    UNIQUE INDEX idx_promotion_order (promotion_id, order_id),
+    INDEX idx_promotion_customer (promotion_id, customer_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,6 @@
+-- An Idempotency-Key only replays orders of the customer who sent it, so
+-- another customer reusing the key gets an order of their own. Guest orders
+-- (NULL customer_id) share one scope.
+ALTER TABLE orders
+    DROP INDEX idx_idempotency_key,
+    ADD UNIQUE INDEX idx_customer_idempotency_key ((COALESCE(customer_id, '')), idempotency_key);
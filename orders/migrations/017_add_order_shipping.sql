@@ -0,0 +1,6 @@
+-- Carts are charged a shipping fee, which free shipping promotions waive.
+-- It is kept apart from the item discounts; existing orders had none.
+ALTER TABLE orders
+    ADD COLUMN shipping_cents BIGINT NOT NULL DEFAULT 0 AFTER order_discount_cents,
+    ADD COLUMN shipping_discount_cents BIGINT NOT NULL DEFAULT 0 AFTER shipping_cents,
+    ADD COLUMN free_shipping BOOLEAN NOT NULL DEFAULT FALSE AFTER total_cents;
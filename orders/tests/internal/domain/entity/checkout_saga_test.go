@@ -0,0 +1,118 @@
+package entity
+
+import (
+	"errors"
+	"orders/internal/domain/entity"
+	"testing"
+)
+
+func TestNewCheckoutSaga(t *testing.T) {
+	saga := entity.NewCheckoutSaga("order-1", 1, "john@example.com", "John")
+
+	if saga.ID == "" {
+		t.Error("NewCheckoutSaga() ID should not be empty")
+	}
+	if saga.Status != entity.SagaStatusRunning {
+		t.Errorf("NewCheckoutSaga() status = %v, want %v", saga.Status, entity.SagaStatusRunning)
+	}
+	if saga.Step != entity.SagaStepReserveStock {
+		t.Errorf("NewCheckoutSaga() step = %v, want %v", saga.Step, entity.SagaStepReserveStock)
+	}
+}
+
+func TestCheckoutSaga_Advance(t *testing.T) {
+	saga := entity.NewCheckoutSaga("order-1", 1, "john@example.com", "John")
+
+	steps := []entity.SagaStep{entity.SagaStepAuthorizePayment, entity.SagaStepConfirmOrder}
+	for _, want := range steps {
+		if err := saga.Advance(); err != nil {
+			t.Fatalf("Advance() unexpected error = %v", err)
+		}
+		if saga.Step != want {
+			t.Errorf("Advance() step = %v, want %v", saga.Step, want)
+		}
+	}
+
+	saga.Advance()
+	if saga.Status != entity.SagaStatusCompleted || !saga.IsFinished() {
+		t.Errorf("Advance() status = %v, want %v", saga.Status, entity.SagaStatusCompleted)
+	}
+	if err := saga.Advance(); !errors.Is(err, entity.ErrSagaFinished) {
+		t.Errorf("Advance() error = %v, want %v", err, entity.ErrSagaFinished)
+	}
+}
+
+func TestCheckoutSaga_RecordFailure(t *testing.T) {
+	saga := entity.NewCheckoutSaga("order-1", 1, "john@example.com", "John")
+	saga.Advance()
+
+	saga.RecordFailure(errors.New("payments unavailable"))
+	saga.RecordFailure(errors.New("payments unavailable"))
+	if saga.Attempts != 2 || saga.LastError != "payments unavailable" {
+		t.Errorf("RecordFailure() attempts/error = %v/%q, want 2/%q", saga.Attempts, saga.LastError, "payments unavailable")
+	}
+	if saga.Step != entity.SagaStepAuthorizePayment {
+		t.Errorf("RecordFailure() step = %v, want %v", saga.Step, entity.SagaStepAuthorizePayment)
+	}
+
+	// A step that eventually succeeds starts the next one with a clean slate
+	saga.Advance()
+	if saga.Attempts != 0 || saga.LastError != "" {
+		t.Errorf("Advance() attempts/error = %v/%q, want 0/empty", saga.Attempts, saga.LastError)
+	}
+}
+
+func TestCheckoutSaga_Compensate(t *testing.T) {
+	// A paid order is canceled by compensating its completed saga
+	saga := entity.NewCheckoutSaga("order-1", 1, "john@example.com", "John")
+	saga.Advance()
+	saga.Advance()
+	saga.Advance()
+
+	if err := saga.Compensate("customer request"); err != nil {
+		t.Fatalf("Compensate() unexpected error = %v", err)
+	}
+	if !saga.IsCompensating() || saga.FailureReason != "customer request" {
+		t.Errorf("Compensate() status/reason = %v/%q", saga.Status, saga.FailureReason)
+	}
+
+	// Compensating again keeps the progress already made
+	saga.Advance()
+	saga.Compensate("second request")
+	if saga.Step != entity.SagaStepReleaseStock || saga.FailureReason != "customer request" {
+		t.Errorf("Compensate() twice step/reason = %v/%q, want %v/%q",
+			saga.Step, saga.FailureReason, entity.SagaStepReleaseStock, "customer request")
+	}
+
+	// Payment is undone before the stock, and the order is canceled last
+	if saga.Advance(); saga.Step != entity.SagaStepCancelOrder {
+		t.Errorf("Advance() step = %v, want %v", saga.Step, entity.SagaStepCancelOrder)
+	}
+	saga.Advance()
+	if saga.Status != entity.SagaStatusCompensated {
+		t.Errorf("Advance() status = %v, want %v", saga.Status, entity.SagaStatusCompensated)
+	}
+	if err := saga.Compensate("too late"); !errors.Is(err, entity.ErrSagaFinished) {
+		t.Errorf("Compensate() error = %v, want %v", err, entity.ErrSagaFinished)
+	}
+}
+
+func TestCheckoutSaga_CapturesLater(t *testing.T) {
+	tests := []struct {
+		method int32
+		want   bool
+	}{
+		{entity.PaymentMethodCreditCard, true},
+		{entity.PaymentMethodDebitCard, true},
+		{3, false}, // PIX
+		{4, false}, // Boleto
+		{5, false}, // PayPal
+	}
+
+	for _, tt := range tests {
+		saga := entity.NewCheckoutSaga("order-1", tt.method, "john@example.com", "John")
+		if got := saga.CapturesLater(); got != tt.want {
+			t.Errorf("CapturesLater() with method %d = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
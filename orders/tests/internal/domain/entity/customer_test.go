@@ -0,0 +1,169 @@
+package entity
+
+import (
+	"orders/internal/domain/entity"
+	"testing"
+)
+
+func validAddress() entity.Address {
+	return entity.Address{
+		Label:   "casa",
+		Street:  "Avenida Paulista",
+		Number:  "1000",
+		City:    "São Paulo",
+		State:   "sp",
+		ZipCode: "01310-100",
+	}
+}
+
+func TestNewCustomer(t *testing.T) {
+	withoutCEP := validAddress()
+	withoutCEP.ZipCode = "0131"
+
+	tests := []struct {
+		name        string
+		custName    string
+		email       string
+		document    string
+		phone       string
+		addresses   []entity.Address
+		expectedErr error
+	}{
+		{
+			name:      "valid customer",
+			custName:  "Maria Silva",
+			email:     "maria@example.com",
+			document:  "529.982.247-25",
+			phone:     "+55 11 98765-4321",
+			addresses: []entity.Address{validAddress()},
+		},
+		{
+			name:     "without document, phone and addresses",
+			custName: "Maria Silva",
+			email:    "maria@example.com",
+		},
+		{
+			name:        "empty name",
+			custName:    "  ",
+			email:       "maria@example.com",
+			expectedErr: entity.ErrInvalidCustomerName,
+		},
+		{
+			name:        "invalid email",
+			custName:    "Maria Silva",
+			email:       "maria.example.com",
+			expectedErr: entity.ErrInvalidCustomerEmail,
+		},
+		{
+			name:        "email with display name",
+			custName:    "Maria Silva",
+			email:       "Maria <maria@example.com>",
+			expectedErr: entity.ErrInvalidCustomerEmail,
+		},
+		{
+			name:        "wrong CPF check digit",
+			custName:    "Maria Silva",
+			email:       "maria@example.com",
+			document:    "529.982.247-26",
+			expectedErr: entity.ErrInvalidCustomerCPF,
+		},
+		{
+			name:        "CPF with repeated digits",
+			custName:    "Maria Silva",
+			email:       "maria@example.com",
+			document:    "111.111.111-11",
+			expectedErr: entity.ErrInvalidCustomerCPF,
+		},
+		{
+			name:        "CNPJ is not a CPF",
+			custName:    "Maria Silva",
+			email:       "maria@example.com",
+			document:    "11.222.333/0001-81",
+			expectedErr: entity.ErrInvalidCustomerCPF,
+		},
+		{
+			name:        "short phone",
+			custName:    "Maria Silva",
+			email:       "maria@example.com",
+			phone:       "98765-4321",
+			expectedErr: entity.ErrInvalidCustomerPhone,
+		},
+		{
+			name:        "address with short CEP",
+			custName:    "Maria Silva",
+			email:       "maria@example.com",
+			addresses:   []entity.Address{withoutCEP},
+			expectedErr: entity.ErrInvalidAddress,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer, err := entity.NewCustomer(tt.custName, tt.email, tt.document, tt.phone, tt.addresses)
+
+			if tt.expectedErr != nil {
+				if err != tt.expectedErr {
+					t.Errorf("NewCustomer() error = %v, want %v", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewCustomer() unexpected error = %v", err)
+			}
+			if customer.ID == "" {
+				t.Error("NewCustomer() ID should not be empty")
+			}
+		})
+	}
+}
+
+func TestNewCustomer_Normalizes(t *testing.T) {
+	customer, err := entity.NewCustomer(" Maria Silva ", "Maria@Example.COM", "529.982.247-25", "", []entity.Address{validAddress()})
+	if err != nil {
+		t.Fatalf("NewCustomer() unexpected error = %v", err)
+	}
+
+	if customer.Name != "Maria Silva" {
+		t.Errorf("Name = %q, want %q", customer.Name, "Maria Silva")
+	}
+	if customer.Email != "maria@example.com" {
+		t.Errorf("Email = %q, want %q", customer.Email, "maria@example.com")
+	}
+	if customer.Document != "52998224725" {
+		t.Errorf("Document = %q, want %q", customer.Document, "52998224725")
+	}
+
+	address := customer.Addresses[0]
+	if address.ID == "" {
+		t.Error("Address ID should not be empty")
+	}
+	if address.State != "SP" {
+		t.Errorf("State = %q, want %q", address.State, "SP")
+	}
+	if address.ZipCode != "01310100" {
+		t.Errorf("ZipCode = %q, want %q", address.ZipCode, "01310100")
+	}
+}
+
+func TestCustomer_UpdateKeepsAddressIDs(t *testing.T) {
+	customer, _ := entity.NewCustomer("Maria Silva", "maria@example.com", "", "", []entity.Address{validAddress()})
+	kept := customer.Addresses[0]
+
+	err := customer.Update("Maria Souza", "maria@example.com", "", "", []entity.Address{kept, validAddress()})
+	if err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+
+	if customer.Name != "Maria Souza" {
+		t.Errorf("Name = %q, want %q", customer.Name, "Maria Souza")
+	}
+	if len(customer.Addresses) != 2 {
+		t.Fatalf("Addresses length = %d, want 2", len(customer.Addresses))
+	}
+	if customer.Addresses[0].ID != kept.ID {
+		t.Errorf("first address ID = %q, want %q", customer.Addresses[0].ID, kept.ID)
+	}
+	if customer.Addresses[1].ID == "" || customer.Addresses[1].ID == kept.ID {
+		t.Errorf("second address ID = %q, want a new ID", customer.Addresses[1].ID)
+	}
+}
@@ -0,0 +1,122 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"orders/internal/domain/entity"
+	"testing"
+)
+
+func TestOrder_PlaceRecordsOrderCreated(t *testing.T) {
+	order := entity.NewOrder()
+	if err := order.Place(); !errors.Is(err, entity.ErrEmptyOrder) {
+		t.Errorf("Place() empty order error = %v, want %v", err, entity.ErrEmptyOrder)
+	}
+	if len(order.Events()) != 0 {
+		t.Errorf("Place() empty order recorded %v events, want 0", len(order.Events()))
+	}
+
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	item, _ := entity.NewItem(order.ID, product.ID, product, 2)
+	order.AddItem(item)
+
+	if err := order.Place(); err != nil {
+		t.Fatalf("Place() unexpected error = %v", err)
+	}
+
+	events := order.Events()
+	if len(events) != 1 || events[0].Type != entity.EventOrderCreated {
+		t.Fatalf("Place() events = %+v, want one %v", events, entity.EventOrderCreated)
+	}
+	if events[0].AggregateType != entity.AggregateOrder || events[0].AggregateID != order.ID {
+		t.Errorf("Place() event aggregate = %v/%v, want %v/%v", events[0].AggregateType, events[0].AggregateID, entity.AggregateOrder, order.ID)
+	}
+
+	var payload entity.OrderEventPayload
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil {
+		t.Fatalf("Place() payload is not valid JSON: %v", err)
+	}
+	if payload.Total.Amount != 300000 || len(payload.Items) != 1 || payload.Items[0].Quantity != 2 {
+		t.Errorf("Place() payload = %+v", payload)
+	}
+}
+
+func TestOrder_UpdateStatusRecordsEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		status entity.OrderStatus
+		want   []entity.EventType
+	}{
+		{"paid", entity.OrderStatusPaid, []entity.EventType{entity.EventOrderPaid}},
+		{"canceled", entity.OrderStatusCanceled, []entity.EventType{entity.EventOrderCanceled}},
+		{"payment failed", entity.OrderStatusPaymentFailed, nil},
+		{"unchanged", entity.OrderStatusPending, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := entity.NewOrder()
+			if err := order.UpdateStatus(tt.status); err != nil {
+				t.Fatalf("UpdateStatus() unexpected error = %v", err)
+			}
+
+			events := order.Events()
+			if len(events) != len(tt.want) {
+				t.Fatalf("UpdateStatus() recorded %v events, want %v", len(events), len(tt.want))
+			}
+			for i, event := range events {
+				if event.Type != tt.want[i] {
+					t.Errorf("UpdateStatus() event type = %v, want %v", event.Type, tt.want[i])
+				}
+			}
+
+			order.ClearEvents()
+			if len(order.Events()) != 0 {
+				t.Error("ClearEvents() should drop recorded events")
+			}
+		})
+	}
+}
+
+func TestOrder_ExpireRecordsCartAbandoned(t *testing.T) {
+	// Empty carts just expire
+	empty := entity.NewOrder()
+	if err := empty.Expire(nil); err != nil {
+		t.Fatalf("Expire() unexpected error = %v", err)
+	}
+	if empty.Status != entity.OrderStatusExpired || len(empty.Events()) != 0 {
+		t.Errorf("Expire() empty cart status = %v, events = %v, want expired without events", empty.Status, len(empty.Events()))
+	}
+
+	order := entity.NewOrder()
+	order.CustomerID = "customer-1"
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	item, _ := entity.NewItem(order.ID, product.ID, product, 2)
+	order.AddItem(item)
+
+	customer := &entity.Customer{ID: "customer-1", Name: "Maria", Email: "maria@example.com"}
+	if err := order.Expire(customer); err != nil {
+		t.Fatalf("Expire() unexpected error = %v", err)
+	}
+	order.Expire(customer)
+
+	events := order.Events()
+	if len(events) != 1 || events[0].Type != entity.EventCartAbandoned {
+		t.Fatalf("Expire() events = %+v, want one %v", events, entity.EventCartAbandoned)
+	}
+	var payload entity.CartAbandonedPayload
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil {
+		t.Fatalf("Expire() payload is not valid JSON: %v", err)
+	}
+	if payload.CustomerEmail != "maria@example.com" || payload.Total.Amount != 300000 || len(payload.Items) != 1 || payload.Items[0].Quantity != 2 {
+		t.Errorf("Expire() payload = %+v", payload)
+	}
+
+	// Placed orders are not carts anymore
+	placed := entity.NewOrder()
+	placed.AddItem(item)
+	placed.Place()
+	if err := placed.Expire(nil); !errors.Is(err, entity.ErrCartLocked) {
+		t.Errorf("Expire() placed order error = %v, want %v", err, entity.ErrCartLocked)
+	}
+}
@@ -1,12 +1,14 @@
 package entity
 
 import (
+	"errors"
+	"math"
 	"orders/internal/domain/entity"
 	"testing"
 )
 
 func TestNewItem(t *testing.T) {
-	product, _ := entity.NewProduct("Test Product", "Test", 100.00, 10)
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(10000, "BRL"), 10)
 	tests := []struct {
 		name        string
 		orderID     string
@@ -87,7 +89,7 @@ func TestNewItem(t *testing.T) {
 			if item.UnitPrice != product.Price {
 				t.Errorf("NewItem() unitPrice = %v, want %v", item.UnitPrice, product.Price)
 			}
-			expectedTotal := product.Price * float64(tt.quantity)
+			expectedTotal := entity.NewMoney(product.Price.Amount*int64(tt.quantity), "BRL")
 			if item.Total != expectedTotal {
 				t.Errorf("NewItem() total = %v, want %v", item.Total, expectedTotal)
 			}
@@ -96,24 +98,39 @@ func TestNewItem(t *testing.T) {
 }
 
 func TestItem_CalculateTotal(t *testing.T) {
-	product, _ := entity.NewProduct("Test Product", "Test", 50.00, 10)
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(5000, "BRL"), 10)
 	item, _ := entity.NewItem("order-123", product.ID, product, 3)
 
-	if item.Total != 150.00 {
-		t.Errorf("CalculateTotal() total = %v, want 150.00", item.Total)
+	if item.Total.Amount != 15000 {
+		t.Errorf("CalculateTotal() total = %v, want 15000", item.Total)
 	}
 
 	// Change quantity and recalculate
 	item.Quantity = 5
-	item.CalculateTotal()
+	if err := item.CalculateTotal(); err != nil {
+		t.Errorf("CalculateTotal() unexpected error = %v", err)
+	}
+
+	if item.Total.Amount != 25000 {
+		t.Errorf("CalculateTotal() total = %v, want 25000", item.Total)
+	}
+}
 
-	if item.Total != 250.00 {
-		t.Errorf("CalculateTotal() total = %v, want 250.00", item.Total)
+func TestItem_UpdateQuantity_Overflow(t *testing.T) {
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(5000, "BRL"), 10)
+	item, _ := entity.NewItem("order-123", product.ID, product, 3)
+
+	err := item.UpdateQuantity(math.MaxInt64 / 1000)
+	if !errors.Is(err, entity.ErrMoneyOverflow) {
+		t.Errorf("UpdateQuantity() error = %v, want %v", err, entity.ErrMoneyOverflow)
+	}
+	if item.Quantity != 3 || item.Total.Amount != 15000 {
+		t.Errorf("UpdateQuantity() left quantity %d and total %v, want 3 and 15000", item.Quantity, item.Total)
 	}
 }
 
 func TestItem_UpdateQuantity(t *testing.T) {
-	product, _ := entity.NewProduct("Test Product", "Test", 50.00, 10)
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(5000, "BRL"), 10)
 	item, _ := entity.NewItem("order-123", product.ID, product, 3)
 
 	// Valid update
@@ -124,8 +141,8 @@ func TestItem_UpdateQuantity(t *testing.T) {
 	if item.Quantity != 5 {
 		t.Errorf("UpdateQuantity() quantity = %v, want 5", item.Quantity)
 	}
-	if item.Total != 250.00 {
-		t.Errorf("UpdateQuantity() total = %v, want 250.00", item.Total)
+	if item.Total.Amount != 25000 {
+		t.Errorf("UpdateQuantity() total = %v, want 25000", item.Total)
 	}
 
 	// Invalid quantity
@@ -0,0 +1,186 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"orders/internal/domain/entity"
+	"testing"
+)
+
+func TestMoney_Add(t *testing.T) {
+	a := entity.NewMoney(1010, "BRL")
+	b := entity.NewMoney(2020, "brl")
+
+	sum, err := a.Add(b)
+	if err != nil {
+		t.Errorf("Add() unexpected error = %v", err)
+	}
+	if sum != entity.NewMoney(3030, "BRL") {
+		t.Errorf("Add() = %v, want BRL 30.30", sum)
+	}
+
+	_, err = a.Add(entity.NewMoney(100, "USD"))
+	if !errors.Is(err, entity.ErrCurrencyMismatch) {
+		t.Errorf("Add() error = %v, want %v", err, entity.ErrCurrencyMismatch)
+	}
+}
+
+func TestMoney_Subtract(t *testing.T) {
+	remaining, err := entity.NewMoney(10000, "BRL").Subtract(entity.NewMoney(2550, "brl"))
+	if err != nil {
+		t.Errorf("Subtract() unexpected error = %v", err)
+	}
+	if remaining != entity.NewMoney(7450, "BRL") {
+		t.Errorf("Subtract() = %v, want BRL 74.50", remaining)
+	}
+
+	_, err = remaining.Subtract(entity.NewMoney(100, "USD"))
+	if !errors.Is(err, entity.ErrCurrencyMismatch) {
+		t.Errorf("Subtract() error = %v, want %v", err, entity.ErrCurrencyMismatch)
+	}
+}
+
+func TestMoney_Multiply(t *testing.T) {
+	total, err := entity.NewMoney(1050, "BRL").Multiply(3)
+	if err != nil {
+		t.Errorf("Multiply() unexpected error = %v", err)
+	}
+	if total != entity.NewMoney(3150, "BRL") {
+		t.Errorf("Multiply() = %v, want BRL 31.50", total)
+	}
+}
+
+func TestMoney_Overflow(t *testing.T) {
+	largest := entity.NewMoney(math.MaxInt64, "BRL")
+	smallest := entity.NewMoney(math.MinInt64, "BRL")
+	belowLargest := entity.NewMoney(math.MaxInt64-1, "BRL")
+
+	tests := []struct {
+		name    string
+		operate func() (entity.Money, error)
+		want    int64
+		wantErr bool
+	}{
+		{"Add up to the limit", func() (entity.Money, error) { return belowLargest.Add(entity.NewMoney(1, "BRL")) }, math.MaxInt64, false},
+		{"Add past the limit", func() (entity.Money, error) { return largest.Add(entity.NewMoney(1, "BRL")) }, 0, true},
+		{"Add past the negative limit", func() (entity.Money, error) { return smallest.Add(entity.NewMoney(-1, "BRL")) }, 0, true},
+		{"Subtract past the negative limit", func() (entity.Money, error) { return smallest.Subtract(entity.NewMoney(1, "BRL")) }, 0, true},
+		{"Subtract a negative past the limit", func() (entity.Money, error) { return largest.Subtract(entity.NewMoney(-1, "BRL")) }, 0, true},
+		{"Multiply up to the limit", func() (entity.Money, error) { return entity.NewMoney(math.MaxInt64/2, "BRL").Multiply(2) }, math.MaxInt64 - 1, false},
+		{"Multiply past the limit", func() (entity.Money, error) { return entity.NewMoney(math.MaxInt64/2+1, "BRL").Multiply(2) }, 0, true},
+		{"Multiply the negative limit by -1", func() (entity.Money, error) { return smallest.Multiply(-1) }, 0, true},
+		{"Multiply by zero", func() (entity.Money, error) { return largest.Multiply(0) }, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.operate()
+			if tt.wantErr {
+				if !errors.Is(err, entity.ErrMoneyOverflow) || !errors.Is(err, entity.ErrInvalidMoney) {
+					t.Errorf("error = %v, want %v", err, entity.ErrMoneyOverflow)
+				}
+				return
+			}
+			if err != nil || got.Amount != tt.want {
+				t.Errorf("got %d (error %v), want %d", got.Amount, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_ExactTotals(t *testing.T) {
+	// 0.1 + 0.2 style sums must not drift
+	total := entity.Zero("BRL")
+	for i := 0; i < 1000; i++ {
+		total, _ = total.Add(entity.NewMoney(10, "BRL"))
+	}
+	if total.Amount != 10000 {
+		t.Errorf("Add() accumulated = %v, want 10000", total.Amount)
+	}
+	if total.String() != "BRL 100.00" {
+		t.Errorf("String() = %v, want BRL 100.00", total.String())
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"3500.00", 350000, false},
+		{"10.5", 1050, false},
+		{"0.07", 7, false},
+		{"42", 4200, false},
+		{"-1.25", -125, false},
+		{"1.005", 0, true},
+		{"abc", 0, true},
+		{"", 0, true},
+		{".5", 50, false},
+		{"92233720368547758.07", 9223372036854775807, false},
+		{"92233720368547758.08", 0, true},
+		{"99999999999999999999", 0, true},
+		{"1.-5", 0, true},
+		{"1.+5", 0, true},
+		{"--1", 0, true},
+		{"+1", 0, true},
+		{"-", 0, true},
+		{".", 0, true},
+		{"1_000", 0, true},
+		{"1.5 ", 150, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := entity.ParseDecimal(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDecimal(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseDecimal(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_UnmarshalJSON(t *testing.T) {
+	var m entity.Money
+	if err := json.Unmarshal([]byte(`{"amount": 1999, "currency": "usd"}`), &m); err != nil {
+		t.Fatalf("Unmarshal() object unexpected error = %v", err)
+	}
+	if m != entity.NewMoney(1999, "USD") {
+		t.Errorf("Unmarshal() object = %v, want USD 19.99", m)
+	}
+
+	// Legacy clients still send plain decimals in major units
+	if err := json.Unmarshal([]byte(`19.99`), &m); err != nil {
+		t.Fatalf("Unmarshal() number unexpected error = %v", err)
+	}
+	if m != entity.NewMoney(1999, entity.DefaultCurrency) {
+		t.Errorf("Unmarshal() number = %v, want BRL 19.99", m)
+	}
+}
+
+func TestOrder_AddItem_CurrencyMismatch(t *testing.T) {
+	order := entity.NewOrder()
+	brl, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(10000, "BRL"), 10)
+	usd, _ := entity.NewProduct("Mouse", "Logitech", entity.NewMoney(2000, "USD"), 10)
+
+	item, _ := entity.NewItem(order.ID, brl.ID, brl, 1)
+	if err := order.AddItem(item); err != nil {
+		t.Fatalf("AddItem() unexpected error = %v", err)
+	}
+
+	item, _ = entity.NewItem(order.ID, usd.ID, usd, 1)
+	err := order.AddItem(item)
+	if !errors.Is(err, entity.ErrCurrencyMismatch) {
+		t.Errorf("AddItem() error = %v, want %v", err, entity.ErrCurrencyMismatch)
+	}
+	if len(order.Items) != 1 || order.Total != entity.NewMoney(10000, "BRL") {
+		t.Errorf("AddItem() order changed after mismatch: items = %v, total = %v", len(order.Items), order.Total)
+	}
+}
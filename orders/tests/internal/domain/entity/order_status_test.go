@@ -0,0 +1,98 @@
+package entity
+
+import (
+	"errors"
+	"orders/internal/domain/entity"
+	"testing"
+)
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from entity.OrderStatus
+		to   entity.OrderStatus
+		want bool
+	}{
+		{entity.OrderStatusPending, entity.OrderStatusPaid, true},
+		{entity.OrderStatusPending, entity.OrderStatusPaymentFailed, true},
+		{entity.OrderStatusPaymentFailed, entity.OrderStatusPaid, true},
+		{entity.OrderStatusPending, entity.OrderStatusAuthorized, true},
+		{entity.OrderStatusAuthorized, entity.OrderStatusPaid, true},
+		{entity.OrderStatusAuthorized, entity.OrderStatusCanceled, true},
+		{entity.OrderStatusAuthorized, entity.OrderStatusShipped, false},
+		{entity.OrderStatusAuthorized, entity.OrderStatusRefunded, false},
+		{entity.OrderStatusPaid, entity.OrderStatusShipped, true},
+		{entity.OrderStatusShipped, entity.OrderStatusDelivered, true},
+		{entity.OrderStatusDelivered, entity.OrderStatusRefunded, true},
+		{entity.OrderStatusPending, entity.OrderStatusShipped, false},
+		{entity.OrderStatusShipped, entity.OrderStatusCanceled, false},
+		{entity.OrderStatusCompleted, entity.OrderStatusPending, false},
+		{entity.OrderStatusCanceled, entity.OrderStatusPaid, false},
+		{entity.OrderStatusRefunded, entity.OrderStatusCompleted, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("CanTransitionTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !entity.OrderStatusCanceled.IsFinal() || entity.OrderStatusPaid.IsFinal() {
+		t.Error("IsFinal() should only hold for canceled and refunded")
+	}
+}
+
+func TestOrder_TransitionTo(t *testing.T) {
+	order := entity.NewOrder()
+	order.UpdateStatus(entity.OrderStatusPaid)
+	order.UpdateStatus(entity.OrderStatusCompleted)
+	order.ClearEvents()
+
+	err := order.TransitionTo(entity.OrderStatusPending, entity.ActorAPI, "")
+	if !errors.Is(err, entity.ErrInvalidStatusTransition) {
+		t.Errorf("TransitionTo() error = %v, want %v", err, entity.ErrInvalidStatusTransition)
+	}
+	if order.Status != entity.OrderStatusCompleted {
+		t.Errorf("TransitionTo() status = %v, want %v", order.Status, entity.OrderStatusCompleted)
+	}
+	if len(order.StatusChanges()) != 0 {
+		t.Errorf("TransitionTo() recorded %v changes for a rejected transition", len(order.StatusChanges()))
+	}
+
+	if err := order.TransitionTo(entity.OrderStatusRefunded, entity.ActorAPI, "damaged item"); err != nil {
+		t.Fatalf("TransitionTo() unexpected error = %v", err)
+	}
+	changes := order.StatusChanges()
+	if len(changes) != 1 {
+		t.Fatalf("TransitionTo() recorded %v changes, want 1", len(changes))
+	}
+	change := changes[0]
+	if change.OrderID != order.ID || change.From != entity.OrderStatusCompleted || change.To != entity.OrderStatusRefunded ||
+		change.Actor != entity.ActorAPI || change.Reason != "damaged item" || change.ChangedAt.IsZero() {
+		t.Errorf("TransitionTo() change = %+v", change)
+	}
+
+	// Repeating the current status is a no-op
+	if err := order.TransitionTo(entity.OrderStatusRefunded, entity.ActorAPI, ""); err != nil {
+		t.Errorf("TransitionTo() same status error = %v", err)
+	}
+	if len(order.StatusChanges()) != 1 {
+		t.Errorf("TransitionTo() same status recorded %v changes, want 1", len(order.StatusChanges()))
+	}
+}
+
+func TestOrder_PlaceRecordsHistory(t *testing.T) {
+	order := entity.NewOrder()
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(10000, "BRL"), 10)
+	item, _ := entity.NewItem(order.ID, product.ID, product, 1)
+	order.AddItem(item)
+
+	if err := order.Place(); err != nil {
+		t.Fatalf("Place() unexpected error = %v", err)
+	}
+	changes := order.StatusChanges()
+	if len(changes) != 1 || changes[0].From != "" || changes[0].To != entity.OrderStatusPending {
+		t.Errorf("Place() changes = %+v, want one entry to pending", changes)
+	}
+}
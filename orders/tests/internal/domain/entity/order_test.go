@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"orders/internal/domain/entity"
 	"testing"
 )
@@ -14,7 +15,7 @@ func TestNewOrder(t *testing.T) {
 	if order.Status != entity.OrderStatusPending {
 		t.Errorf("NewOrder() status = %v, want %v", order.Status, entity.OrderStatusPending)
 	}
-	if order.Total != 0 {
+	if order.Total.Amount != 0 {
 		t.Errorf("NewOrder() total = %v, want 0", order.Total)
 	}
 	if len(order.Items) != 0 {
@@ -24,7 +25,7 @@ func TestNewOrder(t *testing.T) {
 
 func TestOrder_AddItem(t *testing.T) {
 	order := entity.NewOrder()
-	product, _ := entity.NewProduct("Test Product", "Test", 100.00, 10)
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(10000, "BRL"), 10)
 	item, _ := entity.NewItem(order.ID, product.ID, product, 2)
 
 	order.AddItem(item)
@@ -32,8 +33,8 @@ func TestOrder_AddItem(t *testing.T) {
 	if len(order.Items) != 1 {
 		t.Errorf("AddItem() items length = %v, want 1", len(order.Items))
 	}
-	if order.Total != 200.00 {
-		t.Errorf("AddItem() total = %v, want 200.00", order.Total)
+	if order.Total.Amount != 20000 {
+		t.Errorf("AddItem() total = %v, want 20000", order.Total)
 	}
 
 	// Add same product again
@@ -46,14 +47,14 @@ func TestOrder_AddItem(t *testing.T) {
 	if order.Items[0].Quantity != 5 {
 		t.Errorf("AddItem() quantity = %v, want 5", order.Items[0].Quantity)
 	}
-	if order.Total != 500.00 {
-		t.Errorf("AddItem() total = %v, want 500.00", order.Total)
+	if order.Total.Amount != 50000 {
+		t.Errorf("AddItem() total = %v, want 50000", order.Total)
 	}
 }
 
 func TestOrder_RemoveItem(t *testing.T) {
 	order := entity.NewOrder()
-	product, _ := entity.NewProduct("Test Product", "Test", 100.00, 10)
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(10000, "BRL"), 10)
 	item, _ := entity.NewItem(order.ID, product.ID, product, 2)
 	order.AddItem(item)
 
@@ -65,7 +66,7 @@ func TestOrder_RemoveItem(t *testing.T) {
 	if len(order.Items) != 0 {
 		t.Errorf("RemoveItem() items length = %v, want 0", len(order.Items))
 	}
-	if order.Total != 0 {
+	if order.Total.Amount != 0 {
 		t.Errorf("RemoveItem() total = %v, want 0", order.Total)
 	}
 
@@ -78,7 +79,7 @@ func TestOrder_RemoveItem(t *testing.T) {
 
 func TestOrder_UpdateItemQuantity(t *testing.T) {
 	order := entity.NewOrder()
-	product, _ := entity.NewProduct("Test Product", "Test", 100.00, 10)
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(10000, "BRL"), 10)
 	item, _ := entity.NewItem(order.ID, product.ID, product, 2)
 	order.AddItem(item)
 
@@ -90,8 +91,8 @@ func TestOrder_UpdateItemQuantity(t *testing.T) {
 	if order.Items[0].Quantity != 5 {
 		t.Errorf("UpdateItemQuantity() quantity = %v, want 5", order.Items[0].Quantity)
 	}
-	if order.Total != 500.00 {
-		t.Errorf("UpdateItemQuantity() total = %v, want 500.00", order.Total)
+	if order.Total.Amount != 50000 {
+		t.Errorf("UpdateItemQuantity() total = %v, want 50000", order.Total)
 	}
 
 	// Invalid quantity
@@ -101,6 +102,58 @@ func TestOrder_UpdateItemQuantity(t *testing.T) {
 	}
 }
 
+func TestOrder_UpdateItemPrice(t *testing.T) {
+	order := entity.NewOrder()
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(10000, "BRL"), 10)
+	item, _ := entity.NewItem(order.ID, product.ID, product, 2)
+	order.AddItem(item)
+
+	if err := order.UpdateItemPrice(item.ID, entity.NewMoney(12500, "BRL")); err != nil {
+		t.Fatalf("UpdateItemPrice() unexpected error = %v", err)
+	}
+	if order.Items[0].Total.Amount != 25000 || order.Total.Amount != 25000 {
+		t.Errorf("UpdateItemPrice() totals = %v/%v, want 25000", order.Items[0].Total, order.Total)
+	}
+
+	if err := order.UpdateItemPrice(item.ID, entity.NewMoney(2500, "USD")); !errors.Is(err, entity.ErrCurrencyMismatch) {
+		t.Errorf("UpdateItemPrice() error = %v, want %v", err, entity.ErrCurrencyMismatch)
+	}
+	if err := order.UpdateItemPrice("missing", entity.NewMoney(2500, "BRL")); !errors.Is(err, entity.ErrItemNotFound) {
+		t.Errorf("UpdateItemPrice() error = %v, want %v", err, entity.ErrItemNotFound)
+	}
+}
+
+func TestOrder_Place_LocksItems(t *testing.T) {
+	order := entity.NewOrder()
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(10000, "BRL"), 10)
+	item, _ := entity.NewItem(order.ID, product.ID, product, 2)
+	order.AddItem(item)
+
+	if err := order.Place(); err != nil {
+		t.Fatalf("Place() unexpected error = %v", err)
+	}
+	if !order.IsPlaced() {
+		t.Fatal("Place() did not set PlacedAt")
+	}
+
+	another, _ := entity.NewItem(order.ID, "another-product", product, 1)
+	if err := order.AddItem(another); !errors.Is(err, entity.ErrCartLocked) {
+		t.Errorf("AddItem() error = %v, want %v", err, entity.ErrCartLocked)
+	}
+	if err := order.UpdateItemQuantity(item.ID, 5); !errors.Is(err, entity.ErrCartLocked) {
+		t.Errorf("UpdateItemQuantity() error = %v, want %v", err, entity.ErrCartLocked)
+	}
+	if err := order.RemoveItem(item.ID); !errors.Is(err, entity.ErrCartLocked) {
+		t.Errorf("RemoveItem() error = %v, want %v", err, entity.ErrCartLocked)
+	}
+	if err := order.Place(); !errors.Is(err, entity.ErrCartLocked) {
+		t.Errorf("Place() twice error = %v, want %v", err, entity.ErrCartLocked)
+	}
+	if len(order.Items) != 1 || order.Total.Amount != 20000 {
+		t.Errorf("locked order items = %v, total = %v, want unchanged", order.Items, order.Total)
+	}
+}
+
 func TestOrder_PrepareForPayment(t *testing.T) {
 	// Empty order
 	order := entity.NewOrder()
@@ -110,7 +163,7 @@ func TestOrder_PrepareForPayment(t *testing.T) {
 	}
 
 	// Order with items
-	product, _ := entity.NewProduct("Test Product", "Test", 100.00, 10)
+	product, _ := entity.NewProduct("Test Product", "Test", entity.NewMoney(10000, "BRL"), 10)
 	item, _ := entity.NewItem(order.ID, product.ID, product, 2)
 	order.AddItem(item)
 
@@ -118,8 +171,8 @@ func TestOrder_PrepareForPayment(t *testing.T) {
 	if err != nil {
 		t.Errorf("PrepareForPayment() unexpected error = %v", err)
 	}
-	if order.Total != 200.00 {
-		t.Errorf("PrepareForPayment() total = %v, want 200.00", order.Total)
+	if order.Total.Amount != 20000 {
+		t.Errorf("PrepareForPayment() total = %v, want 20000", order.Total)
 	}
 }
 
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"errors"
+	"orders/internal/domain/entity"
+	"testing"
+)
+
+func TestPaymentDetails_Validate(t *testing.T) {
+	card := &entity.CardDetails{Number: "4111111111111111", HolderName: "John", ExpiryDate: "12/30", CVV: "123"}
+	pix := &entity.PixDetails{Key: "john@example.com"}
+	boleto := &entity.BoletoDetails{Document: "52998224725"}
+
+	tests := []struct {
+		name          string
+		details       entity.PaymentDetails
+		paymentMethod int32
+		wantErr       bool
+	}{
+		{name: "card for credit card", details: entity.PaymentDetails{Card: card}, paymentMethod: entity.PaymentMethodCreditCard},
+		{name: "card for debit card", details: entity.PaymentDetails{Card: card}, paymentMethod: entity.PaymentMethodDebitCard},
+		{name: "pix key for pix", details: entity.PaymentDetails{Pix: pix}, paymentMethod: entity.PaymentMethodPix},
+		{name: "boleto for boleto", details: entity.PaymentDetails{Boleto: boleto}, paymentMethod: entity.PaymentMethodBoleto},
+		{name: "no details", details: entity.PaymentDetails{}, paymentMethod: entity.PaymentMethodPix},
+		{name: "card for pix", details: entity.PaymentDetails{Card: card}, paymentMethod: entity.PaymentMethodPix, wantErr: true},
+		{name: "pix key for boleto", details: entity.PaymentDetails{Pix: pix}, paymentMethod: entity.PaymentMethodBoleto, wantErr: true},
+		{name: "card and pix", details: entity.PaymentDetails{Card: card, Pix: pix}, paymentMethod: entity.PaymentMethodCreditCard, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.details.Validate(tt.paymentMethod)
+			if tt.wantErr && !errors.Is(err, entity.ErrInvalidPaymentDetails) {
+				t.Errorf("Validate() error = %v, want %v", err, entity.ErrInvalidPaymentDetails)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate() unexpected error = %v", err)
+			}
+		})
+	}
+}
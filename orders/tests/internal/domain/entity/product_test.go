@@ -10,7 +10,7 @@ func TestNewProduct(t *testing.T) {
 		name        string
 		productName string
 		description string
-		price       float64
+		price       entity.Money
 		stock       int
 		wantErr     bool
 		expectedErr error
@@ -19,7 +19,7 @@ func TestNewProduct(t *testing.T) {
 			name:        "valid product",
 			productName: "Laptop",
 			description: "Dell Inspiron",
-			price:       1500.00,
+			price:       entity.NewMoney(150000, "BRL"),
 			stock:       10,
 			wantErr:     false,
 		},
@@ -27,7 +27,7 @@ func TestNewProduct(t *testing.T) {
 			name:        "empty name",
 			productName: "",
 			description: "Test",
-			price:       100.00,
+			price:       entity.NewMoney(10000, "BRL"),
 			stock:       5,
 			wantErr:     true,
 			expectedErr: entity.ErrInvalidProductName,
@@ -36,7 +36,7 @@ func TestNewProduct(t *testing.T) {
 			name:        "zero price",
 			productName: "Test Product",
 			description: "Test",
-			price:       0,
+			price:       entity.NewMoney(0, "BRL"),
 			stock:       5,
 			wantErr:     true,
 			expectedErr: entity.ErrInvalidProductPrice,
@@ -45,11 +45,20 @@ func TestNewProduct(t *testing.T) {
 			name:        "negative price",
 			productName: "Test Product",
 			description: "Test",
-			price:       -10.00,
+			price:       entity.NewMoney(-1000, "BRL"),
 			stock:       5,
 			wantErr:     true,
 			expectedErr: entity.ErrInvalidProductPrice,
 		},
+		{
+			name:        "invalid currency",
+			productName: "Test Product",
+			description: "Test",
+			price:       entity.NewMoney(1000, "R$"),
+			stock:       5,
+			wantErr:     true,
+			expectedErr: entity.ErrInvalidCurrency,
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,7 +97,7 @@ func TestNewProduct(t *testing.T) {
 }
 
 func TestProduct_UpdateStock(t *testing.T) {
-	product, _ := entity.NewProduct("Test", "Test", 100.00, 10)
+	product, _ := entity.NewProduct("Test", "Test", entity.NewMoney(10000, "BRL"), 10)
 
 	tests := []struct {
 		name     string
@@ -0,0 +1,318 @@
+package entity
+
+import (
+	"errors"
+	"orders/internal/domain/entity"
+	"testing"
+	"time"
+)
+
+// newDiscountOrder creates an order with 2 laptops of BRL 1,500.00, in the
+// notebooks category, and 4 mice of BRL 50.00
+func newDiscountOrder(t *testing.T) (*entity.Order, *entity.Product, *entity.Product) {
+	t.Helper()
+	order := entity.NewOrder()
+	laptop, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	laptop.Category = "notebooks"
+	mouse, _ := entity.NewProduct("Mouse", "Logitech", entity.NewMoney(5000, "BRL"), 10)
+
+	for _, line := range []struct {
+		product  *entity.Product
+		quantity int
+	}{{laptop, 2}, {mouse, 4}} {
+		item, err := entity.NewItem(order.ID, line.product.ID, line.product, line.quantity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := order.AddItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return order, laptop, mouse
+}
+
+func newTestPromotion(t *testing.T, promotion entity.Promotion) entity.Promotion {
+	t.Helper()
+	created, err := entity.NewPromotion(promotion)
+	if err != nil {
+		t.Fatalf("NewPromotion() unexpected error = %v", err)
+	}
+	return *created
+}
+
+func TestNewPromotion_Validation(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name      string
+		promotion entity.Promotion
+		want      error
+	}{
+		{"name", entity.Promotion{Type: entity.PromotionPercentage, Percentage: 10}, entity.ErrInvalidPromotionName},
+		{"type", entity.Promotion{Name: "X", Type: "bogus"}, entity.ErrInvalidPromotionType},
+		{"free shipping", entity.Promotion{Name: "X", Code: "10% OFF", Type: entity.PromotionFreeShipping}, entity.ErrInvalidCouponCode},
+		{"code", entity.Promotion{Name: "X", Code: "10% OFF", Type: entity.PromotionPercentage, Percentage: 10}, entity.ErrInvalidCouponCode},
+		{"percentage", entity.Promotion{Name: "X", Type: entity.PromotionPercentage, Percentage: 101}, entity.ErrInvalidPercentage},
+		{"amount", entity.Promotion{Name: "X", Type: entity.PromotionFixedAmount}, entity.ErrInvalidDiscountAmount},
+		{"buy x get y", entity.Promotion{Name: "X", Type: entity.PromotionBuyXGetY, BuyQuantity: 2}, entity.ErrInvalidBuyXGetY},
+		{"tiers", entity.Promotion{Name: "X", Type: entity.PromotionTieredQuantity, Tiers: []entity.DiscountTier{{MinQuantity: 5, Percentage: 10}, {MinQuantity: 3, Percentage: 20}}}, entity.ErrInvalidDiscountTiers},
+		{"window", entity.Promotion{Name: "X", Type: entity.PromotionPercentage, Percentage: 10, StartsAt: &start, EndsAt: &start}, entity.ErrInvalidValidityWindow},
+		{"usage limit", entity.Promotion{Name: "X", Type: entity.PromotionPercentage, Percentage: 10, UsageLimit: -1}, entity.ErrInvalidUsageLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := entity.NewPromotion(tt.promotion); !errors.Is(err, tt.want) {
+				t.Errorf("NewPromotion() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPromotion_NormalizesCodeAndCategories(t *testing.T) {
+	promotion := newTestPromotion(t, entity.Promotion{Name: "X", Code: " welcome10 ", Type: entity.PromotionPercentage, Percentage: 10, Categories: []string{" Notebooks "}})
+
+	if promotion.Code != "WELCOME10" || promotion.Categories[0] != "notebooks" || !promotion.Active {
+		t.Errorf("NewPromotion() = code %q, categories %v, active %v", promotion.Code, promotion.Categories, promotion.Active)
+	}
+}
+
+func TestPromotion_IsValidAt(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	promotion := newTestPromotion(t, entity.Promotion{Name: "X", Type: entity.PromotionPercentage, Percentage: 10, StartsAt: &now, EndsAt: &later})
+
+	if promotion.IsValidAt(now.Add(-time.Minute)) || !promotion.IsValidAt(now) || promotion.IsValidAt(later) {
+		t.Error("IsValidAt() should only hold from StartsAt until before EndsAt")
+	}
+	promotion.Deactivate()
+	if promotion.IsValidAt(now) {
+		t.Error("IsValidAt() should not hold once deactivated")
+	}
+}
+
+func TestOrder_ApplyPromotions(t *testing.T) {
+	tests := []struct {
+		name          string
+		promotion     func(laptop, mouse *entity.Product) entity.Promotion
+		lineDiscount  int64
+		orderDiscount int64
+	}{
+		{"percentage off the order", func(_, _ *entity.Product) entity.Promotion {
+			return entity.Promotion{Name: "10%", Type: entity.PromotionPercentage, Percentage: 10}
+		}, 0, 32000},
+		{"fixed amount off the order", func(_, _ *entity.Product) entity.Promotion {
+			return entity.Promotion{Name: "BRL 20", Type: entity.PromotionFixedAmount, Amount: &entity.Money{Amount: 2000, Currency: "BRL"}}
+		}, 0, 2000},
+		{"fixed amount off each unit of a product", func(_, mouse *entity.Product) entity.Promotion {
+			return entity.Promotion{Name: "BRL 5 per mouse", Type: entity.PromotionFixedAmount, Amount: &entity.Money{Amount: 500, Currency: "BRL"}, ProductIDs: []string{mouse.ID}}
+		}, 2000, 0},
+		{"percentage off a category", func(_, _ *entity.Product) entity.Promotion {
+			return entity.Promotion{Name: "Notebooks 5%", Type: entity.PromotionPercentage, Percentage: 5, Categories: []string{"notebooks"}}
+		}, 15000, 0},
+		{"buy 3 get 1", func(_, mouse *entity.Product) entity.Promotion {
+			return entity.Promotion{Name: "Mice 4 for 3", Type: entity.PromotionBuyXGetY, BuyQuantity: 3, GetQuantity: 1, ProductIDs: []string{mouse.ID}}
+		}, 5000, 0},
+		{"tiered quantity", func(_, _ *entity.Product) entity.Promotion {
+			return entity.Promotion{Name: "Bulk", Type: entity.PromotionTieredQuantity, Tiers: []entity.DiscountTier{{MinQuantity: 2, Percentage: 5}, {MinQuantity: 4, Percentage: 10}}}
+		}, 15000 + 2000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, laptop, mouse := newDiscountOrder(t)
+			promotion := newTestPromotion(t, tt.promotion(laptop, mouse))
+
+			skipped, err := order.ApplyPromotions([]entity.Promotion{promotion})
+			if err != nil {
+				t.Fatalf("ApplyPromotions() unexpected error = %v", err)
+			}
+
+			if len(skipped) != 0 {
+				t.Errorf("skipped = %v, want none", skipped)
+			}
+			if order.Subtotal.Amount != 320000 || order.LineDiscount.Amount != tt.lineDiscount || order.OrderDiscount.Amount != tt.orderDiscount {
+				t.Errorf("subtotal, line, order discount = %d, %d, %d, want 320000, %d, %d",
+					order.Subtotal.Amount, order.LineDiscount.Amount, order.OrderDiscount.Amount, tt.lineDiscount, tt.orderDiscount)
+			}
+			if want := 320000 - tt.lineDiscount - tt.orderDiscount; order.Total.Amount != want {
+				t.Errorf("total = %d, want %d", order.Total.Amount, want)
+			}
+		})
+	}
+}
+
+func TestOrder_ApplyPromotions_OrderDiscountAfterLineDiscounts(t *testing.T) {
+	order, _, _ := newDiscountOrder(t)
+	category := newTestPromotion(t, entity.Promotion{Name: "Notebooks 10%", Type: entity.PromotionPercentage, Percentage: 10, Categories: []string{"notebooks"}, Stackable: true})
+	coupon := newTestPromotion(t, entity.Promotion{Name: "Welcome", Code: "WELCOME10", Type: entity.PromotionPercentage, Percentage: 10, Stackable: true})
+
+	if _, err := order.ApplyPromotions([]entity.Promotion{coupon, category}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 10% off the laptops, then 10% off the BRL 2,900.00 left
+	if order.LineDiscount.Amount != 30000 || order.OrderDiscount.Amount != 29000 || order.Total.Amount != 261000 {
+		t.Errorf("line, order discount, total = %d, %d, %d, want 30000, 29000, 261000",
+			order.LineDiscount.Amount, order.OrderDiscount.Amount, order.Total.Amount)
+	}
+	if order.Items[0].Discount.Amount != 30000 || order.Items[1].Discount.Amount != 0 {
+		t.Errorf("item discounts = %d, %d, want the line discount on the laptops", order.Items[0].Discount.Amount, order.Items[1].Discount.Amount)
+	}
+	if len(order.Discounts) != 2 || order.Discounts[0].Level != entity.DiscountLine || order.Discounts[1].Level != entity.DiscountOrder || order.Discounts[1].Code != "WELCOME10" {
+		t.Errorf("discounts = %+v, want the line discount then the coupon off the order", order.Discounts)
+	}
+}
+
+func TestOrder_ApplyPromotions_Stacking(t *testing.T) {
+	order, _, _ := newDiscountOrder(t)
+	exclusive := newTestPromotion(t, entity.Promotion{Name: "Big sale", Type: entity.PromotionPercentage, Percentage: 20, Priority: 10})
+	stackable := newTestPromotion(t, entity.Promotion{Name: "Welcome", Code: "WELCOME10", Type: entity.PromotionPercentage, Percentage: 10, Stackable: true})
+	notebooks := newTestPromotion(t, entity.Promotion{Name: "Notebooks 5%", Type: entity.PromotionPercentage, Percentage: 5, Categories: []string{"notebooks"}, Stackable: true, Priority: 20})
+	other := newTestPromotion(t, entity.Promotion{Name: "Phones", Code: "PHONES", Type: entity.PromotionPercentage, Percentage: 10, Categories: []string{"phones"}, Stackable: true})
+
+	skipped, err := order.ApplyPromotions([]entity.Promotion{stackable, exclusive, other, notebooks})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The notebooks discount comes first, so the exclusive sale cannot join it
+	if !errors.Is(skipped[exclusive.ID], entity.ErrCouponNotStackable) {
+		t.Errorf("skipped[exclusive] = %v, want %v", skipped[exclusive.ID], entity.ErrCouponNotStackable)
+	}
+	if !errors.Is(skipped[other.ID], entity.ErrCouponNotApplicable) {
+		t.Errorf("skipped[other] = %v, want %v", skipped[other.ID], entity.ErrCouponNotApplicable)
+	}
+	// 5% off the laptops, then 10% off the BRL 3,050.00 left
+	if _, ok := skipped[stackable.ID]; ok || order.LineDiscount.Amount != 15000 || order.OrderDiscount.Amount != 30500 {
+		t.Errorf("line, order discount = %d, %d, want the notebooks discount and the stackable coupon", order.LineDiscount.Amount, order.OrderDiscount.Amount)
+	}
+}
+
+func TestOrder_ApplyPromotions_ExclusiveFirstBlocksOthers(t *testing.T) {
+	order, _, _ := newDiscountOrder(t)
+	exclusive := newTestPromotion(t, entity.Promotion{Name: "Big sale", Type: entity.PromotionPercentage, Percentage: 20, Priority: 10})
+	stackable := newTestPromotion(t, entity.Promotion{Name: "Welcome", Code: "WELCOME10", Type: entity.PromotionPercentage, Percentage: 10, Stackable: true})
+
+	skipped, err := order.ApplyPromotions([]entity.Promotion{stackable, exclusive})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(skipped[stackable.ID], entity.ErrCouponNotStackable) || order.OrderDiscount.Amount != 64000 {
+		t.Errorf("skipped = %v, order discount = %d, want only the exclusive sale", skipped, order.OrderDiscount.Amount)
+	}
+}
+
+func TestOrder_ApplyPromotions_LeavesSomethingToCharge(t *testing.T) {
+	order, _, _ := newDiscountOrder(t)
+	lines := newTestPromotion(t, entity.Promotion{Name: "Everything free", Type: entity.PromotionPercentage, Percentage: 100, ProductIDs: []string{order.Items[0].ProductID, order.Items[1].ProductID}, Stackable: true})
+	coupon := newTestPromotion(t, entity.Promotion{Name: "Gift card", Code: "GIFT", Type: entity.PromotionFixedAmount, Amount: &entity.Money{Amount: 50000, Currency: "BRL"}, Stackable: true})
+
+	skipped, err := order.ApplyPromotions([]entity.Promotion{lines, coupon})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The last item keeps one cent and the coupon has nothing left to take
+	if !errors.Is(skipped[coupon.ID], entity.ErrCouponNotApplicable) {
+		t.Errorf("skipped[coupon] = %v, want %v", skipped[coupon.ID], entity.ErrCouponNotApplicable)
+	}
+	if order.Total.Amount != 1 || order.LineDiscount.Amount != 319999 || order.OrderDiscount.Amount != 0 {
+		t.Errorf("total, line, order discount = %d, %d, %d, want 1, 319999, 0",
+			order.Total.Amount, order.LineDiscount.Amount, order.OrderDiscount.Amount)
+	}
+	if err := order.Place(); err != nil {
+		t.Errorf("Place() unexpected error = %v", err)
+	}
+}
+
+func TestOrder_ApplyPromotions_FreeShipping(t *testing.T) {
+	order, _, _ := newDiscountOrder(t)
+	shipping := newTestPromotion(t, entity.Promotion{Name: "Free shipping", Code: "FRETE", Type: entity.PromotionFreeShipping, Stackable: true})
+	lines := newTestPromotion(t, entity.Promotion{Name: "Everything free", Type: entity.PromotionPercentage, Percentage: 100, ProductIDs: []string{order.Items[0].ProductID, order.Items[1].ProductID}, Stackable: true})
+
+	// Without a shipping fee there is nothing to waive
+	skipped, err := order.ApplyPromotions([]entity.Promotion{shipping})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(skipped[shipping.ID], entity.ErrCouponNotApplicable) || order.FreeShipping {
+		t.Errorf("skipped[shipping] = %v, free shipping = %v, want it not applicable", skipped[shipping.ID], order.FreeShipping)
+	}
+
+	if err := order.SetShipping(1590); err != nil {
+		t.Fatal(err)
+	}
+	if order.Total.Amount != 321590 {
+		t.Errorf("total with shipping = %d, want 321590", order.Total.Amount)
+	}
+
+	// The shipping fee is waived apart from the items, which still keep
+	// one cent to charge
+	skipped, err = order.ApplyPromotions([]entity.Promotion{shipping, lines})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 || !order.FreeShipping {
+		t.Errorf("skipped = %v, free shipping = %v, want both promotions taken", skipped, order.FreeShipping)
+	}
+	if order.Shipping.Amount != 1590 || order.ShippingDiscount.Amount != 1590 || order.Total.Amount != 1 {
+		t.Errorf("shipping, shipping discount, total = %d, %d, %d, want 1590, 1590, 1",
+			order.Shipping.Amount, order.ShippingDiscount.Amount, order.Total.Amount)
+	}
+}
+
+func TestOrder_DiscountsClearedWhenItemsChange(t *testing.T) {
+	order, _, _ := newDiscountOrder(t)
+	promotion := newTestPromotion(t, entity.Promotion{Name: "Notebooks 10%", Type: entity.PromotionPercentage, Percentage: 10, Categories: []string{"notebooks"}})
+	if _, err := order.ApplyPromotions([]entity.Promotion{promotion}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := order.UpdateItemQuantity(order.Items[0].ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(order.Discounts) != 0 || order.Items[0].Discount.Amount != 0 || order.Total.Amount != 170000 {
+		t.Errorf("discounts = %v, total = %d, want the discounts dropped until applied again", order.Discounts, order.Total.Amount)
+	}
+}
+
+func TestOrder_Coupons(t *testing.T) {
+	order, _, _ := newDiscountOrder(t)
+
+	order.AddCoupon("WELCOME10")
+	order.AddCoupon("WELCOME10")
+	if len(order.Coupons) != 1 {
+		t.Errorf("coupons = %v, want the code once", order.Coupons)
+	}
+
+	if err := order.RemoveCoupon("OTHER"); !errors.Is(err, entity.ErrCouponNotApplied) {
+		t.Errorf("RemoveCoupon() error = %v, want %v", err, entity.ErrCouponNotApplied)
+	}
+	if err := order.RemoveCoupon("WELCOME10"); err != nil || len(order.Coupons) != 0 {
+		t.Errorf("RemoveCoupon() = %v, coupons = %v, want it removed", err, order.Coupons)
+	}
+}
+
+func TestOrder_PlaceRecordsRedemptions(t *testing.T) {
+	order, _, _ := newDiscountOrder(t)
+	order.CustomerID = "customer-1"
+	promotion := newTestPromotion(t, entity.Promotion{Name: "Bulk", Type: entity.PromotionTieredQuantity, Tiers: []entity.DiscountTier{{MinQuantity: 2, Percentage: 5}}})
+	if _, err := order.ApplyPromotions([]entity.Promotion{promotion}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := order.Place(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both items are discounted, but the promotion is redeemed once
+	redemptions := order.Redemptions()
+	if len(redemptions) != 1 || redemptions[0].PromotionID != promotion.ID || redemptions[0].CustomerID != "customer-1" {
+		t.Errorf("redemptions = %+v, want one of the promotion by the customer", redemptions)
+	}
+	if _, err := order.ApplyPromotions(nil); !errors.Is(err, entity.ErrCartLocked) {
+		t.Errorf("ApplyPromotions() after placing error = %v, want %v", err, entity.ErrCartLocked)
+	}
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"orders/internal/domain/entity"
+	"testing"
+	"time"
+)
+
+func TestNewStockReservation(t *testing.T) {
+	reservation, err := entity.NewStockReservation("order-1", "product-1", 2, time.Minute)
+	if err != nil {
+		t.Fatalf("NewStockReservation() unexpected error = %v", err)
+	}
+	if reservation.Status != entity.ReservationStatusReserved {
+		t.Errorf("NewStockReservation() status = %v, want %v", reservation.Status, entity.ReservationStatusReserved)
+	}
+	if reservation.ExpiresAt == nil {
+		t.Error("NewStockReservation() with ttl should set ExpiresAt")
+	}
+
+	checkout, _ := entity.NewStockReservation("order-1", "product-1", 2, 0)
+	if checkout.ExpiresAt != nil {
+		t.Error("NewStockReservation() without ttl should not expire")
+	}
+
+	if _, err := entity.NewStockReservation("order-1", "product-1", 0, 0); err != entity.ErrInvalidQuantity {
+		t.Errorf("NewStockReservation() error = %v, want %v", err, entity.ErrInvalidQuantity)
+	}
+}
+
+func TestStockReservation_Reduce(t *testing.T) {
+	reservation, _ := entity.NewStockReservation("order-1", "product-1", 3, 0)
+
+	if err := reservation.Reduce(2); err != nil {
+		t.Errorf("Reduce() unexpected error = %v", err)
+	}
+	if reservation.Quantity != 1 || !reservation.IsActive() {
+		t.Errorf("Reduce() quantity = %v active = %v, want 1 true", reservation.Quantity, reservation.IsActive())
+	}
+
+	if err := reservation.Reduce(1); err != nil {
+		t.Errorf("Reduce() unexpected error = %v", err)
+	}
+	if reservation.Status != entity.ReservationStatusReleased {
+		t.Errorf("Reduce() to zero status = %v, want %v", reservation.Status, entity.ReservationStatusReleased)
+	}
+}
+
+func TestStockReservation_Transitions(t *testing.T) {
+	reservation, _ := entity.NewStockReservation("order-1", "product-1", 3, time.Minute)
+
+	if err := reservation.Commit(); err != nil {
+		t.Errorf("Commit() unexpected error = %v", err)
+	}
+	if reservation.ExpiresAt != nil {
+		t.Error("Commit() should clear ExpiresAt")
+	}
+	if err := reservation.Commit(); err != entity.ErrReservationCannotBeCommitted {
+		t.Errorf("Commit() twice error = %v, want %v", err, entity.ErrReservationCannotBeCommitted)
+	}
+	if err := reservation.Add(1, 0); err != entity.ErrReservationNotActive {
+		t.Errorf("Add() on committed error = %v, want %v", err, entity.ErrReservationNotActive)
+	}
+
+	if err := reservation.Release(); err != nil {
+		t.Errorf("Release() unexpected error = %v", err)
+	}
+	if err := reservation.Release(); err != entity.ErrReservationNotActive {
+		t.Errorf("Release() twice error = %v, want %v", err, entity.ErrReservationNotActive)
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"orders/internal/domain/entity"
+	"orders/internal/infra/grpc/client"
+	"orders/tests/mocks"
+	"path"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakePaymentService answers every call with the error set for its method,
+// as the payments service does
+type fakePaymentService struct {
+	errs          map[string]error
+	authorization string
+}
+
+func (f *fakePaymentService) handle(srv interface{}, stream grpc.ServerStream) error {
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok && len(md.Get("authorization")) > 0 {
+		f.authorization = md.Get("authorization")[0]
+	}
+	method, _ := grpc.MethodFromServerStream(stream)
+	if err, ok := f.errs[path.Base(method)]; ok {
+		return err
+	}
+	return status.Error(codes.Unimplemented, method)
+}
+
+func paymentStatus(t *testing.T, code codes.Code, message, reason, field string) error {
+	st := status.New(code, message)
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: "payments"}
+	var err error
+	if field != "" {
+		st, err = st.WithDetails(info, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: message}},
+		})
+	} else {
+		st, err = st.WithDetails(info)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.Err()
+}
+
+func startFakeService(t *testing.T, service *fakePaymentService) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer(grpc.UnknownServiceHandler(service.handle))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func TestPaymentClient_TranslatesErrors(t *testing.T) {
+	service := &fakePaymentService{errs: map[string]error{
+		"ProcessPayment": paymentStatus(t, codes.InvalidArgument,
+			"invalid payment details: invalid card security code", "INVALID_PAYMENT_DETAILS", "card_details.cvv"),
+		"GetPayment": paymentStatus(t, codes.NotFound,
+			"payment not found", "PAYMENT_NOT_FOUND", ""),
+		"RefundPayment": paymentStatus(t, codes.FailedPrecondition,
+			"only approved or partially refunded payments can be refunded", "PAYMENT_NOT_REFUNDABLE", ""),
+		"ListPayments": paymentStatus(t, codes.Unavailable,
+			"database unavailable", "DATABASE_UNAVAILABLE", ""),
+	}}
+	addr := startFakeService(t, service)
+
+	paymentClient, err := client.NewPaymentClient(addr, client.SecurityConfig{Token: "orders-token"}, mocks.NewMockLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer paymentClient.Close()
+	ctx := context.Background()
+
+	_, err = paymentClient.ProcessPayment(ctx, "order-1", entity.NewMoney(1000, "BRL"), 1, "a@example.com", "A", "order-1", nil)
+	var paymentErr *entity.PaymentError
+	if !errors.Is(err, entity.ErrInvalidPaymentDetails) || !errors.As(err, &paymentErr) {
+		t.Fatalf("ProcessPayment() error = %v, want %v", err, entity.ErrInvalidPaymentDetails)
+	}
+	if len(paymentErr.Fields) != 1 || paymentErr.Fields[0].Field != "card_details.cvv" {
+		t.Errorf("ProcessPayment() fields = %v, want card_details.cvv", paymentErr.Fields)
+	}
+
+	if _, err := paymentClient.GetPayment(ctx, "payment-1"); !errors.Is(err, entity.ErrPaymentNotFound) {
+		t.Errorf("GetPayment() error = %v, want %v", err, entity.ErrPaymentNotFound)
+	}
+
+	_, err = paymentClient.RefundPayment(ctx, "payment-1", nil, "")
+	if !errors.Is(err, entity.ErrPaymentOperationNotAllowed) || !errors.As(err, &paymentErr) || paymentErr.Reason != "PAYMENT_NOT_REFUNDABLE" {
+		t.Errorf("RefundPayment() error = %v, want %v with reason PAYMENT_NOT_REFUNDABLE", err, entity.ErrPaymentOperationNotAllowed)
+	}
+
+	if _, err := paymentClient.ListPayments(ctx, "order-1"); !errors.Is(err, entity.ErrPaymentServiceUnavailable) {
+		t.Errorf("ListPayments() error = %v, want %v", err, entity.ErrPaymentServiceUnavailable)
+	}
+
+	if service.authorization != "Bearer orders-token" {
+		t.Errorf("authorization metadata = %q, want the service token", service.authorization)
+	}
+}
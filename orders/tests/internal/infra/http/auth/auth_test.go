@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"orders/internal/infra/http/auth"
+	"orders/tests/mocks"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const secret = "test-secret"
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func segment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	signed := segment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	signed := segment(t, map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid}) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJWKS(t *testing.T, key *rsa.PublicKey, kid string) string {
+	t.Helper()
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func claims(sub string, roles ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   sub,
+		"exp":   now.Add(time.Hour).Unix(),
+		"roles": roles,
+	}
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	verifier, err := auth.NewVerifier(auth.Config{HMACSecret: secret, Audience: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := claims("customer-1", auth.RoleCustomer)
+	valid["aud"] = "orders"
+	principal, err := verifier.Verify(signHS256(t, valid), now)
+	if err != nil {
+		t.Fatalf("Verify() unexpected error = %v", err)
+	}
+	if principal.Subject != "customer-1" || principal.CustomerID != "customer-1" {
+		t.Errorf("Verify() principal = %+v, want subject and customer customer-1", principal)
+	}
+	if !principal.HasRole(auth.RoleCustomer) || principal.IsPrivileged() {
+		t.Errorf("Verify() roles = %v, want only customer", principal.Roles)
+	}
+
+	expired := claims("customer-1", auth.RoleCustomer)
+	expired["aud"] = "orders"
+	expired["exp"] = now.Add(-time.Minute).Unix()
+	if _, err := verifier.Verify(signHS256(t, expired), now); !errors.Is(err, auth.ErrTokenExpired) {
+		t.Errorf("Verify() expired error = %v, want %v", err, auth.ErrTokenExpired)
+	}
+
+	otherAudience := claims("customer-1", auth.RoleCustomer)
+	otherAudience["aud"] = []string{"payments"}
+	if _, err := verifier.Verify(signHS256(t, otherAudience), now); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Verify() audience error = %v, want %v", err, auth.ErrInvalidToken)
+	}
+
+	tampered := signHS256(t, valid)
+	tampered = tampered[:len(tampered)-2] + "AA"
+	if _, err := verifier.Verify(tampered, now); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Verify() tampered error = %v, want %v", err, auth.ErrInvalidToken)
+	}
+
+	unsigned := segment(t, map[string]string{"alg": "none"}) + "." + segment(t, valid) + "."
+	if _, err := verifier.Verify(unsigned, now); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Verify() alg none error = %v, want %v", err, auth.ErrInvalidToken)
+	}
+}
+
+func TestVerifier_RS256WithJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := auth.NewVerifier(auth.Config{JWKSFile: writeJWKS(t, &key.PublicKey, "key-1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := claims("billing", auth.RoleService)
+	token["role"] = auth.RoleAdmin
+	principal, err := verifier.Verify(signRS256(t, key, "key-1", token), now)
+	if err != nil {
+		t.Fatalf("Verify() unexpected error = %v", err)
+	}
+	if !principal.HasRole(auth.RoleService) || !principal.HasRole(auth.RoleAdmin) {
+		t.Errorf("Verify() roles = %v, want service and admin", principal.Roles)
+	}
+
+	if _, err := verifier.Verify(signRS256(t, key, "key-2", token), now); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Verify() unknown kid error = %v, want %v", err, auth.ErrInvalidToken)
+	}
+
+	// Without a secret, HS256 tokens must not be accepted
+	if _, err := verifier.Verify(signHS256(t, token), now); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Verify() HS256 error = %v, want %v", err, auth.ErrInvalidToken)
+	}
+}
+
+func TestPrincipal_ResolveCustomerID(t *testing.T) {
+	customer := &auth.Principal{Subject: "user-1", Roles: []string{auth.RoleCustomer}, CustomerID: "customer-1"}
+
+	if id, err := customer.ResolveCustomerID(""); err != nil || id != "customer-1" {
+		t.Errorf("ResolveCustomerID(\"\") = %q, %v, want customer-1", id, err)
+	}
+	if _, err := customer.ResolveCustomerID("customer-2"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("ResolveCustomerID(other) error = %v, want %v", err, auth.ErrForbidden)
+	}
+
+	service := &auth.Principal{Subject: "checkout", Roles: []string{auth.RoleService}}
+	if id, err := service.ResolveCustomerID("customer-2"); err != nil || id != "customer-2" {
+		t.Errorf("ResolveCustomerID() for service = %q, %v, want customer-2", id, err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	verifier, _ := auth.NewVerifier(auth.Config{HMACSecret: secret})
+	middleware := auth.NewMiddleware(verifier, mocks.NewMockLogger())
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	owner := func(r *http.Request) (string, error) { return "customer-1", nil }
+
+	adminOnly := middleware.Authenticate(middleware.RequireRole(auth.RoleAdmin)(ok))
+	ownerOnly := middleware.Authenticate(middleware.RequireOwner(owner)(ok))
+
+	token := func(sub string, roles ...string) string {
+		c := claims(sub, roles...)
+		c["exp"] = time.Now().Add(time.Hour).Unix()
+		return signHS256(t, c)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		token   string
+		want    int
+	}{
+		{"missing token", adminOnly, "", http.StatusUnauthorized},
+		{"invalid token", adminOnly, "not-a-jwt", http.StatusUnauthorized},
+		{"admin", adminOnly, token("root", auth.RoleAdmin), http.StatusOK},
+		{"customer on admin route", adminOnly, token("customer-1", auth.RoleCustomer), http.StatusForbidden},
+		{"owner", ownerOnly, token("customer-1", auth.RoleCustomer), http.StatusOK},
+		{"other customer", ownerOnly, token("customer-2", auth.RoleCustomer), http.StatusForbidden},
+		{"service", ownerOnly, token("checkout", auth.RoleService), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package problem
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"orders/internal/domain/entity"
+	"orders/internal/infra/http/problem"
+	"orders/internal/usecase"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// documentedCodes are the codes listed in the swagger enum of Problem.Code
+func documentedCodes(t *testing.T) []string {
+	t.Helper()
+	field, ok := reflect.TypeOf(problem.Problem{}).FieldByName("Code")
+	if !ok {
+		t.Fatal("Problem has no Code field")
+	}
+	return strings.Split(field.Tag.Get("enums"), ",")
+}
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		field  string
+	}{
+		{"missing row", fmt.Errorf("failed to find order: %w", sql.ErrNoRows), http.StatusNotFound, "NOT_FOUND", ""},
+		{"validation", entity.ErrInvalidCustomerEmail, http.StatusBadRequest, "VALIDATION_FAILED", "email"},
+		{"unknown product", usecase.ErrProductNotFound, http.StatusBadRequest, "PRODUCT_NOT_FOUND", "product_id"},
+		{"inactive product", entity.ErrProductInactive, http.StatusBadRequest, "PRODUCT_INACTIVE", "product_id"},
+		{"order items", &usecase.OrderItemsError{Violations: []entity.FieldViolation{
+			{Field: "items[1].price", Description: "price BRL 10.00 does not match the catalog price BRL 12.00"},
+		}}, http.StatusBadRequest, "INVALID_ORDER_ITEMS", "items[1].price"},
+		{"page token", entity.ErrInvalidPageToken, http.StatusBadRequest, "INVALID_PAGE_TOKEN", "page_token"},
+		{"page size", usecase.ErrInvalidPageSize, http.StatusBadRequest, "VALIDATION_FAILED", "page_size"},
+		{"stock", fmt.Errorf("checkout step reserve_stock failed: %w", entity.ErrInsufficientStock), http.StatusConflict, "INSUFFICIENT_STOCK", ""},
+		{"transition", entity.ErrInvalidStatusTransition, http.StatusConflict, "INVALID_STATUS_TRANSITION", ""},
+		{"concurrent update", fmt.Errorf("failed to update order status: %w", entity.ErrOrderConflict), http.StatusConflict, "CONCURRENT_UPDATE", ""},
+		{"expired cart", entity.ErrCartExpired, http.StatusGone, "CART_EXPIRED", ""},
+		{"checked out cart", entity.ErrCartLocked, http.StatusConflict, "CART_LOCKED", ""},
+		{"guest checkout", usecase.ErrCustomerContactRequired, http.StatusBadRequest, "VALIDATION_FAILED", "customer_email"},
+		{"unknown coupon", usecase.ErrCouponNotFound, http.StatusBadRequest, "COUPON_NOT_FOUND", "code"},
+		{"coupon limit", fmt.Errorf("coupon WELCOME10: %w", entity.ErrCouponUsageLimitReached), http.StatusConflict, "COUPON_USAGE_LIMIT_REACHED", ""},
+		{"promotion tiers", entity.ErrInvalidDiscountTiers, http.StatusBadRequest, "VALIDATION_FAILED", "tiers"},
+		{"stale if-match", entity.ErrOrderVersionMismatch, http.StatusPreconditionFailed, "PRECONDITION_FAILED", ""},
+		{"idempotency", entity.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", ""},
+		{"payment without reason", &entity.PaymentError{Kind: entity.ErrPaymentServiceUnavailable}, http.StatusServiceUnavailable, "PAYMENT_SERVICE_UNAVAILABLE", ""},
+		{"payment details", &entity.PaymentError{
+			Kind:    entity.ErrInvalidPaymentDetails,
+			Reason:  "INVALID_PAYMENT_DETAILS",
+			Message: "invalid card security code",
+			Fields:  []entity.FieldViolation{{Field: "card_details.cvv", Description: "invalid card security code"}},
+		}, http.StatusBadRequest, "INVALID_PAYMENT_DETAILS", "card_details.cvv"},
+		{"payment not refundable", &entity.PaymentError{Kind: entity.ErrPaymentOperationNotAllowed, Reason: "PAYMENT_NOT_REFUNDABLE"}, http.StatusConflict, "PAYMENT_NOT_REFUNDABLE", ""},
+		{"unexpected", errors.New("Error 1054: Unknown column 'totl'"), http.StatusInternalServerError, "INTERNAL", ""},
+	}
+
+	codes := documentedCodes(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := problem.FromError(tt.err)
+
+			if p.Status != tt.status || p.Code != tt.code {
+				t.Errorf("FromError() = %d %s, want %d %s", p.Status, p.Code, tt.status, tt.code)
+			}
+			if !slices.Contains(codes, p.Code) {
+				t.Errorf("code %s is not documented in the enums of Problem.Code", p.Code)
+			}
+			if p.Type != "urn:orders:problem:"+strings.ToLower(strings.ReplaceAll(tt.code, "_", "-")) {
+				t.Errorf("type = %s, want it built from the code", p.Type)
+			}
+			if tt.status == http.StatusInternalServerError && strings.Contains(p.Detail, "column") {
+				t.Errorf("detail = %q, internal errors must not leak", p.Detail)
+			}
+
+			if tt.field == "" {
+				if len(p.Errors) != 0 {
+					t.Errorf("errors = %v, want none", p.Errors)
+				}
+				return
+			}
+			if len(p.Errors) != 1 || p.Errors[0].Field != tt.field {
+				t.Errorf("errors = %v, want a violation of %s", p.Errors, tt.field)
+			}
+		})
+	}
+}
+
+func TestOrNotFound(t *testing.T) {
+	err := problem.OrNotFound(fmt.Errorf("failed to find order: %w", sql.ErrNoRows), problem.CodeOrderNotFound, "Order not found")
+	if p := problem.FromError(err); p.Status != http.StatusNotFound || p.Code != problem.CodeOrderNotFound {
+		t.Errorf("FromError() = %d %s, want 404 %s", p.Status, p.Code, problem.CodeOrderNotFound)
+	}
+
+	other := errors.New("connection refused")
+	if err := problem.OrNotFound(other, problem.CodeOrderNotFound, "Order not found"); err != other {
+		t.Errorf("OrNotFound() = %v, want other errors unchanged", err)
+	}
+}
+
+func TestInvalidBody(t *testing.T) {
+	var body struct {
+		Quantity int `json:"quantity"`
+	}
+	err := json.Unmarshal([]byte(`{"quantity": "two"}`), &body)
+
+	p := problem.InvalidBody(err)
+
+	if p.Status != http.StatusBadRequest || p.Code != problem.CodeInvalidBody {
+		t.Errorf("InvalidBody() = %d %s, want 400 %s", p.Status, p.Code, problem.CodeInvalidBody)
+	}
+	if len(p.Errors) != 1 || p.Errors[0].Field != "quantity" {
+		t.Errorf("errors = %v, want a violation of quantity", p.Errors)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var requestID string
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = middleware.GetReqID(r.Context())
+		problem.Write(w, r, problem.Invalid(problem.Field("customer_email", "Customer email is required")))
+	}))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/orders/with-payment", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if got := rec.Header().Get("Content-Type"); got != problem.ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, problem.ContentType)
+	}
+
+	var got problem.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Bad Request" || got.Status != http.StatusBadRequest || got.Code != problem.CodeValidationFailed {
+		t.Errorf("problem = %+v, want a 400 %s", got, problem.CodeValidationFailed)
+	}
+	if got.Instance != "/api/v1/orders/with-payment" {
+		t.Errorf("instance = %q, want the request path", got.Instance)
+	}
+	if requestID == "" || got.RequestID != requestID {
+		t.Errorf("request_id = %q, want %q", got.RequestID, requestID)
+	}
+	if len(got.Errors) != 1 || got.Errors[0].Field != "customer_email" {
+		t.Errorf("errors = %v, want a violation of customer_email", got.Errors)
+	}
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"orders/internal/domain/entity"
+	"orders/internal/usecase"
+	"orders/tests/mocks"
+	"testing"
+	"time"
+)
+
+// newCheckoutCart creates a cart holding a laptop and a mouse. Like the
+// create order tests, checkouts are only followed until the saga would run.
+func newCheckoutCart(t *testing.T, orderRepo *mockOrderRepository, productRepo *mockProductRepository) (*entity.Order, *entity.Product, *entity.Product) {
+	t.Helper()
+	cart := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, mocks.NewMockLogger())
+
+	laptop, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	mouse, _ := entity.NewProduct("Mouse", "Logitech", entity.NewMoney(5000, "BRL"), 10)
+	productRepo.Create(laptop)
+	productRepo.Create(mouse)
+
+	order, _ := cart.CreateOrder("")
+	if _, err := cart.AddItemToCart(order.ID, laptop.ID, 1, usecase.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	order, err := cart.AddItemToCart(order.ID, mouse.ID, 2, usecase.AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order, laptop, mouse
+}
+
+func checkoutInput(orderID string) usecase.CheckoutCartInput {
+	return usecase.CheckoutCartInput{
+		OrderID:       orderID,
+		Version:       usecase.AnyVersion,
+		CustomerEmail: "maria@example.com",
+		CustomerName:  "Maria",
+		PaymentMethod: 3,
+	}
+}
+
+func TestCreateOrderUseCase_CheckoutCart_RejectsItemsNoLongerSold(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	order, laptop, mouse := newCheckoutCart(t, orderRepo, productRepo)
+	uc := newCreateOrderUseCase(orderRepo, productRepo, false)
+
+	laptop.Active = false
+	productRepo.Delete(mouse.ID)
+
+	_, err := uc.CheckoutCart(context.Background(), checkoutInput(order.ID))
+
+	var itemsErr *usecase.OrderItemsError
+	if !errors.As(err, &itemsErr) {
+		t.Fatalf("CheckoutCart() error = %v, want an OrderItemsError", err)
+	}
+	want := []string{"items[0].product_id", "items[1].product_id"}
+	if len(itemsErr.Violations) != len(want) {
+		t.Fatalf("CheckoutCart() violations = %+v, want %v", itemsErr.Violations, want)
+	}
+	for i, field := range want {
+		if itemsErr.Violations[i].Field != field {
+			t.Errorf("CheckoutCart() violation %d = %s, want %s", i, itemsErr.Violations[i].Field, field)
+		}
+	}
+	if order.IsPlaced() {
+		t.Error("CheckoutCart() locked a cart that cannot be sold")
+	}
+}
+
+func TestCreateOrderUseCase_CheckoutCart_Preconditions(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	order, laptop, _ := newCheckoutCart(t, orderRepo, productRepo)
+	uc := newCreateOrderUseCase(orderRepo, productRepo, false)
+
+	// A catalog price change alone does not stop the checkout
+	laptop.Price = entity.NewMoney(140000, "BRL")
+
+	stale := checkoutInput(order.ID)
+	stale.Version = order.Version - 1
+	if _, err := uc.CheckoutCart(context.Background(), stale); !errors.Is(err, entity.ErrOrderVersionMismatch) {
+		t.Errorf("CheckoutCart() error = %v, want %v", err, entity.ErrOrderVersionMismatch)
+	}
+
+	guest := checkoutInput(order.ID)
+	guest.CustomerEmail = ""
+	if _, err := uc.CheckoutCart(context.Background(), guest); !errors.Is(err, usecase.ErrCustomerContactRequired) {
+		t.Errorf("CheckoutCart() error = %v, want %v", err, usecase.ErrCustomerContactRequired)
+	}
+
+	if order.IsPlaced() || order.Items[0].UnitPrice.Amount != 150000 {
+		t.Errorf("CheckoutCart() changed a cart it rejected: placed = %v, price = %v", order.IsPlaced(), order.Items[0].UnitPrice)
+	}
+
+	// Checked out carts are locked
+	order.Place()
+	if _, err := uc.CheckoutCart(context.Background(), checkoutInput(order.ID)); !errors.Is(err, entity.ErrCartLocked) {
+		t.Errorf("CheckoutCart() error = %v, want %v", err, entity.ErrCartLocked)
+	}
+}
+
+func TestCartUseCase_AddItemToCart_CheckedOut(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	order, laptop, _ := newCheckoutCart(t, orderRepo, productRepo)
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, mocks.NewMockLogger())
+
+	order.Place()
+	stock := laptop.Stock
+
+	_, err := uc.AddItemToCart(order.ID, laptop.ID, 1, usecase.AnyVersion)
+	if !errors.Is(err, entity.ErrCartLocked) {
+		t.Errorf("AddItemToCart() error = %v, want %v", err, entity.ErrCartLocked)
+	}
+	if laptop.Stock != stock {
+		t.Errorf("AddItemToCart() stock = %v, want %v after the cart was locked", laptop.Stock, stock)
+	}
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"orders/internal/domain/entity"
+	"orders/internal/usecase"
+	"orders/tests/mocks"
+	"testing"
+	"time"
+)
+
+func TestCartExpiryUseCase_ExpireStale(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	customerRepo := newMockCustomerRepository()
+	stockReservation := newStockReservationUseCase(productRepo)
+	// Guest carts expire quickly, carts of customers live an hour
+	cart := usecase.NewCartUseCase(orderRepo, productRepo, customerRepo, stockReservation, newPromotionUseCase(newMockPromotionRepository()), time.Hour, 50*time.Millisecond, mocks.NewMockLogger())
+	uc := usecase.NewCartExpiryUseCase(orderRepo, customerRepo, stockReservation, 10, mocks.NewMockLogger())
+
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	productRepo.Create(product)
+	customer, _ := entity.NewCustomer("Maria Silva", "maria@example.com", "", "", nil)
+	customerRepo.Create(customer)
+
+	guestCart, _ := cart.CreateOrder("")
+	if _, err := cart.AddItemToCart(guestCart.ID, product.ID, 3, usecase.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	customerCart, _ := cart.CreateOrder(customer.ID)
+	if _, err := cart.AddItemToCart(customerCart.ID, product.ID, 2, usecase.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	expired, err := uc.ExpireStale(context.Background())
+	if err != nil {
+		t.Fatalf("ExpireStale() unexpected error = %v", err)
+	}
+	if expired != 1 {
+		t.Errorf("ExpireStale() expired = %v, want 1", expired)
+	}
+	if guestCart.Status != entity.OrderStatusExpired || customerCart.Status != entity.OrderStatusPending {
+		t.Errorf("ExpireStale() statuses = %v/%v, want expired/pending", guestCart.Status, customerCart.Status)
+	}
+	if product.Stock != 8 {
+		t.Errorf("ExpireStale() stock = %v, want 8 once the guest cart released its items", product.Stock)
+	}
+
+	events := guestCart.Events()
+	if len(events) == 0 || events[len(events)-1].Type != entity.EventCartAbandoned {
+		t.Errorf("ExpireStale() events = %+v, want %v", events, entity.EventCartAbandoned)
+	}
+
+	// Expired carts can no longer change
+	_, err = cart.AddItemToCart(guestCart.ID, product.ID, 1, usecase.AnyVersion)
+	if !errors.Is(err, entity.ErrCartExpired) {
+		t.Errorf("AddItemToCart() error = %v, want %v", err, entity.ErrCartExpired)
+	}
+}
@@ -3,23 +3,34 @@ package usecase
 import (
 	"errors"
 	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
 	"orders/internal/usecase"
 	"orders/tests/mocks"
 	"testing"
+	"time"
 )
 
 // Mock Order Repository
 type mockOrderRepository struct {
 	orders map[string]*entity.Order
+	// versions are the saved versions; bumping one simulates a concurrent
+	// update of the order
+	versions map[string]int
+	// createErr, when set, fails saving new orders
+	createErr error
 }
 
 func newMockOrderRepository() *mockOrderRepository {
 	return &mockOrderRepository{
-		orders: make(map[string]*entity.Order),
+		orders:   make(map[string]*entity.Order),
+		versions: make(map[string]int),
 	}
 }
 
 func (m *mockOrderRepository) Create(order *entity.Order) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
 	m.orders[order.ID] = order
 	return nil
 }
@@ -31,7 +42,16 @@ func (m *mockOrderRepository) FindByID(id string) (*entity.Order, error) {
 	return nil, errors.New("order not found")
 }
 
-func (m *mockOrderRepository) FindAll() ([]entity.Order, error) {
+func (m *mockOrderRepository) FindByIdempotencyKey(customerID, key string) (*entity.Order, error) {
+	for _, order := range m.orders {
+		if order.CustomerID == customerID && order.IdempotencyKey == key {
+			return order, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockOrderRepository) List(filter repository.OrderFilter) ([]entity.Order, error) {
 	orders := make([]entity.Order, 0, len(m.orders))
 	for _, o := range m.orders {
 		orders = append(orders, *o)
@@ -39,10 +59,35 @@ func (m *mockOrderRepository) FindAll() ([]entity.Order, error) {
 	return orders, nil
 }
 
+func (m *mockOrderRepository) FindByCustomerID(customerID string) ([]entity.Order, error) {
+	var orders []entity.Order
+	for _, o := range m.orders {
+		if o.CustomerID == customerID {
+			orders = append(orders, *o)
+		}
+	}
+	return orders, nil
+}
+
+func (m *mockOrderRepository) FindExpiredCarts(now time.Time, limit int) ([]entity.Order, error) {
+	var carts []entity.Order
+	for _, o := range m.orders {
+		if o.Status == entity.OrderStatusPending && !o.IsPlaced() && o.ExpiresAt != nil && !o.ExpiresAt.After(now) && len(carts) < limit {
+			carts = append(carts, *o)
+		}
+	}
+	return carts, nil
+}
+
 func (m *mockOrderRepository) Update(order *entity.Order) error {
 	if _, ok := m.orders[order.ID]; !ok {
 		return errors.New("order not found")
 	}
+	if m.versions[order.ID] != order.Version {
+		return entity.ErrOrderConflict
+	}
+	m.versions[order.ID]++
+	order.Version++
 	m.orders[order.ID] = order
 	return nil
 }
@@ -59,9 +104,9 @@ func TestCartUseCase_CreateOrder(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
 	logger := mocks.NewMockLogger()
-	uc := usecase.NewCartUseCase(orderRepo, productRepo, logger)
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
 
-	order, err := uc.CreateOrder()
+	order, err := uc.CreateOrder("")
 	if err != nil {
 		t.Errorf("CreateOrder() unexpected error = %v", err)
 	}
@@ -81,15 +126,15 @@ func TestCartUseCase_AddItemToCart(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
 	logger := mocks.NewMockLogger()
-	uc := usecase.NewCartUseCase(orderRepo, productRepo, logger)
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
 
 	// Create order and product
-	order, _ := uc.CreateOrder()
-	product, _ := entity.NewProduct("Laptop", "Dell", 1500.00, 10)
+	order, _ := uc.CreateOrder("")
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
 	productRepo.Create(product)
 
 	// Add item
-	updatedOrder, err := uc.AddItemToCart(order.ID, product.ID, 2)
+	updatedOrder, err := uc.AddItemToCart(order.ID, product.ID, 2, usecase.AnyVersion)
 	if err != nil {
 		t.Errorf("AddItemToCart() unexpected error = %v", err)
 	}
@@ -100,8 +145,8 @@ func TestCartUseCase_AddItemToCart(t *testing.T) {
 	if updatedOrder.Items[0].Quantity != 2 {
 		t.Errorf("AddItemToCart() quantity = %v, want 2", updatedOrder.Items[0].Quantity)
 	}
-	if updatedOrder.Total != 3000.00 {
-		t.Errorf("AddItemToCart() total = %v, want 3000.00", updatedOrder.Total)
+	if updatedOrder.Total.Amount != 300000 {
+		t.Errorf("AddItemToCart() total = %v, want 300000", updatedOrder.Total)
 	}
 }
 
@@ -109,31 +154,51 @@ func TestCartUseCase_AddItemToCart_ProductNotFound(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
 	logger := mocks.NewMockLogger()
-	uc := usecase.NewCartUseCase(orderRepo, productRepo, logger)
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
 
-	order, _ := uc.CreateOrder()
+	order, _ := uc.CreateOrder("")
 
-	_, err := uc.AddItemToCart(order.ID, "non-existent-product", 2)
+	_, err := uc.AddItemToCart(order.ID, "non-existent-product", 2, usecase.AnyVersion)
 	if err != usecase.ErrProductNotFound {
 		t.Errorf("AddItemToCart() error = %v, want %v", err, usecase.ErrProductNotFound)
 	}
 }
 
+func TestCartUseCase_AddItemToCart_InactiveProduct(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
+
+	order, _ := uc.CreateOrder("")
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	product.Active = false
+	productRepo.Create(product)
+
+	_, err := uc.AddItemToCart(order.ID, product.ID, 1, usecase.AnyVersion)
+	if !errors.Is(err, entity.ErrProductInactive) {
+		t.Errorf("AddItemToCart() error = %v, want %v", err, entity.ErrProductInactive)
+	}
+	if product.Stock != 10 {
+		t.Errorf("AddItemToCart() stock = %v, want 10", product.Stock)
+	}
+}
+
 func TestCartUseCase_RemoveItemFromCart(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
 	logger := mocks.NewMockLogger()
-	uc := usecase.NewCartUseCase(orderRepo, productRepo, logger)
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
 
 	// Create order and add item
-	order, _ := uc.CreateOrder()
-	product, _ := entity.NewProduct("Laptop", "Dell", 1500.00, 10)
+	order, _ := uc.CreateOrder("")
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
 	productRepo.Create(product)
-	order, _ = uc.AddItemToCart(order.ID, product.ID, 2)
+	order, _ = uc.AddItemToCart(order.ID, product.ID, 2, usecase.AnyVersion)
 
 	// Remove item
 	itemID := order.Items[0].ID
-	updatedOrder, err := uc.RemoveItemFromCart(order.ID, itemID)
+	updatedOrder, err := uc.RemoveItemFromCart(order.ID, itemID, usecase.AnyVersion)
 	if err != nil {
 		t.Errorf("RemoveItemFromCart() unexpected error = %v", err)
 	}
@@ -141,7 +206,7 @@ func TestCartUseCase_RemoveItemFromCart(t *testing.T) {
 	if len(updatedOrder.Items) != 0 {
 		t.Errorf("RemoveItemFromCart() items length = %v, want 0", len(updatedOrder.Items))
 	}
-	if updatedOrder.Total != 0 {
+	if updatedOrder.Total.Amount != 0 {
 		t.Errorf("RemoveItemFromCart() total = %v, want 0", updatedOrder.Total)
 	}
 }
@@ -150,17 +215,17 @@ func TestCartUseCase_UpdateItemQuantity(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
 	logger := mocks.NewMockLogger()
-	uc := usecase.NewCartUseCase(orderRepo, productRepo, logger)
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
 
 	// Create order and add item
-	order, _ := uc.CreateOrder()
-	product, _ := entity.NewProduct("Laptop", "Dell", 1500.00, 10)
+	order, _ := uc.CreateOrder("")
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
 	productRepo.Create(product)
-	order, _ = uc.AddItemToCart(order.ID, product.ID, 2)
+	order, _ = uc.AddItemToCart(order.ID, product.ID, 2, usecase.AnyVersion)
 
 	// Update quantity
 	itemID := order.Items[0].ID
-	updatedOrder, err := uc.UpdateItemQuantity(order.ID, itemID, 5)
+	updatedOrder, err := uc.UpdateItemQuantity(order.ID, itemID, 5, usecase.AnyVersion)
 	if err != nil {
 		t.Errorf("UpdateItemQuantity() unexpected error = %v", err)
 	}
@@ -168,8 +233,8 @@ func TestCartUseCase_UpdateItemQuantity(t *testing.T) {
 	if updatedOrder.Items[0].Quantity != 5 {
 		t.Errorf("UpdateItemQuantity() quantity = %v, want 5", updatedOrder.Items[0].Quantity)
 	}
-	if updatedOrder.Total != 7500.00 {
-		t.Errorf("UpdateItemQuantity() total = %v, want 7500.00", updatedOrder.Total)
+	if updatedOrder.Total.Amount != 750000 {
+		t.Errorf("UpdateItemQuantity() total = %v, want 750000", updatedOrder.Total)
 	}
 }
 
@@ -177,17 +242,17 @@ func TestCartUseCase_CalculateTotal(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
 	logger := mocks.NewMockLogger()
-	uc := usecase.NewCartUseCase(orderRepo, productRepo, logger)
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
 
 	// Create order and add items
-	order, _ := uc.CreateOrder()
-	product1, _ := entity.NewProduct("Laptop", "Dell", 1500.00, 10)
-	product2, _ := entity.NewProduct("Mouse", "Logitech", 50.00, 20)
+	order, _ := uc.CreateOrder("")
+	product1, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	product2, _ := entity.NewProduct("Mouse", "Logitech", entity.NewMoney(5000, "BRL"), 20)
 	productRepo.Create(product1)
 	productRepo.Create(product2)
 
-	uc.AddItemToCart(order.ID, product1.ID, 2)
-	uc.AddItemToCart(order.ID, product2.ID, 3)
+	uc.AddItemToCart(order.ID, product1.ID, 2, usecase.AnyVersion)
+	uc.AddItemToCart(order.ID, product2.ID, 3, usecase.AnyVersion)
 
 	// Calculate total
 	result, err := uc.CalculateTotal(order.ID)
@@ -195,7 +260,7 @@ func TestCartUseCase_CalculateTotal(t *testing.T) {
 		t.Errorf("CalculateTotal() unexpected error = %v", err)
 	}
 
-	expectedTotal := (1500.00 * 2) + (50.00 * 3)
+	expectedTotal := entity.NewMoney((150000*2)+(5000*3), "BRL")
 	if result.Total != expectedTotal {
 		t.Errorf("CalculateTotal() total = %v, want %v", result.Total, expectedTotal)
 	}
@@ -205,12 +270,173 @@ func TestCartUseCase_CalculateTotal_EmptyCart(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
 	logger := mocks.NewMockLogger()
-	uc := usecase.NewCartUseCase(orderRepo, productRepo, logger)
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
 
-	order, _ := uc.CreateOrder()
+	order, _ := uc.CreateOrder("")
 
 	_, err := uc.CalculateTotal(order.ID)
 	if err != entity.ErrEmptyOrder {
 		t.Errorf("CalculateTotal() error = %v, want %v", err, entity.ErrEmptyOrder)
 	}
 }
+
+func TestCartUseCase_AddItemToCart_ReservesStock(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
+
+	order, _ := uc.CreateOrder("")
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 3)
+	productRepo.Create(product)
+
+	if _, err := uc.AddItemToCart(order.ID, product.ID, 2, usecase.AnyVersion); err != nil {
+		t.Fatalf("AddItemToCart() unexpected error = %v", err)
+	}
+	if product.Stock != 1 {
+		t.Errorf("AddItemToCart() stock = %v, want 1", product.Stock)
+	}
+
+	_, err := uc.AddItemToCart(order.ID, product.ID, 2, usecase.AnyVersion)
+	if !errors.Is(err, entity.ErrInsufficientStock) {
+		t.Errorf("AddItemToCart() error = %v, want %v", err, entity.ErrInsufficientStock)
+	}
+	if product.Stock != 1 {
+		t.Errorf("AddItemToCart() stock after failure = %v, want 1", product.Stock)
+	}
+}
+
+func TestCartUseCase_UpdateItemQuantity_AdjustsStock(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
+
+	order, _ := uc.CreateOrder("")
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	productRepo.Create(product)
+	order, _ = uc.AddItemToCart(order.ID, product.ID, 2, usecase.AnyVersion)
+	itemID := order.Items[0].ID
+
+	if _, err := uc.UpdateItemQuantity(order.ID, itemID, 5, usecase.AnyVersion); err != nil {
+		t.Fatalf("UpdateItemQuantity() unexpected error = %v", err)
+	}
+	if product.Stock != 5 {
+		t.Errorf("UpdateItemQuantity() stock = %v, want 5", product.Stock)
+	}
+
+	if _, err := uc.UpdateItemQuantity(order.ID, itemID, 1, usecase.AnyVersion); err != nil {
+		t.Fatalf("UpdateItemQuantity() unexpected error = %v", err)
+	}
+	if product.Stock != 9 {
+		t.Errorf("UpdateItemQuantity() stock = %v, want 9", product.Stock)
+	}
+
+	_, err := uc.UpdateItemQuantity(order.ID, itemID, 20, usecase.AnyVersion)
+	if !errors.Is(err, entity.ErrInsufficientStock) {
+		t.Errorf("UpdateItemQuantity() error = %v, want %v", err, entity.ErrInsufficientStock)
+	}
+}
+
+func TestCartUseCase_RemoveItemFromCart_ReleasesStock(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
+
+	order, _ := uc.CreateOrder("")
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	productRepo.Create(product)
+	order, _ = uc.AddItemToCart(order.ID, product.ID, 4, usecase.AnyVersion)
+
+	if _, err := uc.RemoveItemFromCart(order.ID, order.Items[0].ID, usecase.AnyVersion); err != nil {
+		t.Fatalf("RemoveItemFromCart() unexpected error = %v", err)
+	}
+	if product.Stock != 10 {
+		t.Errorf("RemoveItemFromCart() stock = %v, want 10", product.Stock)
+	}
+}
+
+func TestCartUseCase_AddItemToCart_IfMatch(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
+
+	order, _ := uc.CreateOrder("")
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	productRepo.Create(product)
+
+	order, err := uc.AddItemToCart(order.ID, product.ID, 1, 0)
+	if err != nil {
+		t.Fatalf("AddItemToCart() unexpected error = %v", err)
+	}
+	if order.Version != 1 {
+		t.Errorf("AddItemToCart() version = %v, want 1", order.Version)
+	}
+
+	// A change based on the version before the first one is rejected
+	_, err = uc.AddItemToCart(order.ID, product.ID, 1, 0)
+	if !errors.Is(err, entity.ErrOrderVersionMismatch) {
+		t.Errorf("AddItemToCart() error = %v, want %v", err, entity.ErrOrderVersionMismatch)
+	}
+	if order.Items[0].Quantity != 1 {
+		t.Errorf("AddItemToCart() quantity after mismatch = %v, want 1", order.Items[0].Quantity)
+	}
+	if product.Stock != 9 {
+		t.Errorf("AddItemToCart() stock after mismatch = %v, want 9", product.Stock)
+	}
+}
+
+func TestCartUseCase_AddItemToCart_ConcurrentUpdate(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
+
+	order, _ := uc.CreateOrder("")
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	productRepo.Create(product)
+
+	// Someone else saves the cart after it was read
+	orderRepo.versions[order.ID]++
+
+	_, err := uc.AddItemToCart(order.ID, product.ID, 2, usecase.AnyVersion)
+	if !errors.Is(err, entity.ErrOrderConflict) {
+		t.Errorf("AddItemToCart() error = %v, want %v", err, entity.ErrOrderConflict)
+	}
+	if product.Stock != 10 {
+		t.Errorf("AddItemToCart() stock after conflict = %v, want 10", product.Stock)
+	}
+}
+
+func TestCartUseCase_UpdateStatus(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
+
+	order, _ := uc.CreateOrder("")
+
+	updated, err := uc.UpdateStatus(order.ID, entity.OrderStatusCanceled, "customer gave up", usecase.AnyVersion)
+	if err != nil {
+		t.Fatalf("UpdateStatus() unexpected error = %v", err)
+	}
+	if updated.Version != 1 {
+		t.Errorf("UpdateStatus() version = %v, want 1 after saving", updated.Version)
+	}
+
+	saved, _ := orderRepo.FindByID(order.ID)
+	if saved.Status != entity.OrderStatusCanceled {
+		t.Errorf("UpdateStatus() saved status = %v, want %v", saved.Status, entity.OrderStatusCanceled)
+	}
+	changes := saved.StatusChanges()
+	if len(changes) != 1 || changes[0].Reason != "customer gave up" {
+		t.Errorf("UpdateStatus() status changes = %+v, want the cancellation", changes)
+	}
+
+	_, err = uc.UpdateStatus(order.ID, entity.OrderStatusPaid, "", usecase.AnyVersion)
+	if !errors.Is(err, entity.ErrInvalidStatusTransition) {
+		t.Errorf("UpdateStatus() error = %v, want %v", err, entity.ErrInvalidStatusTransition)
+	}
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"orders/internal/domain/entity"
+	"orders/internal/usecase"
+	"orders/tests/mocks"
+	"testing"
+)
+
+// The items are checked before the checkout saga runs, so these tests need
+// neither the saga nor the payments client
+func newCreateOrderUseCase(orderRepo *mockOrderRepository, productRepo *mockProductRepository, allowQuickAdd bool) *usecase.CreateOrderUseCase {
+	return usecase.NewCreateOrderUseCase(orderRepo, productRepo, newMockCustomerRepository(), newPromotionUseCase(newMockPromotionRepository()), nil, nil, allowQuickAdd, mocks.NewMockLogger())
+}
+
+func TestCreateOrderUseCase_Execute_RejectsItemsOutsideCatalog(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	uc := newCreateOrderUseCase(orderRepo, productRepo, true)
+
+	laptop, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	productRepo.Create(laptop)
+	retired, _ := entity.NewProduct("Old mouse", "Logitech", entity.NewMoney(5000, "BRL"), 10)
+	retired.Active = false
+	productRepo.Create(retired)
+
+	cheaper := entity.NewMoney(100, "BRL")
+	_, err := uc.Execute(context.Background(), usecase.CreateOrderInput{
+		CustomerEmail: "maria@example.com",
+		CustomerName:  "Maria",
+		PaymentMethod: 3,
+		Items: []usecase.OrderItemInput{
+			{ProductID: laptop.ID, Quantity: 1, Price: &cheaper},
+			{ProductID: "unknown-product", Quantity: 1, Price: &cheaper},
+			{ProductID: retired.ID, Quantity: 1},
+			{ProductID: laptop.ID, Quantity: 0},
+		},
+	})
+
+	var itemsErr *usecase.OrderItemsError
+	if !errors.As(err, &itemsErr) || !errors.Is(err, usecase.ErrInvalidOrderItems) {
+		t.Fatalf("Execute() error = %v, want an OrderItemsError", err)
+	}
+	want := []string{"items[0].price", "items[1].product_id", "items[2].product_id", "items[3].quantity"}
+	if len(itemsErr.Violations) != len(want) {
+		t.Fatalf("Execute() violations = %+v, want %v", itemsErr.Violations, want)
+	}
+	for i, field := range want {
+		if itemsErr.Violations[i].Field != field {
+			t.Errorf("Execute() violation %d = %s, want %s", i, itemsErr.Violations[i].Field, field)
+		}
+	}
+
+	// Quick add is only for admins that ask for it
+	if _, ok := productRepo.products["unknown-product"]; ok {
+		t.Error("Execute() created a product without quick add")
+	}
+	if len(orderRepo.orders) != 0 {
+		t.Errorf("Execute() saved %d orders, want none", len(orderRepo.orders))
+	}
+}
+
+func TestCreateOrderUseCase_Execute_QuickAddNeedsConfiguration(t *testing.T) {
+	price := entity.NewMoney(2500, "BRL")
+	input := usecase.CreateOrderInput{
+		CustomerEmail:    "maria@example.com",
+		CustomerName:     "Maria",
+		PaymentMethod:    3,
+		Items:            []usecase.OrderItemInput{{ProductID: "new-product", Quantity: 2, Price: &price}},
+		QuickAddProducts: true,
+	}
+
+	productRepo := newMockProductRepository()
+	_, err := newCreateOrderUseCase(newMockOrderRepository(), productRepo, false).Execute(context.Background(), input)
+	if !errors.Is(err, usecase.ErrInvalidOrderItems) {
+		t.Errorf("Execute() error = %v, want %v when quick add is disabled", err, usecase.ErrInvalidOrderItems)
+	}
+	if len(productRepo.products) != 0 {
+		t.Errorf("Execute() created %d products, want none when quick add is disabled", len(productRepo.products))
+	}
+
+	// Enabled, nothing is created while any item is rejected
+	input.Items = append(input.Items, usecase.OrderItemInput{ProductID: "priceless-product", Quantity: 1})
+	_, err = newCreateOrderUseCase(newMockOrderRepository(), productRepo, true).Execute(context.Background(), input)
+	var itemsErr *usecase.OrderItemsError
+	if !errors.As(err, &itemsErr) || len(itemsErr.Violations) != 1 || itemsErr.Violations[0].Field != "items[1].price" {
+		t.Fatalf("Execute() error = %v, want only the item without a price rejected", err)
+	}
+	if len(productRepo.products) != 0 {
+		t.Errorf("Execute() created %d products, want none when an item is rejected", len(productRepo.products))
+	}
+
+	// With every item valid the product is created, with stock for all the
+	// items that have it, before the order is saved; saving fails here so
+	// the order stops before the checkout, which these tests do not wire
+	input.Items = []usecase.OrderItemInput{
+		{ProductID: "new-product", Quantity: 2, Price: &price},
+		{ProductID: "new-product", Quantity: 1, Price: &price},
+	}
+	orderRepo := newMockOrderRepository()
+	orderRepo.createErr = errors.New("database is down")
+	if _, err := newCreateOrderUseCase(orderRepo, productRepo, true).Execute(context.Background(), input); !errors.Is(err, orderRepo.createErr) {
+		t.Fatalf("Execute() error = %v, want %v", err, orderRepo.createErr)
+	}
+	product, ok := productRepo.products["new-product"]
+	if !ok {
+		t.Fatal("Execute() did not quick add the product")
+	}
+	if product.Price != price || product.Stock != 3 || !product.Active {
+		t.Errorf("quick added product = %+v, want price %s, stock 3 and active", product, price)
+	}
+}
+
+func TestCreateOrderUseCase_Execute_IdempotencyKeyIsScopedToCustomer(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	customerRepo := newMockCustomerRepository()
+	uc := usecase.NewCreateOrderUseCase(orderRepo, productRepo, customerRepo, newPromotionUseCase(newMockPromotionRepository()), nil, nil, false, mocks.NewMockLogger())
+
+	laptop, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	productRepo.Create(laptop)
+	maria, _ := entity.NewCustomer("Maria", "maria@example.com", "", "", nil)
+	customerRepo.Create(maria)
+	joao, _ := entity.NewCustomer("Joao", "joao@example.com", "", "", nil)
+	customerRepo.Create(joao)
+
+	// Maria already ordered with the key
+	first := entity.NewOrder()
+	first.CustomerID = maria.ID
+	first.IdempotencyKey = "checkout-42"
+	item, _ := entity.NewItem(first.ID, laptop.ID, laptop, 1)
+	first.AddItem(item)
+	orderRepo.Create(first)
+
+	// Joao sends the same key and items: his request is a new order, not a
+	// replay of Maria's. Saving fails here so it stops before the checkout,
+	// which these tests do not wire.
+	orderRepo.createErr = errors.New("database is down")
+	output, err := uc.Execute(context.Background(), usecase.CreateOrderInput{
+		CustomerID:     joao.ID,
+		PaymentMethod:  3,
+		IdempotencyKey: "checkout-42",
+		Items:          []usecase.OrderItemInput{{ProductID: laptop.ID, Quantity: 1}},
+	})
+	if !errors.Is(err, orderRepo.createErr) {
+		t.Fatalf("Execute() = %+v, %v, want a new order for the second customer", output, err)
+	}
+	if len(orderRepo.orders) != 1 || orderRepo.orders[first.ID].CustomerID != maria.ID {
+		t.Errorf("Execute() changed the first customer's order")
+	}
+}
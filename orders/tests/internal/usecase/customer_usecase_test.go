@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"orders/internal/domain/entity"
+	"orders/internal/usecase"
+	"orders/tests/mocks"
+	"testing"
+	"time"
+)
+
+// Mock Customer Repository
+type mockCustomerRepository struct {
+	customers map[string]*entity.Customer
+}
+
+func newMockCustomerRepository() *mockCustomerRepository {
+	return &mockCustomerRepository{
+		customers: make(map[string]*entity.Customer),
+	}
+}
+
+func (m *mockCustomerRepository) Create(customer *entity.Customer) error {
+	for _, c := range m.customers {
+		if c.Email == customer.Email {
+			return entity.ErrDuplicateCustomer
+		}
+	}
+	m.customers[customer.ID] = customer
+	return nil
+}
+
+func (m *mockCustomerRepository) FindByID(id string) (*entity.Customer, error) {
+	if customer, ok := m.customers[id]; ok {
+		return customer, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockCustomerRepository) FindAll() ([]entity.Customer, error) {
+	customers := make([]entity.Customer, 0, len(m.customers))
+	for _, c := range m.customers {
+		customers = append(customers, *c)
+	}
+	return customers, nil
+}
+
+func (m *mockCustomerRepository) Update(customer *entity.Customer) error {
+	if _, ok := m.customers[customer.ID]; !ok {
+		return errors.New("customer not found")
+	}
+	m.customers[customer.ID] = customer
+	return nil
+}
+
+func (m *mockCustomerRepository) Delete(id string) error {
+	delete(m.customers, id)
+	return nil
+}
+
+func TestCustomerUseCase_CreateCustomer(t *testing.T) {
+	uc := usecase.NewCustomerUseCase(newMockCustomerRepository(), newMockOrderRepository(), mocks.NewMockLogger())
+
+	customer, err := uc.CreateCustomer(usecase.CustomerInput{
+		Name:     "Maria Silva",
+		Email:    "maria@example.com",
+		Document: "529.982.247-25",
+	})
+	if err != nil {
+		t.Fatalf("CreateCustomer() unexpected error = %v", err)
+	}
+	if customer.Document != "52998224725" {
+		t.Errorf("CreateCustomer() document = %q, want %q", customer.Document, "52998224725")
+	}
+
+	_, err = uc.CreateCustomer(usecase.CustomerInput{Name: "Maria Souza", Email: "maria@example.com"})
+	if !errors.Is(err, entity.ErrDuplicateCustomer) {
+		t.Errorf("CreateCustomer() error = %v, want %v", err, entity.ErrDuplicateCustomer)
+	}
+}
+
+func TestCustomerUseCase_UpdateCustomer_Invalid(t *testing.T) {
+	repo := newMockCustomerRepository()
+	uc := usecase.NewCustomerUseCase(repo, newMockOrderRepository(), mocks.NewMockLogger())
+
+	customer, _ := uc.CreateCustomer(usecase.CustomerInput{Name: "Maria Silva", Email: "maria@example.com"})
+
+	_, err := uc.UpdateCustomer(customer.ID, usecase.CustomerInput{Name: "Maria Silva", Email: "invalid"})
+	if !errors.Is(err, entity.ErrInvalidCustomerEmail) {
+		t.Errorf("UpdateCustomer() error = %v, want %v", err, entity.ErrInvalidCustomerEmail)
+	}
+
+	_, err = uc.UpdateCustomer("non-existent-customer", usecase.CustomerInput{Name: "Maria Silva", Email: "maria@example.com"})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateCustomer() error = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestCustomerUseCase_ListOrders(t *testing.T) {
+	customerRepo := newMockCustomerRepository()
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewCustomerUseCase(customerRepo, orderRepo, logger)
+	cartUC := usecase.NewCartUseCase(orderRepo, productRepo, customerRepo, newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, logger)
+
+	customer, _ := uc.CreateCustomer(usecase.CustomerInput{Name: "Maria Silva", Email: "maria@example.com"})
+	cart, err := cartUC.CreateOrder(customer.ID)
+	if err != nil {
+		t.Fatalf("CreateOrder() unexpected error = %v", err)
+	}
+	// Anonymous cart, not in the customer history
+	cartUC.CreateOrder("")
+
+	orders, err := uc.ListOrders(customer.ID)
+	if err != nil {
+		t.Fatalf("ListOrders() unexpected error = %v", err)
+	}
+	if len(orders) != 1 || orders[0].ID != cart.ID {
+		t.Errorf("ListOrders() = %v, want only cart %s", orders, cart.ID)
+	}
+
+	_, err = uc.ListOrders("non-existent-customer")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ListOrders() error = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestCartUseCase_CreateOrder_UnknownCustomer(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, newMockCustomerRepository(), newStockReservationUseCase(productRepo), newPromotionUseCase(newMockPromotionRepository()), time.Hour, time.Hour, mocks.NewMockLogger())
+
+	_, err := uc.CreateOrder("non-existent-customer")
+	if err != usecase.ErrCustomerNotFound {
+		t.Errorf("CreateOrder() error = %v, want %v", err, usecase.ErrCustomerNotFound)
+	}
+	if len(orderRepo.orders) != 0 {
+		t.Errorf("CreateOrder() saved %d orders, want 0", len(orderRepo.orders))
+	}
+}
@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"orders/internal/domain/entity"
+	"orders/internal/infra/broker"
+	"orders/internal/usecase"
+	"orders/tests/mocks"
+	"testing"
+	"time"
+)
+
+// Mock Outbox Repository
+type mockOutboxRepository struct {
+	events []entity.Event
+}
+
+func (m *mockOutboxRepository) FindUnpublished(limit int) ([]entity.Event, error) {
+	var events []entity.Event
+	for _, event := range m.events {
+		if event.PublishedAt == nil && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *mockOutboxRepository) MarkPublished(id string, publishedAt time.Time) error {
+	for i := range m.events {
+		if m.events[i].ID == id {
+			m.events[i].PublishedAt = &publishedAt
+			m.events[i].Attempts++
+		}
+	}
+	return nil
+}
+
+func (m *mockOutboxRepository) MarkFailed(id string, reason string) error {
+	for i := range m.events {
+		if m.events[i].ID == id {
+			m.events[i].Attempts++
+			m.events[i].LastError = reason
+		}
+	}
+	return nil
+}
+
+// failingBroker rejects every event until it is healed
+type failingBroker struct {
+	*broker.MemoryBroker
+	failing bool
+}
+
+func (b *failingBroker) Publish(ctx context.Context, event entity.Event) error {
+	if b.failing {
+		return errors.New("broker unavailable")
+	}
+	return b.MemoryBroker.Publish(ctx, event)
+}
+
+func newOutboxEvent(t *testing.T, eventType entity.EventType, orderID string) entity.Event {
+	t.Helper()
+	event, err := entity.NewEvent(eventType, entity.AggregateOrder, orderID, map[string]string{"order_id": orderID})
+	if err != nil {
+		t.Fatalf("NewEvent() unexpected error = %v", err)
+	}
+	return event
+}
+
+func TestOutboxRelayUseCase_PublishPending(t *testing.T) {
+	outboxRepo := &mockOutboxRepository{events: []entity.Event{
+		newOutboxEvent(t, entity.EventOrderCreated, "order-1"),
+		newOutboxEvent(t, entity.EventOrderPaid, "order-1"),
+		newOutboxEvent(t, entity.EventOrderCreated, "order-2"),
+	}}
+	memoryBroker := broker.NewMemoryBroker()
+	uc := usecase.NewOutboxRelayUseCase(outboxRepo, memoryBroker, 2, mocks.NewMockLogger())
+
+	published, err := uc.PublishPending(context.Background())
+	if err != nil {
+		t.Fatalf("PublishPending() unexpected error = %v", err)
+	}
+	if published != 2 {
+		t.Errorf("PublishPending() published = %v, want 2 (batch size)", published)
+	}
+
+	published, _ = uc.PublishPending(context.Background())
+	if published != 1 {
+		t.Errorf("PublishPending() second batch published = %v, want 1", published)
+	}
+
+	events := memoryBroker.Events()
+	if len(events) != 3 || events[0].Type != entity.EventOrderCreated || events[1].Type != entity.EventOrderPaid {
+		t.Errorf("PublishPending() broker received %+v, want events in outbox order", events)
+	}
+
+	published, _ = uc.PublishPending(context.Background())
+	if published != 0 {
+		t.Errorf("PublishPending() with nothing pending published = %v, want 0", published)
+	}
+}
+
+func TestOutboxRelayUseCase_RetriesFailedEvents(t *testing.T) {
+	outboxRepo := &mockOutboxRepository{events: []entity.Event{
+		newOutboxEvent(t, entity.EventOrderCreated, "order-1"),
+		newOutboxEvent(t, entity.EventOrderCanceled, "order-1"),
+	}}
+	flaky := &failingBroker{MemoryBroker: broker.NewMemoryBroker(), failing: true}
+	uc := usecase.NewOutboxRelayUseCase(outboxRepo, flaky, 10, mocks.NewMockLogger())
+
+	published, err := uc.PublishPending(context.Background())
+	if err == nil {
+		t.Fatal("PublishPending() expected error from unavailable broker")
+	}
+	if published != 0 {
+		t.Errorf("PublishPending() published = %v, want 0", published)
+	}
+	if outboxRepo.events[0].Attempts != 1 || outboxRepo.events[0].LastError == "" {
+		t.Errorf("PublishPending() should record the failed attempt, got %+v", outboxRepo.events[0])
+	}
+	if outboxRepo.events[1].Attempts != 0 {
+		t.Error("PublishPending() should stop at the first failure to keep events in order")
+	}
+
+	flaky.failing = false
+	published, err = uc.PublishPending(context.Background())
+	if err != nil {
+		t.Fatalf("PublishPending() unexpected error after recovery = %v", err)
+	}
+	if published != 2 || len(flaky.Events()) != 2 {
+		t.Errorf("PublishPending() after recovery published = %v, want 2", published)
+	}
+}
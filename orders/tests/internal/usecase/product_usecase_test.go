@@ -1,11 +1,15 @@
 package usecase
 
 import (
+	"database/sql"
 	"errors"
 	"orders/internal/domain/entity"
+	"orders/internal/domain/repository"
 	"orders/internal/usecase"
 	"orders/tests/mocks"
+	"sort"
 	"testing"
+	"time"
 )
 
 // Mock Repository
@@ -28,15 +32,46 @@ func (m *mockProductRepository) FindByID(id string) (*entity.Product, error) {
 	if product, ok := m.products[id]; ok {
 		return product, nil
 	}
-	return nil, errors.New("product not found")
+	return nil, sql.ErrNoRows
 }
 
-func (m *mockProductRepository) FindAll() ([]entity.Product, error) {
+// List sorts and pages like the MySQL repository; filters are ignored
+func (m *mockProductRepository) List(filter repository.ProductFilter) ([]entity.Product, error) {
 	products := make([]entity.Product, 0, len(m.products))
 	for _, p := range m.products {
 		products = append(products, *p)
 	}
-	return products, nil
+	before := func(a, b entity.PageCursor) bool {
+		if a.CreatedAt.Equal(b.CreatedAt) {
+			return a.ID < b.ID
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	cursor := func(p entity.Product) entity.PageCursor {
+		return entity.PageCursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	}
+	// precedes tells whether a comes first in the requested order
+	precedes := func(a, b entity.PageCursor) bool {
+		if filter.Page.Ascending {
+			return before(a, b)
+		}
+		return before(b, a)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return precedes(cursor(products[i]), cursor(products[j]))
+	})
+
+	page := make([]entity.Product, 0, len(products))
+	for _, p := range products {
+		if after := filter.Page.After; after != nil && !precedes(*after, cursor(p)) {
+			continue
+		}
+		page = append(page, p)
+	}
+	if filter.Page.Limit > 0 && len(page) > filter.Page.Limit {
+		page = page[:filter.Page.Limit]
+	}
+	return page, nil
 }
 
 func (m *mockProductRepository) Update(product *entity.Product) error {
@@ -55,12 +90,28 @@ func (m *mockProductRepository) Delete(id string) error {
 	return nil
 }
 
+func (m *mockProductRepository) DecreaseStock(id string, quantity int) error {
+	product, ok := m.products[id]
+	if !ok {
+		return errors.New("product not found")
+	}
+	return product.UpdateStock(-quantity)
+}
+
+func (m *mockProductRepository) IncreaseStock(id string, quantity int) error {
+	product, ok := m.products[id]
+	if !ok {
+		return errors.New("product not found")
+	}
+	return product.UpdateStock(quantity)
+}
+
 func TestProductUseCase_CreateProduct(t *testing.T) {
 	repo := newMockProductRepository()
 	logger := mocks.NewMockLogger()
 	uc := usecase.NewProductUseCase(repo, logger)
 
-	product, err := uc.CreateProduct("Laptop", "Dell Inspiron", 1500.00, 10)
+	product, err := uc.CreateProduct("Laptop", "Dell Inspiron", "", entity.NewMoney(150000, "BRL"), 10)
 	if err != nil {
 		t.Errorf("CreateProduct() unexpected error = %v", err)
 	}
@@ -68,8 +119,8 @@ func TestProductUseCase_CreateProduct(t *testing.T) {
 	if product.Name != "Laptop" {
 		t.Errorf("CreateProduct() name = %v, want Laptop", product.Name)
 	}
-	if product.Price != 1500.00 {
-		t.Errorf("CreateProduct() price = %v, want 1500.00", product.Price)
+	if product.Price.Amount != 150000 {
+		t.Errorf("CreateProduct() price = %v, want 150000", product.Price)
 	}
 	if product.Stock != 10 {
 		t.Errorf("CreateProduct() stock = %v, want 10", product.Stock)
@@ -91,13 +142,13 @@ func TestProductUseCase_CreateProduct_InvalidData(t *testing.T) {
 	uc := usecase.NewProductUseCase(repo, logger)
 
 	// Empty name
-	_, err := uc.CreateProduct("", "Test", 100.00, 10)
+	_, err := uc.CreateProduct("", "Test", "", entity.NewMoney(10000, "BRL"), 10)
 	if err == nil {
 		t.Error("CreateProduct() expected error for empty name")
 	}
 
 	// Invalid price
-	_, err = uc.CreateProduct("Test", "Test", 0, 10)
+	_, err = uc.CreateProduct("Test", "Test", "", entity.NewMoney(0, "BRL"), 10)
 	if err == nil {
 		t.Error("CreateProduct() expected error for zero price")
 	}
@@ -109,7 +160,7 @@ func TestProductUseCase_GetProduct(t *testing.T) {
 	uc := usecase.NewProductUseCase(repo, logger)
 
 	// Create product
-	created, _ := uc.CreateProduct("Laptop", "Dell Inspiron", 1500.00, 10)
+	created, _ := uc.CreateProduct("Laptop", "Dell Inspiron", "", entity.NewMoney(150000, "BRL"), 10)
 
 	// Get product
 	product, err := uc.GetProduct(created.ID)
@@ -133,16 +184,100 @@ func TestProductUseCase_ListProducts(t *testing.T) {
 	uc := usecase.NewProductUseCase(repo, logger)
 
 	// Create products
-	uc.CreateProduct("Laptop", "Dell", 1500.00, 10)
-	uc.CreateProduct("Mouse", "Logitech", 50.00, 20)
+	uc.CreateProduct("Laptop", "Dell", "", entity.NewMoney(150000, "BRL"), 10)
+	uc.CreateProduct("Mouse", "Logitech", "", entity.NewMoney(5000, "BRL"), 20)
 
-	products, err := uc.ListProducts()
+	products, next, err := uc.ListProducts(repository.ProductFilter{}, usecase.ListOptions{})
 	if err != nil {
 		t.Errorf("ListProducts() unexpected error = %v", err)
 	}
 	if len(products) != 2 {
 		t.Errorf("ListProducts() length = %v, want 2", len(products))
 	}
+	if next != "" {
+		t.Errorf("ListProducts() next page token = %q, want none on the last page", next)
+	}
+}
+
+func TestProductUseCase_ListProducts_Pages(t *testing.T) {
+	repo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewProductUseCase(repo, logger)
+
+	// Five products created a minute apart, oldest first
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 5; i++ {
+		product, _ := uc.CreateProduct("Product", "Test", "", entity.NewMoney(1000, "BRL"), 1)
+		product.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		ids = append(ids, product.ID)
+	}
+
+	for _, tt := range []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{ids[4], ids[3], ids[2], ids[1], ids[0]}},
+		{"created_at", ids},
+	} {
+		var got []string
+		options := usecase.ListOptions{PageSize: 2, Sort: tt.sort}
+		for pages := 1; ; pages++ {
+			products, next, err := uc.ListProducts(repository.ProductFilter{}, options)
+			if err != nil {
+				t.Fatalf("ListProducts(sort %q) unexpected error = %v", tt.sort, err)
+			}
+			for _, p := range products {
+				got = append(got, p.ID)
+			}
+			if next == "" {
+				if pages != 3 {
+					t.Errorf("ListProducts(sort %q) pages = %d, want 3", tt.sort, pages)
+				}
+				break
+			}
+			options.PageToken = next
+		}
+
+		if len(got) != len(tt.want) {
+			t.Fatalf("ListProducts(sort %q) got %d products, want %d", tt.sort, len(got), len(tt.want))
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ListProducts(sort %q) product %d = %s, want %s", tt.sort, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestProductUseCase_ListProducts_InvalidOptions(t *testing.T) {
+	repo := newMockProductRepository()
+	logger := mocks.NewMockLogger()
+	uc := usecase.NewProductUseCase(repo, logger)
+
+	uc.CreateProduct("Laptop", "Dell", "", entity.NewMoney(150000, "BRL"), 10)
+	uc.CreateProduct("Mouse", "Logitech", "", entity.NewMoney(5000, "BRL"), 20)
+	_, descToken, _ := uc.ListProducts(repository.ProductFilter{}, usecase.ListOptions{PageSize: 1})
+
+	tests := []struct {
+		name    string
+		options usecase.ListOptions
+		want    error
+	}{
+		{"page size too large", usecase.ListOptions{PageSize: usecase.MaxPageSize + 1}, usecase.ErrInvalidPageSize},
+		{"negative page size", usecase.ListOptions{PageSize: -1}, usecase.ErrInvalidPageSize},
+		{"unknown sort", usecase.ListOptions{Sort: "price"}, usecase.ErrInvalidSort},
+		{"garbage token", usecase.ListOptions{PageToken: "not-a-token"}, entity.ErrInvalidPageToken},
+		{"token of another sort", usecase.ListOptions{PageToken: descToken, Sort: "created_at"}, entity.ErrInvalidPageToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := uc.ListProducts(repository.ProductFilter{}, tt.options)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ListProducts() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
 }
 
 func TestProductUseCase_UpdateProduct(t *testing.T) {
@@ -151,22 +286,22 @@ func TestProductUseCase_UpdateProduct(t *testing.T) {
 	uc := usecase.NewProductUseCase(repo, logger)
 
 	// Create product
-	created, _ := uc.CreateProduct("Laptop", "Dell", 1500.00, 10)
+	created, _ := uc.CreateProduct("Laptop", "Dell", "", entity.NewMoney(150000, "BRL"), 10)
 
 	// Update product
-	updated, err := uc.UpdateProduct(created.ID, "Laptop Pro", "Dell Inspiron", 2000.00, 15)
+	updated, err := uc.UpdateProduct(created.ID, "Laptop Pro", "Dell Inspiron", "", entity.NewMoney(200000, "BRL"), 15, nil)
 	if err != nil {
 		t.Errorf("UpdateProduct() unexpected error = %v", err)
 	}
 	if updated.Name != "Laptop Pro" {
 		t.Errorf("UpdateProduct() name = %v, want Laptop Pro", updated.Name)
 	}
-	if updated.Price != 2000.00 {
-		t.Errorf("UpdateProduct() price = %v, want 2000.00", updated.Price)
+	if updated.Price.Amount != 200000 {
+		t.Errorf("UpdateProduct() price = %v, want 200000", updated.Price)
 	}
 
 	// Update non-existent product
-	_, err = uc.UpdateProduct("non-existent", "Test", "Test", 100.00, 10)
+	_, err = uc.UpdateProduct("non-existent", "Test", "Test", "", entity.NewMoney(10000, "BRL"), 10, nil)
 	if err == nil {
 		t.Error("UpdateProduct() expected error for non-existent product")
 	}
@@ -178,7 +313,7 @@ func TestProductUseCase_DeleteProduct(t *testing.T) {
 	uc := usecase.NewProductUseCase(repo, logger)
 
 	// Create product
-	created, _ := uc.CreateProduct("Laptop", "Dell", 1500.00, 10)
+	created, _ := uc.CreateProduct("Laptop", "Dell", "", entity.NewMoney(150000, "BRL"), 10)
 
 	// Delete product
 	err := uc.DeleteProduct(created.ID)
@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"orders/internal/domain/entity"
+	"orders/internal/usecase"
+	"orders/tests/mocks"
+	"testing"
+	"time"
+)
+
+// Mock Promotion Repository
+type mockPromotionRepository struct {
+	promotions map[string]*entity.Promotion
+	// redemptions are the customers who redeemed each promotion; an empty
+	// customer is a guest order
+	redemptions map[string][]string
+}
+
+func newMockPromotionRepository() *mockPromotionRepository {
+	return &mockPromotionRepository{
+		promotions:  make(map[string]*entity.Promotion),
+		redemptions: make(map[string][]string),
+	}
+}
+
+func (m *mockPromotionRepository) Create(promotion *entity.Promotion) error {
+	for _, p := range m.promotions {
+		if promotion.Code != "" && p.Code == promotion.Code {
+			return entity.ErrDuplicateCouponCode
+		}
+	}
+	m.promotions[promotion.ID] = promotion
+	return nil
+}
+
+func (m *mockPromotionRepository) FindByID(id string) (*entity.Promotion, error) {
+	if promotion, ok := m.promotions[id]; ok {
+		return promotion, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockPromotionRepository) FindByCode(code string) (*entity.Promotion, error) {
+	for _, promotion := range m.promotions {
+		if promotion.Code == code {
+			return promotion, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockPromotionRepository) FindAll() ([]entity.Promotion, error) {
+	promotions := make([]entity.Promotion, 0, len(m.promotions))
+	for _, p := range m.promotions {
+		promotions = append(promotions, *p)
+	}
+	return promotions, nil
+}
+
+func (m *mockPromotionRepository) FindAutomatic(now time.Time) ([]entity.Promotion, error) {
+	var promotions []entity.Promotion
+	for _, p := range m.promotions {
+		if !p.IsCoupon() && p.IsValidAt(now) {
+			promotions = append(promotions, *p)
+		}
+	}
+	return promotions, nil
+}
+
+func (m *mockPromotionRepository) Update(promotion *entity.Promotion) error {
+	m.promotions[promotion.ID] = promotion
+	return nil
+}
+
+func (m *mockPromotionRepository) CountRedemptions(promotionID, customerID string) (int, int, error) {
+	byCustomer := 0
+	for _, c := range m.redemptions[promotionID] {
+		if customerID != "" && c == customerID {
+			byCustomer++
+		}
+	}
+	return len(m.redemptions[promotionID]), byCustomer, nil
+}
+
+func newPromotionUseCase(promotionRepo *mockPromotionRepository) *usecase.PromotionUseCase {
+	return usecase.NewPromotionUseCase(promotionRepo, 0, mocks.NewMockLogger())
+}
+
+// newPromotionCart creates a cart of customerID holding two laptops
+func newPromotionCart(t *testing.T, promotionRepo *mockPromotionRepository, customerID string) (*usecase.CartUseCase, *entity.Order, *mockOrderRepository, *mockProductRepository) {
+	t.Helper()
+	orderRepo := newMockOrderRepository()
+	productRepo := newMockProductRepository()
+	customerRepo := newMockCustomerRepository()
+	uc := usecase.NewCartUseCase(orderRepo, productRepo, customerRepo, newStockReservationUseCase(productRepo), newPromotionUseCase(promotionRepo), time.Hour, time.Hour, mocks.NewMockLogger())
+
+	if customerID != "" {
+		customerRepo.customers[customerID] = &entity.Customer{ID: customerID}
+	}
+	laptop, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 10)
+	laptop.Category = "notebooks"
+	productRepo.Create(laptop)
+
+	order, err := uc.CreateOrder(customerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err = uc.AddItemToCart(order.ID, laptop.ID, 2, usecase.AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uc, order, orderRepo, productRepo
+}
+
+func createPromotion(t *testing.T, promotionRepo *mockPromotionRepository, promotion entity.Promotion) *entity.Promotion {
+	t.Helper()
+	created, err := newPromotionUseCase(promotionRepo).CreatePromotion(promotion)
+	if err != nil {
+		t.Fatalf("CreatePromotion() unexpected error = %v", err)
+	}
+	return created
+}
+
+func TestPromotionUseCase_CreatePromotion_RejectsDuplicateCode(t *testing.T) {
+	promotionRepo := newMockPromotionRepository()
+	uc := newPromotionUseCase(promotionRepo)
+	createPromotion(t, promotionRepo, entity.Promotion{Name: "Welcome", Code: "WELCOME10", Type: entity.PromotionPercentage, Percentage: 10})
+
+	_, err := uc.CreatePromotion(entity.Promotion{Name: "Welcome again", Code: "welcome10", Type: entity.PromotionPercentage, Percentage: 5})
+
+	if !errors.Is(err, entity.ErrDuplicateCouponCode) {
+		t.Errorf("CreatePromotion() error = %v, want %v", err, entity.ErrDuplicateCouponCode)
+	}
+}
+
+func TestCartUseCase_ApplyCoupon(t *testing.T) {
+	promotionRepo := newMockPromotionRepository()
+	createPromotion(t, promotionRepo, entity.Promotion{Name: "Welcome", Code: "WELCOME10", Type: entity.PromotionPercentage, Percentage: 10})
+	uc, order, _, _ := newPromotionCart(t, promotionRepo, "")
+
+	order, err := uc.ApplyCoupon(order.ID, " welcome10 ", usecase.AnyVersion)
+	if err != nil {
+		t.Fatalf("ApplyCoupon() unexpected error = %v", err)
+	}
+
+	if len(order.Coupons) != 1 || order.Coupons[0] != "WELCOME10" {
+		t.Errorf("coupons = %v, want [WELCOME10]", order.Coupons)
+	}
+	if order.Subtotal.Amount != 300000 || order.OrderDiscount.Amount != 30000 || order.Total.Amount != 270000 {
+		t.Errorf("subtotal, order discount, total = %d, %d, %d, want 300000, 30000, 270000",
+			order.Subtotal.Amount, order.OrderDiscount.Amount, order.Total.Amount)
+	}
+}
+
+func TestCartUseCase_ApplyCoupon_Rejected(t *testing.T) {
+	ended := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		promotion *entity.Promotion
+		code      string
+		want      error
+	}{
+		{"unknown", nil, "NOPE", usecase.ErrCouponNotFound},
+		{"expired", &entity.Promotion{Name: "Old", Code: "OLD", Type: entity.PromotionPercentage, Percentage: 10, EndsAt: &ended}, "OLD", entity.ErrCouponNotValid},
+		{"other category", &entity.Promotion{Name: "Phones", Code: "PHONES", Type: entity.PromotionPercentage, Percentage: 10, Categories: []string{"phones"}}, "PHONES", entity.ErrCouponNotApplicable},
+		{"global limit", &entity.Promotion{Name: "First", Code: "FIRST", Type: entity.PromotionPercentage, Percentage: 10, UsageLimit: 1}, "FIRST", entity.ErrCouponUsageLimitReached},
+		{"per customer on guest cart", &entity.Promotion{Name: "Once", Code: "ONCE", Type: entity.PromotionPercentage, Percentage: 10, UsageLimitPerCustomer: 1}, "ONCE", entity.ErrCouponNotApplicable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotionRepo := newMockPromotionRepository()
+			if tt.promotion != nil {
+				promotion := *tt.promotion
+				promotion.ID = tt.code
+				promotion.Active = true
+				promotionRepo.promotions[promotion.ID] = &promotion
+				promotionRepo.redemptions[promotion.ID] = []string{"someone"}
+			}
+			uc, order, _, _ := newPromotionCart(t, promotionRepo, "")
+
+			_, err := uc.ApplyCoupon(order.ID, tt.code, usecase.AnyVersion)
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ApplyCoupon() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCartUseCase_ApplyCoupon_PerCustomerLimit(t *testing.T) {
+	promotionRepo := newMockPromotionRepository()
+	promotion := createPromotion(t, promotionRepo, entity.Promotion{Name: "Once", Code: "ONCE", Type: entity.PromotionPercentage, Percentage: 10, UsageLimitPerCustomer: 1})
+	promotionRepo.redemptions[promotion.ID] = []string{"other-customer"}
+	uc, order, _, _ := newPromotionCart(t, promotionRepo, "customer-1")
+
+	if _, err := uc.ApplyCoupon(order.ID, "ONCE", usecase.AnyVersion); err != nil {
+		t.Fatalf("ApplyCoupon() unexpected error = %v, other customers' redemptions do not count", err)
+	}
+	if _, err := uc.RemoveCoupon(order.ID, "ONCE", usecase.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	promotionRepo.redemptions[promotion.ID] = append(promotionRepo.redemptions[promotion.ID], "customer-1")
+	_, err := uc.ApplyCoupon(order.ID, "ONCE", usecase.AnyVersion)
+
+	if !errors.Is(err, entity.ErrCouponUsageLimitReached) {
+		t.Errorf("ApplyCoupon() error = %v, want %v", err, entity.ErrCouponUsageLimitReached)
+	}
+}
+
+func TestCartUseCase_RemoveCoupon(t *testing.T) {
+	promotionRepo := newMockPromotionRepository()
+	createPromotion(t, promotionRepo, entity.Promotion{Name: "Ten off", Code: "TENOFF", Type: entity.PromotionFixedAmount, Amount: &entity.Money{Amount: 1000, Currency: "BRL"}})
+	uc, order, _, _ := newPromotionCart(t, promotionRepo, "")
+	if _, err := uc.ApplyCoupon(order.ID, "TENOFF", usecase.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := uc.RemoveCoupon(order.ID, "tenoff", usecase.AnyVersion)
+	if err != nil {
+		t.Fatalf("RemoveCoupon() unexpected error = %v", err)
+	}
+	if len(order.Coupons) != 0 || len(order.Discounts) != 0 || order.Total.Amount != 300000 {
+		t.Errorf("coupons = %v, discounts = %v, total = %d, want no discount left", order.Coupons, order.Discounts, order.Total.Amount)
+	}
+
+	if _, err := uc.RemoveCoupon(order.ID, "TENOFF", usecase.AnyVersion); !errors.Is(err, entity.ErrCouponNotApplied) {
+		t.Errorf("RemoveCoupon() again error = %v, want %v", err, entity.ErrCouponNotApplied)
+	}
+}
+
+func TestCartUseCase_AutomaticPromotionsFollowItems(t *testing.T) {
+	promotionRepo := newMockPromotionRepository()
+	createPromotion(t, promotionRepo, entity.Promotion{Name: "Notebooks 2 for 1", Type: entity.PromotionBuyXGetY, BuyQuantity: 1, GetQuantity: 1, Categories: []string{"Notebooks"}})
+	uc, order, _, _ := newPromotionCart(t, promotionRepo, "")
+
+	if order.LineDiscount.Amount != 150000 || order.Items[0].Discount.Amount != 150000 || order.Total.Amount != 150000 {
+		t.Errorf("line discount = %d, item discount = %d, total = %d, want one laptop free",
+			order.LineDiscount.Amount, order.Items[0].Discount.Amount, order.Total.Amount)
+	}
+
+	order, err := uc.UpdateItemQuantity(order.ID, order.Items[0].ID, 1, usecase.AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Discounts) != 0 || order.Total.Amount != 150000 {
+		t.Errorf("discounts = %v, total = %d, want no discount for a single laptop", order.Discounts, order.Total.Amount)
+	}
+}
+
+func TestCreateOrderUseCase_CheckoutCart_RejectsCouponPastItsLimit(t *testing.T) {
+	promotionRepo := newMockPromotionRepository()
+	promotion := createPromotion(t, promotionRepo, entity.Promotion{Name: "First", Code: "FIRST", Type: entity.PromotionPercentage, Percentage: 10, UsageLimit: 1})
+	cart, order, orderRepo, productRepo := newPromotionCart(t, promotionRepo, "")
+	if _, err := cart.ApplyCoupon(order.ID, "FIRST", usecase.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	uc := usecase.NewCreateOrderUseCase(orderRepo, productRepo, newMockCustomerRepository(), newPromotionUseCase(promotionRepo), nil, nil, false, mocks.NewMockLogger())
+
+	// Another order redeemed the coupon since it was applied
+	promotionRepo.redemptions[promotion.ID] = []string{""}
+	_, err := uc.CheckoutCart(context.Background(), checkoutInput(order.ID))
+
+	if !errors.Is(err, entity.ErrCouponUsageLimitReached) {
+		t.Errorf("CheckoutCart() error = %v, want %v", err, entity.ErrCouponUsageLimitReached)
+	}
+}
@@ -0,0 +1,323 @@
+package usecase
+
+import (
+	"errors"
+	"orders/internal/domain/entity"
+	"orders/internal/usecase"
+	"orders/tests/mocks"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Mock Stock Reservation Repository
+type mockStockReservationRepository struct {
+	mu           sync.Mutex
+	reservations map[string]*entity.StockReservation
+	productRepo  *mockProductRepository
+	// beforeSave, when set, runs once before Update or GiveBack saves,
+	// standing for a request that changes the reservation at the same time
+	beforeSave func()
+}
+
+func newMockStockReservationRepository(productRepo *mockProductRepository) *mockStockReservationRepository {
+	return &mockStockReservationRepository{
+		reservations: make(map[string]*entity.StockReservation),
+		productRepo:  productRepo,
+	}
+}
+
+func (m *mockStockReservationRepository) Create(reservation *entity.StockReservation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := *reservation
+	m.reservations[reservation.ID] = &saved
+	return nil
+}
+
+func (m *mockStockReservationRepository) FindByOrderID(orderID string) ([]entity.StockReservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var reservations []entity.StockReservation
+	for _, r := range m.reservations {
+		if r.OrderID == orderID {
+			reservations = append(reservations, *r)
+		}
+	}
+	return reservations, nil
+}
+
+func (m *mockStockReservationRepository) FindActive(orderID, productID string) (*entity.StockReservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.reservations {
+		if r.OrderID == orderID && r.ProductID == productID && r.IsActive() {
+			found := *r
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockStockReservationRepository) FindExpired(now time.Time) ([]entity.StockReservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var reservations []entity.StockReservation
+	for _, r := range m.reservations {
+		if r.IsExpired(now) {
+			reservations = append(reservations, *r)
+		}
+	}
+	return reservations, nil
+}
+
+func (m *mockStockReservationRepository) Update(reservation *entity.StockReservation, previous entity.StockReservation) error {
+	m.runBeforeSave()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.update(reservation, previous)
+}
+
+func (m *mockStockReservationRepository) GiveBack(reservation *entity.StockReservation, previous entity.StockReservation, quantity int) error {
+	m.runBeforeSave()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.update(reservation, previous); err != nil {
+		return err
+	}
+	return m.productRepo.IncreaseStock(reservation.ProductID, quantity)
+}
+
+func (m *mockStockReservationRepository) runBeforeSave() {
+	if hook := m.beforeSave; hook != nil {
+		m.beforeSave = nil
+		hook()
+	}
+}
+
+func (m *mockStockReservationRepository) update(reservation *entity.StockReservation, previous entity.StockReservation) error {
+	saved, ok := m.reservations[reservation.ID]
+	if !ok {
+		return errors.New("reservation not found")
+	}
+	sameExpiry := saved.ExpiresAt == nil && previous.ExpiresAt == nil ||
+		saved.ExpiresAt != nil && previous.ExpiresAt != nil && saved.ExpiresAt.Equal(*previous.ExpiresAt)
+	if saved.Status != previous.Status || saved.Quantity != previous.Quantity || !sameExpiry {
+		return entity.ErrReservationConflict
+	}
+	updated := *reservation
+	m.reservations[reservation.ID] = &updated
+	return nil
+}
+
+func newStockReservationUseCase(productRepo *mockProductRepository) *usecase.StockReservationUseCase {
+	return usecase.NewStockReservationUseCase(newMockStockReservationRepository(productRepo), productRepo, time.Hour, mocks.NewMockLogger())
+}
+
+func TestStockReservationUseCase_ReserveItems(t *testing.T) {
+	productRepo := newMockProductRepository()
+	uc := newStockReservationUseCase(productRepo)
+
+	laptop, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 5)
+	mouse, _ := entity.NewProduct("Mouse", "Logitech", entity.NewMoney(5000, "BRL"), 1)
+	productRepo.Create(laptop)
+	productRepo.Create(mouse)
+
+	items := []entity.Item{
+		{ProductID: laptop.ID, Quantity: 2},
+		{ProductID: mouse.ID, Quantity: 1},
+	}
+	if err := uc.ReserveItems("order-1", items); err != nil {
+		t.Fatalf("ReserveItems() unexpected error = %v", err)
+	}
+	if laptop.Stock != 3 || mouse.Stock != 0 {
+		t.Errorf("ReserveItems() stock = %v/%v, want 3/0", laptop.Stock, mouse.Stock)
+	}
+
+	// Second order cannot get the mouse, and must not keep the laptops either
+	err := uc.ReserveItems("order-2", items)
+	if !errors.Is(err, entity.ErrInsufficientStock) {
+		t.Errorf("ReserveItems() error = %v, want %v", err, entity.ErrInsufficientStock)
+	}
+	if laptop.Stock != 3 {
+		t.Errorf("ReserveItems() laptop stock after rollback = %v, want 3", laptop.Stock)
+	}
+}
+
+func TestStockReservationUseCase_ReserveItemsIsIdempotent(t *testing.T) {
+	productRepo := newMockProductRepository()
+	uc := newStockReservationUseCase(productRepo)
+
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 5)
+	productRepo.Create(product)
+
+	// A resumed checkout reserves the same items again
+	items := []entity.Item{{ProductID: product.ID, Quantity: 2}}
+	uc.ReserveItems("order-1", items)
+	if err := uc.ReserveItems("order-1", items); err != nil {
+		t.Fatalf("ReserveItems() unexpected error = %v", err)
+	}
+	if product.Stock != 3 {
+		t.Errorf("ReserveItems() twice stock = %v, want 3", product.Stock)
+	}
+
+	// Only the missing quantity is taken when the order holds part of it
+	items[0].Quantity = 3
+	if err := uc.ReserveItems("order-1", items); err != nil {
+		t.Fatalf("ReserveItems() unexpected error = %v", err)
+	}
+	if product.Stock != 2 {
+		t.Errorf("ReserveItems() stock = %v, want 2", product.Stock)
+	}
+}
+
+func TestStockReservationUseCase_CommitAndRelease(t *testing.T) {
+	productRepo := newMockProductRepository()
+	uc := newStockReservationUseCase(productRepo)
+
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 5)
+	productRepo.Create(product)
+
+	items := []entity.Item{{ProductID: product.ID, Quantity: 2}}
+
+	// Approved payment keeps the stock sold
+	uc.ReserveItems("paid-order", items)
+	if err := uc.Commit("paid-order"); err != nil {
+		t.Fatalf("Commit() unexpected error = %v", err)
+	}
+	if product.Stock != 3 {
+		t.Errorf("Commit() stock = %v, want 3", product.Stock)
+	}
+
+	// Declined payment gives it back
+	uc.ReserveItems("declined-order", items)
+	if err := uc.Release("declined-order"); err != nil {
+		t.Fatalf("Release() unexpected error = %v", err)
+	}
+	if product.Stock != 3 {
+		t.Errorf("Release() stock = %v, want 3", product.Stock)
+	}
+
+	// Canceling a paid order returns committed stock, only once
+	uc.Release("paid-order")
+	uc.Release("paid-order")
+	if product.Stock != 5 {
+		t.Errorf("Release() committed stock = %v, want 5", product.Stock)
+	}
+}
+
+func TestStockReservationUseCase_ReleaseExpired(t *testing.T) {
+	productRepo := newMockProductRepository()
+	reservationRepo := newMockStockReservationRepository(productRepo)
+	uc := usecase.NewStockReservationUseCase(reservationRepo, productRepo, time.Nanosecond, mocks.NewMockLogger())
+
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 5)
+	productRepo.Create(product)
+
+	if err := uc.ReserveForCart("cart-1", product.ID, 4); err != nil {
+		t.Fatalf("ReserveForCart() unexpected error = %v", err)
+	}
+	if err := uc.ReserveItems("order-1", []entity.Item{{ProductID: product.ID, Quantity: 1}}); err != nil {
+		t.Fatalf("ReserveItems() unexpected error = %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	released, err := uc.ReleaseExpired()
+	if err != nil {
+		t.Fatalf("ReleaseExpired() unexpected error = %v", err)
+	}
+	if released != 1 {
+		t.Errorf("ReleaseExpired() released = %v, want 1", released)
+	}
+	if product.Stock != 4 {
+		t.Errorf("ReleaseExpired() stock = %v, want 4", product.Stock)
+	}
+}
+
+func TestStockReservationUseCase_ReserveItemsKeepsCartReservation(t *testing.T) {
+	productRepo := newMockProductRepository()
+	reservationRepo := newMockStockReservationRepository(productRepo)
+	uc := usecase.NewStockReservationUseCase(reservationRepo, productRepo, time.Millisecond, mocks.NewMockLogger())
+
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 5)
+	productRepo.Create(product)
+
+	// The cart is checked out before its reservation expires
+	if err := uc.ReserveForCart("cart-1", product.ID, 2); err != nil {
+		t.Fatalf("ReserveForCart() unexpected error = %v", err)
+	}
+	if err := uc.ReserveItems("cart-1", []entity.Item{{ProductID: product.ID, Quantity: 2}}); err != nil {
+		t.Fatalf("ReserveItems() unexpected error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	released, err := uc.ReleaseExpired()
+	if err != nil {
+		t.Fatalf("ReleaseExpired() unexpected error = %v", err)
+	}
+	if released != 0 || product.Stock != 3 {
+		t.Errorf("ReleaseExpired() released = %v, stock = %v, want 0 and 3", released, product.Stock)
+	}
+}
+
+func TestStockReservationUseCase_ReleaseExpiredRacingCancel(t *testing.T) {
+	productRepo := newMockProductRepository()
+	reservationRepo := newMockStockReservationRepository(productRepo)
+	uc := usecase.NewStockReservationUseCase(reservationRepo, productRepo, time.Nanosecond, mocks.NewMockLogger())
+
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 5)
+	productRepo.Create(product)
+
+	if err := uc.ReserveForCart("cart-1", product.ID, 4); err != nil {
+		t.Fatalf("ReserveForCart() unexpected error = %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	// The cart is canceled while the expiry is releasing it
+	reservationRepo.beforeSave = func() {
+		if err := uc.Release("cart-1"); err != nil {
+			t.Errorf("Release() unexpected error = %v", err)
+		}
+	}
+
+	released, err := uc.ReleaseExpired()
+	if err != nil {
+		t.Fatalf("ReleaseExpired() unexpected error = %v", err)
+	}
+	if released != 0 || product.Stock != 5 {
+		t.Errorf("ReleaseExpired() released = %v, stock = %v, want 0 and the stock given back once", released, product.Stock)
+	}
+}
+
+func TestStockReservationUseCase_CommitRacingRelease(t *testing.T) {
+	productRepo := newMockProductRepository()
+	reservationRepo := newMockStockReservationRepository(productRepo)
+	uc := usecase.NewStockReservationUseCase(reservationRepo, productRepo, time.Hour, mocks.NewMockLogger())
+
+	product, _ := entity.NewProduct("Laptop", "Dell", entity.NewMoney(150000, "BRL"), 5)
+	productRepo.Create(product)
+
+	if err := uc.ReserveItems("order-1", []entity.Item{{ProductID: product.ID, Quantity: 2}}); err != nil {
+		t.Fatalf("ReserveItems() unexpected error = %v", err)
+	}
+
+	// The order is canceled after the commit loaded the reservation
+	reservationRepo.beforeSave = func() {
+		if err := uc.Release("order-1"); err != nil {
+			t.Errorf("Release() unexpected error = %v", err)
+		}
+	}
+
+	if err := uc.Commit("order-1"); err != nil {
+		t.Fatalf("Commit() unexpected error = %v", err)
+	}
+	reservations, _ := reservationRepo.FindByOrderID("order-1")
+	if len(reservations) != 1 || reservations[0].Status != entity.ReservationStatusReleased {
+		t.Errorf("Commit() reservations = %+v, want the released one not committed back", reservations)
+	}
+	if product.Stock != 5 {
+		t.Errorf("Commit() stock = %v, want 5 with the released stock counted once", product.Stock)
+	}
+}
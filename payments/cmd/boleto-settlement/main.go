@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"payments/internal/infra/boleto"
+	"payments/internal/infra/database"
+	"payments/internal/infra/repository"
+	"payments/internal/usecase"
+
+	"github.com/joho/godotenv"
+)
+
+// boleto-settlement imports a bank settlement file, confirming the boletos
+// paid in it. Importing the same file again is safe.
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+	slog.SetDefault(logger)
+
+	file := flag.String("file", "", "settlement file (CSV: number,amount_cents,paid_at)")
+	flag.Parse()
+	if *file == "" {
+		slog.Error("Missing -file")
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("No .env file found, using environment variables")
+	}
+
+	// Dates without a time are read in the timezone of the bank
+	location, err := time.LoadLocation(getEnv("BOLETO_SETTLEMENT_TIMEZONE", "America/Sao_Paulo"))
+	if err != nil {
+		slog.Error("Invalid BOLETO_SETTLEMENT_TIMEZONE", "error", err)
+		os.Exit(1)
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		slog.Error("Failed to open settlement file", "error", err)
+		os.Exit(1)
+	}
+	defer input.Close()
+
+	settlements, err := boleto.ParseSettlementFile(input, location)
+	if err != nil {
+		slog.Error("Invalid settlement file", "file", *file, "error", err)
+		os.Exit(1)
+	}
+
+	db, err := database.NewMySQL(
+		getEnv("DB_HOST", "localhost"),
+		getEnv("DB_PORT", "3307"),
+		getEnv("DB_USER", "root"),
+		getEnv("DB_PASSWORD", "root"),
+		getEnv("DB_NAME", "payments_db"),
+	)
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	settleBoletosUC := usecase.NewSettleBoletosUseCase(repository.NewPaymentRepositoryMySQL(db.GetDB()))
+	report, err := settleBoletosUC.Execute(ctx, settlements)
+	if err != nil {
+		slog.Error("Settlement interrupted", "error", err)
+		os.Exit(1)
+	}
+
+	for _, failure := range report.Failed {
+		slog.Warn("Boleto not settled", "boleto_number", failure.Number, "reason", failure.Reason)
+	}
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"payments/internal/infra/gateway"
+
+	"github.com/joho/godotenv"
+)
+
+// gateway-stub is a local payment provider for PAYMENT_GATEWAY=http. It
+// decides charges with the same rules as the fake gateway.
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+	slog.SetDefault(logger)
+
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("No .env file found, using environment variables")
+	}
+
+	port := getEnv("GATEWAY_STUB_PORT", "8090")
+	rulesFile := getEnv("PAYMENT_GATEWAY_RULES", "")
+	timeout, err := time.ParseDuration(getEnv("PAYMENT_GATEWAY_TIMEOUT", "5s"))
+	if err != nil {
+		slog.Error("Invalid PAYMENT_GATEWAY_TIMEOUT", "error", err)
+		os.Exit(1)
+	}
+
+	rules := gateway.DefaultFakeRules()
+	if rulesFile != "" {
+		if rules, err = gateway.LoadFakeRules(rulesFile); err != nil {
+			slog.Error("Failed to load gateway rules", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Timeout rules hang a little longer than the client waits
+	handler := gateway.NewStubHandler(gateway.NewFakeGateway(rules, timeout+time.Second))
+
+	slog.Info("Gateway stub listening", "port", port, "rules", len(rules))
+	if err := http.ListenAndServe(fmt.Sprintf(":%s", port), handler); err != nil {
+		slog.Error("Failed to serve gateway stub", "error", err)
+		os.Exit(1)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
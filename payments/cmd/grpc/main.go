@@ -1,21 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"payments/internal/domain/boleto"
+	"payments/internal/domain/pix"
+	boletoSlip "payments/internal/infra/boleto"
+	"payments/internal/infra/broker"
 	"payments/internal/infra/database"
+	"payments/internal/infra/gateway"
 	grpcHandler "payments/internal/infra/grpc/handler"
+	"payments/internal/infra/grpc/security"
 	"payments/internal/infra/repository"
+	"payments/internal/infra/webhook"
 	"payments/internal/usecase"
 	pb "payments/proto"
 
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -40,6 +53,83 @@ func main() {
 	dbPassword := getEnv("DB_PASSWORD", "root")
 	dbName := getEnv("DB_NAME", "payments_db")
 	grpcPort := getEnv("GRPC_PORT", "50051")
+	eventBrokerKind := getEnv("EVENT_BROKER", "memory")
+	eventBrokerFile := getEnv("EVENT_BROKER_FILE", "events.jsonl")
+	outboxRelayInterval, err := time.ParseDuration(getEnv("OUTBOX_RELAY_INTERVAL", "2s"))
+	if err != nil {
+		slog.Error("Invalid OUTBOX_RELAY_INTERVAL", "error", err)
+		os.Exit(1)
+	}
+	paymentGatewayKind := getEnv("PAYMENT_GATEWAY", "fake")
+	paymentGatewayRules := getEnv("PAYMENT_GATEWAY_RULES", "")
+	paymentGatewayURL := getEnv("PAYMENT_GATEWAY_URL", "http://localhost:8090")
+	paymentGatewayTimeout, err := time.ParseDuration(getEnv("PAYMENT_GATEWAY_TIMEOUT", "5s"))
+	if err != nil {
+		slog.Error("Invalid PAYMENT_GATEWAY_TIMEOUT", "error", err)
+		os.Exit(1)
+	}
+	authorizationTTL, err := time.ParseDuration(getEnv("AUTHORIZATION_TTL", "168h"))
+	if err != nil {
+		slog.Error("Invalid AUTHORIZATION_TTL", "error", err)
+		os.Exit(1)
+	}
+	paymentExpiryInterval, err := time.ParseDuration(getEnv("PAYMENT_EXPIRY_INTERVAL", "1m"))
+	if err != nil {
+		slog.Error("Invalid PAYMENT_EXPIRY_INTERVAL", "error", err)
+		os.Exit(1)
+	}
+	pixChargeTTL, err := time.ParseDuration(getEnv("PIX_CHARGE_TTL", "30m"))
+	if err != nil {
+		slog.Error("Invalid PIX_CHARGE_TTL", "error", err)
+		os.Exit(1)
+	}
+	pixMerchant := pix.Merchant{
+		Key:  getEnv("PIX_KEY", "pagamentos@example.com"),
+		Name: getEnv("PIX_MERCHANT_NAME", "Go E-commerce"),
+		City: getEnv("PIX_MERCHANT_CITY", "Sao Paulo"),
+	}
+	if err := pixMerchant.Validate(); err != nil {
+		slog.Error("Invalid PIX merchant", "error", err)
+		os.Exit(1)
+	}
+	boletoGracePeriod, err := time.ParseDuration(getEnv("BOLETO_GRACE_PERIOD", "72h"))
+	if err != nil {
+		slog.Error("Invalid BOLETO_GRACE_PERIOD", "error", err)
+		os.Exit(1)
+	}
+	boletoIssuer := boleto.Issuer{
+		BankCode:            getEnv("BOLETO_BANK_CODE", "001"),
+		Agreement:           getEnv("BOLETO_AGREEMENT", "1234567"),
+		Wallet:              getEnv("BOLETO_WALLET", "17"),
+		BeneficiaryName:     getEnv("BOLETO_BENEFICIARY_NAME", "Go E-commerce"),
+		BeneficiaryDocument: getEnv("BOLETO_BENEFICIARY_DOCUMENT", ""),
+	}
+	if err := boletoIssuer.Validate(); err != nil {
+		slog.Error("Invalid boleto issuer", "error", err)
+		os.Exit(1)
+	}
+	watchPaymentsInterval, err := time.ParseDuration(getEnv("WATCH_PAYMENTS_INTERVAL", "1s"))
+	if err != nil {
+		slog.Error("Invalid WATCH_PAYMENTS_INTERVAL", "error", err)
+		os.Exit(1)
+	}
+	webhookPort := getEnv("WEBHOOK_PORT", "8081")
+	webhookSecret := getEnv("WEBHOOK_SECRET", "")
+	webhookTolerance, err := time.ParseDuration(getEnv("WEBHOOK_TOLERANCE", "5m"))
+	if err != nil {
+		slog.Error("Invalid WEBHOOK_TOLERANCE", "error", err)
+		os.Exit(1)
+	}
+	tlsCertFile := getEnv("GRPC_TLS_CERT_FILE", "")
+	tlsKeyFile := getEnv("GRPC_TLS_KEY_FILE", "")
+	tlsClientCAFile := getEnv("GRPC_TLS_CLIENT_CA_FILE", "")
+	serviceTokens, err := parseServiceTokens(getEnv("GRPC_SERVICE_TOKENS", ""))
+	if err != nil {
+		slog.Error("Invalid GRPC_SERVICE_TOKENS", "error", err)
+		os.Exit(1)
+	}
+	allowedClients := splitList(getEnv("GRPC_ALLOWED_CLIENTS", ""))
+	authDisabled := getEnv("GRPC_AUTH_DISABLED", "false") == "true"
 
 	// Initialize database connection
 	db, err := database.NewMySQL(dbHost, dbPort, dbUser, dbPassword, dbName)
@@ -53,15 +143,99 @@ func main() {
 
 	// Initialize repositories
 	paymentRepo := repository.NewPaymentRepositoryMySQL(db.GetDB())
+	refundRepo := repository.NewRefundRepositoryMySQL(db.GetDB())
+	outboxRepo := repository.NewOutboxRepositoryMySQL(db.GetDB())
+	webhookEventRepo := repository.NewWebhookEventRepositoryMySQL(db.GetDB())
+	statusChangeRepo := repository.NewPaymentStatusChangeRepositoryMySQL(db.GetDB())
+
+	// Payment events are published through the broker chosen by EVENT_BROKER
+	eventBroker, err := broker.New(eventBrokerKind, eventBrokerFile)
+	if err != nil {
+		slog.Error("Failed to create event broker", "error", err)
+		os.Exit(1)
+	}
+	defer eventBroker.Close()
+
+	// Payments are decided by the gateway chosen by PAYMENT_GATEWAY
+	paymentGateway, err := gateway.New(gateway.Config{
+		Kind:      paymentGatewayKind,
+		RulesFile: paymentGatewayRules,
+		BaseURL:   paymentGatewayURL,
+		Timeout:   paymentGatewayTimeout,
+	})
+	if err != nil {
+		slog.Error("Failed to create payment gateway", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Payment gateway configured", "kind", paymentGatewayKind)
 
 	// Initialize use cases
-	processPaymentUC := usecase.NewProcessPaymentUseCase(paymentRepo)
+	processPaymentUC := usecase.NewProcessPaymentUseCase(paymentRepo, paymentGateway, usecase.ProcessPaymentConfig{
+		AuthorizationTTL:  authorizationTTL,
+		PixMerchant:       pixMerchant,
+		PixChargeTTL:      pixChargeTTL,
+		BoletoIssuer:      boletoIssuer,
+		BoletoGracePeriod: boletoGracePeriod,
+	})
 	getPaymentUC := usecase.NewGetPaymentUseCase(paymentRepo)
 	cancelPaymentUC := usecase.NewCancelPaymentUseCase(paymentRepo)
 	listPaymentsUC := usecase.NewListPaymentsUseCase(paymentRepo)
+	refundPaymentUC := usecase.NewRefundPaymentUseCase(paymentRepo, refundRepo, paymentGateway)
+	capturePaymentUC := usecase.NewCapturePaymentUseCase(paymentRepo, paymentGateway)
+	voidAuthorizationUC := usecase.NewVoidAuthorizationUseCase(paymentRepo, paymentGateway)
+	confirmPaymentUC := usecase.NewConfirmPaymentUseCase(paymentRepo)
+	boletoSlipUC := usecase.NewGetBoletoSlipUseCase(paymentRepo, boletoIssuer, boletoSlip.NewHTMLSlipRenderer())
+	outboxRelayUC := usecase.NewOutboxRelayUseCase(outboxRepo, eventBroker, 100)
+	expirePaymentsUC := usecase.NewExpirePaymentsUseCase(paymentRepo, 100)
+	handleWebhookUC := usecase.NewHandleWebhookUseCase(paymentRepo, refundRepo, webhookEventRepo, paymentGateway)
+	watchPaymentsUC := usecase.NewWatchPaymentsUseCase(statusChangeRepo, watchPaymentsInterval, 100)
+
+	// Publish payment events saved in the outbox and expire authorizations
+	// that were never captured and charges that were never paid
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	go outboxRelayUC.Run(relayCtx, outboxRelayInterval)
+	go expirePaymentsUC.Run(relayCtx, paymentExpiryInterval)
 
-	// Initialize gRPC server
-	grpcServer := grpc.NewServer()
+	// Initialize gRPC server. TLS is on when a certificate is configured,
+	// and mutual when a client CA is too; the files are read again when they
+	// are rotated.
+	var serverOptions []grpc.ServerOption
+	if tlsCertFile != "" {
+		certReloader, err := security.NewCertReloader(tlsCertFile, tlsKeyFile, tlsClientCAFile)
+		if err != nil {
+			slog.Error("Failed to load TLS certificates", "error", err)
+			os.Exit(1)
+		}
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(certReloader.ServerConfig())))
+		slog.Info("gRPC TLS enabled", "mutual", certReloader.MutualTLS())
+	} else {
+		slog.Warn("GRPC_TLS_CERT_FILE not set, gRPC traffic is not encrypted")
+	}
+
+	// Every call must carry a service token or an allowed client certificate
+	switch {
+	case authDisabled:
+		slog.Warn("GRPC_AUTH_DISABLED is set, gRPC calls are not authenticated")
+	case len(serviceTokens) == 0 && len(allowedClients) == 0:
+		slog.Error("Set GRPC_SERVICE_TOKENS or GRPC_ALLOWED_CLIENTS, or GRPC_AUTH_DISABLED=true for local development")
+		os.Exit(1)
+	default:
+		if len(allowedClients) > 0 && tlsClientCAFile == "" {
+			slog.Warn("GRPC_ALLOWED_CLIENTS needs GRPC_TLS_CLIENT_CA_FILE, client certificates are not verified")
+		}
+		authenticator := security.NewAuthenticator(security.AuthConfig{
+			ServiceTokens:  serviceTokens,
+			AllowedClients: allowedClients,
+		})
+		serverOptions = append(serverOptions,
+			grpc.UnaryInterceptor(authenticator.UnaryInterceptor()),
+			grpc.StreamInterceptor(authenticator.StreamInterceptor()),
+		)
+		slog.Info("gRPC authentication enabled", "services", len(serviceTokens), "client_identities", len(allowedClients))
+	}
+
+	grpcServer := grpc.NewServer(serverOptions...)
 
 	// Register payment service
 	paymentServiceServer := grpcHandler.NewPaymentServiceServer(
@@ -69,6 +243,12 @@ func main() {
 		getPaymentUC,
 		cancelPaymentUC,
 		listPaymentsUC,
+		refundPaymentUC,
+		capturePaymentUC,
+		voidAuthorizationUC,
+		confirmPaymentUC,
+		boletoSlipUC,
+		watchPaymentsUC,
 	)
 	pb.RegisterPaymentServiceServer(grpcServer, paymentServiceServer)
 
@@ -84,6 +264,30 @@ func main() {
 
 	slog.Info("gRPC server listening", "port", grpcPort)
 
+	// Start the HTTP listener for gateway webhooks. Without a secret the
+	// requests could not be verified, so the listener stays off.
+	var webhookServer *http.Server
+	if webhookSecret == "" {
+		slog.Warn("WEBHOOK_SECRET not set, gateway webhooks are disabled")
+	} else {
+		webhookHandler := webhook.NewHandler(webhook.NewVerifier(webhookSecret, webhookTolerance), handleWebhookUC)
+		webhookServer = &http.Server{
+			Addr:              fmt.Sprintf(":%s", webhookPort),
+			Handler:           webhookHandler.Routes(),
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      15 * time.Second,
+		}
+
+		go func() {
+			slog.Info("Webhook server listening", "port", webhookPort, "path", webhook.GatewayPath)
+			if err := webhookServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Failed to serve webhooks", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	// Handle graceful shutdown
 	go func() {
 		sigint := make(chan os.Signal, 1)
@@ -91,6 +295,16 @@ func main() {
 		<-sigint
 
 		slog.Info("Shutting down gRPC server...")
+		stopRelay()
+		// Streams never end on their own and would block GracefulStop
+		watchPaymentsUC.Stop()
+		if webhookServer != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := webhookServer.Shutdown(shutdownCtx); err != nil {
+				slog.Error("Failed to shut down webhook server", "error", err)
+			}
+			cancel()
+		}
 		grpcServer.GracefulStop()
 	}()
 
@@ -101,6 +315,30 @@ func main() {
 	}
 }
 
+// parseServiceTokens reads a comma separated list of service=token pairs
+func parseServiceTokens(value string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for i, pair := range splitList(value) {
+		service, token, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(service) == "" || strings.TrimSpace(token) == "" {
+			// The entry itself is not logged, it may hold a token
+			return nil, fmt.Errorf("entry %d is not service=token", i+1)
+		}
+		tokens[strings.TrimSpace(service)] = strings.TrimSpace(token)
+	}
+	return tokens, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
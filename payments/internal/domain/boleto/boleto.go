@@ -0,0 +1,208 @@
+package boleto
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"payments/internal/domain/entity"
+)
+
+var (
+	ErrInvalidIssuer       = errors.New("boleto issuer needs a 3 digit bank code, a 7 digit agreement, a 2 digit wallet and a beneficiary name")
+	ErrUnsupportedCurrency = errors.New("boletos must be in BRL")
+	ErrAmountTooLarge      = errors.New("boleto amount does not fit the barcode")
+	ErrDueDateOutOfRange   = errors.New("boleto due date is before the FEBRABAN base date")
+)
+
+// Layout of the barcode, as defined by FEBRABAN
+const (
+	currencyBRL    = "9"
+	maxAmountCents = 9_999_999_999
+	sequenceDigits = 10
+	NumberLength   = 17
+)
+
+// factorBase is the date the due date factor counts days from. The factor
+// has 4 digits and starts over at 1000 after 9999, which happened on
+// 2025-02-22.
+var factorBase = time.Date(1997, time.October, 7, 0, 0, 0, 0, time.UTC)
+
+// Issuer is the beneficiary of the boletos and its agreement (convênio)
+// with the bank
+type Issuer struct {
+	BankCode            string
+	Agreement           string
+	Wallet              string
+	BeneficiaryName     string
+	BeneficiaryDocument string
+}
+
+func (i Issuer) Validate() error {
+	if !isDigits(i.BankCode, 3) || !isDigits(i.Agreement, 7) || !isDigits(i.Wallet, 2) ||
+		strings.TrimSpace(i.BeneficiaryName) == "" {
+		return ErrInvalidIssuer
+	}
+	return nil
+}
+
+// Boleto is an issued boleto
+type Boleto struct {
+	// Number is the "nosso número" that identifies the boleto in the bank's
+	// settlement file: the agreement followed by a 10 digit sequence
+	Number string
+	// Barcode is the 44 digit number printed as the ITF barcode
+	Barcode string
+	// DigitableLine is the 47 digit "linha digitável" typed in banking apps
+	DigitableLine string
+	DueDate       time.Time
+}
+
+// NewNumber picks a random sequence for a new boleto of the issuer
+func NewNumber(issuer Issuer) (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(sequenceDigits), nil)
+	sequence, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate boleto number: %w", err)
+	}
+	return fmt.Sprintf("%s%0*d", issuer.Agreement, sequenceDigits, sequence), nil
+}
+
+// Issue builds the barcode and digitable line of a boleto. The free field
+// follows the layout for 7 digit agreements: six zeros, the number and the
+// wallet.
+func Issue(issuer Issuer, number string, amount entity.Money, dueDate time.Time) (*Boleto, error) {
+	if err := issuer.Validate(); err != nil {
+		return nil, err
+	}
+	if !isDigits(number, NumberLength) {
+		return nil, fmt.Errorf("boleto number must have %d digits", NumberLength)
+	}
+	if amount.Currency != "BRL" {
+		return nil, ErrUnsupportedCurrency
+	}
+	if amount.Amount <= 0 || amount.Amount > maxAmountCents {
+		return nil, ErrAmountTooLarge
+	}
+
+	factor, err := dueDateFactor(dueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	freeField := "000000" + number + issuer.Wallet
+	// Barcode without its check digit, which goes in position 5
+	partial := issuer.BankCode + currencyBRL + factor + fmt.Sprintf("%010d", amount.Amount) + freeField
+	barcode := partial[:4] + barcodeCheckDigit(partial) + partial[4:]
+
+	return &Boleto{
+		Number:        number,
+		Barcode:       barcode,
+		DigitableLine: DigitableLine(barcode),
+		DueDate:       dueDate,
+	}, nil
+}
+
+// DigitableLine rearranges a barcode in the five fields of the linha
+// digitável. The first three get a modulo 10 check digit each, the fourth is
+// the barcode check digit and the fifth is the due date factor and amount.
+func DigitableLine(barcode string) string {
+	freeField := barcode[19:44]
+
+	field1 := barcode[0:4] + freeField[0:5]
+	field2 := freeField[5:15]
+	field3 := freeField[15:25]
+
+	return field1 + mod10(field1) +
+		field2 + mod10(field2) +
+		field3 + mod10(field3) +
+		barcode[4:5] +
+		barcode[5:19]
+}
+
+// FormatDigitableLine groups the 47 digits the way they are printed:
+// AAAAA.AAAAA BBBBB.BBBBBB CCCCC.CCCCCC D EEEEEEEEEEEEEE
+func FormatDigitableLine(line string) string {
+	if len(line) != 47 {
+		return line
+	}
+	return fmt.Sprintf("%s.%s %s.%s %s.%s %s %s",
+		line[0:5], line[5:10], line[10:15], line[15:21], line[21:26], line[26:32], line[32:33], line[33:47])
+}
+
+func dueDateFactor(dueDate time.Time) (string, error) {
+	date := time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(date.Sub(factorBase).Hours() / 24)
+	if days < 1000 {
+		return "", ErrDueDateOutOfRange
+	}
+	if days > 9999 {
+		days = (days-10000)%9000 + 1000
+	}
+	return fmt.Sprintf("%04d", days), nil
+}
+
+// barcodeCheckDigit is the modulo 11 check digit of the barcode, with
+// weights 2 to 9 from the right; results of 10 and 11 become 1
+func barcodeCheckDigit(digits string) string {
+	sum, weight := 0, 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * weight
+		weight++
+		if weight > 9 {
+			weight = 2
+		}
+	}
+
+	digit := 11 - sum%11
+	if digit == 10 || digit == 11 {
+		digit = 1
+	}
+	return fmt.Sprint(digit)
+}
+
+// mod10 is the check digit of a digitable line field: weights 2 and 1 from
+// the right, adding up the digits of products above 9
+func mod10(digits string) string {
+	sum, weight := 0, 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		product := int(digits[i]-'0') * weight
+		sum += product/10 + product%10
+		weight = 3 - weight
+	}
+	return fmt.Sprint((10 - sum%10) % 10)
+}
+
+func isDigits(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// BankCodeWithDigit is the bank code followed by its modulo 11 check digit,
+// as printed on the slip header, e.g. 001-9
+func BankCodeWithDigit(bankCode string) string {
+	sum, weight := 0, 2
+	for i := len(bankCode) - 1; i >= 0; i-- {
+		sum += int(bankCode[i]-'0') * weight
+		weight++
+	}
+
+	digit := 11 - sum%11
+	switch digit {
+	case 10:
+		return bankCode + "-X"
+	case 11:
+		return bankCode + "-0"
+	}
+	return fmt.Sprintf("%s-%d", bankCode, digit)
+}
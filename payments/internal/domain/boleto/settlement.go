@@ -0,0 +1,16 @@
+package boleto
+
+import (
+	"time"
+
+	"payments/internal/domain/entity"
+)
+
+// Settlement is a boleto payment reported by the bank
+type Settlement struct {
+	Number string
+	// PaidAmount is what the payer paid, which banks let differ from the
+	// boleto amount
+	PaidAmount entity.Money
+	PaidAt     time.Time
+}
@@ -0,0 +1,28 @@
+package boleto
+
+import (
+	"errors"
+	"time"
+
+	"payments/internal/domain/entity"
+)
+
+var ErrNotIssued = errors.New("payment has no boleto")
+
+// Slip is what is printed on a boleto
+type Slip struct {
+	Issuer Issuer
+	Boleto Boleto
+	Amount entity.Money
+	// PayerDocument is masked, since the full document is never stored
+	PayerName     string
+	PayerDocument string
+	Description   string
+	IssuedAt      time.Time
+}
+
+// SlipRenderer renders a slip the customer can print, such as a HTML page
+// or a PDF
+type SlipRenderer interface {
+	Render(slip Slip) (contentType string, content []byte, err error)
+}
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventPaymentApproved   EventType = "PaymentApproved"
+	EventPaymentAuthorized EventType = "PaymentAuthorized"
+	EventPaymentRefunded   EventType = "PaymentRefunded"
+)
+
+const AggregatePayment = "payment"
+
+// Event is a domain event stored in the transactional outbox and later
+// published to the message broker. Delivery is at least once, so consumers
+// must deduplicate by ID.
+type Event struct {
+	ID            string          `json:"id"`
+	Type          EventType       `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	PublishedAt   *time.Time      `json:"-"`
+	Attempts      int             `json:"-"`
+	LastError     string          `json:"-"`
+}
+
+func NewEvent(eventType EventType, aggregateType, aggregateID string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:            uuid.New().String(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+		OccurredAt:    time.Now(),
+	}, nil
+}
+
+// PaymentEventPayload is the body of every payment event
+type PaymentEventPayload struct {
+	PaymentID      string        `json:"payment_id"`
+	OrderID        string        `json:"order_id"`
+	Status         PaymentStatus `json:"status"`
+	PaymentMethod  PaymentMethod `json:"payment_method"`
+	Amount         Money         `json:"amount"`
+	RefundedAmount Money         `json:"refunded_amount"`
+	CapturedAmount Money         `json:"captured_amount"`
+	TransactionID  string        `json:"transaction_id,omitempty"`
+	// Refund is only set on PaymentRefunded
+	Refund *Refund `json:"refund,omitempty"`
+}
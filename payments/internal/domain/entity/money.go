@@ -0,0 +1,171 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used when a legacy decimal value arrives without a currency.
+const DefaultCurrency = "BRL"
+
+var (
+	ErrInvalidCurrency  = errors.New("currency must be a three-letter ISO-4217 code")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidMoney     = errors.New("invalid monetary amount")
+	// ErrMoneyOverflow means the result does not fit in int64 minor units
+	ErrMoneyOverflow = fmt.Errorf("%w: amount out of range", ErrInvalidMoney)
+)
+
+// Money is an exact monetary value: Amount is expressed in minor units
+// (cents) of Currency, so 1050 BRL means R$ 10,50. It mirrors the Money
+// message in payment.proto. The orders and payments services keep identical
+// copies of this file, so a change to one must be made to both.
+type Money struct {
+	Amount   int64  `json:"amount" example:"350000"`
+	Currency string `json:"currency" example:"BRL"`
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+func Zero(currency string) Money {
+	return NewMoney(0, currency)
+}
+
+func (m Money) Validate() error {
+	if len(m.Currency) != 3 {
+		return ErrInvalidCurrency
+	}
+	for _, c := range m.Currency {
+		if c < 'A' || c > 'Z' {
+			return ErrInvalidCurrency
+		}
+	}
+	return nil
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+func (m Money) Subtract(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	difference := m.Amount - other.Amount
+	if (other.Amount > 0 && difference > m.Amount) || (other.Amount < 0 && difference < m.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: difference, Currency: m.Currency}, nil
+}
+
+func (m Money) Multiply(quantity int) (Money, error) {
+	factor := int64(quantity)
+	product := m.Amount * factor
+	if factor != 0 && (product/factor != m.Amount || (factor == -1 && m.Amount == math.MinInt64)) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// String formats the value with two decimal places, e.g. "BRL 10.50".
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s %s%d.%02d", m.Currency, sign, amount/100, amount%100)
+}
+
+// UnmarshalJSON accepts the {"amount": 1050, "currency": "BRL"} object and,
+// for clients that still send plain decimals, a number such as 10.50 which
+// is read as major units of DefaultCurrency without going through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		type money Money
+		var decoded money
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return err
+		}
+		*m = NewMoney(decoded.Amount, decoded.Currency)
+		return nil
+	}
+
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	amount, err := ParseDecimal(string(data))
+	if err != nil {
+		return err
+	}
+	*m = NewMoney(amount, DefaultCurrency)
+	return nil
+}
+
+// ParseDecimal converts a decimal string in major units ("10.5", "3500.00")
+// into minor units. Only digits with an optional leading minus and decimal
+// point are accepted; more than two decimal places is rejected rather than
+// silently rounded, and so are amounts beyond int64 minor units.
+func ParseDecimal(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" || len(fraction) > 2 || !isDigits(whole) || !isDigits(fraction) {
+		return 0, ErrInvalidMoney
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMoney
+	}
+	cents, _ := strconv.ParseInt(fraction, 10, 64)
+	if units > (math.MaxInt64-cents)/100 {
+		return 0, ErrInvalidMoney
+	}
+
+	amount := units*100 + cents
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -26,60 +26,128 @@ const (
 	PaymentStatusDeclined   PaymentStatus = "declined"
 	PaymentStatusCanceled   PaymentStatus = "canceled"
 	PaymentStatusRefunded   PaymentStatus = "refunded"
+
+	// PaymentStatusPartiallyRefunded means part of the approved amount was
+	// given back; further refunds are allowed up to the approved amount
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
+
+	// PaymentStatusAuthorized means the amount is held on the customer's card
+	// but not charged yet: it must be captured (or voided) before it expires
+	PaymentStatusAuthorized PaymentStatus = "authorized"
+	// PaymentStatusExpired marks authorizations that were never captured and
+	// charges, such as PIX or boletos, that were never paid
+	PaymentStatusExpired PaymentStatus = "expired"
 )
 
 var (
 	ErrInvalidAmount           = errors.New("amount must be greater than zero")
 	ErrInvalidPaymentMethod    = errors.New("invalid payment method")
 	ErrInvalidPaymentStatus    = errors.New("invalid payment status")
+	ErrEmptyPaymentID          = errors.New("payment_id cannot be empty")
 	ErrPaymentNotFound         = errors.New("payment not found")
 	ErrPaymentCannotBeCanceled = errors.New("payment cannot be canceled in current status")
 	ErrEmptyOrderID            = errors.New("order_id cannot be empty")
 	ErrEmptyCustomerEmail      = errors.New("customer email cannot be empty")
+	ErrPaymentCannotBeRefunded = errors.New("only approved or partially refunded payments can be refunded")
+	ErrInvalidRefundAmount     = errors.New("refund amount must be greater than zero")
+	ErrRefundExceedsAmount     = errors.New("refund exceeds the remaining refundable amount")
+	ErrConcurrentRefund        = errors.New("payment was refunded concurrently, try again")
+	ErrPaymentConflict         = errors.New("payment was changed concurrently, try again")
+	ErrIdempotencyKeyReused    = errors.New("idempotency key was already used for a different payment")
+	ErrDuplicateIdempotencyKey = errors.New("a payment with this idempotency key already exists")
+	ErrDuplicateBoletoNumber   = errors.New("a boleto with this number already exists")
+
+	ErrAuthorizationNotSupported   = errors.New("only card payments can be authorized and captured later")
+	ErrPaymentNotAuthorized        = errors.New("payment must be authorized to be captured or voided")
+	ErrAuthorizationExpired        = errors.New("payment authorization has expired")
+	ErrInvalidCaptureAmount        = errors.New("capture amount must be greater than zero")
+	ErrCaptureExceedsAuthorization = errors.New("capture exceeds the authorized amount")
+
+	ErrConfirmationNotSupported = errors.New("only PIX and boleto payments wait for a confirmation")
+	ErrPaymentNotAwaiting       = errors.New("payment is not waiting for a confirmation")
+	ErrPaymentExpired           = errors.New("payment expired before it was paid")
 )
 
 type Payment struct {
-	ID            string        `json:"id"`
-	OrderID       string        `json:"order_id"`
-	Amount        float64       `json:"amount"`
-	PaymentMethod PaymentMethod `json:"payment_method"`
-	Status        PaymentStatus `json:"status"`
-	TransactionID string        `json:"transaction_id,omitempty"`
-	CustomerEmail string        `json:"customer_email"`
-	CustomerName  string        `json:"customer_name"`
-	CreatedAt     time.Time     `json:"created_at"`
-	UpdatedAt     time.Time     `json:"updated_at"`
-	CanceledAt    *time.Time    `json:"canceled_at,omitempty"`
-	CancelReason  string        `json:"cancel_reason,omitempty"`
-}
-
-func NewPayment(orderID string, amount float64, paymentMethod PaymentMethod, customerEmail, customerName string) (*Payment, error) {
+	ID             string        `json:"id"`
+	OrderID        string        `json:"order_id"`
+	Amount         Money         `json:"amount"`
+	RefundedAmount Money         `json:"refunded_amount"`
+	PaymentMethod  PaymentMethod `json:"payment_method"`
+	Status         PaymentStatus `json:"status"`
+	TransactionID  string        `json:"transaction_id,omitempty"`
+	CustomerEmail  string        `json:"customer_email"`
+	CustomerName   string        `json:"customer_name"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	CanceledAt     *time.Time    `json:"canceled_at,omitempty"`
+	CancelReason   string        `json:"cancel_reason,omitempty"`
+	// IdempotencyKey identifies the client request that created the payment
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// CapturedAmount is what was actually charged: Amount for payments
+	// approved at once, possibly less for captured authorizations
+	CapturedAmount         Money      `json:"captured_amount"`
+	AuthorizedAt           *time.Time `json:"authorized_at,omitempty"`
+	AuthorizationExpiresAt *time.Time `json:"authorization_expires_at,omitempty"`
+	// Gateway* keep what the payment provider answered, e.g. the decline
+	// code, for support and reconciliation
+	GatewayResponseCode string `json:"gateway_response_code,omitempty"`
+	AuthorizationCode   string `json:"authorization_code,omitempty"`
+	GatewayMessage      string `json:"gateway_message,omitempty"`
+	// Details are the safe fields of the card, PIX key or boleto used
+	Details *PaymentDetails `json:"details,omitempty"`
+	// ExpiresAt is when a payment waiting for the customer to pay it, such as
+	// a PIX charge or a boleto, expires unpaid
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// PixQRCode is the BR Code ("copia e cola") of PIX charges
+	PixQRCode string `json:"pix_qr_code,omitempty"`
+	// BoletoNumber ("nosso número") identifies a boleto in the settlement
+	// files; the barcode and digitable line are printed on its slip
+	BoletoNumber        string `json:"boleto_number,omitempty"`
+	BoletoBarcode       string `json:"boleto_barcode,omitempty"`
+	BoletoDigitableLine string `json:"boleto_digitable_line,omitempty"`
+
+	// events recorded since the payment was loaded, saved to the outbox by
+	// the repository in the same transaction as the payment
+	events []Event
+	// savedStatus is the status the payment had when it was loaded or last
+	// saved. The repository only saves over that status, so a change made
+	// concurrently, such as a capture racing an expiry, is not overwritten.
+	savedStatus PaymentStatus
+}
+
+func NewPayment(orderID string, amount Money, paymentMethod PaymentMethod, customerEmail, customerName string) (*Payment, error) {
 	if err := validatePaymentData(orderID, amount, paymentMethod, customerEmail); err != nil {
 		return nil, err
 	}
 
 	return &Payment{
-		ID:            uuid.New().String(),
-		OrderID:       orderID,
-		Amount:        amount,
-		PaymentMethod: paymentMethod,
-		Status:        PaymentStatusPending,
-		CustomerEmail: customerEmail,
-		CustomerName:  customerName,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		ID:             uuid.New().String(),
+		OrderID:        orderID,
+		Amount:         amount,
+		RefundedAmount: Zero(amount.Currency),
+		PaymentMethod:  paymentMethod,
+		Status:         PaymentStatusPending,
+		CustomerEmail:  customerEmail,
+		CustomerName:   customerName,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}, nil
 }
 
-func validatePaymentData(orderID string, amount float64, paymentMethod PaymentMethod, customerEmail string) error {
+func validatePaymentData(orderID string, amount Money, paymentMethod PaymentMethod, customerEmail string) error {
 	if orderID == "" {
 		return ErrEmptyOrderID
 	}
 
-	if amount <= 0 {
+	if !amount.IsPositive() {
 		return ErrInvalidAmount
 	}
 
+	if err := amount.Validate(); err != nil {
+		return err
+	}
+
 	if !isValidPaymentMethod(paymentMethod) {
 		return ErrInvalidPaymentMethod
 	}
@@ -100,6 +168,18 @@ func isValidPaymentMethod(method PaymentMethod) bool {
 	}
 }
 
+// SupportsAuthorization tells whether the method can hold an amount to be
+// captured later, which only cards can do
+func (m PaymentMethod) SupportsAuthorization() bool {
+	return m == PaymentMethodCreditCard || m == PaymentMethodDebitCard
+}
+
+// RequiresConfirmation tells whether payments of the method are paid by the
+// customer after the charge is created, and so wait for a confirmation
+func (m PaymentMethod) RequiresConfirmation() bool {
+	return m == PaymentMethodPix || m == PaymentMethodBoleto
+}
+
 func (p *Payment) Process(transactionID string) error {
 	if p.Status != PaymentStatusPending {
 		return errors.New("payment must be in pending status to be processed")
@@ -111,19 +191,152 @@ func (p *Payment) Process(transactionID string) error {
 	return nil
 }
 
+// RecordGatewayResponse keeps the provider answer for the payment
+func (p *Payment) RecordGatewayResponse(responseCode, authorizationCode, message string) {
+	p.GatewayResponseCode = responseCode
+	p.AuthorizationCode = authorizationCode
+	p.GatewayMessage = message
+	p.UpdatedAt = time.Now()
+}
+
 func (p *Payment) Approve() error {
 	if p.Status != PaymentStatusProcessing {
 		return errors.New("payment must be in processing status to be approved")
 	}
 
 	p.Status = PaymentStatusApproved
+	p.CapturedAmount = p.Amount
+	p.UpdatedAt = time.Now()
+	return p.recordEvent(EventPaymentApproved, nil)
+}
+
+// Authorize holds the amount on the customer's card until expiresAt instead
+// of charging it. Only card payments support authorization.
+func (p *Payment) Authorize(expiresAt time.Time) error {
+	if !p.PaymentMethod.SupportsAuthorization() {
+		return ErrAuthorizationNotSupported
+	}
+	if p.Status != PaymentStatusProcessing {
+		return errors.New("payment must be in processing status to be authorized")
+	}
+
+	now := time.Now()
+	p.Status = PaymentStatusAuthorized
+	p.AuthorizedAt = &now
+	p.AuthorizationExpiresAt = &expiresAt
+	p.UpdatedAt = now
+	return p.recordEvent(EventPaymentAuthorized, nil)
+}
+
+// AwaitConfirmation keeps a charge created at the provider pending until
+// the customer pays it (Confirm) or expiresAt passes (Expire).
+func (p *Payment) AwaitConfirmation(transactionID string, expiresAt time.Time) error {
+	if !p.PaymentMethod.RequiresConfirmation() {
+		return ErrConfirmationNotSupported
+	}
+	if p.Status != PaymentStatusPending || p.ExpiresAt != nil {
+		return errors.New("payment must be a new pending payment to await confirmation")
+	}
+
+	p.TransactionID = transactionID
+	p.ExpiresAt = &expiresAt
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// Confirm approves a pending charge once the provider reports it paid.
+func (p *Payment) Confirm(now time.Time) error {
+	if !p.IsAwaitingConfirmation() {
+		return ErrPaymentNotAwaiting
+	}
+	if !p.ExpiresAt.After(now) {
+		return ErrPaymentExpired
+	}
+
+	p.Status = PaymentStatusApproved
+	p.CapturedAmount = p.Amount
+	p.UpdatedAt = now
+	return p.recordEvent(EventPaymentApproved, nil)
+}
+
+func (p *Payment) IsAwaitingConfirmation() bool {
+	return p.Status == PaymentStatusPending && p.ExpiresAt != nil
+}
+
+// Capture charges all or part of an authorized amount. A single capture is
+// allowed; whatever is not captured is released back to the customer.
+func (p *Payment) Capture(amount Money) error {
+	if p.Status != PaymentStatusAuthorized {
+		return ErrPaymentNotAuthorized
+	}
+	if p.IsAuthorizationExpired(time.Now()) {
+		return ErrAuthorizationExpired
+	}
+	if !amount.IsPositive() {
+		return ErrInvalidCaptureAmount
+	}
+
+	remaining, err := p.Amount.Subtract(amount)
+	if err != nil {
+		return err
+	}
+	if remaining.Amount < 0 {
+		return ErrCaptureExceedsAuthorization
+	}
+
+	p.Status = PaymentStatusApproved
+	p.CapturedAmount = amount
 	p.UpdatedAt = time.Now()
+	return p.recordEvent(EventPaymentApproved, nil)
+}
+
+// Void releases an authorization without charging anything.
+func (p *Payment) Void(reason string) error {
+	if p.Status != PaymentStatusAuthorized {
+		return ErrPaymentNotAuthorized
+	}
+	return p.Cancel(reason)
+}
+
+// Expire closes an authorization whose window passed without a capture, or
+// a charge that was not paid in time.
+func (p *Payment) Expire(now time.Time) error {
+	if p.IsAwaitingConfirmation() && !p.IsChargeExpired(now) {
+		return errors.New("payment charge has not expired yet")
+	}
+	if !p.IsAuthorizationExpired(now) && !p.IsChargeExpired(now) {
+		return ErrPaymentNotAuthorized
+	}
+
+	p.Status = PaymentStatusExpired
+	p.UpdatedAt = now
 	return nil
 }
 
+// BoletoDueDate is the due date of an issued boleto
+func (p *Payment) BoletoDueDate() *time.Time {
+	if p.BoletoNumber == "" || p.Details == nil {
+		return nil
+	}
+	return p.Details.BoletoDueDate
+}
+
+func (p *Payment) IsAuthorizationExpired(now time.Time) bool {
+	return p.Status == PaymentStatusAuthorized &&
+		p.AuthorizationExpiresAt != nil &&
+		!p.AuthorizationExpiresAt.After(now)
+}
+
+func (p *Payment) IsChargeExpired(now time.Time) bool {
+	return p.IsAwaitingConfirmation() && !p.ExpiresAt.After(now)
+}
+
+// Decline marks the payment as refused, either right after the gateway
+// processed it or, for charges awaiting confirmation, when the provider
+// reports that the charge was refused
 func (p *Payment) Decline() error {
-	if p.Status != PaymentStatusProcessing {
-		return errors.New("payment must be in processing status to be declined")
+	if p.Status != PaymentStatusProcessing && !p.IsAwaitingConfirmation() {
+		return errors.New("payment must be in processing status or awaiting confirmation to be declined")
 	}
 
 	p.Status = PaymentStatusDeclined
@@ -132,7 +345,7 @@ func (p *Payment) Decline() error {
 }
 
 func (p *Payment) Cancel(reason string) error {
-	if p.Status == PaymentStatusApproved || p.Status == PaymentStatusCanceled || p.Status == PaymentStatusRefunded {
+	if !p.CanBeCanceled() {
 		return ErrPaymentCannotBeCanceled
 	}
 
@@ -144,25 +357,129 @@ func (p *Payment) Cancel(reason string) error {
 	return nil
 }
 
-func (p *Payment) Refund() error {
-	if p.Status != PaymentStatusApproved {
-		return errors.New("only approved payments can be refunded")
+// Refund gives back part or all of the captured amount. Several partial
+// refunds are allowed as long as their sum does not exceed the amount;
+// the payment becomes refunded once nothing is left to refund.
+func (p *Payment) Refund(amount Money, reason string) (*Refund, error) {
+	if p.Status != PaymentStatusApproved && p.Status != PaymentStatusPartiallyRefunded {
+		return nil, ErrPaymentCannotBeRefunded
+	}
+
+	if !amount.IsPositive() {
+		return nil, ErrInvalidRefundAmount
+	}
+
+	remaining, err := p.RefundableAmount().Subtract(amount)
+	if err != nil {
+		return nil, err
+	}
+	if remaining.Amount < 0 {
+		return nil, ErrRefundExceedsAmount
+	}
+
+	refunded, err := p.refundedAmount().Add(amount)
+	if err != nil {
+		return nil, err
 	}
 
-	p.Status = PaymentStatusRefunded
+	p.RefundedAmount = refunded
+	if remaining.IsZero() {
+		p.Status = PaymentStatusRefunded
+	} else {
+		p.Status = PaymentStatusPartiallyRefunded
+	}
 	p.UpdatedAt = time.Now()
+
+	refund := newRefund(p.ID, amount, reason)
+	if err := p.recordEvent(EventPaymentRefunded, refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// RefundableAmount is what is still available to be refunded
+func (p *Payment) RefundableAmount() Money {
+	if p.Status != PaymentStatusApproved && p.Status != PaymentStatusPartiallyRefunded {
+		return Zero(p.Amount.Currency)
+	}
+	remaining, _ := p.capturedAmount().Subtract(p.refundedAmount())
+	return remaining
+}
+
+// capturedAmount falls back to Amount for payments loaded without it
+func (p *Payment) capturedAmount() Money {
+	if p.CapturedAmount.Currency == "" {
+		return p.Amount
+	}
+	return p.CapturedAmount
+}
+
+func (p *Payment) refundedAmount() Money {
+	if p.RefundedAmount.Currency == "" {
+		return Zero(p.Amount.Currency)
+	}
+	return p.RefundedAmount
+}
+
+// Events returns the events recorded and not yet saved
+func (p *Payment) Events() []Event {
+	return p.events
+}
+
+// ClearEvents is called by the repository once the events are saved
+func (p *Payment) ClearEvents() {
+	p.events = nil
+}
+
+// SavedStatus returns the status the payment had when it was loaded or last
+// saved
+func (p *Payment) SavedStatus() PaymentStatus {
+	return p.savedStatus
+}
+
+// MarkSaved is called by the repository once the payment is loaded or saved
+func (p *Payment) MarkSaved() {
+	p.savedStatus = p.Status
+}
+
+func (p *Payment) recordEvent(eventType EventType, refund *Refund) error {
+	event, err := NewEvent(eventType, AggregatePayment, p.ID, PaymentEventPayload{
+		PaymentID:      p.ID,
+		OrderID:        p.OrderID,
+		Status:         p.Status,
+		PaymentMethod:  p.PaymentMethod,
+		Amount:         p.Amount,
+		RefundedAmount: p.refundedAmount(),
+		CapturedAmount: p.capturedAmount(),
+		TransactionID:  p.TransactionID,
+		Refund:         refund,
+	})
+	if err != nil {
+		return err
+	}
+	p.events = append(p.events, event)
 	return nil
 }
 
+// MatchesRequest tells whether a retried request carrying the same
+// idempotency key describes this payment
+func (p *Payment) MatchesRequest(orderID string, amount Money) bool {
+	return p.OrderID == orderID && p.Amount == amount
+}
+
 func (p *Payment) CanBeCanceled() bool {
 	return p.Status != PaymentStatusApproved &&
 		p.Status != PaymentStatusCanceled &&
-		p.Status != PaymentStatusRefunded
+		p.Status != PaymentStatusRefunded &&
+		p.Status != PaymentStatusPartiallyRefunded &&
+		p.Status != PaymentStatusExpired
 }
 
 func (p *Payment) IsFinalized() bool {
 	return p.Status == PaymentStatusApproved ||
 		p.Status == PaymentStatusDeclined ||
 		p.Status == PaymentStatusCanceled ||
-		p.Status == PaymentStatusRefunded
+		p.Status == PaymentStatusRefunded ||
+		p.Status == PaymentStatusPartiallyRefunded ||
+		p.Status == PaymentStatusExpired
 }
@@ -0,0 +1,410 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPaymentDetails is wrapped by every payment details validation
+// error, so callers can tell bad input from other failures
+var ErrInvalidPaymentDetails = errors.New("invalid payment details")
+
+var (
+	ErrPaymentDetailsMismatch = fmt.Errorf("%w: details do not match the payment method", ErrInvalidPaymentDetails)
+	ErrInvalidCardNumber      = fmt.Errorf("%w: invalid card number", ErrInvalidPaymentDetails)
+	ErrInvalidCardExpiry      = fmt.Errorf("%w: card expiry must be MM/YY or MM/YYYY", ErrInvalidPaymentDetails)
+	ErrCardExpired            = fmt.Errorf("%w: card is expired", ErrInvalidPaymentDetails)
+	ErrInvalidCVV             = fmt.Errorf("%w: invalid card security code", ErrInvalidPaymentDetails)
+	ErrEmptyCardHolderName    = fmt.Errorf("%w: card holder name cannot be empty", ErrInvalidPaymentDetails)
+	ErrInvalidDocument        = fmt.Errorf("%w: document must be a valid CPF or CNPJ", ErrInvalidPaymentDetails)
+	ErrInvalidPixKey          = fmt.Errorf("%w: PIX key must be a CPF, CNPJ, email, phone (+55...) or random key", ErrInvalidPaymentDetails)
+	ErrInvalidBoletoDueDate   = fmt.Errorf("%w: boleto due date is out of range", ErrInvalidPaymentDetails)
+	ErrBoletoDetailsRequired  = fmt.Errorf("%w: boletos need the payer CPF or CNPJ", ErrInvalidPaymentDetails)
+	ErrCardDetailsRequired    = fmt.Errorf("%w: card payments need the card data", ErrInvalidPaymentDetails)
+	ErrPixDetailsRequired     = fmt.Errorf("%w: PIX payments need the payer PIX key", ErrInvalidPaymentDetails)
+)
+
+const (
+	// BoletoDefaultDueDays is used when the client does not choose a due date
+	BoletoDefaultDueDays = 3
+	// BoletoMaxDueDays is how far ahead a boleto may be due
+	BoletoMaxDueDays = 90
+)
+
+type CardBrand string
+
+const (
+	CardBrandVisa       CardBrand = "visa"
+	CardBrandMastercard CardBrand = "mastercard"
+	CardBrandAmex       CardBrand = "amex"
+	CardBrandElo        CardBrand = "elo"
+	CardBrandHipercard  CardBrand = "hipercard"
+	CardBrandDiners     CardBrand = "diners"
+	CardBrandDiscover   CardBrand = "discover"
+	CardBrandUnknown    CardBrand = "unknown"
+)
+
+type DocumentType string
+
+const (
+	DocumentTypeCPF  DocumentType = "cpf"
+	DocumentTypeCNPJ DocumentType = "cnpj"
+)
+
+type PixKeyType string
+
+const (
+	PixKeyTypeCPF    PixKeyType = "cpf"
+	PixKeyTypeCNPJ   PixKeyType = "cnpj"
+	PixKeyTypeEmail  PixKeyType = "email"
+	PixKeyTypePhone  PixKeyType = "phone"
+	PixKeyTypeRandom PixKeyType = "random"
+)
+
+// PaymentDetails are the method specific data of a payment that are safe to
+// store: card numbers, security codes and full documents never get here.
+type PaymentDetails struct {
+	// CardToken is the token the gateway issued for the card when it
+	// processed the payment; the card number itself is never stored
+	CardToken      string    `json:"card_token,omitempty"`
+	CardBrand      CardBrand `json:"card_brand,omitempty"`
+	CardLast4      string    `json:"card_last4,omitempty"`
+	CardHolderName string    `json:"card_holder_name,omitempty"`
+	CardExpiry     string    `json:"card_expiry,omitempty"` // MM/YYYY
+
+	PixKeyType   PixKeyType `json:"pix_key_type,omitempty"`
+	MaskedPixKey string     `json:"masked_pix_key,omitempty"`
+
+	DocumentType   DocumentType `json:"document_type,omitempty"`
+	MaskedDocument string       `json:"masked_document,omitempty"`
+	BoletoDueDate  *time.Time   `json:"boleto_due_date,omitempty"`
+}
+
+// CardData is a card as sent by the client. It is only kept in memory while
+// the payment is processed.
+type CardData struct {
+	Number     string
+	HolderName string
+	Expiry     string // MM/YY or MM/YYYY
+	CVV        string
+}
+
+// BIN returns the first six digits of the card, which identify the issuer
+func (c CardData) BIN() string {
+	digits := onlyDigits(c.Number)
+	if len(digits) < 6 {
+		return digits
+	}
+	return digits[:6]
+}
+
+// NewCardDetails validates a card (Luhn checksum, expiry not past at now,
+// CVV length for the brand) and keeps only its safe fields.
+func NewCardDetails(card CardData, now time.Time) (*PaymentDetails, error) {
+	number := onlyDigits(card.Number)
+	if len(number) != len(strings.NewReplacer(" ", "", "-", "").Replace(card.Number)) {
+		return nil, ErrInvalidCardNumber
+	}
+	if len(number) < 12 || len(number) > 19 || !luhnValid(number) {
+		return nil, ErrInvalidCardNumber
+	}
+
+	holderName := strings.TrimSpace(card.HolderName)
+	if holderName == "" {
+		return nil, ErrEmptyCardHolderName
+	}
+
+	month, year, err := parseCardExpiry(card.Expiry)
+	if err != nil {
+		return nil, err
+	}
+	// A card is valid until the last day of its expiry month
+	if !now.Before(time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, now.Location())) {
+		return nil, ErrCardExpired
+	}
+
+	brand := detectCardBrand(number)
+	cvvLength := 3
+	if brand == CardBrandAmex {
+		cvvLength = 4
+	}
+	if len(card.CVV) != cvvLength || onlyDigits(card.CVV) != card.CVV {
+		return nil, ErrInvalidCVV
+	}
+
+	return &PaymentDetails{
+		CardBrand:      brand,
+		CardLast4:      number[len(number)-4:],
+		CardHolderName: holderName,
+		CardExpiry:     fmt.Sprintf("%02d/%04d", month, year),
+	}, nil
+}
+
+// NewPixDetails validates a PIX key and keeps it masked
+func NewPixDetails(key string) (*PaymentDetails, error) {
+	keyType, err := detectPixKeyType(strings.TrimSpace(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentDetails{
+		PixKeyType:   keyType,
+		MaskedPixKey: maskPixKey(strings.TrimSpace(key), keyType),
+	}, nil
+}
+
+// NewBoletoDetails validates the payer document and the due date. Without a
+// due date the boleto is due in BoletoDefaultDueDays; due dates on weekends
+// move to the next Monday, like banks do.
+func NewBoletoDetails(document string, dueDate *time.Time, now time.Time) (*PaymentDetails, error) {
+	documentType, digits, err := parseDocument(document)
+	if err != nil {
+		return nil, err
+	}
+
+	today := startOfDay(now)
+	due := today.AddDate(0, 0, BoletoDefaultDueDays)
+	if dueDate != nil {
+		// Only the calendar date matters, whatever zone it was sent in
+		due = time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, now.Location())
+	}
+	if due.Before(today) || due.After(today.AddDate(0, 0, BoletoMaxDueDays)) {
+		return nil, ErrInvalidBoletoDueDate
+	}
+	switch due.Weekday() {
+	case time.Saturday:
+		due = due.AddDate(0, 0, 2)
+	case time.Sunday:
+		due = due.AddDate(0, 0, 1)
+	}
+
+	return &PaymentDetails{
+		DocumentType:   documentType,
+		MaskedDocument: maskDocument(digits, documentType),
+		BoletoDueDate:  &due,
+	}, nil
+}
+
+// Matches tells whether the details are the kind the payment method needs
+func (d *PaymentDetails) Matches(method PaymentMethod) bool {
+	switch method {
+	case PaymentMethodCreditCard, PaymentMethodDebitCard:
+		return d.CardLast4 != ""
+	case PaymentMethodPix:
+		return d.PixKeyType != ""
+	case PaymentMethodBoleto:
+		return d.DocumentType != ""
+	default:
+		return false
+	}
+}
+
+// AttachDetails sets the safe details of the payment
+func (p *Payment) AttachDetails(details *PaymentDetails) error {
+	if !details.Matches(p.PaymentMethod) {
+		return ErrPaymentDetailsMismatch
+	}
+	p.Details = details
+	return nil
+}
+
+func parseCardExpiry(expiry string) (month, year int, err error) {
+	parts := strings.Split(strings.TrimSpace(expiry), "/")
+	if len(parts) != 2 {
+		return 0, 0, ErrInvalidCardExpiry
+	}
+
+	month, err = strconv.Atoi(parts[0])
+	if err != nil || len(parts[0]) != 2 || month < 1 || month > 12 {
+		return 0, 0, ErrInvalidCardExpiry
+	}
+
+	year, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, ErrInvalidCardExpiry
+	}
+	switch len(parts[1]) {
+	case 2:
+		year += 2000
+	case 4:
+	default:
+		return 0, 0, ErrInvalidCardExpiry
+	}
+
+	return month, year, nil
+}
+
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// eloPrefixes are the Elo ranges that would otherwise be read as Visa,
+// Mastercard or Discover
+var eloPrefixes = []string{
+	"401178", "401179", "431274", "438935", "451416", "457393", "457631", "457632",
+	"504175", "506699", "5067", "509", "627780", "636297", "636368", "650", "6516", "6550",
+}
+
+func detectCardBrand(number string) CardBrand {
+	for _, prefix := range eloPrefixes {
+		if strings.HasPrefix(number, prefix) {
+			return CardBrandElo
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(number, "606282"), strings.HasPrefix(number, "3841"):
+		return CardBrandHipercard
+	case strings.HasPrefix(number, "34"), strings.HasPrefix(number, "37"):
+		return CardBrandAmex
+	case strings.HasPrefix(number, "36"), strings.HasPrefix(number, "38"), prefixBetween(number, 3, 300, 305):
+		return CardBrandDiners
+	case strings.HasPrefix(number, "4"):
+		return CardBrandVisa
+	case prefixBetween(number, 2, 51, 55), prefixBetween(number, 4, 2221, 2720):
+		return CardBrandMastercard
+	case strings.HasPrefix(number, "6011"), strings.HasPrefix(number, "65"):
+		return CardBrandDiscover
+	default:
+		return CardBrandUnknown
+	}
+}
+
+func prefixBetween(number string, length, low, high int) bool {
+	if len(number) < length {
+		return false
+	}
+	prefix, _ := strconv.Atoi(number[:length])
+	return prefix >= low && prefix <= high
+}
+
+// parseDocument accepts a CPF or CNPJ, formatted or not
+func parseDocument(document string) (DocumentType, string, error) {
+	digits := onlyDigits(document)
+	switch {
+	case len(digits) == 11 && cpfValid(digits):
+		return DocumentTypeCPF, digits, nil
+	case len(digits) == 14 && cnpjValid(digits):
+		return DocumentTypeCNPJ, digits, nil
+	default:
+		return "", "", ErrInvalidDocument
+	}
+}
+
+func cpfValid(cpf string) bool {
+	if allSameDigit(cpf) {
+		return false
+	}
+	return checkDigit(cpf[:9], []int{10, 9, 8, 7, 6, 5, 4, 3, 2}) == int(cpf[9]-'0') &&
+		checkDigit(cpf[:10], []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}) == int(cpf[10]-'0')
+}
+
+func cnpjValid(cnpj string) bool {
+	if allSameDigit(cnpj) {
+		return false
+	}
+	return checkDigit(cnpj[:12], []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) == int(cnpj[12]-'0') &&
+		checkDigit(cnpj[:13], []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) == int(cnpj[13]-'0')
+}
+
+// checkDigit is the mod 11 check digit used by CPF and CNPJ
+func checkDigit(digits string, weights []int) int {
+	sum := 0
+	for i, weight := range weights {
+		sum += int(digits[i]-'0') * weight
+	}
+	if rest := sum % 11; rest >= 2 {
+		return 11 - rest
+	}
+	return 0
+}
+
+func allSameDigit(digits string) bool {
+	return strings.Count(digits, digits[:1]) == len(digits)
+}
+
+var (
+	pixPhonePattern  = regexp.MustCompile(`^\+55[1-9][0-9]{9,10}$`)
+	pixRandomPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+// detectPixKeyType follows the DICT key formats: CPF and CNPJ as digits,
+// email, phone as +55DDNUMBER and random keys as UUIDs
+func detectPixKeyType(key string) (PixKeyType, error) {
+	switch {
+	case pixPhonePattern.MatchString(key):
+		return PixKeyTypePhone, nil
+	case pixRandomPattern.MatchString(strings.ToLower(key)):
+		return PixKeyTypeRandom, nil
+	case strings.Contains(key, "@"):
+		if address, err := mail.ParseAddress(key); err == nil && address.Address == key && len(key) <= 77 {
+			return PixKeyTypeEmail, nil
+		}
+		return "", ErrInvalidPixKey
+	}
+
+	if documentType, _, err := parseDocument(key); err == nil {
+		if documentType == DocumentTypeCPF {
+			return PixKeyTypeCPF, nil
+		}
+		return PixKeyTypeCNPJ, nil
+	}
+	return "", ErrInvalidPixKey
+}
+
+func maskPixKey(key string, keyType PixKeyType) string {
+	switch keyType {
+	case PixKeyTypeCPF:
+		return maskDocument(onlyDigits(key), DocumentTypeCPF)
+	case PixKeyTypeCNPJ:
+		return maskDocument(onlyDigits(key), DocumentTypeCNPJ)
+	case PixKeyTypeEmail:
+		at := strings.LastIndex(key, "@")
+		return key[:1] + "***" + key[at:]
+	case PixKeyTypePhone:
+		return key[:5] + "*****" + key[len(key)-4:]
+	default:
+		return key[:8] + "-****-****-****-" + key[len(key)-4:]
+	}
+}
+
+// maskDocument keeps the middle digits, the way receipts usually show them:
+// ***.456.789-** and **.345.678/0001-**
+func maskDocument(digits string, documentType DocumentType) string {
+	if documentType == DocumentTypeCPF {
+		return "***." + digits[3:6] + "." + digits[6:9] + "-**"
+	}
+	return "**." + digits[2:5] + "." + digits[5:8] + "/" + digits[8:12] + "-**"
+}
+
+func onlyDigits(value string) string {
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
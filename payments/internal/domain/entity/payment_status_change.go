@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid payment change cursor")
+
+// PaymentStatusChange is an entry of the payment change feed: a payment was
+// created, changed status or had part of its amount refunded. Changes are
+// numbered by Sequence when they are saved, which may not be the order they
+// are committed in, and by Position once they are committed. Positions only
+// grow, which lets consumers resume the feed after the last change they
+// handled.
+type PaymentStatusChange struct {
+	Sequence  int64         `json:"sequence"`
+	Position  int64         `json:"position"`
+	PaymentID string        `json:"payment_id"`
+	OrderID   string        `json:"order_id"`
+	Status    PaymentStatus `json:"status"`
+	// PreviousStatus is empty for the change recorded when the payment is
+	// created
+	PreviousStatus PaymentStatus `json:"previous_status,omitempty"`
+	Amount         Money         `json:"amount"`
+	RefundedAmount Money         `json:"refunded_amount"`
+	OccurredAt     time.Time     `json:"occurred_at"`
+}
+
+// NewPaymentStatusChange describes the current state of payment, which was
+// previously in the previous status. The sequence is assigned when saved and
+// the position once committed.
+func NewPaymentStatusChange(payment *Payment, previous PaymentStatus) PaymentStatusChange {
+	return PaymentStatusChange{
+		PaymentID:      payment.ID,
+		OrderID:        payment.OrderID,
+		Status:         payment.Status,
+		PreviousStatus: previous,
+		Amount:         payment.Amount,
+		RefundedAmount: payment.refundedAmount(),
+		OccurredAt:     time.Now(),
+	}
+}
+
+// Cursor is the position of the change in the feed, given to consumers to
+// resume after it
+func (c PaymentStatusChange) Cursor() string {
+	return strconv.FormatInt(c.Position, 10)
+}
+
+// ParseCursor returns the position of the change a cursor points to. An empty
+// cursor points before the first change.
+func ParseCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	sequence, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || sequence < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return sequence, nil
+}
@@ -0,0 +1,99 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEventType is the kind of notification sent by the payment gateway
+type WebhookEventType string
+
+const (
+	WebhookPaymentApproved WebhookEventType = "payment.approved"
+	WebhookPaymentDeclined WebhookEventType = "payment.declined"
+	WebhookPaymentRefunded WebhookEventType = "payment.refunded"
+)
+
+type WebhookEventStatus string
+
+const (
+	// WebhookEventReceived is stored before the event is applied; an event
+	// left received (e.g. after a crash) is applied again when redelivered
+	WebhookEventReceived WebhookEventStatus = "received"
+	// WebhookEventProcessed means the event was applied to its payment, or
+	// found already applied
+	WebhookEventProcessed WebhookEventStatus = "processed"
+	// WebhookEventIgnored means the event cannot be applied, e.g. it names an
+	// unknown payment; Result tells why
+	WebhookEventIgnored WebhookEventStatus = "ignored"
+)
+
+var (
+	ErrEmptyWebhookEventID    = errors.New("webhook event id cannot be empty")
+	ErrDuplicateWebhookEvent  = errors.New("webhook event was already received")
+	ErrWebhookEventNotFound   = errors.New("webhook event not found")
+	ErrUnknownWebhookEvent    = errors.New("unknown webhook event type")
+	ErrWebhookEventNotApplied = errors.New("webhook event does not apply to the payment in its current status")
+	ErrWebhookEventClaimed    = errors.New("webhook event is being handled by another delivery")
+)
+
+// WebhookEvent is a notification received from the payment gateway, kept as
+// received for audit. EventID is the gateway ID of the event, which makes
+// redeliveries of the same event idempotent.
+type WebhookEvent struct {
+	ID        string           `json:"id"`
+	EventID   string           `json:"event_id"`
+	Type      WebhookEventType `json:"type"`
+	PaymentID string           `json:"payment_id,omitempty"`
+	// Payload is the raw request body and Signature its signature header
+	Payload     []byte             `json:"-"`
+	Signature   string             `json:"-"`
+	Status      WebhookEventStatus `json:"status"`
+	Result      string             `json:"result,omitempty"`
+	ReceivedAt  time.Time          `json:"received_at"`
+	ProcessedAt *time.Time         `json:"processed_at,omitempty"`
+	// ClaimedUntil is when the delivery applying the event gives it up, so
+	// that a delivery that stopped before finishing does not block the event
+	ClaimedUntil *time.Time `json:"-"`
+}
+
+func NewWebhookEvent(eventID string, eventType WebhookEventType, paymentID string, payload []byte, signature string) (*WebhookEvent, error) {
+	if eventID == "" {
+		return nil, ErrEmptyWebhookEventID
+	}
+
+	return &WebhookEvent{
+		ID:         uuid.New().String(),
+		EventID:    eventID,
+		Type:       eventType,
+		PaymentID:  paymentID,
+		Payload:    payload,
+		Signature:  signature,
+		Status:     WebhookEventReceived,
+		ReceivedAt: time.Now(),
+	}, nil
+}
+
+// Claim takes the event for the delivery applying it, until the given time
+func (e *WebhookEvent) Claim(until time.Time) {
+	e.ClaimedUntil = &until
+}
+
+func (e *WebhookEvent) MarkProcessed(now time.Time, result string) {
+	e.Status = WebhookEventProcessed
+	e.Result = result
+	e.ProcessedAt = &now
+}
+
+func (e *WebhookEvent) MarkIgnored(now time.Time, reason string) {
+	e.Status = WebhookEventIgnored
+	e.Result = reason
+	e.ProcessedAt = &now
+}
+
+// IsHandled tells whether the event needs no further processing
+func (e *WebhookEvent) IsHandled() bool {
+	return e.Status != WebhookEventReceived
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"payments/internal/domain/boleto"
+	"payments/internal/domain/entity"
+)
+
+var (
+	// ErrTimeout means the gateway did not answer in time. The outcome is
+	// unknown, so the same request must be retried with the same
+	// idempotency key rather than treated as declined.
+	ErrTimeout = errors.New("payment gateway timed out")
+	// ErrUnavailable means the gateway could not take the request at all
+	ErrUnavailable = errors.New("payment gateway unavailable")
+	// ErrUnsupportedMethod means no adapter is configured for the method
+	ErrUnsupportedMethod = errors.New("payment method not supported by the gateway")
+	// ErrOperationDeclined means the provider refused a capture, void or
+	// refund, so the payment keeps its status
+	ErrOperationDeclined = errors.New("payment gateway declined the operation")
+)
+
+type Status string
+
+const (
+	StatusApproved Status = "approved"
+	StatusDeclined Status = "declined"
+)
+
+// Request is what a gateway needs to charge (or only authorize) a payment.
+type Request struct {
+	PaymentID string
+	OrderID   string
+	// IdempotencyKey is sent to the provider so that a retried request after
+	// a timeout does not charge twice
+	IdempotencyKey string
+	Amount         entity.Money
+	Method         entity.PaymentMethod
+	CustomerEmail  string
+	CustomerName   string
+	// CardBIN is the first digits of the card number, for card payments
+	CardBIN string
+	// Card is the card as sent by the client, for card payments. It only
+	// travels to the provider, which answers with a token for it.
+	Card *entity.CardData
+	// Details are the safe details of the card, PIX key or boleto, if any
+	Details *entity.PaymentDetails
+	// Boleto is the boleto to register at the bank, for boleto payments
+	Boleto *boleto.Boleto
+	// AuthorizeOnly holds the amount instead of charging it
+	AuthorizeOnly bool
+}
+
+// OperationRequest is what a gateway needs to capture, void or refund a
+// payment it charged or authorized before.
+type OperationRequest struct {
+	PaymentID string
+	// TransactionID is the one the provider returned for the payment
+	TransactionID string
+	Method        entity.PaymentMethod
+	// IdempotencyKey is sent to the provider so that a retried operation
+	// after a timeout is not done twice
+	IdempotencyKey string
+	// Amount to capture or refund; a void releases the whole authorization
+	Amount entity.Money
+	Reason string
+}
+
+// Response is the decision of the provider. The codes are kept on the
+// payment for support and reconciliation.
+type Response struct {
+	Status        Status
+	TransactionID string
+	// ResponseCode is the provider code for the decision, e.g. "00" for
+	// approved or "51" for insufficient funds
+	ResponseCode      string
+	AuthorizationCode string
+	Message           string
+	// CardToken is the token the provider issued for the card, for card
+	// payments; it is what is stored instead of the card
+	CardToken string
+}
+
+func (r *Response) Approved() bool {
+	return r.Status == StatusApproved
+}
+
+// PaymentGateway charges payments with an external provider, and captures,
+// voids and refunds them there. A declined payment or operation is a
+// Response, not an error: errors mean the decision is unknown.
+type PaymentGateway interface {
+	Process(ctx context.Context, req Request) (*Response, error)
+	Capture(ctx context.Context, req OperationRequest) (*Response, error)
+	Void(ctx context.Context, req OperationRequest) (*Response, error)
+	Refund(ctx context.Context, req OperationRequest) (*Response, error)
+}
@@ -0,0 +1,195 @@
+package pix
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"payments/internal/domain/entity"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrInvalidMerchant     = errors.New("PIX merchant needs a key (up to 77 characters), a name (up to 25 characters) and a city (up to 15 characters)")
+	ErrUnsupportedCurrency = errors.New("PIX charges must be in BRL")
+	ErrFieldTooLong        = errors.New("BR Code field is longer than 99 characters")
+)
+
+// EMV field IDs of the BR Code, as defined in the BACEN "Manual do BR Code"
+const (
+	fieldPayloadFormat     = "00"
+	fieldPointOfInitiation = "01"
+	fieldMerchantAccount   = "26"
+	fieldMerchantCategory  = "52"
+	fieldCurrency          = "53"
+	fieldAmount            = "54"
+	fieldCountry           = "58"
+	fieldMerchantName      = "59"
+	fieldMerchantCity      = "60"
+	fieldAdditionalData    = "62"
+	fieldCRC               = "63"
+	subfieldGUI            = "00"
+	subfieldKey            = "01"
+	subfieldDescription    = "02"
+	subfieldReferenceLabel = "05"
+	pixGUI                 = "br.gov.bcb.pix"
+	singleUseInitiation    = "12"
+	currencyBRL            = "986"
+	maxFieldLength         = 99
+	maxKeyLength           = 77
+	maxTxIDLength          = 25
+	maxDescriptionLength   = 40
+	maxMerchantNameLength  = 25
+	maxMerchantCityLength  = 15
+)
+
+// Merchant is who receives the PIX payments
+type Merchant struct {
+	Key  string
+	Name string
+	City string
+}
+
+func (m Merchant) Validate() error {
+	name, city := normalize(m.Name), normalize(m.City)
+	if m.Key == "" || name == "" || city == "" || len(m.Key) > maxKeyLength ||
+		len(name) > maxMerchantNameLength || len(city) > maxMerchantCityLength {
+		return ErrInvalidMerchant
+	}
+	return nil
+}
+
+// Charge is a PIX charge of a single payment
+type Charge struct {
+	Merchant Merchant
+	Amount   entity.Money
+	// TxID identifies the payment in the PIX settlement; only letters and
+	// digits are kept, up to 25 characters
+	TxID        string
+	Description string
+}
+
+// EncodeBRCode builds the "copia e cola" payload of a charge: the EMV fields
+// of the BR Code followed by their CRC16. The same payload is rendered as
+// the QR code.
+func EncodeBRCode(charge Charge) (string, error) {
+	if err := charge.Merchant.Validate(); err != nil {
+		return "", err
+	}
+	if charge.Amount.Currency != "BRL" {
+		return "", ErrUnsupportedCurrency
+	}
+
+	var account fields
+	account.add(subfieldGUI, pixGUI)
+	account.add(subfieldKey, charge.Merchant.Key)
+	// The description gets whatever room the key leaves in the merchant
+	// account template, and is dropped when there is none
+	room := min(maxDescriptionLength, maxFieldLength-account.payload.Len()-4)
+	if description := truncate(normalize(charge.Description), max(room, 0)); description != "" {
+		account.add(subfieldDescription, description)
+	}
+
+	txID := truncate(alphanumeric(charge.TxID), maxTxIDLength)
+	if txID == "" {
+		txID = "***"
+	}
+	var additional fields
+	additional.add(subfieldReferenceLabel, txID)
+
+	var payload fields
+	payload.add(fieldPayloadFormat, "01")
+	payload.add(fieldPointOfInitiation, singleUseInitiation)
+	payload.add(fieldMerchantAccount, account.payload.String())
+	payload.add(fieldMerchantCategory, "0000")
+	payload.add(fieldCurrency, currencyBRL)
+	payload.add(fieldAmount, fmt.Sprintf("%d.%02d", charge.Amount.Amount/100, charge.Amount.Amount%100))
+	payload.add(fieldCountry, "BR")
+	payload.add(fieldMerchantName, normalize(charge.Merchant.Name))
+	payload.add(fieldMerchantCity, normalize(charge.Merchant.City))
+	payload.add(fieldAdditionalData, additional.payload.String())
+	for _, err := range []error{account.err, additional.err, payload.err} {
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// The CRC covers the whole payload including its own ID and length
+	payload.payload.WriteString(fieldCRC + "04")
+	return payload.payload.String() + CRC16(payload.payload.String()), nil
+}
+
+// CRC16 is the CRC16-CCITT (polynomial 0x1021, initial value 0xFFFF) the BR
+// Code ends with, as four uppercase hex digits
+func CRC16(payload string) string {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(payload); i++ {
+		crc ^= uint16(payload[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%04X", crc)
+}
+
+// field encodes an EMV field as ID, two digit length and value. Values
+// longer than 99 characters do not fit the length and are rejected.
+func field(id, value string) (string, error) {
+	if len(value) > maxFieldLength {
+		return "", fmt.Errorf("%w: field %s has %d", ErrFieldTooLong, id, len(value))
+	}
+	return fmt.Sprintf("%s%02d%s", id, len(value), value), nil
+}
+
+// fields appends EMV fields to a payload, keeping the first error
+type fields struct {
+	payload strings.Builder
+	err     error
+}
+
+func (f *fields) add(id, value string) {
+	if f.err != nil {
+		return
+	}
+	encoded, err := field(id, value)
+	if err != nil {
+		f.err = err
+		return
+	}
+	f.payload.WriteString(encoded)
+}
+
+// normalize keeps the payload ASCII, as banking apps expect: accents are
+// dropped and the text is upper cased
+func normalize(value string) string {
+	var ascii strings.Builder
+	for _, r := range norm.NFD.String(strings.TrimSpace(value)) {
+		if r < unicode.MaxASCII && !unicode.Is(unicode.Mn, r) {
+			ascii.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return ascii.String()
+}
+
+func alphanumeric(value string) string {
+	var kept strings.Builder
+	for _, r := range value {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			kept.WriteRune(r)
+		}
+	}
+	return kept.String()
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
+}
@@ -3,13 +3,91 @@ package repository
 import (
 	"context"
 	"payments/internal/domain/entity"
+	"time"
 )
 
 type PaymentRepository interface {
+	// Create returns entity.ErrDuplicateIdempotencyKey when another payment
+	// was already stored with the same idempotency key
 	Create(ctx context.Context, payment *entity.Payment) error
 	FindByID(ctx context.Context, id string) (*entity.Payment, error)
 	FindByOrderID(ctx context.Context, orderID string) ([]*entity.Payment, error)
+	// FindByIdempotencyKey returns entity.ErrPaymentNotFound when no payment
+	// was created with the key
+	FindByIdempotencyKey(ctx context.Context, key string) (*entity.Payment, error)
+	// FindByBoletoNumber returns entity.ErrPaymentNotFound when no boleto
+	// was issued with the number
+	FindByBoletoNumber(ctx context.Context, number string) (*entity.Payment, error)
+	// Update saves the payment over the status it was loaded with. It fails
+	// with entity.ErrPaymentConflict when another request changed the
+	// status first; the payment must then be loaded again.
 	Update(ctx context.Context, payment *entity.Payment) error
 	Delete(ctx context.Context, id string) error
-	List(ctx context.Context) ([]*entity.Payment, error)
+	// List returns the payments matching filter, sorted by created_at and id
+	List(ctx context.Context, filter PaymentFilter) ([]*entity.Payment, error)
+	// FindExpiredAuthorizations returns up to limit authorized payments whose
+	// capture window ended at or before now
+	FindExpiredAuthorizations(ctx context.Context, now time.Time, limit int) ([]*entity.Payment, error)
+	// FindExpiredCharges returns up to limit pending payments waiting for a
+	// confirmation whose charge expired at or before now
+	FindExpiredCharges(ctx context.Context, now time.Time, limit int) ([]*entity.Payment, error)
+}
+
+// PaymentFilter selects the payments returned by List; zero fields match
+// every payment. Rows come after the After cursor, up to Limit.
+type PaymentFilter struct {
+	OrderID       string
+	CustomerEmail string
+	Status        entity.PaymentStatus
+	Method        entity.PaymentMethod
+	CreatedFrom   time.Time // inclusive
+	CreatedTo     time.Time // exclusive
+	After         *entity.PageCursor
+	Ascending     bool
+	Limit         int
+}
+
+type RefundRepository interface {
+	// Create stores the refund and the payment it was applied to atomically.
+	// It fails with entity.ErrConcurrentRefund when another refund changed
+	// the payment first.
+	Create(ctx context.Context, refund *entity.Refund, payment *entity.Payment) error
+	FindByPaymentID(ctx context.Context, paymentID string) ([]*entity.Refund, error)
+}
+
+// OutboxRepository reads the events saved by the other repositories in the
+// same transaction as the payment they describe.
+type OutboxRepository interface {
+	// FindUnpublished returns pending events, oldest first
+	FindUnpublished(ctx context.Context, limit int) ([]entity.Event, error)
+	MarkPublished(ctx context.Context, id string, publishedAt time.Time) error
+	MarkFailed(ctx context.Context, id string, reason string) error
+}
+
+// WebhookEventRepository keeps the notifications received from the payment
+// gateway for audit and to ignore redeliveries
+type WebhookEventRepository interface {
+	// Create returns entity.ErrDuplicateWebhookEvent when an event with the
+	// same gateway event ID was already stored
+	Create(ctx context.Context, event *entity.WebhookEvent) error
+	// FindByEventID returns entity.ErrWebhookEventNotFound when no event was
+	// stored with the gateway event ID
+	FindByEventID(ctx context.Context, eventID string) (*entity.WebhookEvent, error)
+	// Claim saves the claim of a received event whose previous claim ended
+	// by now. It returns entity.ErrWebhookEventClaimed when the event was
+	// handled or another delivery still holds it.
+	Claim(ctx context.Context, event *entity.WebhookEvent, now time.Time) error
+	Update(ctx context.Context, event *entity.WebhookEvent) error
+}
+
+// PaymentStatusChangeRepository reads the change feed written by the other
+// repositories in the same transaction as the payment they describe
+type PaymentStatusChangeRepository interface {
+	// AssignPositions gives the next positions, in sequence order, to up to
+	// limit committed changes that have none, and returns how many it gave.
+	// Changes still being written are left for a later call.
+	AssignPositions(ctx context.Context, limit int) (int, error)
+	// FindAfter returns up to limit changes with a position greater than
+	// position, in position order
+	FindAfter(ctx context.Context, position int64, limit int) ([]entity.PaymentStatusChange, error)
 }
@@ -0,0 +1,142 @@
+package boleto
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"payments/internal/domain/boleto"
+	"payments/internal/domain/entity"
+)
+
+const htmlContentType = "text/html; charset=utf-8"
+
+// HTMLSlipRenderer renders boletos as a single HTML page, with the barcode
+// inlined as SVG, ready to be printed or saved as PDF by the browser
+type HTMLSlipRenderer struct {
+	tmpl *template.Template
+}
+
+func NewHTMLSlipRenderer() *HTMLSlipRenderer {
+	return &HTMLSlipRenderer{
+		tmpl: template.Must(template.New("slip").Parse(slipTemplate)),
+	}
+}
+
+type slipView struct {
+	BankCode            string
+	DigitableLine       string
+	BeneficiaryName     string
+	BeneficiaryDocument string
+	Number              string
+	Wallet              string
+	Agreement           string
+	DueDate             string
+	IssuedAt            string
+	Amount              string
+	PayerName           string
+	PayerDocument       string
+	Description         string
+	Barcode             template.HTML
+}
+
+func (r *HTMLSlipRenderer) Render(slip boleto.Slip) (string, []byte, error) {
+	barcode, err := itfSVG(slip.Boleto.Barcode)
+	if err != nil {
+		return "", nil, err
+	}
+
+	view := slipView{
+		BankCode:            boleto.BankCodeWithDigit(slip.Issuer.BankCode),
+		DigitableLine:       boleto.FormatDigitableLine(slip.Boleto.DigitableLine),
+		BeneficiaryName:     slip.Issuer.BeneficiaryName,
+		BeneficiaryDocument: slip.Issuer.BeneficiaryDocument,
+		Number:              slip.Boleto.Number,
+		Wallet:              slip.Issuer.Wallet,
+		Agreement:           slip.Issuer.Agreement,
+		DueDate:             slip.Boleto.DueDate.Format("02/01/2006"),
+		IssuedAt:            slip.IssuedAt.Format("02/01/2006"),
+		Amount:              formatReais(slip.Amount),
+		PayerName:           slip.PayerName,
+		PayerDocument:       slip.PayerDocument,
+		Description:         slip.Description,
+		// Built by itfSVG from digits only
+		Barcode: template.HTML(barcode),
+	}
+
+	var content bytes.Buffer
+	if err := r.tmpl.Execute(&content, view); err != nil {
+		return "", nil, fmt.Errorf("failed to render boleto slip: %w", err)
+	}
+	return htmlContentType, content.Bytes(), nil
+}
+
+// formatReais prints an amount the Brazilian way, e.g. R$ 1.234,56
+func formatReais(money entity.Money) string {
+	units := fmt.Sprint(money.Amount / 100)
+	var grouped strings.Builder
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+	return fmt.Sprintf("R$ %s,%02d", grouped.String(), money.Amount%100)
+}
+
+const slipTemplate = `<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<title>Boleto {{.Number}}</title>
+<style>
+  body { font-family: Arial, Helvetica, sans-serif; font-size: 11px; margin: 24px; }
+  .slip { width: 680px; }
+  .header { display: flex; align-items: flex-end; border-bottom: 2px solid #000; padding-bottom: 4px; }
+  .bank { font-size: 20px; font-weight: bold; border-left: 2px solid #000; border-right: 2px solid #000; padding: 0 12px; margin-right: 12px; }
+  .line { font-size: 15px; font-weight: bold; letter-spacing: 1px; }
+  table { width: 100%; border-collapse: collapse; }
+  td { border: 1px solid #000; padding: 2px 4px; vertical-align: top; }
+  .label { display: block; font-size: 9px; color: #333; }
+  .value { font-size: 12px; }
+  .right { text-align: right; }
+  .barcode { margin-top: 12px; width: 103mm; height: 13mm; }
+  .barcode svg { width: 100%; height: 100%; }
+  @media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<div class="slip">
+  <div class="header">
+    <span class="bank">{{.BankCode}}</span>
+    <span class="line">{{.DigitableLine}}</span>
+  </div>
+  <table>
+    <tr>
+      <td colspan="3"><span class="label">Local de pagamento</span><span class="value">Pagável em qualquer banco até o vencimento</span></td>
+      <td><span class="label">Vencimento</span><span class="value right">{{.DueDate}}</span></td>
+    </tr>
+    <tr>
+      <td colspan="3"><span class="label">Beneficiário</span><span class="value">{{.BeneficiaryName}}{{if .BeneficiaryDocument}} - {{.BeneficiaryDocument}}{{end}}</span></td>
+      <td><span class="label">Agência / Código do beneficiário</span><span class="value">{{.Agreement}}</span></td>
+    </tr>
+    <tr>
+      <td><span class="label">Data do documento</span><span class="value">{{.IssuedAt}}</span></td>
+      <td><span class="label">Carteira</span><span class="value">{{.Wallet}}</span></td>
+      <td><span class="label">Espécie</span><span class="value">R$</span></td>
+      <td><span class="label">Nosso número</span><span class="value">{{.Number}}</span></td>
+    </tr>
+    <tr>
+      <td colspan="3"><span class="label">Instruções</span><span class="value">{{.Description}}</span></td>
+      <td><span class="label">(=) Valor do documento</span><span class="value right">{{.Amount}}</span></td>
+    </tr>
+    <tr>
+      <td colspan="4"><span class="label">Pagador</span><span class="value">{{.PayerName}}{{if .PayerDocument}} - {{.PayerDocument}}{{end}}</span></td>
+    </tr>
+  </table>
+  <div class="barcode">{{.Barcode}}</div>
+</div>
+</body>
+</html>
+`
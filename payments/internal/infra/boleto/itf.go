@@ -0,0 +1,62 @@
+package boleto
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Bar widths of the ITF (Interleaved 2 of 5) symbology used by boletos: each
+// digit is five elements, two of them wide
+const (
+	narrow    = 1
+	wide      = 3
+	barHeight = 50
+)
+
+var itfDigits = [10]string{
+	"nnwwn", "wnnnw", "nwnnw", "wwnnn", "nnwnw",
+	"wnwnn", "nwwnn", "nnnww", "wnnwn", "nwnwn",
+}
+
+// itfSVG draws digits, which must be of even length, as an ITF barcode. Each
+// pair of digits is interleaved: the first one gives the widths of five bars
+// and the second one the widths of the spaces between them.
+func itfSVG(digits string) (string, error) {
+	if len(digits)%2 != 0 {
+		return "", fmt.Errorf("ITF barcodes need an even number of digits, got %d", len(digits))
+	}
+
+	// Start pattern: narrow bar, narrow space, narrow bar, narrow space
+	widths := []int{narrow, narrow, narrow, narrow}
+	for i := 0; i < len(digits); i += 2 {
+		bars, spaces := digits[i]-'0', digits[i+1]-'0'
+		if bars > 9 || spaces > 9 {
+			return "", fmt.Errorf("ITF barcodes only encode digits")
+		}
+		for j := 0; j < 5; j++ {
+			widths = append(widths, elementWidth(itfDigits[bars][j]), elementWidth(itfDigits[spaces][j]))
+		}
+	}
+	// Stop pattern: wide bar, narrow space, narrow bar
+	widths = append(widths, wide, narrow, narrow)
+
+	var rects strings.Builder
+	x := 0
+	for i, width := range widths {
+		// Even elements are bars, odd ones are spaces
+		if i%2 == 0 {
+			fmt.Fprintf(&rects, `<rect x="%d" y="0" width="%d" height="%d"/>`, x, width, barHeight)
+		}
+		x += width
+	}
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" preserveAspectRatio="none" shape-rendering="crispEdges">%s</svg>`,
+		x, barHeight, x, barHeight, rects.String()), nil
+}
+
+func elementWidth(element byte) int {
+	if element == 'w' {
+		return wide
+	}
+	return narrow
+}
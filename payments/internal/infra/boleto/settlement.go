@@ -0,0 +1,91 @@
+package boleto
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"payments/internal/domain/boleto"
+	"payments/internal/domain/entity"
+)
+
+// settlementHeader is the first line of a settlement file. Each following
+// line is a paid boleto: its number, the amount paid in centavos and when it
+// was paid (RFC 3339, or a date for payments settled by day).
+var settlementHeader = []string{"number", "amount_cents", "paid_at"}
+
+// ParseSettlementFile reads a CSV settlement file. Files exported by banks
+// in the CNAB layouts must be converted to it first.
+func ParseSettlementFile(r io.Reader, location *time.Location) ([]boleto.Settlement, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(settlementHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settlement header: %w", err)
+	}
+	for i, column := range settlementHeader {
+		if strings.TrimSpace(header[i]) != column {
+			return nil, fmt.Errorf("settlement file must start with %q", strings.Join(settlementHeader, ","))
+		}
+	}
+
+	var settlements []boleto.Settlement
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return settlements, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read settlement file: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		settlement, err := parseSettlement(record, location)
+		if err != nil {
+			return nil, fmt.Errorf("settlement file line %d: %w", line, err)
+		}
+		settlements = append(settlements, settlement)
+	}
+}
+
+func parseSettlement(record []string, location *time.Location) (boleto.Settlement, error) {
+	number := strings.TrimSpace(record[0])
+	if number == "" {
+		return boleto.Settlement{}, errors.New("boleto number is empty")
+	}
+
+	amount, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+	if err != nil || amount <= 0 {
+		return boleto.Settlement{}, fmt.Errorf("invalid amount %q", record[1])
+	}
+
+	paidAt, err := parsePaidAt(strings.TrimSpace(record[2]), location)
+	if err != nil {
+		return boleto.Settlement{}, err
+	}
+
+	return boleto.Settlement{
+		Number:     number,
+		PaidAmount: entity.NewMoney(amount, "BRL"),
+		PaidAt:     paidAt,
+	}, nil
+}
+
+func parsePaidAt(value string, location *time.Location) (time.Time, error) {
+	if paidAt, err := time.Parse(time.RFC3339, value); err == nil {
+		return paidAt, nil
+	}
+	if paidAt, err := time.ParseInLocation(time.DateOnly, value, location); err == nil {
+		return paidAt, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid payment date %q", value)
+}
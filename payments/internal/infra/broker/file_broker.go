@@ -0,0 +1,51 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"payments/internal/domain/entity"
+	"sync"
+)
+
+// FileBroker appends each event as one JSON line to a file, which can be
+// followed with `tail -f` while developing. Every write is synced to disk
+// before Publish returns.
+type FileBroker struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileBroker(path string) (*FileBroker, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileBroker{file: file}, nil
+}
+
+func (b *FileBroker) Publish(ctx context.Context, event entity.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.file.Write(line); err != nil {
+		return err
+	}
+	return b.file.Sync()
+}
+
+func (b *FileBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.file.Close()
+}
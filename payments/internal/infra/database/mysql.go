@@ -2,11 +2,14 @@ package database
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 type MySQL struct {
@@ -46,3 +49,14 @@ func (m *MySQL) Close() error {
 	}
 	return nil
 }
+
+// IsUnavailable tells whether err means the database could not be reached,
+// as opposed to a query it refused, so callers can report an outage that is
+// worth retrying
+func IsUnavailable(err error) bool {
+	var netErr *net.OpError
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.As(err, &netErr)
+}
@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"context"
+	"payments/internal/domain/gateway"
+)
+
+// Provider endpoints of each payment method
+const (
+	cardChargesPath   = "/v1/card/charges"
+	pixChargesPath    = "/v1/pix/charges"
+	boletoChargesPath = "/v1/boleto/charges"
+	payPalChargesPath = "/v1/paypal/charges"
+)
+
+// Operations on a charge, appended to its path after the transaction ID
+const (
+	captureOperation = "capture"
+	voidOperation    = "void"
+	refundOperation  = "refunds"
+)
+
+// chargeOperations captures, voids and refunds the charges of one payment
+// method. Every adapter embeds it with its own charges path.
+type chargeOperations struct {
+	client      *HTTPClient
+	chargesPath string
+}
+
+func (o chargeOperations) Capture(ctx context.Context, req gateway.OperationRequest) (*gateway.Response, error) {
+	return o.client.Operate(ctx, o.chargesPath, captureOperation, req)
+}
+
+func (o chargeOperations) Void(ctx context.Context, req gateway.OperationRequest) (*gateway.Response, error) {
+	return o.client.Operate(ctx, o.chargesPath, voidOperation, req)
+}
+
+func (o chargeOperations) Refund(ctx context.Context, req gateway.OperationRequest) (*gateway.Response, error) {
+	return o.client.Operate(ctx, o.chargesPath, refundOperation, req)
+}
+
+// CardAdapter charges credit and debit cards, or only authorizes them and
+// captures or voids the authorization later
+type CardAdapter struct {
+	chargeOperations
+	client *HTTPClient
+}
+
+func NewCardAdapter(client *HTTPClient) *CardAdapter {
+	return &CardAdapter{
+		chargeOperations: chargeOperations{client: client, chargesPath: cardChargesPath},
+		client:           client,
+	}
+}
+
+func (a *CardAdapter) Process(ctx context.Context, req gateway.Request) (*gateway.Response, error) {
+	body := newChargeRequest(req)
+	body.Card = &cardDetails{BIN: req.CardBIN}
+	if req.Card != nil {
+		body.Card.Number = req.Card.Number
+		body.Card.HolderName = req.Card.HolderName
+		body.Card.Expiry = req.Card.Expiry
+		body.Card.CVV = req.Card.CVV
+	}
+	if req.Details != nil {
+		body.Card.Brand = string(req.Details.CardBrand)
+		body.Card.Last4 = req.Details.CardLast4
+	}
+	return a.client.Charge(ctx, cardChargesPath, req.IdempotencyKey, body)
+}
+
+// PixAdapter creates PIX charges
+type PixAdapter struct {
+	chargeOperations
+	client *HTTPClient
+}
+
+func NewPixAdapter(client *HTTPClient) *PixAdapter {
+	return &PixAdapter{
+		chargeOperations: chargeOperations{client: client, chargesPath: pixChargesPath},
+		client:           client,
+	}
+}
+
+func (a *PixAdapter) Process(ctx context.Context, req gateway.Request) (*gateway.Response, error) {
+	body := newChargeRequest(req)
+	body.Pix = &pixDetails{}
+	if req.Details != nil {
+		body.Pix.KeyType = string(req.Details.PixKeyType)
+	}
+	return a.client.Charge(ctx, pixChargesPath, req.IdempotencyKey, body)
+}
+
+// BoletoAdapter registers boletos
+type BoletoAdapter struct {
+	chargeOperations
+	client *HTTPClient
+}
+
+func NewBoletoAdapter(client *HTTPClient) *BoletoAdapter {
+	return &BoletoAdapter{
+		chargeOperations: chargeOperations{client: client, chargesPath: boletoChargesPath},
+		client:           client,
+	}
+}
+
+func (a *BoletoAdapter) Process(ctx context.Context, req gateway.Request) (*gateway.Response, error) {
+	body := newChargeRequest(req)
+	body.Boleto = &boletoDetails{}
+	if req.Details != nil {
+		body.Boleto.DocumentType = string(req.Details.DocumentType)
+		body.Boleto.DueDate = req.Details.BoletoDueDate
+	}
+	if req.Boleto != nil {
+		body.Boleto.Number = req.Boleto.Number
+		body.Boleto.Barcode = req.Boleto.Barcode
+	}
+	return a.client.Charge(ctx, boletoChargesPath, req.IdempotencyKey, body)
+}
+
+// PayPalAdapter charges PayPal accounts, identified by the customer email
+type PayPalAdapter struct {
+	chargeOperations
+	client *HTTPClient
+}
+
+func NewPayPalAdapter(client *HTTPClient) *PayPalAdapter {
+	return &PayPalAdapter{
+		chargeOperations: chargeOperations{client: client, chargesPath: payPalChargesPath},
+		client:           client,
+	}
+}
+
+func (a *PayPalAdapter) Process(ctx context.Context, req gateway.Request) (*gateway.Response, error) {
+	body := newChargeRequest(req)
+	body.PayPal = &payPalDetails{PayerEmail: req.CustomerEmail}
+	return a.client.Charge(ctx, payPalChargesPath, req.IdempotencyKey, body)
+}
@@ -0,0 +1,187 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FakeOutcome string
+
+const (
+	FakeOutcomeApprove     FakeOutcome = "approve"
+	FakeOutcomeDecline     FakeOutcome = "decline"
+	FakeOutcomeTimeout     FakeOutcome = "timeout"
+	FakeOutcomeUnavailable FakeOutcome = "unavailable"
+)
+
+// FakeRule decides the outcome of the payments it matches. Every field that
+// is set must match; a rule with no conditions matches everything.
+type FakeRule struct {
+	Method entity.PaymentMethod `json:"method,omitempty"`
+	// CardBIN matches card numbers starting with it
+	CardBIN string `json:"card_bin,omitempty"`
+	// MinAmount matches amounts, in minor units, of at least this value
+	MinAmount int64 `json:"min_amount,omitempty"`
+	// Email matches the customer email, ignoring case
+	Email string `json:"email,omitempty"`
+
+	Outcome      FakeOutcome `json:"outcome"`
+	ResponseCode string      `json:"response_code,omitempty"`
+	Message      string      `json:"message,omitempty"`
+}
+
+func (r FakeRule) matches(req gateway.Request) bool {
+	if r.Method != "" && r.Method != req.Method {
+		return false
+	}
+	if r.CardBIN != "" && (req.CardBIN == "" || !strings.HasPrefix(req.CardBIN, r.CardBIN)) {
+		return false
+	}
+	if r.MinAmount > 0 && req.Amount.Amount < r.MinAmount {
+		return false
+	}
+	if r.Email != "" && !strings.EqualFold(r.Email, req.CustomerEmail) {
+		return false
+	}
+	return true
+}
+
+// DefaultFakeRules declines amounts of 10000.00 or more, and approves the rest
+func DefaultFakeRules() []FakeRule {
+	return []FakeRule{
+		{MinAmount: 1000000, Outcome: FakeOutcomeDecline, ResponseCode: "61", Message: "Exceeds withdrawal amount limit"},
+	}
+}
+
+// LoadFakeRules reads a JSON array of rules
+func LoadFakeRules(path string) ([]FakeRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gateway rules: %w", err)
+	}
+
+	var rules []FakeRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse gateway rules %s: %w", path, err)
+	}
+	for i, rule := range rules {
+		switch rule.Outcome {
+		case FakeOutcomeApprove, FakeOutcomeDecline, FakeOutcomeTimeout, FakeOutcomeUnavailable:
+		default:
+			return nil, fmt.Errorf("gateway rule %d: unknown outcome %q", i, rule.Outcome)
+		}
+	}
+	return rules, nil
+}
+
+// FakeGateway decides payments locally with configurable rules, so declines
+// and provider failures can be reproduced without a real provider. The first
+// matching rule wins; payments no rule matches are approved.
+type FakeGateway struct {
+	rules   []FakeRule
+	timeout time.Duration
+}
+
+// NewFakeGateway builds a fake gateway. Timeout rules wait for timeout (or
+// the request context) before failing with gateway.ErrTimeout.
+func NewFakeGateway(rules []FakeRule, timeout time.Duration) *FakeGateway {
+	return &FakeGateway{rules: rules, timeout: timeout}
+}
+
+func (g *FakeGateway) Process(ctx context.Context, req gateway.Request) (*gateway.Response, error) {
+	return g.decide(ctx, req)
+}
+
+// Capture, Void and Refund are decided by the rules too. Only the method and
+// amount of an operation are known, so rules on a card BIN or an email never
+// match them.
+func (g *FakeGateway) Capture(ctx context.Context, req gateway.OperationRequest) (*gateway.Response, error) {
+	return g.decide(ctx, operationCharge(req))
+}
+
+func (g *FakeGateway) Void(ctx context.Context, req gateway.OperationRequest) (*gateway.Response, error) {
+	return g.decide(ctx, operationCharge(req))
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, req gateway.OperationRequest) (*gateway.Response, error) {
+	return g.decide(ctx, operationCharge(req))
+}
+
+func operationCharge(req gateway.OperationRequest) gateway.Request {
+	return gateway.Request{
+		PaymentID:      req.PaymentID,
+		IdempotencyKey: req.IdempotencyKey,
+		Amount:         req.Amount,
+		Method:         req.Method,
+	}
+}
+
+func (g *FakeGateway) decide(ctx context.Context, req gateway.Request) (*gateway.Response, error) {
+	rule := FakeRule{Outcome: FakeOutcomeApprove}
+	for _, candidate := range g.rules {
+		if candidate.matches(req) {
+			rule = candidate
+			break
+		}
+	}
+
+	switch rule.Outcome {
+	case FakeOutcomeTimeout:
+		timer := time.NewTimer(g.timeout)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		return nil, gateway.ErrTimeout
+	case FakeOutcomeUnavailable:
+		return nil, gateway.ErrUnavailable
+	case FakeOutcomeDecline:
+		return &gateway.Response{
+			Status:        gateway.StatusDeclined,
+			TransactionID: uuid.New().String(),
+			ResponseCode:  valueOr(rule.ResponseCode, "05"),
+			Message:       valueOr(rule.Message, "Do not honor"),
+			CardToken:     newCardToken(req.Card),
+		}, nil
+	default:
+		return &gateway.Response{
+			Status:            gateway.StatusApproved,
+			TransactionID:     uuid.New().String(),
+			ResponseCode:      valueOr(rule.ResponseCode, "00"),
+			AuthorizationCode: newAuthorizationCode(),
+			Message:           valueOr(rule.Message, "Approved"),
+			CardToken:         newCardToken(req.Card),
+		}, nil
+	}
+}
+
+// newAuthorizationCode returns a six character code like the ones issuers
+// send with approvals
+func newAuthorizationCode() string {
+	return strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:6])
+}
+
+// newCardToken tokenizes the card like a provider would, or returns "" when
+// the request has no card
+func newCardToken(card *entity.CardData) string {
+	if card == nil {
+		return ""
+	}
+	return "tok_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
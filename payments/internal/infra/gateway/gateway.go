@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"fmt"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"time"
+)
+
+// Config selects and configures the payment gateway
+type Config struct {
+	// Kind is "fake" (rules evaluated in process) or "http"
+	Kind string
+	// RulesFile is the JSON file with the fake gateway rules; empty uses
+	// DefaultFakeRules
+	RulesFile string
+	// BaseURL is where the http gateway sends requests
+	BaseURL string
+	// Timeout bounds every gateway call
+	Timeout time.Duration
+}
+
+// New builds the gateway selected by cfg.Kind, routing every payment method
+// to its adapter.
+func New(cfg Config) (gateway.PaymentGateway, error) {
+	switch cfg.Kind {
+	case "", "fake":
+		rules := DefaultFakeRules()
+		if cfg.RulesFile != "" {
+			loaded, err := LoadFakeRules(cfg.RulesFile)
+			if err != nil {
+				return nil, err
+			}
+			rules = loaded
+		}
+		fake := NewFakeGateway(rules, cfg.Timeout)
+		return NewRouter(map[entity.PaymentMethod]gateway.PaymentGateway{
+			entity.PaymentMethodCreditCard: fake,
+			entity.PaymentMethodDebitCard:  fake,
+			entity.PaymentMethodPix:        fake,
+			entity.PaymentMethodBoleto:     fake,
+			entity.PaymentMethodPayPal:     fake,
+		}), nil
+	case "http":
+		client := NewHTTPClient(cfg.BaseURL, cfg.Timeout)
+		card := NewCardAdapter(client)
+		return NewRouter(map[entity.PaymentMethod]gateway.PaymentGateway{
+			entity.PaymentMethodCreditCard: card,
+			entity.PaymentMethodDebitCard:  card,
+			entity.PaymentMethodPix:        NewPixAdapter(client),
+			entity.PaymentMethodBoleto:     NewBoletoAdapter(client),
+			entity.PaymentMethodPayPal:     NewPayPalAdapter(client),
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown payment gateway %q", cfg.Kind)
+	}
+}
@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"strings"
+	"time"
+)
+
+// chargeRequest is the body sent to the provider. The common fields are
+// filled for every method; each adapter adds its own section.
+type chargeRequest struct {
+	PaymentID string               `json:"payment_id"`
+	OrderID   string               `json:"order_id"`
+	Method    entity.PaymentMethod `json:"method"`
+	Amount    entity.Money         `json:"amount"`
+	Capture   bool                 `json:"capture"`
+	Customer  customer             `json:"customer"`
+
+	Card   *cardDetails   `json:"card,omitempty"`
+	Pix    *pixDetails    `json:"pix,omitempty"`
+	Boleto *boletoDetails `json:"boleto,omitempty"`
+	PayPal *payPalDetails `json:"paypal,omitempty"`
+}
+
+type customer struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// cardDetails carries the full card, which the provider tokenizes; it is
+// only ever sent, never stored
+type cardDetails struct {
+	Number     string `json:"number,omitempty"`
+	HolderName string `json:"holder_name,omitempty"`
+	Expiry     string `json:"expiry,omitempty"`
+	CVV        string `json:"cvv,omitempty"`
+	BIN        string `json:"bin,omitempty"`
+	Brand      string `json:"brand,omitempty"`
+	Last4      string `json:"last4,omitempty"`
+}
+
+type pixDetails struct {
+	KeyType string `json:"key_type,omitempty"`
+}
+
+type boletoDetails struct {
+	DocumentType string     `json:"document_type,omitempty"`
+	DueDate      *time.Time `json:"due_date,omitempty"`
+	Number       string     `json:"number,omitempty"`
+	Barcode      string     `json:"barcode,omitempty"`
+}
+
+type payPalDetails struct {
+	PayerEmail string `json:"payer_email"`
+}
+
+// operationRequest is the body sent to capture, void or refund a charge
+type operationRequest struct {
+	PaymentID string               `json:"payment_id"`
+	Method    entity.PaymentMethod `json:"method"`
+	Amount    entity.Money         `json:"amount"`
+	Reason    string               `json:"reason,omitempty"`
+}
+
+type chargeResponse struct {
+	Status            gateway.Status `json:"status"`
+	TransactionID     string         `json:"transaction_id"`
+	ResponseCode      string         `json:"response_code"`
+	AuthorizationCode string         `json:"authorization_code,omitempty"`
+	Message           string         `json:"message,omitempty"`
+	CardToken         string         `json:"card_token,omitempty"`
+}
+
+// HTTPClient posts charges to a provider speaking JSON over HTTP, such as
+// the stub server in cmd/gateway-stub
+type HTTPClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTPClient(baseURL string, timeout time.Duration) *HTTPClient {
+	return &HTTPClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Charge posts body to path and reads the decision. Transport failures and
+// 5xx answers mean the outcome is unknown and are reported as
+// gateway.ErrTimeout (including 504) or gateway.ErrUnavailable.
+func (c *HTTPClient) Charge(ctx context.Context, path, idempotencyKey string, body chargeRequest) (*gateway.Response, error) {
+	return c.post(ctx, path, idempotencyKey, body)
+}
+
+// Operate posts a capture, void or refund of the charge at chargesPath, to
+// chargesPath/{transaction_id}/operation, and reads the decision like Charge.
+func (c *HTTPClient) Operate(ctx context.Context, chargesPath, operation string, req gateway.OperationRequest) (*gateway.Response, error) {
+	path := chargesPath + "/" + url.PathEscape(req.TransactionID) + "/" + operation
+	return c.post(ctx, path, req.IdempotencyKey, operationRequest{
+		PaymentID: req.PaymentID,
+		Method:    req.Method,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+	})
+}
+
+func (c *HTTPClient) post(ctx context.Context, path, idempotencyKey string, body any) (*gateway.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || isTimeout(err) {
+			return nil, fmt.Errorf("%w: %v", gateway.ErrTimeout, err)
+		}
+		return nil, fmt.Errorf("%w: %v", gateway.ErrUnavailable, err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode == http.StatusGatewayTimeout {
+		return nil, fmt.Errorf("%w: status %d", gateway.ErrTimeout, httpResp.StatusCode)
+	}
+	if httpResp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: status %d", gateway.ErrUnavailable, httpResp.StatusCode)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return nil, fmt.Errorf("payment gateway rejected the request: status %d: %s", httpResp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var decoded chargeResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode gateway response: %w", err)
+	}
+	if decoded.Status != gateway.StatusApproved && decoded.Status != gateway.StatusDeclined {
+		return nil, fmt.Errorf("unknown gateway status %q", decoded.Status)
+	}
+
+	return &gateway.Response{
+		Status:            decoded.Status,
+		TransactionID:     decoded.TransactionID,
+		ResponseCode:      decoded.ResponseCode,
+		AuthorizationCode: decoded.AuthorizationCode,
+		Message:           decoded.Message,
+		CardToken:         decoded.CardToken,
+	}, nil
+}
+
+func isTimeout(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
+
+func newChargeRequest(req gateway.Request) chargeRequest {
+	return chargeRequest{
+		PaymentID: req.PaymentID,
+		OrderID:   req.OrderID,
+		Method:    req.Method,
+		Amount:    req.Amount,
+		Capture:   !req.AuthorizeOnly,
+		Customer: customer{
+			Email: req.CustomerEmail,
+			Name:  req.CustomerName,
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+)
+
+// Router sends each payment to the adapter of its payment method
+type Router struct {
+	adapters map[entity.PaymentMethod]gateway.PaymentGateway
+}
+
+func NewRouter(adapters map[entity.PaymentMethod]gateway.PaymentGateway) *Router {
+	return &Router{adapters: adapters}
+}
+
+func (r *Router) Process(ctx context.Context, req gateway.Request) (*gateway.Response, error) {
+	adapter, err := r.adapter(req.Method)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.Process(ctx, req)
+}
+
+func (r *Router) Capture(ctx context.Context, req gateway.OperationRequest) (*gateway.Response, error) {
+	adapter, err := r.adapter(req.Method)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.Capture(ctx, req)
+}
+
+func (r *Router) Void(ctx context.Context, req gateway.OperationRequest) (*gateway.Response, error) {
+	adapter, err := r.adapter(req.Method)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.Void(ctx, req)
+}
+
+func (r *Router) Refund(ctx context.Context, req gateway.OperationRequest) (*gateway.Response, error) {
+	adapter, err := r.adapter(req.Method)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.Refund(ctx, req)
+}
+
+func (r *Router) adapter(method entity.PaymentMethod) (gateway.PaymentGateway, error) {
+	adapter, ok := r.adapters[method]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", gateway.ErrUnsupportedMethod, method)
+	}
+	return adapter, nil
+}
@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+)
+
+// NewStubHandler serves the provider API expected by HTTPClient, deciding
+// every charge, capture, void and refund with fake. It lets the http gateway be exercised locally.
+func NewStubHandler(fake *FakeGateway) http.Handler {
+	mux := http.NewServeMux()
+	for _, path := range []string{cardChargesPath, pixChargesPath, boletoChargesPath, payPalChargesPath} {
+		mux.Handle("POST "+path, stubCharge(fake))
+		mux.Handle("POST "+path+"/{transaction_id}/"+captureOperation, stubOperation(captureOperation, fake.Capture))
+		mux.Handle("POST "+path+"/{transaction_id}/"+voidOperation, stubOperation(voidOperation, fake.Void))
+		mux.Handle("POST "+path+"/{transaction_id}/"+refundOperation, stubOperation(refundOperation, fake.Refund))
+	}
+	return mux
+}
+
+func stubCharge(fake *FakeGateway) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body chargeRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		req := gateway.Request{
+			PaymentID:      body.PaymentID,
+			OrderID:        body.OrderID,
+			IdempotencyKey: r.Header.Get("Idempotency-Key"),
+			Amount:         body.Amount,
+			Method:         body.Method,
+			CustomerEmail:  body.Customer.Email,
+			CustomerName:   body.Customer.Name,
+			AuthorizeOnly:  !body.Capture,
+		}
+		if body.Card != nil {
+			req.CardBIN = body.Card.BIN
+			req.Card = &entity.CardData{
+				Number:     body.Card.Number,
+				HolderName: body.Card.HolderName,
+				Expiry:     body.Card.Expiry,
+				CVV:        body.Card.CVV,
+			}
+		}
+
+		response, err := fake.Process(r.Context(), req)
+		if err != nil {
+			writeStubError(w, err)
+			return
+		}
+
+		slog.Info("Stub gateway decided charge",
+			"payment_id", req.PaymentID,
+			"method", req.Method,
+			"status", response.Status,
+			"response_code", response.ResponseCode,
+		)
+
+		writeStubResponse(w, response)
+	}
+}
+
+func stubOperation(operation string, decide func(context.Context, gateway.OperationRequest) (*gateway.Response, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body operationRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		req := gateway.OperationRequest{
+			PaymentID:      body.PaymentID,
+			TransactionID:  r.PathValue("transaction_id"),
+			Method:         body.Method,
+			IdempotencyKey: r.Header.Get("Idempotency-Key"),
+			Amount:         body.Amount,
+			Reason:         body.Reason,
+		}
+
+		response, err := decide(r.Context(), req)
+		if err != nil {
+			writeStubError(w, err)
+			return
+		}
+
+		slog.Info("Stub gateway decided operation",
+			"operation", operation,
+			"payment_id", req.PaymentID,
+			"transaction_id", req.TransactionID,
+			"status", response.Status,
+		)
+
+		writeStubResponse(w, response)
+	}
+}
+
+func writeStubError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gateway.ErrTimeout) {
+		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusServiceUnavailable)
+}
+
+func writeStubResponse(w http.ResponseWriter, response *gateway.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chargeResponse{
+		Status:            response.Status,
+		TransactionID:     response.TransactionID,
+		ResponseCode:      response.ResponseCode,
+		AuthorizationCode: response.AuthorizationCode,
+		Message:           response.Message,
+		CardToken:         response.CardToken,
+	})
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"payments/internal/domain/boleto"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"payments/internal/domain/pix"
+	"payments/internal/infra/database"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// ErrorDomain identifies this service in the ErrorInfo of every error it
+// returns
+const ErrorDomain = "payments"
+
+// errorRule maps a domain error to the status returned to clients. Reason
+// goes into ErrorInfo and is stable, so clients can rely on it instead of
+// the message; field, when set, is reported as a BadRequest violation.
+type errorRule struct {
+	err    error
+	code   codes.Code
+	reason string
+	field  string
+}
+
+// errorRules are checked in order, so specific errors come before the ones
+// they wrap
+var errorRules = []errorRule{
+	// Invalid requests
+	{entity.ErrEmptyPaymentID, codes.InvalidArgument, "INVALID_ARGUMENT", "payment_id"},
+	{entity.ErrEmptyOrderID, codes.InvalidArgument, "INVALID_ARGUMENT", "order_id"},
+	{entity.ErrEmptyCustomerEmail, codes.InvalidArgument, "INVALID_ARGUMENT", "customer_email"},
+	{entity.ErrInvalidPaymentMethod, codes.InvalidArgument, "INVALID_ARGUMENT", "payment_method"},
+	{gateway.ErrUnsupportedMethod, codes.InvalidArgument, "PAYMENT_METHOD_NOT_SUPPORTED", "payment_method"},
+	{entity.ErrInvalidAmount, codes.InvalidArgument, "INVALID_AMOUNT", "money.amount"},
+	{boleto.ErrAmountTooLarge, codes.InvalidArgument, "INVALID_AMOUNT", "money.amount"},
+	{entity.ErrInvalidCurrency, codes.InvalidArgument, "INVALID_CURRENCY", "money.currency"},
+	{pix.ErrUnsupportedCurrency, codes.InvalidArgument, "INVALID_CURRENCY", "money.currency"},
+	{boleto.ErrUnsupportedCurrency, codes.InvalidArgument, "INVALID_CURRENCY", "money.currency"},
+	{entity.ErrCurrencyMismatch, codes.InvalidArgument, "INVALID_CURRENCY", "amount.currency"},
+	{entity.ErrInvalidMoney, codes.InvalidArgument, "INVALID_AMOUNT", ""},
+	{entity.ErrInvalidRefundAmount, codes.InvalidArgument, "INVALID_AMOUNT", "amount.amount"},
+	{entity.ErrRefundExceedsAmount, codes.InvalidArgument, "REFUND_EXCEEDS_AMOUNT", "amount.amount"},
+	{entity.ErrInvalidCaptureAmount, codes.InvalidArgument, "INVALID_AMOUNT", "amount.amount"},
+	{entity.ErrCaptureExceedsAuthorization, codes.InvalidArgument, "CAPTURE_EXCEEDS_AUTHORIZATION", "amount.amount"},
+	{entity.ErrInvalidCursor, codes.InvalidArgument, "INVALID_CURSOR", "cursor"},
+	{entity.ErrInvalidPageToken, codes.InvalidArgument, "INVALID_PAGE_TOKEN", "page_token"},
+	{entity.ErrInvalidPageSize, codes.InvalidArgument, "INVALID_ARGUMENT", "page_size"},
+	{entity.ErrInvalidOrderBy, codes.InvalidArgument, "INVALID_ARGUMENT", "order_by"},
+	{entity.ErrInvalidPaymentStatus, codes.InvalidArgument, "INVALID_ARGUMENT", "status"},
+
+	// Payment details; the reason is the same for all of them
+	{entity.ErrPaymentDetailsMismatch, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "payment_details"},
+	{entity.ErrInvalidCardNumber, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "card_details.card_number"},
+	{entity.ErrInvalidCardExpiry, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "card_details.expiry_date"},
+	{entity.ErrCardExpired, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "card_details.expiry_date"},
+	{entity.ErrInvalidCVV, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "card_details.cvv"},
+	{entity.ErrEmptyCardHolderName, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "card_details.card_holder_name"},
+	{entity.ErrInvalidPixKey, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "pix_details.pix_key"},
+	{entity.ErrInvalidDocument, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "boleto_details.customer_document"},
+	{entity.ErrInvalidBoletoDueDate, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "boleto_details.due_date"},
+	{entity.ErrBoletoDetailsRequired, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "boleto_details"},
+	{entity.ErrCardDetailsRequired, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "card_details"},
+	{entity.ErrPixDetailsRequired, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "pix_details"},
+	{entity.ErrInvalidPaymentDetails, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "payment_details"},
+
+	{entity.ErrPaymentNotFound, codes.NotFound, "PAYMENT_NOT_FOUND", ""},
+
+	// Operations the payment does not allow in its current status
+	{entity.ErrPaymentCannotBeCanceled, codes.FailedPrecondition, "PAYMENT_NOT_CANCELABLE", ""},
+	{entity.ErrPaymentCannotBeRefunded, codes.FailedPrecondition, "PAYMENT_NOT_REFUNDABLE", ""},
+	{entity.ErrAuthorizationNotSupported, codes.FailedPrecondition, "AUTHORIZATION_NOT_SUPPORTED", ""},
+	{entity.ErrPaymentNotAuthorized, codes.FailedPrecondition, "PAYMENT_NOT_AUTHORIZED", ""},
+	{entity.ErrAuthorizationExpired, codes.FailedPrecondition, "AUTHORIZATION_EXPIRED", ""},
+	{entity.ErrConfirmationNotSupported, codes.FailedPrecondition, "CONFIRMATION_NOT_SUPPORTED", ""},
+	{entity.ErrPaymentNotAwaiting, codes.FailedPrecondition, "PAYMENT_NOT_AWAITING_CONFIRMATION", ""},
+	{entity.ErrPaymentExpired, codes.FailedPrecondition, "PAYMENT_EXPIRED", ""},
+	{boleto.ErrNotIssued, codes.FailedPrecondition, "BOLETO_NOT_ISSUED", ""},
+	{gateway.ErrOperationDeclined, codes.FailedPrecondition, "GATEWAY_DECLINED", ""},
+
+	// Conflicts with another request
+	{entity.ErrIdempotencyKeyReused, codes.AlreadyExists, "IDEMPOTENCY_KEY_REUSED", "idempotency_key"},
+	{entity.ErrConcurrentRefund, codes.Aborted, "CONCURRENT_UPDATE", ""},
+	{entity.ErrPaymentConflict, codes.Aborted, "CONCURRENT_UPDATE", ""},
+
+	// Outages worth retrying
+	{gateway.ErrUnavailable, codes.Unavailable, "GATEWAY_UNAVAILABLE", ""},
+	{gateway.ErrTimeout, codes.Unavailable, "GATEWAY_UNAVAILABLE", ""},
+}
+
+// ToStatus turns an error of a use case into a gRPC status with an
+// ErrorInfo, and a BadRequest for invalid fields. Errors without a rule are
+// Internal, with a generic message so internals do not leak to clients.
+func ToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case database.IsUnavailable(err):
+		return newStatus(codes.Unavailable, "database unavailable", "DATABASE_UNAVAILABLE", "")
+	}
+
+	for _, rule := range errorRules {
+		if errors.Is(err, rule.err) {
+			return newStatus(rule.code, err.Error(), rule.reason, rule.field)
+		}
+	}
+
+	return newStatus(codes.Internal, "internal error", "INTERNAL", "")
+}
+
+func newStatus(code codes.Code, message, reason, field string) error {
+	st := status.New(code, message)
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain}}
+	if field != "" {
+		details = append(details, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+				Field:       field,
+				Description: message,
+			}},
+		})
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		slog.Error("Failed to attach error details", "error", err)
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -3,9 +3,12 @@ package handler
 import (
 	"context"
 	"log/slog"
+	"math"
 	"payments/internal/domain/entity"
+	"payments/internal/infra/qrcode"
 	"payments/internal/usecase"
 	pb "payments/proto"
+	"time"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -16,6 +19,12 @@ type PaymentServiceServer struct {
 	getPaymentUC     *usecase.GetPaymentUseCase
 	cancelPaymentUC  *usecase.CancelPaymentUseCase
 	listPaymentsUC   *usecase.ListPaymentsUseCase
+	refundPaymentUC  *usecase.RefundPaymentUseCase
+	capturePaymentUC *usecase.CapturePaymentUseCase
+	voidAuthUC       *usecase.VoidAuthorizationUseCase
+	confirmPaymentUC *usecase.ConfirmPaymentUseCase
+	boletoSlipUC     *usecase.GetBoletoSlipUseCase
+	watchPaymentsUC  *usecase.WatchPaymentsUseCase
 }
 
 func NewPaymentServiceServer(
@@ -23,42 +32,49 @@ func NewPaymentServiceServer(
 	getPaymentUC *usecase.GetPaymentUseCase,
 	cancelPaymentUC *usecase.CancelPaymentUseCase,
 	listPaymentsUC *usecase.ListPaymentsUseCase,
+	refundPaymentUC *usecase.RefundPaymentUseCase,
+	capturePaymentUC *usecase.CapturePaymentUseCase,
+	voidAuthUC *usecase.VoidAuthorizationUseCase,
+	confirmPaymentUC *usecase.ConfirmPaymentUseCase,
+	boletoSlipUC *usecase.GetBoletoSlipUseCase,
+	watchPaymentsUC *usecase.WatchPaymentsUseCase,
 ) *PaymentServiceServer {
 	return &PaymentServiceServer{
 		processPaymentUC: processPaymentUC,
 		getPaymentUC:     getPaymentUC,
 		cancelPaymentUC:  cancelPaymentUC,
 		listPaymentsUC:   listPaymentsUC,
+		refundPaymentUC:  refundPaymentUC,
+		capturePaymentUC: capturePaymentUC,
+		voidAuthUC:       voidAuthUC,
+		confirmPaymentUC: confirmPaymentUC,
+		boletoSlipUC:     boletoSlipUC,
+		watchPaymentsUC:  watchPaymentsUC,
 	}
 }
 
 func (s *PaymentServiceServer) ProcessPayment(ctx context.Context, req *pb.ProcessPaymentRequest) (*pb.ProcessPaymentResponse, error) {
-	slog.Info("Received ProcessPayment request", "order_id", req.OrderId, "amount", req.Amount)
+	slog.Info("Received ProcessPayment request", "order_id", req.OrderId)
 
-	// Convert proto payment method to entity payment method
-	paymentMethod := convertProtoPaymentMethodToEntity(req.PaymentMethod)
-
-	input := usecase.ProcessPaymentInput{
-		OrderID:       req.OrderId,
-		Amount:        req.Amount,
-		PaymentMethod: paymentMethod,
-		CustomerEmail: req.CustomerEmail,
-		CustomerName:  req.CustomerName,
-	}
-
-	output, err := s.processPaymentUC.Execute(ctx, input)
+	output, err := s.processPaymentUC.Execute(ctx, convertProcessPaymentRequest(req, false))
 	if err != nil {
 		slog.Error("Failed to process payment", "error", err)
-		return nil, err
+		return nil, ToStatus(err)
 	}
 
 	return &pb.ProcessPaymentResponse{
-		PaymentId:     output.PaymentID,
-		OrderId:       output.OrderID,
-		Status:        convertEntityStatusToProto(output.Status),
-		Message:       output.Message,
-		TransactionId: output.TransactionID,
-		CreatedAt:     timestamppb.Now(),
+		PaymentId:           output.PaymentID,
+		OrderId:             output.OrderID,
+		Status:              convertEntityStatusToProto(output.Status),
+		Message:             output.Message,
+		TransactionId:       output.TransactionID,
+		CreatedAt:           timestamppb.Now(),
+		GatewayResponseCode: output.GatewayResponseCode,
+		AuthorizationCode:   output.AuthorizationCode,
+		PixCharge:           convertPixChargeToProto(output.PixQRCode, output.ExpiresAt),
+		BoletoCharge: convertBoletoChargeToProto(
+			output.BoletoNumber, output.BoletoBarcode, output.BoletoDigitableLine, output.BoletoDueDate, output.ExpiresAt,
+		),
 	}, nil
 }
 
@@ -68,19 +84,10 @@ func (s *PaymentServiceServer) GetPayment(ctx context.Context, req *pb.GetPaymen
 	payment, err := s.getPaymentUC.Execute(ctx, req.PaymentId)
 	if err != nil {
 		slog.Error("Failed to get payment", "error", err)
-		return nil, err
-	}
-
-	return &pb.GetPaymentResponse{
-		PaymentId:     payment.ID,
-		OrderId:       payment.OrderID,
-		Amount:        payment.Amount,
-		PaymentMethod: convertEntityPaymentMethodToProto(payment.PaymentMethod),
-		Status:        convertEntityStatusToProto(payment.Status),
-		TransactionId: payment.TransactionID,
-		CreatedAt:     timestamppb.New(payment.CreatedAt),
-		UpdatedAt:     timestamppb.New(payment.UpdatedAt),
-	}, nil
+		return nil, ToStatus(err)
+	}
+
+	return convertEntityPaymentToProto(payment), nil
 }
 
 func (s *PaymentServiceServer) CancelPayment(ctx context.Context, req *pb.CancelPaymentRequest) (*pb.CancelPaymentResponse, error) {
@@ -94,10 +101,7 @@ func (s *PaymentServiceServer) CancelPayment(ctx context.Context, req *pb.Cancel
 	err := s.cancelPaymentUC.Execute(ctx, input)
 	if err != nil {
 		slog.Error("Failed to cancel payment", "error", err)
-		return &pb.CancelPaymentResponse{
-			Success: false,
-			Message: err.Error(),
-		}, nil
+		return nil, ToStatus(err)
 	}
 
 	return &pb.CancelPaymentResponse{
@@ -108,35 +112,354 @@ func (s *PaymentServiceServer) CancelPayment(ctx context.Context, req *pb.Cancel
 }
 
 func (s *PaymentServiceServer) ListPayments(ctx context.Context, req *pb.ListPaymentsRequest) (*pb.ListPaymentsResponse, error) {
-	slog.Info("Received ListPayments request", "order_id", req.OrderId)
+	slog.Info("Received ListPayments request", "order_id", req.OrderId, "page_size", req.PageSize)
 
-	payments, err := s.listPaymentsUC.Execute(ctx, req.OrderId)
+	input := usecase.ListPaymentsInput{
+		OrderID:       req.OrderId,
+		CustomerEmail: req.CustomerEmail,
+		PageSize:      int(req.PageSize),
+		PageToken:     req.PageToken,
+		OrderBy:       req.OrderBy,
+	}
+	if req.Status != pb.PaymentStatus_PAYMENT_STATUS_UNSPECIFIED {
+		input.Status = convertProtoStatusToEntity(req.Status)
+		if input.Status == "" {
+			return nil, ToStatus(entity.ErrInvalidPaymentStatus)
+		}
+	}
+	if req.PaymentMethod != pb.PaymentMethod_PAYMENT_METHOD_UNSPECIFIED {
+		// The converter falls back to credit card, which would filter the wrong payments
+		if req.PaymentMethod < 0 || req.PaymentMethod > pb.PaymentMethod_PAYMENT_METHOD_PAYPAL {
+			return nil, ToStatus(entity.ErrInvalidPaymentMethod)
+		}
+		input.Method = convertProtoPaymentMethodToEntity(req.PaymentMethod)
+	}
+	if req.CreatedFrom != nil {
+		input.CreatedFrom = req.CreatedFrom.AsTime()
+	}
+	if req.CreatedTo != nil {
+		input.CreatedTo = req.CreatedTo.AsTime()
+	}
+
+	output, err := s.listPaymentsUC.Execute(ctx, input)
 	if err != nil {
 		slog.Error("Failed to list payments", "error", err)
-		return nil, err
+		return nil, ToStatus(err)
 	}
 
 	var pbPayments []*pb.GetPaymentResponse
-	for _, payment := range payments {
-		pbPayments = append(pbPayments, &pb.GetPaymentResponse{
-			PaymentId:     payment.ID,
-			OrderId:       payment.OrderID,
-			Amount:        payment.Amount,
-			PaymentMethod: convertEntityPaymentMethodToProto(payment.PaymentMethod),
-			Status:        convertEntityStatusToProto(payment.Status),
-			TransactionId: payment.TransactionID,
-			CreatedAt:     timestamppb.New(payment.CreatedAt),
-			UpdatedAt:     timestamppb.New(payment.UpdatedAt),
-		})
+	for _, payment := range output.Payments {
+		pbPayments = append(pbPayments, convertEntityPaymentToProto(payment))
 	}
 
 	return &pb.ListPaymentsResponse{
-		Payments: pbPayments,
+		Payments:      pbPayments,
+		NextPageToken: output.NextPageToken,
+	}, nil
+}
+
+func (s *PaymentServiceServer) RefundPayment(ctx context.Context, req *pb.RefundPaymentRequest) (*pb.RefundPaymentResponse, error) {
+	slog.Info("Received RefundPayment request", "payment_id", req.PaymentId)
+
+	input := usecase.RefundPaymentInput{
+		PaymentID: req.PaymentId,
+		Reason:    req.Reason,
+	}
+	if req.Amount != nil {
+		amount := entity.NewMoney(req.Amount.Amount, req.Amount.Currency)
+		input.Amount = &amount
+	}
+
+	output, err := s.refundPaymentUC.Execute(ctx, input)
+	if err != nil {
+		slog.Error("Failed to refund payment", "error", err)
+		return nil, ToStatus(err)
+	}
+
+	return &pb.RefundPaymentResponse{
+		RefundId:        output.Refund.ID,
+		PaymentId:       output.Payment.ID,
+		Status:          convertEntityStatusToProto(output.Payment.Status),
+		Amount:          convertEntityMoneyToProto(output.Refund.Amount),
+		RefundedAmount:  convertEntityMoneyToProto(output.Payment.RefundedAmount),
+		RemainingAmount: convertEntityMoneyToProto(output.Payment.RefundableAmount()),
+		CreatedAt:       timestamppb.New(output.Refund.CreatedAt),
+	}, nil
+}
+
+func (s *PaymentServiceServer) AuthorizePayment(ctx context.Context, req *pb.ProcessPaymentRequest) (*pb.AuthorizePaymentResponse, error) {
+	slog.Info("Received AuthorizePayment request", "order_id", req.OrderId)
+
+	output, err := s.processPaymentUC.Execute(ctx, convertProcessPaymentRequest(req, true))
+	if err != nil {
+		slog.Error("Failed to authorize payment", "error", err)
+		return nil, ToStatus(err)
+	}
+
+	response := &pb.AuthorizePaymentResponse{
+		PaymentId:           output.PaymentID,
+		OrderId:             output.OrderID,
+		Status:              convertEntityStatusToProto(output.Status),
+		Message:             output.Message,
+		TransactionId:       output.TransactionID,
+		AuthorizedAmount:    convertEntityMoneyToProto(output.Amount),
+		GatewayResponseCode: output.GatewayResponseCode,
+		AuthorizationCode:   output.AuthorizationCode,
+	}
+	if output.AuthorizationExpiresAt != nil {
+		response.ExpiresAt = timestamppb.New(*output.AuthorizationExpiresAt)
+	}
+	return response, nil
+}
+
+func (s *PaymentServiceServer) CapturePayment(ctx context.Context, req *pb.CapturePaymentRequest) (*pb.CapturePaymentResponse, error) {
+	slog.Info("Received CapturePayment request", "payment_id", req.PaymentId)
+
+	input := usecase.CapturePaymentInput{
+		PaymentID: req.PaymentId,
+	}
+	if req.Amount != nil {
+		amount := entity.NewMoney(req.Amount.Amount, req.Amount.Currency)
+		input.Amount = &amount
+	}
+
+	payment, err := s.capturePaymentUC.Execute(ctx, input)
+	if err != nil {
+		slog.Error("Failed to capture payment", "error", err)
+		return nil, ToStatus(err)
+	}
+
+	return &pb.CapturePaymentResponse{
+		PaymentId:      payment.ID,
+		Status:         convertEntityStatusToProto(payment.Status),
+		CapturedAmount: convertEntityMoneyToProto(payment.CapturedAmount),
+		CapturedAt:     timestamppb.New(payment.UpdatedAt),
 	}, nil
 }
 
+func (s *PaymentServiceServer) VoidAuthorization(ctx context.Context, req *pb.VoidAuthorizationRequest) (*pb.VoidAuthorizationResponse, error) {
+	slog.Info("Received VoidAuthorization request", "payment_id", req.PaymentId)
+
+	payment, err := s.voidAuthUC.Execute(ctx, usecase.VoidAuthorizationInput{
+		PaymentID: req.PaymentId,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		slog.Error("Failed to void authorization", "error", err)
+		return nil, ToStatus(err)
+	}
+
+	return &pb.VoidAuthorizationResponse{
+		PaymentId: payment.ID,
+		Status:    convertEntityStatusToProto(payment.Status),
+		VoidedAt:  timestamppb.New(payment.UpdatedAt),
+	}, nil
+}
+
+func (s *PaymentServiceServer) ConfirmPayment(ctx context.Context, req *pb.ConfirmPaymentRequest) (*pb.ConfirmPaymentResponse, error) {
+	slog.Info("Received ConfirmPayment request", "payment_id", req.PaymentId)
+
+	payment, err := s.confirmPaymentUC.Execute(ctx, req.PaymentId)
+	if err != nil {
+		slog.Error("Failed to confirm payment", "error", err)
+		return nil, ToStatus(err)
+	}
+
+	return &pb.ConfirmPaymentResponse{
+		PaymentId:   payment.ID,
+		Status:      convertEntityStatusToProto(payment.Status),
+		ConfirmedAt: timestamppb.New(payment.UpdatedAt),
+	}, nil
+}
+
+func (s *PaymentServiceServer) GetBoletoSlip(ctx context.Context, req *pb.GetBoletoSlipRequest) (*pb.GetBoletoSlipResponse, error) {
+	slog.Info("Received GetBoletoSlip request", "payment_id", req.PaymentId)
+
+	output, err := s.boletoSlipUC.Execute(ctx, req.PaymentId)
+	if err != nil {
+		slog.Error("Failed to get boleto slip", "error", err)
+		return nil, ToStatus(err)
+	}
+
+	return &pb.GetBoletoSlipResponse{
+		PaymentId:   output.PaymentID,
+		ContentType: output.ContentType,
+		Content:     output.Content,
+	}, nil
+}
+
+func (s *PaymentServiceServer) WatchPayments(req *pb.WatchPaymentsRequest, stream pb.PaymentService_WatchPaymentsServer) error {
+	slog.Info("Received WatchPayments request", "cursor", req.Cursor)
+
+	err := s.watchPaymentsUC.Watch(stream.Context(), req.Cursor, func(change entity.PaymentStatusChange) error {
+		return stream.Send(convertStatusChangeToProto(change))
+	})
+	if err != nil && stream.Context().Err() == nil {
+		slog.Error("Failed to watch payments", "error", err)
+		return ToStatus(err)
+	}
+	return nil
+}
+
 // Helper functions to convert between proto and entity types
 
+func convertStatusChangeToProto(change entity.PaymentStatusChange) *pb.PaymentStatusChange {
+	return &pb.PaymentStatusChange{
+		Cursor:         change.Cursor(),
+		PaymentId:      change.PaymentID,
+		OrderId:        change.OrderID,
+		Status:         convertEntityStatusToProto(change.Status),
+		PreviousStatus: convertEntityStatusToProto(change.PreviousStatus),
+		Amount:         convertEntityMoneyToProto(change.Amount),
+		RefundedAmount: convertEntityMoneyToProto(change.RefundedAmount),
+		OccurredAt:     timestamppb.New(change.OccurredAt),
+	}
+}
+
+func convertProcessPaymentRequest(req *pb.ProcessPaymentRequest, authorizeOnly bool) usecase.ProcessPaymentInput {
+	input := usecase.ProcessPaymentInput{
+		OrderID:        req.OrderId,
+		Amount:         convertProtoMoneyToEntity(req.Money, req.Amount),
+		PaymentMethod:  convertProtoPaymentMethodToEntity(req.PaymentMethod),
+		CustomerEmail:  req.CustomerEmail,
+		CustomerName:   req.CustomerName,
+		IdempotencyKey: req.IdempotencyKey,
+		AuthorizeOnly:  authorizeOnly,
+	}
+
+	switch details := req.PaymentDetails.(type) {
+	case *pb.ProcessPaymentRequest_CardDetails:
+		input.Card = &entity.CardData{
+			Number:     details.CardDetails.GetCardNumber(),
+			HolderName: details.CardDetails.GetCardHolderName(),
+			Expiry:     details.CardDetails.GetExpiryDate(),
+			CVV:        details.CardDetails.GetCvv(),
+		}
+	case *pb.ProcessPaymentRequest_PixDetails:
+		input.PixKey = details.PixDetails.GetPixKey()
+	case *pb.ProcessPaymentRequest_BoletoDetails:
+		input.Boleto = &usecase.BoletoInput{Document: details.BoletoDetails.GetCustomerDocument()}
+		if dueDate := details.BoletoDetails.GetDueDate(); dueDate != nil {
+			due := dueDate.AsTime()
+			input.Boleto.DueDate = &due
+		}
+	}
+
+	return input
+}
+
+// convertPixChargeToProto renders the BR Code as a QR code image as well; a
+// payment without a BR Code has no charge
+func convertPixChargeToProto(qrCode string, expiresAt *time.Time) *pb.PixCharge {
+	if qrCode == "" {
+		return nil
+	}
+
+	charge := &pb.PixCharge{QrCode: qrCode}
+	if expiresAt != nil {
+		charge.ExpiresAt = timestamppb.New(*expiresAt)
+	}
+
+	image, err := qrcode.PNG(qrCode)
+	if err != nil {
+		slog.Error("Failed to render PIX QR code", "error", err)
+		return charge
+	}
+	charge.QrCodePng = image
+	return charge
+}
+
+// convertBoletoChargeToProto returns nil for payments without a boleto
+func convertBoletoChargeToProto(number, barcode, digitableLine string, dueDate, expiresAt *time.Time) *pb.BoletoCharge {
+	if number == "" {
+		return nil
+	}
+
+	charge := &pb.BoletoCharge{
+		Number:        number,
+		Barcode:       barcode,
+		DigitableLine: digitableLine,
+	}
+	if dueDate != nil {
+		charge.DueDate = timestamppb.New(*dueDate)
+	}
+	if expiresAt != nil {
+		charge.ExpiresAt = timestamppb.New(*expiresAt)
+	}
+	return charge
+}
+
+func convertEntityDetailsToProto(details *entity.PaymentDetails) *pb.PaymentDetailsSummary {
+	summary := &pb.PaymentDetailsSummary{
+		CardToken:      details.CardToken,
+		CardBrand:      string(details.CardBrand),
+		CardLast4:      details.CardLast4,
+		CardHolderName: details.CardHolderName,
+		CardExpiry:     details.CardExpiry,
+		PixKeyType:     string(details.PixKeyType),
+		MaskedPixKey:   details.MaskedPixKey,
+		DocumentType:   string(details.DocumentType),
+		MaskedDocument: details.MaskedDocument,
+	}
+	if details.BoletoDueDate != nil {
+		summary.BoletoDueDate = timestamppb.New(*details.BoletoDueDate)
+	}
+	return summary
+}
+
+func convertEntityPaymentToProto(payment *entity.Payment) *pb.GetPaymentResponse {
+	response := &pb.GetPaymentResponse{
+		PaymentId:      payment.ID,
+		OrderId:        payment.OrderID,
+		Amount:         convertEntityMoneyToLegacyAmount(payment.Amount),
+		Money:          convertEntityMoneyToProto(payment.Amount),
+		RefundedAmount: convertEntityMoneyToProto(payment.RefundedAmount),
+		CapturedAmount: convertEntityMoneyToProto(payment.CapturedAmount),
+		PaymentMethod:  convertEntityPaymentMethodToProto(payment.PaymentMethod),
+		Status:         convertEntityStatusToProto(payment.Status),
+		TransactionId:  payment.TransactionID,
+		CreatedAt:      timestamppb.New(payment.CreatedAt),
+		UpdatedAt:      timestamppb.New(payment.UpdatedAt),
+
+		GatewayResponseCode: payment.GatewayResponseCode,
+		AuthorizationCode:   payment.AuthorizationCode,
+		GatewayMessage:      payment.GatewayMessage,
+	}
+	if payment.AuthorizationExpiresAt != nil {
+		response.AuthorizationExpiresAt = timestamppb.New(*payment.AuthorizationExpiresAt)
+	}
+	if payment.Details != nil {
+		response.Details = convertEntityDetailsToProto(payment.Details)
+	}
+	response.PixCharge = convertPixChargeToProto(payment.PixQRCode, payment.ExpiresAt)
+	response.BoletoCharge = convertBoletoChargeToProto(
+		payment.BoletoNumber, payment.BoletoBarcode, payment.BoletoDigitableLine, payment.BoletoDueDate(), payment.ExpiresAt,
+	)
+	return response
+}
+
+// convertProtoMoneyToEntity prefers the Money message and falls back to the
+// deprecated double amount, read as BRL, for clients not yet migrated
+func convertProtoMoneyToEntity(money *pb.Money, legacyAmount float64) entity.Money {
+	if money != nil {
+		return entity.NewMoney(money.Amount, money.Currency)
+	}
+	return entity.NewMoney(int64(math.Round(legacyAmount*100)), "BRL")
+}
+
+func convertEntityMoneyToProto(money entity.Money) *pb.Money {
+	return &pb.Money{
+		Amount:   money.Amount,
+		Currency: money.Currency,
+	}
+}
+
+// convertEntityMoneyToLegacyAmount fills the deprecated double amount so
+// clients that do not read Money yet keep working
+func convertEntityMoneyToLegacyAmount(money entity.Money) float64 {
+	return float64(money.Amount) / 100
+}
+
 func convertProtoPaymentMethodToEntity(method pb.PaymentMethod) entity.PaymentMethod {
 	switch method {
 	case pb.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD:
@@ -171,6 +494,33 @@ func convertEntityPaymentMethodToProto(method entity.PaymentMethod) pb.PaymentMe
 	}
 }
 
+// convertProtoStatusToEntity returns an empty status for values it does
+// not know
+func convertProtoStatusToEntity(status pb.PaymentStatus) entity.PaymentStatus {
+	switch status {
+	case pb.PaymentStatus_PAYMENT_STATUS_PENDING:
+		return entity.PaymentStatusPending
+	case pb.PaymentStatus_PAYMENT_STATUS_PROCESSING:
+		return entity.PaymentStatusProcessing
+	case pb.PaymentStatus_PAYMENT_STATUS_APPROVED:
+		return entity.PaymentStatusApproved
+	case pb.PaymentStatus_PAYMENT_STATUS_DECLINED:
+		return entity.PaymentStatusDeclined
+	case pb.PaymentStatus_PAYMENT_STATUS_CANCELED:
+		return entity.PaymentStatusCanceled
+	case pb.PaymentStatus_PAYMENT_STATUS_REFUNDED:
+		return entity.PaymentStatusRefunded
+	case pb.PaymentStatus_PAYMENT_STATUS_PARTIALLY_REFUNDED:
+		return entity.PaymentStatusPartiallyRefunded
+	case pb.PaymentStatus_PAYMENT_STATUS_AUTHORIZED:
+		return entity.PaymentStatusAuthorized
+	case pb.PaymentStatus_PAYMENT_STATUS_EXPIRED:
+		return entity.PaymentStatusExpired
+	default:
+		return ""
+	}
+}
+
 func convertEntityStatusToProto(status entity.PaymentStatus) pb.PaymentStatus {
 	switch status {
 	case entity.PaymentStatusPending:
@@ -185,6 +535,12 @@ func convertEntityStatusToProto(status entity.PaymentStatus) pb.PaymentStatus {
 		return pb.PaymentStatus_PAYMENT_STATUS_CANCELED
 	case entity.PaymentStatusRefunded:
 		return pb.PaymentStatus_PAYMENT_STATUS_REFUNDED
+	case entity.PaymentStatusPartiallyRefunded:
+		return pb.PaymentStatus_PAYMENT_STATUS_PARTIALLY_REFUNDED
+	case entity.PaymentStatusAuthorized:
+		return pb.PaymentStatus_PAYMENT_STATUS_AUTHORIZED
+	case entity.PaymentStatusExpired:
+		return pb.PaymentStatus_PAYMENT_STATUS_EXPIRED
 	default:
 		return pb.PaymentStatus_PAYMENT_STATUS_UNSPECIFIED
 	}
@@ -0,0 +1,157 @@
+package security
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// AuthConfig lists who may call the payment service. ServiceTokens maps the
+// name of each calling service to the bearer token it sends in the
+// authorization metadata; AllowedClients lists the identities, common name
+// or DNS/URI SAN, of the client certificates accepted under mTLS.
+type AuthConfig struct {
+	ServiceTokens  map[string]string
+	AllowedClients []string
+}
+
+// Authenticator rejects calls that carry neither a known service token nor a
+// verified client certificate of an allowed identity
+type Authenticator struct {
+	tokens         map[[sha256.Size]byte]string
+	allowedClients []string
+}
+
+func NewAuthenticator(cfg AuthConfig) *Authenticator {
+	// Tokens are compared by digest, in constant time, so the comparison
+	// does not tell how much of a guess was right
+	tokens := make(map[[sha256.Size]byte]string, len(cfg.ServiceTokens))
+	for service, token := range cfg.ServiceTokens {
+		tokens[sha256.Sum256([]byte(token))] = service
+	}
+	return &Authenticator{
+		tokens:         tokens,
+		allowedClients: cfg.AllowedClients,
+	}
+}
+
+type callerKey struct{}
+
+// CallerFrom returns the service, or certificate identity, that made the call
+func CallerFrom(ctx context.Context) (string, bool) {
+	caller, ok := ctx.Value(callerKey{}).(string)
+	return caller, ok
+}
+
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		caller, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, callerKey{}, caller), req)
+	}
+}
+
+// StreamInterceptor protects streaming calls such as WatchPayments and the
+// reflection service
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		caller, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ss.Context(), callerKey{}, caller),
+		})
+	}
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, method string) (string, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token, ok := strings.CutPrefix(values[0], "Bearer ")
+			if !ok || token == "" {
+				slog.Warn("Rejected gRPC call with malformed authorization", "method", method)
+				return "", status.Error(codes.Unauthenticated, "malformed authorization metadata")
+			}
+			service, ok := a.service(token)
+			if !ok {
+				slog.Warn("Rejected gRPC call with unknown service token", "method", method)
+				return "", status.Error(codes.Unauthenticated, "invalid service token")
+			}
+			return service, nil
+		}
+	}
+
+	if identity, ok := a.certificateIdentity(ctx); ok {
+		return identity, nil
+	}
+
+	slog.Warn("Rejected unauthenticated gRPC call", "method", method)
+	return "", status.Error(codes.Unauthenticated, "a service token or an allowed client certificate is required")
+}
+
+func (a *Authenticator) service(token string) (string, bool) {
+	digest := sha256.Sum256([]byte(token))
+	for known, service := range a.tokens {
+		if subtle.ConstantTimeCompare(digest[:], known[:]) == 1 {
+			return service, true
+		}
+	}
+	return "", false
+}
+
+// certificateIdentity returns the allowed identity of the client
+// certificate. Only certificates verified during the handshake count, which
+// the server does when started with a client CA.
+func (a *Authenticator) certificateIdentity(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	for _, identity := range identities(tlsInfo.State.VerifiedChains[0][0]) {
+		if slices.Contains(a.allowedClients, identity) {
+			return identity, true
+		}
+	}
+	return "", false
+}
+
+func identities(cert *x509.Certificate) []string {
+	identities := make([]string, 0, 1+len(cert.DNSNames)+len(cert.URIs))
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	identities = append(identities, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return identities
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,152 @@
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// reloadCheckInterval is how often the certificate files are checked for
+// changes, so rotated certificates are picked up without a restart
+const reloadCheckInterval = 10 * time.Second
+
+// CertReloader holds the server certificate and, for mTLS, the CA pool that
+// client certificates are verified against. Both are read again when their
+// files change; connections already open keep the certificates of their
+// handshake.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  [3]time.Time
+	checkedAt time.Time
+}
+
+// NewCertReloader loads the certificate and key. caFile is optional: without
+// it clients are not asked for a certificate.
+func NewCertReloader(certFile, keyFile, caFile string) (*CertReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("a certificate and a key file are required")
+	}
+
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ServerConfig returns the TLS configuration of the gRPC server. Every
+// handshake takes the certificates loaded last, and clients must present a
+// certificate signed by the CA when one was given.
+func (r *CertReloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs := r.current()
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				// gRPC runs over HTTP/2 and refuses connections without ALPN
+				NextProtos: []string{"h2"},
+			}
+			if clientCAs != nil {
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+				config.ClientCAs = clientCAs
+			}
+			return config, nil
+		},
+	}
+}
+
+// MutualTLS tells whether clients must present a certificate
+func (r *CertReloader) MutualTLS() bool {
+	return r.caFile != ""
+}
+
+func (r *CertReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) >= reloadCheckInterval {
+		r.checkedAt = time.Now()
+		if modTimes, err := r.stat(); err == nil && modTimes != r.modTimes {
+			// A certificate and key written one after the other do not
+			// match for a moment; the previous pair is kept until they do
+			if err := r.reload(); err != nil {
+				slog.Warn("Failed to reload TLS certificates, keeping the previous ones", "error", err)
+			} else {
+				slog.Info("TLS certificates reloaded", "cert_file", r.certFile)
+			}
+		}
+	}
+
+	return r.cert, r.clientCAs
+}
+
+// reload reads the files; callers other than NewCertReloader hold r.mu
+func (r *CertReloader) reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.caFile != "" {
+		clientCAs, err = loadCertPool(r.caFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.checkedAt = time.Now()
+	return nil
+}
+
+func (r *CertReloader) stat() ([3]time.Time, error) {
+	var modTimes [3]time.Time
+	for i, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("CA file has no PEM certificates")
+	}
+	return pool, nil
+}
@@ -0,0 +1,14 @@
+package qrcode
+
+import (
+	qr "github.com/skip2/go-qrcode"
+)
+
+// imageSize is the side of the generated images, in pixels
+const imageSize = 256
+
+// PNG renders payload, such as a PIX BR Code, as a QR code image. Medium
+// error correction is what the BR Code manual recommends.
+func PNG(payload string) ([]byte, error) {
+	return qr.Encode(payload, qr.Medium, imageSize)
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payments/internal/domain/entity"
+	"time"
+)
+
+type OutboxRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewOutboxRepositoryMySQL(db *sql.DB) *OutboxRepositoryMySQL {
+	return &OutboxRepositoryMySQL{db: db}
+}
+
+// insertOutboxEvents writes events inside the transaction that changes the
+// payment, so an event exists if and only if the change was committed
+func insertOutboxEvents(ctx context.Context, tx *sql.Tx, events []entity.Event) error {
+	query := `
+		INSERT INTO outbox_events (id, aggregate_type, aggregate_id, event_type, payload, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	for _, event := range events {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			event.ID,
+			event.AggregateType,
+			event.AggregateID,
+			event.Type,
+			[]byte(event.Payload),
+			event.OccurredAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert outbox event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *OutboxRepositoryMySQL) FindUnpublished(ctx context.Context, limit int) ([]entity.Event, error) {
+	query := `
+		SELECT id, aggregate_type, aggregate_id, event_type, payload, occurred_at, attempts, last_error
+		FROM outbox_events
+		WHERE published_at IS NULL
+		ORDER BY occurred_at, id
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []entity.Event
+
+	for rows.Next() {
+		var event entity.Event
+		var payload []byte
+		var lastError sql.NullString
+
+		err := rows.Scan(
+			&event.ID,
+			&event.AggregateType,
+			&event.AggregateID,
+			&event.Type,
+			&payload,
+			&event.OccurredAt,
+			&event.Attempts,
+			&lastError,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+
+		event.Payload = payload
+		if lastError.Valid {
+			event.LastError = lastError.String
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (r *OutboxRepositoryMySQL) MarkPublished(ctx context.Context, id string, publishedAt time.Time) error {
+	query := "UPDATE outbox_events SET published_at = ?, attempts = attempts + 1 WHERE id = ?"
+
+	if _, err := r.db.ExecContext(ctx, query, publishedAt, id); err != nil {
+		return fmt.Errorf("failed to mark outbox event as published: %w", err)
+	}
+
+	return nil
+}
+
+func (r *OutboxRepositoryMySQL) MarkFailed(ctx context.Context, id string, reason string) error {
+	query := "UPDATE outbox_events SET attempts = attempts + 1, last_error = ? WHERE id = ?"
+
+	if _, err := r.db.ExecContext(ctx, query, reason, id); err != nil {
+		return fmt.Errorf("failed to record outbox publish failure: %w", err)
+	}
+
+	return nil
+}
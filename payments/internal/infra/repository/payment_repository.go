@@ -3,11 +3,20 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"payments/internal/domain/entity"
+	"payments/internal/domain/repository"
+	"strings"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
+// mysqlDuplicateEntry is the MySQL error number for unique key violations
+const mysqlDuplicateEntry = 1062
+
 type PaymentRepositoryMySQL struct {
 	db *sql.DB
 }
@@ -18,61 +27,109 @@ func NewPaymentRepositoryMySQL(db *sql.DB) *PaymentRepositoryMySQL {
 
 func (r *PaymentRepositoryMySQL) Create(ctx context.Context, payment *entity.Payment) error {
 	query := `
-		INSERT INTO payments (id, order_id, amount, payment_method, status, transaction_id, 
-		                     customer_email, customer_name, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO payments (id, order_id, amount_cents, currency, captured_cents, payment_method, status, transaction_id,
+		                     gateway_response_code, gateway_authorization_code, gateway_message,
+		                     customer_email, customer_name, created_at, updated_at, idempotency_key,
+		                     authorized_at, authorization_expires_at, payment_details, expires_at, pix_qr_code,
+		                     boleto_number, boleto_barcode, boleto_digitable_line)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(
+	details, err := marshalDetails(payment.Details)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
 		ctx,
 		query,
 		payment.ID,
 		payment.OrderID,
-		payment.Amount,
+		payment.Amount.Amount,
+		payment.Amount.Currency,
+		payment.CapturedAmount.Amount,
 		payment.PaymentMethod,
 		payment.Status,
 		payment.TransactionID,
+		nullString(payment.GatewayResponseCode),
+		nullString(payment.AuthorizationCode),
+		nullString(payment.GatewayMessage),
 		payment.CustomerEmail,
 		payment.CustomerName,
 		payment.CreatedAt,
 		payment.UpdatedAt,
+		nullString(payment.IdempotencyKey),
+		payment.AuthorizedAt,
+		payment.AuthorizationExpiresAt,
+		details,
+		payment.ExpiresAt,
+		nullString(payment.PixQRCode),
+		nullString(payment.BoletoNumber),
+		nullString(payment.BoletoBarcode),
+		nullString(payment.BoletoDigitableLine),
 	)
 
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		if strings.Contains(mysqlErr.Message, "idx_boleto_number") {
+			return entity.ErrDuplicateBoletoNumber
+		}
+		return entity.ErrDuplicateIdempotencyKey
+	}
+
 	if err != nil {
 		return fmt.Errorf("failed to create payment: %w", err)
 	}
 
+	if err := insertStatusChange(ctx, tx, entity.NewPaymentStatusChange(payment, "")); err != nil {
+		return err
+	}
+
+	if err := insertOutboxEvents(ctx, tx, payment.Events()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment: %w", err)
+	}
+	payment.ClearEvents()
+	payment.MarkSaved()
+
 	return nil
 }
 
 func (r *PaymentRepositoryMySQL) FindByID(ctx context.Context, id string) (*entity.Payment, error) {
-	query := `
-		SELECT id, order_id, amount, payment_method, status, transaction_id,
-		       customer_email, customer_name, created_at, updated_at, canceled_at, cancel_reason
-		FROM payments
-		WHERE id = ?
-	`
+	return r.findOne(ctx, "id", id)
+}
 
-	payment := &entity.Payment{}
-	var canceledAt sql.NullTime
-	var cancelReason sql.NullString
-	var transactionID sql.NullString
+func (r *PaymentRepositoryMySQL) FindByIdempotencyKey(ctx context.Context, key string) (*entity.Payment, error) {
+	return r.findOne(ctx, "idempotency_key", key)
+}
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&payment.ID,
-		&payment.OrderID,
-		&payment.Amount,
-		&payment.PaymentMethod,
-		&payment.Status,
-		&transactionID,
-		&payment.CustomerEmail,
-		&payment.CustomerName,
-		&payment.CreatedAt,
-		&payment.UpdatedAt,
-		&canceledAt,
-		&cancelReason,
-	)
+func (r *PaymentRepositoryMySQL) FindByBoletoNumber(ctx context.Context, number string) (*entity.Payment, error) {
+	return r.findOne(ctx, "boleto_number", number)
+}
+
+// paymentColumns are read in the order expected by scanPayment
+const paymentColumns = `
+	id, order_id, amount_cents, currency, refunded_cents, captured_cents, payment_method, status, transaction_id,
+	gateway_response_code, gateway_authorization_code, gateway_message,
+	customer_email, customer_name, created_at, updated_at, canceled_at, cancel_reason, idempotency_key,
+	authorized_at, authorization_expires_at, payment_details, expires_at, pix_qr_code,
+	boleto_number, boleto_barcode, boleto_digitable_line`
+
+// findOne loads the payment whose column matches value; column is never
+// taken from user input
+func (r *PaymentRepositoryMySQL) findOne(ctx context.Context, column, value string) (*entity.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE ` + column + ` = ?`
 
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, query, value))
 	if err == sql.ErrNoRows {
 		return nil, entity.ErrPaymentNotFound
 	}
@@ -81,102 +138,112 @@ func (r *PaymentRepositoryMySQL) FindByID(ctx context.Context, id string) (*enti
 		return nil, fmt.Errorf("failed to find payment: %w", err)
 	}
 
-	if transactionID.Valid {
-		payment.TransactionID = transactionID.String
-	}
-
-	if canceledAt.Valid {
-		payment.CanceledAt = &canceledAt.Time
-	}
-
-	if cancelReason.Valid {
-		payment.CancelReason = cancelReason.String
-	}
-
 	return payment, nil
 }
 
 func (r *PaymentRepositoryMySQL) FindByOrderID(ctx context.Context, orderID string) ([]*entity.Payment, error) {
-	query := `
-		SELECT id, order_id, amount, payment_method, status, transaction_id,
-		       customer_email, customer_name, created_at, updated_at, canceled_at, cancel_reason
-		FROM payments
-		WHERE order_id = ?
-		ORDER BY created_at DESC
-	`
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE order_id = ? ORDER BY created_at DESC`
 
 	rows, err := r.db.QueryContext(ctx, query, orderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find payments by order_id: %w", err)
 	}
-	defer rows.Close()
 
-	var payments []*entity.Payment
-
-	for rows.Next() {
-		payment := &entity.Payment{}
-		var canceledAt sql.NullTime
-		var cancelReason sql.NullString
-		var transactionID sql.NullString
-
-		err := rows.Scan(
-			&payment.ID,
-			&payment.OrderID,
-			&payment.Amount,
-			&payment.PaymentMethod,
-			&payment.Status,
-			&transactionID,
-			&payment.CustomerEmail,
-			&payment.CustomerName,
-			&payment.CreatedAt,
-			&payment.UpdatedAt,
-			&canceledAt,
-			&cancelReason,
-		)
+	return scanPayments(rows)
+}
 
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan payment: %w", err)
-		}
+// FindExpiredAuthorizations returns authorized payments whose capture window
+// ended before now, oldest first
+func (r *PaymentRepositoryMySQL) FindExpiredAuthorizations(ctx context.Context, now time.Time, limit int) ([]*entity.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments
+		WHERE status = ? AND authorization_expires_at <= ?
+		ORDER BY authorization_expires_at
+		LIMIT ?`
 
-		if transactionID.Valid {
-			payment.TransactionID = transactionID.String
-		}
+	rows, err := r.db.QueryContext(ctx, query, entity.PaymentStatusAuthorized, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired authorizations: %w", err)
+	}
 
-		if canceledAt.Valid {
-			payment.CanceledAt = &canceledAt.Time
-		}
+	return scanPayments(rows)
+}
 
-		if cancelReason.Valid {
-			payment.CancelReason = cancelReason.String
-		}
+// FindExpiredCharges returns pending charges, such as PIX or boletos, that
+// were not paid before now, oldest first
+func (r *PaymentRepositoryMySQL) FindExpiredCharges(ctx context.Context, now time.Time, limit int) ([]*entity.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments
+		WHERE status = ? AND expires_at <= ?
+		ORDER BY expires_at
+		LIMIT ?`
 
-		payments = append(payments, payment)
+	rows, err := r.db.QueryContext(ctx, query, entity.PaymentStatusPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired charges: %w", err)
 	}
 
-	return payments, nil
+	return scanPayments(rows)
 }
 
 func (r *PaymentRepositoryMySQL) Update(ctx context.Context, payment *entity.Payment) error {
 	query := `
 		UPDATE payments
-		SET status = ?, transaction_id = ?, updated_at = ?, canceled_at = ?, cancel_reason = ?
-		WHERE id = ?
+		SET status = ?, transaction_id = ?, captured_cents = ?, updated_at = ?, canceled_at = ?, cancel_reason = ?
+		WHERE id = ? AND status = ?
 	`
 
-	_, err := r.db.ExecContext(
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	previous, err := currentStatus(ctx, tx, payment.ID)
+	if err != nil {
+		return err
+	}
+	if previous != payment.SavedStatus() {
+		return fmt.Errorf("%w: status is %s, the payment was loaded as %s", entity.ErrPaymentConflict, previous, payment.SavedStatus())
+	}
+
+	result, err := tx.ExecContext(
 		ctx,
 		query,
 		payment.Status,
 		payment.TransactionID,
+		payment.CapturedAmount.Amount,
 		time.Now(),
 		payment.CanceledAt,
 		payment.CancelReason,
 		payment.ID,
+		payment.SavedStatus(),
 	)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
 
+	rows, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to update payment: %w", err)
 	}
+	if rows == 0 {
+		return entity.ErrPaymentConflict
+	}
+
+	if payment.Status != previous {
+		if err := insertStatusChange(ctx, tx, entity.NewPaymentStatusChange(payment, previous)); err != nil {
+			return err
+		}
+	}
+
+	if err := insertOutboxEvents(ctx, tx, payment.Events()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment: %w", err)
+	}
+	payment.ClearEvents()
+	payment.MarkSaved()
 
 	return nil
 }
@@ -192,61 +259,201 @@ func (r *PaymentRepositoryMySQL) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-func (r *PaymentRepositoryMySQL) List(ctx context.Context) ([]*entity.Payment, error) {
-	query := `
-		SELECT id, order_id, amount, payment_method, status, transaction_id,
-		       customer_email, customer_name, created_at, updated_at, canceled_at, cancel_reason
-		FROM payments
-		ORDER BY created_at DESC
-	`
+// List pages with a keyset on (created_at, id), served by the
+// idx_created_at_id index, so deep pages cost the same as the first one
+func (r *PaymentRepositoryMySQL) List(ctx context.Context, filter repository.PaymentFilter) ([]*entity.Payment, error) {
+	var conditions []string
+	var args []any
+	if filter.OrderID != "" {
+		conditions = append(conditions, "order_id = ?")
+		args = append(args, filter.OrderID)
+	}
+	if filter.CustomerEmail != "" {
+		conditions = append(conditions, "customer_email = ?")
+		args = append(args, filter.CustomerEmail)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Method != "" {
+		conditions = append(conditions, "payment_method = ?")
+		args = append(args, filter.Method)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.CreatedTo)
+	}
+
+	direction, comparison := "DESC", "<"
+	if filter.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if filter.After != nil {
+		conditions = append(conditions, "(created_at, id) "+comparison+" (?, ?)")
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+	}
 
-	rows, err := r.db.QueryContext(ctx, query)
+	query := `SELECT ` + paymentColumns + ` FROM payments`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at ` + direction + `, id ` + direction + ` LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list payments: %w", err)
 	}
+
+	return scanPayments(rows)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPayments(rows *sql.Rows) ([]*entity.Payment, error) {
 	defer rows.Close()
 
 	var payments []*entity.Payment
-
 	for rows.Next() {
-		payment := &entity.Payment{}
-		var canceledAt sql.NullTime
-		var cancelReason sql.NullString
-		var transactionID sql.NullString
-
-		err := rows.Scan(
-			&payment.ID,
-			&payment.OrderID,
-			&payment.Amount,
-			&payment.PaymentMethod,
-			&payment.Status,
-			&transactionID,
-			&payment.CustomerEmail,
-			&payment.CustomerName,
-			&payment.CreatedAt,
-			&payment.UpdatedAt,
-			&canceledAt,
-			&cancelReason,
-		)
-
+		payment, err := scanPayment(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan payment: %w", err)
 		}
+		payments = append(payments, payment)
+	}
 
-		if transactionID.Valid {
-			payment.TransactionID = transactionID.String
-		}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read payments: %w", err)
+	}
 
-		if canceledAt.Valid {
-			payment.CanceledAt = &canceledAt.Time
-		}
+	return payments, nil
+}
+
+// scanPayment reads a row selected with paymentColumns
+func scanPayment(row rowScanner) (*entity.Payment, error) {
+	payment := &entity.Payment{}
+	var canceledAt sql.NullTime
+	var cancelReason sql.NullString
+	var transactionID sql.NullString
+	var idempotencyKey sql.NullString
+	var authorizedAt sql.NullTime
+	var authorizationExpiresAt sql.NullTime
+	var gatewayResponseCode sql.NullString
+	var authorizationCode sql.NullString
+	var gatewayMessage sql.NullString
+	var details []byte
+	var expiresAt sql.NullTime
+	var pixQRCode sql.NullString
+	var boletoNumber sql.NullString
+	var boletoBarcode sql.NullString
+	var boletoDigitableLine sql.NullString
+
+	err := row.Scan(
+		&payment.ID,
+		&payment.OrderID,
+		&payment.Amount.Amount,
+		&payment.Amount.Currency,
+		&payment.RefundedAmount.Amount,
+		&payment.CapturedAmount.Amount,
+		&payment.PaymentMethod,
+		&payment.Status,
+		&transactionID,
+		&gatewayResponseCode,
+		&authorizationCode,
+		&gatewayMessage,
+		&payment.CustomerEmail,
+		&payment.CustomerName,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+		&canceledAt,
+		&cancelReason,
+		&idempotencyKey,
+		&authorizedAt,
+		&authorizationExpiresAt,
+		&details,
+		&expiresAt,
+		&pixQRCode,
+		&boletoNumber,
+		&boletoBarcode,
+		&boletoDigitableLine,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	payment.RefundedAmount.Currency = payment.Amount.Currency
+	payment.CapturedAmount.Currency = payment.Amount.Currency
+
+	if transactionID.Valid {
+		payment.TransactionID = transactionID.String
+	}
+
+	payment.GatewayResponseCode = gatewayResponseCode.String
+	payment.AuthorizationCode = authorizationCode.String
+	payment.GatewayMessage = gatewayMessage.String
+
+	if canceledAt.Valid {
+		payment.CanceledAt = &canceledAt.Time
+	}
+
+	if cancelReason.Valid {
+		payment.CancelReason = cancelReason.String
+	}
+
+	if idempotencyKey.Valid {
+		payment.IdempotencyKey = idempotencyKey.String
+	}
+
+	if authorizedAt.Valid {
+		payment.AuthorizedAt = &authorizedAt.Time
+	}
+
+	if authorizationExpiresAt.Valid {
+		payment.AuthorizationExpiresAt = &authorizationExpiresAt.Time
+	}
+
+	if expiresAt.Valid {
+		payment.ExpiresAt = &expiresAt.Time
+	}
+
+	payment.PixQRCode = pixQRCode.String
+	payment.BoletoNumber = boletoNumber.String
+	payment.BoletoBarcode = boletoBarcode.String
+	payment.BoletoDigitableLine = boletoDigitableLine.String
 
-		if cancelReason.Valid {
-			payment.CancelReason = cancelReason.String
+	if details != nil {
+		payment.Details = &entity.PaymentDetails{}
+		if err := json.Unmarshal(details, payment.Details); err != nil {
+			return nil, fmt.Errorf("failed to decode payment details: %w", err)
 		}
+	}
 
-		payments = append(payments, payment)
+	payment.MarkSaved()
+	return payment, nil
+}
+
+// marshalDetails stores payments without details as NULL
+func marshalDetails(details *entity.PaymentDetails) (any, error) {
+	if details == nil {
+		return nil, nil
 	}
 
-	return payments, nil
+	data, err := json.Marshal(details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payment details: %w", err)
+	}
+	return data, nil
+}
+
+// nullString stores empty optional values as NULL, so unique indexes only
+// apply to rows that actually carry a value
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
 }
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payments/internal/domain/entity"
+)
+
+type PaymentStatusChangeRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewPaymentStatusChangeRepositoryMySQL(db *sql.DB) *PaymentStatusChangeRepositoryMySQL {
+	return &PaymentStatusChangeRepositoryMySQL{db: db}
+}
+
+// currentStatus reads and locks the saved status of a payment, so the change
+// written by the transaction has the right previous status even when two
+// updates of the same payment race
+func currentStatus(ctx context.Context, tx *sql.Tx, paymentID string) (entity.PaymentStatus, error) {
+	var status entity.PaymentStatus
+	err := tx.QueryRowContext(ctx, "SELECT status FROM payments WHERE id = ? FOR UPDATE", paymentID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", entity.ErrPaymentNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read payment status: %w", err)
+	}
+	return status, nil
+}
+
+// insertStatusChange appends a change to the feed inside the transaction
+// that changes the payment, so the feed has a change if and only if the
+// change was committed
+func insertStatusChange(ctx context.Context, tx *sql.Tx, change entity.PaymentStatusChange) error {
+	query := `
+		INSERT INTO payment_status_changes (
+			payment_id, order_id, status, previous_status,
+			amount_cents, refunded_cents, currency, occurred_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		change.PaymentID,
+		change.OrderID,
+		change.Status,
+		nullString(string(change.PreviousStatus)),
+		change.Amount.Amount,
+		change.RefundedAmount.Amount,
+		change.Amount.Currency,
+		change.OccurredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert payment status change: %w", err)
+	}
+
+	return nil
+}
+
+// AssignPositions gives the next positions to committed changes without one.
+// The counter row serializes concurrent calls, and SKIP LOCKED passes over
+// rows whose insert is not committed yet, so a change committed late gets a
+// position after every change already found instead of before them.
+func (r *PaymentStatusChangeRepositoryMySQL) AssignPositions(ctx context.Context, limit int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var last int64
+	err = tx.QueryRowContext(ctx, "SELECT last_position FROM payment_status_change_positions WHERE id = 1 FOR UPDATE").Scan(&last)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read last payment change position: %w", err)
+	}
+
+	query := `
+		SELECT sequence
+		FROM payment_status_changes
+		WHERE position IS NULL
+		ORDER BY sequence
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find unpositioned payment changes: %w", err)
+	}
+	var sequences []int64
+	for rows.Next() {
+		var sequence int64
+		if err := rows.Scan(&sequence); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan payment change sequence: %w", err)
+		}
+		sequences = append(sequences, sequence)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read unpositioned payment changes: %w", err)
+	}
+	if len(sequences) == 0 {
+		return 0, nil
+	}
+
+	for _, sequence := range sequences {
+		last++
+		if _, err := tx.ExecContext(ctx, "UPDATE payment_status_changes SET position = ? WHERE sequence = ?", last, sequence); err != nil {
+			return 0, fmt.Errorf("failed to position payment change: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE payment_status_change_positions SET last_position = ? WHERE id = 1", last); err != nil {
+		return 0, fmt.Errorf("failed to save last payment change position: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit payment change positions: %w", err)
+	}
+	return len(sequences), nil
+}
+
+// FindAfter returns up to limit changes with a position greater than
+// position, in position order
+func (r *PaymentStatusChangeRepositoryMySQL) FindAfter(ctx context.Context, position int64, limit int) ([]entity.PaymentStatusChange, error) {
+	query := `
+		SELECT sequence, position, payment_id, order_id, status, previous_status,
+			amount_cents, refunded_cents, currency, occurred_at
+		FROM payment_status_changes
+		WHERE position > ?
+		ORDER BY position
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, position, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find payment status changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []entity.PaymentStatusChange
+	for rows.Next() {
+		var change entity.PaymentStatusChange
+		var previousStatus sql.NullString
+		var refundedCents int64
+
+		if err := rows.Scan(
+			&change.Sequence,
+			&change.Position,
+			&change.PaymentID,
+			&change.OrderID,
+			&change.Status,
+			&previousStatus,
+			&change.Amount.Amount,
+			&refundedCents,
+			&change.Amount.Currency,
+			&change.OccurredAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan payment status change: %w", err)
+		}
+
+		change.PreviousStatus = entity.PaymentStatus(previousStatus.String)
+		change.RefundedAmount = entity.NewMoney(refundedCents, change.Amount.Currency)
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read payment status changes: %w", err)
+	}
+
+	return changes, nil
+}
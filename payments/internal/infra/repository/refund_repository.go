@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payments/internal/domain/entity"
+	"time"
+)
+
+type RefundRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewRefundRepositoryMySQL(db *sql.DB) *RefundRepositoryMySQL {
+	return &RefundRepositoryMySQL{db: db}
+}
+
+func (r *RefundRepositoryMySQL) Create(ctx context.Context, refund *entity.Refund, payment *entity.Payment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	previous, err := currentStatus(ctx, tx, payment.ID)
+	if err != nil {
+		return err
+	}
+
+	// Only apply the refund over the refunded total it was computed from,
+	// so two concurrent partial refunds cannot exceed the payment amount
+	previousRefunded := payment.RefundedAmount.Amount - refund.Amount.Amount
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE payments
+		 SET status = ?, refunded_cents = ?, updated_at = ?
+		 WHERE id = ? AND refunded_cents = ?`,
+		payment.Status,
+		payment.RefundedAmount.Amount,
+		time.Now(),
+		payment.ID,
+		previousRefunded,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update refunded payment: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update refunded payment: %w", err)
+	}
+	if rows == 0 {
+		return entity.ErrConcurrentRefund
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO refunds (id, payment_id, amount_cents, currency, reason, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		refund.ID,
+		refund.PaymentID,
+		refund.Amount.Amount,
+		refund.Amount.Currency,
+		refund.Reason,
+		refund.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	// Partial refunds are reported too, even when the status stays the same
+	if err := insertStatusChange(ctx, tx, entity.NewPaymentStatusChange(payment, previous)); err != nil {
+		return err
+	}
+
+	if err := insertOutboxEvents(ctx, tx, payment.Events()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit refund: %w", err)
+	}
+	payment.ClearEvents()
+	payment.MarkSaved()
+
+	return nil
+}
+
+func (r *RefundRepositoryMySQL) FindByPaymentID(ctx context.Context, paymentID string) ([]*entity.Refund, error) {
+	query := `
+		SELECT id, payment_id, amount_cents, currency, reason, created_at
+		FROM refunds
+		WHERE payment_id = ?
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refunds by payment_id: %w", err)
+	}
+	defer rows.Close()
+
+	var refunds []*entity.Refund
+
+	for rows.Next() {
+		refund := &entity.Refund{}
+		var reason sql.NullString
+
+		err := rows.Scan(
+			&refund.ID,
+			&refund.PaymentID,
+			&refund.Amount.Amount,
+			&refund.Amount.Currency,
+			&reason,
+			&refund.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+
+		if reason.Valid {
+			refund.Reason = reason.String
+		}
+
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, nil
+}
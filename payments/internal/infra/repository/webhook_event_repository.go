@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"payments/internal/domain/entity"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type WebhookEventRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewWebhookEventRepositoryMySQL(db *sql.DB) *WebhookEventRepositoryMySQL {
+	return &WebhookEventRepositoryMySQL{db: db}
+}
+
+func (r *WebhookEventRepositoryMySQL) Create(ctx context.Context, event *entity.WebhookEvent) error {
+	query := `
+		INSERT INTO webhook_events (
+			id, event_id, event_type, payment_id, payload, signature,
+			status, claimed_until, result, received_at, processed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		event.ID,
+		event.EventID,
+		event.Type,
+		nullString(event.PaymentID),
+		event.Payload,
+		event.Signature,
+		event.Status,
+		event.ClaimedUntil,
+		event.Result,
+		event.ReceivedAt,
+		event.ProcessedAt,
+	)
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return entity.ErrDuplicateWebhookEvent
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to create webhook event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookEventRepositoryMySQL) FindByEventID(ctx context.Context, eventID string) (*entity.WebhookEvent, error) {
+	query := `
+		SELECT id, event_id, event_type, payment_id, payload, signature,
+			status, claimed_until, result, received_at, processed_at
+		FROM webhook_events
+		WHERE event_id = ?
+	`
+
+	var event entity.WebhookEvent
+	var paymentID, result sql.NullString
+	var claimedUntil, processedAt sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, query, eventID).Scan(
+		&event.ID,
+		&event.EventID,
+		&event.Type,
+		&paymentID,
+		&event.Payload,
+		&event.Signature,
+		&event.Status,
+		&claimedUntil,
+		&result,
+		&event.ReceivedAt,
+		&processedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrWebhookEventNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook event: %w", err)
+	}
+
+	event.PaymentID = paymentID.String
+	event.Result = result.String
+	if claimedUntil.Valid {
+		event.ClaimedUntil = &claimedUntil.Time
+	}
+	if processedAt.Valid {
+		event.ProcessedAt = &processedAt.Time
+	}
+
+	return &event, nil
+}
+
+// Claim checks and takes the claim in one statement, so only one of the
+// deliveries arriving at once gets it
+func (r *WebhookEventRepositoryMySQL) Claim(ctx context.Context, event *entity.WebhookEvent, now time.Time) error {
+	query := `
+		UPDATE webhook_events
+		SET claimed_until = ?
+		WHERE id = ? AND status = ? AND (claimed_until IS NULL OR claimed_until <= ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, event.ClaimedUntil, event.ID, entity.WebhookEventReceived, now)
+	if err != nil {
+		return fmt.Errorf("failed to claim webhook event: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to claim webhook event: %w", err)
+	}
+	if rows == 0 {
+		return entity.ErrWebhookEventClaimed
+	}
+
+	return nil
+}
+
+func (r *WebhookEventRepositoryMySQL) Update(ctx context.Context, event *entity.WebhookEvent) error {
+	query := `
+		UPDATE webhook_events
+		SET payment_id = ?, status = ?, result = ?, processed_at = ?
+		WHERE id = ?
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		nullString(event.PaymentID),
+		event.Status,
+		event.Result,
+		event.ProcessedAt,
+		event.ID,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update webhook event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"payments/internal/domain/entity"
+	"payments/internal/usecase"
+	"time"
+)
+
+const (
+	// GatewayPath receives the notifications of the payment gateway
+	GatewayPath = "/webhooks/gateway"
+
+	maxBodyBytes = 1 << 20
+)
+
+// Handler serves the webhook endpoint called by the payment gateway
+type Handler struct {
+	verifier *Verifier
+	handleUC *usecase.HandleWebhookUseCase
+}
+
+func NewHandler(verifier *Verifier, handleUC *usecase.HandleWebhookUseCase) *Handler {
+	return &Handler{
+		verifier: verifier,
+		handleUC: handleUC,
+	}
+}
+
+// Routes returns the routes of the webhook listener
+func (h *Handler) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+GatewayPath, h.HandleGatewayEvent)
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// gatewayEvent is the notification body sent by the gateway
+type gatewayEvent struct {
+	ID        string                  `json:"id"`
+	Type      entity.WebhookEventType `json:"type"`
+	CreatedAt time.Time               `json:"created_at"`
+	Data      struct {
+		PaymentID     string        `json:"payment_id"`
+		TransactionID string        `json:"transaction_id"`
+		Amount        *entity.Money `json:"amount,omitempty"`
+		ResponseCode  string        `json:"response_code,omitempty"`
+		Message       string        `json:"message,omitempty"`
+	} `json:"data"`
+}
+
+type eventResponse struct {
+	EventID   string                    `json:"event_id"`
+	Status    entity.WebhookEventStatus `json:"status"`
+	Result    string                    `json:"result,omitempty"`
+	Duplicate bool                      `json:"duplicate"`
+}
+
+// HandleGatewayEvent verifies and applies a gateway notification. Events are
+// answered with 200 once stored, even when they cannot be applied, so the
+// gateway only redelivers on 5xx. A delivery arriving while another applies
+// the same event gets 503, to be delivered again once that one finishes.
+func (h *Handler) HandleGatewayEvent(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	signature := r.Header.Get(SignatureHeader)
+	if err := h.verifier.Verify(signature, body, time.Now()); err != nil {
+		slog.Warn("Rejected webhook request", "remote_addr", r.RemoteAddr, "error", err)
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var event gatewayEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid event body")
+		return
+	}
+	if event.ID == "" || event.Type == "" {
+		respondWithError(w, http.StatusBadRequest, "event id and type are required")
+		return
+	}
+	if event.Data.Amount != nil {
+		money := entity.NewMoney(event.Data.Amount.Amount, event.Data.Amount.Currency)
+		if err := money.Validate(); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		event.Data.Amount = &money
+	}
+
+	output, err := h.handleUC.Execute(r.Context(), usecase.HandleWebhookInput{
+		EventID:       event.ID,
+		Type:          event.Type,
+		PaymentID:     event.Data.PaymentID,
+		TransactionID: event.Data.TransactionID,
+		Amount:        event.Data.Amount,
+		Message:       event.Data.Message,
+		Payload:       body,
+		Signature:     signature,
+	})
+	if errors.Is(err, entity.ErrWebhookEventClaimed) {
+		respondWithError(w, http.StatusServiceUnavailable, "event is being handled by another delivery")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to handle event")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, eventResponse{
+		EventID:   output.Event.EventID,
+		Status:    output.Event.Status,
+		Result:    output.Event.Result,
+		Duplicate: output.Duplicate,
+	})
+}
+
+func respondWithError(w http.ResponseWriter, code int, message string) {
+	respondWithJSON(w, code, map[string]string{"error": message})
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		slog.Error("Failed to write webhook response", "error", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"payments/internal/domain/entity"
 	"payments/internal/domain/repository"
 )
 
@@ -24,7 +25,7 @@ type CancelPaymentInput struct {
 
 func (uc *CancelPaymentUseCase) Execute(ctx context.Context, input CancelPaymentInput) error {
 	if input.PaymentID == "" {
-		return fmt.Errorf("payment_id cannot be empty")
+		return entity.ErrEmptyPaymentID
 	}
 
 	slog.Info("Canceling payment", "payment_id", input.PaymentID, "reason", input.Reason)
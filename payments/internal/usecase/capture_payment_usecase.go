@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"payments/internal/domain/repository"
+	"time"
+)
+
+type CapturePaymentUseCase struct {
+	paymentRepo repository.PaymentRepository
+	gateway     gateway.PaymentGateway
+}
+
+func NewCapturePaymentUseCase(paymentRepo repository.PaymentRepository, paymentGateway gateway.PaymentGateway) *CapturePaymentUseCase {
+	return &CapturePaymentUseCase{
+		paymentRepo: paymentRepo,
+		gateway:     paymentGateway,
+	}
+}
+
+type CapturePaymentInput struct {
+	PaymentID string
+	// Amount to capture; nil captures the whole authorized amount
+	Amount *entity.Money
+}
+
+func (uc *CapturePaymentUseCase) Execute(ctx context.Context, input CapturePaymentInput) (*entity.Payment, error) {
+	if input.PaymentID == "" {
+		return nil, entity.ErrEmptyPaymentID
+	}
+
+	slog.Info("Capturing payment", "payment_id", input.PaymentID)
+
+	// Find payment
+	payment, err := uc.paymentRepo.FindByID(ctx, input.PaymentID)
+	if err != nil {
+		slog.Error("Failed to find payment", "payment_id", input.PaymentID, "error", err)
+		return nil, err
+	}
+
+	amount := payment.Amount
+	if input.Amount != nil {
+		amount = *input.Amount
+	}
+
+	// Capture payment
+	if err := payment.Capture(amount); err != nil {
+		slog.Error("Failed to capture payment", "payment_id", input.PaymentID, "amount", amount.String(), "error", err)
+		if errors.Is(err, entity.ErrAuthorizationExpired) {
+			// Do not wait for the expiry worker to close it
+			uc.expire(ctx, payment)
+		}
+		return nil, err
+	}
+
+	// Capture at the gateway before the new status is saved. Without an
+	// approval the payment stays authorized and the client can retry; a
+	// single capture is allowed, so the key is the same for every retry.
+	response, err := uc.gateway.Capture(ctx, gateway.OperationRequest{
+		PaymentID:      payment.ID,
+		TransactionID:  payment.TransactionID,
+		Method:         payment.PaymentMethod,
+		IdempotencyKey: payment.ID + ":capture",
+		Amount:         amount,
+	})
+	if err := operationResult(response, err); err != nil {
+		slog.Error("Payment gateway failed to capture payment", "payment_id", input.PaymentID, "error", err)
+		return nil, fmt.Errorf("failed to capture payment: %w", err)
+	}
+
+	// Update payment in database
+	if err := uc.paymentRepo.Update(ctx, payment); err != nil {
+		slog.Error("Failed to update payment", "payment_id", input.PaymentID, "error", err)
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	slog.Info("Payment captured successfully",
+		"payment_id", payment.ID,
+		"captured_amount", payment.CapturedAmount.String(),
+	)
+
+	return payment, nil
+}
+
+func (uc *CapturePaymentUseCase) expire(ctx context.Context, payment *entity.Payment) {
+	if err := payment.Expire(time.Now()); err != nil {
+		return
+	}
+	if err := uc.paymentRepo.Update(ctx, payment); err != nil {
+		slog.Error("Failed to expire authorization", "payment_id", payment.ID, "error", err)
+	}
+}
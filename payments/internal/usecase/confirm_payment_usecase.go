@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/repository"
+	"time"
+)
+
+// ConfirmPaymentUseCase approves a pending charge, such as PIX, once the
+// provider reports that the customer paid it.
+type ConfirmPaymentUseCase struct {
+	paymentRepo repository.PaymentRepository
+}
+
+func NewConfirmPaymentUseCase(paymentRepo repository.PaymentRepository) *ConfirmPaymentUseCase {
+	return &ConfirmPaymentUseCase{
+		paymentRepo: paymentRepo,
+	}
+}
+
+// Execute confirms the payment. Confirming an approved payment again is a
+// no-op, since providers may report the same payment more than once.
+func (uc *ConfirmPaymentUseCase) Execute(ctx context.Context, paymentID string) (*entity.Payment, error) {
+	if paymentID == "" {
+		return nil, entity.ErrEmptyPaymentID
+	}
+
+	slog.Info("Confirming payment", "payment_id", paymentID)
+
+	payment, err := uc.paymentRepo.FindByID(ctx, paymentID)
+	if err != nil {
+		slog.Error("Failed to find payment", "payment_id", paymentID, "error", err)
+		return nil, err
+	}
+
+	if payment.Status == entity.PaymentStatusApproved && payment.ExpiresAt != nil {
+		slog.Info("Payment already confirmed", "payment_id", paymentID)
+		return payment, nil
+	}
+
+	if err := confirmPayment(ctx, uc.paymentRepo, payment, time.Now()); err != nil {
+		slog.Error("Failed to confirm payment", "payment_id", paymentID, "error", err)
+		return nil, err
+	}
+
+	slog.Info("Payment confirmed", "payment_id", paymentID, "transaction_id", payment.TransactionID)
+
+	return payment, nil
+}
+
+// confirmPayment approves a payment paid at paidAt and saves it. A payment
+// paid after it expired is closed right away instead of waiting for the
+// expiry worker.
+func confirmPayment(ctx context.Context, paymentRepo repository.PaymentRepository, payment *entity.Payment, paidAt time.Time) error {
+	if err := payment.Confirm(paidAt); err != nil {
+		if errors.Is(err, entity.ErrPaymentExpired) {
+			if expireErr := payment.Expire(time.Now()); expireErr == nil {
+				if updateErr := paymentRepo.Update(ctx, payment); updateErr != nil {
+					slog.Error("Failed to expire payment", "payment_id", payment.ID, "error", updateErr)
+				}
+			}
+		}
+		return err
+	}
+
+	if err := paymentRepo.Update(ctx, payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/repository"
+	"time"
+)
+
+// ExpirePaymentsUseCase closes authorizations that were neither captured nor
+// voided within their window, so the held amount is released, and charges
+// such as PIX or boletos that the customer did not pay in time.
+type ExpirePaymentsUseCase struct {
+	paymentRepo repository.PaymentRepository
+	batchSize   int
+}
+
+func NewExpirePaymentsUseCase(paymentRepo repository.PaymentRepository, batchSize int) *ExpirePaymentsUseCase {
+	return &ExpirePaymentsUseCase{
+		paymentRepo: paymentRepo,
+		batchSize:   batchSize,
+	}
+}
+
+// ExpireDue expires up to one batch of overdue authorizations and one of
+// overdue charges, and returns how many were expired.
+func (uc *ExpirePaymentsUseCase) ExpireDue(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	authorizations, err := uc.paymentRepo.FindExpiredAuthorizations(ctx, now, uc.batchSize)
+	if err != nil {
+		slog.Error("Failed to load expired authorizations", "error", err)
+		return 0, err
+	}
+
+	charges, err := uc.paymentRepo.FindExpiredCharges(ctx, now, uc.batchSize)
+	if err != nil {
+		slog.Error("Failed to load expired charges", "error", err)
+		return 0, err
+	}
+
+	expired := 0
+	for _, payment := range append(authorizations, charges...) {
+		if err := uc.expire(ctx, payment, now); err != nil {
+			return expired, err
+		}
+		if payment.Status == entity.PaymentStatusExpired {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+func (uc *ExpirePaymentsUseCase) expire(ctx context.Context, payment *entity.Payment, now time.Time) error {
+	expiredAt := payment.AuthorizationExpiresAt
+	if payment.IsAwaitingConfirmation() {
+		expiredAt = payment.ExpiresAt
+	}
+
+	if err := payment.Expire(now); err != nil {
+		// Captured, voided or paid since it was loaded
+		return nil
+	}
+
+	if err := uc.paymentRepo.Update(ctx, payment); err != nil {
+		if errors.Is(err, entity.ErrPaymentConflict) {
+			// Captured, voided or paid while it was being expired
+			slog.Info("Payment changed before it expired", "payment_id", payment.ID)
+			return nil
+		}
+		slog.Error("Failed to expire payment", "payment_id", payment.ID, "error", err)
+		return err
+	}
+
+	slog.Info("Payment expired",
+		"payment_id", payment.ID,
+		"order_id", payment.OrderID,
+		"method", payment.PaymentMethod,
+		"expired_at", expiredAt,
+	)
+	return nil
+}
+
+// Run expires overdue payments every interval until ctx is canceled
+func (uc *ExpirePaymentsUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				expired, err := uc.ExpireDue(ctx)
+				if err != nil || expired < uc.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
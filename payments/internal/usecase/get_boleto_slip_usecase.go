@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"payments/internal/domain/boleto"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/repository"
+)
+
+// GetBoletoSlipUseCase renders the printable slip of an issued boleto
+type GetBoletoSlipUseCase struct {
+	paymentRepo repository.PaymentRepository
+	issuer      boleto.Issuer
+	renderer    boleto.SlipRenderer
+}
+
+func NewGetBoletoSlipUseCase(paymentRepo repository.PaymentRepository, issuer boleto.Issuer, renderer boleto.SlipRenderer) *GetBoletoSlipUseCase {
+	return &GetBoletoSlipUseCase{
+		paymentRepo: paymentRepo,
+		issuer:      issuer,
+		renderer:    renderer,
+	}
+}
+
+type BoletoSlipOutput struct {
+	PaymentID   string
+	ContentType string
+	Content     []byte
+}
+
+func (uc *GetBoletoSlipUseCase) Execute(ctx context.Context, paymentID string) (*BoletoSlipOutput, error) {
+	if paymentID == "" {
+		return nil, entity.ErrEmptyPaymentID
+	}
+
+	payment, err := uc.paymentRepo.FindByID(ctx, paymentID)
+	if err != nil {
+		slog.Error("Failed to get payment", "payment_id", paymentID, "error", err)
+		return nil, err
+	}
+
+	dueDate := payment.BoletoDueDate()
+	if dueDate == nil {
+		return nil, boleto.ErrNotIssued
+	}
+
+	contentType, content, err := uc.renderer.Render(boleto.Slip{
+		Issuer: uc.issuer,
+		Boleto: boleto.Boleto{
+			Number:        payment.BoletoNumber,
+			Barcode:       payment.BoletoBarcode,
+			DigitableLine: payment.BoletoDigitableLine,
+			DueDate:       *dueDate,
+		},
+		Amount:        payment.Amount,
+		PayerName:     payment.CustomerName,
+		PayerDocument: payment.Details.MaskedDocument,
+		Description:   "Pedido " + payment.OrderID,
+		IssuedAt:      payment.CreatedAt,
+	})
+	if err != nil {
+		slog.Error("Failed to render boleto slip", "payment_id", paymentID, "error", err)
+		return nil, err
+	}
+
+	return &BoletoSlipOutput{
+		PaymentID:   payment.ID,
+		ContentType: contentType,
+		Content:     content,
+	}, nil
+}
@@ -2,7 +2,6 @@ package usecase
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"payments/internal/domain/entity"
 	"payments/internal/domain/repository"
@@ -20,7 +19,7 @@ func NewGetPaymentUseCase(paymentRepo repository.PaymentRepository) *GetPaymentU
 
 func (uc *GetPaymentUseCase) Execute(ctx context.Context, paymentID string) (*entity.Payment, error) {
 	if paymentID == "" {
-		return nil, fmt.Errorf("payment_id cannot be empty")
+		return nil, entity.ErrEmptyPaymentID
 	}
 
 	slog.Info("Getting payment", "payment_id", paymentID)
@@ -0,0 +1,300 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"payments/internal/domain/repository"
+	"time"
+)
+
+// webhookClaimTTL is how long a delivery holds the event it applies; a
+// delivery that failed or crashed lets another apply the event after that
+const webhookClaimTTL = time.Minute
+
+// HandleWebhookUseCase applies the notifications sent by the payment gateway
+// to the payments they refer to. Every event is stored and claimed before
+// being applied, and an event ID already handled is answered with the stored
+// result, so gateways can redeliver freely.
+type HandleWebhookUseCase struct {
+	paymentRepo repository.PaymentRepository
+	refundRepo  repository.RefundRepository
+	eventRepo   repository.WebhookEventRepository
+	gateway     gateway.PaymentGateway
+}
+
+func NewHandleWebhookUseCase(
+	paymentRepo repository.PaymentRepository,
+	refundRepo repository.RefundRepository,
+	eventRepo repository.WebhookEventRepository,
+	paymentGateway gateway.PaymentGateway,
+) *HandleWebhookUseCase {
+	return &HandleWebhookUseCase{
+		paymentRepo: paymentRepo,
+		refundRepo:  refundRepo,
+		eventRepo:   eventRepo,
+		gateway:     paymentGateway,
+	}
+}
+
+type HandleWebhookInput struct {
+	EventID       string
+	Type          entity.WebhookEventType
+	PaymentID     string
+	TransactionID string
+	// Amount of a refund; nil refunds everything still refundable
+	Amount  *entity.Money
+	Message string
+	// Payload and Signature are the request as received, kept for audit
+	Payload   []byte
+	Signature string
+}
+
+type HandleWebhookOutput struct {
+	Event *entity.WebhookEvent
+	// Duplicate is true when the event had already been handled
+	Duplicate bool
+}
+
+// Execute stores and applies the event. Events that can never be applied,
+// such as one naming an unknown payment, are stored as ignored and are not
+// an error; errors are transient and the gateway should deliver the event
+// again. A delivery arriving while another applies the same event fails
+// with entity.ErrWebhookEventClaimed.
+func (uc *HandleWebhookUseCase) Execute(ctx context.Context, input HandleWebhookInput) (*HandleWebhookOutput, error) {
+	event, err := entity.NewWebhookEvent(input.EventID, input.Type, input.PaymentID, input.Payload, input.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Handling webhook event", "event_id", event.EventID, "type", event.Type, "payment_id", event.PaymentID)
+
+	// Storing the event claims it; only one delivery can insert it
+	now := time.Now()
+	event.Claim(now.Add(webhookClaimTTL))
+	if err := uc.eventRepo.Create(ctx, event); err != nil {
+		if !errors.Is(err, entity.ErrDuplicateWebhookEvent) {
+			slog.Error("Failed to store webhook event", "event_id", event.EventID, "error", err)
+			return nil, err
+		}
+
+		existing, err := uc.claimStored(ctx, input.EventID, now)
+		if err != nil {
+			return nil, err
+		}
+		if existing.IsHandled() {
+			return &HandleWebhookOutput{Event: existing, Duplicate: true}, nil
+		}
+		event = existing
+	}
+
+	result, err := uc.apply(ctx, input)
+	now = time.Now()
+	switch {
+	case err == nil:
+		event.MarkProcessed(now, result)
+	case isPermanentWebhookError(err):
+		slog.Warn("Ignoring webhook event", "event_id", event.EventID, "type", event.Type, "reason", err)
+		event.MarkIgnored(now, err.Error())
+	default:
+		slog.Error("Failed to apply webhook event", "event_id", event.EventID, "type", event.Type, "error", err)
+		return nil, err
+	}
+
+	if err := uc.eventRepo.Update(ctx, event); err != nil {
+		slog.Error("Failed to update webhook event", "event_id", event.EventID, "error", err)
+		return nil, err
+	}
+
+	slog.Info("Webhook event handled", "event_id", event.EventID, "status", event.Status, "result", event.Result)
+
+	return &HandleWebhookOutput{Event: event}, nil
+}
+
+// claimStored claims an event stored by an earlier delivery, so that one
+// that stopped before finishing is applied again once its claim ends. An
+// event already handled is returned unclaimed.
+func (uc *HandleWebhookUseCase) claimStored(ctx context.Context, eventID string, now time.Time) (*entity.WebhookEvent, error) {
+	existing, err := uc.eventRepo.FindByEventID(ctx, eventID)
+	if err != nil {
+		slog.Error("Failed to find webhook event", "event_id", eventID, "error", err)
+		return nil, err
+	}
+	if existing.IsHandled() {
+		slog.Info("Webhook event already handled", "event_id", existing.EventID, "status", existing.Status)
+		return existing, nil
+	}
+
+	existing.Claim(now.Add(webhookClaimTTL))
+	err = uc.eventRepo.Claim(ctx, existing, now)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, entity.ErrWebhookEventClaimed) {
+		slog.Error("Failed to claim webhook event", "event_id", eventID, "error", err)
+		return nil, err
+	}
+
+	// The other delivery may have finished in between
+	existing, findErr := uc.eventRepo.FindByEventID(ctx, eventID)
+	if findErr != nil {
+		slog.Error("Failed to find webhook event", "event_id", eventID, "error", findErr)
+		return nil, findErr
+	}
+	if existing.IsHandled() {
+		return existing, nil
+	}
+
+	slog.Info("Webhook event is being handled by another delivery", "event_id", eventID)
+	return nil, err
+}
+
+// webhookEventError is an event that can never be applied, so delivering it
+// again would not help
+type webhookEventError struct {
+	err error
+}
+
+func (e webhookEventError) Error() string { return e.err.Error() }
+func (e webhookEventError) Unwrap() error { return e.err }
+
+func ignoreEvent(err error) error {
+	return webhookEventError{err: err}
+}
+
+func isPermanentWebhookError(err error) bool {
+	var eventErr webhookEventError
+	return errors.As(err, &eventErr)
+}
+
+// apply makes the transition the event reports and describes the outcome
+func (uc *HandleWebhookUseCase) apply(ctx context.Context, input HandleWebhookInput) (string, error) {
+	if input.PaymentID == "" {
+		return "", ignoreEvent(errors.New("event has no payment_id"))
+	}
+
+	payment, err := uc.paymentRepo.FindByID(ctx, input.PaymentID)
+	if errors.Is(err, entity.ErrPaymentNotFound) {
+		return "", ignoreEvent(err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if input.TransactionID != "" && payment.TransactionID != "" && input.TransactionID != payment.TransactionID {
+		return "", ignoreEvent(fmt.Errorf("event transaction %s does not match payment transaction %s", input.TransactionID, payment.TransactionID))
+	}
+
+	switch input.Type {
+	case entity.WebhookPaymentApproved:
+		return uc.approve(ctx, payment)
+	case entity.WebhookPaymentDeclined:
+		return uc.decline(ctx, payment, input.Message)
+	case entity.WebhookPaymentRefunded:
+		return uc.refund(ctx, payment, input)
+	}
+	return "", ignoreEvent(fmt.Errorf("%w: %s", entity.ErrUnknownWebhookEvent, input.Type))
+}
+
+func (uc *HandleWebhookUseCase) approve(ctx context.Context, payment *entity.Payment) (string, error) {
+	switch {
+	case payment.IsAwaitingConfirmation():
+		if err := confirmPayment(ctx, uc.paymentRepo, payment, time.Now()); err != nil {
+			if errors.Is(err, entity.ErrPaymentExpired) {
+				return "", ignoreEvent(err)
+			}
+			return "", err
+		}
+		return "payment confirmed", nil
+	case payment.Status == entity.PaymentStatusProcessing:
+		if err := payment.Approve(); err != nil {
+			return "", ignoreEvent(err)
+		}
+	case payment.Status == entity.PaymentStatusApproved ||
+		payment.Status == entity.PaymentStatusPartiallyRefunded ||
+		payment.Status == entity.PaymentStatusRefunded:
+		return "payment already approved", nil
+	default:
+		return "", ignoreEvent(fmt.Errorf("%w: payment is %s", entity.ErrWebhookEventNotApplied, payment.Status))
+	}
+
+	if err := uc.paymentRepo.Update(ctx, payment); err != nil {
+		return "", fmt.Errorf("failed to update payment: %w", err)
+	}
+	return "payment approved", nil
+}
+
+func (uc *HandleWebhookUseCase) decline(ctx context.Context, payment *entity.Payment, message string) (string, error) {
+	if payment.Status == entity.PaymentStatusDeclined {
+		return "payment already declined", nil
+	}
+
+	if err := payment.Decline(); err != nil {
+		return "", ignoreEvent(fmt.Errorf("%w: payment is %s", entity.ErrWebhookEventNotApplied, payment.Status))
+	}
+	if message != "" {
+		payment.GatewayMessage = message
+	}
+
+	if err := uc.paymentRepo.Update(ctx, payment); err != nil {
+		return "", fmt.Errorf("failed to update payment: %w", err)
+	}
+	return "payment declined", nil
+}
+
+// refund records a refund made at the gateway. The refund reason names the
+// event, so an event applied before its status was saved is not refunded
+// twice when delivered again.
+func (uc *HandleWebhookUseCase) refund(ctx context.Context, payment *entity.Payment, input HandleWebhookInput) (string, error) {
+	reason := webhookRefundReason(input.EventID)
+
+	refunds, err := uc.refundRepo.FindByPaymentID(ctx, payment.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, refund := range refunds {
+		if refund.Reason == reason {
+			return fmt.Sprintf("refund %s already recorded", refund.ID), nil
+		}
+	}
+
+	amount := payment.RefundableAmount()
+	if input.Amount != nil {
+		amount = *input.Amount
+	}
+
+	refund, err := payment.Refund(amount, reason)
+	if err != nil {
+		return "", ignoreEvent(err)
+	}
+
+	// Confirm the refund at the gateway before it is recorded. The event ID
+	// is the idempotency key, so the provider matches it to the refund it
+	// reported instead of refunding again, however often it is delivered.
+	response, err := uc.gateway.Refund(ctx, gateway.OperationRequest{
+		PaymentID:      payment.ID,
+		TransactionID:  payment.TransactionID,
+		Method:         payment.PaymentMethod,
+		IdempotencyKey: "webhook:" + input.EventID,
+		Amount:         refund.Amount,
+		Reason:         reason,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to refund payment at the gateway: %w", err)
+	}
+	if !response.Approved() {
+		return "", ignoreEvent(operationResult(response, nil))
+	}
+
+	if err := uc.refundRepo.Create(ctx, refund, payment); err != nil {
+		return "", fmt.Errorf("failed to save refund: %w", err)
+	}
+	return fmt.Sprintf("refund %s of %s recorded", refund.ID, refund.Amount.String()), nil
+}
+
+func webhookRefundReason(eventID string) string {
+	return "gateway webhook event " + eventID
+}
@@ -2,10 +2,18 @@ package usecase
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"payments/internal/domain/entity"
 	"payments/internal/domain/repository"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPageSize is used when the request does not set one
+	DefaultPageSize = 50
+	// MaxPageSize caps the page size asked by clients
+	MaxPageSize = 100
 )
 
 type ListPaymentsUseCase struct {
@@ -18,18 +26,76 @@ func NewListPaymentsUseCase(paymentRepo repository.PaymentRepository) *ListPayme
 	}
 }
 
-func (uc *ListPaymentsUseCase) Execute(ctx context.Context, orderID string) ([]*entity.Payment, error) {
-	if orderID == "" {
-		return nil, fmt.Errorf("order_id cannot be empty")
+// ListPaymentsInput filters the payments listed; zero fields match every
+// payment
+type ListPaymentsInput struct {
+	OrderID       string
+	CustomerEmail string
+	Status        entity.PaymentStatus
+	Method        entity.PaymentMethod
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	PageSize      int
+	PageToken     string
+	// OrderBy is "created_at desc", the default, or "created_at asc"
+	OrderBy string
+}
+
+type ListPaymentsOutput struct {
+	Payments []*entity.Payment
+	// NextPageToken is empty on the last page
+	NextPageToken string
+}
+
+func (uc *ListPaymentsUseCase) Execute(ctx context.Context, input ListPaymentsInput) (*ListPaymentsOutput, error) {
+	var ascending bool
+	switch strings.ToLower(strings.TrimSpace(input.OrderBy)) {
+	case "", "created_at desc", "created_at":
+	case "created_at asc":
+		ascending = true
+	default:
+		return nil, entity.ErrInvalidOrderBy
 	}
 
-	slog.Info("Listing payments for order", "order_id", orderID)
+	pageSize := input.PageSize
+	switch {
+	case pageSize < 0:
+		return nil, entity.ErrInvalidPageSize
+	case pageSize == 0:
+		pageSize = DefaultPageSize
+	case pageSize > MaxPageSize:
+		pageSize = MaxPageSize
+	}
 
-	payments, err := uc.paymentRepo.FindByOrderID(ctx, orderID)
+	after, err := entity.ParsePageToken(input.PageToken, ascending)
 	if err != nil {
-		slog.Error("Failed to list payments", "order_id", orderID, "error", err)
 		return nil, err
 	}
 
-	return payments, nil
+	slog.Info("Listing payments", "order_id", input.OrderID, "status", input.Status, "page_size", pageSize)
+
+	// One row more than the page tells whether there is a next page
+	payments, err := uc.paymentRepo.List(ctx, repository.PaymentFilter{
+		OrderID:       input.OrderID,
+		CustomerEmail: input.CustomerEmail,
+		Status:        input.Status,
+		Method:        input.Method,
+		CreatedFrom:   input.CreatedFrom,
+		CreatedTo:     input.CreatedTo,
+		After:         after,
+		Ascending:     ascending,
+		Limit:         pageSize + 1,
+	})
+	if err != nil {
+		slog.Error("Failed to list payments", "order_id", input.OrderID, "error", err)
+		return nil, err
+	}
+
+	output := &ListPaymentsOutput{Payments: payments}
+	if len(payments) > pageSize {
+		output.Payments = payments[:pageSize]
+		last := output.Payments[pageSize-1]
+		output.NextPageToken = entity.EncodePageToken(entity.PageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, ascending)
+	}
+	return output, nil
 }
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"payments/internal/domain/repository"
+	"payments/internal/infra/broker"
+	"time"
+)
+
+// OutboxRelayUseCase publishes the events saved in the outbox. An event is
+// only marked as published after the broker accepted it, so a crash in
+// between publishes it again: consumers must deduplicate by event ID.
+type OutboxRelayUseCase struct {
+	outboxRepo repository.OutboxRepository
+	broker     broker.Broker
+	batchSize  int
+}
+
+func NewOutboxRelayUseCase(outboxRepo repository.OutboxRepository, broker broker.Broker, batchSize int) *OutboxRelayUseCase {
+	return &OutboxRelayUseCase{
+		outboxRepo: outboxRepo,
+		broker:     broker,
+		batchSize:  batchSize,
+	}
+}
+
+// PublishPending publishes up to one batch of pending events, oldest first,
+// and returns how many were published. It stops at the first failure so
+// events of the same payment are never delivered out of order.
+func (uc *OutboxRelayUseCase) PublishPending(ctx context.Context) (int, error) {
+	events, err := uc.outboxRepo.FindUnpublished(ctx, uc.batchSize)
+	if err != nil {
+		slog.Error("Failed to load outbox events", "error", err)
+		return 0, err
+	}
+
+	published := 0
+	for _, event := range events {
+		if err := uc.broker.Publish(ctx, event); err != nil {
+			slog.Error("Failed to publish event",
+				"event_id", event.ID,
+				"event_type", event.Type,
+				"attempts", event.Attempts+1,
+				"error", err,
+			)
+			if markErr := uc.outboxRepo.MarkFailed(ctx, event.ID, err.Error()); markErr != nil {
+				return published, markErr
+			}
+			return published, err
+		}
+
+		if err := uc.outboxRepo.MarkPublished(ctx, event.ID, time.Now()); err != nil {
+			return published, err
+		}
+		published++
+
+		slog.Info("Event published",
+			"event_id", event.ID,
+			"event_type", event.Type,
+			"aggregate_id", event.AggregateID,
+		)
+	}
+
+	return published, nil
+}
+
+// Run publishes pending events every interval until ctx is canceled
+func (uc *OutboxRelayUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Drain the backlog before waiting for the next tick
+			for {
+				published, err := uc.PublishPending(ctx)
+				if err != nil || published < uc.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
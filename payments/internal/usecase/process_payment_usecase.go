@@ -2,42 +2,95 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"payments/internal/domain/boleto"
 	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"payments/internal/domain/pix"
 	"payments/internal/domain/repository"
-
-	"github.com/google/uuid"
+	"time"
 )
 
 type ProcessPaymentUseCase struct {
 	paymentRepo repository.PaymentRepository
+	gateway     gateway.PaymentGateway
+	config      ProcessPaymentConfig
+}
+
+// ProcessPaymentConfig holds the rules of the methods that are not charged
+// right away
+type ProcessPaymentConfig struct {
+	// AuthorizationTTL is how long an authorization can wait for its capture
+	AuthorizationTTL time.Duration
+	// PixMerchant receives the PIX payments; PixChargeTTL is how long the
+	// customer has to pay a PIX charge
+	PixMerchant  pix.Merchant
+	PixChargeTTL time.Duration
+	// BoletoIssuer is the beneficiary of the boletos. Unpaid boletos expire
+	// BoletoGracePeriod after the end of their due date, which leaves time
+	// for payments made on the due date to show up in the settlement.
+	BoletoIssuer      boleto.Issuer
+	BoletoGracePeriod time.Duration
 }
 
-func NewProcessPaymentUseCase(paymentRepo repository.PaymentRepository) *ProcessPaymentUseCase {
+func NewProcessPaymentUseCase(paymentRepo repository.PaymentRepository, paymentGateway gateway.PaymentGateway, config ProcessPaymentConfig) *ProcessPaymentUseCase {
 	return &ProcessPaymentUseCase{
 		paymentRepo: paymentRepo,
+		gateway:     paymentGateway,
+		config:      config,
 	}
 }
 
 type ProcessPaymentInput struct {
 	OrderID       string
-	Amount        float64
+	Amount        entity.Money
 	PaymentMethod entity.PaymentMethod
 	CustomerEmail string
 	CustomerName  string
+	// IdempotencyKey makes retries of the same request return the original
+	// payment instead of charging again; empty disables the check
+	IdempotencyKey string
+	// AuthorizeOnly holds the amount on the card instead of charging it;
+	// the payment is charged later by CapturePaymentUseCase
+	AuthorizeOnly bool
+	// Card, PixKey and Boleto are the method specific data sent by the
+	// client; at most one is set, and only its safe fields are stored
+	Card   *entity.CardData
+	PixKey string
+	Boleto *BoletoInput
+}
+
+type BoletoInput struct {
+	// Document is the CPF or CNPJ of the payer
+	Document string
+	// DueDate is optional; the boleto is due in a few days without it
+	DueDate *time.Time
 }
 
 type ProcessPaymentOutput struct {
-	PaymentID     string
-	OrderID       string
-	Status        entity.PaymentStatus
-	Message       string
-	TransactionID string
+	PaymentID              string
+	OrderID                string
+	Status                 entity.PaymentStatus
+	Message                string
+	TransactionID          string
+	Amount                 entity.Money
+	AuthorizationExpiresAt *time.Time
+	GatewayResponseCode    string
+	AuthorizationCode      string
+	// PixQRCode, the Boleto* fields and ExpiresAt describe the charge the
+	// customer has to pay, for payments waiting for a confirmation
+	PixQRCode           string
+	BoletoNumber        string
+	BoletoBarcode       string
+	BoletoDigitableLine string
+	BoletoDueDate       *time.Time
+	ExpiresAt           *time.Time
 }
 
 func (uc *ProcessPaymentUseCase) Execute(ctx context.Context, input ProcessPaymentInput) (*ProcessPaymentOutput, error) {
-	slog.Info("Processing payment", "order_id", input.OrderID, "amount", input.Amount, "method", input.PaymentMethod)
+	slog.Info("Processing payment", "order_id", input.OrderID, "amount", input.Amount.String(), "method", input.PaymentMethod)
 
 	// Check if context is cancelled
 	select {
@@ -46,6 +99,33 @@ func (uc *ProcessPaymentUseCase) Execute(ctx context.Context, input ProcessPayme
 	default:
 	}
 
+	// A retried request returns the payment created by the first attempt
+	if input.IdempotencyKey != "" {
+		existing, err := uc.paymentRepo.FindByIdempotencyKey(ctx, input.IdempotencyKey)
+		if err == nil {
+			return replayPayment(existing, input)
+		}
+		if !errors.Is(err, entity.ErrPaymentNotFound) {
+			slog.Error("Failed to look up idempotency key", "error", err)
+			return nil, err
+		}
+	}
+
+	if input.AuthorizeOnly && !input.PaymentMethod.SupportsAuthorization() {
+		return nil, entity.ErrAuthorizationNotSupported
+	}
+
+	// Only a retry answered above with the payment of the first attempt may
+	// leave the details out: a new charge is never sent without them
+	details, err := newPaymentDetails(input, time.Now())
+	if err == nil && details == nil {
+		err = missingDetailsError(input.PaymentMethod)
+	}
+	if err != nil {
+		slog.Warn("Invalid payment details", "order_id", input.OrderID, "error", err)
+		return nil, err
+	}
+
 	// Create new payment
 	payment, err := entity.NewPayment(
 		input.OrderID,
@@ -58,65 +138,278 @@ func (uc *ProcessPaymentUseCase) Execute(ctx context.Context, input ProcessPayme
 		slog.Error("Failed to create payment", "error", err)
 		return nil, err
 	}
+	payment.IdempotencyKey = input.IdempotencyKey
+	if details != nil {
+		if err := payment.AttachDetails(details); err != nil {
+			return nil, err
+		}
+	}
 
-	// Simulate payment processing with external gateway
-	transactionID := uuid.New().String()
-	if err := payment.Process(transactionID); err != nil {
-		slog.Error("Failed to process payment", "error", err)
-		return nil, err
+	// PIX charges are paid with a BR Code and boletos with their digitable
+	// line; building them first rejects charges that cannot have one before
+	// anything is sent to the gateway
+	var pixQRCode string
+	var issued *boleto.Boleto
+	switch payment.PaymentMethod {
+	case entity.PaymentMethodPix:
+		pixQRCode, err = uc.pixQRCode(payment)
+		if err != nil {
+			slog.Error("Failed to create PIX charge", "error", err)
+			return nil, err
+		}
+	case entity.PaymentMethodBoleto:
+		issued, err = uc.issueBoleto(payment)
+		if err != nil {
+			slog.Error("Failed to issue boleto", "error", err)
+			return nil, err
+		}
 	}
 
-	// Simulate payment approval (in production, this would be async)
-	// Here we could call an external payment gateway
-	approved := simulatePaymentGateway(input.PaymentMethod, input.Amount)
+	// Ask the payment gateway for a decision. Without a decision nothing is
+	// saved: the client retries with the same idempotency key, which is
+	// forwarded to the provider so the retry cannot charge twice.
+	response, err := uc.gateway.Process(ctx, gateway.Request{
+		PaymentID:      payment.ID,
+		OrderID:        payment.OrderID,
+		IdempotencyKey: gatewayIdempotencyKey(payment),
+		Amount:         payment.Amount,
+		Method:         payment.PaymentMethod,
+		CustomerEmail:  payment.CustomerEmail,
+		CustomerName:   payment.CustomerName,
+		CardBIN:        cardBIN(input.Card),
+		Card:           input.Card,
+		Details:        payment.Details,
+		Boleto:         issued,
+		AuthorizeOnly:  input.AuthorizeOnly,
+	})
+	if err != nil {
+		slog.Error("Payment gateway failed", "payment_id", payment.ID, "error", err)
+		return nil, fmt.Errorf("failed to process payment: %w", err)
+	}
 
-	if approved {
-		if err := payment.Approve(); err != nil {
-			slog.Error("Failed to approve payment", "error", err)
+	transactionID := response.TransactionID
+	approved := response.Approved()
+
+	// The card is kept as the token the provider issued for it
+	if payment.Details != nil && response.CardToken != "" {
+		payment.Details.CardToken = response.CardToken
+	}
+
+	if approved && payment.PaymentMethod.RequiresConfirmation() {
+		expiresAt := time.Now().Add(uc.config.PixChargeTTL)
+		if issued != nil {
+			payment.BoletoNumber = issued.Number
+			payment.BoletoBarcode = issued.Barcode
+			payment.BoletoDigitableLine = issued.DigitableLine
+			expiresAt = boletoExpiresAt(issued.DueDate, uc.config.BoletoGracePeriod)
+		} else {
+			payment.PixQRCode = pixQRCode
+		}
+		if err := payment.AwaitConfirmation(transactionID, expiresAt); err != nil {
+			slog.Error("Failed to create charge", "error", err)
 			return nil, err
 		}
-		slog.Info("Payment approved", "payment_id", payment.ID, "transaction_id", transactionID)
+		payment.RecordGatewayResponse(response.ResponseCode, response.AuthorizationCode, response.Message)
+		slog.Info("Payment waiting for confirmation", "payment_id", payment.ID, "expires_at", payment.ExpiresAt)
 	} else {
-		if err := payment.Decline(); err != nil {
-			slog.Error("Failed to decline payment", "error", err)
+		if err := payment.Process(transactionID); err != nil {
+			slog.Error("Failed to process payment", "error", err)
 			return nil, err
 		}
-		slog.Warn("Payment declined", "payment_id", payment.ID)
+		payment.RecordGatewayResponse(response.ResponseCode, response.AuthorizationCode, response.Message)
+
+		if approved && input.AuthorizeOnly {
+			if err := payment.Authorize(time.Now().Add(uc.config.AuthorizationTTL)); err != nil {
+				slog.Error("Failed to authorize payment", "error", err)
+				return nil, err
+			}
+			slog.Info("Payment authorized", "payment_id", payment.ID, "expires_at", payment.AuthorizationExpiresAt)
+		} else if approved {
+			if err := payment.Approve(); err != nil {
+				slog.Error("Failed to approve payment", "error", err)
+				return nil, err
+			}
+			slog.Info("Payment approved", "payment_id", payment.ID, "transaction_id", transactionID)
+		} else {
+			if err := payment.Decline(); err != nil {
+				slog.Error("Failed to decline payment", "error", err)
+				return nil, err
+			}
+			slog.Warn("Payment declined",
+				"payment_id", payment.ID,
+				"response_code", response.ResponseCode,
+				"message", response.Message,
+			)
+		}
 	}
 
 	// Save payment to database
 	slog.Info("About to save payment to database", "payment_id", payment.ID)
-	if err := uc.paymentRepo.Create(ctx, payment); err != nil {
+	err = uc.paymentRepo.Create(ctx, payment)
+	if errors.Is(err, entity.ErrDuplicateIdempotencyKey) {
+		// A concurrent request with the same key was saved first
+		existing, findErr := uc.paymentRepo.FindByIdempotencyKey(ctx, input.IdempotencyKey)
+		if findErr != nil {
+			slog.Error("Failed to load payment for idempotency key", "error", findErr)
+			return nil, findErr
+		}
+		return replayPayment(existing, input)
+	}
+	if err != nil {
 		slog.Error("Failed to save payment", "error", err)
 		return nil, fmt.Errorf("failed to save payment: %w", err)
 	}
 	slog.Info("Payment saved successfully", "payment_id", payment.ID)
 
+	return newProcessPaymentOutput(payment), nil
+}
+
+// pixQRCode is the BR Code the customer pays the payment with; the payment
+// ID identifies it in the PIX settlement
+func (uc *ProcessPaymentUseCase) pixQRCode(payment *entity.Payment) (string, error) {
+	return pix.EncodeBRCode(pix.Charge{
+		Merchant:    uc.config.PixMerchant,
+		Amount:      payment.Amount,
+		TxID:        payment.ID,
+		Description: "Pedido " + payment.OrderID,
+	})
+}
+
+// issueBoleto numbers the boleto of the payment and builds its barcode. The
+// due date was validated with the payment details.
+func (uc *ProcessPaymentUseCase) issueBoleto(payment *entity.Payment) (*boleto.Boleto, error) {
+	number, err := boleto.NewNumber(uc.config.BoletoIssuer)
+	if err != nil {
+		return nil, err
+	}
+	return boleto.Issue(uc.config.BoletoIssuer, number, payment.Amount, *payment.Details.BoletoDueDate)
+}
+
+// boletoExpiresAt is the end of the due date plus the grace period
+func boletoExpiresAt(dueDate time.Time, gracePeriod time.Duration) time.Time {
+	return dueDate.AddDate(0, 0, 1).Add(gracePeriod)
+}
+
+// replayPayment answers a retried request with the payment it already
+// created, refusing keys reused for a different order or amount
+func replayPayment(payment *entity.Payment, input ProcessPaymentInput) (*ProcessPaymentOutput, error) {
+	if !payment.MatchesRequest(input.OrderID, input.Amount) {
+		slog.Warn("Idempotency key reused for a different payment",
+			"idempotency_key", input.IdempotencyKey,
+			"payment_id", payment.ID,
+			"order_id", input.OrderID,
+		)
+		return nil, entity.ErrIdempotencyKeyReused
+	}
+
+	slog.Info("Returning payment already processed for idempotency key",
+		"idempotency_key", input.IdempotencyKey,
+		"payment_id", payment.ID,
+	)
+	return newProcessPaymentOutput(payment), nil
+}
+
+func newProcessPaymentOutput(payment *entity.Payment) *ProcessPaymentOutput {
 	message := "Payment processed successfully"
-	if payment.Status == entity.PaymentStatusDeclined {
+	switch payment.Status {
+	case entity.PaymentStatusDeclined:
 		message = "Payment was declined by the payment gateway"
+		if payment.GatewayMessage != "" {
+			message = fmt.Sprintf("%s: %s (%s)", message, payment.GatewayMessage, payment.GatewayResponseCode)
+		}
+	case entity.PaymentStatusAuthorized:
+		message = "Payment authorized, waiting for capture"
+	case entity.PaymentStatusPending:
+		if payment.IsAwaitingConfirmation() {
+			message = "Payment created, waiting for the customer to pay it"
+		}
+	case entity.PaymentStatusExpired:
+		message = "Payment expired before it was paid"
 	}
 
 	return &ProcessPaymentOutput{
-		PaymentID:     payment.ID,
-		OrderID:       payment.OrderID,
-		Status:        payment.Status,
-		Message:       message,
-		TransactionID: payment.TransactionID,
-	}, nil
-}
-
-// simulatePaymentGateway simulates a payment gateway response
-// In production, this would make actual HTTP calls to payment providers
-func simulatePaymentGateway(method entity.PaymentMethod, amount float64) bool {
-	// Simulate 95% approval rate for valid payments
-	// In production, you would integrate with:
-	// - Stripe, PayPal, PagSeguro, etc for credit/debit cards
-	// - Brazilian payment gateways for PIX and Boleto
-	if amount <= 0 {
-		return false
-	}
-
-	// Simple simulation: approve all payments under 10000
-	return amount < 10000.0
+		PaymentID:              payment.ID,
+		OrderID:                payment.OrderID,
+		Status:                 payment.Status,
+		Message:                message,
+		TransactionID:          payment.TransactionID,
+		Amount:                 payment.Amount,
+		AuthorizationExpiresAt: payment.AuthorizationExpiresAt,
+		GatewayResponseCode:    payment.GatewayResponseCode,
+		AuthorizationCode:      payment.AuthorizationCode,
+		PixQRCode:              payment.PixQRCode,
+		BoletoNumber:           payment.BoletoNumber,
+		BoletoBarcode:          payment.BoletoBarcode,
+		BoletoDigitableLine:    payment.BoletoDigitableLine,
+		BoletoDueDate:          payment.BoletoDueDate(),
+		ExpiresAt:              payment.ExpiresAt,
+	}
+}
+
+// newPaymentDetails validates the details sent with the request and keeps
+// their safe fields. It returns nil, nil when no details were sent.
+func newPaymentDetails(input ProcessPaymentInput, now time.Time) (*entity.PaymentDetails, error) {
+	sent := 0
+	for _, set := range []bool{input.Card != nil, input.PixKey != "", input.Boleto != nil} {
+		if set {
+			sent++
+		}
+	}
+
+	switch {
+	case sent == 0:
+		return nil, nil
+	case sent > 1:
+		return nil, entity.ErrPaymentDetailsMismatch
+	case input.Card != nil:
+		return entity.NewCardDetails(*input.Card, now)
+	case input.PixKey != "":
+		return entity.NewPixDetails(input.PixKey)
+	default:
+		return entity.NewBoletoDetails(input.Boleto.Document, input.Boleto.DueDate, now)
+	}
+}
+
+// missingDetailsError is the error for a new payment sent without details,
+// or nil for methods that do not need any
+func missingDetailsError(method entity.PaymentMethod) error {
+	switch method {
+	case entity.PaymentMethodCreditCard, entity.PaymentMethodDebitCard:
+		return entity.ErrCardDetailsRequired
+	case entity.PaymentMethodPix:
+		return entity.ErrPixDetailsRequired
+	case entity.PaymentMethodBoleto:
+		// The payer document is printed on the slip and registered at the bank
+		return entity.ErrBoletoDetailsRequired
+	default:
+		return nil
+	}
+}
+
+func cardBIN(card *entity.CardData) string {
+	if card == nil {
+		return ""
+	}
+	return card.BIN()
+}
+
+// operationResult turns the answer of the gateway to a capture, void or
+// refund into an error when there was no answer or the provider declined
+func operationResult(response *gateway.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if !response.Approved() {
+		return fmt.Errorf("%w: %s %s", gateway.ErrOperationDeclined, response.ResponseCode, response.Message)
+	}
+	return nil
+}
+
+// gatewayIdempotencyKey is the client key when there is one, so retries of
+// the same request reach the provider with the same key
+func gatewayIdempotencyKey(payment *entity.Payment) string {
+	if payment.IdempotencyKey != "" {
+		return payment.IdempotencyKey
+	}
+	return payment.ID
 }
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"payments/internal/domain/repository"
+)
+
+type RefundPaymentUseCase struct {
+	paymentRepo repository.PaymentRepository
+	refundRepo  repository.RefundRepository
+	gateway     gateway.PaymentGateway
+}
+
+func NewRefundPaymentUseCase(paymentRepo repository.PaymentRepository, refundRepo repository.RefundRepository, paymentGateway gateway.PaymentGateway) *RefundPaymentUseCase {
+	return &RefundPaymentUseCase{
+		paymentRepo: paymentRepo,
+		refundRepo:  refundRepo,
+		gateway:     paymentGateway,
+	}
+}
+
+type RefundPaymentInput struct {
+	PaymentID string
+	// Amount to refund; nil refunds everything still refundable
+	Amount *entity.Money
+	Reason string
+}
+
+type RefundPaymentOutput struct {
+	Refund  *entity.Refund
+	Payment *entity.Payment
+}
+
+func (uc *RefundPaymentUseCase) Execute(ctx context.Context, input RefundPaymentInput) (*RefundPaymentOutput, error) {
+	if input.PaymentID == "" {
+		return nil, entity.ErrEmptyPaymentID
+	}
+
+	slog.Info("Refunding payment", "payment_id", input.PaymentID, "reason", input.Reason)
+
+	// Find payment
+	payment, err := uc.paymentRepo.FindByID(ctx, input.PaymentID)
+	if err != nil {
+		slog.Error("Failed to find payment", "payment_id", input.PaymentID, "error", err)
+		return nil, err
+	}
+
+	amount := payment.RefundableAmount()
+	if input.Amount != nil {
+		amount = *input.Amount
+	}
+
+	// The key names the refunds already made, so a retry of this refund
+	// reaches the provider with the same key and a later refund does not
+	idempotencyKey := fmt.Sprintf("%s:refund:%d", payment.ID, payment.RefundedAmount.Amount)
+
+	// Refund payment
+	refund, err := payment.Refund(amount, input.Reason)
+	if err != nil {
+		slog.Error("Failed to refund payment", "payment_id", input.PaymentID, "amount", amount.String(), "error", err)
+		return nil, err
+	}
+
+	// Refund at the gateway before the refund is saved; without an approval
+	// nothing is recorded and the client can retry
+	response, err := uc.gateway.Refund(ctx, gateway.OperationRequest{
+		PaymentID:      payment.ID,
+		TransactionID:  payment.TransactionID,
+		Method:         payment.PaymentMethod,
+		IdempotencyKey: idempotencyKey,
+		Amount:         refund.Amount,
+		Reason:         input.Reason,
+	})
+	if err := operationResult(response, err); err != nil {
+		slog.Error("Payment gateway failed to refund payment", "payment_id", input.PaymentID, "error", err)
+		return nil, fmt.Errorf("failed to refund payment: %w", err)
+	}
+
+	// Save refund and payment in database
+	if err := uc.refundRepo.Create(ctx, refund, payment); err != nil {
+		slog.Error("Failed to save refund", "payment_id", input.PaymentID, "error", err)
+		return nil, fmt.Errorf("failed to save refund: %w", err)
+	}
+
+	slog.Info("Payment refunded successfully",
+		"payment_id", payment.ID,
+		"refund_id", refund.ID,
+		"amount", refund.Amount.String(),
+		"status", payment.Status,
+	)
+
+	return &RefundPaymentOutput{
+		Refund:  refund,
+		Payment: payment,
+	}, nil
+}
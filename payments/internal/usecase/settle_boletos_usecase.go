@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"payments/internal/domain/boleto"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/repository"
+)
+
+var ErrBoletoUnderpaid = errors.New("boleto was paid with less than its amount")
+
+// SettleBoletosUseCase confirms the boletos listed in a bank settlement file
+type SettleBoletosUseCase struct {
+	paymentRepo repository.PaymentRepository
+}
+
+func NewSettleBoletosUseCase(paymentRepo repository.PaymentRepository) *SettleBoletosUseCase {
+	return &SettleBoletosUseCase{
+		paymentRepo: paymentRepo,
+	}
+}
+
+// SettlementReport tells what happened to each settled boleto
+type SettlementReport struct {
+	Confirmed   []string
+	AlreadyPaid []string
+	Failed      []SettlementFailure
+}
+
+type SettlementFailure struct {
+	Number string
+	Reason string
+}
+
+// Execute confirms each settled boleto. A failure only affects its own
+// boleto; the file is expected to be imported again after being fixed, which
+// is safe because boletos already confirmed are skipped.
+func (uc *SettleBoletosUseCase) Execute(ctx context.Context, settlements []boleto.Settlement) (*SettlementReport, error) {
+	slog.Info("Settling boletos", "count", len(settlements))
+
+	report := &SettlementReport{}
+	for _, settlement := range settlements {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		alreadyPaid, err := uc.settle(ctx, settlement)
+		switch {
+		case err != nil:
+			slog.Warn("Failed to settle boleto", "boleto_number", settlement.Number, "error", err)
+			report.Failed = append(report.Failed, SettlementFailure{Number: settlement.Number, Reason: err.Error()})
+		case alreadyPaid:
+			report.AlreadyPaid = append(report.AlreadyPaid, settlement.Number)
+		default:
+			report.Confirmed = append(report.Confirmed, settlement.Number)
+		}
+	}
+
+	slog.Info("Boletos settled",
+		"confirmed", len(report.Confirmed),
+		"already_paid", len(report.AlreadyPaid),
+		"failed", len(report.Failed),
+	)
+	return report, nil
+}
+
+func (uc *SettleBoletosUseCase) settle(ctx context.Context, settlement boleto.Settlement) (bool, error) {
+	payment, err := uc.paymentRepo.FindByBoletoNumber(ctx, settlement.Number)
+	if err != nil {
+		return false, err
+	}
+
+	if payment.Status == entity.PaymentStatusApproved {
+		return true, nil
+	}
+
+	if settlement.PaidAmount.Currency != payment.Amount.Currency ||
+		settlement.PaidAmount.Amount < payment.Amount.Amount {
+		return false, ErrBoletoUnderpaid
+	}
+
+	// The boleto counts as paid when the payer paid it, not when the file
+	// was imported, so payments made within the grace period are accepted
+	if err := confirmPayment(ctx, uc.paymentRepo, payment, settlement.PaidAt); err != nil {
+		return false, err
+	}
+
+	slog.Info("Boleto confirmed", "payment_id", payment.ID, "boleto_number", settlement.Number)
+	return false, nil
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"payments/internal/domain/repository"
+)
+
+type VoidAuthorizationUseCase struct {
+	paymentRepo repository.PaymentRepository
+	gateway     gateway.PaymentGateway
+}
+
+func NewVoidAuthorizationUseCase(paymentRepo repository.PaymentRepository, paymentGateway gateway.PaymentGateway) *VoidAuthorizationUseCase {
+	return &VoidAuthorizationUseCase{
+		paymentRepo: paymentRepo,
+		gateway:     paymentGateway,
+	}
+}
+
+type VoidAuthorizationInput struct {
+	PaymentID string
+	Reason    string
+}
+
+func (uc *VoidAuthorizationUseCase) Execute(ctx context.Context, input VoidAuthorizationInput) (*entity.Payment, error) {
+	if input.PaymentID == "" {
+		return nil, entity.ErrEmptyPaymentID
+	}
+
+	slog.Info("Voiding authorization", "payment_id", input.PaymentID, "reason", input.Reason)
+
+	// Find payment
+	payment, err := uc.paymentRepo.FindByID(ctx, input.PaymentID)
+	if err != nil {
+		slog.Error("Failed to find payment", "payment_id", input.PaymentID, "error", err)
+		return nil, err
+	}
+
+	// Release the held amount
+	if err := payment.Void(input.Reason); err != nil {
+		slog.Error("Failed to void authorization", "payment_id", input.PaymentID, "error", err)
+		return nil, err
+	}
+
+	// Void at the gateway before the new status is saved, so the hold is
+	// really released; without an approval the payment stays authorized
+	response, err := uc.gateway.Void(ctx, gateway.OperationRequest{
+		PaymentID:      payment.ID,
+		TransactionID:  payment.TransactionID,
+		Method:         payment.PaymentMethod,
+		IdempotencyKey: payment.ID + ":void",
+		Amount:         payment.Amount,
+		Reason:         input.Reason,
+	})
+	if err := operationResult(response, err); err != nil {
+		slog.Error("Payment gateway failed to void authorization", "payment_id", input.PaymentID, "error", err)
+		return nil, fmt.Errorf("failed to void authorization: %w", err)
+	}
+
+	// Update payment in database
+	if err := uc.paymentRepo.Update(ctx, payment); err != nil {
+		slog.Error("Failed to update payment", "payment_id", input.PaymentID, "error", err)
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	slog.Info("Authorization voided successfully", "payment_id", input.PaymentID)
+
+	return payment, nil
+}
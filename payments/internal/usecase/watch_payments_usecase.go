@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"payments/internal/domain/entity"
+	"payments/internal/domain/repository"
+	"sync"
+	"time"
+)
+
+// WatchPaymentsUseCase streams the payment change feed from a cursor,
+// checking for new changes every poll interval. The cursor is the position
+// of a change, given only once the change is committed, so a transaction
+// committing late cannot slip a change in behind one already sent.
+type WatchPaymentsUseCase struct {
+	changeRepo   repository.PaymentStatusChangeRepository
+	pollInterval time.Duration
+	batchSize    int
+	stopped      chan struct{}
+	stopOnce     sync.Once
+}
+
+func NewWatchPaymentsUseCase(changeRepo repository.PaymentStatusChangeRepository, pollInterval time.Duration, batchSize int) *WatchPaymentsUseCase {
+	return &WatchPaymentsUseCase{
+		changeRepo:   changeRepo,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+		stopped:      make(chan struct{}),
+	}
+}
+
+// Watch sends every change after cursor, in order, until ctx is canceled,
+// send fails or Stop is called. Changes are sent at least once: a consumer
+// resuming from the cursor of the last change it handled misses none.
+func (uc *WatchPaymentsUseCase) Watch(ctx context.Context, cursor string, send func(entity.PaymentStatusChange) error) error {
+	after, err := entity.ParseCursor(cursor)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Watching payment changes", "cursor", cursor)
+
+	for {
+		changes, full, err := uc.nextChanges(ctx, after)
+		if err != nil {
+			slog.Error("Failed to load payment changes", "after", after, "error", err)
+			return err
+		}
+
+		for _, change := range changes {
+			if err := send(change); err != nil {
+				slog.Warn("Stopped watching payment changes", "after", after, "error", err)
+				return err
+			}
+			after = change.Position
+		}
+
+		// A full batch means more changes are waiting
+		if full && len(changes) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-uc.stopped:
+			return nil
+		case <-time.After(uc.pollInterval):
+		}
+	}
+}
+
+// Stop ends every Watch, so the server can shut down gracefully
+func (uc *WatchPaymentsUseCase) Stop() {
+	uc.stopOnce.Do(func() {
+		close(uc.stopped)
+	})
+}
+
+// nextChanges positions the changes committed since the last call and loads
+// the ones after the given position. full tells whether more may be waiting.
+func (uc *WatchPaymentsUseCase) nextChanges(ctx context.Context, after int64) ([]entity.PaymentStatusChange, bool, error) {
+	positioned, err := uc.changeRepo.AssignPositions(ctx, uc.batchSize)
+	if err != nil {
+		return nil, false, err
+	}
+
+	changes, err := uc.changeRepo.FindAfter(ctx, after, uc.batchSize)
+	if err != nil {
+		return nil, false, err
+	}
+
+	full := positioned == uc.batchSize || len(changes) == uc.batchSize
+	return changes, full, nil
+}
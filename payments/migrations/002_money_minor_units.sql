@@ -0,0 +1,7 @@
+-- Store the payment amount as integer minor units (cents) plus an ISO-4217
+-- currency instead of DECIMAL(10, 2).
+ALTER TABLE payments
+    ADD COLUMN amount_cents BIGINT NOT NULL DEFAULT 0 AFTER amount,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'BRL' AFTER amount_cents;
+UPDATE payments SET amount_cents = ROUND(amount * 100);
+ALTER TABLE payments DROP COLUMN amount;
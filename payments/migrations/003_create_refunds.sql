@@ -0,0 +1,15 @@
+-- Track how much of each payment was given back and every refund issued,
+-- so several partial refunds can add up to the approved amount.
+ALTER TABLE payments
+    ADD COLUMN refunded_cents BIGINT NOT NULL DEFAULT 0 AFTER currency;
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id VARCHAR(36) PRIMARY KEY,
+    payment_id VARCHAR(36) NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_payment_id (payment_id),
+    CONSTRAINT fk_refunds_payment FOREIGN KEY (payment_id) REFERENCES payments(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
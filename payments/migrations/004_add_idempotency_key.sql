@@ -0,0 +1,6 @@
+-- Remember the client idempotency key of each payment so a retried
+-- ProcessPayment returns the original payment instead of charging twice.
+-- NULL keys are allowed repeatedly by the unique index.
+ALTER TABLE payments
+    ADD COLUMN idempotency_key VARCHAR(255) NULL AFTER cancel_reason,
+    ADD UNIQUE INDEX idx_idempotency_key (idempotency_key);
@@ -0,0 +1,15 @@
+-- Transactional outbox: events are written in the same transaction as the
+-- payment change they describe and published later by the relay worker.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id VARCHAR(36) PRIMARY KEY,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(36) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSON NOT NULL,
+    occurred_at TIMESTAMP(6) NOT NULL,
+    published_at TIMESTAMP(6) NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    INDEX idx_unpublished (published_at, occurred_at),
+    INDEX idx_aggregate (aggregate_type, aggregate_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,11 @@
+-- Support authorize-then-capture card payments: keep how much was actually
+-- captured and until when an authorization may still be captured.
+ALTER TABLE payments
+    ADD COLUMN captured_cents BIGINT NOT NULL DEFAULT 0 AFTER refunded_cents,
+    ADD COLUMN authorized_at TIMESTAMP NULL AFTER cancel_reason,
+    ADD COLUMN authorization_expires_at TIMESTAMP NULL AFTER authorized_at,
+    ADD INDEX idx_status_authorization_expires_at (status, authorization_expires_at);
+
+-- Payments approved so far were captured in full
+UPDATE payments SET captured_cents = amount_cents
+WHERE status IN ('approved', 'refunded', 'partially_refunded');
@@ -0,0 +1,7 @@
+-- Keep what the payment gateway answered for each payment (decline or
+-- response code, issuer authorization code and message) for support and
+-- reconciliation.
+ALTER TABLE payments
+    ADD COLUMN gateway_response_code VARCHAR(32) NULL AFTER transaction_id,
+    ADD COLUMN gateway_authorization_code VARCHAR(32) NULL AFTER gateway_response_code,
+    ADD COLUMN gateway_message VARCHAR(255) NULL AFTER gateway_authorization_code;
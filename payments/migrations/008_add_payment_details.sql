@@ -0,0 +1,5 @@
+-- Safe details of the card, PIX key or boleto of each payment (card token,
+-- brand and last four digits, masked documents and keys, boleto due date).
+-- Card numbers, security codes and full documents are never stored.
+ALTER TABLE payments
+    ADD COLUMN payment_details JSON NULL AFTER payment_method;
@@ -0,0 +1,6 @@
+-- PIX charges wait for the customer to pay them: keep their BR Code and when
+-- they expire unpaid, so an expiry worker can close them.
+ALTER TABLE payments
+    ADD COLUMN expires_at TIMESTAMP NULL AFTER authorization_expires_at,
+    ADD COLUMN pix_qr_code TEXT NULL AFTER expires_at,
+    ADD INDEX idx_status_expires_at (status, expires_at);
@@ -0,0 +1,8 @@
+-- Boletos wait for a settlement file or webhook to report them paid. Keep
+-- their number (nosso número), which identifies them in the settlement,
+-- and the barcode and digitable line printed on the slip.
+ALTER TABLE payments
+    ADD COLUMN boleto_number VARCHAR(17) NULL AFTER pix_qr_code,
+    ADD COLUMN boleto_barcode CHAR(44) NULL AFTER boleto_number,
+    ADD COLUMN boleto_digitable_line CHAR(47) NULL AFTER boleto_barcode,
+    ADD UNIQUE INDEX idx_boleto_number (boleto_number);
@@ -0,0 +1,16 @@
+-- Notifications received from the payment gateway, stored as received for
+-- audit. The unique gateway event ID makes redeliveries idempotent.
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id VARCHAR(36) PRIMARY KEY,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payment_id VARCHAR(36) NULL,
+    payload MEDIUMBLOB NOT NULL,
+    signature VARCHAR(512) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    result TEXT,
+    received_at TIMESTAMP(6) NOT NULL,
+    processed_at TIMESTAMP(6) NULL,
+    UNIQUE INDEX idx_event_id (event_id),
+    INDEX idx_payment_id (payment_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
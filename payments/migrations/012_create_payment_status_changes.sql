@@ -0,0 +1,15 @@
+-- Change feed of payments, streamed to other services by WatchPayments.
+-- Rows are written in the same transaction as the payment change; the
+-- sequence orders them and is the cursor consumers resume from.
+CREATE TABLE IF NOT EXISTS payment_status_changes (
+    sequence BIGINT AUTO_INCREMENT PRIMARY KEY,
+    payment_id VARCHAR(36) NOT NULL,
+    order_id VARCHAR(36) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    previous_status VARCHAR(50) NULL,
+    amount_cents BIGINT NOT NULL,
+    refunded_cents BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL,
+    occurred_at TIMESTAMP(6) NOT NULL,
+    INDEX idx_payment_id (payment_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,5 @@
+-- Keyset pagination of ListPayments: pages are sorted by created_at and id
+-- and continue after the last row of the previous page.
+ALTER TABLE payments
+    ADD INDEX idx_created_at_id (created_at, id),
+    ADD INDEX idx_customer_email_created_at (customer_email, created_at);
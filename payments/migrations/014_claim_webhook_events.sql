@@ -0,0 +1,7 @@
+-- A delivery claims the event it applies until claimed_until, so another
+-- delivery of the same event arriving meanwhile does not apply it twice.
+-- The signature header may carry several v1= signatures while the secret
+-- is rotated, so it is no longer bounded.
+ALTER TABLE webhook_events
+    MODIFY COLUMN signature TEXT NOT NULL,
+    ADD COLUMN claimed_until TIMESTAMP(6) NULL AFTER status;
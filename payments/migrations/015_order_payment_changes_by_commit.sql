@@ -0,0 +1,20 @@
+-- Sequences are taken when a change is inserted, not when it is committed,
+-- so a transaction committing late can make a lower sequence visible after
+-- a higher one was streamed. Changes now get a position once committed, in
+-- the order WatchPayments finds them, and the position is the cursor.
+-- Changes already saved keep their sequence as position, so the cursors
+-- consumers hold stay valid.
+ALTER TABLE payment_status_changes
+    ADD COLUMN position BIGINT NULL AFTER sequence,
+    ADD UNIQUE INDEX idx_position (position);
+
+UPDATE payment_status_changes SET position = sequence;
+
+-- Last position given, locked while the next ones are given
+CREATE TABLE IF NOT EXISTS payment_status_change_positions (
+    id TINYINT PRIMARY KEY,
+    last_position BIGINT NOT NULL
+) ENGINE=InnoDB;
+
+INSERT INTO payment_status_change_positions (id, last_position)
+SELECT 1, COALESCE(MAX(position), 0) FROM payment_status_changes;
@@ -0,0 +1,118 @@
+package boleto_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"payments/internal/domain/boleto"
+	"payments/internal/domain/entity"
+)
+
+var issuer = boleto.Issuer{
+	BankCode:        "001",
+	Agreement:       "1234567",
+	Wallet:          "17",
+	BeneficiaryName: "Go E-commerce",
+}
+
+func TestDigitableLine(t *testing.T) {
+	// Banco do Brasil boleto used as example in FEBRABAN material
+	barcode := "00193373700000001000500940144816060680935031"
+	expected := "00190.50095 40144.816069 06809.350314 3 37370000000100"
+
+	if line := boleto.FormatDigitableLine(boleto.DigitableLine(barcode)); line != expected {
+		t.Errorf("Expected digitable line %s but got %s", expected, line)
+	}
+}
+
+func TestIssue(t *testing.T) {
+	dueDate := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	issued, err := boleto.Issue(issuer, "12345670000000001", entity.NewMoney(15050, "BRL"), dueDate)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	// bank, currency, check digit, due date factor, amount and free field
+	expectedBarcode := "001" + "9" + "9" + "1129" + "0000015050" + "000000" + "12345670000000001" + "17"
+	if issued.Barcode != expectedBarcode {
+		t.Errorf("Expected barcode %s but got %s", expectedBarcode, issued.Barcode)
+	}
+
+	expectedLine := "00190.00009 01234.567004 00000.001172 9 11290000015050"
+	if line := boleto.FormatDigitableLine(issued.DigitableLine); line != expectedLine {
+		t.Errorf("Expected digitable line %s but got %s", expectedLine, line)
+	}
+	if len(issued.DigitableLine) != 47 {
+		t.Errorf("Expected 47 digits but got %d", len(issued.DigitableLine))
+	}
+}
+
+func TestIssueDueDateFactor(t *testing.T) {
+	tests := []struct {
+		name    string
+		dueDate time.Time
+		factor  string
+	}{
+		{"Last day of the first cycle", time.Date(2025, time.February, 21, 0, 0, 0, 0, time.UTC), "9999"},
+		{"First day of the second cycle", time.Date(2025, time.February, 22, 0, 0, 0, 0, time.UTC), "1000"},
+		{"Time of day is ignored", time.Date(2025, time.February, 22, 23, 59, 0, 0, time.UTC), "1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued, err := boleto.Issue(issuer, "12345670000000001", entity.NewMoney(100, "BRL"), tt.dueDate)
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if factor := issued.Barcode[5:9]; factor != tt.factor {
+				t.Errorf("Expected factor %s but got %s", tt.factor, factor)
+			}
+		})
+	}
+}
+
+func TestIssueErrors(t *testing.T) {
+	dueDate := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		issuer boleto.Issuer
+		amount entity.Money
+		err    error
+	}{
+		{"Currency other than BRL", issuer, entity.NewMoney(100, "USD"), boleto.ErrUnsupportedCurrency},
+		{"Amount too large", issuer, entity.NewMoney(10_000_000_000, "BRL"), boleto.ErrAmountTooLarge},
+		{"Agreement with 6 digits", boleto.Issuer{BankCode: "001", Agreement: "123456", Wallet: "17", BeneficiaryName: "Loja"}, entity.NewMoney(100, "BRL"), boleto.ErrInvalidIssuer},
+		{"Missing beneficiary", boleto.Issuer{BankCode: "001", Agreement: "1234567", Wallet: "17"}, entity.NewMoney(100, "BRL"), boleto.ErrInvalidIssuer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := boleto.Issue(tt.issuer, "12345670000000001", tt.amount, dueDate)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected error %v but got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestNewNumber(t *testing.T) {
+	number, err := boleto.NewNumber(issuer)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if len(number) != boleto.NumberLength || !strings.HasPrefix(number, issuer.Agreement) {
+		t.Errorf("Expected a 17 digit number starting with the agreement but got %s", number)
+	}
+}
+
+func TestBankCodeWithDigit(t *testing.T) {
+	for bankCode, expected := range map[string]string{"001": "001-9", "237": "237-2", "341": "341-7"} {
+		if code := boleto.BankCodeWithDigit(bankCode); code != expected {
+			t.Errorf("Expected %s but got %s", expected, code)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package entity_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"payments/internal/domain/entity"
+)
+
+func TestMoneyArithmetic(t *testing.T) {
+	captured := entity.NewMoney(10000, "brl")
+
+	remaining, err := captured.Subtract(entity.NewMoney(2550, "BRL"))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if remaining != entity.NewMoney(7450, "BRL") {
+		t.Errorf("Expected BRL 74.50 but got %s", remaining)
+	}
+
+	if _, err := captured.Add(entity.NewMoney(100, "USD")); !errors.Is(err, entity.ErrCurrencyMismatch) {
+		t.Errorf("Expected ErrCurrencyMismatch but got: %v", err)
+	}
+}
+
+func TestMoneyOverflow(t *testing.T) {
+	largest := entity.NewMoney(math.MaxInt64, "BRL")
+	smallest := entity.NewMoney(math.MinInt64, "BRL")
+	one := entity.NewMoney(1, "BRL")
+
+	if _, err := largest.Add(one); !errors.Is(err, entity.ErrMoneyOverflow) {
+		t.Errorf("Expected ErrMoneyOverflow adding past the limit but got: %v", err)
+	}
+	if _, err := smallest.Subtract(one); !errors.Is(err, entity.ErrMoneyOverflow) {
+		t.Errorf("Expected ErrMoneyOverflow subtracting past the limit but got: %v", err)
+	}
+	if _, err := entity.NewMoney(math.MaxInt64/2+1, "BRL").Multiply(2); !errors.Is(err, entity.ErrMoneyOverflow) {
+		t.Errorf("Expected ErrMoneyOverflow multiplying past the limit but got: %v", err)
+	}
+	if _, err := smallest.Multiply(-1); !errors.Is(err, entity.ErrMoneyOverflow) {
+		t.Errorf("Expected ErrMoneyOverflow negating the negative limit but got: %v", err)
+	}
+
+	sum, err := entity.NewMoney(math.MaxInt64-1, "BRL").Add(one)
+	if err != nil || sum != largest {
+		t.Errorf("Expected the limit itself but got %s (error: %v)", sum, err)
+	}
+	product, err := entity.NewMoney(1050, "BRL").Multiply(3)
+	if err != nil || product != entity.NewMoney(3150, "BRL") {
+		t.Errorf("Expected BRL 31.50 but got %s (error: %v)", product, err)
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"10.5", 1050, false},
+		{"3500.00", 350000, false},
+		{"-0.01", -1, false},
+		{"92233720368547758.07", math.MaxInt64, false},
+		{"92233720368547758.08", 0, true},
+		{"1.005", 0, true},
+		{"1e3", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := entity.ParseDecimal(tt.value)
+		if tt.wantErr {
+			if !errors.Is(err, entity.ErrInvalidMoney) {
+				t.Errorf("ParseDecimal(%q): expected ErrInvalidMoney but got %d (error: %v)", tt.value, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDecimal(%q): expected %d but got %d (error: %v)", tt.value, tt.want, got, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package entity_test
+
+import (
+	"payments/internal/domain/entity"
+	"testing"
+	"time"
+)
+
+func TestPageToken(t *testing.T) {
+	cursor := entity.PageCursor{CreatedAt: time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC), ID: "payment-42"}
+
+	token := entity.EncodePageToken(cursor, false)
+	parsed, err := entity.ParsePageToken(token, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !parsed.CreatedAt.Equal(cursor.CreatedAt) || parsed.ID != cursor.ID {
+		t.Errorf("Expected cursor %v but got %v", cursor, *parsed)
+	}
+
+	if parsed, err := entity.ParsePageToken("", false); err != nil || parsed != nil {
+		t.Errorf("Expected an empty token to be the first page but got %v (error: %v)", parsed, err)
+	}
+
+	if _, err := entity.ParsePageToken(token, true); err != entity.ErrInvalidPageToken {
+		t.Errorf("Expected ErrInvalidPageToken for a token of another sort order but got: %v", err)
+	}
+
+	for _, token := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		if _, err := entity.ParsePageToken(token, false); err != entity.ErrInvalidPageToken {
+			t.Errorf("Expected ErrInvalidPageToken for %q but got: %v", token, err)
+		}
+	}
+}
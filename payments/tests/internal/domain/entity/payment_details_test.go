@@ -0,0 +1,229 @@
+package entity_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"payments/internal/domain/entity"
+)
+
+var detailsNow = time.Date(2025, time.June, 11, 15, 0, 0, 0, time.UTC) // a Wednesday
+
+func TestNewCardDetails(t *testing.T) {
+	tests := []struct {
+		name          string
+		card          entity.CardData
+		expectedBrand entity.CardBrand
+		expectedError error
+	}{
+		{
+			name:          "Valid Visa",
+			card:          entity.CardData{Number: "4111 1111 1111 1111", HolderName: "Test User", Expiry: "12/30", CVV: "123"},
+			expectedBrand: entity.CardBrandVisa,
+		},
+		{
+			name:          "Valid Mastercard with four digit year",
+			card:          entity.CardData{Number: "5555-5555-5555-4444", HolderName: "Test User", Expiry: "06/2025", CVV: "123"},
+			expectedBrand: entity.CardBrandMastercard,
+		},
+		{
+			name:          "Valid Amex needs four digit CVV",
+			card:          entity.CardData{Number: "378282246310005", HolderName: "Test User", Expiry: "12/30", CVV: "1234"},
+			expectedBrand: entity.CardBrandAmex,
+		},
+		{
+			name:          "Valid Elo",
+			card:          entity.CardData{Number: "6362970000457013", HolderName: "Test User", Expiry: "12/30", CVV: "123"},
+			expectedBrand: entity.CardBrandElo,
+		},
+		{
+			name:          "Luhn check fails",
+			card:          entity.CardData{Number: "4111111111111112", HolderName: "Test User", Expiry: "12/30", CVV: "123"},
+			expectedError: entity.ErrInvalidCardNumber,
+		},
+		{
+			name:          "Letters in number",
+			card:          entity.CardData{Number: "4111a11111111111", HolderName: "Test User", Expiry: "12/30", CVV: "123"},
+			expectedError: entity.ErrInvalidCardNumber,
+		},
+		{
+			name:          "Empty holder name",
+			card:          entity.CardData{Number: "4111111111111111", HolderName: " ", Expiry: "12/30", CVV: "123"},
+			expectedError: entity.ErrEmptyCardHolderName,
+		},
+		{
+			name:          "Expired last month",
+			card:          entity.CardData{Number: "4111111111111111", HolderName: "Test User", Expiry: "05/25", CVV: "123"},
+			expectedError: entity.ErrCardExpired,
+		},
+		{
+			name:          "Invalid expiry month",
+			card:          entity.CardData{Number: "4111111111111111", HolderName: "Test User", Expiry: "13/30", CVV: "123"},
+			expectedError: entity.ErrInvalidCardExpiry,
+		},
+		{
+			name:          "Amex with three digit CVV",
+			card:          entity.CardData{Number: "378282246310005", HolderName: "Test User", Expiry: "12/30", CVV: "123"},
+			expectedError: entity.ErrInvalidCVV,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := entity.NewCardDetails(tt.card, detailsNow)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("Expected error %v but got %v", tt.expectedError, err)
+				}
+				if !errors.Is(err, entity.ErrInvalidPaymentDetails) {
+					t.Errorf("Expected error to wrap %v but got %v", entity.ErrInvalidPaymentDetails, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if details.CardBrand != tt.expectedBrand {
+				t.Errorf("Expected brand %s but got %s", tt.expectedBrand, details.CardBrand)
+			}
+			if details.CardToken != "" {
+				t.Errorf("Expected the card token to be left to the gateway but got %q", details.CardToken)
+			}
+		})
+	}
+}
+
+func TestCardDetailsKeepOnlySafeFields(t *testing.T) {
+	card := entity.CardData{Number: "4111111111111111", HolderName: "Test User", Expiry: "12/30", CVV: "123"}
+
+	details, err := entity.NewCardDetails(card, detailsNow)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if details.CardLast4 != "1111" {
+		t.Errorf("Expected last4 1111 but got %s", details.CardLast4)
+	}
+	if details.CardExpiry != "12/2030" {
+		t.Errorf("Expected expiry 12/2030 but got %s", details.CardExpiry)
+	}
+	if card.BIN() != "411111" {
+		t.Errorf("Expected BIN 411111 but got %s", card.BIN())
+	}
+
+	stored, _ := json.Marshal(details)
+	if strings.Contains(string(stored), card.Number) || strings.Contains(string(stored), `"123"`) {
+		t.Errorf("Expected card number and CVV not to be stored but got %s", stored)
+	}
+}
+
+func TestNewPixDetails(t *testing.T) {
+	tests := []struct {
+		name         string
+		key          string
+		expectedType entity.PixKeyType
+		expectedMask string
+		expectError  bool
+	}{
+		{name: "CPF", key: "52998224725", expectedType: entity.PixKeyTypeCPF, expectedMask: "***.982.247-**"},
+		{name: "CNPJ", key: "11222333000181", expectedType: entity.PixKeyTypeCNPJ, expectedMask: "**.222.333/0001-**"},
+		{name: "Email", key: "maria@example.com", expectedType: entity.PixKeyTypeEmail, expectedMask: "m***@example.com"},
+		{name: "Phone", key: "+5511987654321", expectedType: entity.PixKeyTypePhone, expectedMask: "+5511*****4321"},
+		{name: "Random key", key: "123e4567-e89b-12d3-a456-426614174000", expectedType: entity.PixKeyTypeRandom, expectedMask: "123e4567-****-****-****-4000"},
+		{name: "CPF with wrong check digit", key: "52998224726", expectError: true},
+		{name: "Phone without country code", key: "11987654321", expectError: true},
+		{name: "Malformed email", key: "maria@", expectError: true},
+		{name: "Empty key", key: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := entity.NewPixDetails(tt.key)
+
+			if tt.expectError {
+				if !errors.Is(err, entity.ErrInvalidPixKey) {
+					t.Errorf("Expected error %v but got %v", entity.ErrInvalidPixKey, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if details.PixKeyType != tt.expectedType {
+				t.Errorf("Expected key type %s but got %s", tt.expectedType, details.PixKeyType)
+			}
+			if details.MaskedPixKey != tt.expectedMask {
+				t.Errorf("Expected masked key %s but got %s", tt.expectedMask, details.MaskedPixKey)
+			}
+		})
+	}
+}
+
+func TestNewBoletoDetails(t *testing.T) {
+	day := func(year int, month time.Month, d int) *time.Time {
+		date := time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+
+	tests := []struct {
+		name          string
+		document      string
+		dueDate       *time.Time
+		expectedDue   time.Time
+		expectedError error
+	}{
+		{name: "Default due date falls on Saturday", document: "529.982.247-25", expectedDue: *day(2025, time.June, 16)},
+		{name: "Due today", document: "11.222.333/0001-81", dueDate: day(2025, time.June, 11), expectedDue: *day(2025, time.June, 11)},
+		{name: "Sunday moves to Monday", document: "52998224725", dueDate: day(2025, time.June, 15), expectedDue: *day(2025, time.June, 16)},
+		{name: "Due in the past", document: "52998224725", dueDate: day(2025, time.June, 10), expectedError: entity.ErrInvalidBoletoDueDate},
+		{name: "Due too far ahead", document: "52998224725", dueDate: day(2025, time.December, 1), expectedError: entity.ErrInvalidBoletoDueDate},
+		{name: "Repeated digits", document: "111.111.111-11", expectedError: entity.ErrInvalidDocument},
+		{name: "Wrong CNPJ check digit", document: "11.222.333/0001-82", expectedError: entity.ErrInvalidDocument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := entity.NewBoletoDetails(tt.document, tt.dueDate, detailsNow)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("Expected error %v but got %v", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if !details.BoletoDueDate.Equal(tt.expectedDue) {
+				t.Errorf("Expected due date %v but got %v", tt.expectedDue, details.BoletoDueDate)
+			}
+			if strings.Contains(details.MaskedDocument, "529") || strings.Contains(details.MaskedDocument, "11.") {
+				t.Errorf("Expected masked document but got %s", details.MaskedDocument)
+			}
+		})
+	}
+}
+
+func TestPaymentAttachDetails(t *testing.T) {
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodPix, "test@example.com", "Test User")
+
+	card, _ := entity.NewCardDetails(entity.CardData{Number: "4111111111111111", HolderName: "Test User", Expiry: "12/30", CVV: "123"}, detailsNow)
+	if err := payment.AttachDetails(card); !errors.Is(err, entity.ErrPaymentDetailsMismatch) {
+		t.Errorf("Expected error %v but got %v", entity.ErrPaymentDetailsMismatch, err)
+	}
+
+	pix, _ := entity.NewPixDetails("maria@example.com")
+	if err := payment.AttachDetails(pix); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if payment.Details != pix {
+		t.Errorf("Expected details to be attached")
+	}
+}
@@ -0,0 +1,47 @@
+package entity_test
+
+import (
+	"payments/internal/domain/entity"
+	"testing"
+)
+
+func TestNewPaymentStatusChange(t *testing.T) {
+	payment := newApprovedPayment(t, 10000)
+	payment.Refund(entity.NewMoney(2500, "BRL"), "Late delivery")
+
+	change := entity.NewPaymentStatusChange(payment, entity.PaymentStatusApproved)
+
+	if change.PaymentID != payment.ID || change.OrderID != payment.OrderID {
+		t.Errorf("Expected change of payment %s but got %s", payment.ID, change.PaymentID)
+	}
+	if change.Status != entity.PaymentStatusPartiallyRefunded || change.PreviousStatus != entity.PaymentStatusApproved {
+		t.Errorf("Expected approved to partially_refunded but got %s to %s", change.PreviousStatus, change.Status)
+	}
+	if change.RefundedAmount != entity.NewMoney(2500, "BRL") {
+		t.Errorf("Expected refunded amount 2500 BRL but got %s", change.RefundedAmount.String())
+	}
+
+	created, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodPix, "test@example.com", "Test User")
+	if refunded := entity.NewPaymentStatusChange(created, "").RefundedAmount; refunded != entity.Zero("BRL") {
+		t.Errorf("Expected zero refunded amount but got %s", refunded.String())
+	}
+}
+
+func TestPaymentStatusChangeCursor(t *testing.T) {
+	change := entity.PaymentStatusChange{Sequence: 7, Position: 42}
+
+	position, err := entity.ParseCursor(change.Cursor())
+	if err != nil || position != 42 {
+		t.Errorf("Expected position 42 but got %d (error: %v)", position, err)
+	}
+
+	if position, err := entity.ParseCursor(""); err != nil || position != 0 {
+		t.Errorf("Expected an empty cursor to start at 0 but got %d (error: %v)", position, err)
+	}
+
+	for _, cursor := range []string{"abc", "-1", "1.5"} {
+		if _, err := entity.ParseCursor(cursor); err != entity.ErrInvalidCursor {
+			t.Errorf("Expected ErrInvalidCursor for %q but got: %v", cursor, err)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package entity_test
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"payments/internal/domain/entity"
 )
@@ -10,7 +13,7 @@ func TestNewPayment(t *testing.T) {
 	tests := []struct {
 		name          string
 		orderID       string
-		amount        float64
+		amount        entity.Money
 		paymentMethod entity.PaymentMethod
 		customerEmail string
 		customerName  string
@@ -19,7 +22,7 @@ func TestNewPayment(t *testing.T) {
 		{
 			name:          "Valid payment",
 			orderID:       "order-123",
-			amount:        100.50,
+			amount:        entity.NewMoney(10050, "BRL"),
 			paymentMethod: entity.PaymentMethodCreditCard,
 			customerEmail: "test@example.com",
 			customerName:  "Test User",
@@ -28,7 +31,7 @@ func TestNewPayment(t *testing.T) {
 		{
 			name:          "Invalid amount - zero",
 			orderID:       "order-123",
-			amount:        0,
+			amount:        entity.NewMoney(0, "BRL"),
 			paymentMethod: entity.PaymentMethodCreditCard,
 			customerEmail: "test@example.com",
 			customerName:  "Test User",
@@ -37,7 +40,7 @@ func TestNewPayment(t *testing.T) {
 		{
 			name:          "Invalid amount - negative",
 			orderID:       "order-123",
-			amount:        -10.0,
+			amount:        entity.NewMoney(-1000, "BRL"),
 			paymentMethod: entity.PaymentMethodCreditCard,
 			customerEmail: "test@example.com",
 			customerName:  "Test User",
@@ -46,7 +49,7 @@ func TestNewPayment(t *testing.T) {
 		{
 			name:          "Empty order ID",
 			orderID:       "",
-			amount:        100.0,
+			amount:        entity.NewMoney(10000, "BRL"),
 			paymentMethod: entity.PaymentMethodCreditCard,
 			customerEmail: "test@example.com",
 			customerName:  "Test User",
@@ -55,16 +58,25 @@ func TestNewPayment(t *testing.T) {
 		{
 			name:          "Empty customer email",
 			orderID:       "order-123",
-			amount:        100.0,
+			amount:        entity.NewMoney(10000, "BRL"),
 			paymentMethod: entity.PaymentMethodCreditCard,
 			customerEmail: "",
 			customerName:  "Test User",
 			expectError:   true,
 		},
+		{
+			name:          "Invalid currency",
+			orderID:       "order-123",
+			amount:        entity.NewMoney(10000, "REAL"),
+			paymentMethod: entity.PaymentMethodCreditCard,
+			customerEmail: "test@example.com",
+			customerName:  "Test User",
+			expectError:   true,
+		},
 		{
 			name:          "Invalid payment method",
 			orderID:       "order-123",
-			amount:        100.0,
+			amount:        entity.NewMoney(10000, "BRL"),
 			paymentMethod: "invalid",
 			customerEmail: "test@example.com",
 			customerName:  "Test User",
@@ -98,7 +110,7 @@ func TestNewPayment(t *testing.T) {
 					t.Errorf("Expected order_id %s but got %s", tt.orderID, payment.OrderID)
 				}
 				if payment.Amount != tt.amount {
-					t.Errorf("Expected amount %.2f but got %.2f", tt.amount, payment.Amount)
+					t.Errorf("Expected amount %s but got %s", tt.amount, payment.Amount)
 				}
 			}
 		})
@@ -106,7 +118,7 @@ func TestNewPayment(t *testing.T) {
 }
 
 func TestPaymentProcess(t *testing.T) {
-	payment, _ := entity.NewPayment("order-123", 100.0, entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
 
 	err := payment.Process("txn-123")
 	if err != nil {
@@ -129,7 +141,7 @@ func TestPaymentProcess(t *testing.T) {
 }
 
 func TestPaymentApprove(t *testing.T) {
-	payment, _ := entity.NewPayment("order-123", 100.0, entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
 	payment.Process("txn-123")
 
 	err := payment.Approve()
@@ -149,7 +161,7 @@ func TestPaymentApprove(t *testing.T) {
 }
 
 func TestPaymentDecline(t *testing.T) {
-	payment, _ := entity.NewPayment("order-123", 100.0, entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
 	payment.Process("txn-123")
 
 	err := payment.Decline()
@@ -163,7 +175,7 @@ func TestPaymentDecline(t *testing.T) {
 }
 
 func TestPaymentCancel(t *testing.T) {
-	payment, _ := entity.NewPayment("order-123", 100.0, entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
 
 	err := payment.Cancel("Customer requested cancellation")
 	if err != nil {
@@ -184,7 +196,7 @@ func TestPaymentCancel(t *testing.T) {
 }
 
 func TestPaymentCannotBeCanceled(t *testing.T) {
-	payment, _ := entity.NewPayment("order-123", 100.0, entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
 	payment.Process("txn-123")
 	payment.Approve()
 
@@ -210,11 +222,12 @@ func TestPaymentCanBeCanceled(t *testing.T) {
 		{"Declined can be canceled", entity.PaymentStatusDeclined, true},
 		{"Canceled cannot be canceled", entity.PaymentStatusCanceled, false},
 		{"Refunded cannot be canceled", entity.PaymentStatusRefunded, false},
+		{"Partially refunded cannot be canceled", entity.PaymentStatusPartiallyRefunded, false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			payment, _ := entity.NewPayment("order-123", 100.0, entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+			payment, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
 			payment.Status = tt.status
 
 			result := payment.CanBeCanceled()
@@ -224,3 +237,356 @@ func TestPaymentCanBeCanceled(t *testing.T) {
 		})
 	}
 }
+
+func newApprovedPayment(t *testing.T, cents int64) *entity.Payment {
+	t.Helper()
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(cents, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	payment.Process("txn-123")
+	payment.Approve()
+	return payment
+}
+
+func TestPaymentPartialRefunds(t *testing.T) {
+	payment := newApprovedPayment(t, 10000)
+
+	refund, err := payment.Refund(entity.NewMoney(3000, "BRL"), "Damaged item")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if refund.PaymentID != payment.ID || refund.Reason != "Damaged item" || refund.Amount.Amount != 3000 {
+		t.Errorf("Unexpected refund record: %+v", refund)
+	}
+	if payment.Status != entity.PaymentStatusPartiallyRefunded {
+		t.Errorf("Expected status %s but got %s", entity.PaymentStatusPartiallyRefunded, payment.Status)
+	}
+	if payment.RefundableAmount().Amount != 7000 {
+		t.Errorf("Expected 7000 refundable but got %d", payment.RefundableAmount().Amount)
+	}
+
+	if _, err := payment.Refund(entity.NewMoney(7001, "BRL"), ""); err != entity.ErrRefundExceedsAmount {
+		t.Errorf("Expected ErrRefundExceedsAmount but got: %v", err)
+	}
+
+	if _, err := payment.Refund(entity.NewMoney(7000, "BRL"), ""); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if payment.Status != entity.PaymentStatusRefunded {
+		t.Errorf("Expected status %s but got %s", entity.PaymentStatusRefunded, payment.Status)
+	}
+	if payment.RefundedAmount.Amount != 10000 {
+		t.Errorf("Expected 10000 refunded but got %d", payment.RefundedAmount.Amount)
+	}
+
+	if _, err := payment.Refund(entity.NewMoney(1, "BRL"), ""); err != entity.ErrPaymentCannotBeRefunded {
+		t.Errorf("Expected ErrPaymentCannotBeRefunded but got: %v", err)
+	}
+}
+
+func TestPaymentRefundValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      entity.Money
+		expectedErr error
+	}{
+		{"Zero amount", entity.NewMoney(0, "BRL"), entity.ErrInvalidRefundAmount},
+		{"Negative amount", entity.NewMoney(-100, "BRL"), entity.ErrInvalidRefundAmount},
+		{"Other currency", entity.NewMoney(100, "USD"), entity.ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := newApprovedPayment(t, 10000)
+
+			_, err := payment.Refund(tt.amount, "")
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Expected %v but got: %v", tt.expectedErr, err)
+			}
+			if payment.Status != entity.PaymentStatusApproved {
+				t.Errorf("Expected status to stay %s but got %s", entity.PaymentStatusApproved, payment.Status)
+			}
+		})
+	}
+
+	pending, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	if _, err := pending.Refund(entity.NewMoney(100, "BRL"), ""); err != entity.ErrPaymentCannotBeRefunded {
+		t.Errorf("Expected ErrPaymentCannotBeRefunded but got: %v", err)
+	}
+}
+
+func newAuthorizedPayment(t *testing.T, cents int64, expiresAt time.Time) *entity.Payment {
+	t.Helper()
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(cents, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	payment.Process("txn-123")
+	if err := payment.Authorize(expiresAt); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return payment
+}
+
+func TestPaymentAuthorize(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	payment := newAuthorizedPayment(t, 10000, expiresAt)
+
+	if payment.Status != entity.PaymentStatusAuthorized {
+		t.Errorf("Expected status %s but got %s", entity.PaymentStatusAuthorized, payment.Status)
+	}
+	if payment.AuthorizedAt == nil || !payment.AuthorizationExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected authorization window to be set, got %v - %v", payment.AuthorizedAt, payment.AuthorizationExpiresAt)
+	}
+	if !payment.CanBeCanceled() || payment.IsFinalized() {
+		t.Error("Expected an authorized payment to be open")
+	}
+	if payment.RefundableAmount().Amount != 0 {
+		t.Errorf("Expected nothing refundable before capture but got %d", payment.RefundableAmount().Amount)
+	}
+
+	pix, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodPix, "test@example.com", "Test User")
+	pix.Process("txn-123")
+	if err := pix.Authorize(expiresAt); err != entity.ErrAuthorizationNotSupported {
+		t.Errorf("Expected ErrAuthorizationNotSupported but got: %v", err)
+	}
+}
+
+func TestPaymentCapture(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      entity.Money
+		expectedErr error
+	}{
+		{"Full amount", entity.NewMoney(10000, "BRL"), nil},
+		{"Partial amount", entity.NewMoney(6000, "BRL"), nil},
+		{"Zero amount", entity.NewMoney(0, "BRL"), entity.ErrInvalidCaptureAmount},
+		{"Above authorization", entity.NewMoney(10001, "BRL"), entity.ErrCaptureExceedsAuthorization},
+		{"Other currency", entity.NewMoney(100, "USD"), entity.ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := newAuthorizedPayment(t, 10000, time.Now().Add(time.Hour))
+
+			err := payment.Capture(tt.amount)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected %v but got: %v", tt.expectedErr, err)
+			}
+			if err != nil {
+				if payment.Status != entity.PaymentStatusAuthorized {
+					t.Errorf("Expected status to stay %s but got %s", entity.PaymentStatusAuthorized, payment.Status)
+				}
+				return
+			}
+
+			if payment.Status != entity.PaymentStatusApproved {
+				t.Errorf("Expected status %s but got %s", entity.PaymentStatusApproved, payment.Status)
+			}
+			if payment.CapturedAmount != tt.amount {
+				t.Errorf("Expected %v captured but got %v", tt.amount, payment.CapturedAmount)
+			}
+			// Only the captured amount can be refunded
+			if payment.RefundableAmount() != tt.amount {
+				t.Errorf("Expected %v refundable but got %v", tt.amount, payment.RefundableAmount())
+			}
+		})
+	}
+}
+
+func TestPaymentCaptureRequiresOpenAuthorization(t *testing.T) {
+	approved := newApprovedPayment(t, 10000)
+	if err := approved.Capture(entity.NewMoney(10000, "BRL")); err != entity.ErrPaymentNotAuthorized {
+		t.Errorf("Expected ErrPaymentNotAuthorized but got: %v", err)
+	}
+
+	expired := newAuthorizedPayment(t, 10000, time.Now().Add(-time.Second))
+	if err := expired.Capture(entity.NewMoney(10000, "BRL")); err != entity.ErrAuthorizationExpired {
+		t.Errorf("Expected ErrAuthorizationExpired but got: %v", err)
+	}
+}
+
+func TestPaymentVoid(t *testing.T) {
+	payment := newAuthorizedPayment(t, 10000, time.Now().Add(time.Hour))
+
+	if err := payment.Void("Order canceled"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if payment.Status != entity.PaymentStatusCanceled || payment.CancelReason != "Order canceled" {
+		t.Errorf("Expected canceled payment but got status %s, reason %q", payment.Status, payment.CancelReason)
+	}
+
+	if err := payment.Void(""); err != entity.ErrPaymentNotAuthorized {
+		t.Errorf("Expected ErrPaymentNotAuthorized but got: %v", err)
+	}
+	if err := newApprovedPayment(t, 10000).Void(""); err != entity.ErrPaymentNotAuthorized {
+		t.Errorf("Expected ErrPaymentNotAuthorized but got: %v", err)
+	}
+}
+
+func TestPaymentExpire(t *testing.T) {
+	now := time.Now()
+	payment := newAuthorizedPayment(t, 10000, now.Add(time.Hour))
+
+	if err := payment.Expire(now); err != entity.ErrPaymentNotAuthorized {
+		t.Errorf("Expected ErrPaymentNotAuthorized before the window ends but got: %v", err)
+	}
+
+	if err := payment.Expire(now.Add(time.Hour)); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if payment.Status != entity.PaymentStatusExpired || !payment.IsFinalized() || payment.CanBeCanceled() {
+		t.Errorf("Expected a finalized expired payment but got status %s", payment.Status)
+	}
+}
+
+func newPixCharge(t *testing.T, expiresAt time.Time) *entity.Payment {
+	t.Helper()
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodPix, "test@example.com", "Test User")
+	if err := payment.AwaitConfirmation("txn-123", expiresAt); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return payment
+}
+
+func TestPaymentAwaitConfirmation(t *testing.T) {
+	payment := newPixCharge(t, time.Now().Add(time.Hour))
+
+	if payment.Status != entity.PaymentStatusPending || !payment.IsAwaitingConfirmation() {
+		t.Errorf("Expected a pending charge but got status %s", payment.Status)
+	}
+	if payment.TransactionID != "txn-123" {
+		t.Errorf("Expected transaction ID txn-123 but got %s", payment.TransactionID)
+	}
+
+	card, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	if err := card.AwaitConfirmation("txn-456", time.Now().Add(time.Hour)); err != entity.ErrConfirmationNotSupported {
+		t.Errorf("Expected ErrConfirmationNotSupported but got: %v", err)
+	}
+}
+
+func TestPaymentDeclineAwaitingCharge(t *testing.T) {
+	payment := newPixCharge(t, time.Now().Add(time.Hour))
+
+	if err := payment.Decline(); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if payment.Status != entity.PaymentStatusDeclined {
+		t.Errorf("Expected status %s but got %s", entity.PaymentStatusDeclined, payment.Status)
+	}
+
+	pending, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	if err := pending.Decline(); err == nil {
+		t.Error("Expected an error declining a payment not yet processed")
+	}
+}
+
+func TestPaymentConfirm(t *testing.T) {
+	now := time.Now()
+	payment := newPixCharge(t, now.Add(time.Hour))
+
+	if err := payment.Confirm(now); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if payment.Status != entity.PaymentStatusApproved || payment.CapturedAmount != payment.Amount {
+		t.Errorf("Expected an approved payment but got status %s, captured %v", payment.Status, payment.CapturedAmount)
+	}
+	if len(payment.Events()) != 1 || payment.Events()[0].Type != entity.EventPaymentApproved {
+		t.Errorf("Expected a PaymentApproved event but got %v", payment.Events())
+	}
+	if err := payment.Confirm(now); err != entity.ErrPaymentNotAwaiting {
+		t.Errorf("Expected ErrPaymentNotAwaiting but got: %v", err)
+	}
+
+	late := newPixCharge(t, now)
+	if err := late.Confirm(now); err != entity.ErrPaymentExpired {
+		t.Errorf("Expected ErrPaymentExpired but got: %v", err)
+	}
+}
+
+func TestPaymentExpireCharge(t *testing.T) {
+	now := time.Now()
+	payment := newPixCharge(t, now.Add(time.Hour))
+
+	if err := payment.Expire(now); err == nil {
+		t.Errorf("Expected an error before the charge expires")
+	}
+
+	if err := payment.Expire(now.Add(time.Hour)); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if payment.Status != entity.PaymentStatusExpired || !payment.IsFinalized() {
+		t.Errorf("Expected a finalized expired payment but got status %s", payment.Status)
+	}
+}
+
+func TestPaymentMatchesRequest(t *testing.T) {
+	payment, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodPix, "test@example.com", "Test User")
+
+	tests := []struct {
+		name     string
+		orderID  string
+		amount   entity.Money
+		expected bool
+	}{
+		{"Same order and amount", "order-123", entity.NewMoney(10000, "BRL"), true},
+		{"Different order", "order-456", entity.NewMoney(10000, "BRL"), false},
+		{"Different amount", "order-123", entity.NewMoney(9999, "BRL"), false},
+		{"Different currency", "order-123", entity.NewMoney(10000, "USD"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := payment.MatchesRequest(tt.orderID, tt.amount); got != tt.expected {
+				t.Errorf("Expected MatchesRequest() to return %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPaymentRecordsEvents(t *testing.T) {
+	payment := newApprovedPayment(t, 10000)
+
+	events := payment.Events()
+	if len(events) != 1 || events[0].Type != entity.EventPaymentApproved {
+		t.Fatalf("Expected one PaymentApproved event but got %+v", events)
+	}
+	if events[0].AggregateType != entity.AggregatePayment || events[0].AggregateID != payment.ID {
+		t.Errorf("Unexpected event aggregate %s/%s", events[0].AggregateType, events[0].AggregateID)
+	}
+	payment.ClearEvents()
+
+	refund, _ := payment.Refund(entity.NewMoney(2500, "BRL"), "Late delivery")
+
+	events = payment.Events()
+	if len(events) != 1 || events[0].Type != entity.EventPaymentRefunded {
+		t.Fatalf("Expected one PaymentRefunded event but got %+v", events)
+	}
+
+	var payload entity.PaymentEventPayload
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil {
+		t.Fatalf("Expected JSON payload but got error: %v", err)
+	}
+	if payload.Refund == nil || payload.Refund.ID != refund.ID || payload.RefundedAmount.Amount != 2500 {
+		t.Errorf("Unexpected PaymentRefunded payload: %+v", payload)
+	}
+
+	declined, _ := entity.NewPayment("order-123", entity.NewMoney(10000, "BRL"), entity.PaymentMethodCreditCard, "test@example.com", "Test User")
+	declined.Process("txn-456")
+	declined.Decline()
+	if len(declined.Events()) != 0 {
+		t.Errorf("Expected no events for a declined payment but got %d", len(declined.Events()))
+	}
+}
+
+func TestPaymentSavedStatus(t *testing.T) {
+	payment := newAuthorizedPayment(t, 10000, time.Now().Add(time.Hour))
+	payment.MarkSaved()
+
+	if err := payment.Capture(payment.Amount); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if payment.SavedStatus() != entity.PaymentStatusAuthorized {
+		t.Errorf("Expected the saved status to stay authorized until saved but got %s", payment.SavedStatus())
+	}
+
+	payment.MarkSaved()
+	if payment.SavedStatus() != entity.PaymentStatusApproved {
+		t.Errorf("Expected saved status approved but got %s", payment.SavedStatus())
+	}
+}
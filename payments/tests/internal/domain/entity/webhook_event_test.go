@@ -0,0 +1,47 @@
+package entity_test
+
+import (
+	"payments/internal/domain/entity"
+	"testing"
+	"time"
+)
+
+func TestNewWebhookEvent(t *testing.T) {
+	event, err := entity.NewWebhookEvent("evt_123", entity.WebhookPaymentApproved, "payment-123", []byte(`{"id":"evt_123"}`), "t=1,v1=ab")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if event.ID == "" || event.EventID != "evt_123" {
+		t.Errorf("Expected a new ID and event ID evt_123 but got %s/%s", event.ID, event.EventID)
+	}
+	if event.Status != entity.WebhookEventReceived || event.IsHandled() {
+		t.Errorf("Expected a received event but got status %s", event.Status)
+	}
+	if event.ProcessedAt != nil {
+		t.Errorf("Expected no processed time but got %v", event.ProcessedAt)
+	}
+
+	if _, err := entity.NewWebhookEvent("", entity.WebhookPaymentApproved, "payment-123", nil, ""); err != entity.ErrEmptyWebhookEventID {
+		t.Errorf("Expected ErrEmptyWebhookEventID but got: %v", err)
+	}
+}
+
+func TestWebhookEventMarkProcessedAndIgnored(t *testing.T) {
+	now := time.Now()
+
+	processed, _ := entity.NewWebhookEvent("evt_1", entity.WebhookPaymentDeclined, "payment-123", nil, "")
+	processed.MarkProcessed(now, "payment declined")
+	if processed.Status != entity.WebhookEventProcessed || !processed.IsHandled() {
+		t.Errorf("Expected a processed event but got status %s", processed.Status)
+	}
+	if processed.Result != "payment declined" || processed.ProcessedAt == nil || !processed.ProcessedAt.Equal(now) {
+		t.Errorf("Unexpected result %q at %v", processed.Result, processed.ProcessedAt)
+	}
+
+	ignored, _ := entity.NewWebhookEvent("evt_2", entity.WebhookPaymentRefunded, "missing", nil, "")
+	ignored.MarkIgnored(now, "payment not found")
+	if ignored.Status != entity.WebhookEventIgnored || !ignored.IsHandled() || ignored.Result != "payment not found" {
+		t.Errorf("Expected an ignored event but got status %s with %q", ignored.Status, ignored.Result)
+	}
+}
@@ -0,0 +1,112 @@
+package pix_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"payments/internal/domain/entity"
+	"payments/internal/domain/pix"
+)
+
+var merchant = pix.Merchant{
+	Key:  "pagamentos@example.com",
+	Name: "Loja São João",
+	City: "São Paulo",
+}
+
+func TestCRC16(t *testing.T) {
+	// Example from the BACEN "Manual do BR Code"
+	payload := "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-426655440000" +
+		"5204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***6304"
+
+	if crc := pix.CRC16(payload); crc != "1D3D" {
+		t.Errorf("Expected CRC 1D3D but got %s", crc)
+	}
+	if crc := pix.CRC16("123456789"); crc != "29B1" {
+		t.Errorf("Expected CRC 29B1 but got %s", crc)
+	}
+}
+
+func TestEncodeBRCode(t *testing.T) {
+	code, err := pix.EncodeBRCode(pix.Charge{
+		Merchant:    merchant,
+		Amount:      entity.NewMoney(12345, "BRL"),
+		TxID:        "3f2b9c1e-7a4d-4b8e-9c6f-2d1e0a9b8c7d",
+		Description: "Pedido 42",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	expectedFields := []string{
+		"000201",                             // payload format
+		"010212",                             // single use
+		"0014br.gov.bcb.pix",                 // PIX GUI
+		"0122pagamentos@example.com",         // merchant key
+		"0209PEDIDO 42",                      // description
+		"5303986",                            // BRL
+		"5406123.45",                         // amount
+		"5802BR",                             // country
+		"5913LOJA SAO JOAO",                  // name without accents
+		"6009SAO PAULO",                      // city without accents
+		"0525" + "3f2b9c1e7a4d4b8e9c6f2d1e0", // txid, alphanumeric and truncated
+	}
+	for _, field := range expectedFields {
+		if !strings.Contains(code, field) {
+			t.Errorf("Expected BR Code to contain %q but got %s", field, code)
+		}
+	}
+
+	body, crc := code[:len(code)-4], code[len(code)-4:]
+	if !strings.HasSuffix(body, "6304") || pix.CRC16(body) != crc {
+		t.Errorf("Expected BR Code to end with a valid CRC but got %s", code)
+	}
+}
+
+func TestEncodeBRCodeFitsDescriptionAfterLongKey(t *testing.T) {
+	evp := merchant
+	evp.Key = "123e4567-e12b-12d1-a456-426655440000"
+
+	code, err := pix.EncodeBRCode(pix.Charge{
+		Merchant:    evp,
+		Amount:      entity.NewMoney(12345, "BRL"),
+		TxID:        "3f2b9c1e-7a4d-4b8e-9c6f-2d1e0a9b8c7d",
+		Description: "Pedido 3f2b9c1e-7a4d-4b8e-9c6f-2d1e0a9b8c7d",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	// GUI (18) + key (40) + description (4 + 37) fill the 99 characters
+	account := "2699" + "0014br.gov.bcb.pix" + "0136" + evp.Key + "0237" + "PEDIDO 3F2B9C1E-7A4D-4B8E-9C6F-2D1E0A"
+	if !strings.Contains(code, account) {
+		t.Errorf("Expected BR Code to contain %q but got %s", account, code)
+	}
+
+	body, crc := code[:len(code)-4], code[len(code)-4:]
+	if pix.CRC16(body) != crc {
+		t.Errorf("Expected BR Code to end with a valid CRC but got %s", code)
+	}
+}
+
+func TestEncodeBRCodeRejectsInvalidCharges(t *testing.T) {
+	_, err := pix.EncodeBRCode(pix.Charge{Merchant: merchant, Amount: entity.NewMoney(1000, "USD")})
+	if !errors.Is(err, pix.ErrUnsupportedCurrency) {
+		t.Errorf("Expected error %v but got %v", pix.ErrUnsupportedCurrency, err)
+	}
+
+	longName := merchant
+	longName.Name = "Uma Loja Com Um Nome Muito Comprido"
+	_, err = pix.EncodeBRCode(pix.Charge{Merchant: longName, Amount: entity.NewMoney(1000, "BRL")})
+	if !errors.Is(err, pix.ErrInvalidMerchant) {
+		t.Errorf("Expected error %v but got %v", pix.ErrInvalidMerchant, err)
+	}
+
+	longKey := merchant
+	longKey.Key = strings.Repeat("a", 70) + "@ex.com.br"
+	_, err = pix.EncodeBRCode(pix.Charge{Merchant: longKey, Amount: entity.NewMoney(1000, "BRL")})
+	if !errors.Is(err, pix.ErrInvalidMerchant) {
+		t.Errorf("Expected error %v but got %v", pix.ErrInvalidMerchant, err)
+	}
+}
@@ -0,0 +1,91 @@
+package boleto_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	domain "payments/internal/domain/boleto"
+	"payments/internal/domain/entity"
+	"payments/internal/infra/boleto"
+)
+
+func TestParseSettlementFile(t *testing.T) {
+	location := time.FixedZone("BRT", -3*60*60)
+	file := "number,amount_cents,paid_at\n" +
+		"12345670000000001,15050,2025-07-01T10:30:00-03:00\n" +
+		"12345670000000002, 990, 2025-07-02\n"
+
+	settlements, err := boleto.ParseSettlementFile(strings.NewReader(file), location)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if len(settlements) != 2 {
+		t.Fatalf("Expected 2 settlements but got %d", len(settlements))
+	}
+
+	if settlements[0].Number != "12345670000000001" || settlements[0].PaidAmount != entity.NewMoney(15050, "BRL") {
+		t.Errorf("Expected first boleto paid with 15050 but got %+v", settlements[0])
+	}
+	expectedPaidAt := time.Date(2025, time.July, 2, 0, 0, 0, 0, location)
+	if !settlements[1].PaidAt.Equal(expectedPaidAt) {
+		t.Errorf("Expected date read in the bank timezone %v but got %v", expectedPaidAt, settlements[1].PaidAt)
+	}
+}
+
+func TestParseSettlementFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"Wrong header", "nosso_numero,valor,data\n123,100,2025-07-01\n"},
+		{"Invalid amount", "number,amount_cents,paid_at\n123,10.50,2025-07-01\n"},
+		{"Invalid date", "number,amount_cents,paid_at\n123,100,01/07/2025\n"},
+		{"Missing column", "number,amount_cents,paid_at\n123,100\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := boleto.ParseSettlementFile(strings.NewReader(tt.file), time.UTC); err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+		})
+	}
+}
+
+func TestHTMLSlipRenderer(t *testing.T) {
+	issuer := domain.Issuer{BankCode: "001", Agreement: "1234567", Wallet: "17", BeneficiaryName: "Go E-commerce"}
+	issued, err := domain.Issue(issuer, "12345670000000001", entity.NewMoney(123456, "BRL"), time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	contentType, content, err := boleto.NewHTMLSlipRenderer().Render(domain.Slip{
+		Issuer:        issuer,
+		Boleto:        *issued,
+		Amount:        entity.NewMoney(123456, "BRL"),
+		PayerName:     "Maria <Silva>",
+		PayerDocument: "***.456.789-**",
+		IssuedAt:      time.Date(2025, time.June, 28, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !strings.HasPrefix(contentType, "text/html") {
+		t.Errorf("Expected HTML but got %s", contentType)
+	}
+
+	html := string(content)
+	for _, expected := range []string{
+		"001-9",
+		domain.FormatDigitableLine(issued.DigitableLine),
+		"01/07/2025",
+		"R$ 1.234,56",
+		"Maria &lt;Silva&gt;", // payer data is escaped
+		"<svg",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected slip to contain %q", expected)
+		}
+	}
+}
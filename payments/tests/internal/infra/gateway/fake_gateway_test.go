@@ -0,0 +1,300 @@
+package gateway_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	infra "payments/internal/infra/gateway"
+)
+
+func newRequest(method entity.PaymentMethod, cents int64, email, bin string) gateway.Request {
+	return gateway.Request{
+		PaymentID:     "payment-123",
+		OrderID:       "order-123",
+		Amount:        entity.NewMoney(cents, "BRL"),
+		Method:        method,
+		CustomerEmail: email,
+		CardBIN:       bin,
+	}
+}
+
+func TestFakeGatewayRules(t *testing.T) {
+	fake := infra.NewFakeGateway([]infra.FakeRule{
+		{CardBIN: "400000", Outcome: infra.FakeOutcomeDecline, ResponseCode: "51", Message: "Insufficient funds"},
+		{Email: "Blocked@Example.com", Outcome: infra.FakeOutcomeDecline, ResponseCode: "59"},
+		{Method: entity.PaymentMethodPix, MinAmount: 50000, Outcome: infra.FakeOutcomeDecline, ResponseCode: "61"},
+	}, time.Second)
+
+	tests := []struct {
+		name         string
+		req          gateway.Request
+		wantStatus   gateway.Status
+		wantResponse string
+	}{
+		{"Declined BIN", newRequest(entity.PaymentMethodCreditCard, 1000, "a@example.com", "400000"), gateway.StatusDeclined, "51"},
+		{"Other BIN", newRequest(entity.PaymentMethodCreditCard, 1000, "a@example.com", "555555"), gateway.StatusApproved, "00"},
+		{"Blocked email ignoring case", newRequest(entity.PaymentMethodPayPal, 1000, "blocked@example.com", ""), gateway.StatusDeclined, "59"},
+		{"PIX above limit", newRequest(entity.PaymentMethodPix, 50000, "a@example.com", ""), gateway.StatusDeclined, "61"},
+		{"PIX below limit", newRequest(entity.PaymentMethodPix, 49999, "a@example.com", ""), gateway.StatusApproved, "00"},
+		{"Limit is only for PIX", newRequest(entity.PaymentMethodBoleto, 50000, "a@example.com", ""), gateway.StatusApproved, "00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := fake.Process(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if response.Status != tt.wantStatus || response.ResponseCode != tt.wantResponse {
+				t.Errorf("Expected %s/%s but got %s/%s", tt.wantStatus, tt.wantResponse, response.Status, response.ResponseCode)
+			}
+			if response.TransactionID == "" {
+				t.Error("Expected a transaction ID")
+			}
+			if response.Approved() && response.AuthorizationCode == "" {
+				t.Error("Expected an authorization code on approval")
+			}
+		})
+	}
+}
+
+func TestFakeGatewayFailures(t *testing.T) {
+	fake := infra.NewFakeGateway([]infra.FakeRule{
+		{Email: "timeout@example.com", Outcome: infra.FakeOutcomeTimeout},
+		{Email: "down@example.com", Outcome: infra.FakeOutcomeUnavailable},
+	}, 10*time.Millisecond)
+
+	_, err := fake.Process(context.Background(), newRequest(entity.PaymentMethodCreditCard, 1000, "timeout@example.com", ""))
+	if !errors.Is(err, gateway.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout but got: %v", err)
+	}
+
+	_, err = fake.Process(context.Background(), newRequest(entity.PaymentMethodCreditCard, 1000, "down@example.com", ""))
+	if !errors.Is(err, gateway.ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable but got: %v", err)
+	}
+}
+
+func TestDefaultFakeRules(t *testing.T) {
+	fake := infra.NewFakeGateway(infra.DefaultFakeRules(), time.Second)
+
+	response, _ := fake.Process(context.Background(), newRequest(entity.PaymentMethodCreditCard, 999999, "a@example.com", ""))
+	if !response.Approved() {
+		t.Errorf("Expected amounts under 10000.00 to be approved, got %s", response.Status)
+	}
+
+	response, _ = fake.Process(context.Background(), newRequest(entity.PaymentMethodCreditCard, 1000000, "a@example.com", ""))
+	if response.Approved() {
+		t.Error("Expected amounts of 10000.00 or more to be declined")
+	}
+}
+
+func TestLoadFakeRules(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "rules.json")
+	os.WriteFile(valid, []byte(`[{"card_bin": "400000", "outcome": "decline", "response_code": "05"}]`), 0o644)
+	rules, err := infra.LoadFakeRules(valid)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(rules) != 1 || rules[0].CardBIN != "400000" || rules[0].Outcome != infra.FakeOutcomeDecline {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`[{"outcome": "explode"}]`), 0o644)
+	if _, err := infra.LoadFakeRules(invalid); err == nil {
+		t.Error("Expected an error for an unknown outcome")
+	}
+}
+
+func TestRouterRejectsUnconfiguredMethod(t *testing.T) {
+	router := infra.NewRouter(map[entity.PaymentMethod]gateway.PaymentGateway{
+		entity.PaymentMethodCreditCard: infra.NewFakeGateway(nil, time.Second),
+	})
+
+	_, err := router.Process(context.Background(), newRequest(entity.PaymentMethodPix, 1000, "a@example.com", ""))
+	if !errors.Is(err, gateway.ErrUnsupportedMethod) {
+		t.Errorf("Expected ErrUnsupportedMethod but got: %v", err)
+	}
+}
+
+func TestHTTPGatewayAgainstStub(t *testing.T) {
+	stub := httptest.NewServer(infra.NewStubHandler(infra.NewFakeGateway([]infra.FakeRule{
+		{CardBIN: "400000", Outcome: infra.FakeOutcomeDecline, ResponseCode: "51", Message: "Insufficient funds"},
+		{Email: "timeout@example.com", Outcome: infra.FakeOutcomeTimeout},
+	}, time.Second)))
+	defer stub.Close()
+
+	gw, err := infra.New(infra.Config{Kind: "http", BaseURL: stub.URL, Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	for _, method := range []entity.PaymentMethod{
+		entity.PaymentMethodCreditCard,
+		entity.PaymentMethodDebitCard,
+		entity.PaymentMethodPix,
+		entity.PaymentMethodBoleto,
+		entity.PaymentMethodPayPal,
+	} {
+		response, err := gw.Process(context.Background(), newRequest(method, 1000, "a@example.com", ""))
+		if err != nil {
+			t.Fatalf("%s: expected no error but got: %v", method, err)
+		}
+		if !response.Approved() || response.AuthorizationCode == "" {
+			t.Errorf("%s: expected an approval with authorization code, got %+v", method, response)
+		}
+	}
+
+	response, err := gw.Process(context.Background(), newRequest(entity.PaymentMethodCreditCard, 1000, "a@example.com", "400000"))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if response.Status != gateway.StatusDeclined || response.ResponseCode != "51" || response.Message != "Insufficient funds" {
+		t.Errorf("Expected decline 51 but got %+v", response)
+	}
+
+	_, err = gw.Process(context.Background(), newRequest(entity.PaymentMethodCreditCard, 1000, "timeout@example.com", ""))
+	if !errors.Is(err, gateway.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout but got: %v", err)
+	}
+}
+
+func newOperation(method entity.PaymentMethod, cents int64) gateway.OperationRequest {
+	return gateway.OperationRequest{
+		PaymentID:      "payment-123",
+		TransactionID:  "txn-123",
+		Method:         method,
+		IdempotencyKey: "payment-123:capture",
+		Amount:         entity.NewMoney(cents, "BRL"),
+	}
+}
+
+func TestFakeGatewayOperations(t *testing.T) {
+	fake := infra.NewFakeGateway([]infra.FakeRule{
+		{CardBIN: "400000", Outcome: infra.FakeOutcomeDecline},
+		{Method: entity.PaymentMethodPix, Outcome: infra.FakeOutcomeDecline, ResponseCode: "57"},
+		{MinAmount: 50000, Outcome: infra.FakeOutcomeUnavailable},
+	}, time.Second)
+
+	operations := map[string]func(context.Context, gateway.OperationRequest) (*gateway.Response, error){
+		"capture": fake.Capture,
+		"void":    fake.Void,
+		"refund":  fake.Refund,
+	}
+	for name, operate := range operations {
+		response, err := operate(context.Background(), newOperation(entity.PaymentMethodCreditCard, 1000))
+		if err != nil {
+			t.Fatalf("%s: expected no error but got: %v", name, err)
+		}
+		if !response.Approved() {
+			t.Errorf("%s: expected rules on a card BIN not to match operations, got %+v", name, response)
+		}
+
+		response, err = operate(context.Background(), newOperation(entity.PaymentMethodPix, 1000))
+		if err != nil {
+			t.Fatalf("%s: expected no error but got: %v", name, err)
+		}
+		if response.Status != gateway.StatusDeclined || response.ResponseCode != "57" {
+			t.Errorf("%s: expected decline 57 but got %+v", name, response)
+		}
+
+		_, err = operate(context.Background(), newOperation(entity.PaymentMethodCreditCard, 50000))
+		if !errors.Is(err, gateway.ErrUnavailable) {
+			t.Errorf("%s: expected ErrUnavailable but got: %v", name, err)
+		}
+	}
+}
+
+func TestHTTPGatewayOperationsAgainstStub(t *testing.T) {
+	stub := httptest.NewServer(infra.NewStubHandler(infra.NewFakeGateway([]infra.FakeRule{
+		{Method: entity.PaymentMethodBoleto, Outcome: infra.FakeOutcomeDecline, ResponseCode: "57"},
+		{MinAmount: 50000, Outcome: infra.FakeOutcomeTimeout},
+	}, time.Second)))
+	defer stub.Close()
+
+	gw, err := infra.New(infra.Config{Kind: "http", BaseURL: stub.URL, Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	operations := map[string]func(context.Context, gateway.OperationRequest) (*gateway.Response, error){
+		"capture": gw.Capture,
+		"void":    gw.Void,
+		"refund":  gw.Refund,
+	}
+	for name, operate := range operations {
+		for _, method := range []entity.PaymentMethod{
+			entity.PaymentMethodCreditCard,
+			entity.PaymentMethodDebitCard,
+			entity.PaymentMethodPix,
+			entity.PaymentMethodPayPal,
+		} {
+			response, err := operate(context.Background(), newOperation(method, 1000))
+			if err != nil {
+				t.Fatalf("%s %s: expected no error but got: %v", name, method, err)
+			}
+			if !response.Approved() {
+				t.Errorf("%s %s: expected an approval, got %+v", name, method, response)
+			}
+		}
+
+		response, err := operate(context.Background(), newOperation(entity.PaymentMethodBoleto, 1000))
+		if err != nil {
+			t.Fatalf("%s: expected no error but got: %v", name, err)
+		}
+		if response.Status != gateway.StatusDeclined || response.ResponseCode != "57" {
+			t.Errorf("%s: expected decline 57 but got %+v", name, response)
+		}
+
+		_, err = operate(context.Background(), newOperation(entity.PaymentMethodCreditCard, 50000))
+		if !errors.Is(err, gateway.ErrTimeout) {
+			t.Errorf("%s: expected ErrTimeout but got: %v", name, err)
+		}
+	}
+}
+
+func TestHTTPGatewayTokenizesCards(t *testing.T) {
+	stub := httptest.NewServer(infra.NewStubHandler(infra.NewFakeGateway([]infra.FakeRule{
+		{CardBIN: "400000", Outcome: infra.FakeOutcomeDecline},
+	}, time.Second)))
+	defer stub.Close()
+
+	gw, err := infra.New(infra.Config{Kind: "http", BaseURL: stub.URL, Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	card := &entity.CardData{Number: "4000000000000002", HolderName: "Test User", Expiry: "12/30", CVV: "123"}
+	req := newRequest(entity.PaymentMethodCreditCard, 1000, "a@example.com", card.BIN())
+	req.Card = card
+
+	first, err := gw.Process(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if first.Status != gateway.StatusDeclined {
+		t.Errorf("Expected the stub to see the card BIN and decline, got %s", first.Status)
+	}
+	if !strings.HasPrefix(first.CardToken, "tok_") {
+		t.Errorf("Expected a card token from the gateway but got %q", first.CardToken)
+	}
+
+	response, err := gw.Process(context.Background(), newRequest(entity.PaymentMethodPix, 1000, "a@example.com", ""))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if response.CardToken != "" {
+		t.Errorf("Expected no card token without a card but got %q", response.CardToken)
+	}
+}
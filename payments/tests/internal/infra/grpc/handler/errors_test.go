@@ -0,0 +1,87 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"payments/internal/domain/entity"
+	"payments/internal/domain/gateway"
+	"payments/internal/infra/grpc/handler"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		code     codes.Code
+		reason   string
+		field    string
+		internal bool
+	}{
+		{"validation", entity.ErrInvalidAmount, codes.InvalidArgument, "INVALID_AMOUNT", "money.amount", false},
+		{"payment details", entity.ErrInvalidCVV, codes.InvalidArgument, "INVALID_PAYMENT_DETAILS", "card_details.cvv", false},
+		{"not found", fmt.Errorf("failed to find payment: %w", entity.ErrPaymentNotFound), codes.NotFound, "PAYMENT_NOT_FOUND", "", false},
+		{"illegal transition", entity.ErrPaymentCannotBeCanceled, codes.FailedPrecondition, "PAYMENT_NOT_CANCELABLE", "", false},
+		{"database outage", fmt.Errorf("failed to update payment: %w", driver.ErrBadConn), codes.Unavailable, "DATABASE_UNAVAILABLE", "", false},
+		{"page token", entity.ErrInvalidPageToken, codes.InvalidArgument, "INVALID_PAGE_TOKEN", "page_token", false},
+		{"gateway outage", fmt.Errorf("failed to process payment: %w", gateway.ErrUnavailable), codes.Unavailable, "GATEWAY_UNAVAILABLE", "", false},
+		{"unexpected", errors.New("duplicate column in query"), codes.Internal, "INTERNAL", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(handler.ToStatus(tt.err))
+
+			if st.Code() != tt.code {
+				t.Errorf("Expected code %v but got: %v", tt.code, st.Code())
+			}
+			if tt.internal && st.Message() == tt.err.Error() {
+				t.Errorf("Expected internal errors not to be sent to clients, got: %q", st.Message())
+			}
+
+			var info *errdetails.ErrorInfo
+			var badRequest *errdetails.BadRequest
+			for _, detail := range st.Details() {
+				switch d := detail.(type) {
+				case *errdetails.ErrorInfo:
+					info = d
+				case *errdetails.BadRequest:
+					badRequest = d
+				}
+			}
+
+			if info == nil || info.Reason != tt.reason || info.Domain != handler.ErrorDomain {
+				t.Errorf("Expected ErrorInfo with reason %s but got: %v", tt.reason, info)
+			}
+			if tt.field == "" {
+				if badRequest != nil {
+					t.Errorf("Expected no BadRequest but got: %v", badRequest)
+				}
+				return
+			}
+			if badRequest == nil || len(badRequest.FieldViolations) != 1 || badRequest.FieldViolations[0].Field != tt.field {
+				t.Errorf("Expected a violation of %s but got: %v", tt.field, badRequest)
+			}
+		})
+	}
+}
+
+func TestToStatusKeepsStatusAndContextErrors(t *testing.T) {
+	original := status.Error(codes.PermissionDenied, "denied")
+	if err := handler.ToStatus(original); err != original {
+		t.Errorf("Expected a status error to be returned as is but got: %v", err)
+	}
+	if code := status.Code(handler.ToStatus(context.Canceled)); code != codes.Canceled {
+		t.Errorf("Expected Canceled but got: %v", code)
+	}
+	if handler.ToStatus(nil) != nil {
+		t.Error("Expected nil for a nil error")
+	}
+}
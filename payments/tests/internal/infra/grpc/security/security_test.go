@@ -0,0 +1,230 @@
+package security_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"payments/internal/infra/grpc/security"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T) *authority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a certificate for commonName and returns it with its key, in PEM
+func (a *authority) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func unaryCall(t *testing.T, authenticator *security.Authenticator, ctx context.Context) error {
+	t.Helper()
+	interceptor := authenticator.UnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/payment.PaymentService/GetPayment"}
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		if caller, ok := security.CallerFrom(ctx); !ok || caller == "" {
+			t.Error("Expected the caller in the handler context")
+		}
+		return "ok", nil
+	})
+	return err
+}
+
+func TestAuthenticatorServiceToken(t *testing.T) {
+	authenticator := security.NewAuthenticator(security.AuthConfig{
+		ServiceTokens: map[string]string{"orders": "orders-token"},
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		expected      codes.Code
+	}{
+		{"valid token", "Bearer orders-token", codes.OK},
+		{"missing token", "", codes.Unauthenticated},
+		{"unknown token", "Bearer other-token", codes.Unauthenticated},
+		{"not a bearer token", "Basic b3JkZXJzOnRva2Vu", codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+			}
+
+			err := unaryCall(t, authenticator, ctx)
+
+			if status.Code(err) != tt.expected {
+				t.Errorf("Expected code %v but got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorRequiresVerifiedCertificate(t *testing.T) {
+	ca := newAuthority(t)
+	certPEM, _ := ca.issue(t, "orders", x509.ExtKeyUsageClientAuth)
+	block, _ := pem.Decode(certPEM)
+	cert, _ := x509.ParseCertificate(block.Bytes)
+
+	authenticator := security.NewAuthenticator(security.AuthConfig{AllowedClients: []string{"orders"}})
+
+	verified := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, ca.cert}}}},
+	})
+	if err := unaryCall(t, authenticator, verified); err != nil {
+		t.Errorf("Expected no error with a verified certificate but got: %v", err)
+	}
+
+	// Presented but not verified, as on a server without a client CA
+	unverified := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+	})
+	if err := unaryCall(t, authenticator, unverified); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated with an unverified certificate but got: %v", err)
+	}
+}
+
+func TestMutualTLSServer(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t)
+	caFile := writeFile(t, dir, "ca.pem", ca.pem)
+	serverCert, serverKey := ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+
+	reloader, err := security.NewCertReloader(
+		writeFile(t, dir, "server.pem", serverCert),
+		writeFile(t, dir, "server-key.pem", serverKey),
+		caFile,
+	)
+	if err != nil {
+		t.Fatalf("Expected no error loading certificates but got: %v", err)
+	}
+	if !reloader.MutualTLS() {
+		t.Error("Expected mutual TLS with a client CA")
+	}
+
+	authenticator := security.NewAuthenticator(security.AuthConfig{AllowedClients: []string{"orders"}})
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(reloader.ServerConfig())),
+		grpc.UnaryInterceptor(authenticator.UnaryInterceptor()),
+		grpc.StreamInterceptor(authenticator.StreamInterceptor()),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	defer server.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+
+	check := func(commonName string) error {
+		clientCert, clientKey := ca.issue(t, commonName, x509.ExtKeyUsageClientAuth)
+		pair, err := tls.X509KeyPair(clientCert, clientKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			ServerName:   "localhost",
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{pair},
+		})))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		return err
+	}
+
+	if err := check("orders"); err != nil {
+		t.Errorf("Expected no error for an allowed client but got: %v", err)
+	}
+	if err := check("intruder"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for a client not allowed but got: %v", err)
+	}
+}
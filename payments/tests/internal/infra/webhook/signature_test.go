@@ -0,0 +1,57 @@
+package webhook_test
+
+import (
+	"payments/internal/infra/webhook"
+	"strings"
+	"testing"
+	"time"
+)
+
+const secret = "whsec_test"
+
+func TestVerifierAcceptsSignedRequest(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":"evt_123","type":"payment.approved"}`)
+	verifier := webhook.NewVerifier(secret, 5*time.Minute)
+
+	if err := verifier.Verify(webhook.Sign(secret, now, body), body, now); err != nil {
+		t.Errorf("Expected no error but got: %v", err)
+	}
+
+	// Signed with the previous secret and the new one while rotating
+	_, current, _ := strings.Cut(webhook.Sign(secret, now, body), ",")
+	header := webhook.Sign("whsec_old", now, body) + "," + current
+	if err := verifier.Verify(header, body, now.Add(time.Minute)); err != nil {
+		t.Errorf("Expected no error with two signatures but got: %v", err)
+	}
+}
+
+func TestVerifierRejectsInvalidRequests(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":"evt_123","type":"payment.approved"}`)
+	verifier := webhook.NewVerifier(secret, 5*time.Minute)
+
+	tests := []struct {
+		name     string
+		header   string
+		body     []byte
+		now      time.Time
+		expected error
+	}{
+		{"Missing header", "", body, now, webhook.ErrMissingSignature},
+		{"Malformed header", "v1", body, now, webhook.ErrInvalidSignature},
+		{"Missing timestamp", "v1=00", body, now, webhook.ErrInvalidSignature},
+		{"Wrong secret", webhook.Sign("other", now, body), body, now, webhook.ErrInvalidSignature},
+		{"Tampered body", webhook.Sign(secret, now, body), []byte(`{"id":"evt_123","type":"payment.refunded"}`), now, webhook.ErrInvalidSignature},
+		{"Replayed later", webhook.Sign(secret, now, body), body, now.Add(6 * time.Minute), webhook.ErrTimestampOutOfTolerance},
+		{"Signed in the future", webhook.Sign(secret, now.Add(6*time.Minute), body), body, now, webhook.ErrTimestampOutOfTolerance},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifier.Verify(tt.header, tt.body, tt.now); err != tt.expected {
+				t.Errorf("Expected %v but got: %v", tt.expected, err)
+			}
+		})
+	}
+}
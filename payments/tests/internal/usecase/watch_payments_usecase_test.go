@@ -0,0 +1,118 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"payments/internal/domain/entity"
+	"payments/internal/usecase"
+)
+
+// mockChangeRepository keeps changes the way the MySQL table does: each has
+// a sequence from its insert, but is only positioned once committed
+type mockChangeRepository struct {
+	mu        sync.Mutex
+	changes   []entity.PaymentStatusChange
+	committed map[int64]bool
+	last      int64
+}
+
+func newMockChangeRepository() *mockChangeRepository {
+	return &mockChangeRepository{committed: make(map[int64]bool)}
+}
+
+// insert saves a change that stays invisible until commit is called
+func (m *mockChangeRepository) insert(sequence int64, paymentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.changes = append(m.changes, entity.PaymentStatusChange{Sequence: sequence, PaymentID: paymentID})
+}
+
+func (m *mockChangeRepository) commit(sequence int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.committed[sequence] = true
+}
+
+func (m *mockChangeRepository) AssignPositions(ctx context.Context, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assigned := 0
+	for i := range m.changes {
+		if assigned == limit {
+			break
+		}
+		if m.changes[i].Position == 0 && m.committed[m.changes[i].Sequence] {
+			m.last++
+			m.changes[i].Position = m.last
+			assigned++
+		}
+	}
+	return assigned, nil
+}
+
+func (m *mockChangeRepository) FindAfter(ctx context.Context, position int64, limit int) ([]entity.PaymentStatusChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var found []entity.PaymentStatusChange
+	for p := position + 1; len(found) < limit; p++ {
+		index := -1
+		for i, change := range m.changes {
+			if change.Position == p {
+				index = i
+			}
+		}
+		if index < 0 {
+			break
+		}
+		found = append(found, m.changes[index])
+	}
+	return found, nil
+}
+
+var errStopWatching = errors.New("stop watching")
+
+func TestWatchPaymentsUseCase_StreamsLowerSequenceCommittedLate(t *testing.T) {
+	repo := newMockChangeRepository()
+	uc := usecase.NewWatchPaymentsUseCase(repo, time.Millisecond, 10)
+
+	// Sequence 1 is taken first but its transaction commits after 2's
+	repo.insert(1, "payment-late")
+	repo.insert(2, "payment-early")
+	repo.commit(2)
+
+	var sent []entity.PaymentStatusChange
+	err := uc.Watch(context.Background(), "", func(change entity.PaymentStatusChange) error {
+		sent = append(sent, change)
+		if len(sent) == 1 {
+			// Commit well after the change behind it was streamed
+			time.Sleep(5 * time.Millisecond)
+			repo.commit(1)
+			return nil
+		}
+		return errStopWatching
+	})
+	if !errors.Is(err, errStopWatching) {
+		t.Fatalf("Expected the watch to stream both changes, got: %v", err)
+	}
+
+	if sent[0].PaymentID != "payment-early" || sent[1].PaymentID != "payment-late" {
+		t.Fatalf("Expected the early commit then the late one, got %s then %s", sent[0].PaymentID, sent[1].PaymentID)
+	}
+	if sent[1].Position <= sent[0].Position {
+		t.Errorf("Expected the late commit to be positioned after %d, got %d", sent[0].Position, sent[1].Position)
+	}
+
+	// A consumer resuming from the first cursor still gets the late change
+	var resumed []entity.PaymentStatusChange
+	uc.Watch(context.Background(), sent[0].Cursor(), func(change entity.PaymentStatusChange) error {
+		resumed = append(resumed, change)
+		return errStopWatching
+	})
+	if len(resumed) != 1 || resumed[0].PaymentID != "payment-late" {
+		t.Errorf("Expected to resume with the late change, got %+v", resumed)
+	}
+}
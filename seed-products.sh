@@ -15,13 +15,22 @@ DB_NAME=${DB_NAME:-orders_db}
 #     exit 1
 # fi
 
+# Admin token for the Orders API, signed with the same JWT_SECRET (HS256);
+# set ORDERS_TOKEN to use another one
+JWT_SECRET=${JWT_SECRET:-dev-orders-jwt-secret}
+b64url() { openssl base64 -A | tr '+/' '-_' | tr -d '='; }
+JWT_HEADER=$(printf '{"alg":"HS256","typ":"JWT"}' | b64url)
+JWT_CLAIMS=$(printf '{"sub":"%s","roles":["admin"],"exp":%d}' "$(basename "$0")" $(( $(date +%s) + 3600 )) | b64url)
+JWT_SIGNATURE=$(printf '%s.%s' "$JWT_HEADER" "$JWT_CLAIMS" | openssl dgst -sha256 -hmac "$JWT_SECRET" -binary | b64url)
+ORDERS_TOKEN=${ORDERS_TOKEN:-$JWT_HEADER.$JWT_CLAIMS.$JWT_SIGNATURE}
+
 # Create products using HTTP API
 echo ""
 echo "Creating products via API..."
 
 # Product 1
 echo "📦 Creating Product 1: Notebook Dell"
-RESPONSE1=$(curl -s -X POST http://localhost:8080/api/v1/products \
+RESPONSE1=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/products \
   -H "Content-Type: application/json" \
   -d '{
     "name": "Notebook Dell Inspiron 15",
@@ -35,7 +44,7 @@ echo "   ✅ Created: $PRODUCT_1"
 
 # Product 2
 echo "📦 Creating Product 2: Mouse Logitech"
-RESPONSE2=$(curl -s -X POST http://localhost:8080/api/v1/products \
+RESPONSE2=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/products \
   -H "Content-Type: application/json" \
   -d '{
     "name": "Mouse Logitech MX Master 3",
@@ -49,7 +58,7 @@ echo "   ✅ Created: $PRODUCT_2"
 
 # Product 3
 echo "📦 Creating Product 3: Teclado Mecânico"
-RESPONSE3=$(curl -s -X POST http://localhost:8080/api/v1/products \
+RESPONSE3=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/products \
   -H "Content-Type: application/json" \
   -d '{
     "name": "Teclado Mecânico Keychron K2",
@@ -63,7 +72,7 @@ echo "   ✅ Created: $PRODUCT_3"
 
 # Product 4
 echo "📦 Creating Product 4: Monitor LG"
-RESPONSE4=$(curl -s -X POST http://localhost:8080/api/v1/products \
+RESPONSE4=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/products \
   -H "Content-Type: application/json" \
   -d '{
     "name": "Monitor LG UltraWide 34\"",
@@ -77,7 +86,7 @@ echo "   ✅ Created: $PRODUCT_4"
 
 # Product 5
 echo "📦 Creating Product 5: Webcam Logitech"
-RESPONSE5=$(curl -s -X POST http://localhost:8080/api/v1/products \
+RESPONSE5=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/products \
   -H "Content-Type: application/json" \
   -d '{
     "name": "Webcam Logitech C920",
@@ -91,7 +100,7 @@ echo "   ✅ Created: $PRODUCT_5"
 
 # Product 6
 echo "📦 Creating Product 6: Fone Bluetooth"
-RESPONSE6=$(curl -s -X POST http://localhost:8080/api/v1/products \
+RESPONSE6=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/products \
   -H "Content-Type: application/json" \
   -d '{
     "name": "Fone Sony WH-1000XM5",
@@ -120,6 +129,7 @@ echo "You can now use these product IDs to create orders!"
 echo ""
 echo "Example:"
 echo "curl -X POST http://localhost:8080/api/v1/orders/with-payment \\"
+echo "  -H 'Authorization: Bearer $ORDERS_TOKEN' \\"
 echo "  -H 'Content-Type: application/json' \\"
 echo "  -d '{"
 echo "    \"customer_email\": \"cliente@example.com\","
@@ -26,13 +26,22 @@ if ! nc -z localhost 50051 2>/dev/null; then
 fi
 echo -e "${GREEN}✅ Payment Service is running${NC}"
 
+# Admin token for the Orders API, signed with the same JWT_SECRET (HS256);
+# set ORDERS_TOKEN to use another one
+JWT_SECRET=${JWT_SECRET:-dev-orders-jwt-secret}
+b64url() { openssl base64 -A | tr '+/' '-_' | tr -d '='; }
+JWT_HEADER=$(printf '{"alg":"HS256","typ":"JWT"}' | b64url)
+JWT_CLAIMS=$(printf '{"sub":"%s","roles":["admin"],"exp":%d}' "$(basename "$0")" $(( $(date +%s) + 3600 )) | b64url)
+JWT_SIGNATURE=$(printf '%s.%s' "$JWT_HEADER" "$JWT_CLAIMS" | openssl dgst -sha256 -hmac "$JWT_SECRET" -binary | b64url)
+ORDERS_TOKEN=${ORDERS_TOKEN:-$JWT_HEADER.$JWT_CLAIMS.$JWT_SIGNATURE}
+
 echo ""
 echo "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
 echo "SETUP: Creating sample products"
 echo "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
 
 # Create Product 1
-PROD1_RESPONSE=$(curl -s -X POST http://localhost:8080/api/v1/products \
+PROD1_RESPONSE=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/products \
   -H "Content-Type: application/json" \
   -d '{
     "name": "Test Product 1",
@@ -43,7 +52,7 @@ PRODUCT_ID_1=$(echo "$PROD1_RESPONSE" | jq -r '.id')
 echo "✅ Product 1 created: $PRODUCT_ID_1"
 
 # Create Product 2
-PROD2_RESPONSE=$(curl -s -X POST http://localhost:8080/api/v1/products \
+PROD2_RESPONSE=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/products \
   -H "Content-Type: application/json" \
   -d '{
     "name": "Test Product 2",
@@ -58,7 +67,7 @@ echo "━━━━━━━━━━━━━━━━━━━━━━━━
 echo "TEST 1: Create Order with Payment"
 echo "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
 
-RESPONSE=$(curl -s -X POST http://localhost:8080/api/v1/orders/with-payment \
+RESPONSE=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/orders/with-payment \
   -H "Content-Type: application/json" \
   -d "{
     \"customer_email\": \"teste@example.com\",
@@ -83,7 +92,7 @@ echo "$RESPONSE" | jq '.'
 
 ORDER_ID=$(echo "$RESPONSE" | jq -r '.order_id')
 PAYMENT_ID=$(echo "$RESPONSE" | jq -r '.payment_id')
-TOTAL=$(echo "$RESPONSE" | jq -r '.total')
+TOTAL=$(echo "$RESPONSE" | jq -r '.total.amount / 100')
 STATUS=$(echo "$RESPONSE" | jq -r '.status')
 
 if [ "$ORDER_ID" != "null" ] && [ "$ORDER_ID" != "" ]; then
@@ -102,7 +111,7 @@ echo "━━━━━━━━━━━━━━━━━━━━━━━━
 echo "TEST 2: Get Order Details"
 echo "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
 
-ORDER_DETAILS=$(curl -s http://localhost:8080/api/v1/orders/$ORDER_ID)
+ORDER_DETAILS=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" http://localhost:8080/api/v1/orders/$ORDER_ID)
 echo "Order Details:"
 echo "$ORDER_DETAILS" | jq '.'
 
@@ -117,11 +126,11 @@ echo "━━━━━━━━━━━━━━━━━━━━━━━━
 echo "TEST 3: Cancel Order and Payment"
 echo "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
 
-CANCEL_RESPONSE=$(curl -s -X POST http://localhost:8080/api/v1/orders/$ORDER_ID/cancel \
+CANCEL_RESPONSE=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X POST http://localhost:8080/api/v1/orders/$ORDER_ID/cancel \
   -H "Content-Type: application/json" \
-  -d "{
-    \"payment_id\": \"$PAYMENT_ID\"
-  }")
+  -d '{
+    "reason": "Integration test"
+  }')
 
 echo "Cancel Response:"
 echo "$CANCEL_RESPONSE" | jq '.'
@@ -138,7 +147,7 @@ echo "━━━━━━━━━━━━━━━━━━━━━━━━
 echo "TEST 4: Verify Order Status After Cancel"
 echo "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
 
-ORDER_AFTER_CANCEL=$(curl -s http://localhost:8080/api/v1/orders/$ORDER_ID)
+ORDER_AFTER_CANCEL=$(curl -s -H "Authorization: Bearer $ORDERS_TOKEN" http://localhost:8080/api/v1/orders/$ORDER_ID)
 echo "Order After Cancel:"
 echo "$ORDER_AFTER_CANCEL" | jq '.'
 
@@ -156,6 +165,6 @@ echo "━━━━━━━━━━━━━━━━━━━━━━━━
 
 echo ""
 echo "🧹 Cleaning up test products..."
-curl -s -X DELETE http://localhost:8080/api/v1/products/$PRODUCT_ID_1 > /dev/null
-curl -s -X DELETE http://localhost:8080/api/v1/products/$PRODUCT_ID_2 > /dev/null
+curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X DELETE http://localhost:8080/api/v1/products/$PRODUCT_ID_1 > /dev/null
+curl -s -H "Authorization: Bearer $ORDERS_TOKEN" -X DELETE http://localhost:8080/api/v1/products/$PRODUCT_ID_2 > /dev/null
 echo "✅ Test products deleted"